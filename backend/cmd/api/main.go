@@ -15,8 +15,14 @@ import (
 	"github.com/AkshayDubey29/MoniFlux/backend/internal/config/utils"
 	"github.com/AkshayDubey29/MoniFlux/backend/internal/controllers"
 	"github.com/AkshayDubey29/MoniFlux/backend/internal/db/mongo"
+	"github.com/AkshayDubey29/MoniFlux/backend/internal/loadgen/metrics"
+	"github.com/AkshayDubey29/MoniFlux/backend/internal/services/audit"
 	"github.com/AkshayDubey29/MoniFlux/backend/internal/services/authentication"
+	"github.com/AkshayDubey29/MoniFlux/backend/internal/services/authorization"
+	"github.com/AkshayDubey29/MoniFlux/backend/internal/services/monitoring"
 	"github.com/AkshayDubey29/MoniFlux/backend/pkg/logger"
+	"github.com/AkshayDubey29/MoniFlux/backend/pkg/tracing"
+	"github.com/prometheus/client_golang/prometheus"
 )
 
 func main() {
@@ -31,6 +37,12 @@ func main() {
 	customLogger := logger.NewLogger(cfg.LogLevel, cfg.LogFormat, cfg.LogFilePath)
 	customLogger.Info("Custom logger initialized")
 
+	// Export the spans of the API, MongoDB commands and deliveries
+	shutdownTracing, err := tracing.Setup(context.Background(), cfg.Tracing, "moniflux-api")
+	if err != nil {
+		customLogger.Fatalf("Failed to initialize tracing: %v", err)
+	}
+
 	// Initialize MongoDB connection
 	mongoClient, err := mongo.NewMongoClient(cfg, customLogger)
 	if err != nil {
@@ -38,8 +50,42 @@ func main() {
 	}
 	customLogger.Info("MongoDB initialized")
 
+	// Ensure the indexes used by test listing and lookups exist
+	indexCtx, indexCancel := context.WithTimeout(context.Background(), 30*time.Second)
+	if err := mongoClient.EnsureIndexes(indexCtx); err != nil {
+		customLogger.Errorf("Failed to ensure MongoDB indexes: %v", err)
+	}
+	indexCancel()
+
 	// Initialize controller with MongoClient
 	controller := controllers.NewLoadGenController(cfg, customLogger, mongoClient.Client)
+	prometheus.MustRegister(controller.Metrics)
+
+	// Register the destinations of the configuration file so tests can reference them by name
+	destinationCtx, destinationCancel := context.WithTimeout(context.Background(), 30*time.Second)
+	if err := controller.Destinations.LoadConfig(destinationCtx, cfg.Destinations); err != nil {
+		customLogger.Errorf("Failed to load configured destinations: %v", err)
+	}
+	destinationCancel()
+
+	// Resume the tests that were waiting for capacity when the process last stopped
+	queueCtx, queueCancel := context.WithTimeout(context.Background(), 30*time.Second)
+	if err := controller.RestoreQueue(queueCtx); err != nil {
+		customLogger.Errorf("Failed to restore queued tests: %v", err)
+	}
+	queueCancel()
+
+	// Initialize MonitoringService with the dependency checks of /readyz, and record
+	// their results periodically
+	monitoringService := monitoring.NewMonitoringService(cfg, customLogger, mongoClient.Client)
+	monitoringService.AddCheck(monitoring.CheckMongoDB, mongoClient.Ping)
+	monitoringService.AddCheck(monitoring.CheckDestinations, controller.CheckDestinations)
+	monitoringService.AddCheck(monitoring.CheckWorkers, func(context.Context) error {
+		return controller.CheckSaturation(cfg.Monitoring.SaturationThreshold)
+	})
+	monitorCtx, monitorCancel := context.WithCancel(context.Background())
+	defer monitorCancel()
+	monitoringService.StartHealthCheckScheduler(monitorCtx)
 
 	// Initialize AuthenticationService
 	authService, err := authentication.NewAuthenticationService(cfg, customLogger, mongoClient.Client)
@@ -48,8 +94,38 @@ func main() {
 	}
 	customLogger.Info("AuthenticationService initialized")
 
+	// Initialize AuthorizationService and seed the default roles and permissions
+	authzService := authorization.NewAuthorizationService(cfg, customLogger, mongoClient)
+	seedCtx, seedCancel := context.WithTimeout(context.Background(), 30*time.Second)
+	if err := authzService.CreateDefaultRoles(seedCtx); err != nil {
+		customLogger.Fatalf("Failed to create default roles: %v", err)
+	}
+	if err := authzService.EnsureAdminUsers(seedCtx, cfg.AdminUsers); err != nil {
+		customLogger.Errorf("Failed to grant admin role to configured users: %v", err)
+	}
+	seedCancel()
+	customLogger.Info("AuthorizationService initialized")
+
+	// Enable single sign-on; IdP groups are mapped onto roles by the AuthorizationService
+	if cfg.OIDC.Enabled {
+		oidcCtx, oidcCancel := context.WithTimeout(context.Background(), 30*time.Second)
+		provider, err := authentication.NewOIDCProvider(oidcCtx, cfg.OIDC, &http.Client{Timeout: 10 * time.Second})
+		oidcCancel()
+		if err != nil {
+			customLogger.Fatalf("Failed to initialize OIDC provider: %v", err)
+		}
+		authService.EnableOIDC(provider, authzService)
+	}
+
+	// Initialize AuditService recording mutating API actions
+	auditService, err := audit.NewAuditService(cfg, customLogger, mongoClient.Client)
+	if err != nil {
+		customLogger.Fatalf("Failed to initialize AuditService: %v", err)
+	}
+	customLogger.Info("AuditService initialized")
+
 	// Set up the API router with all routes and middleware
-	router := routers.SetupRouter(customLogger, controller, authService, cfg)
+	router := routers.SetupRouter(customLogger, controller, authService, authzService, auditService, monitoringService, cfg)
 
 	// Define the HTTP server with timeouts and the router as the handler
 	srv := &http.Server{
@@ -68,6 +144,17 @@ func main() {
 		}
 	}()
 
+	// Serve the Prometheus metrics on their own port
+	metricsSrv := metrics.NewServer(cfg.Metrics)
+	if metricsSrv != nil {
+		go func() {
+			customLogger.Infof("Serving metrics on port %d at %s", cfg.Metrics.PrometheusPort, cfg.Metrics.PrometheusEndpoint)
+			if err := metricsSrv.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+				customLogger.Errorf("Metrics server failed: %v", err)
+			}
+		}()
+	}
+
 	// Channel to listen for interrupt or terminate signals
 	quit := make(chan os.Signal, 1)
 	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
@@ -78,10 +165,21 @@ func main() {
 	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
 	defer cancel()
 
+	monitorCancel()
+
 	// Attempt to gracefully shut down the server
 	if err := srv.Shutdown(ctx); err != nil {
 		customLogger.Fatalf("Server Shutdown Failed:%+v", err)
 	}
+	if metricsSrv != nil {
+		if err := metricsSrv.Shutdown(ctx); err != nil {
+			customLogger.Errorf("Metrics server shutdown failed: %v", err)
+		}
+	}
+
+	if err := auditService.Close(); err != nil {
+		customLogger.Errorf("Error closing audit log file: %v", err)
+	}
 
 	// Disconnect MongoDB client
 	if mongoClient != nil && mongoClient.Client != nil {
@@ -92,5 +190,9 @@ func main() {
 		}
 	}
 
+	if err := shutdownTracing(ctx); err != nil {
+		customLogger.Errorf("Error flushing traces: %v", err)
+	}
+
 	customLogger.Info("Server gracefully stopped")
 }
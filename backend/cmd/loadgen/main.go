@@ -12,11 +12,20 @@ import (
 	"time"
 
 	"github.com/AkshayDubey29/MoniFlux/backend/internal/api/handlers"
+	"github.com/AkshayDubey29/MoniFlux/backend/internal/api/middlewares"
+	"github.com/AkshayDubey29/MoniFlux/backend/internal/api/models"
+	"github.com/AkshayDubey29/MoniFlux/backend/internal/api/openapi"
+	"github.com/AkshayDubey29/MoniFlux/backend/internal/api/routers"
 	"github.com/AkshayDubey29/MoniFlux/backend/internal/config/utils"
 	"github.com/AkshayDubey29/MoniFlux/backend/internal/controllers"
 	"github.com/AkshayDubey29/MoniFlux/backend/internal/db/mongo"
+	"github.com/AkshayDubey29/MoniFlux/backend/internal/loadgen/metrics"
 	"github.com/AkshayDubey29/MoniFlux/backend/internal/services/authentication"
+	"github.com/AkshayDubey29/MoniFlux/backend/internal/services/authorization"
+	"github.com/AkshayDubey29/MoniFlux/backend/internal/services/monitoring"
+	"github.com/AkshayDubey29/MoniFlux/backend/pkg/tracing"
 	"github.com/gorilla/mux"
+	"github.com/prometheus/client_golang/prometheus"
 	"github.com/sirupsen/logrus"
 )
 
@@ -38,6 +47,12 @@ func main() {
 		logger.Fatalf("Failed to load config: %v", err)
 	}
 
+	// Export the spans of the API, MongoDB commands and deliveries
+	shutdownTracing, err := tracing.Setup(context.Background(), cfg.Tracing, "moniflux-loadgen")
+	if err != nil {
+		logger.Fatalf("Failed to initialize tracing: %v", err)
+	}
+
 	// Initialize MongoDB client
 	mongoClient, err := mongo.NewMongoClient(cfg, logger)
 	if err != nil {
@@ -45,8 +60,35 @@ func main() {
 	}
 	defer mongoClient.Disconnect(context.Background())
 
+	// Ensure the indexes used by test listing and lookups exist
+	indexCtx, indexCancel := context.WithTimeout(context.Background(), 30*time.Second)
+	if err := mongoClient.EnsureIndexes(indexCtx); err != nil {
+		logger.Errorf("Failed to ensure MongoDB indexes: %v", err)
+	}
+	indexCancel()
+
 	// Initialize controller with MongoClient's internal client
 	controller := controllers.NewLoadGenController(cfg, logger, mongoClient.Client)
+	prometheus.MustRegister(controller.Metrics)
+
+	// Register the destinations of the configuration file so tests can reference them by name
+	destinationCtx, destinationCancel := context.WithTimeout(context.Background(), 30*time.Second)
+	if err := controller.Destinations.LoadConfig(destinationCtx, cfg.Destinations); err != nil {
+		logger.Errorf("Failed to load configured destinations: %v", err)
+	}
+	destinationCancel()
+
+	// Initialize MonitoringService with the dependency checks of /readyz, and record
+	// their results periodically
+	monitoringService := monitoring.NewMonitoringService(cfg, logger, mongoClient.Client)
+	monitoringService.AddCheck(monitoring.CheckMongoDB, mongoClient.Ping)
+	monitoringService.AddCheck(monitoring.CheckDestinations, controller.CheckDestinations)
+	monitoringService.AddCheck(monitoring.CheckWorkers, func(context.Context) error {
+		return controller.CheckSaturation(cfg.Monitoring.SaturationThreshold)
+	})
+	monitorCtx, monitorCancel := context.WithCancel(context.Background())
+	defer monitorCancel()
+	monitoringService.StartHealthCheckScheduler(monitorCtx)
 
 	// Initialize authentication service
 	authService, err := authentication.NewAuthenticationService(cfg, logger, mongoClient.Client)
@@ -54,21 +96,55 @@ func main() {
 		logger.Fatalf("Failed to initialize AuthenticationService: %v", err)
 	}
 
+	// Initialize authorization service enforcing the same per-route permissions as the API server
+	authzService := authorization.NewAuthorizationService(cfg, logger, mongoClient)
+
 	// Initialize handlers
 	handler := handlers.NewHandler(controller, authService, logger)
 
 	// Set up router
 	router := mux.NewRouter()
+	router.Use(middlewares.RequestIDMiddleware)
+	router.Use(middlewares.TracingMiddleware)
 
-	// Define routes
-	router.HandleFunc("/tests", handler.StartTest).Methods("POST")
-	router.HandleFunc("/tests/schedule", handler.ScheduleTest).Methods("POST")
-	router.HandleFunc("/tests/cancel", handler.CancelTest).Methods("POST")
-	//router.HandleFunc("/tests/restart", handler.RestartTest).Methods("POST")
-	router.HandleFunc("/tests/results", handler.SaveResults).Methods("POST")
-	router.HandleFunc("/tests", handler.GetAllTests).Methods("GET")
-	router.HandleFunc("/tests/{testID}", handler.GetTestByID).Methods("GET")
-	router.HandleFunc("/health", handlers.HealthCheck).Methods("GET") // Health Check Endpoint
+	// Apply authentication middleware to all routes except the probes and the OpenAPI document
+	apiRouter := router.PathPrefix("/").Subrouter()
+	apiRouter.Use(middlewares.NewAuthMiddleware(authService, logger).MiddlewareFunc)
+
+	// requirePermission wraps a handler so that it only runs for users holding the permission.
+	requirePermission := func(permission string, handler http.HandlerFunc) http.Handler {
+		return authorization.NewAuthorizationMiddleware(authzService, logger, []string{permission}).MiddlewareFunc(handler)
+	}
+
+	// Define routes: the /api/v1 test resource, and the paths the load generator served
+	// before it as deprecated aliases
+	mh := monitoring.NewMonitoringHandlers(monitoringService, logger)
+	routes := routers.TestRoutes(handler)
+	routes = append(routes, []routers.Route{
+		{Method: "POST", Path: "/tests", Summary: "Create and start a test", Tag: "tests", Permission: authorization.PermTestsStart, Successor: routers.APIPrefix + "/tests/{testID}:start",
+			Request: models.Test{}, Response: models.Test{}, Status: http.StatusCreated, Handler: handler.StartTest},
+		{Method: "POST", Path: "/tests/schedule", Summary: "Schedule a test", Tag: "tests", Permission: authorization.PermTestsStart, Successor: routers.APIPrefix + "/tests/{testID}:schedule",
+			Request: models.ScheduleRequest{}, Response: models.ScheduleRequest{}, Handler: handler.ScheduleTest},
+		{Method: "POST", Path: "/tests/cancel", Summary: "Cancel a test", Tag: "tests", Permission: authorization.PermTestsCancel, Successor: routers.APIPrefix + "/tests/{testID}:cancel",
+			Request: models.CancelRequest{}, Response: map[string]string{}, Handler: handler.CancelTest},
+		{Method: "POST", Path: "/tests/results", Summary: "Save the results of a test", Tag: "tests", Permission: authorization.PermResultsWrite, Successor: routers.APIPrefix + "/tests/{testID}/results",
+			Request: models.TestResults{}, Response: models.TestResults{}, Handler: handler.SaveResults},
+		{Method: "GET", Path: "/tests", Summary: "List tests", Tag: "tests", Scope: authorization.PermTestsRead, Successor: routers.APIPrefix + "/tests",
+			Response: models.TestPage{}, Handler: handler.GetAllTests},
+		{Method: "GET", Path: "/tests/{testID}", Summary: "Get a test", Tag: "tests", Scope: authorization.PermTestsRead, Successor: routers.APIPrefix + "/tests/{testID}",
+			Response: models.Test{}, Handler: handler.GetTestByID},
+
+		// Liveness, readiness and health check history endpoints
+		{Method: "GET", Path: "/livez", Summary: "Liveness probe", Tag: "health", Public: true, Handler: mh.LivezHandler},
+		{Method: "GET", Path: "/health", Summary: "Liveness probe", Tag: "health", Public: true, Handler: mh.LivezHandler},
+		{Method: "GET", Path: "/readyz", Summary: "Readiness probe running the dependency checks", Tag: "health", Public: true, Handler: mh.ReadyzHandler},
+		{Method: "GET", Path: "/health/history", Summary: "Get the recorded health check results", Tag: "health", Handler: mh.GetHealthCheckHistoryHandler},
+		{Method: "GET", Path: "/health/status", Summary: "Get the latest result of each health check", Tag: "health", Handler: mh.HealthCheckStatusHandler},
+	}...)
+	routes = append(routes, routers.OpenAPIRoute(func() *openapi.Document {
+		return routers.BuildOpenAPI("MoniFlux load generator API", routes)
+	}))
+	routers.Register(router, apiRouter, routes, requirePermission, logger)
 
 	// Start HTTP server
 	srv := &http.Server{
@@ -83,12 +159,24 @@ func main() {
 		}
 	}()
 
+	// Serve the Prometheus metrics on their own port
+	metricsSrv := metrics.NewServer(cfg.Metrics)
+	if metricsSrv != nil {
+		go func() {
+			logger.Infof("Serving metrics on port %d at %s", cfg.Metrics.PrometheusPort, cfg.Metrics.PrometheusEndpoint)
+			if err := metricsSrv.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+				logger.Errorf("Metrics server failed: %v", err)
+			}
+		}()
+	}
+
 	// Graceful shutdown on interrupt signal
 	stop := make(chan os.Signal, 1)
 	signal.Notify(stop, os.Interrupt, syscall.SIGTERM)
 
 	<-stop
 	logger.Info("Shutting down server...")
+	monitorCancel()
 
 	// Shutdown the server with a timeout
 	ctxShutdown, cancelShutdown := context.WithTimeout(context.Background(), 5*time.Second)
@@ -97,6 +185,15 @@ func main() {
 	if err := srv.Shutdown(ctxShutdown); err != nil {
 		logger.Fatalf("Server Shutdown Failed:%+v", err)
 	}
+	if metricsSrv != nil {
+		if err := metricsSrv.Shutdown(ctxShutdown); err != nil {
+			logger.Errorf("Metrics server shutdown failed: %v", err)
+		}
+	}
+
+	if err := shutdownTracing(ctxShutdown); err != nil {
+		logger.Errorf("Error flushing traces: %v", err)
+	}
 
 	logger.Info("Server exited gracefully")
 }
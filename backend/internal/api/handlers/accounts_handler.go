@@ -0,0 +1,202 @@
+// backend/internal/api/handlers/accounts_handler.go
+
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/AkshayDubey29/MoniFlux/backend/internal/api/problem"
+	"github.com/AkshayDubey29/MoniFlux/backend/internal/services/authentication"
+	"github.com/gorilla/mux"
+)
+
+// GetCurrentUser handles returning the profile of the caller.
+func (h *Handler) GetCurrentUser(w http.ResponseWriter, r *http.Request) {
+	principal, ok := requirePrincipal(w, r)
+	if !ok {
+		return
+	}
+
+	profile, err := h.AuthService.GetProfile(r.Context(), principal.UserID)
+	if err != nil {
+		h.respondWithAccountError(w, err, "Failed to retrieve user")
+		return
+	}
+	respondWithJSON(w, http.StatusOK, profile)
+}
+
+// UpdateCurrentUser handles updating the caller's profile. Changing the email address
+// sends a new verification email.
+func (h *Handler) UpdateCurrentUser(w http.ResponseWriter, r *http.Request) {
+	principal, ok := requirePrincipal(w, r)
+	if !ok {
+		return
+	}
+
+	var req struct {
+		Email *string `json:"email,omitempty" validate:"omitempty,email"`
+	}
+	if !h.decodeAndValidate(w, r, &req) {
+		return
+	}
+
+	if req.Email != nil {
+		if err := h.AuthService.UpdateEmail(r.Context(), principal.UserID, *req.Email); err != nil &&
+			!errors.Is(err, authentication.ErrEmailSenderUnavailable) {
+			h.respondWithAccountError(w, err, "Failed to update user")
+			return
+		}
+	}
+
+	profile, err := h.AuthService.GetProfile(r.Context(), principal.UserID)
+	if err != nil {
+		h.respondWithAccountError(w, err, "Failed to retrieve user")
+		return
+	}
+	respondWithJSON(w, http.StatusOK, profile)
+}
+
+// ChangePassword handles changing the caller's password. All of their sessions are ended.
+func (h *Handler) ChangePassword(w http.ResponseWriter, r *http.Request) {
+	principal, ok := requirePrincipal(w, r)
+	if !ok {
+		return
+	}
+
+	var req struct {
+		CurrentPassword string `json:"currentPassword" validate:"required"`
+		NewPassword     string `json:"newPassword" validate:"required"`
+	}
+	if !h.decodeAndValidate(w, r, &req) {
+		return
+	}
+
+	if err := h.AuthService.ChangePassword(r.Context(), principal.UserID, req.CurrentPassword, req.NewPassword); err != nil {
+		h.respondWithAccountError(w, err, "Failed to change password")
+		return
+	}
+	respondWithJSON(w, http.StatusOK, map[string]string{"status": "password changed"})
+}
+
+// RequestEmailVerification handles re-sending the verification email to the caller.
+func (h *Handler) RequestEmailVerification(w http.ResponseWriter, r *http.Request) {
+	principal, ok := requirePrincipal(w, r)
+	if !ok {
+		return
+	}
+
+	user, err := h.AuthService.GetUserByID(r.Context(), principal.UserID)
+	if err != nil {
+		h.respondWithAccountError(w, err, "Failed to retrieve user")
+		return
+	}
+	if err := h.AuthService.SendVerificationEmail(r.Context(), user); err != nil {
+		h.respondWithAccountError(w, err, "Failed to send verification email")
+		return
+	}
+	respondWithJSON(w, http.StatusAccepted, map[string]string{"status": "verification email sent"})
+}
+
+// VerifyEmail handles confirming an email address with the token from the verification email.
+func (h *Handler) VerifyEmail(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Token string `json:"token" validate:"required"`
+	}
+	if !h.decodeAndValidate(w, r, &req) {
+		return
+	}
+
+	if err := h.AuthService.VerifyEmail(r.Context(), req.Token); err != nil {
+		h.respondWithAccountError(w, err, "Failed to verify email")
+		return
+	}
+	respondWithJSON(w, http.StatusOK, map[string]string{"status": "email verified"})
+}
+
+// ResetPassword handles setting a new password with a reset token.
+func (h *Handler) ResetPassword(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Token       string `json:"token" validate:"required"`
+		NewPassword string `json:"newPassword" validate:"required"`
+	}
+	if !h.decodeAndValidate(w, r, &req) {
+		return
+	}
+
+	if err := h.AuthService.ResetPassword(r.Context(), req.Token, req.NewPassword); err != nil {
+		h.respondWithAccountError(w, err, "Failed to reset password")
+		return
+	}
+	respondWithJSON(w, http.StatusOK, map[string]string{"status": "password reset"})
+}
+
+// AdminResetPassword handles an administrator starting a password reset for a user.
+// The reset link is emailed to the user.
+func (h *Handler) AdminResetPassword(w http.ResponseWriter, r *http.Request) {
+	userID := mux.Vars(r)["userID"]
+	expiresAt, err := h.AuthService.CreatePasswordReset(r.Context(), userID)
+	if err != nil {
+		h.respondWithAccountError(w, err, "Failed to start password reset")
+		return
+	}
+	respondWithJSON(w, http.StatusAccepted, map[string]interface{}{"status": "reset email sent", "userID": userID, "expiresAt": expiresAt})
+}
+
+// DisableUser handles disabling a user and ending their sessions.
+func (h *Handler) DisableUser(w http.ResponseWriter, r *http.Request) {
+	h.setUserDisabled(w, r, true)
+}
+
+// EnableUser handles re-enabling a user and clearing any login lockout.
+func (h *Handler) EnableUser(w http.ResponseWriter, r *http.Request) {
+	h.setUserDisabled(w, r, false)
+}
+
+func (h *Handler) setUserDisabled(w http.ResponseWriter, r *http.Request, disabled bool) {
+	userID := mux.Vars(r)["userID"]
+	if err := h.AuthService.SetUserDisabled(r.Context(), userID, disabled); err != nil {
+		h.respondWithAccountError(w, err, "Failed to update user")
+		return
+	}
+	respondWithJSON(w, http.StatusOK, map[string]interface{}{"userID": userID, "disabled": disabled})
+}
+
+// decodeAndValidate decodes the JSON body into req and validates it, writing a 400 response on failure.
+func (h *Handler) decodeAndValidate(w http.ResponseWriter, r *http.Request, req interface{}) bool {
+	if err := json.NewDecoder(r.Body).Decode(req); err != nil {
+		h.Logger.Errorf("Failed to decode request: %v", err)
+		respondWithInvalidPayload(w)
+		return false
+	}
+	if err := h.Validator.Struct(req); err != nil {
+		h.Logger.Errorf("Validation error: %v", err)
+		respondWithValidationError(w, err)
+		return false
+	}
+	return true
+}
+
+// respondWithAccountError maps account management errors to HTTP responses.
+func (h *Handler) respondWithAccountError(w http.ResponseWriter, err error, fallback string) {
+	h.Logger.Errorf("%s: %v", fallback, err)
+	switch {
+	case errors.Is(err, authentication.ErrWeakPassword),
+		errors.Is(err, authentication.ErrPasswordReused):
+		problem.Error(w, http.StatusBadRequest, problem.CodeWeakPassword, err.Error())
+	case errors.Is(err, authentication.ErrInvalidAccountToken),
+		errors.Is(err, authentication.ErrEmailAlreadyVerified):
+		problem.Error(w, http.StatusBadRequest, problem.CodeInvalidRequest, err.Error())
+	case errors.Is(err, authentication.ErrInvalidCredentials):
+		problem.Error(w, http.StatusForbidden, problem.CodeForbidden, "Current password is incorrect")
+	case errors.Is(err, authentication.ErrPasswordManagedBySSO):
+		problem.Error(w, http.StatusConflict, problem.CodeConflict, err.Error())
+	case errors.Is(err, authentication.ErrUserNotFound):
+		problem.Error(w, http.StatusNotFound, problem.CodeNotFound, err.Error())
+	case errors.Is(err, authentication.ErrEmailSenderUnavailable):
+		problem.Error(w, http.StatusBadGateway, problem.CodeUpstreamFailed, err.Error())
+	default:
+		problem.Error(w, http.StatusInternalServerError, problem.CodeInternal, fallback)
+	}
+}
@@ -0,0 +1,174 @@
+// backend/internal/api/handlers/admin_handler.go
+
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/AkshayDubey29/MoniFlux/backend/internal/api/problem"
+	"github.com/AkshayDubey29/MoniFlux/backend/internal/services/authorization"
+	validator "github.com/go-playground/validator/v10"
+	"github.com/gorilla/mux"
+	"github.com/sirupsen/logrus"
+)
+
+// AdminHandler serves the role, permission and user role assignment endpoints.
+type AdminHandler struct {
+	Authz     *authorization.AuthorizationService
+	Validator *validator.Validate
+	Logger    *logrus.Logger
+}
+
+// NewAdminHandler creates a new AdminHandler instance.
+func NewAdminHandler(authzService *authorization.AuthorizationService, logger *logrus.Logger) *AdminHandler {
+	return &AdminHandler{
+		Authz:     authzService,
+		Validator: validator.New(),
+		Logger:    logger,
+	}
+}
+
+// ListPermissions handles listing all permissions.
+func (ah *AdminHandler) ListPermissions(w http.ResponseWriter, r *http.Request) {
+	permissions, err := ah.Authz.ListPermissions(r.Context())
+	if err != nil {
+		ah.respondWithAuthzError(w, err, "Failed to retrieve permissions")
+		return
+	}
+	respondWithJSON(w, http.StatusOK, permissions)
+}
+
+// CreatePermission handles creating a new permission.
+func (ah *AdminHandler) CreatePermission(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Name        string `json:"name" validate:"required,min=3,max=64"`
+		Description string `json:"description"`
+	}
+	if !ah.decodeAndValidate(w, r, &req) {
+		return
+	}
+
+	permission, err := ah.Authz.CreatePermission(r.Context(), req.Name, req.Description)
+	if err != nil {
+		ah.respondWithAuthzError(w, err, "Failed to create permission")
+		return
+	}
+	respondWithJSON(w, http.StatusCreated, permission)
+}
+
+// ListRoles handles listing all roles with their permissions.
+func (ah *AdminHandler) ListRoles(w http.ResponseWriter, r *http.Request) {
+	roles, err := ah.Authz.ListRoles(r.Context())
+	if err != nil {
+		ah.respondWithAuthzError(w, err, "Failed to retrieve roles")
+		return
+	}
+	respondWithJSON(w, http.StatusOK, roles)
+}
+
+// CreateRole handles creating a new role with a set of permissions.
+func (ah *AdminHandler) CreateRole(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Name        string   `json:"name" validate:"required,min=2,max=64"`
+		Permissions []string `json:"permissions" validate:"dive,required"`
+	}
+	if !ah.decodeAndValidate(w, r, &req) {
+		return
+	}
+
+	if _, err := ah.Authz.CreateRole(r.Context(), req.Name, req.Permissions); err != nil {
+		ah.respondWithAuthzError(w, err, "Failed to create role")
+		return
+	}
+	respondWithJSON(w, http.StatusCreated, map[string]interface{}{"name": req.Name, "permissions": req.Permissions})
+}
+
+// SetRolePermissions handles replacing the permissions of a role.
+func (ah *AdminHandler) SetRolePermissions(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Permissions []string `json:"permissions" validate:"required,dive,required"`
+	}
+	if !ah.decodeAndValidate(w, r, &req) {
+		return
+	}
+
+	roleName := mux.Vars(r)["role"]
+	if err := ah.Authz.SetRolePermissions(r.Context(), roleName, req.Permissions); err != nil {
+		ah.respondWithAuthzError(w, err, "Failed to update role")
+		return
+	}
+	respondWithJSON(w, http.StatusOK, map[string]interface{}{"name": roleName, "permissions": req.Permissions})
+}
+
+// DeleteRole handles deleting a role.
+func (ah *AdminHandler) DeleteRole(w http.ResponseWriter, r *http.Request) {
+	if err := ah.Authz.DeleteRole(r.Context(), mux.Vars(r)["role"]); err != nil {
+		ah.respondWithAuthzError(w, err, "Failed to delete role")
+		return
+	}
+	respondWithJSON(w, http.StatusOK, map[string]string{"status": "deleted"})
+}
+
+// AssignUserRole handles assigning a role to a user.
+func (ah *AdminHandler) AssignUserRole(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Role string `json:"role" validate:"required"`
+	}
+	if !ah.decodeAndValidate(w, r, &req) {
+		return
+	}
+
+	userID := mux.Vars(r)["userID"]
+	if err := ah.Authz.AssignRoleToUser(r.Context(), userID, req.Role); err != nil {
+		ah.respondWithAuthzError(w, err, "Failed to assign role")
+		return
+	}
+	respondWithJSON(w, http.StatusOK, map[string]string{"status": "assigned", "userID": userID, "role": req.Role})
+}
+
+// RemoveUserRole handles removing a role from a user.
+func (ah *AdminHandler) RemoveUserRole(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	if err := ah.Authz.RemoveRoleFromUser(r.Context(), vars["userID"], vars["role"]); err != nil {
+		ah.respondWithAuthzError(w, err, "Failed to remove role")
+		return
+	}
+	respondWithJSON(w, http.StatusOK, map[string]string{"status": "removed", "userID": vars["userID"], "role": vars["role"]})
+}
+
+// decodeAndValidate decodes the JSON body into req and validates it, writing a 400 response on failure.
+func (ah *AdminHandler) decodeAndValidate(w http.ResponseWriter, r *http.Request, req interface{}) bool {
+	if err := json.NewDecoder(r.Body).Decode(req); err != nil {
+		ah.Logger.Errorf("Failed to decode admin request: %v", err)
+		respondWithInvalidPayload(w)
+		return false
+	}
+	if err := ah.Validator.Struct(req); err != nil {
+		ah.Logger.Errorf("Validation error: %v", err)
+		respondWithValidationError(w, err)
+		return false
+	}
+	return true
+}
+
+// respondWithAuthzError maps authorization service errors to HTTP responses.
+func (ah *AdminHandler) respondWithAuthzError(w http.ResponseWriter, err error, fallback string) {
+	ah.Logger.Errorf("%s: %v", fallback, err)
+	switch {
+	case errors.Is(err, authorization.ErrRoleNotFound),
+		errors.Is(err, authorization.ErrPermissionNotFound),
+		errors.Is(err, authorization.ErrUserNotFound):
+		problem.Error(w, http.StatusNotFound, problem.CodeNotFound, err.Error())
+	case errors.Is(err, authorization.ErrRoleExists),
+		errors.Is(err, authorization.ErrPermissionExists),
+		errors.Is(err, authorization.ErrAdminRoleProtected),
+		errors.Is(err, authorization.ErrLastAdmin):
+		problem.Error(w, http.StatusConflict, problem.CodeConflict, err.Error())
+	case errors.Is(err, authorization.ErrInvalidUserID):
+		problem.Error(w, http.StatusBadRequest, problem.CodeInvalidRequest, err.Error())
+	default:
+		problem.Error(w, http.StatusInternalServerError, problem.CodeInternal, fallback)
+	}
+}
@@ -0,0 +1,136 @@
+// backend/internal/api/handlers/apikey_handler.go
+
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/AkshayDubey29/MoniFlux/backend/internal/api/models"
+	"github.com/AkshayDubey29/MoniFlux/backend/internal/api/problem"
+	"github.com/AkshayDubey29/MoniFlux/backend/internal/services/authentication"
+	"github.com/AkshayDubey29/MoniFlux/backend/internal/services/authorization"
+	validator "github.com/go-playground/validator/v10"
+	"github.com/gorilla/mux"
+	"github.com/sirupsen/logrus"
+)
+
+// APIKeyHandler serves the API key management endpoints.
+type APIKeyHandler struct {
+	Auth      *authentication.AuthenticationService
+	Authz     *authorization.AuthorizationService
+	Validator *validator.Validate
+	Logger    *logrus.Logger
+}
+
+// NewAPIKeyHandler creates a new APIKeyHandler instance.
+func NewAPIKeyHandler(authService *authentication.AuthenticationService, authzService *authorization.AuthorizationService, logger *logrus.Logger) *APIKeyHandler {
+	return &APIKeyHandler{
+		Auth:      authService,
+		Authz:     authzService,
+		Validator: validator.New(),
+		Logger:    logger,
+	}
+}
+
+// CreateAPIKey handles issuing a new API key for the caller. The key is limited to
+// the requested scopes, each of which the caller must currently hold.
+func (kh *APIKeyHandler) CreateAPIKey(w http.ResponseWriter, r *http.Request) {
+	principal, ok := requirePrincipal(w, r)
+	if !ok {
+		return
+	}
+	// Keys cannot mint further keys; otherwise a leaked key could outlive its revocation.
+	if principal.APIKeyID != "" {
+		problem.Error(w, http.StatusForbidden, problem.CodeForbidden, "API keys cannot be created with an API key")
+		return
+	}
+
+	var req struct {
+		Name      string     `json:"name" validate:"required,min=2,max=64"`
+		Scopes    []string   `json:"scopes" validate:"required,min=1,dive,required"`
+		ExpiresAt *time.Time `json:"expiresAt,omitempty"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		kh.Logger.Errorf("Failed to decode API key request: %v", err)
+		respondWithInvalidPayload(w)
+		return
+	}
+	if err := kh.Validator.Struct(req); err != nil {
+		kh.Logger.Errorf("Validation error: %v", err)
+		respondWithValidationError(w, err)
+		return
+	}
+	if req.ExpiresAt != nil && !req.ExpiresAt.After(time.Now()) {
+		problem.Error(w, http.StatusBadRequest, problem.CodeInvalidRequest, "expiresAt must be in the future")
+		return
+	}
+
+	for _, scope := range req.Scopes {
+		hasPerm, err := kh.Authz.UserHasPermission(r.Context(), principal.UserID, scope)
+		if errors.Is(err, authorization.ErrPermissionNotFound) {
+			problem.Error(w, http.StatusBadRequest, problem.CodeInvalidRequest, "Unknown scope: "+scope)
+			return
+		}
+		if err != nil {
+			kh.Logger.Errorf("Error checking permission %s for user %s: %v", scope, principal.UserID, err)
+			problem.Error(w, http.StatusInternalServerError, problem.CodeInternal, "Failed to create API key")
+			return
+		}
+		if !hasPerm {
+			problem.Error(w, http.StatusForbidden, problem.CodeForbidden, "Forbidden: cannot grant scope "+scope)
+			return
+		}
+	}
+
+	plaintext, key, err := kh.Auth.CreateAPIKey(r.Context(), principal.UserID, req.Name, req.Scopes, req.ExpiresAt)
+	if err != nil {
+		kh.Logger.Errorf("Failed to create API key: %v", err)
+		problem.Error(w, http.StatusInternalServerError, problem.CodeInternal, "Failed to create API key")
+		return
+	}
+
+	// The plaintext key is only ever returned in this response.
+	respondWithJSON(w, http.StatusCreated, struct {
+		Key    string         `json:"key"`
+		APIKey *models.APIKey `json:"apiKey"`
+	}{Key: plaintext, APIKey: key})
+}
+
+// ListAPIKeys handles listing the caller's API keys. Secrets are never included.
+func (kh *APIKeyHandler) ListAPIKeys(w http.ResponseWriter, r *http.Request) {
+	principal, ok := requirePrincipal(w, r)
+	if !ok {
+		return
+	}
+
+	keys, err := kh.Auth.ListAPIKeys(r.Context(), principal.UserID)
+	if err != nil {
+		problem.Error(w, http.StatusInternalServerError, problem.CodeInternal, "Failed to retrieve API keys")
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, keys)
+}
+
+// RevokeAPIKey handles revoking one of the caller's API keys. Admins may revoke any key.
+func (kh *APIKeyHandler) RevokeAPIKey(w http.ResponseWriter, r *http.Request) {
+	principal, ok := requirePrincipal(w, r)
+	if !ok {
+		return
+	}
+
+	keyID := mux.Vars(r)["keyID"]
+	if err := kh.Auth.RevokeAPIKey(r.Context(), principal.UserID, keyID, principal.IsAdmin()); err != nil {
+		if errors.Is(err, authentication.ErrInvalidAPIKey) {
+			problem.Error(w, http.StatusNotFound, problem.CodeNotFound, "API key not found")
+			return
+		}
+		problem.Error(w, http.StatusInternalServerError, problem.CodeInternal, "Failed to revoke API key")
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, map[string]string{"status": "revoked", "keyID": keyID})
+}
@@ -0,0 +1,147 @@
+// backend/internal/api/handlers/audit_handler.go
+
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/AkshayDubey29/MoniFlux/backend/internal/api/models"
+	"github.com/AkshayDubey29/MoniFlux/backend/internal/api/problem"
+	"github.com/AkshayDubey29/MoniFlux/backend/internal/services/audit"
+	validator "github.com/go-playground/validator/v10"
+	"github.com/sirupsen/logrus"
+)
+
+// AuditHandler serves the audit log query endpoint.
+type AuditHandler struct {
+	Audit     *audit.AuditService
+	Validator *validator.Validate
+	Logger    *logrus.Logger
+}
+
+// NewAuditHandler creates a new AuditHandler instance.
+func NewAuditHandler(auditService *audit.AuditService, logger *logrus.Logger) *AuditHandler {
+	return &AuditHandler{
+		Audit:     auditService,
+		Validator: validator.New(),
+		Logger:    logger,
+	}
+}
+
+// ListAuditEvents handles querying the audit log, newest events first.
+// Supported query parameters: userID, action, targetID, requestID, outcome,
+// since and until (RFC3339), limit and cursor.
+func (ah *AuditHandler) ListAuditEvents(w http.ResponseWriter, r *http.Request) {
+	query, err := parseAuditQuery(r)
+	if err != nil {
+		ah.Logger.Errorf("Invalid audit query: %v", err)
+		problem.Error(w, http.StatusBadRequest, problem.CodeInvalidRequest, err.Error())
+		return
+	}
+
+	if err := ah.Validator.Struct(query); err != nil {
+		ah.Logger.Errorf("Validation error: %v", err)
+		respondWithValidationError(w, err)
+		return
+	}
+
+	page, err := ah.Audit.Query(r.Context(), query)
+	if err != nil {
+		if errors.Is(err, models.ErrInvalidCursor) {
+			problem.Error(w, http.StatusBadRequest, problem.CodeInvalidCursor, err.Error())
+			return
+		}
+		problem.Error(w, http.StatusInternalServerError, problem.CodeInternal, "Failed to retrieve audit events")
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, page)
+}
+
+// parseAuditQuery builds an audit query from the URL query parameters.
+func parseAuditQuery(r *http.Request) (*audit.Query, error) {
+	q := r.URL.Query()
+	query := &audit.Query{
+		UserID:    q.Get("userID"),
+		Action:    q.Get("action"),
+		TargetID:  q.Get("targetID"),
+		RequestID: q.Get("requestID"),
+		Outcome:   q.Get("outcome"),
+		Cursor:    q.Get("cursor"),
+	}
+
+	if v := q.Get("limit"); v != "" {
+		limit, err := strconv.Atoi(v)
+		if err != nil {
+			return nil, fmt.Errorf("invalid limit: %s", v)
+		}
+		query.Limit = limit
+	}
+
+	timeParams := map[string]*time.Time{
+		"since": &query.Since,
+		"until": &query.Until,
+	}
+	for name, dst := range timeParams {
+		v := q.Get(name)
+		if v == "" {
+			continue
+		}
+		t, err := time.Parse(time.RFC3339, v)
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s: expected RFC3339 timestamp", name)
+		}
+		*dst = t
+	}
+
+	return query, nil
+}
+
+// snapshotTest returns the current state of a test for the audit log, or nil if the
+// request is not audited or the test does not exist or is not visible to the caller.
+func (h *Handler) snapshotTest(r *http.Request, testID string) *models.Test {
+	if testID == "" || audit.EntryFromContext(r.Context()) == nil {
+		return nil
+	}
+	test, err := h.Controller.GetTestByID(r.Context(), testID)
+	if err != nil {
+		return nil
+	}
+	return test
+}
+
+// auditTestAction names the audited action on a test and records how the test
+// changed since before was taken, along with the destination it sends to.
+func (h *Handler) auditTestAction(r *http.Request, action, testID string, before *models.Test) {
+	entry := audit.EntryFromContext(r.Context())
+	if entry == nil {
+		return
+	}
+	entry.SetAction(action)
+	entry.SetTarget("test", testID)
+
+	after := h.snapshotTest(r, testID)
+	entry.RecordChange(before, after)
+
+	current := after
+	if current == nil {
+		current = before
+	}
+	if current != nil {
+		entry.AddMetadata("destinationType", current.Destination.Type)
+		switch current.Destination.Type {
+		case "http", "syslog", "tcp", "udp":
+			entry.AddMetadata("destination", fmt.Sprintf("%s:%d", current.Destination.Endpoint, current.Destination.Port))
+		case "file":
+			entry.AddMetadata("destination", current.Destination.FilePath)
+		case "s3":
+			if current.Destination.S3 != nil {
+				entry.AddMetadata("destination", "s3://"+current.Destination.S3.Bucket)
+			}
+		}
+	}
+}
@@ -0,0 +1,194 @@
+// backend/internal/api/handlers/destination_handler.go
+
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/AkshayDubey29/MoniFlux/backend/internal/api/models"
+	"github.com/AkshayDubey29/MoniFlux/backend/internal/api/problem"
+	"github.com/AkshayDubey29/MoniFlux/backend/internal/services/audit"
+	"github.com/AkshayDubey29/MoniFlux/backend/internal/services/destinations"
+	"github.com/AkshayDubey29/MoniFlux/backend/internal/services/tenancy"
+	validator "github.com/go-playground/validator/v10"
+	"github.com/gorilla/mux"
+	"github.com/sirupsen/logrus"
+)
+
+// DestinationHandler serves the destination registry endpoints. Team destinations
+// are only visible to, and managed by, members of the team.
+type DestinationHandler struct {
+	Destinations *destinations.DestinationService
+	Tenancy      *tenancy.TenancyService
+	Validator    *validator.Validate
+	Logger       *logrus.Logger
+}
+
+// NewDestinationHandler creates a new DestinationHandler instance.
+func NewDestinationHandler(destinationService *destinations.DestinationService, tenancyService *tenancy.TenancyService, logger *logrus.Logger) *DestinationHandler {
+	return &DestinationHandler{
+		Destinations: destinationService,
+		Tenancy:      tenancyService,
+		Validator:    validator.New(),
+		Logger:       logger,
+	}
+}
+
+// ListDestinations handles listing the registered destinations the caller may use.
+func (dh *DestinationHandler) ListDestinations(w http.ResponseWriter, r *http.Request) {
+	principal, ok := requirePrincipal(w, r)
+	if !ok {
+		return
+	}
+	var teamIDs []string
+	if !principal.IsAdmin() {
+		var err error
+		if teamIDs, err = dh.Tenancy.TeamIDsForUser(r.Context(), principal.UserID); err != nil {
+			dh.respondWithRegistryError(w, err, "Failed to list destinations")
+			return
+		}
+	}
+
+	list, err := dh.Destinations.ListDestinations(r.Context(), teamIDs, principal.IsAdmin())
+	if err != nil {
+		dh.respondWithRegistryError(w, err, "Failed to list destinations")
+		return
+	}
+	respondWithJSON(w, http.StatusOK, list)
+}
+
+// GetDestination handles retrieving the destination in the URL.
+func (dh *DestinationHandler) GetDestination(w http.ResponseWriter, r *http.Request) {
+	principal, ok := requirePrincipal(w, r)
+	if !ok {
+		return
+	}
+	registered, err := dh.Destinations.GetDestination(r.Context(), mux.Vars(r)["name"])
+	if err == nil {
+		err = dh.authorize(r.Context(), principal, registered)
+	}
+	if err != nil {
+		dh.respondWithRegistryError(w, err, "Failed to retrieve destination")
+		return
+	}
+	respondWithJSON(w, http.StatusOK, registered)
+}
+
+// PutDestination handles registering or replacing the destination in the URL. Team
+// destinations can only be registered by members of the team.
+func (dh *DestinationHandler) PutDestination(w http.ResponseWriter, r *http.Request) {
+	principal, ok := requirePrincipal(w, r)
+	if !ok {
+		return
+	}
+	name := mux.Vars(r)["name"]
+
+	var req destinations.DestinationRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		dh.Logger.Errorf("Failed to decode destination request: %v", err)
+		respondWithInvalidPayload(w)
+		return
+	}
+	if err := dh.Validator.Struct(req); err != nil {
+		dh.Logger.Errorf("Validation error: %v", err)
+		respondWithValidationError(w, err)
+		return
+	}
+
+	if req.TeamID != "" && !principal.IsAdmin() {
+		member, err := dh.Tenancy.IsMember(r.Context(), req.TeamID, principal.UserID)
+		if err != nil {
+			dh.respondWithRegistryError(w, err, "Failed to verify team membership")
+			return
+		}
+		if !member {
+			problem.Error(w, http.StatusForbidden, problem.CodeForbidden, "You are not a member of this team")
+			return
+		}
+	}
+
+	// Replacing a team destination also requires membership of its current team.
+	before, err := dh.Destinations.GetDestination(r.Context(), name)
+	switch {
+	case errors.Is(err, destinations.ErrDestinationNotFound):
+	case err != nil:
+		dh.respondWithRegistryError(w, err, "Failed to store destination")
+		return
+	default:
+		if err := dh.authorize(r.Context(), principal, before); err != nil {
+			dh.respondWithRegistryError(w, err, "Failed to store destination")
+			return
+		}
+	}
+
+	registered, err := dh.Destinations.PutDestination(r.Context(), name, &req, principal.UserID)
+	if err != nil {
+		dh.respondWithRegistryError(w, err, "Failed to store destination")
+		return
+	}
+	entry := audit.EntryFromContext(r.Context())
+	entry.SetTarget("destination", name)
+	entry.RecordChange(before, registered)
+
+	respondWithJSON(w, http.StatusOK, registered)
+}
+
+// DeleteDestination handles removing the destination in the URL. Tests already
+// started with it keep their copy.
+func (dh *DestinationHandler) DeleteDestination(w http.ResponseWriter, r *http.Request) {
+	principal, ok := requirePrincipal(w, r)
+	if !ok {
+		return
+	}
+	name := mux.Vars(r)["name"]
+
+	registered, err := dh.Destinations.GetDestination(r.Context(), name)
+	if err == nil {
+		err = dh.authorize(r.Context(), principal, registered)
+	}
+	if err == nil {
+		err = dh.Destinations.DeleteDestination(r.Context(), name)
+	}
+	if err != nil {
+		dh.respondWithRegistryError(w, err, "Failed to delete destination")
+		return
+	}
+	audit.EntryFromContext(r.Context()).SetTarget("destination", name)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// authorize ensures the caller may see the destination. Destinations of other teams
+// are reported as not found so their existence is not leaked.
+func (dh *DestinationHandler) authorize(ctx context.Context, principal *models.Principal, registered *destinations.RegisteredDestination) error {
+	if registered.TeamID == "" || principal.IsAdmin() {
+		return nil
+	}
+	member, err := dh.Tenancy.IsMember(ctx, registered.TeamID, principal.UserID)
+	if err != nil {
+		return err
+	}
+	if !member {
+		return destinations.ErrDestinationNotFound
+	}
+	return nil
+}
+
+// respondWithRegistryError maps destination service errors to HTTP responses.
+func (dh *DestinationHandler) respondWithRegistryError(w http.ResponseWriter, err error, fallback string) {
+	dh.Logger.Errorf("%s: %v", fallback, err)
+	switch {
+	case errors.Is(err, destinations.ErrDestinationNotFound):
+		problem.Error(w, http.StatusNotFound, problem.CodeDestinationNotFound, err.Error())
+	case errors.Is(err, models.ErrTeamNotFound):
+		problem.Error(w, http.StatusNotFound, problem.CodeTeamNotFound, err.Error())
+	case errors.Is(err, destinations.ErrInvalidName), errors.Is(err, destinations.ErrInvalidDestination):
+		problem.Error(w, http.StatusBadRequest, problem.CodeInvalidDestination, err.Error())
+	case errors.Is(err, destinations.ErrReadOnly):
+		problem.Error(w, http.StatusConflict, problem.CodeDestinationReadOnly, err.Error())
+	default:
+		problem.Error(w, http.StatusInternalServerError, problem.CodeInternal, fallback)
+	}
+}
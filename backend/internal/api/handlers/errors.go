@@ -0,0 +1,105 @@
+// backend/internal/api/handlers/errors.go
+
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/AkshayDubey29/MoniFlux/backend/internal/api/models"
+	"github.com/AkshayDubey29/MoniFlux/backend/internal/api/problem"
+	"github.com/AkshayDubey29/MoniFlux/backend/internal/loadgen/delivery"
+	"github.com/AkshayDubey29/MoniFlux/backend/internal/services/destinations"
+	"github.com/AkshayDubey29/MoniFlux/backend/internal/services/guardrails"
+	"github.com/AkshayDubey29/MoniFlux/backend/internal/services/secrets"
+	validator "github.com/go-playground/validator/v10"
+)
+
+// errorProblem is the status and code of the problem reported for an error.
+type errorProblem struct {
+	err    error
+	status int
+	code   string
+}
+
+// testErrorProblems maps the errors of the load test endpoints to problems, in the
+// order they are matched. Secrets and destinations referenced by a test are part of
+// the request, so errors about them are client errors rather than missing resources.
+var testErrorProblems = []errorProblem{
+	{models.ErrTestNotFound, http.StatusNotFound, problem.CodeTestNotFound},
+	{models.ErrTeamNotFound, http.StatusNotFound, problem.CodeTeamNotFound},
+	{models.ErrProjectNotFound, http.StatusNotFound, problem.CodeProjectNotFound},
+	{models.ErrForbidden, http.StatusForbidden, problem.CodeForbidden},
+	{models.ErrTestAlreadyExists, http.StatusConflict, problem.CodeTestAlreadyExists},
+	{models.ErrTestAlreadyRunning, http.StatusConflict, problem.CodeTestAlreadyRunning},
+	{models.ErrTestAlreadyQueued, http.StatusConflict, problem.CodeTestAlreadyQueued},
+	{models.ErrTestAlreadyCompleted, http.StatusConflict, problem.CodeTestAlreadyCompleted},
+	{models.ErrTestAlreadyCancelled, http.StatusConflict, problem.CodeTestAlreadyCancelled},
+	{models.ErrInvalidTestState, http.StatusConflict, problem.CodeInvalidTestState},
+	{models.ErrInvalidTest, http.StatusBadRequest, problem.CodeInvalidTest},
+	{models.ErrDestinationUnsupported, http.StatusBadRequest, problem.CodeDestinationUnsupported},
+	{models.ErrInvalidCursor, http.StatusBadRequest, problem.CodeInvalidCursor},
+	{models.ErrCapacityExceeded, http.StatusUnprocessableEntity, problem.CodeCapacityExceeded},
+	{models.ErrAdmissionQueueFull, http.StatusServiceUnavailable, problem.CodeAdmissionQueueFull},
+	{destinations.ErrDestinationNotFound, http.StatusBadRequest, problem.CodeDestinationNotFound},
+	{destinations.ErrInvalidDestination, http.StatusBadRequest, problem.CodeInvalidDestination},
+	{secrets.ErrSecretNotFound, http.StatusBadRequest, problem.CodeSecretNotFound},
+	{secrets.ErrInvalidRef, http.StatusBadRequest, problem.CodeInvalidSecretRef},
+	{secrets.ErrInvalidName, http.StatusBadRequest, problem.CodeInvalidSecretRef},
+	{secrets.ErrStoreDisabled, http.StatusBadRequest, problem.CodeSecretStoreDisabled},
+	{delivery.ErrInvalidAuth, http.StatusBadRequest, problem.CodeInvalidDestination},
+}
+
+// respondWithError reports an error of the load test endpoints. Guardrail violations,
+// failed preflight probes, validation errors and the errors of testErrorProblems get
+// their own status and code; anything else is an internal error described by fallback.
+func respondWithError(w http.ResponseWriter, err error, fallback string) {
+	var violation *guardrails.ViolationError
+	if errors.As(err, &violation) {
+		p := problem.New(http.StatusForbidden, problem.CodeGuardrailViolation, err.Error())
+		p.Violations = violation.Violations
+		problem.Respond(w, p)
+		return
+	}
+	var preflight *models.PreflightError
+	if errors.As(err, &preflight) {
+		p := problem.New(http.StatusUnprocessableEntity, problem.CodePreflightFailed, err.Error())
+		p.Report = preflight.Report
+		problem.Respond(w, p)
+		return
+	}
+	var invalid validator.ValidationErrors
+	if errors.As(err, &invalid) {
+		respondWithValidationError(w, invalid)
+		return
+	}
+
+	for _, known := range testErrorProblems {
+		if !errors.Is(err, known.err) {
+			continue
+		}
+		detail := err.Error()
+		switch known.err {
+		case models.ErrAdmissionQueueFull:
+			w.Header().Set("Retry-After", "60")
+		case secrets.ErrStoreDisabled:
+			detail = "Inline API keys require the secret store: " + detail
+		}
+		problem.Error(w, known.status, known.code, detail)
+		return
+	}
+	problem.Error(w, http.StatusInternalServerError, problem.CodeInternal, fallback)
+}
+
+// respondWithValidationError reports a request rejected by the validator, with an
+// entry per invalid field.
+func respondWithValidationError(w http.ResponseWriter, err error) {
+	p := problem.New(http.StatusBadRequest, problem.CodeValidationFailed, "The request has invalid fields")
+	p.Errors = extractValidationErrors(err)
+	problem.Respond(w, p)
+}
+
+// respondWithInvalidPayload reports a request body that cannot be decoded.
+func respondWithInvalidPayload(w http.ResponseWriter) {
+	problem.Error(w, http.StatusBadRequest, problem.CodeInvalidRequest, "Invalid request payload")
+}
@@ -0,0 +1,194 @@
+// backend/internal/api/handlers/export_handler.go
+
+package handlers
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/AkshayDubey29/MoniFlux/backend/internal/api/models"
+	"github.com/AkshayDubey29/MoniFlux/backend/internal/api/problem"
+	"github.com/AkshayDubey29/MoniFlux/backend/internal/loadgen/delivery"
+	"github.com/gorilla/mux"
+)
+
+// Export formats of GET /tests/{testID}/results/export.
+const (
+	ExportJSON    = "json"
+	ExportCSV     = "csv"
+	ExportParquet = "parquet"
+)
+
+// exportRowGroupRows is the number of seconds per row group of a Parquet export.
+const exportRowGroupRows = 10000
+
+// timeSeriesColumns are the columns of CSV and Parquet exports, named like the JSON
+// fields of models.RunSecond.
+var timeSeriesColumns = []delivery.ParquetColumn{
+	{Name: "second", Type: delivery.ParquetInt64},
+	{Name: "timestamp", Type: delivery.ParquetTimestamp},
+	{Name: "rate", Type: delivery.ParquetInt64},
+	{Name: "logs", Type: delivery.ParquetInt64},
+	{Name: "metrics", Type: delivery.ParquetInt64},
+	{Name: "traces", Type: delivery.ParquetInt64},
+	{Name: "dropped", Type: delivery.ParquetInt64},
+	{Name: "delivered", Type: delivery.ParquetInt64},
+	{Name: "errors", Type: delivery.ParquetInt64},
+	{Name: "batches", Type: delivery.ParquetInt64},
+	{Name: "latencyAvgMs", Type: delivery.ParquetDouble},
+	{Name: "latencyMaxMs", Type: delivery.ParquetDouble},
+}
+
+// timeSeriesRow returns the values of a second in the order of timeSeriesColumns.
+func timeSeriesRow(s models.RunSecond) []interface{} {
+	return []interface{}{
+		s.Second, s.Timestamp, s.Rate, s.Logs, s.Metrics, s.Traces, s.Dropped,
+		s.Delivered, s.Errors, s.Batches, s.LatencyAvgMs, s.LatencyMaxMs,
+	}
+}
+
+// ExportResults streams the summary and per-second statistics of the latest run of a
+// test as JSON (the default), CSV or Parquet, selected by the format query parameter.
+// CSV exports start with the summary as JSON on a comment line; Parquet exports carry
+// it in the "summary" key of the file metadata.
+func (h *Handler) ExportResults(w http.ResponseWriter, r *http.Request) {
+	testID := mux.Vars(r)["testID"]
+	format := r.URL.Query().Get("format")
+	if format == "" {
+		format = ExportJSON
+	}
+	if format != ExportJSON && format != ExportCSV && format != ExportParquet {
+		problem.Error(w, http.StatusBadRequest, problem.CodeInvalidRequest, "format must be json, csv or parquet")
+		return
+	}
+
+	results, err := h.Controller.GetRunResults(r.Context(), testID)
+	if err != nil {
+		h.Logger.Errorf("Failed to get results of test %s: %v", testID, err)
+		respondWithError(w, err, "Failed to retrieve results")
+		return
+	}
+
+	contentType := "application/json"
+	switch format {
+	case ExportCSV:
+		contentType = "text/csv; charset=utf-8"
+	case ExportParquet:
+		contentType = "application/vnd.apache.parquet"
+	}
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", testID+"-results."+format))
+	w.WriteHeader(http.StatusOK)
+
+	switch format {
+	case ExportCSV:
+		err = writeResultsCSV(w, results)
+	case ExportParquet:
+		err = writeResultsParquet(w, results)
+	default:
+		err = writeResultsJSON(w, results)
+	}
+	if err != nil {
+		// The status has been sent; the client sees a truncated body.
+		h.Logger.Errorf("Failed to export results of test %s: %v", testID, err)
+	}
+}
+
+// writeResultsJSON writes the results as a models.RunResults object, one second at
+// a time.
+func writeResultsJSON(w io.Writer, results *models.RunResults) error {
+	summary, err := json.Marshal(results.Summary)
+	if err != nil {
+		return err
+	}
+	if _, err := fmt.Fprintf(w, `{"summary":%s,"timeSeries":[`, summary); err != nil {
+		return err
+	}
+	for i, second := range results.TimeSeries {
+		data, err := json.Marshal(second)
+		if err != nil {
+			return err
+		}
+		if i > 0 {
+			data = append([]byte{','}, data...)
+		}
+		if _, err := w.Write(data); err != nil {
+			return err
+		}
+	}
+	_, err = io.WriteString(w, "]}\n")
+	return err
+}
+
+// writeResultsCSV writes the summary as a comment line followed by a row per second.
+func writeResultsCSV(w io.Writer, results *models.RunResults) error {
+	summary, err := json.Marshal(results.Summary)
+	if err != nil {
+		return err
+	}
+	if _, err := fmt.Fprintf(w, "# %s\n", summary); err != nil {
+		return err
+	}
+
+	writer := csv.NewWriter(w)
+	header := make([]string, len(timeSeriesColumns))
+	for i, column := range timeSeriesColumns {
+		header[i] = column.Name
+	}
+	if err := writer.Write(header); err != nil {
+		return err
+	}
+	record := make([]string, len(timeSeriesColumns))
+	for _, second := range results.TimeSeries {
+		for i, value := range timeSeriesRow(second) {
+			switch v := value.(type) {
+			case int:
+				record[i] = strconv.Itoa(v)
+			case int64:
+				record[i] = strconv.FormatInt(v, 10)
+			case float64:
+				record[i] = strconv.FormatFloat(v, 'f', -1, 64)
+			case time.Time:
+				record[i] = v.UTC().Format(time.RFC3339Nano)
+			}
+		}
+		if err := writer.Write(record); err != nil {
+			return err
+		}
+	}
+	writer.Flush()
+	return writer.Error()
+}
+
+// writeResultsParquet writes a row per second, with the summary in the metadata.
+func writeResultsParquet(w io.Writer, results *models.RunResults) error {
+	summary, err := json.Marshal(results.Summary)
+	if err != nil {
+		return err
+	}
+	writer, err := delivery.NewParquetWriter(w, timeSeriesColumns, delivery.CompressionNone)
+	if err != nil {
+		return err
+	}
+	writer.SetMetadata("summary", string(summary))
+
+	rows := make([][]interface{}, 0, exportRowGroupRows)
+	for _, second := range results.TimeSeries {
+		rows = append(rows, timeSeriesRow(second))
+		if len(rows) == exportRowGroupRows {
+			if err := writer.WriteRows(rows); err != nil {
+				return err
+			}
+			rows = rows[:0]
+		}
+	}
+	if err := writer.WriteRows(rows); err != nil {
+		return err
+	}
+	return writer.Close()
+}
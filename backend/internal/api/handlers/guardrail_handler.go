@@ -0,0 +1,137 @@
+// backend/internal/api/handlers/guardrail_handler.go
+
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/AkshayDubey29/MoniFlux/backend/internal/api/models"
+	"github.com/AkshayDubey29/MoniFlux/backend/internal/api/problem"
+	"github.com/AkshayDubey29/MoniFlux/backend/internal/services/audit"
+	"github.com/AkshayDubey29/MoniFlux/backend/internal/services/destinations"
+	"github.com/AkshayDubey29/MoniFlux/backend/internal/services/guardrails"
+	"github.com/AkshayDubey29/MoniFlux/backend/internal/services/tenancy"
+	validator "github.com/go-playground/validator/v10"
+	"github.com/gorilla/mux"
+	"github.com/sirupsen/logrus"
+)
+
+// GuardrailHandler serves the guardrail policy administration endpoints.
+type GuardrailHandler struct {
+	Guardrails *guardrails.GuardrailService
+	Tenancy    *tenancy.TenancyService
+	Validator  *validator.Validate
+	Logger     *logrus.Logger
+}
+
+// NewGuardrailHandler creates a new GuardrailHandler instance.
+func NewGuardrailHandler(guardrailService *guardrails.GuardrailService, tenancyService *tenancy.TenancyService, logger *logrus.Logger) *GuardrailHandler {
+	return &GuardrailHandler{
+		Guardrails: guardrailService,
+		Tenancy:    tenancyService,
+		Validator:  validator.New(),
+		Logger:     logger,
+	}
+}
+
+// GetPolicy handles retrieving the global policy, or the policy of the team in the URL.
+func (gh *GuardrailHandler) GetPolicy(w http.ResponseWriter, r *http.Request) {
+	policy, err := gh.Guardrails.GetPolicy(r.Context(), mux.Vars(r)["teamID"])
+	if err != nil {
+		gh.respondWithGuardrailError(w, err, "Failed to retrieve guardrail policy")
+		return
+	}
+	respondWithJSON(w, http.StatusOK, policy)
+}
+
+// SetPolicy handles replacing the global policy, or the policy of the team in the URL.
+func (gh *GuardrailHandler) SetPolicy(w http.ResponseWriter, r *http.Request) {
+	principal, ok := requirePrincipal(w, r)
+	if !ok {
+		return
+	}
+	teamID := mux.Vars(r)["teamID"]
+	if teamID != "" {
+		if _, err := gh.Tenancy.GetTeam(r.Context(), teamID); err != nil {
+			gh.respondWithGuardrailError(w, err, "Failed to retrieve team")
+			return
+		}
+	}
+
+	var policy guardrails.Policy
+	if err := json.NewDecoder(r.Body).Decode(&policy); err != nil {
+		gh.Logger.Errorf("Failed to decode guardrail policy: %v", err)
+		respondWithInvalidPayload(w)
+		return
+	}
+	if err := gh.Validator.Struct(policy); err != nil {
+		gh.Logger.Errorf("Validation error: %v", err)
+		respondWithValidationError(w, err)
+		return
+	}
+
+	before, _ := gh.Guardrails.GetPolicy(r.Context(), teamID)
+	updated, err := gh.Guardrails.SetPolicy(r.Context(), teamID, &policy, principal.UserID)
+	if err != nil {
+		gh.respondWithGuardrailError(w, err, "Failed to update guardrail policy")
+		return
+	}
+	audit.EntryFromContext(r.Context()).RecordChange(before, updated)
+
+	respondWithJSON(w, http.StatusOK, updated)
+}
+
+// DeletePolicy handles removing the policy of the team in the URL, or resetting the
+// global policy to the configured defaults.
+func (gh *GuardrailHandler) DeletePolicy(w http.ResponseWriter, r *http.Request) {
+	if err := gh.Guardrails.DeletePolicy(r.Context(), mux.Vars(r)["teamID"]); err != nil {
+		gh.respondWithGuardrailError(w, err, "Failed to delete guardrail policy")
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// respondWithGuardrailError maps guardrail service errors to HTTP responses.
+func (gh *GuardrailHandler) respondWithGuardrailError(w http.ResponseWriter, err error, fallback string) {
+	gh.Logger.Errorf("%s: %v", fallback, err)
+	switch {
+	case errors.Is(err, guardrails.ErrPolicyNotFound):
+		problem.Error(w, http.StatusNotFound, problem.CodePolicyNotFound, err.Error())
+	case errors.Is(err, models.ErrTeamNotFound):
+		problem.Error(w, http.StatusNotFound, problem.CodeTeamNotFound, err.Error())
+	case errors.Is(err, guardrails.ErrInvalidPolicy):
+		problem.Error(w, http.StatusBadRequest, problem.CodeInvalidPolicy, err.Error())
+	default:
+		problem.Error(w, http.StatusInternalServerError, problem.CodeInternal, fallback)
+	}
+}
+
+// ValidateTest handles a dry run of starting a load test. It responds with whether
+// the configuration would be accepted and, if not, every reason it would be rejected.
+func (h *Handler) ValidateTest(w http.ResponseWriter, r *http.Request) {
+	var test models.Test
+	if err := json.NewDecoder(r.Body).Decode(&test); err != nil {
+		h.Logger.Errorf("Failed to decode test: %v", err)
+		respondWithInvalidPayload(w)
+		return
+	}
+
+	setOwnerFromPrincipal(r, &test)
+	if err := h.Controller.ResolveDestination(r.Context(), &test); err != nil {
+		h.Logger.Errorf("Failed to resolve destination: %v", err)
+		respondWithError(w, err, "Failed to validate test")
+		return
+	}
+	destinations.ApplyDefaults(&test.Destination)
+
+	report, err := h.Controller.ValidateTest(r.Context(), &test)
+	if err != nil {
+		h.Logger.Errorf("Failed to validate test: %v", err)
+		respondWithError(w, err, "Failed to validate test")
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, report)
+}
@@ -7,15 +7,20 @@ import (
 	"errors"
 	"fmt"
 	"net/http"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/AkshayDubey29/MoniFlux/backend/internal/api/models"
+	"github.com/AkshayDubey29/MoniFlux/backend/internal/api/problem"
 	"github.com/AkshayDubey29/MoniFlux/backend/internal/controllers"
+	"github.com/AkshayDubey29/MoniFlux/backend/internal/loadgen/delivery"
+	"github.com/AkshayDubey29/MoniFlux/backend/internal/services/audit"
 	"github.com/AkshayDubey29/MoniFlux/backend/internal/services/authentication"
+	"github.com/AkshayDubey29/MoniFlux/backend/internal/services/destinations"
 	validator "github.com/go-playground/validator/v10"
 	"github.com/gorilla/mux"
 	"github.com/sirupsen/logrus"
-	"go.mongodb.org/mongo-driver/mongo"
 )
 
 // Handler encapsulates the controller, validator, and logger.
@@ -37,51 +42,64 @@ func NewHandler(controller *controllers.LoadGenController, authService *authenti
 }
 
 // StartTest handles the initiation of a new load test.
-// backend/internal/api/handlers/handler.go
-
 func (h *Handler) StartTest(w http.ResponseWriter, r *http.Request) {
 	var test models.Test
 	if err := json.NewDecoder(r.Body).Decode(&test); err != nil {
 		h.Logger.Errorf("Failed to decode test: %v", err)
-		http.Error(w, "Invalid request payload", http.StatusBadRequest)
+		respondWithInvalidPayload(w)
 		return
 	}
 
 	// Log the incoming test details
 	h.Logger.Debugf("Received Test: %+v", test)
 
-	// Assign default values if necessary (if not handled in controller)
-	// Example:
-	if test.Destination.Type == "file" {
-		if test.Destination.FileCount == 0 {
-			test.Destination.FileCount = 10
-		}
-		if test.Destination.FileFreq == 0 {
-			test.Destination.FileFreq = 5
-		}
-	} else if test.Destination.Type == "http" {
-		if test.Destination.Port == 0 {
-			test.Destination.Port = 80
-		}
+	// The authenticated caller owns the test, regardless of the submitted userID.
+	setOwnerFromPrincipal(r, &test)
+
+	// Copy a registered destination into the test, then fill in the settings clients may omit.
+	if err := h.Controller.ResolveDestination(r.Context(), &test); err != nil {
+		h.Logger.Errorf("Failed to resolve destination: %v", err)
+		respondWithError(w, err, "Failed to start test")
+		return
 	}
+	destinations.ApplyDefaults(&test.Destination)
+
+	h.startTest(w, r, &test, http.StatusCreated)
+}
 
+// startTest validates and starts the test, responding with the test and the status
+// given, or 202 Accepted if it is queued for capacity.
+func (h *Handler) startTest(w http.ResponseWriter, r *http.Request, test *models.Test, status int) {
 	// Validate the test struct.
 	if err := h.Validator.Struct(test); err != nil {
 		h.Logger.Errorf("Validation error: %v", err)
-		validationErrors := extractValidationErrors(err)
-		respondWithJSON(w, http.StatusBadRequest, validationErrors)
+		respondWithValidationError(w, err)
 		return
 	}
+	if test.Destination.Type == "s3" {
+		if err := delivery.CheckS3Options(test.Destination.S3); err != nil {
+			problem.Error(w, http.StatusBadRequest, problem.CodeInvalidDestination, err.Error())
+			return
+		}
+	}
+
+	before := h.snapshotTest(r, test.TestID)
+	defer func() { h.auditTestAction(r, "test.start", test.TestID, before) }()
 
 	// Start the test using the controller.
-	if err := h.Controller.StartTest(r.Context(), &test); err != nil {
+	if err := h.Controller.StartTest(r.Context(), test); err != nil {
 		h.Logger.Errorf("Failed to start test: %v", err)
-		http.Error(w, "Failed to start test", http.StatusInternalServerError)
+		respondWithError(w, err, "Failed to start test")
 		return
 	}
 
-	// Respond with the created test.
-	respondWithJSON(w, http.StatusCreated, test)
+	// Tests waiting for capacity are accepted but not started yet.
+	if test.Status == "Queued" {
+		respondWithJSON(w, http.StatusAccepted, test)
+		return
+	}
+
+	respondWithJSON(w, status, test)
 }
 
 // ScheduleTest handles scheduling a load test.
@@ -89,22 +107,28 @@ func (h *Handler) ScheduleTest(w http.ResponseWriter, r *http.Request) {
 	var scheduleReq models.ScheduleRequest
 	if err := json.NewDecoder(r.Body).Decode(&scheduleReq); err != nil {
 		h.Logger.Errorf("Failed to decode schedule request: %v", err)
-		http.Error(w, "Invalid request payload", http.StatusBadRequest)
+		respondWithInvalidPayload(w)
 		return
 	}
+	h.scheduleTest(w, r, &scheduleReq)
+}
 
+// scheduleTest validates and schedules the request.
+func (h *Handler) scheduleTest(w http.ResponseWriter, r *http.Request, scheduleReq *models.ScheduleRequest) {
 	// Validate the schedule request.
 	if err := h.Validator.Struct(scheduleReq); err != nil {
 		h.Logger.Errorf("Validation error: %v", err)
-		validationErrors := extractValidationErrors(err)
-		respondWithJSON(w, http.StatusBadRequest, validationErrors)
+		respondWithValidationError(w, err)
 		return
 	}
 
+	before := h.snapshotTest(r, scheduleReq.TestID)
+	defer h.auditTestAction(r, "test.schedule", scheduleReq.TestID, before)
+
 	// Schedule the test using the controller.
-	if err := h.Controller.ScheduleTest(r.Context(), &scheduleReq); err != nil {
+	if err := h.Controller.ScheduleTest(r.Context(), scheduleReq); err != nil {
 		h.Logger.Errorf("Failed to schedule test: %v", err)
-		http.Error(w, "Failed to schedule test", http.StatusInternalServerError)
+		respondWithError(w, err, "Failed to schedule test")
 		return
 	}
 
@@ -119,27 +143,29 @@ func (h *Handler) CancelTest(w http.ResponseWriter, r *http.Request) {
 	// Decode the request body
 	if err := json.NewDecoder(r.Body).Decode(&cancelReq); err != nil {
 		h.Logger.Errorf("Failed to decode cancel request: %v", err)
-		http.Error(w, "Invalid request payload", http.StatusBadRequest)
+		respondWithInvalidPayload(w)
 		return
 	}
+	h.cancelTest(w, r, &cancelReq)
+}
 
+// cancelTest validates and cancels the request.
+func (h *Handler) cancelTest(w http.ResponseWriter, r *http.Request, cancelReq *models.CancelRequest) {
 	// Validate the cancel request structure
 	if err := h.Validator.Struct(cancelReq); err != nil {
 		h.Logger.Errorf("Validation error: %v", err)
-		validationErrors := extractValidationErrors(err)
-		respondWithJSON(w, http.StatusBadRequest, validationErrors)
+		respondWithValidationError(w, err)
 		return
 	}
 
+	before := h.snapshotTest(r, cancelReq.TestID)
+	defer h.auditTestAction(r, "test.cancel", cancelReq.TestID, before)
+
 	// Attempt to cancel the test
 	err := h.Controller.CancelTest(r.Context(), cancelReq.TestID)
 	if err != nil {
-		if errors.Is(err, models.ErrTestAlreadyCompleted) || errors.Is(err, models.ErrTestAlreadyCancelled) {
-			http.Error(w, err.Error(), http.StatusConflict)
-			return
-		}
 		h.Logger.Errorf("Failed to cancel test: %v", err)
-		http.Error(w, "Failed to cancel test", http.StatusInternalServerError)
+		respondWithError(w, err, "Failed to cancel test")
 		return
 	}
 
@@ -157,27 +183,37 @@ func (h *Handler) RestartTest(w http.ResponseWriter, r *http.Request) {
 	// Decode the request payload
 	if err := json.NewDecoder(r.Body).Decode(&restartReq); err != nil {
 		h.Logger.Errorf("Failed to decode restart request: %v", err)
-		http.Error(w, "Invalid request payload", http.StatusBadRequest)
+		respondWithInvalidPayload(w)
 		return
 	}
 	h.Logger.Infof("Decoded RestartRequest: %+v", restartReq)
+	h.restartTest(w, r, &restartReq)
+}
 
+// restartTest validates and restarts the request.
+func (h *Handler) restartTest(w http.ResponseWriter, r *http.Request, restartReq *models.RestartRequest) {
 	// Validate the request
 	if err := h.Validator.Struct(restartReq); err != nil {
 		h.Logger.Errorf("Validation error: %v", err)
-		validationErrors := extractValidationErrors(err)
-		respondWithJSON(w, http.StatusBadRequest, validationErrors)
+		respondWithValidationError(w, err)
 		return
 	}
 
+	before := h.snapshotTest(r, restartReq.TestID)
+	defer h.auditTestAction(r, "test.restart", restartReq.TestID, before)
+
 	// Attempt to restart the test
-	err := h.Controller.RestartTest(r.Context(), &restartReq)
+	err := h.Controller.RestartTest(r.Context(), restartReq)
 	if err != nil {
 		h.Logger.Errorf("Failed to restart test: %v", err)
-		respondWithJSON(w, http.StatusInternalServerError, map[string]string{
-			"status": "restart failed",
-			"error":  err.Error(),
-		})
+		respondWithError(w, err, "Failed to restart test")
+		return
+	}
+
+	// Tests waiting for capacity are accepted but not started yet.
+	if test, err := h.Controller.GetTestByID(r.Context(), restartReq.TestID); err == nil && test.Status == "Queued" {
+		h.Logger.Infof("Test %s queued for restart at position %d", restartReq.TestID, test.QueuePosition)
+		respondWithJSON(w, http.StatusAccepted, map[string]interface{}{"status": "queued", "queuePosition": test.QueuePosition})
 		return
 	}
 
@@ -192,22 +228,28 @@ func (h *Handler) SaveResults(w http.ResponseWriter, r *http.Request) {
 	var results models.TestResults
 	if err := json.NewDecoder(r.Body).Decode(&results); err != nil {
 		h.Logger.Errorf("Failed to decode test results: %v", err)
-		http.Error(w, "Invalid request payload", http.StatusBadRequest)
+		respondWithInvalidPayload(w)
 		return
 	}
+	h.saveResults(w, r, &results)
+}
 
+// saveResults validates and saves the results.
+func (h *Handler) saveResults(w http.ResponseWriter, r *http.Request, results *models.TestResults) {
 	// Validate the test results.
 	if err := h.Validator.Struct(results); err != nil {
 		h.Logger.Errorf("Validation error: %v", err)
-		validationErrors := extractValidationErrors(err)
-		respondWithJSON(w, http.StatusBadRequest, validationErrors)
+		respondWithValidationError(w, err)
 		return
 	}
 
+	before := h.snapshotTest(r, results.TestID)
+	defer h.auditTestAction(r, "test.save_results", results.TestID, before)
+
 	// Save the results using the controller.
-	if err := h.Controller.SaveResults(r.Context(), &results); err != nil {
+	if err := h.Controller.SaveResults(r.Context(), results); err != nil {
 		h.Logger.Errorf("Failed to save test results: %v", err)
-		http.Error(w, "Failed to save test results", http.StatusInternalServerError)
+		respondWithError(w, err, "Failed to save test results")
 		return
 	}
 
@@ -215,17 +257,40 @@ func (h *Handler) SaveResults(w http.ResponseWriter, r *http.Request) {
 	respondWithJSON(w, http.StatusOK, results)
 }
 
-// GetAllTests handles retrieving all active and scheduled tests.
+// GetAdmissionStatus handles retrieving the load generator capacity limits, the
+// resources used by running tests and the number of tests waiting for capacity.
+func (h *Handler) GetAdmissionStatus(w http.ResponseWriter, r *http.Request) {
+	respondWithJSON(w, http.StatusOK, h.Controller.AdmissionStatus())
+}
+
+// GetAllTests handles retrieving a filtered, sorted and paginated list of tests.
+// Supported query parameters: status (comma-separated), userID, destinationType, tags
+// (comma-separated, all must match), createdAfter, createdBefore, updatedAfter,
+// updatedBefore (RFC3339), sortBy, sortOrder, limit and cursor.
 func (h *Handler) GetAllTests(w http.ResponseWriter, r *http.Request) {
-	tests, err := h.Controller.GetAllTests(r.Context())
+	query, err := parseTestListQuery(r)
+	if err != nil {
+		h.Logger.Errorf("Invalid test listing query: %v", err)
+		problem.Error(w, http.StatusBadRequest, problem.CodeInvalidRequest, err.Error())
+		return
+	}
+
+	// Validate the listing query.
+	if err := h.Validator.Struct(query); err != nil {
+		h.Logger.Errorf("Validation error: %v", err)
+		respondWithValidationError(w, err)
+		return
+	}
+
+	page, err := h.Controller.GetAllTests(r.Context(), query)
 	if err != nil {
 		h.Logger.Errorf("Failed to get all tests: %v", err)
-		http.Error(w, "Failed to retrieve tests", http.StatusInternalServerError)
+		respondWithError(w, err, "Failed to retrieve tests")
 		return
 	}
 
-	// Respond with the list of tests.
-	respondWithJSON(w, http.StatusOK, tests)
+	// Respond with the page of tests.
+	respondWithJSON(w, http.StatusOK, page)
 }
 
 // GetTestByID handles retrieving a specific test by its TestID.
@@ -234,18 +299,14 @@ func (h *Handler) GetTestByID(w http.ResponseWriter, r *http.Request) {
 	testID, exists := vars["testID"]
 	if !exists {
 		h.Logger.Errorf("TestID not provided in URL")
-		http.Error(w, "TestID is required", http.StatusBadRequest)
+		problem.Error(w, http.StatusBadRequest, problem.CodeInvalidRequest, "TestID is required")
 		return
 	}
 
 	test, err := h.Controller.GetTestByID(r.Context(), testID)
 	if err != nil {
-		if errors.Is(err, mongo.ErrNoDocuments) {
-			http.Error(w, "Test not found", http.StatusNotFound)
-			return
-		}
 		h.Logger.Errorf("Failed to get test by ID: %v", err)
-		http.Error(w, "Failed to retrieve test", http.StatusInternalServerError)
+		respondWithError(w, err, "Failed to retrieve test")
 		return
 	}
 
@@ -263,22 +324,30 @@ func (h *Handler) RegisterUser(w http.ResponseWriter, r *http.Request) {
 
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
 		h.Logger.Errorf("Failed to decode registration request: %v", err)
-		http.Error(w, "Invalid request payload", http.StatusBadRequest)
+		respondWithInvalidPayload(w)
 		return
 	}
 
 	// Validate the registration request.
 	if err := h.Validator.Struct(req); err != nil {
 		h.Logger.Errorf("Validation error: %v", err)
-		validationErrors := extractValidationErrors(err)
-		respondWithJSON(w, http.StatusBadRequest, validationErrors)
+		respondWithValidationError(w, err)
 		return
 	}
 
+	audit.EntryFromContext(r.Context()).SetActorName(req.Username)
+
 	// Register the user using the authentication service.
 	if err := h.AuthService.RegisterUser(req.Username, req.Email, req.Password); err != nil {
 		h.Logger.Errorf("Failed to register user: %v", err)
-		http.Error(w, "Failed to register user", http.StatusInternalServerError)
+		switch {
+		case errors.Is(err, authentication.ErrUserExists):
+			problem.Error(w, http.StatusConflict, problem.CodeConflict, err.Error())
+		case errors.Is(err, authentication.ErrWeakPassword):
+			problem.Error(w, http.StatusBadRequest, problem.CodeWeakPassword, err.Error())
+		default:
+			problem.Error(w, http.StatusInternalServerError, problem.CodeInternal, "Failed to register user")
+		}
 		return
 	}
 
@@ -295,28 +364,88 @@ func (h *Handler) AuthenticateUser(w http.ResponseWriter, r *http.Request) {
 
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
 		h.Logger.Errorf("Failed to decode authentication request: %v", err)
-		http.Error(w, "Invalid request payload", http.StatusBadRequest)
+		respondWithInvalidPayload(w)
 		return
 	}
 
 	// Validate the authentication request.
 	if err := h.Validator.Struct(req); err != nil {
 		h.Logger.Errorf("Validation error: %v", err)
-		validationErrors := extractValidationErrors(err)
-		respondWithJSON(w, http.StatusBadRequest, validationErrors)
+		respondWithValidationError(w, err)
 		return
 	}
 
+	audit.EntryFromContext(r.Context()).SetActorName(req.Username)
+
 	// Authenticate the user using the authentication service.
-	token, err := h.AuthService.AuthenticateUser(req.Username, req.Password)
+	tokens, err := h.AuthService.AuthenticateUser(req.Username, req.Password)
 	if err != nil {
 		h.Logger.Errorf("Failed to authenticate user: %v", err)
-		http.Error(w, "Failed to authenticate user", http.StatusUnauthorized)
+		switch {
+		case errors.Is(err, authentication.ErrAccountLocked):
+			problem.Error(w, http.StatusLocked, problem.CodeAccountLocked, err.Error())
+		case errors.Is(err, authentication.ErrAccountDisabled):
+			problem.Error(w, http.StatusForbidden, problem.CodeAccountDisabled, err.Error())
+		default:
+			problem.Error(w, http.StatusUnauthorized, problem.CodeUnauthorized, "Failed to authenticate user")
+		}
+		return
+	}
+
+	// Respond with the access and refresh tokens.
+	respondWithJSON(w, http.StatusOK, tokens)
+}
+
+// RefreshToken handles exchanging a refresh token for a new access and refresh token pair.
+func (h *Handler) RefreshToken(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		RefreshToken string `json:"refreshToken" validate:"required"`
+	}
+
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.Logger.Errorf("Failed to decode refresh request: %v", err)
+		respondWithInvalidPayload(w)
+		return
+	}
+
+	if err := h.Validator.Struct(req); err != nil {
+		h.Logger.Errorf("Validation error: %v", err)
+		respondWithValidationError(w, err)
+		return
+	}
+
+	tokens, err := h.AuthService.RefreshSession(r.Context(), req.RefreshToken)
+	if err != nil {
+		h.Logger.Errorf("Failed to refresh token: %v", err)
+		if errors.Is(err, authentication.ErrInvalidRefreshToken) || errors.Is(err, authentication.ErrRefreshTokenReused) {
+			problem.Error(w, http.StatusUnauthorized, problem.CodeUnauthorized, err.Error())
+			return
+		}
+		problem.Error(w, http.StatusInternalServerError, problem.CodeInternal, "Failed to refresh token")
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, tokens)
+}
+
+// Logout handles revoking the caller's access token and ending its session.
+func (h *Handler) Logout(w http.ResponseWriter, r *http.Request) {
+	principal, ok := requirePrincipal(w, r)
+	if !ok {
+		return
+	}
+	if principal.APIKeyID != "" {
+		problem.Error(w, http.StatusBadRequest, problem.CodeInvalidRequest, "API keys are revoked with DELETE /api-keys/{keyID}")
+		return
+	}
+
+	if err := h.AuthService.Logout(r.Context(), principal); err != nil {
+		h.Logger.Errorf("Failed to log out user %s: %v", principal.UserID, err)
+		problem.Error(w, http.StatusInternalServerError, problem.CodeInternal, "Failed to log out")
 		return
 	}
 
-	// Respond with the JWT token.
-	respondWithJSON(w, http.StatusOK, map[string]string{"token": token})
+	respondWithJSON(w, http.StatusOK, map[string]string{"status": "logged out"})
 }
 
 // CreateTest handles the creation of a new load test.
@@ -327,25 +456,31 @@ func (h *Handler) CreateTest(w http.ResponseWriter, r *http.Request) {
 	var test models.Test
 	if err := json.NewDecoder(r.Body).Decode(&test); err != nil {
 		h.Logger.Errorf("Failed to decode create-test request: %v", err)
-		http.Error(w, "Invalid request payload", http.StatusBadRequest)
+		respondWithInvalidPayload(w)
 		return
 	}
 	h.Logger.Debugf("Decoded Test object: %+v", test)
 
+	// The authenticated caller owns the test, regardless of the submitted userID.
+	setOwnerFromPrincipal(r, &test)
+
 	// Validate the test struct
 	if err := h.Validator.Struct(test); err != nil {
 		h.Logger.Errorf("Validation error in create-test: %v", err)
-		validationErrors := extractValidationErrors(err)
-		respondWithJSON(w, http.StatusBadRequest, validationErrors)
+		respondWithValidationError(w, err)
 		return
 	}
 	h.Logger.Debug("Test object passed validation")
 
+	// The test ID may be generated by the controller, so it is read once it returns.
+	before := h.snapshotTest(r, test.TestID)
+	defer func() { h.auditTestAction(r, "test.create", test.TestID, before) }()
+
 	// Call the controller to create the test
 	h.Logger.Debug("Calling Controller.CreateTest")
 	if err := h.Controller.CreateTest(r.Context(), &test); err != nil {
 		h.Logger.Errorf("Failed to create test: %v", err)
-		http.Error(w, "Failed to create test", http.StatusInternalServerError)
+		respondWithError(w, err, "Failed to create test")
 		return
 	}
 	h.Logger.Debugf("Test created successfully: %+v", test)
@@ -354,16 +489,79 @@ func (h *Handler) CreateTest(w http.ResponseWriter, r *http.Request) {
 	respondWithJSON(w, http.StatusCreated, test)
 }
 
-// HealthCheck handles the /health endpoint.
-func HealthCheck(w http.ResponseWriter, r *http.Request) {
-	w.WriteHeader(http.StatusOK)
-	w.Write([]byte("OK"))
+// setOwnerFromPrincipal assigns the authenticated caller as the owner of the test.
+func setOwnerFromPrincipal(r *http.Request, test *models.Test) {
+	if principal, ok := models.PrincipalFromContext(r.Context()); ok {
+		test.UserID = principal.UserID
+	}
+}
+
+// parseTestListQuery builds a TestListQuery from the request's URL query parameters.
+func parseTestListQuery(r *http.Request) (*models.TestListQuery, error) {
+	q := r.URL.Query()
+	query := &models.TestListQuery{
+		Status:          splitQueryList(q.Get("status")),
+		UserID:          q.Get("userID"),
+		TeamID:          q.Get("teamID"),
+		ProjectID:       q.Get("projectID"),
+		DestinationType: q.Get("destinationType"),
+		Tags:            splitQueryList(q.Get("tags")),
+		SortBy:          q.Get("sortBy"),
+		SortOrder:       strings.ToLower(q.Get("sortOrder")),
+		Cursor:          q.Get("cursor"),
+	}
+
+	if v := q.Get("limit"); v != "" {
+		limit, err := strconv.Atoi(v)
+		if err != nil {
+			return nil, fmt.Errorf("invalid limit: %s", v)
+		}
+		query.Limit = limit
+	}
+
+	timeParams := map[string]*time.Time{
+		"createdAfter":  &query.CreatedAfter,
+		"createdBefore": &query.CreatedBefore,
+		"updatedAfter":  &query.UpdatedAfter,
+		"updatedBefore": &query.UpdatedBefore,
+	}
+	for name, dst := range timeParams {
+		v := q.Get(name)
+		if v == "" {
+			continue
+		}
+		t, err := time.Parse(time.RFC3339, v)
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s: expected RFC3339 timestamp", name)
+		}
+		*dst = t
+	}
+
+	return query, nil
+}
+
+// splitQueryList splits a comma-separated query parameter, dropping empty items.
+func splitQueryList(v string) []string {
+	if v == "" {
+		return nil
+	}
+	var items []string
+	for _, item := range strings.Split(v, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
 }
 
 // Helper function to extract validation errors.
 func extractValidationErrors(err error) []models.ValidationError {
 	var validationErrors []models.ValidationError
-	for _, err := range err.(validator.ValidationErrors) {
+	var fieldErrors validator.ValidationErrors
+	if !errors.As(err, &fieldErrors) {
+		return validationErrors
+	}
+	for _, err := range fieldErrors {
 		validationErrors = append(validationErrors, models.ValidationError{
 			Field:   err.Field(),
 			Message: getValidationMessage(err),
@@ -395,8 +593,7 @@ func respondWithJSON(w http.ResponseWriter, statusCode int, payload interface{})
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(statusCode)
 	if err := json.NewEncoder(w).Encode(payload); err != nil {
-		// If encoding fails, log the error and send a generic error response.
+		// The status is already written, so encoding errors can only be logged.
 		logrus.Errorf("Failed to encode response: %v", err)
-		http.Error(w, "Failed to encode response", http.StatusInternalServerError)
 	}
 }
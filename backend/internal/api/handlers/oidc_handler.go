@@ -0,0 +1,56 @@
+// backend/internal/api/handlers/oidc_handler.go
+
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/AkshayDubey29/MoniFlux/backend/internal/api/problem"
+	"github.com/AkshayDubey29/MoniFlux/backend/internal/services/authentication"
+)
+
+// OIDCLogin handles starting a single sign-on login by redirecting to the IdP.
+func (h *Handler) OIDCLogin(w http.ResponseWriter, r *http.Request) {
+	authURL, err := h.AuthService.BeginOIDCLogin(r.Context())
+	if err != nil {
+		h.Logger.Errorf("Failed to start single sign-on: %v", err)
+		if errors.Is(err, authentication.ErrOIDCDisabled) {
+			problem.Error(w, http.StatusNotFound, problem.CodeNotFound, err.Error())
+			return
+		}
+		problem.Error(w, http.StatusInternalServerError, problem.CodeInternal, "Failed to start single sign-on")
+		return
+	}
+
+	http.Redirect(w, r, authURL, http.StatusFound)
+}
+
+// OIDCCallback handles the IdP redirect after login and responds with MoniFlux tokens.
+func (h *Handler) OIDCCallback(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	if idpErr := query.Get("error"); idpErr != "" {
+		h.Logger.Errorf("Single sign-on rejected by IdP: %s %s", idpErr, query.Get("error_description"))
+		problem.Error(w, http.StatusUnauthorized, problem.CodeUnauthorized, "Single sign-on failed: "+idpErr)
+		return
+	}
+
+	state, code := query.Get("state"), query.Get("code")
+	if state == "" || code == "" {
+		problem.Error(w, http.StatusBadRequest, problem.CodeInvalidRequest, "Missing state or code")
+		return
+	}
+
+	tokens, err := h.AuthService.CompleteOIDCLogin(r.Context(), state, code)
+	if err != nil {
+		h.Logger.Errorf("Failed to complete single sign-on: %v", err)
+		if errors.Is(err, authentication.ErrOIDCDisabled) {
+			problem.Error(w, http.StatusNotFound, problem.CodeNotFound, err.Error())
+			return
+		}
+		problem.Error(w, http.StatusUnauthorized, problem.CodeUnauthorized, "Single sign-on failed")
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, tokens)
+}
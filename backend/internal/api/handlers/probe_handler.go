@@ -0,0 +1,56 @@
+// backend/internal/api/handlers/probe_handler.go
+
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/AkshayDubey29/MoniFlux/backend/internal/api/models"
+	"github.com/AkshayDubey29/MoniFlux/backend/internal/api/problem"
+	"github.com/AkshayDubey29/MoniFlux/backend/internal/common"
+	"github.com/AkshayDubey29/MoniFlux/backend/internal/loadgen/delivery"
+	"github.com/AkshayDubey29/MoniFlux/backend/internal/services/destinations"
+)
+
+// ProbeDestination handles checking a destination before a test uses it. The body is
+// a destination; the optional 'teamID' and 'projectID' query parameters select the
+// team whose guardrails and secrets apply. The report is returned with 200 OK whether
+// or not the destination passed, so that clients read its Success field.
+func (h *Handler) ProbeDestination(w http.ResponseWriter, r *http.Request) {
+	var destination common.Destination
+	if err := json.NewDecoder(r.Body).Decode(&destination); err != nil {
+		h.Logger.Errorf("Failed to decode destination: %v", err)
+		respondWithInvalidPayload(w)
+		return
+	}
+
+	test := models.Test{
+		TeamID:      r.URL.Query().Get("teamID"),
+		ProjectID:   r.URL.Query().Get("projectID"),
+		Destination: destination,
+	}
+	setOwnerFromPrincipal(r, &test)
+	destinations.ApplyDefaults(&test.Destination)
+
+	if err := h.Validator.Struct(test.Destination); err != nil {
+		h.Logger.Errorf("Validation error: %v", err)
+		respondWithValidationError(w, err)
+		return
+	}
+	if test.Destination.Type == "s3" {
+		if err := delivery.CheckS3Options(test.Destination.S3); err != nil {
+			problem.Error(w, http.StatusBadRequest, problem.CodeInvalidDestination, err.Error())
+			return
+		}
+	}
+
+	report, err := h.Controller.ProbeDestination(r.Context(), &test)
+	if err != nil {
+		h.Logger.Errorf("Failed to probe destination: %v", err)
+		respondWithError(w, err, "Failed to probe destination")
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, report)
+}
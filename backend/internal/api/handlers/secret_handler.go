@@ -0,0 +1,131 @@
+// backend/internal/api/handlers/secret_handler.go
+
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/AkshayDubey29/MoniFlux/backend/internal/api/models"
+	"github.com/AkshayDubey29/MoniFlux/backend/internal/api/problem"
+	"github.com/AkshayDubey29/MoniFlux/backend/internal/services/audit"
+	"github.com/AkshayDubey29/MoniFlux/backend/internal/services/secrets"
+	"github.com/AkshayDubey29/MoniFlux/backend/internal/services/tenancy"
+	validator "github.com/go-playground/validator/v10"
+	"github.com/gorilla/mux"
+	"github.com/sirupsen/logrus"
+)
+
+// SecretHandler serves the secret store endpoints. Secret values are write-only:
+// responses only ever contain their metadata.
+type SecretHandler struct {
+	Secrets   *secrets.SecretService
+	Tenancy   *tenancy.TenancyService
+	Validator *validator.Validate
+	Logger    *logrus.Logger
+}
+
+// NewSecretHandler creates a new SecretHandler instance.
+func NewSecretHandler(secretService *secrets.SecretService, tenancyService *tenancy.TenancyService, logger *logrus.Logger) *SecretHandler {
+	return &SecretHandler{
+		Secrets:   secretService,
+		Tenancy:   tenancyService,
+		Validator: validator.New(),
+		Logger:    logger,
+	}
+}
+
+// ListSecrets handles listing the metadata of the stored secrets.
+func (sh *SecretHandler) ListSecrets(w http.ResponseWriter, r *http.Request) {
+	list, err := sh.Secrets.ListSecrets(r.Context())
+	if err != nil {
+		sh.respondWithSecretStoreError(w, err, "Failed to list secrets")
+		return
+	}
+	respondWithJSON(w, http.StatusOK, list)
+}
+
+// GetSecret handles retrieving the metadata of the secret in the URL.
+func (sh *SecretHandler) GetSecret(w http.ResponseWriter, r *http.Request) {
+	secret, err := sh.Secrets.GetSecret(r.Context(), mux.Vars(r)["name"])
+	if err != nil {
+		sh.respondWithSecretStoreError(w, err, "Failed to retrieve secret")
+		return
+	}
+	respondWithJSON(w, http.StatusOK, secret)
+}
+
+// PutSecret handles creating or replacing the secret in the URL. Team secrets can
+// only be stored by members of the team.
+func (sh *SecretHandler) PutSecret(w http.ResponseWriter, r *http.Request) {
+	principal, ok := requirePrincipal(w, r)
+	if !ok {
+		return
+	}
+	name := mux.Vars(r)["name"]
+
+	var req secrets.SecretRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		sh.Logger.Errorf("Failed to decode secret request: %v", err)
+		respondWithInvalidPayload(w)
+		return
+	}
+	if err := sh.Validator.Struct(req); err != nil {
+		sh.Logger.Errorf("Validation error: %v", err)
+		respondWithValidationError(w, err)
+		return
+	}
+
+	if req.TeamID != "" && !principal.IsAdmin() {
+		member, err := sh.Tenancy.IsMember(r.Context(), req.TeamID, principal.UserID)
+		if err != nil {
+			sh.respondWithSecretStoreError(w, err, "Failed to verify team membership")
+			return
+		}
+		if !member {
+			problem.Error(w, http.StatusForbidden, problem.CodeForbidden, "You are not a member of this team")
+			return
+		}
+	}
+
+	before, _ := sh.Secrets.GetSecret(r.Context(), name)
+	secret, err := sh.Secrets.PutSecret(r.Context(), name, &req, principal.UserID)
+	if err != nil {
+		sh.respondWithSecretStoreError(w, err, "Failed to store secret")
+		return
+	}
+	entry := audit.EntryFromContext(r.Context())
+	entry.SetTarget("secret", name)
+	entry.RecordChange(before, secret)
+
+	respondWithJSON(w, http.StatusOK, secret)
+}
+
+// DeleteSecret handles removing the secret in the URL.
+func (sh *SecretHandler) DeleteSecret(w http.ResponseWriter, r *http.Request) {
+	name := mux.Vars(r)["name"]
+	if err := sh.Secrets.DeleteSecret(r.Context(), name); err != nil {
+		sh.respondWithSecretStoreError(w, err, "Failed to delete secret")
+		return
+	}
+	audit.EntryFromContext(r.Context()).SetTarget("secret", name)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// respondWithSecretStoreError maps secret service errors to HTTP responses.
+func (sh *SecretHandler) respondWithSecretStoreError(w http.ResponseWriter, err error, fallback string) {
+	sh.Logger.Errorf("%s: %v", fallback, err)
+	switch {
+	case errors.Is(err, secrets.ErrSecretNotFound):
+		problem.Error(w, http.StatusNotFound, problem.CodeSecretNotFound, err.Error())
+	case errors.Is(err, models.ErrTeamNotFound):
+		problem.Error(w, http.StatusNotFound, problem.CodeTeamNotFound, err.Error())
+	case errors.Is(err, secrets.ErrInvalidName):
+		problem.Error(w, http.StatusBadRequest, problem.CodeInvalidSecretRef, err.Error())
+	case errors.Is(err, secrets.ErrStoreDisabled):
+		problem.Error(w, http.StatusNotImplemented, problem.CodeSecretStoreDisabled, err.Error())
+	default:
+		problem.Error(w, http.StatusInternalServerError, problem.CodeInternal, fallback)
+	}
+}
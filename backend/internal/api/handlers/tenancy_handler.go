@@ -0,0 +1,188 @@
+// backend/internal/api/handlers/tenancy_handler.go
+
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/AkshayDubey29/MoniFlux/backend/internal/api/models"
+	"github.com/AkshayDubey29/MoniFlux/backend/internal/api/problem"
+	"github.com/AkshayDubey29/MoniFlux/backend/internal/services/tenancy"
+	validator "github.com/go-playground/validator/v10"
+	"github.com/gorilla/mux"
+	"github.com/sirupsen/logrus"
+)
+
+// TenancyHandler serves the team and project management endpoints.
+type TenancyHandler struct {
+	Tenancy   *tenancy.TenancyService
+	Validator *validator.Validate
+	Logger    *logrus.Logger
+}
+
+// NewTenancyHandler creates a new TenancyHandler instance.
+func NewTenancyHandler(tenancyService *tenancy.TenancyService, logger *logrus.Logger) *TenancyHandler {
+	return &TenancyHandler{
+		Tenancy:   tenancyService,
+		Validator: validator.New(),
+		Logger:    logger,
+	}
+}
+
+// requirePrincipal returns the authenticated principal or writes a 401 response.
+func requirePrincipal(w http.ResponseWriter, r *http.Request) (*models.Principal, bool) {
+	principal, ok := models.PrincipalFromContext(r.Context())
+	if !ok {
+		problem.Error(w, http.StatusUnauthorized, problem.CodeUnauthorized, "Unauthorized")
+	}
+	return principal, ok
+}
+
+// CreateTeam handles creating a new team owned by the caller.
+func (th *TenancyHandler) CreateTeam(w http.ResponseWriter, r *http.Request) {
+	principal, ok := requirePrincipal(w, r)
+	if !ok {
+		return
+	}
+
+	var req struct {
+		Name string `json:"name" validate:"required,min=2,max=64"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		th.Logger.Errorf("Failed to decode team request: %v", err)
+		respondWithInvalidPayload(w)
+		return
+	}
+	if err := th.Validator.Struct(req); err != nil {
+		th.Logger.Errorf("Validation error: %v", err)
+		respondWithValidationError(w, err)
+		return
+	}
+
+	team, err := th.Tenancy.CreateTeam(r.Context(), principal, req.Name)
+	if err != nil {
+		problem.Error(w, http.StatusInternalServerError, problem.CodeInternal, "Failed to create team")
+		return
+	}
+
+	respondWithJSON(w, http.StatusCreated, team)
+}
+
+// ListTeams handles listing the teams visible to the caller.
+func (th *TenancyHandler) ListTeams(w http.ResponseWriter, r *http.Request) {
+	principal, ok := requirePrincipal(w, r)
+	if !ok {
+		return
+	}
+
+	teams, err := th.Tenancy.ListTeams(r.Context(), principal)
+	if err != nil {
+		problem.Error(w, http.StatusInternalServerError, problem.CodeInternal, "Failed to retrieve teams")
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, teams)
+}
+
+// AddTeamMember handles adding a user to a team or changing their role.
+func (th *TenancyHandler) AddTeamMember(w http.ResponseWriter, r *http.Request) {
+	principal, ok := requirePrincipal(w, r)
+	if !ok {
+		return
+	}
+
+	var member tenancy.TeamMember
+	if err := json.NewDecoder(r.Body).Decode(&member); err != nil {
+		th.Logger.Errorf("Failed to decode team member request: %v", err)
+		respondWithInvalidPayload(w)
+		return
+	}
+	if member.Role == "" {
+		member.Role = tenancy.TeamRoleMember
+	}
+	if err := th.Validator.Struct(member); err != nil {
+		th.Logger.Errorf("Validation error: %v", err)
+		respondWithValidationError(w, err)
+		return
+	}
+
+	teamID := mux.Vars(r)["teamID"]
+	if err := th.Tenancy.AddMember(r.Context(), principal, teamID, member); err != nil {
+		th.respondWithTenancyError(w, err, "Failed to add team member")
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, member)
+}
+
+// RemoveTeamMember handles removing a user from a team.
+func (th *TenancyHandler) RemoveTeamMember(w http.ResponseWriter, r *http.Request) {
+	principal, ok := requirePrincipal(w, r)
+	if !ok {
+		return
+	}
+
+	vars := mux.Vars(r)
+	if err := th.Tenancy.RemoveMember(r.Context(), principal, vars["teamID"], vars["userID"]); err != nil {
+		th.respondWithTenancyError(w, err, "Failed to remove team member")
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, map[string]string{"status": "removed"})
+}
+
+// CreateProject handles creating a project within a team.
+func (th *TenancyHandler) CreateProject(w http.ResponseWriter, r *http.Request) {
+	principal, ok := requirePrincipal(w, r)
+	if !ok {
+		return
+	}
+
+	var project tenancy.Project
+	if err := json.NewDecoder(r.Body).Decode(&project); err != nil {
+		th.Logger.Errorf("Failed to decode project request: %v", err)
+		respondWithInvalidPayload(w)
+		return
+	}
+	if err := th.Validator.Struct(project); err != nil {
+		th.Logger.Errorf("Validation error: %v", err)
+		respondWithValidationError(w, err)
+		return
+	}
+
+	teamID := mux.Vars(r)["teamID"]
+	if err := th.Tenancy.CreateProject(r.Context(), principal, teamID, &project); err != nil {
+		th.respondWithTenancyError(w, err, "Failed to create project")
+		return
+	}
+
+	respondWithJSON(w, http.StatusCreated, project)
+}
+
+// ListProjects handles listing the projects of a team.
+func (th *TenancyHandler) ListProjects(w http.ResponseWriter, r *http.Request) {
+	principal, ok := requirePrincipal(w, r)
+	if !ok {
+		return
+	}
+
+	projects, err := th.Tenancy.ListProjects(r.Context(), principal, mux.Vars(r)["teamID"])
+	if err != nil {
+		th.respondWithTenancyError(w, err, "Failed to retrieve projects")
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, projects)
+}
+
+// respondWithTenancyError maps tenancy errors to HTTP responses.
+func (th *TenancyHandler) respondWithTenancyError(w http.ResponseWriter, err error, fallback string) {
+	th.Logger.Errorf("%s: %v", fallback, err)
+	if errors.Is(err, tenancy.ErrLastTeamOwner) {
+		problem.Error(w, http.StatusConflict, problem.CodeLastTeamOwner, err.Error())
+		return
+	}
+	respondWithError(w, err, fallback)
+}
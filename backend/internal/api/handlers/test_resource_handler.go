@@ -0,0 +1,172 @@
+// backend/internal/api/handlers/test_resource_handler.go
+
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+
+	"github.com/AkshayDubey29/MoniFlux/backend/internal/api/models"
+	"github.com/AkshayDubey29/MoniFlux/backend/internal/api/problem"
+	"github.com/AkshayDubey29/MoniFlux/backend/internal/common"
+	"github.com/AkshayDubey29/MoniFlux/backend/internal/services/destinations"
+	"github.com/gorilla/mux"
+)
+
+// The handlers below serve the /api/v1 test resource, where the test is named by the
+// 'testID' path variable instead of the request body.
+
+// StartTestByID handles starting a stored test. The optional body holds test fields
+// to change first, as for UpdateTestByID.
+func (h *Handler) StartTestByID(w http.ResponseWriter, r *http.Request) {
+	test, ok := h.mergeTest(w, r, "Failed to start test")
+	if !ok {
+		return
+	}
+	h.startTest(w, r, test, http.StatusOK)
+}
+
+// ScheduleTestByID handles scheduling a stored test. The body holds 'scheduleAt'.
+func (h *Handler) ScheduleTestByID(w http.ResponseWriter, r *http.Request) {
+	var scheduleReq models.ScheduleRequest
+	if !h.decodeOptionalBody(w, r, &scheduleReq) {
+		return
+	}
+	scheduleReq.TestID = mux.Vars(r)["testID"]
+	h.scheduleTest(w, r, &scheduleReq)
+}
+
+// CancelTestByID handles cancelling a test. The request has no body.
+func (h *Handler) CancelTestByID(w http.ResponseWriter, r *http.Request) {
+	h.cancelTest(w, r, &models.CancelRequest{TestID: mux.Vars(r)["testID"]})
+}
+
+// RestartTestByID handles restarting a test. The body holds the duration and rates
+// of the new run.
+func (h *Handler) RestartTestByID(w http.ResponseWriter, r *http.Request) {
+	var restartReq models.RestartRequest
+	if !h.decodeOptionalBody(w, r, &restartReq) {
+		return
+	}
+	restartReq.TestID = mux.Vars(r)["testID"]
+	h.restartTest(w, r, &restartReq)
+}
+
+// SaveResultsByID handles saving the results of a test.
+func (h *Handler) SaveResultsByID(w http.ResponseWriter, r *http.Request) {
+	var results models.TestResults
+	if !h.decodeOptionalBody(w, r, &results) {
+		return
+	}
+	results.TestID = mux.Vars(r)["testID"]
+	h.saveResults(w, r, &results)
+}
+
+// UpdateTestByID handles changing the configuration of a test that is not running or
+// queued. The body is a JSON merge patch of the test: fields it omits are unchanged.
+// Setting 'destinationName' alone switches to that registered destination.
+func (h *Handler) UpdateTestByID(w http.ResponseWriter, r *http.Request) {
+	test, ok := h.mergeTest(w, r, "Failed to update test")
+	if !ok {
+		return
+	}
+	if err := h.Validator.Struct(test); err != nil {
+		h.Logger.Errorf("Validation error: %v", err)
+		respondWithValidationError(w, err)
+		return
+	}
+
+	before := h.snapshotTest(r, test.TestID)
+	defer h.auditTestAction(r, "test.update", test.TestID, before)
+
+	if err := h.Controller.UpdateTest(r.Context(), test); err != nil {
+		h.Logger.Errorf("Failed to update test: %v", err)
+		respondWithError(w, err, "Failed to update test")
+		return
+	}
+	respondWithJSON(w, http.StatusOK, test)
+}
+
+// DeleteTestByID handles removing a test that is not running, queued or scheduled,
+// together with its results.
+func (h *Handler) DeleteTestByID(w http.ResponseWriter, r *http.Request) {
+	testID := mux.Vars(r)["testID"]
+	before := h.snapshotTest(r, testID)
+	defer h.auditTestAction(r, "test.delete", testID, before)
+
+	if err := h.Controller.DeleteTest(r.Context(), testID); err != nil {
+		h.Logger.Errorf("Failed to delete test: %v", err)
+		respondWithError(w, err, "Failed to delete test")
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// mergeTest returns the stored test in the URL with the fields of the request body
+// applied. The identity, owner, status and timestamps of the test cannot be changed.
+func (h *Handler) mergeTest(w http.ResponseWriter, r *http.Request, fallback string) (*models.Test, bool) {
+	testID := mux.Vars(r)["testID"]
+	stored, err := h.Controller.GetTestByID(r.Context(), testID)
+	if err != nil {
+		h.Logger.Errorf("Failed to get test %s: %v", testID, err)
+		respondWithError(w, err, fallback)
+		return nil, false
+	}
+
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		h.Logger.Errorf("Failed to read request body: %v", err)
+		respondWithInvalidPayload(w)
+		return nil, false
+	}
+	test := *stored
+	test.DeliveryStats, test.QueuePosition = nil, 0
+	if len(body) > 0 {
+		var fields map[string]json.RawMessage
+		if err := json.Unmarshal(body, &fields); err != nil {
+			h.Logger.Errorf("Failed to decode test: %v", err)
+			respondWithInvalidPayload(w)
+			return nil, false
+		}
+		// A destination and a destination name replace each other.
+		_, hasDestination := fields["destination"]
+		_, hasName := fields["destinationName"]
+		if hasDestination && !hasName {
+			test.DestinationName = ""
+		}
+		if err := json.Unmarshal(body, &test); err != nil {
+			h.Logger.Errorf("Failed to decode test: %v", err)
+			respondWithInvalidPayload(w)
+			return nil, false
+		}
+		if hasName && !hasDestination {
+			test.Destination = common.Destination{}
+		}
+	}
+	test.TestID, test.UserID, test.TeamID, test.ProjectID = stored.TestID, stored.UserID, stored.TeamID, stored.ProjectID
+	test.Status, test.CreatedAt, test.UpdatedAt = stored.Status, stored.CreatedAt, stored.UpdatedAt
+	test.ScheduledTime, test.CompletedAt = stored.ScheduledTime, stored.CompletedAt
+
+	if test.Destination.Type == "" {
+		if err := h.Controller.ResolveDestination(r.Context(), &test); err != nil {
+			h.Logger.Errorf("Failed to resolve destination: %v", err)
+			respondWithError(w, err, fallback)
+			return nil, false
+		}
+	}
+	destinations.ApplyDefaults(&test.Destination)
+	return &test, true
+}
+
+// decodeOptionalBody decodes the JSON request body into v, if there is one.
+func (h *Handler) decodeOptionalBody(w http.ResponseWriter, r *http.Request, v interface{}) bool {
+	err := json.NewDecoder(r.Body).Decode(v)
+	if err != nil && !errors.Is(err, io.EOF) {
+		h.Logger.Errorf("Failed to decode request: %v", err)
+		problem.Error(w, http.StatusBadRequest, problem.CodeInvalidRequest, "Invalid request payload")
+		return false
+	}
+	return true
+}
@@ -0,0 +1,92 @@
+// backend/internal/api/middlewares/audit.go
+
+package middlewares
+
+import (
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/AkshayDubey29/MoniFlux/backend/internal/services/audit"
+	"github.com/gorilla/mux"
+	"github.com/sirupsen/logrus"
+)
+
+// AuditMiddleware records an audit event for every mutating request (POST, PUT, PATCH
+// and DELETE) once it has been handled. The actor is filled in by AuthMiddleware and
+// handlers may name the action and record the change they made; otherwise the action
+// is the method and route template, e.g. "DELETE /api-keys/{keyID}".
+func AuditMiddleware(auditService *audit.AuditService, logger *logrus.Logger) func(next http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if !isMutatingMethod(r.Method) {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			startTime := time.Now()
+			event := audit.Event{
+				Timestamp:    startTime.UTC(),
+				Method:       r.Method,
+				Path:         r.URL.Path,
+				Action:       r.Method + " " + r.URL.Path,
+				SourceIP:     remoteIP(r),
+				ForwardedFor: r.Header.Get("X-Forwarded-For"),
+				UserAgent:    r.UserAgent(),
+			}
+			if requestID, ok := GetRequestID(r.Context()); ok {
+				event.RequestID = requestID
+			}
+			if route := mux.CurrentRoute(r); route != nil {
+				if template, err := route.GetPathTemplate(); err == nil {
+					event.Action = r.Method + " " + template
+				}
+			}
+			if vars := mux.Vars(r); len(vars) > 0 {
+				event.Params = vars
+			}
+
+			entry := audit.NewEntry(event)
+			rec := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+			next.ServeHTTP(rec, r.WithContext(audit.ContextWithEntry(r.Context(), entry)))
+
+			recorded := entry.Event()
+			recorded.StatusCode = rec.status
+			recorded.Outcome = outcomeForStatus(rec.status)
+			recorded.DurationMs = time.Since(startTime).Milliseconds()
+			if err := auditService.Record(&recorded); err != nil {
+				logger.Errorf("Audit event for request %s was not recorded: %v", recorded.RequestID, err)
+			}
+		})
+	}
+}
+
+// isMutatingMethod reports whether requests with the method change server state.
+func isMutatingMethod(method string) bool {
+	switch method {
+	case http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete:
+		return true
+	}
+	return false
+}
+
+// outcomeForStatus classifies a response status code.
+func outcomeForStatus(status int) string {
+	switch {
+	case status == http.StatusUnauthorized || status == http.StatusForbidden:
+		return audit.OutcomeDenied
+	case status >= 400:
+		return audit.OutcomeFailure
+	default:
+		return audit.OutcomeSuccess
+	}
+}
+
+// remoteIP returns the IP address of the peer that sent the request.
+func remoteIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
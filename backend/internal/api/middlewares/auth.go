@@ -7,11 +7,14 @@ import (
 	"net/http"
 	"strings"
 
+	"github.com/AkshayDubey29/MoniFlux/backend/internal/api/models"
+	"github.com/AkshayDubey29/MoniFlux/backend/internal/api/problem"
+	"github.com/AkshayDubey29/MoniFlux/backend/internal/services/audit"
 	"github.com/AkshayDubey29/MoniFlux/backend/internal/services/authentication"
 	"github.com/sirupsen/logrus"
 )
 
-// AuthMiddleware handles JWT authentication for API routes.
+// AuthMiddleware handles JWT and API key authentication for API routes.
 type AuthMiddleware struct {
 	authService *authentication.AuthenticationService
 	logger      *logrus.Logger
@@ -26,29 +29,40 @@ func NewAuthMiddleware(authService *authentication.AuthenticationService, logger
 }
 
 // MiddlewareFunc is the HTTP middleware function that enforces authentication.
+// Requests may authenticate with a JWT ("Authorization: Bearer <jwt>") or with an
+// API key, sent either as "X-API-Key: <key>" or as "Authorization: Bearer <key>".
 func (am *AuthMiddleware) MiddlewareFunc(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if apiKey := r.Header.Get("X-API-Key"); apiKey != "" {
+			am.serveWithAPIKey(w, r, next, apiKey)
+			return
+		}
+
 		// Retrieve the Authorization header.
 		authHeader := r.Header.Get("Authorization")
 		if authHeader == "" {
-			http.Error(w, "Missing Authorization header", http.StatusUnauthorized)
+			problem.Error(w, http.StatusUnauthorized, problem.CodeUnauthorized, "Missing Authorization header")
 			return
 		}
 
 		// Expected format: "Bearer <token>"
 		parts := strings.SplitN(authHeader, " ", 2)
 		if len(parts) != 2 || strings.ToLower(parts[0]) != "bearer" {
-			http.Error(w, "Invalid Authorization header format", http.StatusUnauthorized)
+			problem.Error(w, http.StatusUnauthorized, problem.CodeUnauthorized, "Invalid Authorization header format")
 			return
 		}
 
 		tokenString := parts[1]
+		if authentication.IsAPIKey(tokenString) {
+			am.serveWithAPIKey(w, r, next, tokenString)
+			return
+		}
 
 		// Validate the JWT token.
-		claims, err := am.authService.ValidateJWT(tokenString)
+		claims, err := am.authService.ValidateJWT(r.Context(), tokenString)
 		if err != nil {
 			am.logger.Errorf("Invalid JWT token: %v", err)
-			http.Error(w, "Invalid token", http.StatusUnauthorized)
+			problem.Error(w, http.StatusUnauthorized, problem.CodeUnauthorized, "Invalid token")
 			return
 		}
 
@@ -56,12 +70,67 @@ func (am *AuthMiddleware) MiddlewareFunc(next http.Handler) http.Handler {
 		user, err := am.authService.GetUserByID(r.Context(), claims.UserID)
 		if err != nil {
 			am.logger.Errorf("Failed to retrieve user from token: %v", err)
-			http.Error(w, "Invalid token", http.StatusUnauthorized)
+			problem.Error(w, http.StatusUnauthorized, problem.CodeUnauthorized, "Invalid token")
+			return
+		}
+		if user.Disabled {
+			problem.Error(w, http.StatusUnauthorized, problem.CodeAccountDisabled, "Account disabled")
 			return
 		}
 
-		// Inject the user into the request context.
+		// Inject the user and the principal derived from the token claims into the request context.
+		principal := &models.Principal{
+			UserID:    claims.UserID,
+			Username:  user.Username,
+			Roles:     claims.Roles,
+			SessionID: claims.SessionID,
+			TokenID:   claims.ID,
+		}
 		ctx := context.WithValue(r.Context(), "user", user)
+		ctx = models.ContextWithPrincipal(ctx, principal)
+		audit.EntryFromContext(ctx).SetActor(principal)
 		next.ServeHTTP(w, r.WithContext(ctx))
 	})
 }
+
+// serveWithAPIKey authenticates the request with an API key. The principal acts as
+// the key's owner with their current roles, limited to the key's scopes; it is only
+// an admin when the key holds models.AdminScope.
+func (am *AuthMiddleware) serveWithAPIKey(w http.ResponseWriter, r *http.Request, next http.Handler, apiKey string) {
+	key, err := am.authService.ValidateAPIKey(r.Context(), apiKey)
+	if err != nil {
+		am.logger.Errorf("Invalid API key: %v", err)
+		problem.Error(w, http.StatusUnauthorized, problem.CodeUnauthorized, "Invalid API key")
+		return
+	}
+
+	user, err := am.authService.GetUserByID(r.Context(), key.UserID)
+	if err != nil {
+		am.logger.Errorf("Failed to retrieve owner of API key %s: %v", key.Prefix, err)
+		problem.Error(w, http.StatusUnauthorized, problem.CodeUnauthorized, "Invalid API key")
+		return
+	}
+	if user.Disabled {
+		problem.Error(w, http.StatusUnauthorized, problem.CodeAccountDisabled, "Account disabled")
+		return
+	}
+
+	roles, err := am.authService.GetRoleNames(r.Context(), user.Roles)
+	if err != nil {
+		am.logger.Errorf("Failed to resolve roles for API key %s: %v", key.Prefix, err)
+		problem.Error(w, http.StatusInternalServerError, problem.CodeInternal, "Internal server error")
+		return
+	}
+
+	principal := &models.Principal{
+		UserID:   key.UserID,
+		Username: user.Username,
+		Roles:    roles,
+		APIKeyID: key.KeyID,
+		Scopes:   key.Scopes,
+	}
+	ctx := context.WithValue(r.Context(), "user", user)
+	ctx = models.ContextWithPrincipal(ctx, principal)
+	audit.EntryFromContext(ctx).SetActor(principal)
+	next.ServeHTTP(w, r.WithContext(ctx))
+}
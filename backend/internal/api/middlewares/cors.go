@@ -5,6 +5,7 @@ package middlewares
 import (
 	"net/http"
 
+	"github.com/AkshayDubey29/MoniFlux/backend/internal/api/problem"
 	"github.com/sirupsen/logrus"
 )
 
@@ -18,12 +19,12 @@ func CORSMiddleware(allowedOrigins []string, logger *logrus.Logger) func(next ht
 			if isOriginAllowed(origin, allowedOrigins) {
 				w.Header().Set("Access-Control-Allow-Origin", origin)
 				w.Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, DELETE, OPTIONS")
-				w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization, X-Requested-With")
+				w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization, X-API-Key, X-Requested-With")
 				w.Header().Set("Access-Control-Allow-Credentials", "true")
 			} else if origin != "" {
 				// Origin is not allowed
 				logger.Warnf("CORS request from disallowed origin: %s", origin)
-				http.Error(w, "CORS origin denied", http.StatusForbidden)
+				problem.Error(w, http.StatusForbidden, problem.CodeForbidden, "CORS origin denied")
 				return
 			}
 
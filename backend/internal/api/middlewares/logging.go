@@ -8,6 +8,8 @@ import (
 )
 
 // LoggingMiddleware logs each incoming HTTP request and its corresponding response.
+// The request and trace IDs are read from the response headers set by the
+// RequestIDMiddleware and TracingMiddleware.
 func LoggingMiddleware(logger *logrus.Logger) func(next http.Handler) http.Handler {
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
@@ -27,6 +29,8 @@ func LoggingMiddleware(logger *logrus.Logger) func(next http.Handler) http.Handl
 				"remote_addr":  r.RemoteAddr,
 				"user_agent":   r.UserAgent(),
 				"request_time": startTime.Format(time.RFC3339),
+				"request_id":   rec.Header().Get("X-Request-ID"),
+				"trace_id":     rec.Header().Get("X-Trace-ID"),
 			}).Info("Handled request")
 		})
 	}
@@ -8,6 +8,7 @@ import (
 	"sync"
 	"time"
 
+	"github.com/AkshayDubey29/MoniFlux/backend/internal/api/problem"
 	"github.com/sirupsen/logrus"
 	"golang.org/x/time/rate"
 )
@@ -78,7 +79,7 @@ func RateLimitMiddleware(rl *RateLimiter) func(next http.Handler) http.Handler {
 			limiter := rl.getVisitor(ip)
 
 			if !limiter.Allow() {
-				http.Error(w, "Too Many Requests", http.StatusTooManyRequests)
+				problem.Error(w, http.StatusTooManyRequests, problem.CodeRateLimited, "Too Many Requests")
 				return
 			}
 
@@ -5,6 +5,7 @@ package middlewares
 import (
 	"net/http"
 
+	"github.com/AkshayDubey29/MoniFlux/backend/internal/api/problem"
 	"github.com/sirupsen/logrus"
 )
 
@@ -15,7 +16,7 @@ func RecoveryMiddleware(logger *logrus.Logger) func(next http.Handler) http.Hand
 			defer func() {
 				if err := recover(); err != nil {
 					logger.Errorf("Panic recovered: %v", err)
-					http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+					problem.Error(w, http.StatusInternalServerError, problem.CodeInternal, "Internal Server Error")
 				}
 			}()
 			next.ServeHTTP(w, r)
@@ -0,0 +1,58 @@
+// backend/internal/api/middlewares/tracing.go
+
+package middlewares
+
+import (
+	"net/http"
+
+	"github.com/AkshayDubey29/MoniFlux/backend/pkg/tracing"
+	"github.com/gorilla/mux"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const tracerName = "github.com/AkshayDubey29/MoniFlux/backend/internal/api"
+
+// TracingMiddleware records a server span for each request, continuing the trace of
+// an incoming W3C traceparent header. The span is named after the route template and
+// holds the request ID set by RequestIDMiddleware, which must run first. The trace ID
+// is returned in the X-Trace-ID header.
+func TracingMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := otel.GetTextMapPropagator().Extract(r.Context(), propagation.HeaderCarrier(r.Header))
+
+		route := r.URL.Path
+		if current := mux.CurrentRoute(r); current != nil {
+			if template, err := current.GetPathTemplate(); err == nil {
+				route = template
+			}
+		}
+		ctx, span := otel.Tracer(tracerName).Start(ctx, r.Method+" "+route,
+			trace.WithSpanKind(trace.SpanKindServer),
+			trace.WithAttributes(
+				semconv.HTTPRequestMethodKey.String(r.Method),
+				semconv.HTTPRoute(route),
+				semconv.URLPath(r.URL.Path),
+				semconv.ClientAddress(r.RemoteAddr),
+			),
+		)
+		defer span.End()
+		if requestID, ok := GetRequestID(ctx); ok {
+			span.SetAttributes(tracing.RequestIDKey.String(requestID))
+		}
+		if spanContext := span.SpanContext(); spanContext.HasTraceID() {
+			w.Header().Set("X-Trace-ID", spanContext.TraceID().String())
+		}
+
+		rec := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+		next.ServeHTTP(rec, r.WithContext(ctx))
+
+		span.SetAttributes(semconv.HTTPResponseStatusCode(rec.status))
+		if rec.status >= http.StatusInternalServerError {
+			span.SetStatus(codes.Error, http.StatusText(rec.status))
+		}
+	})
+}
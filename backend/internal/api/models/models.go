@@ -3,6 +3,7 @@
 package models
 
 import (
+	"context"
 	"errors"
 	"time"
 
@@ -20,33 +21,176 @@ type User struct {
 	Roles     []primitive.ObjectID `bson:"roles" json:"roles" validate:"required,dive,required"`
 	CreatedAt time.Time            `bson:"created_at" json:"createdAt"`
 	UpdatedAt time.Time            `bson:"updated_at" json:"updatedAt"`
+	// OIDCIssuer and OIDCSubject identify users provisioned through single sign-on.
+	OIDCIssuer  string `bson:"oidcIssuer,omitempty" json:"oidcIssuer,omitempty"`
+	OIDCSubject string `bson:"oidcSubject,omitempty" json:"oidcSubject,omitempty"`
+	// Account state maintained by the authentication service.
+	EmailVerified       bool       `bson:"emailVerified" json:"emailVerified"`
+	Disabled            bool       `bson:"disabled" json:"disabled"`
+	FailedLoginAttempts int        `bson:"failedLoginAttempts" json:"-"`
+	LockedUntil         *time.Time `bson:"lockedUntil,omitempty" json:"lockedUntil,omitempty"`
+	PasswordChangedAt   *time.Time `bson:"passwordChangedAt,omitempty" json:"passwordChangedAt,omitempty"`
+}
+
+// UserProfile is the representation of a user returned by the API. It never includes credentials.
+type UserProfile struct {
+	ID            string     `json:"id"`
+	Username      string     `json:"username"`
+	Email         string     `json:"email"`
+	EmailVerified bool       `json:"emailVerified"`
+	Roles         []string   `json:"roles"`
+	SSO           bool       `json:"sso"`
+	CreatedAt     time.Time  `json:"createdAt"`
+	PasswordSetAt *time.Time `json:"passwordChangedAt,omitempty"`
 }
 
 // Claims represents the JWT claims.
 type Claims struct {
-	UserID string `json:"userID" bson:"userID"`
+	UserID string   `json:"userID" bson:"userID"`
+	Roles  []string `json:"roles,omitempty" bson:"roles,omitempty"` // Role names at the time the token was issued
+	// SessionID binds an access token to the login session whose refresh token issued it.
+	SessionID string `json:"sid,omitempty" bson:"sid,omitempty"`
 	jwt.RegisteredClaims
 }
 
 // Ensure that Claims implements the jwt.Claims interface.
 var _ jwt.Claims = &Claims{}
 
+// AdminRole is the role name that grants access to every tenant's resources.
+const AdminRole = "admin"
+
+// AdminScope is the API key scope that lets a key made by an admin act as one.
+const AdminScope = "tenants:admin"
+
+// Principal identifies the authenticated caller of a request.
+type Principal struct {
+	UserID   string   `json:"userID"`
+	Username string   `json:"username"`
+	Roles    []string `json:"roles"`
+	APIKeyID string   `json:"apiKeyID,omitempty"` // Set when authenticated with an API key
+	Scopes   []string `json:"scopes,omitempty"`   // Permissions the API key is limited to
+	// SessionID and TokenID identify the session and access token (jti) of JWT-authenticated requests.
+	SessionID string `json:"sessionID,omitempty"`
+	TokenID   string `json:"-"`
+}
+
+// AllowsScope reports whether the credential used for the request may exercise the permission.
+// JWT-authenticated principals are not scope-limited.
+func (p *Principal) AllowsScope(permission string) bool {
+	if p.APIKeyID == "" {
+		return true
+	}
+	for _, s := range p.Scopes {
+		if s == permission {
+			return true
+		}
+	}
+	return false
+}
+
+// HasRole reports whether the principal holds the named role.
+func (p *Principal) HasRole(role string) bool {
+	for _, r := range p.Roles {
+		if r == role {
+			return true
+		}
+	}
+	return false
+}
+
+// IsAdmin reports whether the principal may act on behalf of every tenant.
+// API keys only do so when their owner is an admin and they carry AdminScope.
+func (p *Principal) IsAdmin() bool {
+	return p.HasRole(AdminRole) && p.AllowsScope(AdminScope)
+}
+
+type principalContextKey struct{}
+
+// ContextWithPrincipal returns a copy of ctx carrying the given principal.
+func ContextWithPrincipal(ctx context.Context, principal *Principal) context.Context {
+	return context.WithValue(ctx, principalContextKey{}, principal)
+}
+
+// PrincipalFromContext retrieves the authenticated principal from the context.
+// It returns false for requests that did not pass through authentication and for
+// internal operations such as scheduled starts.
+func PrincipalFromContext(ctx context.Context) (*Principal, bool) {
+	principal, ok := ctx.Value(principalContextKey{}).(*Principal)
+	return principal, ok && principal != nil
+}
+
 // Test represents a load test configuration and status.
 type Test struct {
-	TestID        string             `json:"testID" bson:"testID" validate:"required"`
-	UserID        string             `json:"userID" bson:"userID" validate:"required"`
-	LogType       string             `json:"logType" bson:"logType" validate:"required,oneof=INFO WARN ERROR DEBUG"`
-	LogRate       int                `json:"logRate,omitempty" bson:"logRate" validate:"omitempty,min=1"`         // Logs per second
-	LogSize       int                `json:"logSize,omitempty" bson:"logSize" validate:"omitempty,min=1"`         // Size of each log entry in bytes
-	MetricsRate   int                `json:"metricsRate,omitempty" bson:"metricsRate" validate:"omitempty,min=1"` // Metrics per second
-	TraceRate     int                `json:"traceRate,omitempty" bson:"traceRate" validate:"omitempty,min=1"`     // Traces per second
-	Duration      int                `json:"duration" bson:"duration" validate:"required,min=1"`                  // Duration in seconds
-	Destination   common.Destination `json:"destination" bson:"destination" validate:"required"`
-	Status        string             `json:"status" bson:"status" validate:"required,oneof=Pending Running Completed Cancelled"`
-	ScheduledTime time.Time          `json:"scheduledTime,omitempty" bson:"scheduledTime,omitempty"`
-	CreatedAt     time.Time          `json:"createdAt" bson:"createdAt"`
-	UpdatedAt     time.Time          `json:"updatedAt" bson:"updatedAt"`
-	CompletedAt   time.Time          `json:"completedAt,omitempty" bson:"completedAt,omitempty"`
+	TestID          string             `json:"testID" bson:"testID" validate:"required"`
+	UserID          string             `json:"userID" bson:"userID" validate:"required"`
+	TeamID          string             `json:"teamID,omitempty" bson:"teamID,omitempty"`       // Owning team (tenant), if shared
+	ProjectID       string             `json:"projectID,omitempty" bson:"projectID,omitempty"` // Project within the owning team
+	LogType         string             `json:"logType" bson:"logType" validate:"required,oneof=INFO WARN ERROR DEBUG"`
+	LogRate         int                `json:"logRate,omitempty" bson:"logRate" validate:"omitempty,min=1"`         // Logs per second
+	LogSize         int                `json:"logSize,omitempty" bson:"logSize" validate:"omitempty,min=1"`         // Size of each log entry in bytes
+	MetricsRate     int                `json:"metricsRate,omitempty" bson:"metricsRate" validate:"omitempty,min=1"` // Metrics per second
+	TraceRate       int                `json:"traceRate,omitempty" bson:"traceRate" validate:"omitempty,min=1"`     // Traces per second
+	Duration        int                `json:"duration" bson:"duration" validate:"required,min=1"`                  // Duration in seconds
+	Destination     common.Destination `json:"destination" bson:"destination" validate:"required"`
+	DestinationName string             `json:"destinationName,omitempty" bson:"destinationName,omitempty"` // Registered destination copied into Destination when the test is started
+	Tags            []string           `json:"tags,omitempty" bson:"tags,omitempty" validate:"omitempty,dive,required"`
+	Status          string             `json:"status" bson:"status" validate:"required,oneof=Pending Scheduled Queued Running Completed Cancelled Error Stopped 'Results Saved'"`
+	QueuePosition   int                `json:"queuePosition,omitempty" bson:"-"` // 1-based position in the admission queue while Queued
+	ScheduledTime   time.Time          `json:"scheduledTime,omitempty" bson:"scheduledTime,omitempty"`
+	CreatedAt       time.Time          `json:"createdAt" bson:"createdAt"`
+	UpdatedAt       time.Time          `json:"updatedAt" bson:"updatedAt"`
+	CompletedAt     time.Time          `json:"completedAt,omitempty" bson:"completedAt,omitempty"`
+	DeliveryStats   *DeliveryStats     `json:"deliveryStats,omitempty" bson:"deliveryStats,omitempty"` // Live while Running
+	Preflight       bool               `json:"preflight,omitempty" bson:"-"`                           // Probe the destination before starting; refuse to start if it fails
+}
+
+// DeliveryStats summarizes the deliveries of a test run. File destinations report
+// the entries written and File only.
+type DeliveryStats struct {
+	Requests         int64             `json:"requests" bson:"requests"`                                     // Requests accepted by the destination
+	Failures         int64             `json:"failures" bson:"failures"`                                     // Requests that failed on every attempt
+	Entries          int64             `json:"entries" bson:"entries"`                                       // Entries in accepted requests
+	RawBytes         int64             `json:"rawBytes" bson:"rawBytes"`                                     // Encoded size of accepted requests before compression
+	WireBytes        int64             `json:"wireBytes" bson:"wireBytes"`                                   // Body size of accepted requests as sent
+	CompressionRatio float64           `json:"compressionRatio,omitempty" bson:"compressionRatio,omitempty"` // RawBytes / WireBytes
+	Retries          int64             `json:"retries" bson:"retries"`                                       // Requests sent again after a retryable failure
+	DeadLettered     int64             `json:"deadLettered" bson:"deadLettered"`                             // Entries written to the dead-letter file
+	CircuitOpens     int64             `json:"circuitOpens" bson:"circuitOpens"`                             // Times the circuit breaker paused delivery
+	Connections      []ConnectionStats `json:"connections,omitempty" bson:"connections,omitempty"`           // syslog, tcp and udp destinations only
+	File             *FileStats        `json:"file,omitempty" bson:"file,omitempty"`                         // file destinations only
+	Objects          *ObjectStats      `json:"objects,omitempty" bson:"objects,omitempty"`                   // s3 destinations only
+}
+
+// ObjectStats summarizes the segment objects uploaded by a test with an s3 destination.
+// DeliveryStats counts uploaded objects as requests and failed uploads as failures.
+type ObjectStats struct {
+	Bucket          string `json:"bucket" bson:"bucket"`
+	Parts           int64  `json:"parts" bson:"parts"`                     // Multipart upload parts sent
+	PendingSegments int    `json:"pendingSegments" bson:"pendingSegments"` // Segments being written or waiting for their upload
+	KeptSegments    int64  `json:"keptSegments" bson:"keptSegments"`       // Segments that failed to upload and were left in the segment directory
+	LastKey         string `json:"lastKey,omitempty" bson:"lastKey,omitempty"`
+}
+
+// FileStats summarizes the files written by a test with a file destination.
+type FileStats struct {
+	BytesWritten    int64 `json:"bytesWritten" bson:"bytesWritten"`       // Before compression of rotated files
+	DiskUsageBytes  int64 `json:"diskUsageBytes" bson:"diskUsageBytes"`   // Size of the current and retained files
+	Files           int   `json:"files" bson:"files"`                     // Current and retained files
+	Rotations       int64 `json:"rotations" bson:"rotations"`             // Files rotated by size or time
+	DeletedFiles    int64 `json:"deletedFiles" bson:"deletedFiles"`       // Rotated files removed by retention
+	DroppedEntries  int64 `json:"droppedEntries" bson:"droppedEntries"`   // Entries not written, e.g. over the disk budget
+	BudgetExhausted bool  `json:"budgetExhausted" bson:"budgetExhausted"` // Writing stopped at the disk budget
+}
+
+// ConnectionStats summarizes the traffic of a pooled syslog, TCP or UDP connection.
+type ConnectionStats struct {
+	ID             int     `json:"id" bson:"id"`
+	Address        string  `json:"address" bson:"address"`
+	Messages       int64   `json:"messages" bson:"messages"`
+	Bytes          int64   `json:"bytes" bson:"bytes"`                   // Including the framing
+	Errors         int64   `json:"errors" bson:"errors"`                 // Failed dials and writes
+	Reconnects     int64   `json:"reconnects" bson:"reconnects"`         // Connections replaced after a failure
+	BytesPerSecond float64 `json:"bytesPerSecond" bson:"bytesPerSecond"` // From the first connection to the last write
 }
 
 // LogEntry represents a log entry.
@@ -104,6 +248,168 @@ type TestResults struct {
 	Traces      []Trace    `json:"traces" bson:"traces" validate:"dive"`
 }
 
+// RunSecond holds the statistics of one second of a test run.
+type RunSecond struct {
+	Second       int       `json:"second" bson:"second"`             // Seconds since the start of the run
+	Timestamp    time.Time `json:"timestamp" bson:"timestamp"`       // Start of the second
+	Rate         int64     `json:"rate" bson:"rate"`                 // Entries generated in the second: the achieved rate
+	Logs         int64     `json:"logs" bson:"logs"`                 // Log entries generated
+	Metrics      int64     `json:"metrics" bson:"metrics"`           // Metrics generated
+	Traces       int64     `json:"traces" bson:"traces"`             // Traces generated
+	Dropped      int64     `json:"dropped" bson:"dropped"`           // Entries dropped because the workers were busy
+	Delivered    int64     `json:"delivered" bson:"delivered"`       // Entries delivered, uploaded or written
+	Errors       int64     `json:"errors" bson:"errors"`             // Failed requests, batches or uploads, or entries a file destination dropped
+	Batches      int64     `json:"batches" bson:"batches"`           // Batches handed to the destination; entries for file and s3 destinations
+	LatencyAvgMs float64   `json:"latencyAvgMs" bson:"latencyAvgMs"` // Time to hand a batch to the destination
+	LatencyMaxMs float64   `json:"latencyMaxMs" bson:"latencyMaxMs"`
+}
+
+// TestRun holds the per-second statistics of the latest run of a test, stored in the
+// test_runs collection. Seconds is truncated after MaxRunSeconds; the totals cover
+// the whole run.
+type TestRun struct {
+	TestID       string      `json:"testID" bson:"testID"`
+	StartedAt    time.Time   `json:"startedAt" bson:"startedAt"`
+	EndedAt      time.Time   `json:"endedAt,omitempty" bson:"endedAt,omitempty"`
+	Generated    int64       `json:"generated" bson:"generated"`
+	Dropped      int64       `json:"dropped" bson:"dropped"`
+	Batches      int64       `json:"batches" bson:"batches"`
+	LatencyAvgMs float64     `json:"latencyAvgMs" bson:"latencyAvgMs"`
+	LatencyMaxMs float64     `json:"latencyMaxMs" bson:"latencyMaxMs"`
+	Truncated    bool        `json:"truncated,omitempty" bson:"truncated,omitempty"`
+	Seconds      []RunSecond `json:"seconds" bson:"seconds"`
+}
+
+// MaxRunSeconds is the number of seconds of a run kept in TestRun.Seconds.
+const MaxRunSeconds = 6 * 60 * 60
+
+// RunSummary summarizes a test run.
+type RunSummary struct {
+	TestID        string         `json:"testID"`
+	Status        string         `json:"status"`
+	StartedAt     *time.Time     `json:"startedAt,omitempty"`
+	EndedAt       *time.Time     `json:"endedAt,omitempty"`       // Unset while Running
+	DurationSec   float64        `json:"durationSec"`             // Elapsed time of the run
+	TargetRate    int            `json:"targetRate"`              // Configured entries per second
+	AchievedRate  float64        `json:"achievedRate"`            // Entries generated per second of the run
+	Generated     int64          `json:"generated"`               // Entries generated
+	Dropped       int64          `json:"dropped"`                 // Entries dropped because the workers were busy
+	Delivered     int64          `json:"delivered"`               // Entries delivered, uploaded or written
+	Errors        int64          `json:"errors"`                  // Failures counted by the delivery statistics
+	Batches       int64          `json:"batches"`                 // Batches handed to the destination
+	LatencyAvgMs  float64        `json:"latencyAvgMs"`            // Time to hand a batch to the destination
+	LatencyMaxMs  float64        `json:"latencyMaxMs"`            // Slowest batch
+	Truncated     bool           `json:"truncated,omitempty"`     // The time series stops after MaxRunSeconds
+	DeliveryStats *DeliveryStats `json:"deliveryStats,omitempty"` // As reported by GET /tests/{testID}
+}
+
+// RunResults is the summary and time series of a test run, as exported by
+// GET /tests/{testID}/results/export.
+type RunResults struct {
+	Summary    RunSummary  `json:"summary"`
+	TimeSeries []RunSecond `json:"timeSeries"`
+}
+
+// APIKey represents a long-lived, scoped credential for non-interactive clients such as CI jobs.
+type APIKey struct {
+	ID         primitive.ObjectID `json:"-" bson:"_id,omitempty"`
+	KeyID      string             `json:"keyID" bson:"keyID"`
+	Prefix     string             `json:"prefix" bson:"prefix"` // Displayable identifier, e.g. mfx_AbC123xy
+	Hash       string             `json:"-" bson:"hash"`
+	UserID     string             `json:"userID" bson:"userID"`
+	Name       string             `json:"name" bson:"name"`
+	Scopes     []string           `json:"scopes" bson:"scopes"`
+	CreatedAt  time.Time          `json:"createdAt" bson:"createdAt"`
+	LastUsedAt *time.Time         `json:"lastUsedAt,omitempty" bson:"lastUsedAt,omitempty"`
+	ExpiresAt  *time.Time         `json:"expiresAt,omitempty" bson:"expiresAt,omitempty"`
+	RevokedAt  *time.Time         `json:"revokedAt,omitempty" bson:"revokedAt,omitempty"`
+}
+
+// TestListQuery represents the filters, sorting and pagination options for listing tests.
+type TestListQuery struct {
+	Status          []string  `json:"status,omitempty" validate:"omitempty,dive,oneof=Pending Scheduled Queued Running Completed Cancelled Error Stopped 'Results Saved'"`
+	UserID          string    `json:"userID,omitempty"`
+	TeamID          string    `json:"teamID,omitempty"`
+	ProjectID       string    `json:"projectID,omitempty"`
+	DestinationType string    `json:"destinationType,omitempty" validate:"omitempty,oneof=http file syslog tcp udp s3"`
+	Tags            []string  `json:"tags,omitempty" validate:"omitempty,dive,required"`
+	CreatedAfter    time.Time `json:"createdAfter,omitempty"`
+	CreatedBefore   time.Time `json:"createdBefore,omitempty"`
+	UpdatedAfter    time.Time `json:"updatedAfter,omitempty"`
+	UpdatedBefore   time.Time `json:"updatedBefore,omitempty"`
+	SortBy          string    `json:"sortBy,omitempty" validate:"omitempty,oneof=createdAt updatedAt status testID"`
+	SortOrder       string    `json:"sortOrder,omitempty" validate:"omitempty,oneof=asc desc"`
+	Limit           int       `json:"limit,omitempty" validate:"omitempty,min=1,max=500"`
+	Cursor          string    `json:"cursor,omitempty"`
+}
+
+// TestPage represents a single page of tests returned by a listing query.
+type TestPage struct {
+	Tests      []Test `json:"tests"`
+	NextCursor string `json:"nextCursor,omitempty"`
+}
+
+// Phases of a destination probe, in the order they run.
+const (
+	ProbePhaseConfig = "config" // Build the sender from the destination settings
+	ProbePhaseDNS    = "dns"
+	ProbePhaseTCP    = "tcp"
+	ProbePhaseTLS    = "tls"
+	ProbePhaseAuth   = "auth" // Resolve the credentials, fetching OAuth2 tokens
+	ProbePhaseSend   = "send" // Deliver a sample entry of a signal
+)
+
+// Outcomes of a probe phase.
+const (
+	ProbeStatusOK      = "ok"
+	ProbeStatusFailed  = "failed"
+	ProbeStatusSkipped = "skipped" // Not applicable to the destination, or a previous phase failed
+)
+
+// ProbePhase is the outcome of a phase of a destination probe.
+type ProbePhase struct {
+	Phase     string  `json:"phase"`
+	Signal    string  `json:"signal,omitempty"` // log, metric or trace, for send phases
+	Status    string  `json:"status"`
+	LatencyMs float64 `json:"latencyMs"`
+	Detail    string  `json:"detail,omitempty"` // e.g. resolved addresses or the negotiated TLS version
+	Error     string  `json:"error,omitempty"`
+}
+
+// ProbeReport is the result of probing a destination before a test is started.
+type ProbeReport struct {
+	Destination string       `json:"destination"` // Destination type
+	Target      string       `json:"target"`      // URL, address, bucket or file probed
+	Success     bool         `json:"success"`     // No phase failed
+	LatencyMs   float64      `json:"latencyMs"`
+	CheckedAt   time.Time    `json:"checkedAt"`
+	Phases      []ProbePhase `json:"phases"`
+}
+
+// PreflightError is returned when a test started with Preflight fails the probe of
+// its destination. It matches ErrPreflightFailed with errors.Is.
+type PreflightError struct {
+	Report *ProbeReport
+}
+
+func (e *PreflightError) Error() string {
+	for _, phase := range e.Report.Phases {
+		if phase.Status == ProbeStatusFailed {
+			name := phase.Phase
+			if phase.Signal != "" {
+				name += " " + phase.Signal
+			}
+			return ErrPreflightFailed.Error() + ": " + name + ": " + phase.Error
+		}
+	}
+	return ErrPreflightFailed.Error()
+}
+
+// Is reports whether target is ErrPreflightFailed.
+func (e *PreflightError) Is(target error) bool {
+	return target == ErrPreflightFailed
+}
+
 // ValidationError represents a structured validation error.
 type ValidationError struct {
 	Field   string `json:"field"`
@@ -115,7 +421,18 @@ var (
 	ErrInvalidToken           = errors.New("invalid token")
 	ErrTestNotFound           = errors.New("test not found")
 	ErrTestAlreadyExists      = errors.New("test already exists")
+	ErrTestAlreadyRunning     = errors.New("test already running")
+	ErrTestAlreadyQueued      = errors.New("test already queued")
+	ErrInvalidTestState       = errors.New("operation not allowed in the test's current state")
+	ErrInvalidTest            = errors.New("invalid test")
 	ErrTestAlreadyCompleted   = errors.New("test already completed")
 	ErrTestAlreadyCancelled   = errors.New("test already cancelled")
 	ErrDestinationUnsupported = errors.New("unsupported destination type")
+	ErrInvalidCursor          = errors.New("invalid pagination cursor")
+	ErrForbidden              = errors.New("forbidden")
+	ErrTeamNotFound           = errors.New("team not found")
+	ErrProjectNotFound        = errors.New("project not found")
+	ErrCapacityExceeded       = errors.New("test exceeds the load generator capacity")
+	ErrAdmissionQueueFull     = errors.New("admission queue is full")
+	ErrPreflightFailed        = errors.New("destination preflight failed")
 )
@@ -0,0 +1,221 @@
+// backend/internal/api/openapi/openapi.go
+
+// Package openapi builds the OpenAPI 3 document of the API from its route
+// definitions, deriving the request and response schemas from the Go types.
+package openapi
+
+import (
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/AkshayDubey29/MoniFlux/backend/internal/api/problem"
+)
+
+// Version is the OpenAPI version of the documents.
+const Version = "3.0.3"
+
+// Operation describes an endpoint of the API.
+type Operation struct {
+	Method     string      // HTTP method
+	Path       string      // gorilla/mux path template
+	Summary    string      // One-line description
+	Tag        string      // Group of the operation
+	Permission string      // Permission required besides authentication, if any
+	Scope      string      // API key scope allowing an operation without Permission
+	Public     bool        // Served without credentials
+	Successor  string      // Path replacing the operation; set on deprecated operations
+	Request    interface{} // Sample of the JSON request body; nil when there is none
+	Optional   bool        // The request body may be omitted
+	Response   interface{} // Sample of the JSON response body; nil when there is none
+	Status     int         // Status of a successful response; 200 when zero
+	Query      []string    // Query parameters
+}
+
+// Document is an OpenAPI document.
+type Document struct {
+	OpenAPI    string                `json:"openapi"`
+	Info       Info                  `json:"info"`
+	Servers    []Server              `json:"servers"`
+	Paths      map[string]PathItem   `json:"paths"`
+	Components Components            `json:"components"`
+	Security   []map[string][]string `json:"security"`
+}
+
+// Info holds the title and version of the API.
+type Info struct {
+	Title   string `json:"title"`
+	Version string `json:"version"`
+}
+
+// Server is a base URL of the API.
+type Server struct {
+	URL string `json:"url"`
+}
+
+// PathItem holds the operations of a path, by lower-case method.
+type PathItem map[string]*OperationObject
+
+// OperationObject is an operation of the document.
+type OperationObject struct {
+	Summary     string                 `json:"summary,omitempty"`
+	Description string                 `json:"description,omitempty"`
+	OperationID string                 `json:"operationId"`
+	Tags        []string               `json:"tags,omitempty"`
+	Deprecated  bool                   `json:"deprecated,omitempty"`
+	Parameters  []Parameter            `json:"parameters,omitempty"`
+	RequestBody *RequestBody           `json:"requestBody,omitempty"`
+	Responses   map[string]Response    `json:"responses"`
+	Security    *[]map[string][]string `json:"security,omitempty"` // Empty for public operations
+}
+
+// Parameter is a path or query parameter.
+type Parameter struct {
+	Name     string  `json:"name"`
+	In       string  `json:"in"`
+	Required bool    `json:"required,omitempty"`
+	Schema   *Schema `json:"schema"`
+}
+
+// RequestBody is the JSON body of a request.
+type RequestBody struct {
+	Required bool                 `json:"required"`
+	Content  map[string]MediaType `json:"content"`
+}
+
+// Response is a response of an operation.
+type Response struct {
+	Description string               `json:"description"`
+	Content     map[string]MediaType `json:"content,omitempty"`
+}
+
+// MediaType holds the schema of a body.
+type MediaType struct {
+	Schema *Schema `json:"schema"`
+}
+
+// Components holds the named schemas and the security schemes.
+type Components struct {
+	Schemas         map[string]*Schema        `json:"schemas"`
+	SecuritySchemes map[string]SecurityScheme `json:"securitySchemes"`
+}
+
+// SecurityScheme is a way to authenticate.
+type SecurityScheme struct {
+	Type         string `json:"type"`
+	Scheme       string `json:"scheme,omitempty"`
+	BearerFormat string `json:"bearerFormat,omitempty"`
+	In           string `json:"in,omitempty"`
+	Name         string `json:"name,omitempty"`
+}
+
+// Schema is a JSON schema.
+type Schema struct {
+	Ref                  string             `json:"$ref,omitempty"`
+	Type                 string             `json:"type,omitempty"`
+	Format               string             `json:"format,omitempty"`
+	Enum                 []string           `json:"enum,omitempty"`
+	Minimum              *float64           `json:"minimum,omitempty"`
+	Maximum              *float64           `json:"maximum,omitempty"`
+	Items                *Schema            `json:"items,omitempty"`
+	Properties           map[string]*Schema `json:"properties,omitempty"`
+	AdditionalProperties *Schema            `json:"additionalProperties,omitempty"`
+	Required             []string           `json:"required,omitempty"`
+}
+
+// pathParam matches the variables of a path template, with their optional pattern.
+var pathParam = regexp.MustCompile(`\{([^}:]+)(?::[^}]*)?\}`)
+
+// Build returns the document of the operations. Every operation may fail with a
+// problem response, and needs a bearer token or API key unless it is public.
+func Build(title, version string, ops []Operation) *Document {
+	g := newGenerator()
+	doc := &Document{
+		OpenAPI: Version,
+		Info:    Info{Title: title, Version: version},
+		Servers: []Server{{URL: "/"}},
+		Paths:   make(map[string]PathItem),
+		Components: Components{
+			Schemas: g.schemas,
+			SecuritySchemes: map[string]SecurityScheme{
+				"bearerAuth": {Type: "http", Scheme: "bearer", BearerFormat: "JWT"},
+				"apiKey":     {Type: "apiKey", In: "header", Name: "X-API-Key"},
+			},
+		},
+		Security: []map[string][]string{{"bearerAuth": {}}, {"apiKey": {}}},
+	}
+	problemSchema := g.schema(typeOf(problem.Problem{}))
+
+	for _, op := range ops {
+		path := pathParam.ReplaceAllString(op.Path, "{$1}")
+		o := &OperationObject{
+			Summary:     op.Summary,
+			OperationID: operationID(op.Method, path),
+			Deprecated:  op.Successor != "",
+			Responses:   make(map[string]Response),
+		}
+		if op.Tag != "" {
+			o.Tags = []string{op.Tag}
+		}
+		var notes []string
+		if op.Successor != "" {
+			notes = append(notes, "Deprecated: use "+op.Successor+" instead.")
+		}
+		if op.Permission != "" {
+			notes = append(notes, "Requires the "+op.Permission+" permission.")
+		} else if op.Scope != "" {
+			notes = append(notes, "API keys need the "+op.Scope+" scope.")
+		} else if !op.Public {
+			notes = append(notes, "Not available to API keys.")
+		}
+		o.Description = strings.Join(notes, " ")
+		if op.Public {
+			o.Security = &[]map[string][]string{}
+		}
+
+		for _, m := range pathParam.FindAllStringSubmatch(op.Path, -1) {
+			o.Parameters = append(o.Parameters, Parameter{Name: m[1], In: "path", Required: true, Schema: &Schema{Type: "string"}})
+		}
+		for _, name := range op.Query {
+			o.Parameters = append(o.Parameters, Parameter{Name: name, In: "query", Schema: &Schema{Type: "string"}})
+		}
+
+		if op.Request != nil {
+			o.RequestBody = &RequestBody{
+				Required: !op.Optional,
+				Content:  map[string]MediaType{"application/json": {Schema: g.schema(typeOf(op.Request))}},
+			}
+		}
+		status := op.Status
+		if status == 0 {
+			status = http.StatusOK
+		}
+		resp := Response{Description: http.StatusText(status)}
+		if op.Response != nil {
+			resp.Content = map[string]MediaType{"application/json": {Schema: g.schema(typeOf(op.Response))}}
+		}
+		o.Responses[strconv.Itoa(status)] = resp
+		o.Responses["default"] = Response{
+			Description: "Error",
+			Content:     map[string]MediaType{problem.ContentType: {Schema: problemSchema}},
+		}
+
+		item, ok := doc.Paths[path]
+		if !ok {
+			item = make(PathItem)
+			doc.Paths[path] = item
+		}
+		item[strings.ToLower(op.Method)] = o
+	}
+	return doc
+}
+
+// operationID derives a unique operation ID from the method and path, e.g.
+// post_api_v1_tests_testID_start for POST /api/v1/tests/{testID}:start.
+func operationID(method, path string) string {
+	words := strings.FieldsFunc(path, func(r rune) bool {
+		return !(r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9')
+	})
+	return strings.ToLower(method) + "_" + strings.Join(words, "_")
+}
@@ -0,0 +1,191 @@
+// backend/internal/api/openapi/schema.go
+
+package openapi
+
+import (
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var timeType = reflect.TypeOf(time.Time{})
+
+// typeOf returns the type of a sample value.
+func typeOf(v interface{}) reflect.Type {
+	return reflect.TypeOf(v)
+}
+
+// generator derives schemas from Go types. Named structs become component schemas
+// referenced with $ref, so recursive and shared types are described once.
+type generator struct {
+	schemas map[string]*Schema
+	names   map[reflect.Type]string
+}
+
+func newGenerator() *generator {
+	return &generator{
+		schemas: make(map[string]*Schema),
+		names:   make(map[reflect.Type]string),
+	}
+}
+
+// schema returns the schema of the JSON encoding of values of type t.
+func (g *generator) schema(t reflect.Type) *Schema {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if t == timeType {
+		return &Schema{Type: "string", Format: "date-time"}
+	}
+	switch t.Kind() {
+	case reflect.Bool:
+		return &Schema{Type: "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32:
+		return &Schema{Type: "integer", Format: "int32"}
+	case reflect.Int64, reflect.Uint64:
+		return &Schema{Type: "integer", Format: "int64"}
+	case reflect.Float32, reflect.Float64:
+		return &Schema{Type: "number"}
+	case reflect.String:
+		return &Schema{Type: "string"}
+	case reflect.Slice, reflect.Array:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return &Schema{Type: "string", Format: "byte"}
+		}
+		return &Schema{Type: "array", Items: g.schema(t.Elem())}
+	case reflect.Map:
+		return &Schema{Type: "object", AdditionalProperties: g.schema(t.Elem())}
+	case reflect.Struct:
+		if t.Name() == "" {
+			return g.object(t)
+		}
+		return &Schema{Ref: "#/components/schemas/" + g.component(t)}
+	}
+	// Interfaces and anything else may hold any value.
+	return &Schema{}
+}
+
+// component registers the schema of a named struct and returns its name. Types of
+// different packages with the same name are told apart by the package name.
+func (g *generator) component(t reflect.Type) string {
+	if name, ok := g.names[t]; ok {
+		return name
+	}
+	name := t.Name()
+	if _, taken := g.schemas[name]; taken {
+		pkg := t.PkgPath()
+		pkg = pkg[strings.LastIndex(pkg, "/")+1:]
+		name = strings.ToUpper(pkg[:1]) + pkg[1:] + name
+	}
+	g.names[t] = name
+	g.schemas[name] = &Schema{} // Placeholder while the fields are described
+	*g.schemas[name] = *g.object(t)
+	return name
+}
+
+// object returns the object schema of a struct. Fields follow their json tags,
+// embedded structs are flattened, and fields with a 'required' validation are
+// required.
+func (g *generator) object(t reflect.Type) *Schema {
+	s := &Schema{Type: "object", Properties: make(map[string]*Schema)}
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		tag := f.Tag.Get("json")
+		if tag == "-" || (f.PkgPath != "" && !f.Anonymous) {
+			continue
+		}
+		name := strings.Split(tag, ",")[0]
+		ft := f.Type
+		for ft.Kind() == reflect.Ptr {
+			ft = ft.Elem()
+		}
+		if f.Anonymous && name == "" && ft.Kind() == reflect.Struct {
+			embedded := g.object(ft)
+			for k, v := range embedded.Properties {
+				s.Properties[k] = v
+			}
+			s.Required = append(s.Required, embedded.Required...)
+			continue
+		}
+		if f.PkgPath != "" {
+			continue
+		}
+		if name == "" {
+			name = f.Name
+		}
+
+		fs := g.schema(f.Type)
+		required := applyValidation(fs, f.Tag.Get("validate"))
+		s.Properties[name] = fs
+		if required {
+			s.Required = append(s.Required, name)
+		}
+	}
+	return s
+}
+
+// applyValidation adds the enum and bounds of a validate tag to the schema of a
+// field, applying the rules after 'dive' to the items, and reports whether the
+// field is required.
+func applyValidation(s *Schema, tag string) bool {
+	if tag == "" || s.Ref != "" {
+		return strings.HasPrefix(tag, "required")
+	}
+	required := false
+	target := s
+	for _, rule := range strings.Split(tag, ",") {
+		key, value, _ := strings.Cut(rule, "=")
+		switch key {
+		case "required":
+			if target == s {
+				required = true
+			}
+		case "dive":
+			if target.Items == nil {
+				return required
+			}
+			target = target.Items
+		case "oneof":
+			if target.Type == "string" {
+				target.Enum = splitOneOf(value)
+			}
+		case "min", "max", "gte", "lte":
+			n, err := strconv.ParseFloat(value, 64)
+			if err != nil || (target.Type != "integer" && target.Type != "number") {
+				continue
+			}
+			if key == "min" || key == "gte" {
+				target.Minimum = &n
+			} else {
+				target.Maximum = &n
+			}
+		}
+	}
+	return required
+}
+
+// splitOneOf splits the values of a oneof rule, which are separated by spaces and
+// may be quoted with single quotes.
+func splitOneOf(value string) []string {
+	var values []string
+	for value != "" {
+		value = strings.TrimLeft(value, " ")
+		if strings.HasPrefix(value, "'") {
+			end := strings.Index(value[1:], "'")
+			if end < 0 {
+				values = append(values, value[1:])
+				break
+			}
+			values = append(values, value[1:end+1])
+			value = value[end+2:]
+			continue
+		}
+		word, rest, _ := strings.Cut(value, " ")
+		if word != "" {
+			values = append(values, word)
+		}
+		value = rest
+	}
+	return values
+}
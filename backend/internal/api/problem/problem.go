@@ -0,0 +1,112 @@
+// backend/internal/api/problem/problem.go
+
+// Package problem writes API errors as RFC 7807 problem details
+// (application/problem+json) carrying a stable, machine-readable error code.
+package problem
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/AkshayDubey29/MoniFlux/backend/internal/api/models"
+	"github.com/sirupsen/logrus"
+)
+
+// ContentType is the media type of problem responses.
+const ContentType = "application/problem+json"
+
+// typePrefix prefixes the error code to form the problem type URI.
+const typePrefix = "urn:moniflux:error:"
+
+// Error codes. Codes are part of the API: clients may branch on them, so existing
+// codes are never renamed or reused for another error.
+const (
+	// Generic errors.
+	CodeInvalidRequest   = "invalid_request"    // Malformed body, path or query parameters
+	CodeValidationFailed = "validation_failed"  // Field-level errors are listed in errors
+	CodeUnauthorized     = "unauthorized"       // Missing or invalid credentials
+	CodeForbidden        = "forbidden"          // Authenticated but not allowed
+	CodeNotFound         = "not_found"          // Resource does not exist or is not visible
+	CodeMethodNotAllowed = "method_not_allowed" // Route exists for other methods
+	CodeConflict         = "conflict"           // Resource already exists or is in use
+	CodeRateLimited      = "rate_limited"       // Too many requests
+	CodeInternal         = "internal_error"     // Unexpected server error
+	CodeUpstreamFailed   = "upstream_failed"    // An external service failed
+
+	// Accounts.
+	CodeAccountLocked   = "account_locked"
+	CodeAccountDisabled = "account_disabled"
+	CodeWeakPassword    = "weak_password"
+
+	// Load tests.
+	CodeTestNotFound         = "test_not_found"
+	CodeTestAlreadyExists    = "test_already_exists"
+	CodeTestAlreadyRunning   = "test_already_running"
+	CodeTestAlreadyQueued    = "test_already_queued"
+	CodeTestAlreadyCompleted = "test_already_completed"
+	CodeTestAlreadyCancelled = "test_already_cancelled"
+	CodeInvalidTestState     = "invalid_test_state"
+	CodeInvalidTest          = "invalid_test"
+	CodeInvalidCursor        = "invalid_cursor"
+	CodeGuardrailViolation   = "guardrail_violation" // Violations are listed in violations
+	CodeCapacityExceeded     = "capacity_exceeded"
+	CodeAdmissionQueueFull   = "admission_queue_full"
+	CodePreflightFailed      = "preflight_failed" // The probe report is in report
+
+	// Tenancy, guardrails, secrets and destinations.
+	CodeTeamNotFound           = "team_not_found"
+	CodeProjectNotFound        = "project_not_found"
+	CodeLastTeamOwner          = "last_team_owner"
+	CodePolicyNotFound         = "policy_not_found"
+	CodeInvalidPolicy          = "invalid_policy"
+	CodeSecretNotFound         = "secret_not_found"
+	CodeInvalidSecretRef       = "invalid_secret_ref"
+	CodeSecretStoreDisabled    = "secret_store_disabled"
+	CodeDestinationNotFound    = "destination_not_found"
+	CodeInvalidDestination     = "invalid_destination"
+	CodeDestinationUnsupported = "destination_unsupported"
+	CodeDestinationReadOnly    = "destination_read_only"
+)
+
+// Problem is the body of every API error response.
+type Problem struct {
+	Type       string                   `json:"type"`                 // urn:moniflux:error:<code>
+	Title      string                   `json:"title"`                // HTTP status text
+	Status     int                      `json:"status"`               // HTTP status code
+	Detail     string                   `json:"detail,omitempty"`     // Human-readable explanation of this occurrence
+	Code       string                   `json:"code"`                 // Stable machine-readable error code
+	RequestID  string                   `json:"requestId,omitempty"`  // X-Request-ID of the request, for support
+	Errors     []models.ValidationError `json:"errors,omitempty"`     // Field-level validation errors
+	Violations interface{}              `json:"violations,omitempty"` // Guardrail violations
+	Report     interface{}              `json:"report,omitempty"`     // Destination probe report
+}
+
+// New returns the problem for the status and code.
+func New(status int, code, detail string) *Problem {
+	return &Problem{
+		Type:   typePrefix + code,
+		Title:  http.StatusText(status),
+		Status: status,
+		Detail: detail,
+		Code:   code,
+	}
+}
+
+// Respond writes the problem. The request ID is read from the X-Request-ID response
+// header set by the request ID middleware.
+func Respond(w http.ResponseWriter, p *Problem) {
+	if p.RequestID == "" {
+		p.RequestID = w.Header().Get("X-Request-ID")
+	}
+	w.Header().Set("Content-Type", ContentType)
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.WriteHeader(p.Status)
+	if err := json.NewEncoder(w).Encode(p); err != nil {
+		logrus.Errorf("Failed to encode problem response: %v", err)
+	}
+}
+
+// Error replies to the request with a problem, like http.Error does with plain text.
+func Error(w http.ResponseWriter, status int, code, detail string) {
+	Respond(w, New(status, code, detail))
+}
@@ -1,13 +1,19 @@
 package routers
 
 import (
+	"net/http"
 	"time"
 
 	"github.com/AkshayDubey29/MoniFlux/backend/internal/api/handlers"
 	"github.com/AkshayDubey29/MoniFlux/backend/internal/api/middlewares"
+	"github.com/AkshayDubey29/MoniFlux/backend/internal/api/openapi"
+	"github.com/AkshayDubey29/MoniFlux/backend/internal/api/problem"
 	"github.com/AkshayDubey29/MoniFlux/backend/internal/common"
 	"github.com/AkshayDubey29/MoniFlux/backend/internal/controllers"
+	"github.com/AkshayDubey29/MoniFlux/backend/internal/services/audit"
 	"github.com/AkshayDubey29/MoniFlux/backend/internal/services/authentication"
+	"github.com/AkshayDubey29/MoniFlux/backend/internal/services/authorization"
+	"github.com/AkshayDubey29/MoniFlux/backend/internal/services/monitoring"
 	"github.com/gorilla/mux"
 	"github.com/sirupsen/logrus"
 	"golang.org/x/time/rate"
@@ -18,12 +24,22 @@ import (
 // - logger: Instance of logrus.Logger for logging purposes.
 // - controller: Instance of LoadGenController to handle business logic.
 // - authService: Instance of AuthenticationService to handle authentication.
+// - authzService: Instance of AuthorizationService to enforce per-route permissions.
+// - auditService: Instance of AuditService recording mutating requests.
+// - monitoringService: Instance of MonitoringService running the health checks.
 // - config: Application configuration containing settings for middlewares.
-func SetupRouter(logger *logrus.Logger, controller *controllers.LoadGenController, authService *authentication.AuthenticationService, config *common.Config) *mux.Router {
+func SetupRouter(logger *logrus.Logger, controller *controllers.LoadGenController, authService *authentication.AuthenticationService, authzService *authorization.AuthorizationService, auditService *audit.AuditService, monitoringService *monitoring.MonitoringService, config *common.Config) *mux.Router {
 	router := mux.NewRouter().StrictSlash(true)
+	router.NotFoundHandler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		problem.Error(w, http.StatusNotFound, problem.CodeNotFound, "No route matches "+r.URL.Path)
+	})
+	router.MethodNotAllowedHandler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		problem.Error(w, http.StatusMethodNotAllowed, problem.CodeMethodNotAllowed, r.Method+" is not allowed on "+r.URL.Path)
+	})
 
 	// Initialize middlewares
 	requestIDMiddleware := middlewares.RequestIDMiddleware
+	tracingMiddleware := middlewares.TracingMiddleware
 	recoveryMiddleware := middlewares.RecoveryMiddleware(logger)
 	loggingMiddleware := middlewares.LoggingMiddleware(logger)
 	// Initialize AuthMiddleware with AuthenticationService and logger
@@ -40,6 +56,9 @@ func SetupRouter(logger *logrus.Logger, controller *controllers.LoadGenControlle
 	metrics := middlewares.NewMetrics()
 	metricsMiddleware := metrics.MetricsMiddleware
 
+	// Initialize Audit Middleware
+	auditMiddleware := middlewares.AuditMiddleware(auditService, logger)
+
 	// Initialize Security Headers Middleware
 	securityHeadersMiddleware := middlewares.SecurityHeadersMiddleware
 
@@ -47,58 +66,51 @@ func SetupRouter(logger *logrus.Logger, controller *controllers.LoadGenControlle
 	// 1. Recovery (to catch panics)
 	// 2. Logging
 	// 3. Request ID
-	// 4. Security Headers
-	// 5. CORS
-	// 6. Rate Limiting
-	// 7. Metrics
+	// 4. Tracing (the span records the request ID)
+	// 5. Security Headers
+	// 6. CORS
+	// 7. Rate Limiting
+	// 8. Metrics
+	// 9. Audit (the actor is filled in by the authentication middleware)
 	router.Use(recoveryMiddleware)
 	router.Use(loggingMiddleware)
 	router.Use(requestIDMiddleware)
+	router.Use(tracingMiddleware)
 	router.Use(securityHeadersMiddleware)
 	router.Use(corsMiddleware)
 	router.Use(rateLimitMiddleware)
 	router.Use(metricsMiddleware)
+	router.Use(auditMiddleware)
 
-	// Apply authentication middleware to all routes except /health
+	// Apply authentication middleware to all routes except the health checks
 	apiRouter := router.PathPrefix("/").Subrouter()
 	apiRouter.Use(authMiddleware)
 
-	// Initialize handlers with dependencies
-	h := handlers.NewHandler(controller, authService, logger)
-
-	// Define API routes with their respective handlers
-	apiRouter.HandleFunc("/start-test", h.StartTest).Methods("POST")
-	logger.Infof("Registered POST /start-test endpoint")
-
-	apiRouter.HandleFunc("/schedule-test", h.ScheduleTest).Methods("POST")
-	logger.Infof("Registered POST /schedule-test endpoint")
-
-	apiRouter.HandleFunc("/create-test", h.CreateTest).Methods("POST")
-	logger.Infof("Registered POST /create-test endpoint")
-
-	apiRouter.HandleFunc("/cancel-test", h.CancelTest).Methods("POST")
-	logger.Infof("Registered POST /cancel-test endpoint")
+	// requirePermission wraps a handler so that it only runs for users holding the permission.
+	requirePermission := func(permission string, handler http.HandlerFunc) http.Handler {
+		return authorization.NewAuthorizationMiddleware(authzService, logger, []string{permission}).MiddlewareFunc(handler)
+	}
 
-	apiRouter.HandleFunc("/restart-test", h.RestartTest).Methods("POST")
-	logger.Infof("Registered POST /restart-test endpoint")
-
-	apiRouter.HandleFunc("/save-results", h.SaveResults).Methods("POST")
-	logger.Infof("Registered POST /save-results endpoint")
-
-	apiRouter.HandleFunc("/get-all-tests", h.GetAllTests).Methods("GET")
-	logger.Infof("Registered GET /get-all-tests endpoint")
-
-	// User registration endpoint
-	router.HandleFunc("/register", h.RegisterUser).Methods("POST")
-	logger.Infof("Registered POST /register endpoint")
-
-	// Authentication endpoint
-	router.HandleFunc("/authenticate", h.AuthenticateUser).Methods("POST")
-	logger.Infof("Registered POST /authenticate endpoint")
-
-	// Health Check Endpoint (Unprotected)
-	router.HandleFunc("/health", handlers.HealthCheck).Methods("GET")
-	logger.Infof("Registered GET /health endpoint")
+	// Initialize handlers with dependencies
+	hs := routeHandlers{
+		tests:        handlers.NewHandler(controller, authService, logger),
+		tenancy:      handlers.NewTenancyHandler(controller.Tenancy, logger),
+		apiKeys:      handlers.NewAPIKeyHandler(authService, authzService, logger),
+		admin:        handlers.NewAdminHandler(authzService, logger),
+		guardrails:   handlers.NewGuardrailHandler(controller.Guardrails, controller.Tenancy, logger),
+		destinations: handlers.NewDestinationHandler(controller.Destinations, controller.Tenancy, logger),
+		secrets:      handlers.NewSecretHandler(controller.Secrets, controller.Tenancy, logger),
+		audit:        handlers.NewAuditHandler(auditService, logger),
+		monitoring:   monitoring.NewMonitoringHandlers(monitoringService, logger),
+	}
+
+	// Register the /api/v1 routes, the deprecated unversioned aliases and the OpenAPI
+	// document describing them all
+	routes := serverRoutes(hs)
+	routes = append(routes, OpenAPIRoute(func() *openapi.Document {
+		return BuildOpenAPI("MoniFlux API", routes)
+	}))
+	Register(router, apiRouter, routes, requirePermission, logger)
 
 	// Metrics Endpoint (Protected or Unprotected based on your needs)
 	router.Handle("/metrics", metrics.ExposeMetricsHandler()).Methods("GET")
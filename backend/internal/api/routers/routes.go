@@ -0,0 +1,368 @@
+// backend/internal/api/routers/routes.go
+
+package routers
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+	"sync"
+
+	"github.com/AkshayDubey29/MoniFlux/backend/internal/api/handlers"
+	"github.com/AkshayDubey29/MoniFlux/backend/internal/api/models"
+	"github.com/AkshayDubey29/MoniFlux/backend/internal/api/openapi"
+	"github.com/AkshayDubey29/MoniFlux/backend/internal/controllers"
+	"github.com/AkshayDubey29/MoniFlux/backend/internal/services/audit"
+	"github.com/AkshayDubey29/MoniFlux/backend/internal/services/authentication"
+	"github.com/AkshayDubey29/MoniFlux/backend/internal/services/authorization"
+	"github.com/AkshayDubey29/MoniFlux/backend/internal/services/destinations"
+	"github.com/AkshayDubey29/MoniFlux/backend/internal/services/guardrails"
+	"github.com/AkshayDubey29/MoniFlux/backend/internal/services/monitoring"
+	"github.com/AkshayDubey29/MoniFlux/backend/internal/services/secrets"
+	"github.com/AkshayDubey29/MoniFlux/backend/internal/services/tenancy"
+	"github.com/gorilla/mux"
+	"github.com/sirupsen/logrus"
+)
+
+// APIPrefix prefixes the paths of the current version of the API.
+const APIPrefix = "/api/v1"
+
+// APIVersion is the version of the API in the OpenAPI document.
+const APIVersion = "1.0.0"
+
+// Route is an endpoint of the API. The routes are both registered on the router and
+// described by the OpenAPI document, so the two cannot drift apart.
+type Route struct {
+	Method     string           // HTTP method
+	Path       string           // gorilla/mux path template
+	Summary    string           // One-line description for the OpenAPI document
+	Tag        string           // Group of the route in the OpenAPI document
+	Permission string           // Permission required besides authentication, if any
+	Scope      string           // API key scope allowing a route without Permission; keys are rejected when empty
+	Public     bool             // Served without authentication
+	Alias      bool             // Also served, deprecated, at the path without the APIPrefix
+	Successor  string           // Path replacing the route; set on deprecated routes
+	Request    interface{}      // Sample of the JSON request body, for the OpenAPI document
+	Optional   bool             // The request body may be omitted
+	Response   interface{}      // Sample of the JSON response body, for the OpenAPI document
+	Status     int              // Status of a successful response; 200 when zero
+	Query      []string         // Query parameters
+	Handler    http.HandlerFunc // Serves the route
+}
+
+// routeHandlers holds the handlers serving the routes of the API server.
+type routeHandlers struct {
+	tests        *handlers.Handler
+	tenancy      *handlers.TenancyHandler
+	apiKeys      *handlers.APIKeyHandler
+	admin        *handlers.AdminHandler
+	guardrails   *handlers.GuardrailHandler
+	destinations *handlers.DestinationHandler
+	secrets      *handlers.SecretHandler
+	audit        *handlers.AuditHandler
+	monitoring   *monitoring.MonitoringHandlers
+}
+
+// testListQuery lists the query parameters of test listings.
+var testListQuery = []string{"status", "userID", "teamID", "projectID", "destinationType", "tags", "createdAfter", "createdBefore", "updatedAfter", "updatedBefore", "sortBy", "sortOrder", "limit", "cursor"}
+
+// TestRoutes returns the routes of the load test resource, which the API server and
+// the load generator both serve.
+func TestRoutes(h *handlers.Handler) []Route {
+	const tests = APIPrefix + "/tests"
+	const test = tests + "/{testID}"
+	return []Route{
+		{Method: "POST", Path: tests, Summary: "Create a test without starting it", Tag: "tests", Permission: authorization.PermTestsCreate,
+			Request: models.Test{}, Response: models.Test{}, Status: http.StatusCreated, Handler: h.CreateTest},
+		{Method: "GET", Path: tests, Summary: "List tests", Tag: "tests", Scope: authorization.PermTestsRead,
+			Response: models.TestPage{}, Query: testListQuery, Handler: h.GetAllTests},
+		{Method: "POST", Path: tests + ":validate", Summary: "Check a test against the guardrails without starting it", Tag: "tests", Permission: authorization.PermTestsStart,
+			Request: models.Test{}, Response: guardrails.Report{}, Handler: h.ValidateTest},
+		{Method: "GET", Path: test, Summary: "Get a test", Tag: "tests", Scope: authorization.PermTestsRead,
+			Response: models.Test{}, Handler: h.GetTestByID},
+		{Method: "PATCH", Path: test, Summary: "Change the configuration of a test that is not running or queued", Tag: "tests", Permission: authorization.PermTestsCreate,
+			Request: models.Test{}, Response: models.Test{}, Handler: h.UpdateTestByID},
+		{Method: "DELETE", Path: test, Summary: "Delete a test and its results", Tag: "tests", Permission: authorization.PermTestsCancel,
+			Status: http.StatusNoContent, Handler: h.DeleteTestByID},
+		{Method: "POST", Path: test + ":start", Summary: "Start a test, after applying the changes in the body", Tag: "tests", Permission: authorization.PermTestsStart,
+			Request: models.Test{}, Optional: true, Response: models.Test{}, Handler: h.StartTestByID},
+		{Method: "POST", Path: test + ":cancel", Summary: "Cancel a running, queued or scheduled test", Tag: "tests", Permission: authorization.PermTestsCancel,
+			Response: map[string]string{}, Handler: h.CancelTestByID},
+		{Method: "POST", Path: test + ":restart", Summary: "Restart a test with a new duration and rates", Tag: "tests", Permission: authorization.PermTestsStart,
+			Request: models.RestartRequest{}, Response: map[string]string{}, Handler: h.RestartTestByID},
+		{Method: "POST", Path: test + ":schedule", Summary: "Schedule a test", Tag: "tests", Permission: authorization.PermTestsStart,
+			Request: models.ScheduleRequest{}, Response: models.ScheduleRequest{}, Handler: h.ScheduleTestByID},
+		{Method: "POST", Path: test + "/results", Summary: "Save the results of a test", Tag: "tests", Permission: authorization.PermResultsWrite,
+			Request: models.TestResults{}, Response: models.TestResults{}, Handler: h.SaveResultsByID},
+		{Method: "GET", Path: test + "/results/export", Summary: "Export the per-second statistics of the runs of a test", Tag: "tests", Scope: authorization.PermTestsRead, Alias: true,
+			Query: []string{"format"}, Handler: h.ExportResults},
+		{Method: "POST", Path: APIPrefix + "/destinations/probe", Summary: "Probe a destination", Tag: "destinations", Permission: authorization.PermTestsStart, Alias: true,
+			Request: models.Test{}.Destination, Response: models.ProbeReport{}, Query: []string{"teamID", "projectID"}, Handler: h.ProbeDestination},
+	}
+}
+
+// legacyTestRoutes returns the verb-named test endpoints the API server served
+// before the test resource, which take the test ID in the body.
+func legacyTestRoutes(h *handlers.Handler) []Route {
+	const test = APIPrefix + "/tests/{testID}"
+	return []Route{
+		{Method: "POST", Path: "/start-test", Summary: "Create and start a test", Tag: "tests", Permission: authorization.PermTestsStart,
+			Successor: test + ":start", Request: models.Test{}, Response: models.Test{}, Status: http.StatusCreated, Handler: h.StartTest},
+		{Method: "POST", Path: "/schedule-test", Summary: "Schedule a test", Tag: "tests", Permission: authorization.PermTestsStart,
+			Successor: test + ":schedule", Request: models.ScheduleRequest{}, Response: models.ScheduleRequest{}, Handler: h.ScheduleTest},
+		{Method: "POST", Path: "/create-test", Summary: "Create a test without starting it", Tag: "tests", Permission: authorization.PermTestsCreate,
+			Successor: APIPrefix + "/tests", Request: models.Test{}, Response: models.Test{}, Status: http.StatusCreated, Handler: h.CreateTest},
+		{Method: "POST", Path: "/cancel-test", Summary: "Cancel a test", Tag: "tests", Permission: authorization.PermTestsCancel,
+			Successor: test + ":cancel", Request: models.CancelRequest{}, Response: map[string]string{}, Handler: h.CancelTest},
+		{Method: "POST", Path: "/restart-test", Summary: "Restart a test", Tag: "tests", Permission: authorization.PermTestsStart,
+			Successor: test + ":restart", Request: models.RestartRequest{}, Response: map[string]string{}, Handler: h.RestartTest},
+		{Method: "POST", Path: "/save-results", Summary: "Save the results of a test", Tag: "tests", Permission: authorization.PermResultsWrite,
+			Successor: test + "/results", Request: models.TestResults{}, Response: models.TestResults{}, Handler: h.SaveResults},
+		{Method: "POST", Path: "/validate-test", Summary: "Check a test against the guardrails", Tag: "tests", Permission: authorization.PermTestsStart,
+			Successor: APIPrefix + "/tests:validate", Request: models.Test{}, Response: guardrails.Report{}, Handler: h.ValidateTest},
+		{Method: "GET", Path: "/get-all-tests", Summary: "List tests", Tag: "tests", Scope: authorization.PermTestsRead,
+			Successor: APIPrefix + "/tests", Response: models.TestPage{}, Query: testListQuery, Handler: h.GetAllTests},
+	}
+}
+
+// apiRoutes returns the routes of the API server other than the load test resource.
+func apiRoutes(hs routeHandlers) []Route {
+	h := hs.tests
+	return []Route{
+		{Method: "GET", Path: APIPrefix + "/admission", Summary: "Get the capacity in use and the admission queue", Tag: "tests", Scope: authorization.PermTestsRead, Alias: true,
+			Response: controllers.AdmissionStatus{}, Handler: h.GetAdmissionStatus},
+
+		// Team and project (tenant) management
+		{Method: "POST", Path: APIPrefix + "/teams", Summary: "Create a team", Tag: "teams", Alias: true,
+			Response: tenancy.Team{}, Status: http.StatusCreated, Handler: hs.tenancy.CreateTeam},
+		{Method: "GET", Path: APIPrefix + "/teams", Summary: "List the teams of the user", Tag: "teams", Alias: true,
+			Response: []tenancy.Team{}, Handler: hs.tenancy.ListTeams},
+		{Method: "POST", Path: APIPrefix + "/teams/{teamID}/members", Summary: "Add or update a team member", Tag: "teams", Alias: true,
+			Request: tenancy.TeamMember{}, Response: tenancy.TeamMember{}, Handler: hs.tenancy.AddTeamMember},
+		{Method: "DELETE", Path: APIPrefix + "/teams/{teamID}/members/{userID}", Summary: "Remove a team member", Tag: "teams", Alias: true,
+			Handler: hs.tenancy.RemoveTeamMember},
+		{Method: "POST", Path: APIPrefix + "/teams/{teamID}/projects", Summary: "Create a project", Tag: "teams", Alias: true,
+			Request: tenancy.Project{}, Response: tenancy.Project{}, Status: http.StatusCreated, Handler: hs.tenancy.CreateProject},
+		{Method: "GET", Path: APIPrefix + "/teams/{teamID}/projects", Summary: "List the projects of a team", Tag: "teams", Alias: true,
+			Response: []tenancy.Project{}, Handler: hs.tenancy.ListProjects},
+
+		// Sessions and account self-service
+		{Method: "POST", Path: APIPrefix + "/logout", Summary: "Revoke the access token", Tag: "accounts", Alias: true,
+			Handler: h.Logout},
+		{Method: "GET", Path: APIPrefix + "/users/me", Summary: "Get the profile of the user", Tag: "accounts", Alias: true,
+			Response: models.UserProfile{}, Handler: h.GetCurrentUser},
+		{Method: "PATCH", Path: APIPrefix + "/users/me", Summary: "Update the profile of the user", Tag: "accounts", Alias: true,
+			Response: models.UserProfile{}, Handler: h.UpdateCurrentUser},
+		{Method: "POST", Path: APIPrefix + "/users/me/password", Summary: "Change the password of the user", Tag: "accounts", Alias: true,
+			Handler: h.ChangePassword},
+		{Method: "POST", Path: APIPrefix + "/users/me/verify-email", Summary: "Send an email verification link", Tag: "accounts", Alias: true,
+			Status: http.StatusAccepted, Handler: h.RequestEmailVerification},
+
+		// API keys (service account tokens)
+		{Method: "POST", Path: APIPrefix + "/api-keys", Summary: "Create an API key", Tag: "api-keys", Alias: true,
+			Status: http.StatusCreated, Handler: hs.apiKeys.CreateAPIKey},
+		{Method: "GET", Path: APIPrefix + "/api-keys", Summary: "List the API keys of the user", Tag: "api-keys", Alias: true,
+			Response: []models.APIKey{}, Handler: hs.apiKeys.ListAPIKeys},
+		{Method: "DELETE", Path: APIPrefix + "/api-keys/{keyID}", Summary: "Revoke an API key", Tag: "api-keys", Alias: true,
+			Handler: hs.apiKeys.RevokeAPIKey},
+
+		// Roles, permissions and user administration
+		{Method: "GET", Path: APIPrefix + "/admin/permissions", Summary: "List permissions", Tag: "admin", Permission: authorization.PermUsersAdmin, Alias: true,
+			Response: []authorization.Permission{}, Handler: hs.admin.ListPermissions},
+		{Method: "POST", Path: APIPrefix + "/admin/permissions", Summary: "Create a permission", Tag: "admin", Permission: authorization.PermUsersAdmin, Alias: true,
+			Response: authorization.Permission{}, Status: http.StatusCreated, Handler: hs.admin.CreatePermission},
+		{Method: "GET", Path: APIPrefix + "/admin/roles", Summary: "List roles with their permissions", Tag: "admin", Permission: authorization.PermUsersAdmin, Alias: true,
+			Response: []authorization.RoleView{}, Handler: hs.admin.ListRoles},
+		{Method: "POST", Path: APIPrefix + "/admin/roles", Summary: "Create a role", Tag: "admin", Permission: authorization.PermUsersAdmin, Alias: true,
+			Status: http.StatusCreated, Handler: hs.admin.CreateRole},
+		{Method: "PUT", Path: APIPrefix + "/admin/roles/{role}/permissions", Summary: "Set the permissions of a role", Tag: "admin", Permission: authorization.PermUsersAdmin, Alias: true,
+			Handler: hs.admin.SetRolePermissions},
+		{Method: "DELETE", Path: APIPrefix + "/admin/roles/{role}", Summary: "Delete a role", Tag: "admin", Permission: authorization.PermUsersAdmin, Alias: true,
+			Handler: hs.admin.DeleteRole},
+		{Method: "POST", Path: APIPrefix + "/admin/users/{userID}/roles", Summary: "Assign a role to a user", Tag: "admin", Permission: authorization.PermUsersAdmin, Alias: true,
+			Handler: hs.admin.AssignUserRole},
+		{Method: "DELETE", Path: APIPrefix + "/admin/users/{userID}/roles/{role}", Summary: "Remove a role from a user", Tag: "admin", Permission: authorization.PermUsersAdmin, Alias: true,
+			Handler: hs.admin.RemoveUserRole},
+		{Method: "POST", Path: APIPrefix + "/admin/users/{userID}/password-reset", Summary: "Send a password reset link to a user", Tag: "admin", Permission: authorization.PermUsersAdmin, Alias: true,
+			Status: http.StatusAccepted, Handler: h.AdminResetPassword},
+		{Method: "POST", Path: APIPrefix + "/admin/users/{userID}/disable", Summary: "Disable a user", Tag: "admin", Permission: authorization.PermUsersAdmin, Alias: true,
+			Handler: h.DisableUser},
+		{Method: "POST", Path: APIPrefix + "/admin/users/{userID}/enable", Summary: "Enable a user", Tag: "admin", Permission: authorization.PermUsersAdmin, Alias: true,
+			Handler: h.EnableUser},
+
+		// Guardrail policies
+		{Method: "GET", Path: APIPrefix + "/admin/guardrails", Summary: "Get the global guardrail policy", Tag: "guardrails", Permission: authorization.PermGuardrails, Alias: true,
+			Response: guardrails.Policy{}, Handler: hs.guardrails.GetPolicy},
+		{Method: "PUT", Path: APIPrefix + "/admin/guardrails", Summary: "Set the global guardrail policy", Tag: "guardrails", Permission: authorization.PermGuardrails, Alias: true,
+			Request: guardrails.Policy{}, Response: guardrails.Policy{}, Handler: hs.guardrails.SetPolicy},
+		{Method: "DELETE", Path: APIPrefix + "/admin/guardrails", Summary: "Reset the global guardrail policy", Tag: "guardrails", Permission: authorization.PermGuardrails, Alias: true,
+			Handler: hs.guardrails.DeletePolicy},
+		{Method: "GET", Path: APIPrefix + "/admin/teams/{teamID}/guardrails", Summary: "Get the guardrail policy of a team", Tag: "guardrails", Permission: authorization.PermGuardrails, Alias: true,
+			Response: guardrails.Policy{}, Handler: hs.guardrails.GetPolicy},
+		{Method: "PUT", Path: APIPrefix + "/admin/teams/{teamID}/guardrails", Summary: "Set the guardrail policy of a team", Tag: "guardrails", Permission: authorization.PermGuardrails, Alias: true,
+			Request: guardrails.Policy{}, Response: guardrails.Policy{}, Handler: hs.guardrails.SetPolicy},
+		{Method: "DELETE", Path: APIPrefix + "/admin/teams/{teamID}/guardrails", Summary: "Delete the guardrail policy of a team", Tag: "guardrails", Permission: authorization.PermGuardrails, Alias: true,
+			Handler: hs.guardrails.DeletePolicy},
+
+		// Destination registry; tests reference the destinations by name
+		{Method: "GET", Path: APIPrefix + "/destinations", Summary: "List registered destinations", Tag: "destinations", Alias: true,
+			Response: []destinations.RegisteredDestination{}, Handler: hs.destinations.ListDestinations},
+		{Method: "GET", Path: APIPrefix + "/destinations/{name}", Summary: "Get a registered destination", Tag: "destinations", Alias: true,
+			Response: destinations.RegisteredDestination{}, Handler: hs.destinations.GetDestination},
+		{Method: "PUT", Path: APIPrefix + "/destinations/{name}", Summary: "Register or replace a destination", Tag: "destinations", Permission: authorization.PermDestinations, Alias: true,
+			Request: destinations.DestinationRequest{}, Response: destinations.RegisteredDestination{}, Handler: hs.destinations.PutDestination},
+		{Method: "DELETE", Path: APIPrefix + "/destinations/{name}", Summary: "Delete a registered destination", Tag: "destinations", Permission: authorization.PermDestinations, Alias: true,
+			Handler: hs.destinations.DeleteDestination},
+
+		// Secret store; values are write-only
+		{Method: "GET", Path: APIPrefix + "/secrets", Summary: "List the metadata of the secrets", Tag: "secrets", Permission: authorization.PermSecrets, Alias: true,
+			Response: []secrets.Secret{}, Handler: hs.secrets.ListSecrets},
+		{Method: "GET", Path: APIPrefix + "/secrets/{name}", Summary: "Get the metadata of a secret", Tag: "secrets", Permission: authorization.PermSecrets, Alias: true,
+			Response: secrets.Secret{}, Handler: hs.secrets.GetSecret},
+		{Method: "PUT", Path: APIPrefix + "/secrets/{name}", Summary: "Store a secret", Tag: "secrets", Permission: authorization.PermSecrets, Alias: true,
+			Request: secrets.SecretRequest{}, Response: secrets.Secret{}, Handler: hs.secrets.PutSecret},
+		{Method: "DELETE", Path: APIPrefix + "/secrets/{name}", Summary: "Delete a secret", Tag: "secrets", Permission: authorization.PermSecrets, Alias: true,
+			Handler: hs.secrets.DeleteSecret},
+
+		// Audit log
+		{Method: "GET", Path: APIPrefix + "/audit", Summary: "Search the audit log", Tag: "audit", Permission: authorization.PermAuditRead, Alias: true,
+			Response: audit.EventPage{}, Query: []string{"userID", "action", "targetID", "requestID", "outcome", "since", "until", "limit", "cursor"}, Handler: hs.audit.ListAuditEvents},
+
+		// Health check history and latest results
+		{Method: "GET", Path: APIPrefix + "/health/history", Summary: "Get the recorded health check results", Tag: "health", Alias: true,
+			Query: []string{"service", "since", "limit"}, Handler: hs.monitoring.GetHealthCheckHistoryHandler},
+		{Method: "GET", Path: APIPrefix + "/health/status", Summary: "Get the latest result of each health check", Tag: "health", Alias: true,
+			Handler: hs.monitoring.HealthCheckStatusHandler},
+
+		// Registration and authentication
+		{Method: "POST", Path: APIPrefix + "/register", Summary: "Register a user", Tag: "accounts", Public: true, Alias: true,
+			Status: http.StatusCreated, Handler: h.RegisterUser},
+		{Method: "POST", Path: APIPrefix + "/authenticate", Summary: "Log in with a username and password", Tag: "accounts", Public: true, Alias: true,
+			Response: authentication.TokenPair{}, Handler: h.AuthenticateUser},
+		{Method: "POST", Path: APIPrefix + "/password-reset", Summary: "Reset a password with an emailed token", Tag: "accounts", Public: true, Alias: true,
+			Handler: h.ResetPassword},
+		{Method: "POST", Path: APIPrefix + "/verify-email", Summary: "Verify an email address with an emailed token", Tag: "accounts", Public: true, Alias: true,
+			Handler: h.VerifyEmail},
+		{Method: "POST", Path: APIPrefix + "/token/refresh", Summary: "Exchange a refresh token for new tokens", Tag: "accounts", Public: true, Alias: true,
+			Response: authentication.TokenPair{}, Handler: h.RefreshToken},
+		{Method: "GET", Path: APIPrefix + "/auth/oidc/login", Summary: "Start single sign-on", Tag: "accounts", Public: true, Alias: true,
+			Status: http.StatusFound, Handler: h.OIDCLogin},
+		{Method: "GET", Path: APIPrefix + "/auth/oidc/callback", Summary: "Complete single sign-on", Tag: "accounts", Public: true, Alias: true,
+			Response: authentication.TokenPair{}, Query: []string{"state", "code"}, Handler: h.OIDCCallback},
+
+		// Liveness and readiness; /health is kept as a liveness alias. Probes are not versioned.
+		{Method: "GET", Path: "/livez", Summary: "Liveness probe", Tag: "health", Public: true, Handler: hs.monitoring.LivezHandler},
+		{Method: "GET", Path: "/health", Summary: "Liveness probe", Tag: "health", Public: true, Handler: hs.monitoring.LivezHandler},
+		{Method: "GET", Path: "/readyz", Summary: "Readiness probe running the dependency checks", Tag: "health", Public: true, Handler: hs.monitoring.ReadyzHandler},
+	}
+}
+
+// serverRoutes returns all the routes of the API server, without the OpenAPI document.
+func serverRoutes(hs routeHandlers) []Route {
+	routes := TestRoutes(hs.tests)
+	routes = append(routes, legacyTestRoutes(hs.tests)...)
+	return append(routes, apiRoutes(hs)...)
+}
+
+// OpenAPIDocument returns the OpenAPI document of the API server.
+func OpenAPIDocument() *openapi.Document {
+	// Method values of nil handlers are only called when serving requests.
+	routes := serverRoutes(routeHandlers{})
+	return BuildOpenAPI("MoniFlux API", append(routes, OpenAPIRoute(nil)))
+}
+
+// BuildOpenAPI returns the OpenAPI document of the routes, including their
+// deprecated aliases.
+func BuildOpenAPI(title string, routes []Route) *openapi.Document {
+	routes = withAliases(routes)
+	ops := make([]openapi.Operation, len(routes))
+	for i, rt := range routes {
+		ops[i] = openapi.Operation{
+			Method:     rt.Method,
+			Path:       rt.Path,
+			Summary:    rt.Summary,
+			Tag:        rt.Tag,
+			Permission: rt.Permission,
+			Scope:      rt.Scope,
+			Public:     rt.Public,
+			Successor:  rt.Successor,
+			Request:    rt.Request,
+			Optional:   rt.Optional,
+			Response:   rt.Response,
+			Status:     rt.Status,
+			Query:      rt.Query,
+		}
+	}
+	return openapi.Build(title, APIVersion, ops)
+}
+
+// OpenAPIRoute returns the route serving the OpenAPI document. The document is
+// encoded once, on the first request.
+func OpenAPIRoute(doc func() *openapi.Document) Route {
+	var once sync.Once
+	var body []byte
+	return Route{
+		Method: "GET", Path: APIPrefix + "/openapi.json", Summary: "Get the OpenAPI document of the API", Tag: "meta", Public: true,
+		Response: map[string]interface{}{},
+		Handler: func(w http.ResponseWriter, r *http.Request) {
+			once.Do(func() { body, _ = json.Marshal(doc()) })
+			w.Header().Set("Content-Type", "application/json")
+			w.Write(body)
+		},
+	}
+}
+
+// withAliases returns the routes followed by the deprecated aliases of the routes
+// that have one.
+func withAliases(routes []Route) []Route {
+	all := append([]Route(nil), routes...)
+	for _, rt := range routes {
+		if rt.Alias && strings.HasPrefix(rt.Path, APIPrefix+"/") {
+			alias := rt
+			alias.Path, alias.Successor, alias.Alias = strings.TrimPrefix(rt.Path, APIPrefix), rt.Path, false
+			all = append(all, alias)
+		}
+	}
+	return all
+}
+
+// Register adds the routes and their deprecated aliases to the routers: public
+// routes to public, the others to protected. Routes requiring a permission are
+// wrapped by authorize, when given; other protected routes only admit the API keys
+// holding their scope. Deprecated routes announce their successor in the
+// Deprecation and Link response headers.
+func Register(public, protected *mux.Router, routes []Route, authorize func(permission string, handler http.HandlerFunc) http.Handler, logger *logrus.Logger) {
+	for _, rt := range withAliases(routes) {
+		var handler http.Handler = rt.Handler
+		switch {
+		case rt.Permission != "":
+			if authorize != nil {
+				handler = authorize(rt.Permission, rt.Handler)
+			}
+		case !rt.Public:
+			handler = authorization.RequireScope(rt.Scope, logger, rt.Handler)
+		}
+		if rt.Successor != "" {
+			handler = deprecated(rt.Successor, handler)
+		}
+		router := protected
+		if rt.Public {
+			router = public
+		}
+		router.Handle(rt.Path, handler).Methods(rt.Method)
+		if rt.Successor != "" {
+			logger.Infof("Registered %s %s endpoint (deprecated, use %s)", rt.Method, rt.Path, rt.Successor)
+		} else {
+			logger.Infof("Registered %s %s endpoint", rt.Method, rt.Path)
+		}
+	}
+}
+
+// deprecated marks the responses of a deprecated route, linking to its successor.
+func deprecated(successor string, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Deprecation", "true")
+		w.Header().Set("Link", "<"+successor+">; rel=\"successor-version\"")
+		next.ServeHTTP(w, r)
+	})
+}
@@ -3,6 +3,7 @@
 package common
 
 import (
+	"fmt"
 	"time"
 
 	"go.mongodb.org/mongo-driver/bson/primitive"
@@ -19,23 +20,254 @@ type Metrics struct {
 	PrometheusEnabled  bool   `mapstructure:"prometheus_enabled" json:"prometheusEnabled" bson:"prometheusEnabled"`
 	PrometheusEndpoint string `mapstructure:"prometheus_endpoint" json:"prometheusEndpoint" bson:"prometheusEndpoint" validate:"required_if=PrometheusEnabled true,url"`
 	PrometheusPort     int    `mapstructure:"prometheus_port" json:"prometheusPort" bson:"prometheusPort" validate:"required_if=PrometheusEnabled true,min=1,max=65535"`
+	MaxTestIDs         int    `mapstructure:"max_test_ids" json:"maxTestIDs" bson:"maxTestIDs" validate:"min=0"` // Tests labelled with their ID in engine metrics; 0 for no limit
+}
+
+// Tracing defines the OpenTelemetry export of the spans of the API, MongoDB commands
+// and deliveries.
+type Tracing struct {
+	Exporter    string            `mapstructure:"exporter" json:"exporter" bson:"exporter" validate:"omitempty,oneof=otlp stdout none"` // none records no spans, but still passes traceparent on
+	Endpoint    string            `mapstructure:"endpoint" json:"endpoint" bson:"endpoint" validate:"omitempty,url"`                    // OTLP/HTTP endpoint, e.g. http://otel-collector:4318
+	Headers     map[string]string `mapstructure:"headers" json:"headers" bson:"headers"`                                                // Headers of OTLP requests, e.g. for authentication
+	SampleRatio float64           `mapstructure:"sample_ratio" json:"sampleRatio" bson:"sampleRatio" validate:"min=0,max=1"`            // Fraction of new traces recorded; spans of a sampled trace are always recorded
 }
 
 // Monitoring defines the structure for monitoring configurations.
 type Monitoring struct {
-	HealthCheckInterval string `mapstructure:"health_check_interval" json:"healthCheckInterval" bson:"healthCheckInterval" validate:"required,nonzero"`
+	HealthCheckInterval  string   `mapstructure:"health_check_interval" json:"healthCheckInterval" bson:"healthCheckInterval" validate:"required,nonzero"`
+	HealthCheckRetention string   `mapstructure:"health_check_retention" json:"healthCheckRetention" bson:"healthCheckRetention"`                    // How long health_checks records are kept
+	HealthCheckTimeout   string   `mapstructure:"health_check_timeout" json:"healthCheckTimeout" bson:"healthCheckTimeout"`                          // Of each dependency check
+	SaturationThreshold  float64  `mapstructure:"saturation_threshold" json:"saturationThreshold" bson:"saturationThreshold" validate:"min=0,max=1"` // Fraction of a test's job queue in use above which the workers are saturated
+	ReadinessChecks      []string `mapstructure:"readiness_checks" json:"readinessChecks" bson:"readinessChecks"`                                    // Checks that must pass for /readyz to succeed
 }
 
 // Destination represents where the payloads are delivered.
 type Destination struct {
-	Type      string `mapstructure:"type" json:"type" bson:"type" validate:"required,oneof=http file"`
-	Name      string `mapstructure:"name" json:"name" bson:"name"`
-	Endpoint  string `mapstructure:"endpoint" json:"endpoint" bson:"endpoint" validate:"omitempty,required_if=Type http,url"`
-	Port      int    `mapstructure:"port" json:"port" bson:"port" validate:"omitempty,required_if=Type http,min=1,max=65535"`
-	APIKey    string `mapstructure:"api_key" json:"apiKey" bson:"apiKey" validate:"omitempty"`
-	FilePath  string `mapstructure:"file_path" json:"filePath" bson:"filePath" validate:"omitempty,required_if=Type file"`
-	FileCount int    `mapstructure:"file_count" json:"fileCount" bson:"fileCount" validate:"omitempty,required_if=Type file,min=1"`
-	FileFreq  int    `mapstructure:"file_freq" json:"fileFreq" bson:"fileFreq" validate:"omitempty,required_if=Type file,min=1"` // Frequency in minutes
+	Type            string           `mapstructure:"type" json:"type" bson:"type" validate:"required,oneof=http file syslog tcp udp s3"`
+	Name            string           `mapstructure:"name" json:"name" bson:"name"`
+	Endpoint        string           `mapstructure:"endpoint" json:"endpoint" bson:"endpoint" validate:"omitempty,required_if=Type http,url|hostname_port|hostname|ip"` // URL, or host[:port] for socket destinations
+	Port            int              `mapstructure:"port" json:"port" bson:"port" validate:"omitempty,required_if=Type http,min=1,max=65535"`
+	APIKey          string           `mapstructure:"api_key" json:"apiKey,omitempty" bson:"apiKey,omitempty" validate:"omitempty"` // Deprecated: moved into the secret store on start; use SecretRef
+	SecretRef       string           `mapstructure:"secret_ref" json:"secretRef,omitempty" bson:"secretRef,omitempty"`             // Secret holding the API key: "name", "env:name" or "file:name"
+	FilePath        string           `mapstructure:"file_path" json:"filePath" bson:"filePath" validate:"omitempty,required_if=Type file"`
+	FileCount       int              `mapstructure:"file_count" json:"fileCount" bson:"fileCount" validate:"omitempty,required_if=Type file,min=1"`
+	FileFreq        int              `mapstructure:"file_freq" json:"fileFreq" bson:"fileFreq" validate:"omitempty,required_if=Type file,min=1"` // Frequency in minutes
+	Auth            *DestinationAuth `mapstructure:"auth" json:"auth,omitempty" bson:"auth,omitempty"`
+	Encoding        string           `mapstructure:"encoding" json:"encoding,omitempty" bson:"encoding,omitempty" validate:"omitempty,oneof=json ndjson protobuf msgpack"`           // HTTP payload encoding; defaults to json
+	Compression     string           `mapstructure:"compression" json:"compression,omitempty" bson:"compression,omitempty" validate:"omitempty,oneof=none gzip zstd snappy deflate"` // Sent as the Content-Encoding; defaults to none
+	BatchSize       int              `mapstructure:"batch_size" json:"batchSize,omitempty" bson:"batchSize,omitempty" validate:"omitempty,min=1,max=10000"`                          // Entries per HTTP request; defaults to 1
+	Retry           *RetryPolicy     `mapstructure:"retry" json:"retry,omitempty" bson:"retry,omitempty"`
+	Breaker         *CircuitBreaker  `mapstructure:"circuit_breaker" json:"circuitBreaker,omitempty" bson:"circuitBreaker,omitempty"`
+	FileMaxSizeMB   int              `mapstructure:"file_max_size_mb" json:"fileMaxSizeMB,omitempty" bson:"fileMaxSizeMB,omitempty" validate:"omitempty,min=1,max=102400"`         // Rotate files at this size; FileFreq rotates by time
+	FileCompression string           `mapstructure:"file_compression" json:"fileCompression,omitempty" bson:"fileCompression,omitempty" validate:"omitempty,oneof=none gzip zstd"` // Compression of rotated files, or of the pages of Parquet files; defaults to none
+	FileFormat      string           `mapstructure:"file_format" json:"fileFormat,omitempty" bson:"fileFormat,omitempty" validate:"omitempty,oneof=ndjson csv parquet"`            // Defaults to ndjson
+	DiskBudgetMB    int              `mapstructure:"disk_budget_mb" json:"diskBudgetMB,omitempty" bson:"diskBudgetMB,omitempty" validate:"omitempty,min=1"`                        // Writing stops once the test's files use this much disk
+	Socket          *SocketOptions   `mapstructure:"socket" json:"socket,omitempty" bson:"socket,omitempty"`                                                                       // syslog, tcp and udp destinations only
+	S3              *S3Options       `mapstructure:"s3" json:"s3,omitempty" bson:"s3,omitempty" validate:"required_if=Type s3"`                                                    // s3 destinations only
+}
+
+// S3Options configures s3 destinations, which upload entries to an S3-compatible
+// bucket in segment objects. Destination.Endpoint sets the URL of S3-compatible
+// services such as MinIO and is addressed path-style; AWS is used when it is empty.
+type S3Options struct {
+	Bucket                   string `mapstructure:"bucket" json:"bucket" bson:"bucket" validate:"required,min=3,max=63"`
+	Region                   string `mapstructure:"region" json:"region,omitempty" bson:"region,omitempty"` // Defaults to us-east-1
+	AccessKeyID              string `mapstructure:"access_key_id" json:"accessKeyID" bson:"accessKeyID" validate:"required"`
+	SecretAccessKeySecretRef string `mapstructure:"secret_access_key_secret_ref" json:"secretAccessKeySecretRef" bson:"secretAccessKeySecretRef" validate:"required"`
+	SessionTokenSecretRef    string `mapstructure:"session_token_secret_ref" json:"sessionTokenSecretRef,omitempty" bson:"sessionTokenSecretRef,omitempty"`                  // For temporary credentials
+	KeyTemplate              string `mapstructure:"key_template" json:"keyTemplate,omitempty" bson:"keyTemplate,omitempty" validate:"omitempty,max=512"`                     // Defaults to {testID}/{signal}/{date}/{seq}
+	Format                   string `mapstructure:"format" json:"format,omitempty" bson:"format,omitempty" validate:"omitempty,oneof=ndjson parquet"`                        // Defaults to ndjson
+	Compression              string `mapstructure:"compression" json:"compression,omitempty" bson:"compression,omitempty" validate:"omitempty,oneof=none gzip zstd"`         // Of NDJSON objects or Parquet pages; defaults to none
+	SegmentSizeMB            int    `mapstructure:"segment_size_mb" json:"segmentSizeMB,omitempty" bson:"segmentSizeMB,omitempty" validate:"omitempty,min=1,max=5120"`       // Encoded size before compression; defaults to 64
+	SegmentIntervalSec       int    `mapstructure:"segment_interval_sec" json:"segmentIntervalSec,omitempty" bson:"segmentIntervalSec,omitempty" validate:"omitempty,min=1"` // Upload segments at least this often; defaults to 60
+	PartSizeMB               int    `mapstructure:"part_size_mb" json:"partSizeMB,omitempty" bson:"partSizeMB,omitempty" validate:"omitempty,min=5,max=5120"`                // Multipart upload part size; defaults to 8
+}
+
+// SocketOptions configures syslog, tcp and udp destinations. TLS is used for TCP when
+// TLS is set or Destination.Auth.TLS provides certificates.
+type SocketOptions struct {
+	Network     string `mapstructure:"network" json:"network,omitempty" bson:"network,omitempty" validate:"omitempty,oneof=tcp udp"`                    // syslog only; defaults to tcp
+	Framing     string `mapstructure:"framing" json:"framing,omitempty" bson:"framing,omitempty" validate:"omitempty,oneof=octet-counting newline"`     // TCP only; defaults to octet-counting for syslog and newline otherwise
+	TLS         bool   `mapstructure:"tls" json:"tls,omitempty" bson:"tls,omitempty"`                                                                   // TCP only
+	Connections int    `mapstructure:"connections" json:"connections,omitempty" bson:"connections,omitempty" validate:"omitempty,min=1,max=64"`         // Pooled connections; defaults to 2
+	Format      string `mapstructure:"format" json:"format,omitempty" bson:"format,omitempty" validate:"omitempty,oneof=rfc5424 rfc3164"`               // syslog only; defaults to rfc5424
+	Facility    *int   `mapstructure:"facility" json:"facility,omitempty" bson:"facility,omitempty" validate:"omitempty,min=0,max=23"`                  // syslog only; defaults to 16 (local0)
+	AppName     string `mapstructure:"app_name" json:"appName,omitempty" bson:"appName,omitempty" validate:"omitempty,max=48,printascii,excludes= "`    // syslog only; defaults to moniflux
+	Hostname    string `mapstructure:"hostname" json:"hostname,omitempty" bson:"hostname,omitempty" validate:"omitempty,max=255,printascii,excludes= "` // syslog only; defaults to the local host name
+}
+
+// RetryPolicy controls how failed HTTP deliveries are retried. Zero values use the
+// defaults of the delivery package.
+type RetryPolicy struct {
+	MaxAttempts          int   `mapstructure:"max_attempts" json:"maxAttempts,omitempty" bson:"maxAttempts,omitempty" validate:"omitempty,min=1,max=20"`                                     // Including the first attempt; defaults to 3
+	InitialBackoffMs     int   `mapstructure:"initial_backoff_ms" json:"initialBackoffMs,omitempty" bson:"initialBackoffMs,omitempty" validate:"omitempty,min=1,max=60000"`                  // Defaults to 200
+	MaxBackoffMs         int   `mapstructure:"max_backoff_ms" json:"maxBackoffMs,omitempty" bson:"maxBackoffMs,omitempty" validate:"omitempty,min=1,max=300000"`                             // Defaults to 10000
+	RetryableStatusCodes []int `mapstructure:"retryable_status_codes" json:"retryableStatusCodes,omitempty" bson:"retryableStatusCodes,omitempty" validate:"omitempty,dive,min=400,max=599"` // Defaults to 408, 429, 500, 502, 503 and 504
+}
+
+// CircuitBreaker pauses the deliveries to a destination after consecutive failures.
+type CircuitBreaker struct {
+	Disabled         bool `mapstructure:"disabled" json:"disabled,omitempty" bson:"disabled,omitempty"`
+	FailureThreshold int  `mapstructure:"failure_threshold" json:"failureThreshold,omitempty" bson:"failureThreshold,omitempty" validate:"omitempty,min=1,max=1000"` // Consecutive failed requests; defaults to 5
+	CooldownMs       int  `mapstructure:"cooldown_ms" json:"cooldownMs,omitempty" bson:"cooldownMs,omitempty" validate:"omitempty,min=100,max=600000"`               // Pause before a probe request; defaults to 30000
+}
+
+// DestinationAuth configures how HTTP deliveries authenticate. Credentials are secret
+// references ("name", "env:name" or "file:name"), never plaintext values.
+type DestinationAuth struct {
+	Basic         *BasicAuth         `mapstructure:"basic" json:"basic,omitempty" bson:"basic,omitempty"`
+	OAuth2        *OAuth2Credentials `mapstructure:"oauth2" json:"oauth2,omitempty" bson:"oauth2,omitempty"`
+	Headers       map[string]string  `mapstructure:"headers" json:"headers,omitempty" bson:"headers,omitempty"`                    // Static, non-secret headers
+	SecretHeaders map[string]string  `mapstructure:"secret_headers" json:"secretHeaders,omitempty" bson:"secretHeaders,omitempty"` // Header name -> secret reference of its value
+	TenantHeader  string             `mapstructure:"tenant_header" json:"tenantHeader,omitempty" bson:"tenantHeader,omitempty"`    // e.g. X-Scope-OrgID; defaults to X-Scope-OrgID when TenantID is set
+	TenantID      string             `mapstructure:"tenant_id" json:"tenantID,omitempty" bson:"tenantID,omitempty"`
+	TLS           *DestinationTLS    `mapstructure:"tls" json:"tls,omitempty" bson:"tls,omitempty"`
+}
+
+// BasicAuth sends HTTP basic authentication.
+type BasicAuth struct {
+	Username          string `mapstructure:"username" json:"username" bson:"username" validate:"required"`
+	PasswordSecretRef string `mapstructure:"password_secret_ref" json:"passwordSecretRef" bson:"passwordSecretRef" validate:"required"`
+}
+
+// OAuth2Credentials fetches bearer tokens with the OAuth2 client credentials grant.
+type OAuth2Credentials struct {
+	TokenURL        string            `mapstructure:"token_url" json:"tokenURL" bson:"tokenURL" validate:"required,url"`
+	ClientID        string            `mapstructure:"client_id" json:"clientID" bson:"clientID" validate:"required"`
+	ClientSecretRef string            `mapstructure:"client_secret_ref" json:"clientSecretRef" bson:"clientSecretRef" validate:"required"`
+	Scopes          []string          `mapstructure:"scopes" json:"scopes,omitempty" bson:"scopes,omitempty"`
+	EndpointParams  map[string]string `mapstructure:"endpoint_params" json:"endpointParams,omitempty" bson:"endpointParams,omitempty"` // Extra form values, e.g. audience
+}
+
+// DestinationTLS configures the TLS connection of HTTP deliveries, including mTLS.
+type DestinationTLS struct {
+	CACert             string `mapstructure:"ca_cert" json:"caCert,omitempty" bson:"caCert,omitempty"`             // PEM CA bundle trusted in addition to the system roots
+	ClientCert         string `mapstructure:"client_cert" json:"clientCert,omitempty" bson:"clientCert,omitempty"` // PEM client certificate chain for mTLS
+	ClientKeySecretRef string `mapstructure:"client_key_secret_ref" json:"clientKeySecretRef,omitempty" bson:"clientKeySecretRef,omitempty" validate:"required_with=ClientCert"`
+	ServerName         string `mapstructure:"server_name" json:"serverName,omitempty" bson:"serverName,omitempty"`
+	InsecureSkipVerify bool   `mapstructure:"insecure_skip_verify" json:"insecureSkipVerify,omitempty" bson:"insecureSkipVerify,omitempty"`
+}
+
+// SecretRefs returns every secret reference of the destination.
+func (d Destination) SecretRefs() []string {
+	var refs []string
+	if d.SecretRef != "" {
+		refs = append(refs, d.SecretRef)
+	}
+	if d.S3 != nil {
+		refs = append(refs, d.S3.SecretAccessKeySecretRef)
+		if d.S3.SessionTokenSecretRef != "" {
+			refs = append(refs, d.S3.SessionTokenSecretRef)
+		}
+	}
+	if d.Auth == nil {
+		return refs
+	}
+	if d.Auth.Basic != nil {
+		refs = append(refs, d.Auth.Basic.PasswordSecretRef)
+	}
+	if d.Auth.OAuth2 != nil {
+		refs = append(refs, d.Auth.OAuth2.ClientSecretRef)
+	}
+	for _, ref := range d.Auth.SecretHeaders {
+		refs = append(refs, ref)
+	}
+	if d.Auth.TLS != nil && d.Auth.TLS.ClientKeySecretRef != "" {
+		refs = append(refs, d.Auth.TLS.ClientKeySecretRef)
+	}
+	return refs
+}
+
+// String describes the destination without its API key, so it can be logged.
+func (d Destination) String() string {
+	apiKey := ""
+	if d.APIKey != "" {
+		apiKey = "[REDACTED]"
+	}
+	return fmt.Sprintf("{Type:%s Name:%s Endpoint:%s Port:%d APIKey:%s SecretRef:%s FilePath:%s FileCount:%d FileFreq:%d}",
+		d.Type, d.Name, d.Endpoint, d.Port, apiKey, d.SecretRef, d.FilePath, d.FileCount, d.FileFreq)
+}
+
+// OIDC defines the settings for single sign-on through an external OpenID Connect provider.
+type OIDC struct {
+	Enabled       bool              `mapstructure:"enabled" json:"enabled" bson:"enabled"`
+	IssuerURL     string            `mapstructure:"issuer_url" json:"issuerURL" bson:"issuerURL" validate:"required_if=Enabled true,omitempty,url"`
+	ClientID      string            `mapstructure:"client_id" json:"clientID" bson:"clientID" validate:"required_if=Enabled true"`
+	ClientSecret  string            `mapstructure:"client_secret" json:"-" bson:"-"` // Optional for public clients using PKCE only
+	RedirectURL   string            `mapstructure:"redirect_url" json:"redirectURL" bson:"redirectURL" validate:"required_if=Enabled true,omitempty,url"`
+	Scopes        []string          `mapstructure:"scopes" json:"scopes" bson:"scopes"`
+	UsernameClaim string            `mapstructure:"username_claim" json:"usernameClaim" bson:"usernameClaim"`
+	GroupsClaim   string            `mapstructure:"groups_claim" json:"groupsClaim" bson:"groupsClaim"`
+	GroupRoles    map[string]string `mapstructure:"group_roles" json:"groupRoles" bson:"groupRoles"` // IdP group -> MoniFlux role
+}
+
+// AccountPolicy defines the password policy, login lockout and account token settings.
+type AccountPolicy struct {
+	PasswordMinLength       int    `mapstructure:"password_min_length" json:"passwordMinLength" bson:"passwordMinLength" validate:"min=8,max=72"`
+	PasswordRequireUpper    bool   `mapstructure:"password_require_upper" json:"passwordRequireUpper" bson:"passwordRequireUpper"`
+	PasswordRequireLower    bool   `mapstructure:"password_require_lower" json:"passwordRequireLower" bson:"passwordRequireLower"`
+	PasswordRequireDigit    bool   `mapstructure:"password_require_digit" json:"passwordRequireDigit" bson:"passwordRequireDigit"`
+	PasswordRequireSymbol   bool   `mapstructure:"password_require_symbol" json:"passwordRequireSymbol" bson:"passwordRequireSymbol"`
+	MaxFailedLogins         int    `mapstructure:"max_failed_logins" json:"maxFailedLogins" bson:"maxFailedLogins"` // 0 disables lockout
+	LockoutDuration         string `mapstructure:"lockout_duration" json:"lockoutDuration" bson:"lockoutDuration"`
+	ResetTokenExpiry        string `mapstructure:"reset_token_expiry" json:"resetTokenExpiry" bson:"resetTokenExpiry"`
+	VerificationTokenExpiry string `mapstructure:"verification_token_expiry" json:"verificationTokenExpiry" bson:"verificationTokenExpiry"`
+	PublicURL               string `mapstructure:"public_url" json:"publicURL" bson:"publicURL"` // Base URL used in links sent by email
+}
+
+// EmailConfig defines how account emails are delivered.
+type EmailConfig struct {
+	Provider     string `mapstructure:"provider" json:"provider" bson:"provider" validate:"omitempty,oneof=log smtp outbox"`
+	From         string `mapstructure:"from" json:"from" bson:"from"`
+	SMTPHost     string `mapstructure:"smtp_host" json:"smtpHost" bson:"smtpHost" validate:"required_if=Provider smtp"`
+	SMTPPort     int    `mapstructure:"smtp_port" json:"smtpPort" bson:"smtpPort"`
+	SMTPUsername string `mapstructure:"smtp_username" json:"smtpUsername" bson:"smtpUsername"`
+	SMTPPassword string `mapstructure:"smtp_password" json:"-" bson:"-"`
+	OutboxDir    string `mapstructure:"outbox_dir" json:"outboxDir" bson:"outboxDir"` // Directory for the outbox provider; empty keeps messages in memory
+}
+
+// RoleLimit bounds the load a holder of a role may generate. Zero means unlimited.
+type RoleLimit struct {
+	MaxRate     int `mapstructure:"max_rate" json:"maxRate" bson:"maxRate" validate:"min=0"`             // Logs, metrics and traces per second combined
+	MaxDuration int `mapstructure:"max_duration" json:"maxDuration" bson:"maxDuration" validate:"min=0"` // Seconds
+}
+
+// Guardrails defines the default safety limits on load tests. Administrators can
+// override them at runtime and per team through the guardrails API.
+type Guardrails struct {
+	AllowedDestinations []string             `mapstructure:"allowed_destinations" json:"allowedDestinations" bson:"allowedDestinations"` // Host, *.domain or CIDR patterns; empty allows any public host
+	AllowedFilePaths    []string             `mapstructure:"allowed_file_paths" json:"allowedFilePaths" bson:"allowedFilePaths"`         // Directory prefixes for file destinations; empty allows any path
+	MaxConcurrentTests  int                  `mapstructure:"max_concurrent_tests" json:"maxConcurrentTests" bson:"maxConcurrentTests"`   // 0 is unlimited
+	RoleLimits          map[string]RoleLimit `mapstructure:"role_limits" json:"roleLimits" bson:"roleLimits"`
+}
+
+// Admission defines the capacity of the load generator shared by all running tests.
+// Tests that do not fit are queued and started when running tests finish.
+type Admission struct {
+	MaxTotalRate   int `mapstructure:"max_total_rate" json:"maxTotalRate" bson:"maxTotalRate"`       // Logs, metrics and traces per second across running tests; 0 is unlimited
+	MaxWorkers     int `mapstructure:"max_workers" json:"maxWorkers" bson:"maxWorkers"`              // Worker goroutines across running tests; 0 is unlimited
+	MaxMemoryMB    int `mapstructure:"max_memory_mb" json:"maxMemoryMB" bson:"maxMemoryMB"`          // Estimated buffer memory across running tests; 0 is unlimited
+	MaxQueueLength int `mapstructure:"max_queue_length" json:"maxQueueLength" bson:"maxQueueLength"` // Tests waiting for capacity; 0 is unlimited
+}
+
+// SecretsConfig defines where destination credentials referenced by secretRef come from.
+type SecretsConfig struct {
+	MasterKey string `mapstructure:"master_key" json:"-" bson:"-"`                 // Base64-encoded 32-byte key encrypting the secrets collection; empty disables the store
+	EnvPrefix string `mapstructure:"env_prefix" json:"envPrefix" bson:"envPrefix"` // Prefix of the environment variables read by "env:" references
+	FileDir   string `mapstructure:"file_dir" json:"fileDir" bson:"fileDir"`       // Directory read by "file:" references; empty disables them
+	CacheTTL  string `mapstructure:"cache_ttl" json:"cacheTTL" bson:"cacheTTL"`    // How long resolved values are kept in memory
+}
+
+// AuditConfig defines where audit events are exported in addition to MongoDB.
+type AuditConfig struct {
+	FilePath string `mapstructure:"file_path" json:"filePath" bson:"filePath"` // NDJSON file sink; empty disables the export
+}
+
+// DeliveryConfig defines the settings shared by the deliveries of all tests.
+type DeliveryConfig struct {
+	DeadLetterDir    string `mapstructure:"dead_letter_dir" json:"deadLetterDir" bson:"deadLetterDir"`                            // Entries that exhaust their retries are appended to <dir>/<testID>.ndjson; empty drops them
+	FileDiskBudgetMB int    `mapstructure:"file_disk_budget_mb" json:"fileDiskBudgetMB" bson:"fileDiskBudgetMB" validate:"min=0"` // Disk budget of file destinations without diskBudgetMB; 0 is unlimited
+	SegmentDir       string `mapstructure:"segment_dir" json:"segmentDir" bson:"segmentDir"`                                      // Segments of s3 destinations are written here before they are uploaded
 }
 
 // ServerConfig represents the server configuration section.
@@ -50,32 +282,44 @@ type ServerConfig struct {
 
 // Config represents the application's configuration settings.
 type Config struct {
-	Server            ServerConfig  `mapstructure:"server" json:"server" bson:"server"`
-	LoadgenURL        string        `mapstructure:"loadgen_url" json:"loadgenUrl" bson:"loadgenUrl" validate:"required,url"`
-	LogLevel          string        `mapstructure:"log_level" json:"logLevel" bson:"logLevel" validate:"required,oneof=debug info warn error fatal"`
-	LogFormat         string        `mapstructure:"log_format" json:"logFormat" bson:"logFormat" validate:"required,oneof=json text"`
-	LogOutput         string        `mapstructure:"log_output" json:"logOutput" bson:"logOutput" validate:"required,oneof=stdout stderr file"`
-	LogFilePath       string        `mapstructure:"log_file_path" json:"logFilePath" bson:"logFilePath" validate:"required_if=LogOutput file"`
-	MongoURI          string        `mapstructure:"mongo_uri" json:"mongoURI" bson:"mongoURI" validate:"required,url"`
-	MongoDB           string        `mapstructure:"mongo_db" json:"mongoDB" bson:"mongoDB" validate:"required"`
-	JWTSecret         string        `mapstructure:"jwt_secret" json:"jwtSecret" bson:"jwtSecret" validate:"required,min=32"`
-	JWTExpiry         string        `mapstructure:"jwt_expiry" json:"jwtExpiry" bson:"jwtExpiry" validate:"required"`
-	AllowedOrigins    []string      `mapstructure:"allowed_origins" json:"allowedOrigins" bson:"allowedOrigins" validate:"required,dive,url"`
-	RateLimit         RateLimit     `mapstructure:"rate_limit" json:"rateLimit" bson:"rateLimit"`
-	SecurityRateLimit RateLimit     `mapstructure:"security.rate_limiting" json:"securityRateLimit" bson:"securityRateLimit"`
-	Metrics           Metrics       `mapstructure:"metrics" json:"metrics" bson:"metrics"`
-	EnableTLS         bool          `mapstructure:"enable_tls" json:"enableTLS" bson:"enableTLS"`
-	TLSCertPath       string        `mapstructure:"tls_cert_path" json:"tlsCertPath" bson:"tlsCertPath" validate:"required_if=EnableTLS true"`
-	TLSKeyPath        string        `mapstructure:"tls_key_path" json:"tlsKeyPath" bson:"tlsKeyPath" validate:"required_if=EnableTLS true"`
-	Destinations      []Destination `mapstructure:"destinations" json:"destinations" bson:"destinations" validate:"required,dive"`
-	LogRate           int           `mapstructure:"log_rate" json:"logRate" bson:"logRate" validate:"required,min=1"`
-	MetricsRate       int           `mapstructure:"metrics_rate" json:"metricsRate" bson:"metricsRate" validate:"required,min=1"`
-	TraceRate         int           `mapstructure:"trace_rate" json:"traceRate" bson:"traceRate" validate:"required,min=1"`
-	LogSize           int           `mapstructure:"log_size" json:"logSize" bson:"logSize" validate:"required,min=1"`
-	MetricsValue      float64       `mapstructure:"metrics_value" json:"metricsValue" bson:"metricsValue" validate:"required"`
-	DefaultRoles      []string      `mapstructure:"default_roles" json:"defaultRoles" bson:"defaultRoles" validate:"required,dive,required"`
-	Monitoring        Monitoring    `mapstructure:"monitoring" json:"monitoring" bson:"monitoring"`
-	ServerPort        string        `mapstructure:"server_port" json:"serverPort" bson:"serverPort" validate:"required,port"`
+	Server             ServerConfig   `mapstructure:"server" json:"server" bson:"server"`
+	LoadgenURL         string         `mapstructure:"loadgen_url" json:"loadgenUrl" bson:"loadgenUrl" validate:"required,url"`
+	LogLevel           string         `mapstructure:"log_level" json:"logLevel" bson:"logLevel" validate:"required,oneof=debug info warn error fatal"`
+	LogFormat          string         `mapstructure:"log_format" json:"logFormat" bson:"logFormat" validate:"required,oneof=json text"`
+	LogOutput          string         `mapstructure:"log_output" json:"logOutput" bson:"logOutput" validate:"required,oneof=stdout stderr file"`
+	LogFilePath        string         `mapstructure:"log_file_path" json:"logFilePath" bson:"logFilePath" validate:"required_if=LogOutput file"`
+	MongoURI           string         `mapstructure:"mongo_uri" json:"mongoURI" bson:"mongoURI" validate:"required,url"`
+	MongoDB            string         `mapstructure:"mongo_db" json:"mongoDB" bson:"mongoDB" validate:"required"`
+	JWTSecret          string         `mapstructure:"jwt_secret" json:"jwtSecret" bson:"jwtSecret" validate:"required,min=32"`
+	JWTExpiry          string         `mapstructure:"jwt_expiry" json:"jwtExpiry" bson:"jwtExpiry" validate:"required"`
+	RefreshTokenExpiry string         `mapstructure:"refresh_token_expiry" json:"refreshTokenExpiry" bson:"refreshTokenExpiry"` // Lifetime of a login session and its refresh tokens
+	AllowedOrigins     []string       `mapstructure:"allowed_origins" json:"allowedOrigins" bson:"allowedOrigins" validate:"required,dive,url"`
+	RateLimit          RateLimit      `mapstructure:"rate_limit" json:"rateLimit" bson:"rateLimit"`
+	SecurityRateLimit  RateLimit      `mapstructure:"security.rate_limiting" json:"securityRateLimit" bson:"securityRateLimit"`
+	Metrics            Metrics        `mapstructure:"metrics" json:"metrics" bson:"metrics"`
+	EnableTLS          bool           `mapstructure:"enable_tls" json:"enableTLS" bson:"enableTLS"`
+	TLSCertPath        string         `mapstructure:"tls_cert_path" json:"tlsCertPath" bson:"tlsCertPath" validate:"required_if=EnableTLS true"`
+	TLSKeyPath         string         `mapstructure:"tls_key_path" json:"tlsKeyPath" bson:"tlsKeyPath" validate:"required_if=EnableTLS true"`
+	Destinations       []Destination  `mapstructure:"destinations" json:"destinations" bson:"destinations" validate:"required,dive"`
+	LogRate            int            `mapstructure:"log_rate" json:"logRate" bson:"logRate" validate:"required,min=1"`
+	MetricsRate        int            `mapstructure:"metrics_rate" json:"metricsRate" bson:"metricsRate" validate:"required,min=1"`
+	TraceRate          int            `mapstructure:"trace_rate" json:"traceRate" bson:"traceRate" validate:"required,min=1"`
+	LogSize            int            `mapstructure:"log_size" json:"logSize" bson:"logSize" validate:"required,min=1"`
+	MetricsValue       float64        `mapstructure:"metrics_value" json:"metricsValue" bson:"metricsValue" validate:"required"`
+	DefaultRoles       []string       `mapstructure:"default_roles" json:"defaultRoles" bson:"defaultRoles" validate:"required,dive,required"`
+	DefaultUserRole    string         `mapstructure:"default_user_role" json:"defaultUserRole" bson:"defaultUserRole"` // Role assigned to newly registered users
+	AdminUsers         []string       `mapstructure:"admin_users" json:"adminUsers" bson:"adminUsers"`                 // Usernames granted the admin role at startup
+	OIDC               OIDC           `mapstructure:"oidc" json:"oidc" bson:"oidc"`
+	Accounts           AccountPolicy  `mapstructure:"accounts" json:"accounts" bson:"accounts"`
+	Email              EmailConfig    `mapstructure:"email" json:"email" bson:"email"`
+	Audit              AuditConfig    `mapstructure:"audit" json:"audit" bson:"audit"`
+	Guardrails         Guardrails     `mapstructure:"guardrails" json:"guardrails" bson:"guardrails"`
+	Admission          Admission      `mapstructure:"admission" json:"admission" bson:"admission"`
+	Secrets            SecretsConfig  `mapstructure:"secrets" json:"secrets" bson:"secrets"`
+	Delivery           DeliveryConfig `mapstructure:"delivery" json:"delivery" bson:"delivery"`
+	Monitoring         Monitoring     `mapstructure:"monitoring" json:"monitoring" bson:"monitoring"`
+	Tracing            Tracing        `mapstructure:"tracing" json:"tracing" bson:"tracing"`
+	ServerPort         string         `mapstructure:"server_port" json:"serverPort" bson:"serverPort" validate:"required,port"`
 }
 
 // User represents a user in the system.
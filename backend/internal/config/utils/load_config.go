@@ -7,6 +7,7 @@ import (
 	"strings"
 
 	"github.com/AkshayDubey29/MoniFlux/backend/internal/common"
+	"github.com/AkshayDubey29/MoniFlux/backend/internal/services/secrets"
 	"github.com/spf13/viper"
 )
 
@@ -79,7 +80,54 @@ func setDefaults(v *viper.Viper) {
 	v.SetDefault("mongo_db", "moniflux")
 
 	v.SetDefault("jwt_secret", "default-jwt-secret")
-	v.SetDefault("jwt_expiry", "24h")
+	v.SetDefault("jwt_expiry", "15m")
+	v.SetDefault("refresh_token_expiry", "720h")
+
+	v.SetDefault("accounts.password_min_length", 12)
+	v.SetDefault("accounts.password_require_upper", true)
+	v.SetDefault("accounts.password_require_lower", true)
+	v.SetDefault("accounts.password_require_digit", true)
+	v.SetDefault("accounts.password_require_symbol", false)
+	v.SetDefault("accounts.max_failed_logins", 5)
+	v.SetDefault("accounts.lockout_duration", "15m")
+	v.SetDefault("accounts.reset_token_expiry", "1h")
+	v.SetDefault("accounts.verification_token_expiry", "48h")
+	v.SetDefault("accounts.public_url", "http://localhost:8080")
+
+	v.SetDefault("email.provider", "log")
+	v.SetDefault("email.from", "MoniFlux <no-reply@moniflux.local>")
+	v.SetDefault("email.smtp_port", 587)
+
+	v.SetDefault("audit.file_path", "")
+
+	v.SetDefault("guardrails.allowed_destinations", []string{})
+	v.SetDefault("guardrails.allowed_file_paths", []string{})
+	v.SetDefault("guardrails.max_concurrent_tests", 20)
+	v.SetDefault("guardrails.role_limits", map[string]interface{}{
+		"admin":   map[string]interface{}{"max_rate": 0, "max_duration": 0},
+		"editor":  map[string]interface{}{"max_rate": 50000, "max_duration": 3600},
+		"default": map[string]interface{}{"max_rate": 1000, "max_duration": 600},
+	})
+
+	v.SetDefault("admission.max_total_rate", 200000)
+	v.SetDefault("admission.max_workers", 2000)
+	v.SetDefault("admission.max_memory_mb", 2048)
+	v.SetDefault("admission.max_queue_length", 100)
+
+	v.SetDefault("secrets.master_key", "")
+	v.SetDefault("secrets.env_prefix", "MONIFLUX_SECRET_")
+	v.SetDefault("secrets.file_dir", "")
+	v.SetDefault("secrets.cache_ttl", "1m")
+
+	v.SetDefault("delivery.dead_letter_dir", "dead-letter")
+	v.SetDefault("delivery.file_disk_budget_mb", 10240)
+	v.SetDefault("delivery.segment_dir", "segments")
+
+	v.SetDefault("oidc.enabled", false)
+	v.SetDefault("oidc.scopes", []string{"openid", "profile", "email"})
+	v.SetDefault("oidc.username_claim", "preferred_username")
+	v.SetDefault("oidc.groups_claim", "groups")
+	v.SetDefault("oidc.group_roles", map[string]string{})
 
 	v.SetDefault("allowed_origins", []string{"https://frontend.example.com"})
 
@@ -93,6 +141,7 @@ func setDefaults(v *viper.Viper) {
 	v.SetDefault("metrics.prometheus_enabled", true)
 	v.SetDefault("metrics.prometheus_endpoint", "/metrics")
 	v.SetDefault("metrics.prometheus_port", 2112)
+	v.SetDefault("metrics.max_test_ids", 100)
 	v.SetDefault("metrics.namespace", "moniflux")
 	v.SetDefault("metrics.subsystem", "api_server")
 
@@ -100,10 +149,7 @@ func setDefaults(v *viper.Viper) {
 	v.SetDefault("tls_cert_path", "/path/to/cert.pem")
 	v.SetDefault("tls_key_path", "/path/to/key.pem")
 
-	v.SetDefault("destinations", []common.Destination{
-		{Name: "destination1", Endpoint: "https://destination1.example.com/api", Port: 443, APIKey: "your_destination1_api_key_here"},
-		{Name: "destination2", Endpoint: "https://destination2.example.com/api", Port: 443, APIKey: "your_destination2_api_key_here"},
-	})
+	v.SetDefault("destinations", []common.Destination{})
 
 	v.SetDefault("log_rate", 100)
 	v.SetDefault("metrics_rate", 50)
@@ -112,8 +158,19 @@ func setDefaults(v *viper.Viper) {
 	v.SetDefault("metrics_value", 100.0)
 
 	v.SetDefault("default_roles", []string{"admin", "editor", "viewer"})
+	v.SetDefault("default_user_role", "editor")
+	v.SetDefault("admin_users", []string{})
 
 	v.SetDefault("monitoring.health_check_interval", "5m")
+	v.SetDefault("monitoring.health_check_retention", "168h")
+	v.SetDefault("monitoring.health_check_timeout", "2s")
+	v.SetDefault("monitoring.saturation_threshold", 0.9)
+	v.SetDefault("monitoring.readiness_checks", []string{"mongodb"})
+
+	v.SetDefault("tracing.exporter", "none")
+	v.SetDefault("tracing.endpoint", "http://localhost:4318")
+	v.SetDefault("tracing.headers", map[string]string{})
+	v.SetDefault("tracing.sample_ratio", 1.0)
 
 	v.SetDefault("environment", "production")
 
@@ -158,6 +215,12 @@ func validateConfig(config *common.Config) error {
 		return fmt.Errorf("server.loadgen_url must be set")
 	}
 
+	if config.Secrets.MasterKey != "" {
+		if _, err := secrets.ParseMasterKey(config.Secrets.MasterKey); err != nil {
+			return err
+		}
+	}
+
 	// Add more validation rules as needed
 
 	return nil
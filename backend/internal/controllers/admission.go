@@ -0,0 +1,300 @@
+// admission.go
+
+package controllers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/AkshayDubey29/MoniFlux/backend/internal/api/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const (
+	// jobBufferPerWorker is the number of entries buffered per worker of a WorkerPool.
+	jobBufferPerWorker = 10000
+
+	// Per-entry memory estimates used for admission.
+	jobSlotBytes       = 16      // Interface value of a channel slot, allocated up front
+	entryOverheadBytes = 256     // Struct, boxing and strings of a queued entry besides the log message
+	workerStackBytes   = 8 << 10 // Goroutine stack of a worker
+)
+
+// Resources is the estimated load a running test puts on the process.
+type Resources struct {
+	Rate        int   `json:"rate"` // Logs, metrics and traces per second combined
+	Workers     int   `json:"workers"`
+	MemoryBytes int64 `json:"memoryBytes"`
+}
+
+func (r Resources) add(o Resources) Resources {
+	return Resources{Rate: r.Rate + o.Rate, Workers: r.Workers + o.Workers, MemoryBytes: r.MemoryBytes + o.MemoryBytes}
+}
+
+// estimateResources returns the resources a test needs while running. Memory is the
+// worst case of a full job buffer, which is what exhausts the process under load.
+func estimateResources(test *models.Test) Resources {
+	workers := determineNumberOfWorkers(test.LogRate, test.LogSize)
+	slots := int64(workers) * jobBufferPerWorker
+	entryBytes := int64(test.LogSize) + entryOverheadBytes
+	return Resources{
+		Rate:        test.LogRate + test.MetricsRate + test.TraceRate,
+		Workers:     workers,
+		MemoryBytes: slots*(jobSlotBytes+entryBytes) + int64(workers)*workerStackBytes,
+	}
+}
+
+// queuedTest is a test waiting for capacity.
+type queuedTest struct {
+	TestID     string
+	Resources  Resources
+	EnqueuedAt time.Time
+}
+
+// AdmissionStatus reports the admission limits, the resources in use and the queue.
+type AdmissionStatus struct {
+	Limits      Resources `json:"limits"` // Zero is unlimited
+	Usage       Resources `json:"usage"`
+	Running     int       `json:"running"`
+	QueueLength int       `json:"queueLength"`
+	MaxQueue    int       `json:"maxQueue"`
+}
+
+// admissionLimits returns the configured limits; zero fields are unlimited.
+func (c *LoadGenController) admissionLimits() Resources {
+	a := c.Config.Admission
+	return Resources{Rate: a.MaxTotalRate, Workers: a.MaxWorkers, MemoryBytes: int64(a.MaxMemoryMB) << 20}
+}
+
+// usageLocked sums the resources of the running tests and of the queued tests being
+// started. The caller must hold c.mu.
+func (c *LoadGenController) usageLocked() Resources {
+	var usage Resources
+	for _, task := range c.tests {
+		usage = usage.add(task.Resources)
+	}
+	for _, need := range c.starting {
+		usage = usage.add(need)
+	}
+	return usage
+}
+
+// exceedsLimits reports which limit, if any, need on its own is above.
+func exceedsLimits(need, limits Resources) string {
+	switch {
+	case limits.Rate > 0 && need.Rate > limits.Rate:
+		return fmt.Sprintf("rate of %d/s exceeds the limit of %d/s", need.Rate, limits.Rate)
+	case limits.Workers > 0 && need.Workers > limits.Workers:
+		return fmt.Sprintf("%d workers exceed the limit of %d", need.Workers, limits.Workers)
+	case limits.MemoryBytes > 0 && need.MemoryBytes > limits.MemoryBytes:
+		return fmt.Sprintf("estimated memory of %d MiB exceeds the limit of %d MiB", need.MemoryBytes>>20, limits.MemoryBytes>>20)
+	}
+	return ""
+}
+
+// fitsLocked reports whether a test needing the resources can start now without
+// exceeding the limits. The caller must hold c.mu.
+func (c *LoadGenController) fitsLocked(need Resources) bool {
+	return exceedsLimits(c.usageLocked().add(need), c.admissionLimits()) == ""
+}
+
+// admitLocked decides whether the test can start now or must be queued. It returns
+// an error if the test can never fit or the queue is full. Tests are admitted in
+// order, so a test is queued while others are waiting. The caller must hold c.mu.
+func (c *LoadGenController) admitLocked(testID string, need Resources) (bool, error) {
+	if reason := exceedsLimits(need, c.admissionLimits()); reason != "" {
+		return false, fmt.Errorf("%w: test %s: %s", models.ErrCapacityExceeded, testID, reason)
+	}
+
+	c.queueMu.Lock()
+	defer c.queueMu.Unlock()
+	if len(c.queue) == 0 && c.fitsLocked(need) {
+		return true, nil
+	}
+	if max := c.Config.Admission.MaxQueueLength; max > 0 && len(c.queue) >= max {
+		return false, fmt.Errorf("%w: %d tests are waiting", models.ErrAdmissionQueueFull, len(c.queue))
+	}
+	return false, nil
+}
+
+// enqueueLocked appends the test to the admission queue and returns its 1-based
+// position. The caller must hold c.mu.
+func (c *LoadGenController) enqueueLocked(testID string, need Resources) int {
+	c.queueMu.Lock()
+	defer c.queueMu.Unlock()
+	c.queue = append(c.queue, queuedTest{TestID: testID, Resources: need, EnqueuedAt: time.Now()})
+	return len(c.queue)
+}
+
+// dequeueLocked removes the test from the admission queue and reports whether it was
+// queued. The caller must hold c.mu.
+func (c *LoadGenController) dequeueLocked(testID string) bool {
+	c.queueMu.Lock()
+	defer c.queueMu.Unlock()
+	for i, q := range c.queue {
+		if q.TestID == testID {
+			c.queue = append(c.queue[:i], c.queue[i+1:]...)
+			return true
+		}
+	}
+	return false
+}
+
+// QueuePosition returns the 1-based position of the test in the admission queue,
+// or 0 if it is not queued.
+func (c *LoadGenController) QueuePosition(testID string) int {
+	c.queueMu.Lock()
+	defer c.queueMu.Unlock()
+	for i, q := range c.queue {
+		if q.TestID == testID {
+			return i + 1
+		}
+	}
+	return 0
+}
+
+// AdmissionStatus returns the current admission limits, usage and queue length.
+func (c *LoadGenController) AdmissionStatus() *AdmissionStatus {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.queueMu.Lock()
+	defer c.queueMu.Unlock()
+	return &AdmissionStatus{
+		Limits:      c.admissionLimits(),
+		Usage:       c.usageLocked(),
+		Running:     len(c.tests),
+		QueueLength: len(c.queue),
+		MaxQueue:    c.Config.Admission.MaxQueueLength,
+	}
+}
+
+// finishTest releases the capacity of a test whose load generation has ended and
+// starts the queued tests that now fit.
+func (c *LoadGenController) finishTest(testID string, task *TestTask) {
+	c.mu.Lock()
+	// The test may have been cancelled and started again in the meantime.
+	if c.tests[testID] == task {
+		delete(c.tests, testID)
+	}
+	c.mu.Unlock()
+
+	c.startQueued()
+}
+
+// popFittingLocked removes from the front of the queue the tests that fit together,
+// reserving their capacity in c.starting. The caller must hold c.mu.
+func (c *LoadGenController) popFittingLocked() []queuedTest {
+	c.queueMu.Lock()
+	defer c.queueMu.Unlock()
+
+	var next []queuedTest
+	for !c.stopped && len(c.queue) > 0 && c.fitsLocked(c.queue[0].Resources) {
+		q := c.queue[0]
+		c.queue = c.queue[1:]
+		c.starting[q.TestID] = q.Resources
+		next = append(next, q)
+	}
+	return next
+}
+
+// startQueued starts queued tests in order for as long as they fit. The tests are
+// moved to "Running" in MongoDB without holding c.mu, so a slow database does not
+// stall the other operations.
+func (c *LoadGenController) startQueued() {
+	for {
+		c.mu.Lock()
+		next := c.popFittingLocked()
+		c.mu.Unlock()
+		if len(next) == 0 {
+			return
+		}
+		for _, q := range next {
+			c.startQueuedTest(q)
+		}
+	}
+}
+
+// startQueuedTest moves a test popped from the queue from "Queued" to "Running" and
+// launches it, unless it was cancelled meanwhile or the controller was stopped.
+func (c *LoadGenController) startQueuedTest(q queuedTest) {
+	collection := c.MongoClient.Database(c.Config.MongoDB).Collection("tests")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	var test models.Test
+	var matched int64
+	err := collection.FindOne(ctx, bson.M{"testID": q.TestID}).Decode(&test)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		err = nil // Deleted while queued
+	} else if err == nil && test.Status == "Queued" {
+		// The status is only changed if the test is still queued, e.g. not cancelled.
+		var result *mongo.UpdateResult
+		result, err = collection.UpdateOne(ctx, bson.M{"testID": test.TestID, "status": "Queued"},
+			bson.M{"$set": bson.M{"status": "Running", "updatedAt": time.Now()}})
+		if err == nil {
+			matched = result.MatchedCount
+		}
+	}
+
+	// The status to record once c.mu is released, if the test is not launched.
+	var revert string
+	c.mu.Lock()
+	_, reserved := c.starting[q.TestID]
+	delete(c.starting, q.TestID)
+	switch {
+	case err != nil:
+		// The test has left the queue, so it is failed rather than left "Queued" with
+		// nothing to start it. A test cancelled meanwhile keeps its status.
+		c.Logger.Errorf("Failed to mark queued test %s as running: %v", q.TestID, err)
+		if reserved {
+			revert = "Error"
+		}
+	case matched != 1 || !reserved:
+		c.Logger.Infof("Skipping queued test %s that is no longer queued", q.TestID)
+	case c.stopped:
+		// Left queued, the test is restored on the next start.
+		c.Logger.Infof("Not starting queued test %s, the load generator is stopping", q.TestID)
+		revert = "Queued"
+	default:
+		test.Status = "Running"
+		c.Logger.Infof("Starting queued test %s after waiting %s", test.TestID, time.Since(q.EnqueuedAt).Round(time.Second))
+		if err := c.launchLocked(context.Background(), &test); err != nil {
+			c.Logger.Errorf("Failed to start queued test %s: %v", test.TestID, err)
+			revert = "Error"
+		}
+	}
+	c.mu.Unlock()
+
+	if revert != "" {
+		c.updateTestStatus(context.Background(), q.TestID, revert)
+	}
+}
+
+// RestoreQueue re-queues the tests left in the "Queued" state by a previous run of
+// the process, in the order they were queued, and starts those that fit.
+func (c *LoadGenController) RestoreQueue(ctx context.Context) error {
+	collection := c.MongoClient.Database(c.Config.MongoDB).Collection("tests")
+	cursor, err := collection.Find(ctx, bson.M{"status": "Queued"}, options.Find().SetSort(bson.D{{Key: "updatedAt", Value: 1}}))
+	if err != nil {
+		return fmt.Errorf("failed to load queued tests: %w", err)
+	}
+	var tests []models.Test
+	if err := cursor.All(ctx, &tests); err != nil {
+		return fmt.Errorf("failed to decode queued tests: %w", err)
+	}
+
+	c.mu.Lock()
+	for i := range tests {
+		c.enqueueLocked(tests[i].TestID, estimateResources(&tests[i]))
+	}
+	c.mu.Unlock()
+	if len(tests) > 0 {
+		c.Logger.Infof("Restored %d queued tests", len(tests))
+	}
+	c.startQueued()
+	return nil
+}
@@ -17,37 +17,68 @@ import (
 
 	"github.com/AkshayDubey29/MoniFlux/backend/internal/api/models"
 	"github.com/AkshayDubey29/MoniFlux/backend/internal/common"
+	"github.com/AkshayDubey29/MoniFlux/backend/internal/loadgen/delivery"
+	"github.com/AkshayDubey29/MoniFlux/backend/internal/loadgen/metrics"
+	"github.com/AkshayDubey29/MoniFlux/backend/internal/services/destinations"
+	"github.com/AkshayDubey29/MoniFlux/backend/internal/services/guardrails"
+	"github.com/AkshayDubey29/MoniFlux/backend/internal/services/secrets"
+	"github.com/AkshayDubey29/MoniFlux/backend/internal/services/tenancy"
+	"github.com/AkshayDubey29/MoniFlux/backend/pkg/tracing"
 	validator "github.com/go-playground/validator/v10"
 	"github.com/google/uuid"
 	"github.com/sirupsen/logrus"
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 )
 
-// TestTask represents a running load test with its cancel function and worker pool.
+// tracer records the spans of the load generation.
+var tracer = otel.Tracer("github.com/AkshayDubey29/MoniFlux/backend/internal/controllers")
+
+// TestTask represents a running load test with its cancel function, worker pool and
+// the resources it was admitted with.
 type TestTask struct {
 	CancelFunc context.CancelFunc
 	WorkerPool *WorkerPool
+	Resources  Resources
 }
 
 // LoadGenController manages the main load generation operations.
 type LoadGenController struct {
-	MongoClient *mongo.Client
-	Config      *common.Config
-	Logger      *logrus.Logger
-	Validator   *validator.Validate
-	mu          sync.Mutex
-	tests       map[string]*TestTask
+	MongoClient  *mongo.Client
+	Config       *common.Config
+	Logger       *logrus.Logger
+	Validator    *validator.Validate
+	Tenancy      *tenancy.TenancyService
+	Guardrails   *guardrails.GuardrailService
+	Secrets      *secrets.SecretService
+	Destinations *destinations.DestinationService // Named destinations tests reference
+	Metrics      *metrics.Engine                  // Prometheus metrics of the load generation
+	mu           sync.Mutex
+	tests        map[string]*TestTask
+	queueMu      sync.Mutex           // Guards queue; acquired after mu when both are held
+	queue        []queuedTest         // Tests waiting for capacity, in start order
+	starting     map[string]Resources // Queued tests being started, with their reserved capacity; guarded by mu
+	stopped      bool                 // Set by StopAllTests; queued tests are no longer started
 }
 
 // NewLoadGenController initializes a new LoadGenController.
 func NewLoadGenController(cfg *common.Config, log *logrus.Logger, mongoClient *mongo.Client) *LoadGenController {
 	return &LoadGenController{
-		Config:      cfg,
-		Logger:      log,
-		MongoClient: mongoClient,
-		Validator:   validator.New(),
-		tests:       make(map[string]*TestTask),
+		Config:       cfg,
+		Logger:       log,
+		MongoClient:  mongoClient,
+		Validator:    validator.New(),
+		Tenancy:      tenancy.NewTenancyService(cfg, log, mongoClient),
+		Guardrails:   guardrails.NewGuardrailService(cfg, log, mongoClient),
+		Secrets:      secrets.NewSecretService(cfg, log, mongoClient),
+		Destinations: destinations.NewDestinationService(cfg, log, mongoClient),
+		Metrics:      metrics.NewEngine(cfg.Metrics.MaxTestIDs),
+		tests:        make(map[string]*TestTask),
+		starting:     make(map[string]Resources),
 	}
 }
 
@@ -94,33 +125,15 @@ func determineNumberOfWorkers(logRate int, logSize int) int {
 
 // assignDefaults sets default values based on destination type and other properties.
 func (c *LoadGenController) assignDefaults(test *models.Test) {
+	destinations.ApplyDefaults(&test.Destination)
 	switch test.Destination.Type {
 	case "file":
-		if test.Destination.FileCount == 0 {
-			test.Destination.FileCount = 10
-			c.Logger.Infof("Defaulting FileCount to %d for test %s", test.Destination.FileCount, test.TestID)
-		}
-		if test.Destination.FileFreq == 0 {
-			test.Destination.FileFreq = 5
-			c.Logger.Infof("Defaulting FileFreq to %d minutes for test %s", test.Destination.FileFreq, test.TestID)
-		}
 		if test.Destination.FilePath == "" {
 			test.Destination.FilePath = "/tmp/default-output.log"
 			c.Logger.Infof("Defaulting FilePath to %s for test %s", test.Destination.FilePath, test.TestID)
 		}
-	case "http":
-		if test.Destination.Port == 0 {
-			test.Destination.Port = 80
-			c.Logger.Infof("Defaulting Port to %d for test %s", test.Destination.Port, test.TestID)
-		}
-		if test.Destination.Endpoint == "" {
-			test.Destination.Endpoint = "http://localhost/api"
-			c.Logger.Infof("Defaulting Endpoint to %s for test %s", test.Destination.Endpoint, test.TestID)
-		}
-		if test.Destination.APIKey == "" {
-			test.Destination.APIKey = "default-api-key"
-			c.Logger.Infof("Defaulting APIKey for test %s", test.TestID)
-		}
+	case "http", "syslog", "tcp", "udp", "s3":
+		// Socket ports, S3 segment and upload options default when the sender is built.
 	default:
 		c.Logger.Warnf("Unknown destination type '%s' for test %s", test.Destination.Type, test.TestID)
 	}
@@ -142,20 +155,31 @@ func (c *LoadGenController) assignDefaults(test *models.Test) {
 	}
 }
 
-// StartTest initiates or updates a load test.
-func (c *LoadGenController) StartTest(ctx context.Context, test *models.Test) error {
+// StartTest initiates or updates a load test. If the load generator is at capacity
+// the test is queued: its status is "Queued", QueuePosition is set and it is started
+// automatically when running tests finish.
+func (c *LoadGenController) StartTest(ctx context.Context, test *models.Test) (err error) {
+	ctx, span := tracer.Start(ctx, "LoadGenController.StartTest", trace.WithAttributes(tracing.TestIDKey.String(test.TestID)))
+	defer func() { tracing.End(span, err) }()
+
+	// The probe does not hold the lock, it may wait on the network.
+	if test.Preflight {
+		if err := c.preflight(ctx, test); err != nil {
+			return err
+		}
+	}
+
 	c.mu.Lock()
 	defer c.mu.Unlock()
 
+	return c.startTestLocked(ctx, test)
+}
+
+// startTestLocked starts or queues the test. The caller must hold c.mu.
+func (c *LoadGenController) startTestLocked(ctx context.Context, test *models.Test) error {
 	// Assign default values based on destination type before validation.
 	c.assignDefaults(test)
 
-	// Validate the test configuration.
-	if err := c.Validator.Struct(test); err != nil {
-		c.Logger.Errorf("Validation failed for test %s: %v", test.TestID, err)
-		return fmt.Errorf("validation failed: %w", err)
-	}
-
 	// Access MongoDB collection and check for an existing test.
 	collection := c.MongoClient.Database(c.Config.MongoDB).Collection("tests")
 	filter := bson.M{"testID": test.TestID}
@@ -164,12 +188,66 @@ func (c *LoadGenController) StartTest(ctx context.Context, test *models.Test) er
 	err := collection.FindOne(ctx, filter).Decode(&existingTest)
 	isNewTest := errors.Is(err, mongo.ErrNoDocuments)
 
+	// New tests belong to the caller; existing tests keep their owner and tenant.
+	if isNewTest {
+		if err := c.assignOwnership(ctx, test); err != nil {
+			return err
+		}
+	} else {
+		if err := c.authorizeTest(ctx, &existingTest); err != nil {
+			return err
+		}
+		test.UserID, test.TeamID, test.ProjectID = existingTest.UserID, existingTest.TeamID, existingTest.ProjectID
+		keepStoredCredentials(test, &existingTest)
+	}
+
+	// Validate the test configuration.
+	if err := c.Validator.Struct(test); err != nil {
+		c.Logger.Errorf("Validation failed for test %s: %v", test.TestID, err)
+		return fmt.Errorf("%w: %w", models.ErrInvalidTest, err)
+	}
+
+	// Enforce destination, rate, duration and concurrency guardrails.
+	if err := c.enforceGuardrails(ctx, test, true); err != nil {
+		return err
+	}
+
+	// Ensure the test is in a startable state.
+	if !isNewTest {
+		switch existingTest.Status {
+		case "Running":
+			return fmt.Errorf("%w: %s", models.ErrTestAlreadyRunning, test.TestID)
+		case "Queued":
+			return fmt.Errorf("%w: %s", models.ErrTestAlreadyQueued, test.TestID)
+		case "Pending", "Scheduled", "Cancelled", "Completed", "Error":
+		default:
+			return fmt.Errorf("%w: test %s cannot be started while %s", models.ErrInvalidTestState, test.TestID, existingTest.Status)
+		}
+	}
+
+	// Keep the destination credentials in the secret store only.
+	if err := c.prepareCredentials(ctx, test); err != nil {
+		return err
+	}
+
+	// Start the test now if it fits within the load generator capacity, or queue it.
+	need := estimateResources(test)
+	admitted, err := c.admitLocked(test.TestID, need)
+	if err != nil {
+		c.Logger.Warnf("Test %s not admitted: %v", test.TestID, err)
+		return err
+	}
+	status := "Running"
+	if !admitted {
+		status = "Queued"
+	}
+
 	if isNewTest {
 		// Set a unique TestID and initialize test status and timestamps.
 		if test.TestID == "" {
 			test.TestID = uuid.New().String()
 		}
-		test.Status = "Running"
+		test.Status = status
 		test.CreatedAt, test.UpdatedAt = time.Now(), time.Now()
 
 		// Insert the new test into the database.
@@ -178,17 +256,10 @@ func (c *LoadGenController) StartTest(ctx context.Context, test *models.Test) er
 			c.Logger.Errorf("Failed to insert test %s: %v", test.TestID, err)
 			return fmt.Errorf("failed to insert test: %w", err)
 		}
-		c.Logger.Infof("Test %s started and inserted as new", test.TestID)
+		c.Logger.Infof("Test %s inserted as new with status %s", test.TestID, status)
 	} else {
-		// Ensure the test is in a startable state.
-		if existingTest.Status == "Running" {
-			return fmt.Errorf("test with ID %s is already running", test.TestID)
-		}
-		if existingTest.Status != "Cancelled" && existingTest.Status != "Completed" && existingTest.Status != "Error" {
-			return fmt.Errorf("test with ID %s cannot be started in its current state: %s", test.TestID, existingTest.Status)
-		}
-
-		// Update the existing test's configuration and set it to "Running".
+		// Update the existing test's configuration and status.
+		test.Status = status
 		update := bson.M{
 			"$set": bson.M{
 				"logRate":       test.LogRate,
@@ -196,7 +267,8 @@ func (c *LoadGenController) StartTest(ctx context.Context, test *models.Test) er
 				"traceRate":     test.TraceRate,
 				"logSize":       test.LogSize,
 				"duration":      test.Duration,
-				"status":        "Running",
+				"destination":   test.Destination,
+				"status":        status,
 				"updatedAt":     time.Now(),
 				"completedAt":   time.Time{},
 				"scheduledTime": time.Time{},
@@ -208,9 +280,39 @@ func (c *LoadGenController) StartTest(ctx context.Context, test *models.Test) er
 			c.Logger.Errorf("Failed to update test %s: %v", test.TestID, err)
 			return fmt.Errorf("failed to update test: %w", err)
 		}
-		c.Logger.Infof("Test %s configuration updated and started", test.TestID)
+		c.Logger.Infof("Test %s configuration updated with status %s", test.TestID, status)
 	}
 
+	if !admitted {
+		test.QueuePosition = c.enqueueLocked(test.TestID, need)
+		c.Logger.Infof("Test %s queued at position %d until capacity is available", test.TestID, test.QueuePosition)
+		return nil
+	}
+
+	if err := c.launchLocked(ctx, test); err != nil {
+		c.updateTestStatus(context.Background(), test.TestID, "Error")
+		return err
+	}
+	return nil
+}
+
+// launchLocked creates the worker pool of an admitted test and runs its load
+// generation in the background. The caller must hold c.mu.
+func (c *LoadGenController) launchLocked(ctx context.Context, test *models.Test) (err error) {
+	// The run is traced as a child of the span in ctx, usually the request that started
+	// it, and outlives it.
+	runCtx, span := tracer.Start(context.WithoutCancel(ctx), "LoadGen run", trace.WithAttributes(
+		tracing.TestIDKey.String(test.TestID),
+		attribute.String("moniflux.destination", test.Destination.Type),
+		attribute.Int("moniflux.rate", test.LogRate+test.MetricsRate+test.TraceRate),
+		attribute.Int("moniflux.duration", test.Duration),
+	))
+	defer func() {
+		if err != nil {
+			tracing.End(span, err)
+		}
+	}()
+
 	// Determine Destination Type and Endpoint
 	destinationType := FileDestination
 	destinationValue := ""
@@ -220,65 +322,150 @@ func (c *LoadGenController) StartTest(ctx context.Context, test *models.Test) er
 		destinationValue = test.Destination.Endpoint
 		if destinationValue == "" {
 			c.Logger.Errorf("HTTP endpoint must be specified for HTTP destination")
-			return fmt.Errorf("HTTP endpoint must be specified for HTTP destination")
+			return fmt.Errorf("%w: HTTP endpoint must be specified for HTTP destination", models.ErrInvalidTest)
 		}
 	} else if test.Destination.Type == "file" {
 		destinationType = FileDestination
 		destinationValue = test.Destination.FilePath
 		if destinationValue == "" {
 			c.Logger.Errorf("filePath must be specified for file destination")
-			return fmt.Errorf("filePath must be specified for file destination")
+			return fmt.Errorf("%w: filePath must be specified for file destination", models.ErrInvalidTest)
+		}
+	} else if test.Destination.Type == "s3" {
+		destinationType = S3Destination
+		if test.Destination.S3 == nil || test.Destination.S3.Bucket == "" {
+			c.Logger.Errorf("bucket must be specified for s3 destination")
+			return fmt.Errorf("%w: bucket must be specified for s3 destination", models.ErrInvalidTest)
+		}
+		destinationValue = test.Destination.S3.Bucket
+	} else if delivery.IsSocketDestination(test.Destination.Type) {
+		destinationType = SocketDestination
+		destinationValue = test.Destination.Endpoint
+		if destinationValue == "" {
+			c.Logger.Errorf("endpoint must be specified for %s destination", test.Destination.Type)
+			return fmt.Errorf("%w: endpoint must be specified for %s destination", models.ErrInvalidTest, test.Destination.Type)
 		}
 	} else {
 		c.Logger.Errorf("Unsupported destination type: %s", test.Destination.Type)
-		return fmt.Errorf("unsupported destination type: %s", test.Destination.Type)
+		return fmt.Errorf("%w: %s", models.ErrDestinationUnsupported, test.Destination.Type)
 	}
 
 	// Create a self-contained cancellable context based on the test's duration
-	loadCtx, cancel := context.WithTimeout(context.Background(), time.Duration(test.Duration)*time.Second)
+	loadCtx, cancel := context.WithTimeout(runCtx, time.Duration(test.Duration)*time.Second)
 
 	// Initialize WorkerPool based on the destination type
-	numWorkers := determineNumberOfWorkers(test.LogRate, test.LogSize)
-	batchSize := 1000                    // Customize as needed
-	batchDelay := 100 * time.Millisecond // Adjust as necessary
+	resources := estimateResources(test)
+	numWorkers := resources.Workers
+	batchSize := test.Destination.BatchSize // Entries per HTTP request; 1 if unset
+	batchDelay := 100 * time.Millisecond    // Adjust as necessary
 
 	// Log initialization details
-	if destinationType == FileDestination {
+	switch destinationType {
+	case FileDestination:
 		c.Logger.Infof("Initializing WorkerPool with filePath: %s for test %s", destinationValue, test.TestID)
-	} else {
+	case SocketDestination:
+		c.Logger.Infof("Initializing WorkerPool with %s endpoint: %s for test %s", test.Destination.Type, destinationValue, test.TestID)
+	case S3Destination:
+		c.Logger.Infof("Initializing WorkerPool with bucket: %s for test %s", destinationValue, test.TestID)
+	default:
 		c.Logger.Infof("Initializing WorkerPool with httpEndpoint: %s for test %s", destinationValue, test.TestID)
 	}
 
-	wp, err := NewWorkerPool(numWorkers, destinationType, destinationValue, c.Logger, batchSize, batchDelay)
+	var deliverer *delivery.Deliverer
+	if destinationType == HTTPDestination {
+		var err error
+		deliverer, err = c.newDeliverer(runCtx, test)
+		if err != nil {
+			c.Logger.Errorf("Failed to set up HTTP delivery for test %s: %v", test.TestID, err)
+			cancel()
+			return fmt.Errorf("failed to set up HTTP delivery: %w", err)
+		}
+	}
+	var socket *delivery.SocketSender
+	if destinationType == SocketDestination {
+		var err error
+		socket, err = c.newSocketSender(runCtx, test)
+		if err != nil {
+			c.Logger.Errorf("Failed to set up %s delivery for test %s: %v", test.Destination.Type, test.TestID, err)
+			cancel()
+			return fmt.Errorf("failed to set up %s delivery: %w", test.Destination.Type, err)
+		}
+	}
+
+	var uploader *delivery.S3Uploader
+	if destinationType == S3Destination {
+		var err error
+		uploader, err = c.newS3Uploader(runCtx, test)
+		if err != nil {
+			c.Logger.Errorf("Failed to set up s3 delivery for test %s: %v", test.TestID, err)
+			cancel()
+			return fmt.Errorf("failed to set up s3 delivery: %w", err)
+		}
+	}
+
+	var file *delivery.RotatingFile
+	if destinationType == FileDestination {
+		var err error
+		file, err = delivery.NewRotatingFile(delivery.FileOptions(test.Destination, c.Config.Delivery.FileDiskBudgetMB))
+		if err != nil {
+			c.Logger.Errorf("Failed to open file %s for test %s: %v", test.Destination.FilePath, test.TestID, err)
+			cancel()
+			return fmt.Errorf("failed to open log file: %w", err)
+		}
+	}
+
+	prom := c.Metrics.Start(test.TestID, test.Destination.Type, numWorkers)
+	wp, err := NewWorkerPool(runCtx, numWorkers, destinationType, file, deliverer, socket, uploader, c.Logger, batchSize, batchDelay, prom)
 	if err != nil {
 		c.Logger.Errorf("Failed to initialize WorkerPool for test %s: %v", test.TestID, err)
+		prom.Finish()
 		cancel()
 		return fmt.Errorf("failed to initialize WorkerPool: %w", err)
 	}
 
-	// Register the test with its CancelFunc and WorkerPool
-	c.tests[test.TestID] = &TestTask{
+	// Register the test with its CancelFunc, WorkerPool and admitted resources
+	task := &TestTask{
 		CancelFunc: cancel,
 		WorkerPool: wp,
+		Resources:  resources,
 	}
+	c.tests[test.TestID] = task
 
 	// Start the load generation in a new goroutine
 	go func() {
+		var loadErr error
 		defer func() {
 			// Shutdown resources and log upon task completion or error
 			err := wp.Shutdown()
 			if err != nil {
 				c.Logger.Errorf("Failed to shutdown WorkerPool for test %s: %v", test.TestID, err)
 			}
+			c.saveDeliveryStats(runCtx, test.TestID, wp.DeliveryStats())
+			run := wp.Run()
+			c.saveRun(runCtx, test.TestID, run)
+			prom.Finish()
 			cancel()
+			// Release the capacity and start queued tests that now fit
+			c.finishTest(test.TestID, task)
+			span.SetAttributes(
+				attribute.Int64("moniflux.generated", run.Generated),
+				attribute.Int64("moniflux.dropped", run.Dropped),
+			)
+			tracing.End(span, loadErr)
 		}()
 
-		// Generate load; handle any errors encountered during the process
-		if err := c.generateLoad(loadCtx, test, wp); err != nil {
+		// Generate load; handle any errors encountered during the process.
+		// Cancelled tests already have their status set by whoever cancelled them.
+		err := c.generateLoad(loadCtx, test, wp)
+		switch {
+		case errors.Is(err, context.Canceled):
+			span.AddEvent("cancelled")
+		case err != nil && !errors.Is(err, context.DeadlineExceeded):
 			c.Logger.Errorf("Load generation for test %s failed: %v", test.TestID, err)
-			c.updateTestStatus(context.Background(), test.TestID, "Error")
-		} else {
-			c.updateTestStatus(context.Background(), test.TestID, "Completed")
+			c.updateTestStatus(runCtx, test.TestID, "Error")
+			loadErr = err
+		default:
+			c.updateTestStatus(runCtx, test.TestID, "Completed")
 		}
 	}()
 
@@ -337,8 +524,8 @@ func (c *LoadGenController) generateLoad(ctx context.Context, test *models.Test,
 		select {
 		case <-done:
 			c.Logger.Infof("Load test duration completed: %s", test.TestID)
-			// Optionally, log final counts if HTTP destination
-			if test.Destination.Type == "http" {
+			// Optionally, log final counts if HTTP or socket destination
+			if test.Destination.Type != "file" {
 				successes, failures := wp.GetCounts()
 				c.Logger.Infof("Load test %s completed. Successes: %d, Failures: %d", test.TestID, successes, failures)
 			}
@@ -346,8 +533,8 @@ func (c *LoadGenController) generateLoad(ctx context.Context, test *models.Test,
 
 		case <-ctx.Done():
 			c.Logger.Infof("Load test context cancelled: %s, Reason: %v", test.TestID, ctx.Err())
-			// Optionally, log final counts if HTTP destination
-			if test.Destination.Type == "http" {
+			// Optionally, log final counts if HTTP or socket destination
+			if test.Destination.Type != "file" {
 				successes, failures := wp.GetCounts()
 				c.Logger.Infof("Load test %s cancelled. Successes: %d, Failures: %d", test.TestID, successes, failures)
 			}
@@ -371,9 +558,9 @@ func (c *LoadGenController) generateLoad(ctx context.Context, test *models.Test,
 			if generatedLogs%100000 == 0 {
 				elapsed := time.Since(startTime).Seconds()
 				c.Logger.Infof("Generated %d logs for test %s in %.2f seconds", generatedLogs, test.TestID, elapsed)
-				if test.Destination.Type == "http" {
+				if test.Destination.Type != "file" {
 					successes, failures := wp.GetCounts()
-					c.Logger.Infof("HTTP Logs - Successes: %d, Failures: %d", successes, failures)
+					c.Logger.Infof("Delivered Logs - Successes: %d, Failures: %d", successes, failures)
 				}
 			}
 
@@ -650,6 +837,92 @@ func (c *LoadGenController) updateTestStatus(ctx context.Context, testID, status
 	return nil
 }
 
+// saveDeliveryStats stores the delivery statistics of a finished test run.
+func (c *LoadGenController) saveDeliveryStats(ctx context.Context, testID string, stats *models.DeliveryStats) {
+	collection := c.MongoClient.Database(c.Config.MongoDB).Collection("tests")
+	_, err := collection.UpdateOne(ctx, bson.M{"testID": testID}, bson.M{"$set": bson.M{"deliveryStats": stats}})
+	if err != nil {
+		c.Logger.Errorf("Failed to save delivery stats for test %s: %v", testID, err)
+		return
+	}
+	if stats.File != nil {
+		c.Logger.Infof("Test %s wrote %d entries (%d bytes) to %d files using %d bytes of disk",
+			testID, stats.Entries, stats.File.BytesWritten, stats.File.Files, stats.File.DiskUsageBytes)
+		return
+	}
+	if stats.Objects != nil {
+		c.Logger.Infof("Test %s uploaded %d entries in %d objects (%d parts, %d bytes) to s3://%s; %d segments failed",
+			testID, stats.Entries, stats.Requests, stats.Objects.Parts, stats.WireBytes, stats.Objects.Bucket, stats.Failures)
+		return
+	}
+	c.Logger.Infof("Test %s sent %d entries in %d requests: %d bytes on the wire for %d raw bytes",
+		testID, stats.Entries, stats.Requests, stats.WireBytes, stats.RawBytes)
+}
+
+// saveRun stores the per-second statistics of a finished test run, replacing those
+// of an earlier run of the test.
+func (c *LoadGenController) saveRun(ctx context.Context, testID string, run models.TestRun) {
+	run.TestID = testID
+	collection := c.MongoClient.Database(c.Config.MongoDB).Collection("test_runs")
+	_, err := collection.ReplaceOne(ctx, bson.M{"testID": testID}, run, options.Replace().SetUpsert(true))
+	if err != nil {
+		c.Logger.Errorf("Failed to save the time series of test %s: %v", testID, err)
+	}
+}
+
+// GetRunResults returns the summary and per-second statistics of the latest run of a
+// test. Running tests report the run so far; tests that never ran have an empty
+// time series.
+func (c *LoadGenController) GetRunResults(ctx context.Context, testID string) (*models.RunResults, error) {
+	test, err := c.findAuthorizedTest(ctx, testID)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, fmt.Errorf("%w: %s", models.ErrTestNotFound, testID)
+		}
+		if errors.Is(err, models.ErrTestNotFound) {
+			return nil, err
+		}
+		return nil, fmt.Errorf("error retrieving test: %w", err)
+	}
+
+	var run *models.TestRun
+	c.mu.Lock()
+	task, running := c.tests[testID]
+	c.mu.Unlock()
+	if running && task.WorkerPool != nil {
+		live := task.WorkerPool.Run()
+		run = &live
+		test.DeliveryStats = task.WorkerPool.DeliveryStats()
+	} else {
+		var stored models.TestRun
+		collection := c.MongoClient.Database(c.Config.MongoDB).Collection("test_runs")
+		err := collection.FindOne(ctx, bson.M{"testID": testID}).Decode(&stored)
+		switch {
+		case err == nil:
+			run = &stored
+		case !errors.Is(err, mongo.ErrNoDocuments):
+			return nil, fmt.Errorf("error retrieving time series: %w", err)
+		}
+	}
+
+	results := &models.RunResults{Summary: summarizeRun(test, run), TimeSeries: []models.RunSecond{}}
+	if run != nil && run.Seconds != nil {
+		results.TimeSeries = run.Seconds
+	}
+	return results, nil
+}
+
+// liveDeliveryStats returns the delivery statistics of a running test, or nil.
+func (c *LoadGenController) liveDeliveryStats(testID string) *models.DeliveryStats {
+	c.mu.Lock()
+	task, ok := c.tests[testID]
+	c.mu.Unlock()
+	if !ok || task.WorkerPool == nil {
+		return nil
+	}
+	return task.WorkerPool.DeliveryStats()
+}
+
 // ScheduleTest schedules a test to start at a specified time.
 func (c *LoadGenController) ScheduleTest(ctx context.Context, scheduleReq *models.ScheduleRequest) error {
 	c.mu.Lock()
@@ -662,14 +935,21 @@ func (c *LoadGenController) ScheduleTest(ctx context.Context, scheduleReq *model
 	err := collection.FindOne(ctx, filter).Decode(&test)
 	if err != nil {
 		if errors.Is(err, mongo.ErrNoDocuments) {
-			return fmt.Errorf("test with ID %s not found", scheduleReq.TestID)
+			return fmt.Errorf("%w: %s", models.ErrTestNotFound, scheduleReq.TestID)
 		}
 		return fmt.Errorf("error retrieving test: %w", err)
 	}
+	if err := c.authorizeTest(ctx, &test); err != nil {
+		return err
+	}
+	// Concurrency limits are checked when the scheduled start is due.
+	if err := c.enforceGuardrails(ctx, &test, false); err != nil {
+		return err
+	}
 
 	// Only allow scheduling if the test is in "Pending" or "Scheduled" state.
 	if test.Status != "Pending" && test.Status != "Scheduled" {
-		return fmt.Errorf("test with ID %s cannot be scheduled in its current state: %s", scheduleReq.TestID, test.Status)
+		return fmt.Errorf("%w: test %s cannot be scheduled while %s", models.ErrInvalidTestState, scheduleReq.TestID, test.Status)
 	}
 
 	// Update the test's scheduledTime and status.
@@ -726,15 +1006,15 @@ func (c *LoadGenController) scheduleTestExecution(testID string, startTime time.
 			return
 		}
 
-		// Start the test.
-		err = c.StartTest(context.Background(), &test)
+		// Start the test, or queue it if the load generator is at capacity.
+		err = c.startTestLocked(context.Background(), &test)
 		if err != nil {
 			c.Logger.Errorf("Failed to start scheduled test %s: %v", testID, err)
 			c.updateTestStatus(context.Background(), testID, "Error")
 			return
 		}
 
-		c.Logger.Infof("Scheduled test %s started successfully", testID)
+		c.Logger.Infof("Scheduled test %s is now %s", testID, test.Status)
 	}
 }
 
@@ -752,16 +1032,22 @@ func (c *LoadGenController) CancelTest(ctx context.Context, testID string) error
 	err := collection.FindOne(ctx, filter).Decode(&test)
 	if err != nil {
 		if errors.Is(err, mongo.ErrNoDocuments) {
-			return fmt.Errorf("test with ID %s not found", testID)
+			return fmt.Errorf("%w: %s", models.ErrTestNotFound, testID)
 		}
 		c.Logger.Errorf("Error fetching test %s: %v", testID, err)
 		return fmt.Errorf("error fetching test: %w", err)
 	}
+	if err := c.authorizeTest(ctx, &test); err != nil {
+		return err
+	}
 
 	// Check if the test is already completed or cancelled.
 	if test.Status == "Completed" || test.Status == "Cancelled" {
 		c.Logger.Infof("Test with ID %s is already %s", testID, test.Status)
-		return fmt.Errorf("test with ID %s is already %s", testID, test.Status)
+		if test.Status == "Completed" {
+			return fmt.Errorf("%w: %s", models.ErrTestAlreadyCompleted, testID)
+		}
+		return fmt.Errorf("%w: %s", models.ErrTestAlreadyCancelled, testID)
 	}
 
 	// If the test is running, cancel the load generation.
@@ -775,6 +1061,16 @@ func (c *LoadGenController) CancelTest(ctx context.Context, testID string) error
 		}
 	}
 
+	// If the test is waiting for capacity, remove it from the queue. A queued test
+	// being started is not launched once its reservation is gone.
+	if test.Status == "Queued" && c.dequeueLocked(testID) {
+		c.Logger.Infof("Queued test %s removed from the admission queue", testID)
+	}
+	if _, starting := c.starting[testID]; starting {
+		delete(c.starting, testID)
+		c.Logger.Infof("Queued test %s cancelled while being started", testID)
+	}
+
 	// Update the test's status to "Cancelled" in the database.
 	update := bson.M{
 		"$set": bson.M{
@@ -809,15 +1105,18 @@ func (c *LoadGenController) RestartTest(ctx context.Context, restartReq *models.
 	err := collection.FindOne(ctx, filter).Decode(&test)
 	if err != nil {
 		if errors.Is(err, mongo.ErrNoDocuments) {
-			return fmt.Errorf("test with ID %s not found", restartReq.TestID)
+			return fmt.Errorf("%w: %s", models.ErrTestNotFound, restartReq.TestID)
 		}
 		c.Logger.Errorf("Error retrieving test with ID %s: %v", restartReq.TestID, err)
 		return fmt.Errorf("error retrieving test: %w", err)
 	}
+	if err := c.authorizeTest(ctx, &test); err != nil {
+		return err
+	}
 
 	// Check if the test status allows restarting.
 	if test.Status != "Completed" && test.Status != "Cancelled" && test.Status != "Error" {
-		return fmt.Errorf("test with ID %s cannot be restarted in its current state: %s", restartReq.TestID, test.Status)
+		return fmt.Errorf("%w: test %s cannot be restarted while %s", models.ErrInvalidTestState, restartReq.TestID, test.Status)
 	}
 
 	// Update the test's configuration if provided.
@@ -841,27 +1140,9 @@ func (c *LoadGenController) RestartTest(ctx context.Context, restartReq *models.
 
 	if len(updatedFields) == 0 {
 		c.Logger.Warnf("No valid configuration fields provided to update for test %s", restartReq.TestID)
-		return fmt.Errorf("no valid configuration fields provided to update")
-	}
-
-	// Update the test's status and reset relevant fields.
-	updatedFields["status"] = "Running"
-	updatedFields["updatedAt"] = time.Now()
-	updatedFields["completedAt"] = time.Time{}
-	updatedFields["scheduledTime"] = time.Time{}
-
-	update := bson.M{
-		"$set": updatedFields,
+		return fmt.Errorf("%w: no valid configuration fields provided to update", models.ErrInvalidTest)
 	}
 
-	_, err = collection.UpdateOne(ctx, filter, update)
-	if err != nil {
-		c.Logger.Errorf("Failed to update test %s in database: %v", restartReq.TestID, err)
-		return fmt.Errorf("failed to update test %s in database: %w", restartReq.TestID, err)
-	}
-
-	c.Logger.Infof("Test %s configuration updated for restart", restartReq.TestID)
-
 	// If the test was previously running, cancel the existing load generation.
 	if task, exists := c.tests[restartReq.TestID]; exists {
 		task.CancelFunc()
@@ -869,15 +1150,15 @@ func (c *LoadGenController) RestartTest(ctx context.Context, restartReq *models.
 		c.Logger.Infof("Existing load generation for test %s stopped for restart", restartReq.TestID)
 	}
 
-	// Start load generation with updated configuration.
-	err = c.StartTest(ctx, &test)
+	// Start load generation with the updated configuration, which enforces the
+	// guardrails and stores the configuration, or queue it if at capacity.
+	err = c.startTestLocked(ctx, &test)
 	if err != nil {
 		c.Logger.Errorf("Failed to restart load generation for test %s: %v", restartReq.TestID, err)
-		c.updateTestStatus(context.Background(), restartReq.TestID, "Error")
 		return fmt.Errorf("failed to restart load generation for test %s: %w", restartReq.TestID, err)
 	}
 
-	c.Logger.Infof("Test %s restarted successfully", restartReq.TestID)
+	c.Logger.Infof("Test %s restarted with status %s", restartReq.TestID, test.Status)
 	return nil
 }
 
@@ -890,14 +1171,17 @@ func (c *LoadGenController) SaveResults(ctx context.Context, results *models.Tes
 	err := collection.FindOne(ctx, filter).Decode(&test)
 	if err != nil {
 		if errors.Is(err, mongo.ErrNoDocuments) {
-			return fmt.Errorf("test with ID %s not found", results.TestID)
+			return fmt.Errorf("%w: %s", models.ErrTestNotFound, results.TestID)
 		}
 		return fmt.Errorf("error retrieving test: %w", err)
 	}
+	if err := c.authorizeTest(ctx, &test); err != nil {
+		return err
+	}
 
 	// Check if the test is in a state that allows saving results.
 	if test.Status != "Completed" && test.Status != "Error" {
-		return fmt.Errorf("test with ID %s cannot have results saved in its current state: %s", results.TestID, test.Status)
+		return fmt.Errorf("%w: results of test %s cannot be saved while %s", models.ErrInvalidTestState, results.TestID, test.Status)
 	}
 
 	// Insert the test results.
@@ -926,50 +1210,29 @@ func (c *LoadGenController) SaveResults(ctx context.Context, results *models.Tes
 	return nil
 }
 
-// GetAllTests retrieves all active and scheduled tests.
-func (c *LoadGenController) GetAllTests(ctx context.Context) ([]models.Test, error) {
-	var tests []models.Test
-	collection := c.MongoClient.Database(c.Config.MongoDB).Collection("tests")
-	cursor, err := collection.Find(ctx, bson.M{})
-	if err != nil {
-		c.Logger.Errorf("Failed to retrieve all tests: %v", err)
-		return nil, fmt.Errorf("failed to retrieve tests: %w", err)
-	}
-	defer cursor.Close(ctx)
-
-	for cursor.Next(ctx) {
-		var test models.Test
-		if err := cursor.Decode(&test); err != nil {
-			c.Logger.Errorf("Failed to decode test: %v", err)
-			continue
-		}
-		tests = append(tests, test)
-	}
-
-	if err := cursor.Err(); err != nil {
-		c.Logger.Errorf("Cursor error: %v", err)
-		return nil, fmt.Errorf("cursor error: %w", err)
-	}
-
-	c.Logger.Infof("Retrieved %d tests from the database", len(tests))
-	return tests, nil
-}
-
 // GetTestByID retrieves a specific test by its TestID.
 func (c *LoadGenController) GetTestByID(ctx context.Context, testID string) (*models.Test, error) {
-	var test models.Test
-	collection := c.MongoClient.Database(c.Config.MongoDB).Collection("tests")
-	filter := bson.M{"testID": testID}
-
-	err := collection.FindOne(ctx, filter).Decode(&test)
+	test, err := c.findAuthorizedTest(ctx, testID)
 	if err != nil {
 		if errors.Is(err, mongo.ErrNoDocuments) {
-			return nil, fmt.Errorf("test with ID %s not found", testID)
+			return nil, fmt.Errorf("%w: %s", models.ErrTestNotFound, testID)
+		}
+		if errors.Is(err, models.ErrTestNotFound) {
+			return nil, err
 		}
 		return nil, fmt.Errorf("error retrieving test: %w", err)
 	}
+	if test.Status == "Queued" {
+		test.QueuePosition = c.QueuePosition(testID)
+	}
+	if test.Status == "Running" {
+		if stats := c.liveDeliveryStats(testID); stats != nil {
+			test.DeliveryStats = stats
+		}
+	}
+	redactCredentials(test)
 
-	return &test, nil
+	return test, nil
 }
 
 // StopAllTests gracefully stops all running tests.
@@ -977,6 +1240,12 @@ func (c *LoadGenController) StopAllTests(ctx context.Context) error {
 	c.mu.Lock()
 	defer c.mu.Unlock()
 
+	// Queued tests stay "Queued" in the database and are restored on the next start.
+	c.stopped = true
+	c.queueMu.Lock()
+	c.queue = nil
+	c.queueMu.Unlock()
+
 	for testID, task := range c.tests {
 		task.CancelFunc()
 		delete(c.tests, testID)
@@ -1005,12 +1274,22 @@ func (c *LoadGenController) CreateTest(ctx context.Context, test *models.Test) e
 	isNewTest := errors.Is(err, mongo.ErrNoDocuments)
 
 	if !isNewTest {
-		return fmt.Errorf("test with ID %s already exists", test.TestID)
+		return fmt.Errorf("%w: %s", models.ErrTestAlreadyExists, test.TestID)
+	}
+
+	if err := c.assignOwnership(ctx, test); err != nil {
+		return err
+	}
+	if err := c.enforceGuardrails(ctx, test, false); err != nil {
+		return err
 	}
 
 	if test.TestID == "" {
 		test.TestID = uuid.New().String()
 	}
+	if err := c.prepareCredentials(ctx, test); err != nil {
+		return err
+	}
 	test.Status = "Pending"
 	test.CreatedAt, test.UpdatedAt = time.Now(), time.Now()
 
@@ -0,0 +1,147 @@
+// credentials.go
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"time"
+
+	"github.com/AkshayDubey29/MoniFlux/backend/internal/api/models"
+	"github.com/AkshayDubey29/MoniFlux/backend/internal/loadgen/delivery"
+	"github.com/AkshayDubey29/MoniFlux/backend/internal/services/secrets"
+)
+
+// credentialTimeout bounds the resolution of a destination credential before a delivery.
+const credentialTimeout = 5 * time.Second
+
+// prepareCredentials moves an inline API key into the secret store, so it is never
+// stored in plaintext with the test, and checks that every secret reference of the
+// destination exists and may be used by the test's team.
+func (c *LoadGenController) prepareCredentials(ctx context.Context, test *models.Test) error {
+	destination := &test.Destination
+	if destination.APIKey != "" {
+		if destination.SecretRef != "" {
+			return fmt.Errorf("%w: set either apiKey or secretRef, not both", secrets.ErrInvalidRef)
+		}
+		name := inlineKeySecret(test.TestID)
+		req := &secrets.SecretRequest{
+			Value:       destination.APIKey,
+			TeamID:      test.TeamID,
+			Description: "API key of test " + test.TestID,
+		}
+		if _, err := c.Secrets.PutSecret(ctx, name, req, test.UserID); err != nil {
+			return err
+		}
+		destination.SecretRef, destination.APIKey = name, ""
+		c.Logger.Infof("API key of test %s moved to secret %s", test.TestID, name)
+	}
+
+	if err := delivery.CheckAuth(*destination); err != nil {
+		return err
+	}
+	for _, ref := range destination.SecretRefs() {
+		if err := c.Secrets.CheckRef(ctx, ref, test.TeamID); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// dialGuard returns the guard checking every connection to the test destination
+// against the guardrails of the test's team, as the destination can resolve or
+// redirect to another address after it was validated.
+func (c *LoadGenController) dialGuard(ctx context.Context, test *models.Test) (delivery.DialGuard, error) {
+	guard, err := c.Guardrails.DialGuard(ctx, test.TeamID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load the destination guardrails: %w", err)
+	}
+	return guard, nil
+}
+
+// newHTTPSender builds the sender of an HTTP test destination, resolving its
+// credentials through the secret service.
+func (c *LoadGenController) newHTTPSender(ctx context.Context, test *models.Test) (*delivery.HTTPSender, error) {
+	ctx, cancel := context.WithTimeout(ctx, credentialTimeout)
+	defer cancel()
+	guard, err := c.dialGuard(ctx, test)
+	if err != nil {
+		return nil, err
+	}
+	if c.Secrets == nil {
+		return delivery.NewHTTPSender(ctx, test.Destination, nil, guard)
+	}
+	return delivery.NewHTTPSender(ctx, test.Destination, c.Secrets, guard)
+}
+
+// newSocketSender builds the sender of a syslog, tcp or udp test destination,
+// resolving its TLS client key through the secret service.
+func (c *LoadGenController) newSocketSender(ctx context.Context, test *models.Test) (*delivery.SocketSender, error) {
+	ctx, cancel := context.WithTimeout(ctx, credentialTimeout)
+	defer cancel()
+	guard, err := c.dialGuard(ctx, test)
+	if err != nil {
+		return nil, err
+	}
+	if c.Secrets == nil {
+		return delivery.NewSocketSender(ctx, test.Destination, nil, guard)
+	}
+	return delivery.NewSocketSender(ctx, test.Destination, c.Secrets, guard)
+}
+
+// newS3Uploader builds the uploader of an s3 test destination, resolving its
+// credentials through the secret service. Segments are written to the configured
+// segment directory.
+func (c *LoadGenController) newS3Uploader(ctx context.Context, test *models.Test) (*delivery.S3Uploader, error) {
+	ctx, cancel := context.WithTimeout(ctx, credentialTimeout)
+	defer cancel()
+	segmentDir := ""
+	if c.Config != nil {
+		segmentDir = c.Config.Delivery.SegmentDir
+	}
+	guard, err := c.dialGuard(ctx, test)
+	if err != nil {
+		return nil, err
+	}
+	if c.Secrets == nil {
+		return delivery.NewS3Uploader(ctx, test.Destination, nil, guard, segmentDir, c.Logger)
+	}
+	return delivery.NewS3Uploader(ctx, test.Destination, c.Secrets, guard, segmentDir, c.Logger)
+}
+
+// newDeliverer builds the deliverer of an HTTP test destination. Entries that exhaust
+// their retries are appended to <dead_letter_dir>/<testID>.ndjson, or dropped if no
+// directory is configured.
+func (c *LoadGenController) newDeliverer(ctx context.Context, test *models.Test) (*delivery.Deliverer, error) {
+	sender, err := c.newHTTPSender(ctx, test)
+	if err != nil {
+		return nil, err
+	}
+	var deadLetter *delivery.DeadLetter
+	if c.Config != nil && c.Config.Delivery.DeadLetterDir != "" {
+		deadLetter = delivery.NewDeadLetter(filepath.Join(c.Config.Delivery.DeadLetterDir, test.TestID+".ndjson"))
+	}
+	return delivery.NewDeliverer(test.Destination, sender, deadLetter, c.Logger), nil
+}
+
+// redactCredentials hides plaintext API keys left on tests stored before secret
+// references were introduced.
+func redactCredentials(test *models.Test) {
+	if test.Destination.APIKey != "" {
+		test.Destination.APIKey = secrets.Redacted
+	}
+}
+
+// keepStoredCredentials restores the stored API key of a test submitted back with the
+// redacted value returned by GetTestByID.
+func keepStoredCredentials(test, stored *models.Test) {
+	if test.Destination.APIKey == secrets.Redacted {
+		test.Destination.APIKey = stored.Destination.APIKey
+	}
+}
+
+// inlineKeySecret names the secret an inline API key of the test is moved to.
+func inlineKeySecret(testID string) string {
+	return "test-" + testID + "-api-key"
+}
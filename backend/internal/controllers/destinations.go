@@ -0,0 +1,65 @@
+// destinations.go
+
+package controllers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/AkshayDubey29/MoniFlux/backend/internal/api/models"
+	"github.com/AkshayDubey29/MoniFlux/backend/internal/services/destinations"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// ResolveDestination copies the registered destination named by the test's
+// DestinationName into its Destination. Tests keep the copy, so they are restarted
+// with the destination they were started with. Only destinations shared with every
+// team and those of the test's team can be used.
+func (c *LoadGenController) ResolveDestination(ctx context.Context, test *models.Test) error {
+	if test.DestinationName == "" {
+		return nil
+	}
+	if test.Destination.Type != "" {
+		return fmt.Errorf("%w: set either destination or destinationName, not both", destinations.ErrInvalidDestination)
+	}
+
+	teamID, err := c.owningTeam(ctx, test)
+	if err != nil {
+		return err
+	}
+	registered, err := c.Destinations.Resolve(ctx, test.DestinationName, teamID)
+	if err != nil {
+		return err
+	}
+	test.Destination = registered.Destination
+	c.Logger.Debugf("Test %s uses destination %s", test.TestID, registered.Name)
+	return nil
+}
+
+// owningTeam returns the team the test belongs to once started: the team of the
+// existing test, or the requested team or project of a new one, which the caller
+// must be a member of.
+func (c *LoadGenController) owningTeam(ctx context.Context, test *models.Test) (string, error) {
+	if test.TestID != "" {
+		var existing models.Test
+		collection := c.MongoClient.Database(c.Config.MongoDB).Collection("tests")
+		err := collection.FindOne(ctx, bson.M{"testID": test.TestID}).Decode(&existing)
+		switch {
+		case err == nil:
+			if err := c.authorizeTest(ctx, &existing); err != nil {
+				return "", err
+			}
+			return existing.TeamID, nil
+		case !errors.Is(err, mongo.ErrNoDocuments):
+			return "", fmt.Errorf("failed to look up test: %w", err)
+		}
+	}
+
+	candidate := models.Test{TeamID: test.TeamID, ProjectID: test.ProjectID}
+	if err := c.assignOwnership(ctx, &candidate); err != nil {
+		return "", err
+	}
+	return candidate.TeamID, nil
+}
@@ -0,0 +1,92 @@
+// guardrails.go
+
+package controllers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/AkshayDubey29/MoniFlux/backend/internal/api/models"
+	"github.com/AkshayDubey29/MoniFlux/backend/internal/loadgen/delivery"
+	"github.com/AkshayDubey29/MoniFlux/backend/internal/services/guardrails"
+	validator "github.com/go-playground/validator/v10"
+)
+
+// enforceGuardrails rejects test configurations that break the guardrails of the
+// test's team and the caller's roles. Concurrency limits apply only when starting.
+func (c *LoadGenController) enforceGuardrails(ctx context.Context, test *models.Test, starting bool) error {
+	principal, _ := models.PrincipalFromContext(ctx)
+	report, err := c.Guardrails.Evaluate(ctx, principal, test, starting)
+	if err != nil {
+		return err
+	}
+	if err := report.Err(); err != nil {
+		c.Logger.Warnf("Test %s rejected by guardrails: %v", test.TestID, err)
+		return err
+	}
+	return nil
+}
+
+// ValidateTest evaluates a test configuration the way StartTest would, without
+// storing or starting it, and reports every reason it would be rejected.
+func (c *LoadGenController) ValidateTest(ctx context.Context, test *models.Test) (*guardrails.Report, error) {
+	candidate := *test
+	c.assignDefaults(&candidate)
+	if err := c.assignOwnership(ctx, &candidate); err != nil {
+		return nil, err
+	}
+
+	principal, _ := models.PrincipalFromContext(ctx)
+	report, err := c.Guardrails.Evaluate(ctx, principal, &candidate, true)
+	if err != nil {
+		return nil, err
+	}
+
+	if reason := exceedsLimits(estimateResources(&candidate), c.admissionLimits()); reason != "" {
+		report.Violations = append(report.Violations, guardrails.Violation{
+			Rule:    guardrails.RuleCapacityExceeded,
+			Message: reason,
+		})
+	}
+
+	if err := delivery.CheckAuth(candidate.Destination); err != nil {
+		report.Violations = append(report.Violations, guardrails.Violation{
+			Rule:    guardrails.RuleInvalidField,
+			Field:   "destination.auth",
+			Message: err.Error(),
+		})
+	}
+	if candidate.Destination.Type == "s3" {
+		if err := delivery.CheckS3Options(candidate.Destination.S3); err != nil {
+			report.Violations = append(report.Violations, guardrails.Violation{
+				Rule:    guardrails.RuleInvalidField,
+				Field:   "destination.s3",
+				Message: err.Error(),
+			})
+		}
+	}
+	for _, ref := range candidate.Destination.SecretRefs() {
+		if err := c.Secrets.CheckRef(ctx, ref, candidate.TeamID); err != nil {
+			report.Violations = append(report.Violations, guardrails.Violation{
+				Rule:    guardrails.RuleInvalidField,
+				Field:   "destination",
+				Message: fmt.Sprintf("secret %q: %v", ref, err),
+			})
+		}
+	}
+
+	var fieldErrors validator.ValidationErrors
+	if err := c.Validator.Struct(candidate); errors.As(err, &fieldErrors) {
+		for _, fe := range fieldErrors {
+			report.Violations = append(report.Violations, guardrails.Violation{
+				Rule:    guardrails.RuleInvalidField,
+				Field:   fe.Namespace(),
+				Message: fmt.Sprintf("%s failed the '%s' validation", fe.Field(), fe.Tag()),
+			})
+		}
+	}
+
+	report.Allowed = len(report.Violations) == 0
+	return report, nil
+}
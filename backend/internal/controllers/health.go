@@ -0,0 +1,65 @@
+// health.go
+
+package controllers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// runningPools returns the worker pools of the running tests, by test ID.
+func (c *LoadGenController) runningPools() map[string]*WorkerPool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	pools := make(map[string]*WorkerPool, len(c.tests))
+	for testID, task := range c.tests {
+		pools[testID] = task.WorkerPool
+	}
+	return pools
+}
+
+// CheckDestinations reports whether the destinations of the running tests can be
+// reached. They are checked concurrently, and the failures are returned together.
+func (c *LoadGenController) CheckDestinations(ctx context.Context) error {
+	pools := c.runningPools()
+	var (
+		mu       sync.Mutex
+		wg       sync.WaitGroup
+		failures []error
+	)
+	for testID, wp := range pools {
+		wg.Add(1)
+		go func(testID string, wp *WorkerPool) {
+			defer wg.Done()
+			if err := wp.CheckDestination(ctx); err != nil {
+				mu.Lock()
+				failures = append(failures, fmt.Errorf("test %s: %w", testID, err))
+				mu.Unlock()
+			}
+		}(testID, wp)
+	}
+	wg.Wait()
+	sort.Slice(failures, func(i, j int) bool { return failures[i].Error() < failures[j].Error() })
+	return errors.Join(failures...)
+}
+
+// CheckSaturation reports the running tests whose job queue is fuller than
+// threshold, a fraction between 0 and 1. Their workers cannot keep up and entries are
+// about to be dropped.
+func (c *LoadGenController) CheckSaturation(threshold float64) error {
+	var saturated []string
+	for testID, wp := range c.runningPools() {
+		if usage := wp.QueueUsage(); usage > threshold {
+			saturated = append(saturated, fmt.Sprintf("%s (%.0f%%)", testID, usage*100))
+		}
+	}
+	if len(saturated) == 0 {
+		return nil
+	}
+	sort.Strings(saturated)
+	return fmt.Errorf("job queue above %.0f%% for tests %s", threshold*100, strings.Join(saturated, ", "))
+}
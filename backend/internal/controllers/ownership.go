@@ -0,0 +1,100 @@
+// ownership.go
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/AkshayDubey29/MoniFlux/backend/internal/api/models"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// scopeFilter returns the MongoDB filter restricting tests to those the caller may see:
+// tests they own and tests belonging to their teams. It returns nil when no restriction
+// applies, i.e. for admins and for internal operations without a principal.
+func (c *LoadGenController) scopeFilter(ctx context.Context) (bson.M, error) {
+	principal, ok := models.PrincipalFromContext(ctx)
+	if !ok || principal.IsAdmin() {
+		return nil, nil
+	}
+
+	teamIDs, err := c.Tenancy.TeamIDsForUser(ctx, principal.UserID)
+	if err != nil {
+		return nil, err
+	}
+
+	scopes := bson.A{bson.M{"userID": principal.UserID}}
+	if len(teamIDs) > 0 {
+		scopes = append(scopes, bson.M{"teamID": bson.M{"$in": teamIDs}})
+	}
+	return bson.M{"$or": scopes}, nil
+}
+
+// authorizeTest ensures the caller may view and mutate the given test.
+// Tests outside the caller's tenants are reported as not found so their existence is not leaked.
+func (c *LoadGenController) authorizeTest(ctx context.Context, test *models.Test) error {
+	principal, ok := models.PrincipalFromContext(ctx)
+	if !ok || principal.IsAdmin() || test.UserID == principal.UserID {
+		return nil
+	}
+
+	if test.TeamID != "" {
+		member, err := c.Tenancy.IsMember(ctx, test.TeamID, principal.UserID)
+		if err != nil {
+			return err
+		}
+		if member {
+			return nil
+		}
+	}
+
+	c.Logger.Warnf("User %s denied access to test %s", principal.UserID, test.TestID)
+	return fmt.Errorf("%w: %s", models.ErrTestNotFound, test.TestID)
+}
+
+// assignOwnership stamps a new test with the caller as its owner and verifies that
+// the requested team and project, if any, belong to the caller's tenants.
+func (c *LoadGenController) assignOwnership(ctx context.Context, test *models.Test) error {
+	principal, ok := models.PrincipalFromContext(ctx)
+	if ok {
+		test.UserID = principal.UserID
+	}
+
+	if test.ProjectID != "" {
+		project, err := c.Tenancy.GetProject(ctx, test.ProjectID)
+		if err != nil {
+			return err
+		}
+		if test.TeamID != "" && test.TeamID != project.TeamID {
+			return fmt.Errorf("%w: project %s does not belong to team %s", models.ErrInvalidTest, test.ProjectID, test.TeamID)
+		}
+		test.TeamID = project.TeamID
+	}
+
+	if test.TeamID == "" || !ok || principal.IsAdmin() {
+		return nil
+	}
+
+	member, err := c.Tenancy.IsMember(ctx, test.TeamID, principal.UserID)
+	if err != nil {
+		return err
+	}
+	if !member {
+		return fmt.Errorf("%w: user %s is not a member of team %s", models.ErrForbidden, principal.UserID, test.TeamID)
+	}
+	return nil
+}
+
+// findAuthorizedTest loads a test by ID and ensures the caller may access it.
+func (c *LoadGenController) findAuthorizedTest(ctx context.Context, testID string) (*models.Test, error) {
+	var test models.Test
+	collection := c.MongoClient.Database(c.Config.MongoDB).Collection("tests")
+	if err := collection.FindOne(ctx, bson.M{"testID": testID}).Decode(&test); err != nil {
+		return nil, err
+	}
+	if err := c.authorizeTest(ctx, &test); err != nil {
+		return nil, err
+	}
+	return &test, nil
+}
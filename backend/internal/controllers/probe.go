@@ -0,0 +1,161 @@
+// probe.go
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/AkshayDubey29/MoniFlux/backend/internal/api/models"
+	"github.com/AkshayDubey29/MoniFlux/backend/internal/loadgen/delivery"
+	"github.com/AkshayDubey29/MoniFlux/backend/internal/loadgen/metrics"
+	"github.com/AkshayDubey29/MoniFlux/backend/internal/services/secrets"
+	"github.com/google/uuid"
+)
+
+// probeAPIKeyRef is the secret reference under which an inline API key is resolved
+// during a probe, without storing it.
+const probeAPIKeyRef = "probe:api-key"
+
+// inlineSecrets resolves the inline API key of a probed destination and delegates
+// every other reference.
+type inlineSecrets struct {
+	apiKey string
+	next   delivery.SecretResolver
+}
+
+func (s inlineSecrets) Resolve(ctx context.Context, ref string) (string, error) {
+	if ref == probeAPIKeyRef {
+		return s.apiKey, nil
+	}
+	if s.next == nil {
+		return "", fmt.Errorf("cannot resolve %q without the secret service", ref)
+	}
+	return s.next.Resolve(ctx, ref)
+}
+
+// ProbeDestination checks that the destination of the test accepts deliveries, by
+// sending one sample entry of each signal, and reports the outcome of every phase.
+// Only the destination, team and project of the test are used. The destination is
+// held to the guardrails of the team, and its secret references must be usable by
+// the team; a rejected destination is returned as an error, not probed.
+func (c *LoadGenController) ProbeDestination(ctx context.Context, test *models.Test) (*models.ProbeReport, error) {
+	candidate := models.Test{
+		TestID:      test.TestID,
+		TeamID:      test.TeamID,
+		ProjectID:   test.ProjectID,
+		LogType:     test.LogType,
+		LogSize:     test.LogSize,
+		Destination: test.Destination,
+	}
+	if err := c.assignOwnership(ctx, &candidate); err != nil {
+		return nil, err
+	}
+
+	// Role and concurrency limits do not apply to a probe; destination rules do.
+	report, err := c.Guardrails.Evaluate(ctx, nil, &candidate, false)
+	if err != nil {
+		return nil, err
+	}
+	if err := report.Err(); err != nil {
+		c.Logger.Warnf("Probe of %s destination rejected by guardrails: %v", candidate.Destination.Type, err)
+		return nil, err
+	}
+
+	destination := candidate.Destination
+	if destination.APIKey != "" {
+		if destination.SecretRef != "" {
+			return nil, fmt.Errorf("%w: set either apiKey or secretRef, not both", secrets.ErrInvalidRef)
+		}
+		destination.SecretRef, destination.APIKey = probeAPIKeyRef, ""
+	}
+	if err := delivery.CheckAuth(destination); err != nil {
+		return nil, err
+	}
+	var resolver delivery.SecretResolver
+	if c.Secrets != nil {
+		for _, ref := range destination.SecretRefs() {
+			if ref == probeAPIKeyRef {
+				continue
+			}
+			if err := c.Secrets.CheckRef(ctx, ref, candidate.TeamID); err != nil {
+				return nil, err
+			}
+		}
+		resolver = c.Secrets
+	}
+	if test.Destination.APIKey != "" || resolver != nil {
+		resolver = inlineSecrets{apiKey: test.Destination.APIKey, next: resolver}
+	}
+
+	guard, err := c.dialGuard(ctx, &candidate)
+	if err != nil {
+		return nil, err
+	}
+	probe := delivery.Probe(ctx, destination, resolver, guard, probeSamples(&candidate))
+	c.Logger.Infof("Probed %s destination %s: success=%t in %.0fms", probe.Destination, probe.Target, probe.Success, probe.LatencyMs)
+	return probe, nil
+}
+
+// probeSamples returns a log, metric and trace entry like those generated by the
+// test, marked with a probe test ID when the test has none.
+func probeSamples(test *models.Test) []delivery.ProbeSample {
+	testID := test.TestID
+	if testID == "" {
+		testID = "probe-" + uuid.New().String()
+	}
+	level := test.LogType
+	if level == "" {
+		level = "INFO"
+	}
+	size := test.LogSize
+	if size <= 0 {
+		size = 64
+	}
+	now := time.Now().UTC()
+	return []delivery.ProbeSample{
+		{Signal: metrics.SignalLog, Entry: models.LogEntry{
+			TestID:    testID,
+			Timestamp: now,
+			Message:   generateRandomMessage(size),
+			Level:     level,
+		}},
+		{Signal: metrics.SignalMetric, Entry: models.Metric{
+			TestID:    testID,
+			Timestamp: now,
+			Value:     generateRandomMetricValue(),
+		}},
+		{Signal: metrics.SignalTrace, Entry: models.Trace{
+			TestID:    testID,
+			Timestamp: now,
+			TraceID:   uuid.New().String(),
+			SpanID:    uuid.New().String(),
+			Operation: "SimulatedOperation",
+			Duration:  generateRandomDuration(),
+		}},
+	}
+}
+
+// preflight probes the destination of a test about to be started and returns a
+// *models.PreflightError if a phase failed. Existing tests are probed with the
+// team they belong to, as they are started.
+func (c *LoadGenController) preflight(ctx context.Context, test *models.Test) error {
+	candidate := *test
+	c.assignDefaults(&candidate)
+	teamID, err := c.owningTeam(ctx, test)
+	if err != nil {
+		return err
+	}
+	candidate.TeamID, candidate.ProjectID = teamID, ""
+
+	report, err := c.ProbeDestination(ctx, &candidate)
+	if err != nil {
+		return err
+	}
+	if !report.Success {
+		c.Logger.Warnf("Test %s not started: destination preflight failed", test.TestID)
+		return &models.PreflightError{Report: report}
+	}
+	return nil
+}
@@ -0,0 +1,246 @@
+// test-query.go
+
+package controllers
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/AkshayDubey29/MoniFlux/backend/internal/api/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const (
+	defaultTestPageSize = 50
+	maxTestPageSize     = 500
+)
+
+// sortFields maps the public sort keys to their BSON field names.
+var sortFields = map[string]string{
+	"createdAt": "createdAt",
+	"updatedAt": "updatedAt",
+	"status":    "status",
+	"testID":    "testID",
+}
+
+// testCursor is the opaque position marker handed out as NextCursor.
+// It holds the sort value of the last returned test and its TestID as a tie-breaker.
+type testCursor struct {
+	SortBy string `json:"s"`
+	Value  string `json:"v"`
+	TestID string `json:"id"`
+}
+
+// encodeTestCursor builds the cursor pointing after the given test.
+func encodeTestCursor(sortBy string, test *models.Test) string {
+	cur := testCursor{SortBy: sortBy, TestID: test.TestID}
+	switch sortBy {
+	case "createdAt":
+		cur.Value = test.CreatedAt.UTC().Format(time.RFC3339Nano)
+	case "updatedAt":
+		cur.Value = test.UpdatedAt.UTC().Format(time.RFC3339Nano)
+	case "status":
+		cur.Value = test.Status
+	case "testID":
+		cur.Value = test.TestID
+	}
+
+	data, _ := json.Marshal(cur)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// decodeTestCursor parses a cursor and returns the sort value in its BSON-comparable form.
+func decodeTestCursor(raw, sortBy string) (interface{}, string, error) {
+	data, err := base64.RawURLEncoding.DecodeString(raw)
+	if err != nil {
+		return nil, "", models.ErrInvalidCursor
+	}
+
+	var cur testCursor
+	if err := json.Unmarshal(data, &cur); err != nil || cur.TestID == "" {
+		return nil, "", models.ErrInvalidCursor
+	}
+	// A cursor is only meaningful for the sort order it was issued for.
+	if cur.SortBy != sortBy {
+		return nil, "", models.ErrInvalidCursor
+	}
+
+	switch sortBy {
+	case "createdAt", "updatedAt":
+		t, err := time.Parse(time.RFC3339Nano, cur.Value)
+		if err != nil {
+			return nil, "", models.ErrInvalidCursor
+		}
+		return t, cur.TestID, nil
+	default:
+		return cur.Value, cur.TestID, nil
+	}
+}
+
+// buildTestFilter translates a TestListQuery into a MongoDB filter, excluding the cursor.
+func buildTestFilter(query *models.TestListQuery) bson.M {
+	filter := bson.M{}
+
+	if len(query.Status) == 1 {
+		filter["status"] = query.Status[0]
+	} else if len(query.Status) > 1 {
+		filter["status"] = bson.M{"$in": query.Status}
+	}
+	if query.UserID != "" {
+		filter["userID"] = query.UserID
+	}
+	if query.TeamID != "" {
+		filter["teamID"] = query.TeamID
+	}
+	if query.ProjectID != "" {
+		filter["projectID"] = query.ProjectID
+	}
+	if query.DestinationType != "" {
+		filter["destination.type"] = query.DestinationType
+	}
+	if len(query.Tags) > 0 {
+		filter["tags"] = bson.M{"$all": query.Tags}
+	}
+
+	if r := timeRange(query.CreatedAfter, query.CreatedBefore); r != nil {
+		filter["createdAt"] = r
+	}
+	if r := timeRange(query.UpdatedAfter, query.UpdatedBefore); r != nil {
+		filter["updatedAt"] = r
+	}
+
+	return filter
+}
+
+// timeRange returns a range condition for the given bounds, or nil if neither is set.
+func timeRange(after, before time.Time) bson.M {
+	if after.IsZero() && before.IsZero() {
+		return nil
+	}
+	r := bson.M{}
+	if !after.IsZero() {
+		r["$gte"] = after
+	}
+	if !before.IsZero() {
+		r["$lt"] = before
+	}
+	return r
+}
+
+// normalizeTestListQuery fills in default sorting and page size.
+func normalizeTestListQuery(query *models.TestListQuery) {
+	if query.SortBy == "" {
+		query.SortBy = "createdAt"
+	}
+	if query.SortOrder == "" {
+		query.SortOrder = "desc"
+	}
+	if query.Limit <= 0 {
+		query.Limit = defaultTestPageSize
+	}
+	if query.Limit > maxTestPageSize {
+		query.Limit = maxTestPageSize
+	}
+}
+
+// GetAllTests retrieves a page of tests matching the query's filters, sorted and paginated
+// with an opaque cursor. A nil query returns the first page with default options.
+func (c *LoadGenController) GetAllTests(ctx context.Context, query *models.TestListQuery) (*models.TestPage, error) {
+	if query == nil {
+		query = &models.TestListQuery{}
+	}
+	normalizeTestListQuery(query)
+
+	field, ok := sortFields[query.SortBy]
+	if !ok {
+		return nil, fmt.Errorf("unsupported sort field: %s", query.SortBy)
+	}
+	direction := -1
+	cmp := "$lt"
+	if query.SortOrder == "asc" {
+		direction = 1
+		cmp = "$gt"
+	}
+
+	filter := buildTestFilter(query)
+
+	// Restrict the listing to the caller's own and team tests.
+	scope, err := c.scopeFilter(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve tenant scope: %w", err)
+	}
+	if scope != nil {
+		filter = bson.M{"$and": bson.A{filter, scope}}
+	}
+
+	if query.Cursor != "" {
+		value, lastID, err := decodeTestCursor(query.Cursor, query.SortBy)
+		if err != nil {
+			return nil, err
+		}
+
+		var after bson.M
+		if field == "testID" {
+			after = bson.M{"testID": bson.M{cmp: lastID}}
+		} else {
+			after = bson.M{"$or": bson.A{
+				bson.M{field: bson.M{cmp: value}},
+				bson.M{field: value, "testID": bson.M{cmp: lastID}},
+			}}
+		}
+		filter = bson.M{"$and": bson.A{filter, after}}
+	}
+
+	sort := bson.D{{Key: field, Value: direction}}
+	if field != "testID" {
+		sort = append(sort, bson.E{Key: "testID", Value: direction})
+	}
+
+	// Fetch one extra document to know whether another page exists.
+	opts := options.Find().SetSort(sort).SetLimit(int64(query.Limit + 1))
+
+	collection := c.MongoClient.Database(c.Config.MongoDB).Collection("tests")
+	cursor, err := collection.Find(ctx, filter, opts)
+	if err != nil {
+		c.Logger.Errorf("Failed to retrieve tests: %v", err)
+		return nil, fmt.Errorf("failed to retrieve tests: %w", err)
+	}
+	defer cursor.Close(ctx)
+
+	tests := make([]models.Test, 0, query.Limit)
+	for cursor.Next(ctx) {
+		var test models.Test
+		if err := cursor.Decode(&test); err != nil {
+			c.Logger.Errorf("Failed to decode test: %v", err)
+			continue
+		}
+		if test.Status == "Queued" {
+			test.QueuePosition = c.QueuePosition(test.TestID)
+		}
+		if test.Status == "Running" {
+			if stats := c.liveDeliveryStats(test.TestID); stats != nil {
+				test.DeliveryStats = stats
+			}
+		}
+		redactCredentials(&test)
+		tests = append(tests, test)
+	}
+
+	if err := cursor.Err(); err != nil {
+		c.Logger.Errorf("Cursor error: %v", err)
+		return nil, fmt.Errorf("cursor error: %w", err)
+	}
+
+	page := &models.TestPage{Tests: tests}
+	if len(tests) > query.Limit {
+		page.Tests = tests[:query.Limit]
+		page.NextCursor = encodeTestCursor(query.SortBy, &page.Tests[query.Limit-1])
+	}
+
+	c.Logger.Infof("Retrieved %d tests from the database", len(page.Tests))
+	return page, nil
+}
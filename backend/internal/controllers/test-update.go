@@ -0,0 +1,117 @@
+// test-update.go
+
+package controllers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/AkshayDubey29/MoniFlux/backend/internal/api/models"
+	"github.com/AkshayDubey29/MoniFlux/backend/internal/services/secrets"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// UpdateTest replaces the configuration of a test that is not running or queued;
+// running tests are changed by restarting them. The owner, tenant, status and
+// timestamps of the stored test are kept.
+func (c *LoadGenController) UpdateTest(ctx context.Context, test *models.Test) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	stored, err := c.lookupTest(ctx, test.TestID)
+	if err != nil {
+		return err
+	}
+	if stored.Status == "Running" || stored.Status == "Queued" {
+		return fmt.Errorf("%w: test %s cannot be updated while %s, restart it with the new settings", models.ErrInvalidTestState, test.TestID, stored.Status)
+	}
+
+	test.UserID, test.TeamID, test.ProjectID = stored.UserID, stored.TeamID, stored.ProjectID
+	test.Status, test.ScheduledTime = stored.Status, stored.ScheduledTime
+	test.CreatedAt, test.CompletedAt = stored.CreatedAt, stored.CompletedAt
+	keepStoredCredentials(test, stored)
+
+	c.assignDefaults(test)
+	if err := c.Validator.Struct(test); err != nil {
+		return fmt.Errorf("%w: %w", models.ErrInvalidTest, err)
+	}
+	if err := c.enforceGuardrails(ctx, test, false); err != nil {
+		return err
+	}
+	if err := c.prepareCredentials(ctx, test); err != nil {
+		return err
+	}
+
+	test.UpdatedAt = time.Now()
+	update := bson.M{
+		"$set": bson.M{
+			"logType":         test.LogType,
+			"logRate":         test.LogRate,
+			"logSize":         test.LogSize,
+			"metricsRate":     test.MetricsRate,
+			"traceRate":       test.TraceRate,
+			"duration":        test.Duration,
+			"destination":     test.Destination,
+			"destinationName": test.DestinationName,
+			"tags":            test.Tags,
+			"updatedAt":       test.UpdatedAt,
+		},
+	}
+	collection := c.MongoClient.Database(c.Config.MongoDB).Collection("tests")
+	if _, err := collection.UpdateOne(ctx, bson.M{"testID": test.TestID}, update); err != nil {
+		c.Logger.Errorf("Failed to update test %s: %v", test.TestID, err)
+		return fmt.Errorf("failed to update test: %w", err)
+	}
+	redactCredentials(test)
+
+	c.Logger.Infof("Test %s configuration updated", test.TestID)
+	return nil
+}
+
+// DeleteTest removes a test, its time series and results, and the secret holding
+// the API key it was created with. Running, queued and scheduled tests must be
+// cancelled first.
+func (c *LoadGenController) DeleteTest(ctx context.Context, testID string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	stored, err := c.lookupTest(ctx, testID)
+	if err != nil {
+		return err
+	}
+	switch stored.Status {
+	case "Running", "Queued", "Scheduled":
+		return fmt.Errorf("%w: test %s cannot be deleted while %s, cancel it first", models.ErrInvalidTestState, testID, stored.Status)
+	}
+
+	db := c.MongoClient.Database(c.Config.MongoDB)
+	if _, err := db.Collection("tests").DeleteOne(ctx, bson.M{"testID": testID}); err != nil {
+		c.Logger.Errorf("Failed to delete test %s: %v", testID, err)
+		return fmt.Errorf("failed to delete test: %w", err)
+	}
+	for _, name := range []string{"test_runs", "test_results"} {
+		if _, err := db.Collection(name).DeleteMany(ctx, bson.M{"testID": testID}); err != nil {
+			c.Logger.Errorf("Failed to delete the %s of test %s: %v", name, testID, err)
+		}
+	}
+	if ref := stored.Destination.SecretRef; ref == inlineKeySecret(testID) {
+		if err := c.Secrets.DeleteSecret(ctx, ref); err != nil && !errors.Is(err, secrets.ErrSecretNotFound) {
+			c.Logger.Errorf("Failed to delete secret %s of test %s: %v", ref, testID, err)
+		}
+	}
+
+	c.Logger.Infof("Test %s deleted", testID)
+	return nil
+}
+
+// lookupTest loads a stored test, with its credentials, that the caller may access.
+func (c *LoadGenController) lookupTest(ctx context.Context, testID string) (*models.Test, error) {
+	test, err := c.findAuthorizedTest(ctx, testID)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, fmt.Errorf("%w: %s", models.ErrTestNotFound, testID)
+	}
+	return test, err
+}
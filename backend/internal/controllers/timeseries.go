@@ -0,0 +1,233 @@
+// timeseries.go
+
+package controllers
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/AkshayDubey29/MoniFlux/backend/internal/api/models"
+	"github.com/AkshayDubey29/MoniFlux/backend/internal/loadgen/metrics"
+)
+
+// runSampleInterval is the resolution of the time series of a run.
+const runSampleInterval = time.Second
+
+// runRecorder collects the per-second statistics of a test run. The counters of the
+// current second are updated atomically by the generator and the workers, and are
+// moved into the time series every second. Delivered entries and errors are the
+// differences between two samples of the delivery statistics. Every event is also
+// passed on to the Prometheus metrics of the test.
+type runRecorder struct {
+	// Counters of the current second, accessed atomically.
+	logs         int64
+	metrics      int64
+	traces       int64
+	dropped      int64
+	batches      int64
+	latencyNanos int64
+	maxNanos     int64
+
+	stats func() *models.DeliveryStats
+	prom  *metrics.Test // nil if the engine metrics are disabled
+
+	mu            sync.Mutex
+	run           models.TestRun
+	totalNanos    int64
+	lastDelivered int64
+	lastErrors    int64
+
+	stopOnce sync.Once
+	quit     chan struct{}
+	done     chan struct{}
+}
+
+// newRunRecorder starts recording a run whose cumulative delivery statistics are
+// returned by stats.
+func newRunRecorder(stats func() *models.DeliveryStats, prom *metrics.Test) *runRecorder {
+	r := &runRecorder{
+		stats: stats,
+		prom:  prom,
+		run:   models.TestRun{StartedAt: time.Now().UTC()},
+		quit:  make(chan struct{}),
+		done:  make(chan struct{}),
+	}
+	go r.sampleLoop()
+	return r
+}
+
+// generated counts a generated entry, including entries dropped later.
+func (r *runRecorder) generated(entry interface{}) {
+	switch entry.(type) {
+	case models.LogEntry:
+		atomic.AddInt64(&r.logs, 1)
+	case models.Metric:
+		atomic.AddInt64(&r.metrics, 1)
+	case models.Trace:
+		atomic.AddInt64(&r.traces, 1)
+	}
+	r.prom.Generated(signalOf(entry))
+}
+
+// enqueued counts an entry handed to the workers.
+func (r *runRecorder) enqueued(entry interface{}) {
+	r.prom.Enqueued(signalOf(entry))
+}
+
+// drop counts an entry dropped because the workers were busy.
+func (r *runRecorder) drop(entry interface{}) {
+	atomic.AddInt64(&r.dropped, 1)
+	r.prom.Dropped(signalOf(entry))
+}
+
+// signalOf returns the signal label of an entry.
+func signalOf(entry interface{}) string {
+	switch entry.(type) {
+	case models.Metric:
+		return metrics.SignalMetric
+	case models.Trace:
+		return metrics.SignalTrace
+	default:
+		return metrics.SignalLog
+	}
+}
+
+// batch records the time it took to hand a batch, or an entry, to the destination.
+func (r *runRecorder) batch(latency time.Duration) {
+	r.prom.ObserveLatency(latency)
+	atomic.AddInt64(&r.batches, 1)
+	atomic.AddInt64(&r.latencyNanos, int64(latency))
+	for {
+		max := atomic.LoadInt64(&r.maxNanos)
+		if int64(latency) <= max || atomic.CompareAndSwapInt64(&r.maxNanos, max, int64(latency)) {
+			return
+		}
+	}
+}
+
+func (r *runRecorder) sampleLoop() {
+	defer close(r.done)
+	ticker := time.NewTicker(runSampleInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			r.sample(false)
+		case <-r.quit:
+			return
+		}
+	}
+}
+
+// sample moves the counters of the current second into the time series. The last
+// sample of a run is kept only if something happened in it.
+func (r *runRecorder) sample(last bool) {
+	second := models.RunSecond{
+		Logs:    atomic.SwapInt64(&r.logs, 0),
+		Metrics: atomic.SwapInt64(&r.metrics, 0),
+		Traces:  atomic.SwapInt64(&r.traces, 0),
+		Dropped: atomic.SwapInt64(&r.dropped, 0),
+		Batches: atomic.SwapInt64(&r.batches, 0),
+	}
+	latency := atomic.SwapInt64(&r.latencyNanos, 0)
+	max := atomic.SwapInt64(&r.maxNanos, 0)
+	second.Rate = second.Logs + second.Metrics + second.Traces
+	if second.Batches > 0 {
+		second.LatencyAvgMs = nanosToMillis(latency / second.Batches)
+	}
+	second.LatencyMaxMs = nanosToMillis(max)
+	var delivered, errors int64
+	if stats := r.stats(); stats != nil {
+		delivered, errors = stats.Entries, stats.Failures
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	second.Delivered, second.Errors = delivered-r.lastDelivered, errors-r.lastErrors
+	r.lastDelivered, r.lastErrors = delivered, errors
+	r.prom.Delivered(second.Delivered, second.Errors)
+
+	run := &r.run
+	run.Generated += second.Rate
+	run.Dropped += second.Dropped
+	run.Batches += second.Batches
+	r.totalNanos += latency
+	if run.Batches > 0 {
+		run.LatencyAvgMs = nanosToMillis(r.totalNanos / run.Batches)
+	}
+	if second.LatencyMaxMs > run.LatencyMaxMs {
+		run.LatencyMaxMs = second.LatencyMaxMs
+	}
+	if last && second.Rate == 0 && second.Dropped == 0 && second.Batches == 0 && second.Delivered == 0 && second.Errors == 0 {
+		return
+	}
+	if len(run.Seconds) >= models.MaxRunSeconds {
+		run.Truncated = true
+		return
+	}
+	second.Second = len(run.Seconds)
+	second.Timestamp = run.StartedAt.Add(time.Duration(second.Second) * runSampleInterval)
+	run.Seconds = append(run.Seconds, second)
+}
+
+// stop records the last, partial second and ends the run. It must be called once
+// the entries have been delivered, so the last sample includes them.
+func (r *runRecorder) stop() {
+	r.stopOnce.Do(func() {
+		close(r.quit)
+		<-r.done
+		r.sample(true)
+		r.mu.Lock()
+		r.run.EndedAt = time.Now().UTC()
+		r.mu.Unlock()
+	})
+}
+
+// snapshot returns a copy of the run recorded so far.
+func (r *runRecorder) snapshot() models.TestRun {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	run := r.run
+	run.Seconds = append([]models.RunSecond(nil), r.run.Seconds...)
+	return run
+}
+
+func nanosToMillis(nanos int64) float64 {
+	return float64(nanos) / float64(time.Millisecond)
+}
+
+// summarizeRun builds the summary of a test run from the test and its recorded
+// statistics.
+func summarizeRun(test *models.Test, run *models.TestRun) models.RunSummary {
+	summary := models.RunSummary{
+		TestID:        test.TestID,
+		Status:        test.Status,
+		TargetRate:    test.LogRate + test.MetricsRate + test.TraceRate,
+		DeliveryStats: test.DeliveryStats,
+	}
+	if stats := test.DeliveryStats; stats != nil {
+		summary.Delivered, summary.Errors = stats.Entries, stats.Failures
+	}
+	if run == nil || run.StartedAt.IsZero() {
+		return summary
+	}
+	startedAt := run.StartedAt
+	summary.StartedAt = &startedAt
+	end := time.Now().UTC()
+	if !run.EndedAt.IsZero() {
+		endedAt := run.EndedAt
+		summary.EndedAt, end = &endedAt, endedAt
+	}
+	summary.DurationSec = end.Sub(startedAt).Seconds()
+	summary.Generated = run.Generated
+	summary.Dropped = run.Dropped
+	summary.Batches = run.Batches
+	summary.LatencyAvgMs = run.LatencyAvgMs
+	summary.LatencyMaxMs = run.LatencyMaxMs
+	summary.Truncated = run.Truncated
+	if summary.DurationSec > 0 {
+		summary.AchievedRate = float64(run.Generated) / summary.DurationSec
+	}
+	return summary
+}
@@ -3,15 +3,15 @@
 package controllers
 
 import (
-	"bytes"
-	"encoding/json"
+	"context"
+	"errors"
 	"fmt"
-	"net/http"
-	"os"
 	"sync"
 	"time"
 
 	"github.com/AkshayDubey29/MoniFlux/backend/internal/api/models"
+	"github.com/AkshayDubey29/MoniFlux/backend/internal/loadgen/delivery"
+	"github.com/AkshayDubey29/MoniFlux/backend/internal/loadgen/metrics"
 	"github.com/sirupsen/logrus"
 )
 
@@ -19,54 +19,71 @@ import (
 type DestinationType string
 
 const (
-	FileDestination DestinationType = "file"
-	HTTPDestination DestinationType = "http"
+	FileDestination   DestinationType = "file"
+	HTTPDestination   DestinationType = "http"
+	SocketDestination DestinationType = "socket" // syslog, tcp and udp
+	S3Destination     DestinationType = "s3"
 )
 
+// socketSendTimeout bounds the delivery of a batch to a socket destination.
+const socketSendTimeout = 30 * time.Second
+
 // WorkerPool manages a pool of workers to process log, metric, and trace entries concurrently.
 type WorkerPool struct {
+	ctx             context.Context // Trace of the run; never cancelled
 	numWorkers      int
 	jobs            chan interface{} // Can accept any type of job entry (logs, metrics, traces)
 	wg              sync.WaitGroup
-	file            *os.File
+	file            *delivery.RotatingFile // Used if destinationType is file
+	budgetOnce      sync.Once              // Logs the exhausted disk budget once
 	logger          *logrus.Logger
 	batchSize       int           // Number of entries per batch
 	batchDelay      time.Duration // Maximum delay before flushing a batch
 	destinationType DestinationType
-	httpEndpoint    string // Used if destinationType is HTTP
-	successCount    int64
-	failureCount    int64
-	mu              sync.Mutex // Protects successCount and failureCount
-	shutdownOnce    sync.Once  // Ensures Shutdown is called only once
+	deliverer       *delivery.Deliverer    // Used if destinationType is HTTP
+	socket          *delivery.SocketSender // Used if destinationType is socket
+	uploader        *delivery.S3Uploader   // Used if destinationType is s3
+	recorder        *runRecorder           // Per-second statistics of the run
+	shutdownOnce    sync.Once              // Ensures Shutdown is called only once
 }
 
 // NewWorkerPool initializes a new WorkerPool with a specified number of workers, destination, batch size, and batch delay.
-func NewWorkerPool(numWorkers int, destinationType DestinationType, destinationEndpoint string, logger *logrus.Logger, batchSize int, batchDelay time.Duration) (*WorkerPool, error) {
-	var file *os.File
-	var err error
-
-	if destinationType == FileDestination {
-		// Open the file in append mode with buffering
-		filePath := destinationEndpoint
-		if filePath == "" {
-			return nil, fmt.Errorf("filePath cannot be empty for file destination")
-		}
-		file, err = os.OpenFile(filePath, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
-		if err != nil {
-			return nil, fmt.Errorf("failed to open log file: %w", err)
-		}
+// HTTP destinations are sent through deliverer, socket destinations through socket,
+// s3 destinations through uploader and file destinations are written to file.
+// The pool reports its activity to prom, which may be nil, and traces deliveries as
+// children of the span in ctx.
+func NewWorkerPool(ctx context.Context, numWorkers int, destinationType DestinationType, file *delivery.RotatingFile, deliverer *delivery.Deliverer, socket *delivery.SocketSender, uploader *delivery.S3Uploader, logger *logrus.Logger, batchSize int, batchDelay time.Duration, prom *metrics.Test) (*WorkerPool, error) {
+	if destinationType == HTTPDestination && deliverer == nil {
+		return nil, fmt.Errorf("deliverer cannot be nil for HTTP destination")
+	}
+	if destinationType == SocketDestination && socket == nil {
+		return nil, fmt.Errorf("socket sender cannot be nil for socket destination")
+	}
+	if destinationType == S3Destination && uploader == nil {
+		return nil, fmt.Errorf("uploader cannot be nil for s3 destination")
+	}
+	if batchSize < 1 {
+		batchSize = 1
+	}
+	if destinationType == FileDestination && file == nil {
+		return nil, fmt.Errorf("file cannot be nil for file destination")
 	}
 
 	wp := &WorkerPool{
+		ctx:             context.WithoutCancel(ctx),
 		numWorkers:      numWorkers,
-		jobs:            make(chan interface{}, numWorkers*10000), // Increased buffer size
+		jobs:            make(chan interface{}, numWorkers*jobBufferPerWorker), // Increased buffer size
 		file:            file,
 		logger:          logger,
 		batchSize:       batchSize,
 		batchDelay:      batchDelay,
 		destinationType: destinationType,
-		httpEndpoint:    destinationEndpoint,
+		deliverer:       deliverer,
+		socket:          socket,
+		uploader:        uploader,
 	}
+	wp.recorder = newRunRecorder(wp.DeliveryStats, prom)
+	prom.ObserveQueue(func() int { return len(wp.jobs) })
 
 	wp.start()
 	return wp, nil
@@ -85,138 +102,105 @@ func (wp *WorkerPool) worker(id int) {
 	defer wp.wg.Done()
 	wp.logger.Debugf("Worker %d started", id)
 
-	for job := range wp.jobs {
-		switch entry := job.(type) {
-		case models.LogEntry:
-			if wp.destinationType == FileDestination {
-				wp.processLog(entry)
-			} else if wp.destinationType == HTTPDestination {
-				wp.processLogHTTP(entry)
-			}
-		case models.Metric:
-			if wp.destinationType == FileDestination {
-				wp.processMetric(entry)
-			} else if wp.destinationType == HTTPDestination {
-				wp.processMetricHTTP(entry)
-			}
-		case models.Trace:
-			if wp.destinationType == FileDestination {
-				wp.processTrace(entry)
-			} else if wp.destinationType == HTTPDestination {
-				wp.processTraceHTTP(entry)
+	switch wp.destinationType {
+	case HTTPDestination:
+		wp.batchEntries(func(batch []interface{}) {
+			start := time.Now()
+			wp.deliverer.Submit(wp.ctx, batch)
+			wp.recorder.batch(time.Since(start))
+		})
+		wp.logger.Debugf("Worker %d stopped", id)
+		return
+	case SocketDestination:
+		wp.batchEntries(wp.sendSocketBatch)
+		wp.logger.Debugf("Worker %d stopped", id)
+		return
+	case S3Destination:
+		for job := range wp.jobs {
+			start := time.Now()
+			if err := wp.uploader.Write(job); err != nil {
+				wp.logger.Errorf("Worker %d: Failed to write entry for s3://%s: %v", id, wp.uploader.Bucket(), err)
 			}
-		default:
-			wp.logger.Errorf("Worker %d: Unknown job type: %T", id, job)
+			wp.recorder.batch(time.Since(start))
 		}
-	}
-	wp.logger.Debugf("Worker %d stopped", id)
-}
-
-// processLog handles LogEntry by writing to a file.
-func (wp *WorkerPool) processLog(logEntry models.LogEntry) {
-	jsonData, err := json.Marshal(logEntry)
-	if err != nil {
-		wp.logger.Errorf("Failed to marshal log entry: %v", err)
+		wp.logger.Debugf("Worker %d stopped", id)
 		return
 	}
-	if _, err := wp.file.Write(append(jsonData, '\n')); err != nil {
-		wp.logger.Errorf("Failed to write log entry to file: %v", err)
-	}
-}
 
-// processLogHTTP handles LogEntry by sending it to an HTTP endpoint.
-func (wp *WorkerPool) processLogHTTP(logEntry models.LogEntry) {
-	wp.sendHTTPEntry(logEntry)
-}
-
-// processMetric handles Metric entries by writing to a file.
-func (wp *WorkerPool) processMetric(metric models.Metric) {
-	jsonData, err := json.Marshal(metric)
-	if err != nil {
-		wp.logger.Errorf("Failed to marshal metric entry: %v", err)
-		return
-	}
-	if _, err := wp.file.Write(append(jsonData, '\n')); err != nil {
-		wp.logger.Errorf("Failed to write metric entry to file: %v", err)
+	for job := range wp.jobs {
+		wp.writeEntry(job)
 	}
+	wp.logger.Debugf("Worker %d stopped", id)
 }
 
-// processMetricHTTP handles Metric entries by sending them to an HTTP endpoint.
-func (wp *WorkerPool) processMetricHTTP(metric models.Metric) {
-	wp.sendHTTPEntry(metric)
-}
+// batchEntries collects entries into batches of batchSize and passes each batch to
+// send when it is full, when batchDelay has passed, and when the pool shuts down.
+// send must not keep the slice.
+func (wp *WorkerPool) batchEntries(send func(batch []interface{})) {
+	ticker := time.NewTicker(wp.batchDelay)
+	defer ticker.Stop()
 
-// processTrace handles Trace entries by writing to a file.
-func (wp *WorkerPool) processTrace(trace models.Trace) {
-	jsonData, err := json.Marshal(trace)
-	if err != nil {
-		wp.logger.Errorf("Failed to marshal trace entry: %v", err)
-		return
-	}
-	if _, err := wp.file.Write(append(jsonData, '\n')); err != nil {
-		wp.logger.Errorf("Failed to write trace entry to file: %v", err)
+	batch := make([]interface{}, 0, wp.batchSize)
+	for {
+		select {
+		case job, ok := <-wp.jobs:
+			if !ok {
+				if len(batch) > 0 {
+					send(batch)
+				}
+				return
+			}
+			batch = append(batch, job)
+			if len(batch) >= wp.batchSize {
+				send(batch)
+				batch = batch[:0]
+			}
+		case <-ticker.C:
+			if len(batch) > 0 {
+				send(batch)
+				batch = batch[:0]
+			}
+		}
 	}
 }
 
-// processTraceHTTP handles Trace entries by sending them to an HTTP endpoint.
-func (wp *WorkerPool) processTraceHTTP(trace models.Trace) {
-	wp.sendHTTPEntry(trace)
+// writeEntry writes a log, metric or trace entry to the file in the file's format.
+// Once the disk budget is exhausted, entries are dropped and counted in the file
+// statistics.
+func (wp *WorkerPool) writeEntry(entry interface{}) {
+	start := time.Now()
+	err := wp.file.Write(entry)
+	wp.recorder.batch(time.Since(start))
+	switch {
+	case errors.Is(err, delivery.ErrDiskBudgetExceeded):
+		wp.budgetOnce.Do(func() {
+			wp.logger.Warnf("Disk budget of %s exhausted; dropping further entries", wp.file.Path())
+		})
+	case err != nil:
+		wp.logger.Errorf("Failed to write %T to file: %v", entry, err)
+	}
 }
 
-// sendHTTPEntry sends any entry (log, metric, trace) to the HTTP endpoint with retry logic.
-func (wp *WorkerPool) sendHTTPEntry(entry interface{}) {
-	jsonData, err := json.Marshal(entry)
+// sendSocketBatch writes a batch to the syslog, TCP or UDP destination.
+func (wp *WorkerPool) sendSocketBatch(batch []interface{}) {
+	ctx, cancel := context.WithTimeout(wp.ctx, socketSendTimeout)
+	defer cancel()
+	start := time.Now()
+	err := wp.socket.Send(ctx, batch)
+	wp.recorder.batch(time.Since(start))
 	if err != nil {
-		wp.logger.Errorf("Failed to marshal entry: %v", err)
-		wp.incrementFailure()
-		return
+		wp.logger.Errorf("Failed to send batch to %s: %v", wp.socket.Address(), err)
 	}
-
-	var attempt int
-	maxAttempts := 3
-	backoff := time.Second
-
-	for attempt = 1; attempt <= maxAttempts; attempt++ {
-		req, err := http.NewRequest("POST", wp.httpEndpoint, bytes.NewBuffer(jsonData))
-		if err != nil {
-			wp.logger.Errorf("Attempt %d: Failed to create HTTP request: %v", attempt, err)
-			wp.incrementFailure()
-			return
-		}
-		req.Header.Set("Content-Type", "application/json")
-
-		client := &http.Client{
-			Timeout: 5 * time.Second, // Set a timeout for the HTTP request
-		}
-
-		resp, err := client.Do(req)
-		if err != nil {
-			wp.logger.Errorf("Attempt %d: Failed to send entry to HTTP endpoint: %v", attempt, err)
-		} else {
-			defer resp.Body.Close()
-			if resp.StatusCode >= 200 && resp.StatusCode < 300 {
-				wp.incrementSuccess()
-				return
-			} else {
-				wp.logger.Errorf("Attempt %d: Received non-success status code %d from HTTP endpoint", attempt, resp.StatusCode)
-			}
-		}
-
-		// Wait before retrying
-		time.Sleep(backoff)
-		backoff *= 2 // Exponential backoff
-	}
-
-	// After max attempts, log failure
-	wp.logger.Errorf("All %d attempts failed to send entry to HTTP endpoint", maxAttempts)
-	wp.incrementFailure()
 }
 
 // Submit enqueues a log, metric, or trace entry for processing.
 func (wp *WorkerPool) Submit(entry interface{}) {
+	wp.recorder.generated(entry)
 	select {
 	case wp.jobs <- entry:
+		wp.recorder.enqueued(entry)
 	default:
+		wp.recorder.drop(entry)
 		wp.logger.Warn("Job channel is full, dropping entry")
 	}
 }
@@ -226,34 +210,104 @@ func (wp *WorkerPool) Shutdown() error {
 	var err error
 	wp.shutdownOnce.Do(func() {
 		close(wp.jobs)
+		if wp.deliverer != nil {
+			// Flush the queued entries, but stop waiting on retries and an open circuit.
+			wp.deliverer.Stop()
+		}
 		wp.wg.Wait()
+		if wp.deliverer != nil {
+			if closeErr := wp.deliverer.Close(); closeErr != nil {
+				wp.logger.Errorf("Failed to close dead letter: %v", closeErr)
+				err = closeErr
+			}
+		}
+		if wp.socket != nil {
+			if closeErr := wp.socket.Close(); closeErr != nil {
+				wp.logger.Errorf("Failed to close connections to %s: %v", wp.socket.Address(), closeErr)
+				err = closeErr
+			}
+		}
+		if wp.uploader != nil {
+			// Uploads the open segments and waits for the queued ones.
+			if closeErr := wp.uploader.Close(); closeErr != nil {
+				wp.logger.Errorf("Failed to upload to s3://%s: %v", wp.uploader.Bucket(), closeErr)
+				err = closeErr
+			}
+		}
 		if wp.file != nil {
-			err = wp.file.Close()
-			if err != nil {
-				wp.logger.Errorf("Failed to close log file: %v", err)
+			if closeErr := wp.file.Close(); closeErr != nil {
+				wp.logger.Errorf("Failed to close log file: %v", closeErr)
+				err = closeErr
 			}
 		}
+		wp.recorder.stop()
 	})
 	return err
 }
 
-// incrementSuccess safely increments the successCount.
-func (wp *WorkerPool) incrementSuccess() {
-	wp.mu.Lock()
-	defer wp.mu.Unlock()
-	wp.successCount++
+// GetCounts returns the number of delivered and failed HTTP requests, socket batches
+// or uploaded objects, or of the entries written to and dropped from a file.
+func (wp *WorkerPool) GetCounts() (successes int64, failures int64) {
+	if wp.file != nil {
+		return wp.file.Entries()
+	}
+	if wp.uploader != nil {
+		return wp.uploader.Counts()
+	}
+	if wp.socket != nil {
+		stats := wp.socket.Stats()
+		return stats.Requests, stats.Failures
+	}
+	if wp.deliverer == nil {
+		return 0, 0
+	}
+	return wp.deliverer.Counts()
 }
 
-// incrementFailure safely increments the failureCount.
-func (wp *WorkerPool) incrementFailure() {
-	wp.mu.Lock()
-	defer wp.mu.Unlock()
-	wp.failureCount++
+// Run returns the per-second statistics of the run so far. The run ends when the
+// pool is shut down.
+func (wp *WorkerPool) Run() models.TestRun {
+	return wp.recorder.snapshot()
 }
 
-// GetCounts returns the number of successful and failed HTTP requests.
-func (wp *WorkerPool) GetCounts() (successes int64, failures int64) {
-	wp.mu.Lock()
-	defer wp.mu.Unlock()
-	return wp.successCount, wp.failureCount
+// DeliveryStats returns the statistics of the deliveries so far.
+func (wp *WorkerPool) DeliveryStats() *models.DeliveryStats {
+	if wp.file != nil {
+		written, dropped := wp.file.Entries()
+		return &models.DeliveryStats{Entries: written, Failures: dropped, File: wp.file.Stats()}
+	}
+	if wp.socket != nil {
+		return wp.socket.Stats()
+	}
+	if wp.uploader != nil {
+		return wp.uploader.Stats()
+	}
+	if wp.deliverer == nil {
+		return nil
+	}
+	return wp.deliverer.Stats()
+}
+
+// CheckDestination reports whether the destination of the pool can be reached.
+func (wp *WorkerPool) CheckDestination(ctx context.Context) error {
+	switch {
+	case wp.file != nil:
+		return wp.file.CheckReachable(ctx)
+	case wp.uploader != nil:
+		return wp.uploader.CheckReachable(ctx)
+	case wp.socket != nil:
+		return wp.socket.CheckReachable(ctx)
+	case wp.deliverer != nil:
+		return wp.deliverer.CheckReachable(ctx)
+	}
+	return nil
+}
+
+// QueueUsage returns the fraction of the job queue holding entries waiting for a
+// worker. It approaches 1 when the workers cannot keep up with the rate.
+func (wp *WorkerPool) QueueUsage() float64 {
+	if cap(wp.jobs) == 0 {
+		return 0
+	}
+	return float64(len(wp.jobs)) / float64(cap(wp.jobs))
 }
@@ -0,0 +1,218 @@
+// backend/internal/db/mongo/indexes.go
+
+package mongo
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// collectionIndexes lists the indexes that must exist on each collection.
+// They are created idempotently at startup by EnsureIndexes.
+var collectionIndexes = map[string][]mongo.IndexModel{
+	"tests": {
+		{
+			Keys:    bson.D{{Key: "testID", Value: 1}},
+			Options: options.Index().SetName("testID_unique").SetUnique(true),
+		},
+		{
+			Keys:    bson.D{{Key: "createdAt", Value: -1}, {Key: "testID", Value: -1}},
+			Options: options.Index().SetName("createdAt_testID"),
+		},
+		{
+			Keys:    bson.D{{Key: "updatedAt", Value: -1}, {Key: "testID", Value: -1}},
+			Options: options.Index().SetName("updatedAt_testID"),
+		},
+		{
+			Keys:    bson.D{{Key: "status", Value: 1}, {Key: "createdAt", Value: -1}, {Key: "testID", Value: -1}},
+			Options: options.Index().SetName("status_createdAt_testID"),
+		},
+		{
+			Keys:    bson.D{{Key: "userID", Value: 1}, {Key: "createdAt", Value: -1}, {Key: "testID", Value: -1}},
+			Options: options.Index().SetName("userID_createdAt_testID"),
+		},
+		{
+			Keys:    bson.D{{Key: "destination.type", Value: 1}, {Key: "createdAt", Value: -1}},
+			Options: options.Index().SetName("destinationType_createdAt"),
+		},
+		{
+			Keys:    bson.D{{Key: "tags", Value: 1}},
+			Options: options.Index().SetName("tags"),
+		},
+		{
+			Keys:    bson.D{{Key: "teamID", Value: 1}, {Key: "createdAt", Value: -1}, {Key: "testID", Value: -1}},
+			Options: options.Index().SetName("teamID_createdAt_testID"),
+		},
+	},
+	"api_keys": {
+		{
+			Keys:    bson.D{{Key: "keyID", Value: 1}},
+			Options: options.Index().SetName("keyID_unique").SetUnique(true),
+		},
+		{
+			Keys:    bson.D{{Key: "userID", Value: 1}, {Key: "createdAt", Value: -1}},
+			Options: options.Index().SetName("userID_createdAt"),
+		},
+	},
+	"sessions": {
+		{
+			Keys:    bson.D{{Key: "sessionID", Value: 1}},
+			Options: options.Index().SetName("sessionID_unique").SetUnique(true),
+		},
+		{
+			Keys:    bson.D{{Key: "userID", Value: 1}},
+			Options: options.Index().SetName("userID"),
+		},
+		{
+			Keys:    bson.D{{Key: "expiresAt", Value: 1}},
+			Options: options.Index().SetName("expiresAt_ttl").SetExpireAfterSeconds(0),
+		},
+	},
+	"revoked_tokens": {
+		{
+			Keys:    bson.D{{Key: "jti", Value: 1}},
+			Options: options.Index().SetName("jti_unique").SetUnique(true),
+		},
+		{
+			Keys:    bson.D{{Key: "expiresAt", Value: 1}},
+			Options: options.Index().SetName("expiresAt_ttl").SetExpireAfterSeconds(0),
+		},
+	},
+	"oidc_states": {
+		{
+			Keys:    bson.D{{Key: "state", Value: 1}},
+			Options: options.Index().SetName("state_unique").SetUnique(true),
+		},
+		{
+			Keys:    bson.D{{Key: "expiresAt", Value: 1}},
+			Options: options.Index().SetName("expiresAt_ttl").SetExpireAfterSeconds(0),
+		},
+	},
+	"users": {
+		{
+			Keys:    bson.D{{Key: "username", Value: 1}},
+			Options: options.Index().SetName("username_unique").SetUnique(true),
+		},
+		{
+			Keys: bson.D{{Key: "oidcIssuer", Value: 1}, {Key: "oidcSubject", Value: 1}},
+			Options: options.Index().SetName("oidcIssuer_oidcSubject_unique").SetUnique(true).
+				SetPartialFilterExpression(bson.M{"oidcSubject": bson.M{"$exists": true}}),
+		},
+	},
+	"account_tokens": {
+		{
+			Keys:    bson.D{{Key: "tokenHash", Value: 1}},
+			Options: options.Index().SetName("tokenHash_unique").SetUnique(true),
+		},
+		{
+			Keys:    bson.D{{Key: "userID", Value: 1}, {Key: "purpose", Value: 1}},
+			Options: options.Index().SetName("userID_purpose"),
+		},
+		{
+			Keys:    bson.D{{Key: "expiresAt", Value: 1}},
+			Options: options.Index().SetName("expiresAt_ttl").SetExpireAfterSeconds(0),
+		},
+	},
+	"teams": {
+		{
+			Keys:    bson.D{{Key: "teamID", Value: 1}},
+			Options: options.Index().SetName("teamID_unique").SetUnique(true),
+		},
+		{
+			Keys:    bson.D{{Key: "members.userID", Value: 1}},
+			Options: options.Index().SetName("members_userID"),
+		},
+	},
+	"projects": {
+		{
+			Keys:    bson.D{{Key: "projectID", Value: 1}},
+			Options: options.Index().SetName("projectID_unique").SetUnique(true),
+		},
+		{
+			Keys:    bson.D{{Key: "teamID", Value: 1}, {Key: "name", Value: 1}},
+			Options: options.Index().SetName("teamID_name_unique").SetUnique(true),
+		},
+	},
+	"guardrail_policies": {
+		{
+			Keys:    bson.D{{Key: "teamID", Value: 1}},
+			Options: options.Index().SetName("teamID_unique").SetUnique(true),
+		},
+	},
+	"secrets": {
+		{
+			Keys:    bson.D{{Key: "name", Value: 1}},
+			Options: options.Index().SetName("name_unique").SetUnique(true),
+		},
+	},
+	"destinations": {
+		{
+			Keys:    bson.D{{Key: "name", Value: 1}},
+			Options: options.Index().SetName("name_unique").SetUnique(true),
+		},
+		{
+			Keys:    bson.D{{Key: "teamID", Value: 1}, {Key: "name", Value: 1}},
+			Options: options.Index().SetName("teamID_name"),
+		},
+	},
+	"health_checks": {
+		{
+			Keys:    bson.D{{Key: "service_name", Value: 1}, {Key: "checked_at", Value: -1}},
+			Options: options.Index().SetName("serviceName_checkedAt"),
+		},
+		{
+			Keys:    bson.D{{Key: "checked_at", Value: -1}},
+			Options: options.Index().SetName("checkedAt"),
+		},
+		{
+			Keys:    bson.D{{Key: "expiresAt", Value: 1}},
+			Options: options.Index().SetName("expiresAt_ttl").SetExpireAfterSeconds(0),
+		},
+	},
+	"audit_events": {
+		{
+			Keys:    bson.D{{Key: "timestamp", Value: -1}},
+			Options: options.Index().SetName("timestamp"),
+		},
+		{
+			Keys:    bson.D{{Key: "actor.userID", Value: 1}, {Key: "_id", Value: -1}},
+			Options: options.Index().SetName("actorUserID_id"),
+		},
+		{
+			Keys:    bson.D{{Key: "targetID", Value: 1}, {Key: "_id", Value: -1}},
+			Options: options.Index().SetName("targetID_id"),
+		},
+		{
+			Keys:    bson.D{{Key: "action", Value: 1}, {Key: "_id", Value: -1}},
+			Options: options.Index().SetName("action_id"),
+		},
+		{
+			Keys:    bson.D{{Key: "requestID", Value: 1}},
+			Options: options.Index().SetName("requestID"),
+		},
+	},
+}
+
+// EnsureIndexes creates all indexes required by the application.
+// Creating an index that already exists is a no-op, so it is safe to call on every startup.
+// A failing index (e.g. a unique index over existing duplicates) does not prevent the
+// remaining indexes from being created; all failures are reported together.
+func (m *MongoClient) EnsureIndexes(ctx context.Context) error {
+	var failures []string
+	for collectionName, indexes := range collectionIndexes {
+		for _, index := range indexes {
+			if _, err := m.CreateIndex(ctx, collectionName, index); err != nil {
+				failures = append(failures, fmt.Sprintf("%s: %v", collectionName, err))
+			}
+		}
+	}
+	if len(failures) > 0 {
+		return fmt.Errorf("failed to ensure indexes: %s", strings.Join(failures, "; "))
+	}
+	return nil
+}
@@ -12,6 +12,7 @@ import (
 	"go.mongodb.org/mongo-driver/mongo/options"
 
 	"github.com/AkshayDubey29/MoniFlux/backend/internal/common"
+	"github.com/AkshayDubey29/MoniFlux/backend/pkg/tracing"
 )
 
 // MongoClient wraps the MongoDB client and provides access to the database and collections.
@@ -36,7 +37,8 @@ func NewMongoClient(cfg *common.Config, logger *logrus.Logger) (*MongoClient, er
 		SetRetryWrites(true).                       // Enable retryable writes
 		SetRetryReads(true).                        // Enable retryable reads
 		SetDirect(false).                           // Enable read preference and server selection
-		SetAppName("MoniFlux")                      // Application name for MongoDB logs and monitoring
+		SetAppName("MoniFlux").                     // Application name for MongoDB logs and monitoring
+		SetMonitor(tracing.MongoMonitor())          // Record a span for every command
 
 	// Create a new MongoDB client
 	client, err := mongo.NewClient(clientOpts)
@@ -105,7 +107,7 @@ func (m *MongoClient) Ping(ctx context.Context) error {
 		return err
 	}
 
-	m.Logger.Info("Ping to MongoDB succeeded")
+	m.Logger.Debug("Ping to MongoDB succeeded")
 	return nil
 }
 
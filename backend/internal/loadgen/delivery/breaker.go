@@ -0,0 +1,144 @@
+// backend/internal/loadgen/delivery/breaker.go
+
+package delivery
+
+import (
+	"sync"
+	"time"
+
+	"github.com/AkshayDubey29/MoniFlux/backend/internal/common"
+)
+
+// Circuit breaker defaults, used for unset fields of common.CircuitBreaker.
+const (
+	DefaultFailureThreshold = 5
+	DefaultBreakerCooldown  = 30 * time.Second
+)
+
+// probePollInterval is how often callers check whether a half-open circuit has closed.
+const probePollInterval = 50 * time.Millisecond
+
+type breakerState int
+
+const (
+	breakerClosed breakerState = iota
+	breakerOpen
+	breakerHalfOpen
+)
+
+// CircuitBreaker pauses deliveries to a destination that keeps failing. After
+// threshold consecutive failures the circuit opens for the cooldown; then a single
+// probe request is let through, which closes the circuit if it succeeds and opens it
+// again if it fails.
+type CircuitBreaker struct {
+	threshold int
+	cooldown  time.Duration
+
+	mu       sync.Mutex
+	state    breakerState
+	failures int // Consecutive failures while closed
+	openedAt time.Time
+	probing  bool // A probe request is in flight while half-open
+	opens    int64
+}
+
+// NewCircuitBreaker returns the breaker configured for a destination, or nil if the
+// destination disables it.
+func NewCircuitBreaker(cfg *common.CircuitBreaker) *CircuitBreaker {
+	b := &CircuitBreaker{threshold: DefaultFailureThreshold, cooldown: DefaultBreakerCooldown}
+	if cfg == nil {
+		return b
+	}
+	if cfg.Disabled {
+		return nil
+	}
+	if cfg.FailureThreshold > 0 {
+		b.threshold = cfg.FailureThreshold
+	}
+	if cfg.CooldownMs > 0 {
+		b.cooldown = time.Duration(cfg.CooldownMs) * time.Millisecond
+	}
+	return b
+}
+
+// allow reports whether a request may be sent now and, if not, how long to wait
+// before asking again.
+func (b *CircuitBreaker) allow(now time.Time) (bool, time.Duration) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	switch b.state {
+	case breakerOpen:
+		if remaining := b.openedAt.Add(b.cooldown).Sub(now); remaining > 0 {
+			return false, remaining
+		}
+		b.state = breakerHalfOpen
+		b.probing = true
+		return true, 0
+	case breakerHalfOpen:
+		if b.probing {
+			return false, probePollInterval
+		}
+		b.probing = true
+		return true, 0
+	default:
+		return true, 0
+	}
+}
+
+// Wait blocks while the circuit is open. It returns false if done is closed first.
+func (b *CircuitBreaker) Wait(done <-chan struct{}) bool {
+	for {
+		ok, wait := b.allow(time.Now())
+		if ok {
+			return true
+		}
+		timer := time.NewTimer(wait)
+		select {
+		case <-timer.C:
+		case <-done:
+			timer.Stop()
+			return false
+		}
+	}
+}
+
+// Success records a request the destination answered, closing the circuit.
+func (b *CircuitBreaker) Success() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.state = breakerClosed
+	b.failures = 0
+	b.probing = false
+}
+
+// Failure records a failed request and opens the circuit once the threshold of
+// consecutive failures is reached, or when a probe fails.
+func (b *CircuitBreaker) Failure() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	switch b.state {
+	case breakerHalfOpen:
+		b.open()
+	case breakerClosed:
+		b.failures++
+		if b.failures >= b.threshold {
+			b.open()
+		}
+	}
+}
+
+// open opens the circuit. The caller must hold b.mu.
+func (b *CircuitBreaker) open() {
+	b.state = breakerOpen
+	b.openedAt = time.Now()
+	b.failures = 0
+	b.probing = false
+	b.opens++
+}
+
+// Opens returns the number of times the circuit has opened.
+func (b *CircuitBreaker) Opens() int64 {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.opens
+}
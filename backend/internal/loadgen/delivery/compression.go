@@ -0,0 +1,71 @@
+// backend/internal/loadgen/delivery/compression.go
+
+package delivery
+
+import (
+	"bytes"
+	"compress/gzip"
+	"compress/zlib"
+	"fmt"
+	"sync"
+
+	"github.com/golang/snappy"
+	"github.com/klauspost/compress/zstd"
+)
+
+// Supported payload compressions. Each is sent as the Content-Encoding of the same name.
+const (
+	CompressionNone    = "none"
+	CompressionGzip    = "gzip"
+	CompressionZstd    = "zstd"
+	CompressionSnappy  = "snappy"  // Snappy block format, as used by Prometheus remote write
+	CompressionDeflate = "deflate" // zlib format (RFC 1950), as HTTP defines deflate
+)
+
+var (
+	zstdOnce    sync.Once
+	zstdEncoder *zstd.Encoder
+	zstdErr     error
+)
+
+// Compress compresses the body and returns it with its Content-Encoding, which is
+// empty if the body is sent as is.
+func Compress(compression string, body []byte) ([]byte, string, error) {
+	switch compression {
+	case "", CompressionNone:
+		return body, "", nil
+	case CompressionGzip:
+		var buf bytes.Buffer
+		writer := gzip.NewWriter(&buf)
+		if _, err := writer.Write(body); err != nil {
+			return nil, "", err
+		}
+		if err := writer.Close(); err != nil {
+			return nil, "", err
+		}
+		return buf.Bytes(), CompressionGzip, nil
+	case CompressionDeflate:
+		var buf bytes.Buffer
+		writer := zlib.NewWriter(&buf)
+		if _, err := writer.Write(body); err != nil {
+			return nil, "", err
+		}
+		if err := writer.Close(); err != nil {
+			return nil, "", err
+		}
+		return buf.Bytes(), CompressionDeflate, nil
+	case CompressionZstd:
+		// The encoder is safe for concurrent EncodeAll calls, so one is shared.
+		zstdOnce.Do(func() {
+			zstdEncoder, zstdErr = zstd.NewWriter(nil)
+		})
+		if zstdErr != nil {
+			return nil, "", zstdErr
+		}
+		return zstdEncoder.EncodeAll(body, make([]byte, 0, len(body)/2)), CompressionZstd, nil
+	case CompressionSnappy:
+		return snappy.Encode(nil, body), CompressionSnappy, nil
+	default:
+		return nil, "", fmt.Errorf("unsupported compression %q", compression)
+	}
+}
@@ -0,0 +1,86 @@
+// backend/internal/loadgen/delivery/deadletter.go
+
+package delivery
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// DeadLetterRecord is a line of a dead-letter file: an entry that could not be
+// delivered, with the last error.
+type DeadLetterRecord struct {
+	DeadLetteredAt time.Time   `json:"deadLetteredAt"`
+	URL            string      `json:"url"`
+	Error          string      `json:"error"`
+	Attempts       int         `json:"attempts"`
+	Entry          interface{} `json:"entry"`
+}
+
+// DeadLetter appends undeliverable entries to an NDJSON file, so they can be
+// inspected or replayed. The file and its directory are created on the first write.
+type DeadLetter struct {
+	path string
+
+	mu   sync.Mutex
+	file *os.File
+}
+
+// NewDeadLetter returns a dead letter writing to path.
+func NewDeadLetter(path string) *DeadLetter {
+	return &DeadLetter{path: path}
+}
+
+// Path returns the path of the dead-letter file.
+func (d *DeadLetter) Path() string {
+	return d.path
+}
+
+// Write appends a record for each entry.
+func (d *DeadLetter) Write(url string, entries []interface{}, attempts int, cause error) error {
+	now := time.Now().UTC()
+	var data []byte
+	for _, entry := range entries {
+		line, err := json.Marshal(DeadLetterRecord{
+			DeadLetteredAt: now,
+			URL:            url,
+			Error:          cause.Error(),
+			Attempts:       attempts,
+			Entry:          entry,
+		})
+		if err != nil {
+			return err
+		}
+		data = append(append(data, line...), '\n')
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.file == nil {
+		if err := os.MkdirAll(filepath.Dir(d.path), 0o755); err != nil {
+			return err
+		}
+		file, err := os.OpenFile(d.path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o644)
+		if err != nil {
+			return err
+		}
+		d.file = file
+	}
+	_, err := d.file.Write(data)
+	return err
+}
+
+// Close closes the file if it was opened.
+func (d *DeadLetter) Close() error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.file == nil {
+		return nil
+	}
+	err := d.file.Close()
+	d.file = nil
+	return err
+}
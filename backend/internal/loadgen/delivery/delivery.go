@@ -20,13 +20,32 @@ type DeliveryService struct {
 }
 
 // NewDeliveryService initializes a new DeliveryService with appropriate handlers based on destinations.
-func NewDeliveryService(logger *logrus.Logger, destinations []common.Destination) (*DeliveryService, error) {
+// The secret resolver supplies the credentials of HTTP, socket and s3 destinations.
+func NewDeliveryService(ctx context.Context, logger *logrus.Logger, destinations []common.Destination, secrets SecretResolver) (*DeliveryService, error) {
 	handlers := make([]DestinationHandler, 0, len(destinations))
 
 	for _, dest := range destinations {
 		switch dest.Type {
 		case "http":
-			handler := NewHTTPDestinationHandler(dest, logger)
+			handler, err := NewHTTPDestinationHandler(ctx, dest, secrets, logger)
+			if err != nil {
+				logger.Errorf("Failed to initialize HTTPDestinationHandler for destination %s: %v", dest.Name, err)
+				continue // Skip this destination and proceed with others
+			}
+			handlers = append(handlers, handler)
+		case "syslog", "tcp", "udp":
+			handler, err := NewSocketDestinationHandler(ctx, dest, secrets, logger)
+			if err != nil {
+				logger.Errorf("Failed to initialize SocketDestinationHandler for destination %s: %v", dest.Name, err)
+				continue // Skip this destination and proceed with others
+			}
+			handlers = append(handlers, handler)
+		case "s3":
+			handler, err := NewS3DestinationHandler(ctx, dest, secrets, logger)
+			if err != nil {
+				logger.Errorf("Failed to initialize S3DestinationHandler for destination %s: %v", dest.Name, err)
+				continue // Skip this destination and proceed with others
+			}
 			handlers = append(handlers, handler)
 		case "file":
 			handler, err := NewFileDestinationHandler(dest, logger)
@@ -63,7 +82,7 @@ func (ds *DeliveryService) SendLogs(ctx context.Context, logs []models.LogEntry)
 		wg.Add(1)
 		go func(h DestinationHandler) {
 			defer wg.Done()
-			if err := h.SendLog(logs); err != nil {
+			if err := h.SendLogs(ctx, logs); err != nil {
 				errChan <- fmt.Errorf("failed to send logs: %w", err)
 			}
 		}(handler)
@@ -94,7 +113,7 @@ func (ds *DeliveryService) SendMetrics(ctx context.Context, metrics []models.Met
 		wg.Add(1)
 		go func(h DestinationHandler) {
 			defer wg.Done()
-			if err := h.SendMetric(metrics); err != nil {
+			if err := h.SendMetrics(ctx, metrics); err != nil {
 				errChan <- fmt.Errorf("failed to send metrics: %w", err)
 			}
 		}(handler)
@@ -125,7 +144,7 @@ func (ds *DeliveryService) SendTraces(ctx context.Context, traces []models.Trace
 		wg.Add(1)
 		go func(h DestinationHandler) {
 			defer wg.Done()
-			if err := h.SendTrace(traces); err != nil {
+			if err := h.SendTraces(ctx, traces); err != nil {
 				errChan <- fmt.Errorf("failed to send traces: %w", err)
 			}
 		}(handler)
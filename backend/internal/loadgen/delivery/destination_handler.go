@@ -3,199 +3,252 @@
 package delivery
 
 import (
-	"encoding/json"
-	"fmt"
-	"os"
-	"time"
+	"context"
 
-	"bytes"
 	"github.com/AkshayDubey29/MoniFlux/backend/internal/api/models"
 	"github.com/AkshayDubey29/MoniFlux/backend/internal/common"
 	"github.com/sirupsen/logrus"
-	"net/http"
 )
 
 // DestinationHandler defines the interface for handling different destinations.
 type DestinationHandler interface {
-	SendLog(log models.LogEntry) error
-	SendMetric(metric models.Metric) error
-	SendTrace(trace models.Trace) error
+	SendLogs(ctx context.Context, logs []models.LogEntry) error
+	SendMetrics(ctx context.Context, metrics []models.Metric) error
+	SendTraces(ctx context.Context, traces []models.Trace) error
 	Close() error
 }
 
 // HTTPDestinationHandler handles sending data to an HTTP endpoint.
 type HTTPDestinationHandler struct {
-	client   *http.Client
-	endpoint string
-	apiKey   string
-	logger   *logrus.Logger
+	sender    *HTTPSender
+	deliverer *Deliverer
+	logger    *logrus.Logger
 }
 
-// NewHTTPDestinationHandler creates a new HTTPDestinationHandler.
-func NewHTTPDestinationHandler(dest common.Destination, logger *logrus.Logger) *HTTPDestinationHandler {
-	return &HTTPDestinationHandler{
-		client:   &http.Client{Timeout: 10 * time.Second},
-		endpoint: fmt.Sprintf("%s:%d", dest.Endpoint, dest.Port),
-		apiKey:   dest.APIKey,
-		logger:   logger,
+// NewHTTPDestinationHandler creates a new HTTPDestinationHandler. Each signal is
+// posted in the destination's encoding to its own path under the destination URL,
+// with the destination's retry policy and circuit breaker. Destinations of the
+// configuration file are set by the operator, so their connections are not guarded.
+func NewHTTPDestinationHandler(ctx context.Context, dest common.Destination, secrets SecretResolver, logger *logrus.Logger) (*HTTPDestinationHandler, error) {
+	sender, err := NewHTTPSender(ctx, dest, secrets, nil)
+	if err != nil {
+		return nil, err
 	}
+	return &HTTPDestinationHandler{
+		sender:    sender,
+		deliverer: NewDeliverer(dest, sender, nil, logger),
+		logger:    logger,
+	}, nil
 }
 
-func (h *HTTPDestinationHandler) SendLog(log models.LogEntry) error {
-	return h.sendPayload("logs", log)
+func (h *HTTPDestinationHandler) SendLogs(ctx context.Context, logs []models.LogEntry) error {
+	entries := make([]interface{}, len(logs))
+	for i, log := range logs {
+		entries[i] = log
+	}
+	return h.sendPayload(ctx, "logs", entries)
 }
 
-func (h *HTTPDestinationHandler) SendMetric(metric models.Metric) error {
-	return h.sendPayload("metrics", metric)
+func (h *HTTPDestinationHandler) SendMetrics(ctx context.Context, metrics []models.Metric) error {
+	entries := make([]interface{}, len(metrics))
+	for i, metric := range metrics {
+		entries[i] = metric
+	}
+	return h.sendPayload(ctx, "metrics", entries)
 }
 
-func (h *HTTPDestinationHandler) SendTrace(trace models.Trace) error {
-	return h.sendPayload("traces", trace)
+func (h *HTTPDestinationHandler) SendTraces(ctx context.Context, traces []models.Trace) error {
+	entries := make([]interface{}, len(traces))
+	for i, trace := range traces {
+		entries[i] = trace
+	}
+	return h.sendPayload(ctx, "traces", entries)
 }
 
-func (h *HTTPDestinationHandler) sendPayload(payloadType string, payload interface{}) error {
-	data, err := json.Marshal(payload)
-	if err != nil {
-		h.logger.Errorf("Failed to marshal %s payload: %v", payloadType, err)
+func (h *HTTPDestinationHandler) sendPayload(ctx context.Context, payloadType string, entries []interface{}) error {
+	if err := h.deliverer.Deliver(ctx, payloadType, entries); err != nil {
+		h.logger.Errorf("Failed to send %s to %s: %v", payloadType, h.sender.URL(), err)
 		return err
 	}
 
-	url := fmt.Sprintf("http://%s/%s", h.endpoint, payloadType)
-	req, err := http.NewRequest(http.MethodPost, url, bytes.NewBuffer(data))
+	h.logger.Debugf("Successfully sent %s to %s", payloadType, h.sender.URL())
+	return nil
+}
+
+func (h *HTTPDestinationHandler) Close() error {
+	return h.deliverer.Close()
+}
+
+// SocketDestinationHandler handles sending data to a syslog, TCP or UDP listener.
+type SocketDestinationHandler struct {
+	sender *SocketSender
+	logger *logrus.Logger
+}
+
+// NewSocketDestinationHandler creates a new SocketDestinationHandler.
+func NewSocketDestinationHandler(ctx context.Context, dest common.Destination, secrets SecretResolver, logger *logrus.Logger) (*SocketDestinationHandler, error) {
+	sender, err := NewSocketSender(ctx, dest, secrets, nil)
 	if err != nil {
-		h.logger.Errorf("Failed to create HTTP request for %s: %v", payloadType, err)
-		return err
+		return nil, err
 	}
+	return &SocketDestinationHandler{
+		sender: sender,
+		logger: logger,
+	}, nil
+}
 
-	req.Header.Set("Content-Type", "application/json")
-	if h.apiKey != "" {
-		req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", h.apiKey))
+func (h *SocketDestinationHandler) SendLogs(ctx context.Context, logs []models.LogEntry) error {
+	entries := make([]interface{}, len(logs))
+	for i, log := range logs {
+		entries[i] = log
 	}
+	return h.send(ctx, "logs", entries)
+}
 
-	resp, err := h.client.Do(req)
-	if err != nil {
-		h.logger.Errorf("Failed to send %s to %s: %v", payloadType, h.endpoint, err)
-		return err
+func (h *SocketDestinationHandler) SendMetrics(ctx context.Context, metrics []models.Metric) error {
+	entries := make([]interface{}, len(metrics))
+	for i, metric := range metrics {
+		entries[i] = metric
 	}
-	defer resp.Body.Close()
+	return h.send(ctx, "metrics", entries)
+}
 
-	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
-		h.logger.Errorf("Received non-success status code %d when sending %s to %s", resp.StatusCode, payloadType, h.endpoint)
-		return fmt.Errorf("non-success status code: %d", resp.StatusCode)
+func (h *SocketDestinationHandler) SendTraces(ctx context.Context, traces []models.Trace) error {
+	entries := make([]interface{}, len(traces))
+	for i, trace := range traces {
+		entries[i] = trace
 	}
+	return h.send(ctx, "traces", entries)
+}
 
-	h.logger.Infof("Successfully sent %s to %s", payloadType, h.endpoint)
+func (h *SocketDestinationHandler) send(ctx context.Context, payloadType string, entries []interface{}) error {
+	if err := h.sender.Send(ctx, entries); err != nil {
+		h.logger.Errorf("Failed to send %s to %s: %v", payloadType, h.sender.Address(), err)
+		return err
+	}
+	h.logger.Debugf("Successfully sent %s to %s", payloadType, h.sender.Address())
 	return nil
 }
 
-func (h *HTTPDestinationHandler) Close() error {
-	// No resources to clean up for HTTP
-	return nil
+func (h *SocketDestinationHandler) Close() error {
+	return h.sender.Close()
 }
 
 // FileDestinationHandler handles writing data to local files.
 type FileDestinationHandler struct {
-	baseFilePath string
-	fileCount    int
-	fileFreq     int // Frequency in minutes
-	currentFile  *os.File
-	logger       *logrus.Logger
-	ticker       *time.Ticker
-	quit         chan struct{}
+	file   *RotatingFile
+	logger *logrus.Logger
 }
 
-// NewFileDestinationHandler creates a new FileDestinationHandler.
+// NewFileDestinationHandler creates a new FileDestinationHandler. The file is rotated
+// by size or every FileFreq minutes and FileCount files are kept.
 func NewFileDestinationHandler(dest common.Destination, logger *logrus.Logger) (*FileDestinationHandler, error) {
-	handler := &FileDestinationHandler{
-		baseFilePath: dest.FilePath,
-		fileCount:    dest.FileCount,
-		fileFreq:     dest.FileFreq,
-		logger:       logger,
-		quit:         make(chan struct{}),
+	file, err := NewRotatingFile(FileOptions(dest, 0))
+	if err != nil {
+		logger.Errorf("Failed to open log file %s: %v", dest.FilePath, err)
+		return nil, err
 	}
+	return &FileDestinationHandler{
+		file:   file,
+		logger: logger,
+	}, nil
+}
 
-	if err := handler.rotateFiles(); err != nil {
-		return nil, err
+func (f *FileDestinationHandler) SendLogs(ctx context.Context, logs []models.LogEntry) error {
+	for _, log := range logs {
+		if err := f.writeToFile(log); err != nil {
+			return err
+		}
 	}
+	return nil
+}
 
-	handler.startFileRotation()
-	return handler, nil
+func (f *FileDestinationHandler) SendMetrics(ctx context.Context, metrics []models.Metric) error {
+	for _, metric := range metrics {
+		if err := f.writeToFile(metric); err != nil {
+			return err
+		}
+	}
+	return nil
 }
 
-func (f *FileDestinationHandler) rotateFiles() error {
-	if f.currentFile != nil {
-		f.currentFile.Close()
+func (f *FileDestinationHandler) SendTraces(ctx context.Context, traces []models.Trace) error {
+	for _, trace := range traces {
+		if err := f.writeToFile(trace); err != nil {
+			return err
+		}
 	}
+	return nil
+}
 
-	timestamp := time.Now().Format("20060102_150405")
-	newFilePath := fmt.Sprintf("%s_%s.log", f.baseFilePath, timestamp)
-	file, err := os.Create(newFilePath)
-	if err != nil {
-		f.logger.Errorf("Failed to create log file %s: %v", newFilePath, err)
+func (f *FileDestinationHandler) writeToFile(payload interface{}) error {
+	if err := f.file.Write(payload); err != nil {
+		f.logger.Errorf("Failed to write to file: %v", err)
 		return err
 	}
 
-	f.currentFile = file
-	f.logger.Infof("Rotated to new log file: %s", newFilePath)
+	f.logger.Debugf("Successfully wrote payload to file")
 	return nil
 }
 
-func (f *FileDestinationHandler) startFileRotation() {
-	duration := time.Duration(f.fileFreq) * time.Minute
-	f.ticker = time.NewTicker(duration)
-
-	go func() {
-		for {
-			select {
-			case <-f.ticker.C:
-				if err := f.rotateFiles(); err != nil {
-					f.logger.Errorf("Error rotating files: %v", err)
-				}
-			case <-f.quit:
-				f.ticker.Stop()
-				if f.currentFile != nil {
-					f.currentFile.Close()
-				}
-				return
-			}
-		}
-	}()
+func (f *FileDestinationHandler) Close() error {
+	return f.file.Close()
 }
 
-func (f *FileDestinationHandler) SendLog(log models.LogEntry) error {
-	return f.writeToFile(log)
+// S3DestinationHandler handles uploading data to an S3-compatible bucket.
+type S3DestinationHandler struct {
+	uploader *S3Uploader
+	logger   *logrus.Logger
 }
 
-func (f *FileDestinationHandler) SendMetric(metric models.Metric) error {
-	return f.writeToFile(metric)
+// NewS3DestinationHandler creates a new S3DestinationHandler. Segments are written to
+// the system temporary directory before they are uploaded.
+func NewS3DestinationHandler(ctx context.Context, dest common.Destination, secrets SecretResolver, logger *logrus.Logger) (*S3DestinationHandler, error) {
+	uploader, err := NewS3Uploader(ctx, dest, secrets, nil, "", logger)
+	if err != nil {
+		return nil, err
+	}
+	return &S3DestinationHandler{
+		uploader: uploader,
+		logger:   logger,
+	}, nil
 }
 
-func (f *FileDestinationHandler) SendTrace(trace models.Trace) error {
-	return f.writeToFile(trace)
+func (h *S3DestinationHandler) SendLogs(ctx context.Context, logs []models.LogEntry) error {
+	for _, log := range logs {
+		if err := h.write(log); err != nil {
+			return err
+		}
+	}
+	return nil
 }
 
-func (f *FileDestinationHandler) writeToFile(payload interface{}) error {
-	if f.currentFile == nil {
-		return fmt.Errorf("log file not initialized")
+func (h *S3DestinationHandler) SendMetrics(ctx context.Context, metrics []models.Metric) error {
+	for _, metric := range metrics {
+		if err := h.write(metric); err != nil {
+			return err
+		}
 	}
+	return nil
+}
 
-	data, err := json.Marshal(payload)
-	if err != nil {
-		f.logger.Errorf("Failed to marshal payload: %v", err)
-		return err
+func (h *S3DestinationHandler) SendTraces(ctx context.Context, traces []models.Trace) error {
+	for _, trace := range traces {
+		if err := h.write(trace); err != nil {
+			return err
+		}
 	}
+	return nil
+}
 
-	_, err = f.currentFile.Write(append(data, '\n'))
-	if err != nil {
-		f.logger.Errorf("Failed to write to file: %v", err)
+func (h *S3DestinationHandler) write(entry interface{}) error {
+	if err := h.uploader.Write(entry); err != nil {
+		h.logger.Errorf("Failed to write to a segment for s3://%s: %v", h.uploader.Bucket(), err)
 		return err
 	}
-
-	f.logger.Debugf("Successfully wrote payload to file")
 	return nil
 }
 
-func (f *FileDestinationHandler) Close() error {
-	close(f.quit)
-	return nil
+func (h *S3DestinationHandler) Close() error {
+	return h.uploader.Close()
 }
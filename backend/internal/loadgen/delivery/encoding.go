@@ -0,0 +1,147 @@
+// backend/internal/loadgen/delivery/encoding.go
+
+package delivery
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math"
+
+	"github.com/AkshayDubey29/MoniFlux/backend/internal/api/models"
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+// Supported payload encodings.
+const (
+	EncodingJSON     = "json"     // A JSON object, or an array when more than one entry is sent
+	EncodingNDJSON   = "ndjson"   // One JSON object per line
+	EncodingProtobuf = "protobuf" // A Batch message, see encodeProtobuf
+	EncodingMsgpack  = "msgpack"  // A MessagePack array of maps keyed like the JSON encoding
+)
+
+// Payload is an encoded and compressed request body.
+type Payload struct {
+	Body            []byte
+	ContentType     string
+	ContentEncoding string // Empty when the body is not compressed
+	RawBytes        int    // Size of the encoded body before compression
+	Entries         int
+}
+
+// EncodePayload encodes the entries (models.LogEntry, models.Metric or models.Trace)
+// and compresses the result.
+func EncodePayload(encoding, compression string, entries []interface{}) (*Payload, error) {
+	body, contentType, err := encode(encoding, entries)
+	if err != nil {
+		return nil, err
+	}
+	compressed, contentEncoding, err := Compress(compression, body)
+	if err != nil {
+		return nil, err
+	}
+	return &Payload{
+		Body:            compressed,
+		ContentType:     contentType,
+		ContentEncoding: contentEncoding,
+		RawBytes:        len(body),
+		Entries:         len(entries),
+	}, nil
+}
+
+// encode returns the encoded entries and their content type.
+func encode(encoding string, entries []interface{}) ([]byte, string, error) {
+	switch encoding {
+	case "", EncodingJSON:
+		var data []byte
+		var err error
+		if len(entries) == 1 {
+			data, err = json.Marshal(entries[0])
+		} else {
+			data, err = json.Marshal(entries)
+		}
+		return data, "application/json", err
+	case EncodingNDJSON:
+		var buf bytes.Buffer
+		encoder := json.NewEncoder(&buf)
+		for _, entry := range entries {
+			if err := encoder.Encode(entry); err != nil {
+				return nil, "", err
+			}
+		}
+		return buf.Bytes(), "application/x-ndjson", nil
+	case EncodingProtobuf:
+		data, err := encodeProtobuf(entries)
+		return data, "application/x-protobuf", err
+	case EncodingMsgpack:
+		data, err := encodeMsgpack(entries)
+		return data, "application/msgpack", err
+	default:
+		return nil, "", fmt.Errorf("unsupported encoding %q", encoding)
+	}
+}
+
+// encodeProtobuf encodes the entries as the following message, with timestamps in
+// nanoseconds since the Unix epoch:
+//
+//	message Batch {
+//	  repeated LogEntry logs = 1;
+//	  repeated Metric metrics = 2;
+//	  repeated Trace traces = 3;
+//	}
+//	message LogEntry { string test_id = 1; int64 timestamp = 2; string message = 3; string level = 4; }
+//	message Metric { string test_id = 1; int64 timestamp = 2; double value = 3; }
+//	message Trace {
+//	  string test_id = 1; int64 timestamp = 2; string trace_id = 3;
+//	  string span_id = 4; string operation = 5; int64 duration_ms = 6;
+//	}
+func encodeProtobuf(entries []interface{}) ([]byte, error) {
+	var batch, msg []byte
+	for _, entry := range entries {
+		msg = msg[:0]
+		var field protowire.Number
+		switch e := entry.(type) {
+		case models.LogEntry:
+			field = 1
+			msg = appendProtoString(msg, 1, e.TestID)
+			msg = appendProtoInt64(msg, 2, e.Timestamp.UnixNano())
+			msg = appendProtoString(msg, 3, e.Message)
+			msg = appendProtoString(msg, 4, e.Level)
+		case models.Metric:
+			field = 2
+			msg = appendProtoString(msg, 1, e.TestID)
+			msg = appendProtoInt64(msg, 2, e.Timestamp.UnixNano())
+			msg = protowire.AppendTag(msg, 3, protowire.Fixed64Type)
+			msg = protowire.AppendFixed64(msg, math.Float64bits(e.Value))
+		case models.Trace:
+			field = 3
+			msg = appendProtoString(msg, 1, e.TestID)
+			msg = appendProtoInt64(msg, 2, e.Timestamp.UnixNano())
+			msg = appendProtoString(msg, 3, e.TraceID)
+			msg = appendProtoString(msg, 4, e.SpanID)
+			msg = appendProtoString(msg, 5, e.Operation)
+			msg = appendProtoInt64(msg, 6, int64(e.Duration))
+		default:
+			return nil, fmt.Errorf("cannot encode %T as protobuf", entry)
+		}
+		batch = protowire.AppendTag(batch, field, protowire.BytesType)
+		batch = protowire.AppendBytes(batch, msg)
+	}
+	return batch, nil
+}
+
+func appendProtoString(b []byte, num protowire.Number, v string) []byte {
+	if v == "" {
+		return b
+	}
+	b = protowire.AppendTag(b, num, protowire.BytesType)
+	return protowire.AppendString(b, v)
+}
+
+func appendProtoInt64(b []byte, num protowire.Number, v int64) []byte {
+	if v == 0 {
+		return b
+	}
+	b = protowire.AppendTag(b, num, protowire.VarintType)
+	return protowire.AppendVarint(b, uint64(v))
+}
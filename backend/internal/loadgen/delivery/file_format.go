@@ -0,0 +1,132 @@
+// backend/internal/loadgen/delivery/file_format.go
+
+package delivery
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/AkshayDubey29/MoniFlux/backend/internal/api/models"
+)
+
+// Output formats of file and s3 destinations. s3 destinations support ndjson and
+// parquet.
+const (
+	FormatNDJSON  = "ndjson"
+	FormatCSV     = "csv"
+	FormatParquet = "parquet"
+)
+
+// fileColumns are the columns of CSV and Parquet files. A file holds the entries of
+// every signal, so the fields of the other signals are empty.
+var fileColumns = []ParquetColumn{
+	{Name: "signal", Type: ParquetString},
+	{Name: "testID", Type: ParquetString},
+	{Name: "timestamp", Type: ParquetTimestamp},
+	{Name: "message", Type: ParquetString, Optional: true},
+	{Name: "level", Type: ParquetString, Optional: true},
+	{Name: "value", Type: ParquetDouble, Optional: true},
+	{Name: "traceID", Type: ParquetString, Optional: true},
+	{Name: "spanID", Type: ParquetString, Optional: true},
+	{Name: "operation", Type: ParquetString, Optional: true},
+	{Name: "duration", Type: ParquetInt64, Optional: true},
+}
+
+// fileRow returns the values of an entry in the order of fileColumns, with nil for
+// the fields of other signals.
+func fileRow(entry interface{}) ([]interface{}, error) {
+	switch e := entry.(type) {
+	case models.LogEntry:
+		return []interface{}{SignalLogs, e.TestID, e.Timestamp, e.Message, e.Level, nil, nil, nil, nil, nil}, nil
+	case models.Metric:
+		return []interface{}{SignalMetrics, e.TestID, e.Timestamp, nil, nil, e.Value, nil, nil, nil, nil}, nil
+	case models.Trace:
+		return []interface{}{SignalTraces, e.TestID, e.Timestamp, nil, nil, nil, e.TraceID, e.SpanID, e.Operation, e.Duration}, nil
+	}
+	return nil, fmt.Errorf("cannot write %T to a file", entry)
+}
+
+// encodeLine returns an entry as a line of a file in the format, which is ndjson or
+// csv.
+func encodeLine(format string, entry interface{}) ([]byte, error) {
+	if format != FormatCSV {
+		data, err := json.Marshal(entry)
+		if err != nil {
+			return nil, err
+		}
+		return append(data, '\n'), nil
+	}
+	row, err := fileRow(entry)
+	if err != nil {
+		return nil, err
+	}
+	fields := make([]string, len(row))
+	for i, value := range row {
+		fields[i] = formatCSVValue(value)
+	}
+	return appendCSVRecord(nil, fields), nil
+}
+
+// csvHeader returns the header line of CSV files.
+func csvHeader() []byte {
+	names := make([]string, len(fileColumns))
+	for i, column := range fileColumns {
+		names[i] = column.Name
+	}
+	return appendCSVRecord(nil, names)
+}
+
+// formatCSVValue formats a row value as a CSV field. Timestamps use RFC 3339 in UTC.
+func formatCSVValue(value interface{}) string {
+	switch v := value.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	case time.Time:
+		return v.UTC().Format(time.RFC3339Nano)
+	case float64:
+		return strconv.FormatFloat(v, 'g', -1, 64)
+	case int:
+		return strconv.Itoa(v)
+	case int64:
+		return strconv.FormatInt(v, 10)
+	}
+	return fmt.Sprint(value)
+}
+
+// appendCSVRecord appends the fields as an RFC 4180 record, quoting the fields that
+// contain a comma, a quote or a line break, or start with a space.
+func appendCSVRecord(b []byte, fields []string) []byte {
+	for i, field := range fields {
+		if i > 0 {
+			b = append(b, ',')
+		}
+		if field == "" || (!strings.ContainsAny(field, ",\"\r\n") && field[0] != ' ' && field[0] != '\t') {
+			b = append(b, field...)
+			continue
+		}
+		b = append(b, '"')
+		b = append(b, strings.ReplaceAll(field, `"`, `""`)...)
+		b = append(b, '"')
+	}
+	return append(b, '\n')
+}
+
+// parquetRowSize estimates the encoded size of a row, before compression.
+func parquetRowSize(row []interface{}) int64 {
+	var size int64
+	for _, value := range row {
+		switch v := value.(type) {
+		case nil:
+		case string:
+			size += 4 + int64(len(v))
+		default:
+			size += 8
+		}
+	}
+	return size
+}
@@ -0,0 +1,339 @@
+// backend/internal/loadgen/delivery/http_sender.go
+
+package delivery
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/AkshayDubey29/MoniFlux/backend/internal/common"
+	"github.com/AkshayDubey29/MoniFlux/backend/pkg/tracing"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+)
+
+// DefaultTenantHeader is sent with the tenant ID when no tenant header is configured.
+const DefaultTenantHeader = "X-Scope-OrgID"
+
+// httpTimeout bounds a single delivery request.
+const httpTimeout = 10 * time.Second
+
+// SecretResolver resolves the secret references of destination credentials. It is
+// implemented by the secret service.
+type SecretResolver interface {
+	Resolve(ctx context.Context, ref string) (string, error)
+}
+
+// DialGuard checks the address of every connection opened to a destination. It is
+// implemented by the dial guards of the guardrail service.
+type DialGuard interface {
+	Control(host string) func(network, address string, c syscall.RawConn) error
+}
+
+// ErrInvalidAuth is returned for destinations combining incompatible credentials.
+var ErrInvalidAuth = errors.New("invalid destination authentication")
+
+// maxRetryAfter caps the Retry-After delay a destination can ask for.
+const maxRetryAfter = time.Minute
+
+// StatusError is returned when a destination answers with a non-2xx status.
+type StatusError struct {
+	StatusCode int
+	RetryAfter time.Duration // From the Retry-After header; zero if absent
+}
+
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("non-success status code: %d", e.StatusCode)
+}
+
+// HTTPSender delivers payloads to an HTTP destination with the destination's
+// authentication, headers and TLS settings.
+type HTTPSender struct {
+	client      *http.Client
+	guard       DialGuard
+	url         string
+	auth        *common.DestinationAuth
+	apiKey      string // Secret reference of the bearer token
+	secrets     SecretResolver
+	oauth2      *tokenSource
+	encoding    string
+	compression string
+}
+
+// NewHTTPSender builds a sender for the destination. The TLS client key is resolved
+// now; every other credential is resolved for each request. Connections are checked
+// by the guard, if not nil, and redirects are not followed.
+func NewHTTPSender(ctx context.Context, dest common.Destination, secrets SecretResolver, guard DialGuard) (*HTTPSender, error) {
+	target, err := TargetURL(dest)
+	if err != nil {
+		return nil, err
+	}
+	if err := CheckAuth(dest); err != nil {
+		return nil, err
+	}
+	// Destinations from the configuration file are not validated like API requests.
+	if err := checkPayloadOptions(dest); err != nil {
+		return nil, err
+	}
+	if len(dest.SecretRefs()) > 0 && secrets == nil {
+		return nil, errors.New("destination credentials require a secret resolver")
+	}
+
+	transport := guardedTransport(guard)
+	if dest.Auth != nil && dest.Auth.TLS != nil {
+		tlsConfig, err := buildTLSConfig(ctx, dest.Auth.TLS, secrets)
+		if err != nil {
+			return nil, err
+		}
+		transport.TLSClientConfig = tlsConfig
+	}
+	client := &http.Client{Timeout: httpTimeout, Transport: transport, CheckRedirect: refuseRedirect}
+
+	sender := &HTTPSender{
+		client:      client,
+		guard:       guard,
+		url:         target,
+		auth:        dest.Auth,
+		apiKey:      dest.SecretRef,
+		secrets:     secrets,
+		encoding:    dest.Encoding,
+		compression: dest.Compression,
+	}
+	if dest.Auth != nil && dest.Auth.OAuth2 != nil {
+		sender.oauth2 = newTokenSource(dest.Auth.OAuth2, client, secrets)
+	}
+	return sender, nil
+}
+
+// refuseRedirect stops a client at a redirect, which is returned as the response:
+// following it would send the request and its credentials to an unchecked target.
+func refuseRedirect(*http.Request, []*http.Request) error {
+	return http.ErrUseLastResponse
+}
+
+// CheckAuth returns an error wrapping ErrInvalidAuth if the destination sets more
+// than one source of the Authorization header.
+func CheckAuth(dest common.Destination) error {
+	if dest.Auth == nil {
+		return nil
+	}
+	if dest.SecretRef != "" && (dest.Auth.Basic != nil || dest.Auth.OAuth2 != nil) {
+		return fmt.Errorf("%w: secretRef cannot be combined with basic or OAuth2 authentication", ErrInvalidAuth)
+	}
+	if dest.Auth.Basic != nil && dest.Auth.OAuth2 != nil {
+		return fmt.Errorf("%w: basic and OAuth2 authentication cannot be combined", ErrInvalidAuth)
+	}
+	return nil
+}
+
+// checkPayloadOptions returns an error for unknown encodings and compressions.
+func checkPayloadOptions(dest common.Destination) error {
+	switch dest.Encoding {
+	case "", EncodingJSON, EncodingNDJSON, EncodingProtobuf, EncodingMsgpack:
+	default:
+		return fmt.Errorf("unsupported encoding %q", dest.Encoding)
+	}
+	switch dest.Compression {
+	case "", CompressionNone, CompressionGzip, CompressionZstd, CompressionSnappy, CompressionDeflate:
+	default:
+		return fmt.Errorf("unsupported compression %q", dest.Compression)
+	}
+	return nil
+}
+
+// URL returns the URL deliveries are sent to.
+func (s *HTTPSender) URL() string {
+	return s.url
+}
+
+// Encode encodes and compresses the entries as configured for the destination.
+func (s *HTTPSender) Encode(entries []interface{}) (*Payload, error) {
+	return EncodePayload(s.encoding, s.compression, entries)
+}
+
+// Send posts the payload to the destination URL, with path appended if it is not
+// empty, and returns a *StatusError for non-2xx responses. The request carries the
+// traceparent of its span, a child of the span in ctx.
+func (s *HTTPSender) Send(ctx context.Context, path string, payload *Payload) (err error) {
+	target := s.url
+	if path != "" {
+		target = strings.TrimSuffix(target, "/") + "/" + strings.TrimPrefix(path, "/")
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, target, bytes.NewReader(payload.Body))
+	if err != nil {
+		return fmt.Errorf("failed to create HTTP request: %w", err)
+	}
+	span := startHTTPSpan(req, tracing.EntriesKey.Int(payload.Entries))
+	defer func() { tracing.End(span, err) }()
+	req.Header.Set("Content-Type", payload.ContentType)
+	if payload.ContentEncoding != "" {
+		req.Header.Set("Content-Encoding", payload.ContentEncoding)
+	}
+	if err := s.authenticate(ctx, req); err != nil {
+		return err
+	}
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	span.SetAttributes(semconv.HTTPResponseStatusCode(resp.StatusCode))
+	// Drain the body so the connection can be reused.
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return &StatusError{
+			StatusCode: resp.StatusCode,
+			RetryAfter: parseRetryAfter(resp.Header.Get("Retry-After"), time.Now()),
+		}
+	}
+	return nil
+}
+
+// parseRetryAfter parses a Retry-After header given in seconds or as an HTTP date.
+// It returns zero for invalid values and caps the delay at maxRetryAfter.
+func parseRetryAfter(value string, now time.Time) time.Duration {
+	if value == "" {
+		return 0
+	}
+	var delay time.Duration
+	if seconds, err := strconv.Atoi(strings.TrimSpace(value)); err == nil {
+		delay = time.Duration(seconds) * time.Second
+	} else if date, err := http.ParseTime(value); err == nil {
+		delay = date.Sub(now)
+	}
+	if delay < 0 {
+		return 0
+	}
+	if delay > maxRetryAfter {
+		return maxRetryAfter
+	}
+	return delay
+}
+
+// authenticate sets the configured headers and credentials on the request.
+func (s *HTTPSender) authenticate(ctx context.Context, req *http.Request) error {
+	if s.apiKey != "" {
+		token, err := s.secrets.Resolve(ctx, s.apiKey)
+		if err != nil {
+			return fmt.Errorf("failed to resolve the API key: %w", err)
+		}
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+
+	auth := s.auth
+	if auth == nil {
+		return nil
+	}
+	for name, value := range auth.Headers {
+		req.Header.Set(name, value)
+	}
+	for name, ref := range auth.SecretHeaders {
+		value, err := s.secrets.Resolve(ctx, ref)
+		if err != nil {
+			return fmt.Errorf("failed to resolve header %s: %w", name, err)
+		}
+		req.Header.Set(name, value)
+	}
+	if auth.TenantID != "" {
+		header := auth.TenantHeader
+		if header == "" {
+			header = DefaultTenantHeader
+		}
+		req.Header.Set(header, auth.TenantID)
+	}
+
+	switch {
+	case auth.Basic != nil:
+		password, err := s.secrets.Resolve(ctx, auth.Basic.PasswordSecretRef)
+		if err != nil {
+			return fmt.Errorf("failed to resolve the basic auth password: %w", err)
+		}
+		req.SetBasicAuth(auth.Basic.Username, password)
+	case s.oauth2 != nil:
+		token, err := s.oauth2.Token(ctx)
+		if err != nil {
+			return err
+		}
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	return nil
+}
+
+// TargetURL returns the URL of an HTTP destination. Endpoints without a scheme use
+// https on port 443 and http otherwise; the destination port is added unless the
+// endpoint has one or it is the scheme's default.
+func TargetURL(dest common.Destination) (string, error) {
+	endpoint := dest.Endpoint
+	if endpoint == "" {
+		return "", errors.New("HTTP endpoint must be specified for HTTP destination")
+	}
+	u, err := url.Parse(endpoint)
+	if err != nil || u.Host == "" {
+		scheme := "http"
+		if dest.Port == 443 {
+			scheme = "https"
+		}
+		u, err = url.Parse(scheme + "://" + endpoint)
+	}
+	if err != nil || u.Host == "" {
+		return "", fmt.Errorf("invalid endpoint %q", endpoint)
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return "", fmt.Errorf("unsupported scheme %q in endpoint %q", u.Scheme, endpoint)
+	}
+
+	defaultPort := 80
+	if u.Scheme == "https" {
+		defaultPort = 443
+	}
+	if u.Port() == "" && dest.Port != 0 && dest.Port != defaultPort {
+		u.Host = net.JoinHostPort(u.Hostname(), strconv.Itoa(dest.Port))
+	}
+	return u.String(), nil
+}
+
+// buildTLSConfig returns the TLS configuration with the custom CA and client certificate.
+func buildTLSConfig(ctx context.Context, cfg *common.DestinationTLS, secrets SecretResolver) (*tls.Config, error) {
+	tlsConfig := &tls.Config{
+		MinVersion:         tls.VersionTLS12,
+		ServerName:         cfg.ServerName,
+		InsecureSkipVerify: cfg.InsecureSkipVerify,
+	}
+
+	if cfg.CACert != "" {
+		pool, err := x509.SystemCertPool()
+		if err != nil {
+			pool = x509.NewCertPool()
+		}
+		if !pool.AppendCertsFromPEM([]byte(cfg.CACert)) {
+			return nil, errors.New("tls.caCert contains no valid PEM certificates")
+		}
+		tlsConfig.RootCAs = pool
+	}
+
+	if cfg.ClientCert != "" {
+		key, err := secrets.Resolve(ctx, cfg.ClientKeySecretRef)
+		if err != nil {
+			return nil, fmt.Errorf("failed to resolve the TLS client key: %w", err)
+		}
+		cert, err := tls.X509KeyPair([]byte(cfg.ClientCert), []byte(key))
+		if err != nil {
+			return nil, fmt.Errorf("invalid TLS client certificate or key: %w", err)
+		}
+		tlsConfig.Certificates = []tls.Certificate{cert}
+	}
+	return tlsConfig, nil
+}
@@ -0,0 +1,136 @@
+// backend/internal/loadgen/delivery/msgpack.go
+
+package delivery
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math"
+	"sort"
+)
+
+// encodeMsgpack encodes the entries as a MessagePack array. Each entry is encoded
+// from its JSON form, so maps have the same keys and timestamps are RFC 3339 strings.
+func encodeMsgpack(entries []interface{}) ([]byte, error) {
+	data, err := json.Marshal(entries)
+	if err != nil {
+		return nil, err
+	}
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	var value interface{}
+	if err := decoder.Decode(&value); err != nil {
+		return nil, err
+	}
+	return appendMsgpack(make([]byte, 0, len(data)), value)
+}
+
+// appendMsgpack appends the MessagePack encoding of a decoded JSON value.
+func appendMsgpack(b []byte, value interface{}) ([]byte, error) {
+	switch v := value.(type) {
+	case nil:
+		return append(b, 0xc0), nil
+	case bool:
+		if v {
+			return append(b, 0xc3), nil
+		}
+		return append(b, 0xc2), nil
+	case json.Number:
+		if i, err := v.Int64(); err == nil {
+			return appendMsgpackInt(b, i), nil
+		}
+		f, err := v.Float64()
+		if err != nil {
+			return nil, err
+		}
+		b = append(b, 0xcb)
+		return appendUint64(b, math.Float64bits(f)), nil
+	case string:
+		return appendMsgpackString(b, v), nil
+	case []interface{}:
+		b = appendMsgpackHeader(b, len(v), 0x90, 0xdc, 0xdd)
+		for _, item := range v {
+			var err error
+			if b, err = appendMsgpack(b, item); err != nil {
+				return nil, err
+			}
+		}
+		return b, nil
+	case map[string]interface{}:
+		keys := make([]string, 0, len(v))
+		for key := range v {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		b = appendMsgpackHeader(b, len(v), 0x80, 0xde, 0xdf)
+		for _, key := range keys {
+			b = appendMsgpackString(b, key)
+			var err error
+			if b, err = appendMsgpack(b, v[key]); err != nil {
+				return nil, err
+			}
+		}
+		return b, nil
+	default:
+		return nil, fmt.Errorf("cannot encode %T as msgpack", value)
+	}
+}
+
+func appendMsgpackInt(b []byte, i int64) []byte {
+	switch {
+	case i >= 0 && i <= 0x7f:
+		return append(b, byte(i))
+	case i < 0 && i >= -32:
+		return append(b, byte(int8(i)))
+	case i >= math.MinInt32 && i <= math.MaxInt32:
+		b = append(b, 0xd2)
+		return appendUint32(b, uint32(int32(i)))
+	default:
+		b = append(b, 0xd3)
+		return appendUint64(b, uint64(i))
+	}
+}
+
+func appendMsgpackString(b []byte, s string) []byte {
+	switch n := len(s); {
+	case n <= 31:
+		b = append(b, 0xa0|byte(n))
+	case n <= math.MaxUint8:
+		b = append(b, 0xd9, byte(n))
+	case n <= math.MaxUint16:
+		b = append(b, 0xda)
+		b = appendUint16(b, uint16(n))
+	default:
+		b = append(b, 0xdb)
+		b = appendUint32(b, uint32(n))
+	}
+	return append(b, s...)
+}
+
+// appendMsgpackHeader appends an array or map header: the fix format for up to 15
+// elements, then the 16-bit and 32-bit formats.
+func appendMsgpackHeader(b []byte, n int, fix, code16, code32 byte) []byte {
+	switch {
+	case n <= 15:
+		return append(b, fix|byte(n))
+	case n <= math.MaxUint16:
+		b = append(b, code16)
+		return appendUint16(b, uint16(n))
+	default:
+		b = append(b, code32)
+		return appendUint32(b, uint32(n))
+	}
+}
+
+func appendUint16(b []byte, v uint16) []byte {
+	return append(b, byte(v>>8), byte(v))
+}
+
+func appendUint32(b []byte, v uint32) []byte {
+	return append(b, byte(v>>24), byte(v>>16), byte(v>>8), byte(v))
+}
+
+func appendUint64(b []byte, v uint64) []byte {
+	return appendUint32(appendUint32(b, uint32(v>>32)), uint32(v))
+}
@@ -0,0 +1,123 @@
+// backend/internal/loadgen/delivery/oauth2.go
+
+package delivery
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/AkshayDubey29/MoniFlux/backend/internal/common"
+)
+
+// tokenExpiryMargin renews tokens this long before they expire.
+const tokenExpiryMargin = 30 * time.Second
+
+// defaultTokenLifetime is assumed for tokens issued without expires_in.
+const defaultTokenLifetime = 5 * time.Minute
+
+// tokenSource fetches OAuth2 access tokens with the client credentials grant and
+// caches them until shortly before they expire.
+type tokenSource struct {
+	config  *common.OAuth2Credentials
+	client  *http.Client
+	secrets SecretResolver
+
+	mu        sync.Mutex
+	token     string
+	expiresAt time.Time
+}
+
+func newTokenSource(config *common.OAuth2Credentials, client *http.Client, secrets SecretResolver) *tokenSource {
+	return &tokenSource{config: config, client: client, secrets: secrets}
+}
+
+// tokenResponse is the successful response of a token endpoint (RFC 6749 section 5.1).
+type tokenResponse struct {
+	AccessToken string `json:"access_token"`
+	TokenType   string `json:"token_type"`
+	ExpiresIn   int    `json:"expires_in"`
+}
+
+// Token returns a cached access token or fetches a new one. Concurrent callers wait
+// for a single fetch.
+func (ts *tokenSource) Token(ctx context.Context) (string, error) {
+	ts.mu.Lock()
+	defer ts.mu.Unlock()
+
+	if ts.token != "" && time.Now().Before(ts.expiresAt) {
+		return ts.token, nil
+	}
+
+	token, lifetime, err := ts.fetch(ctx)
+	if err != nil {
+		return "", err
+	}
+	ts.token = token
+	ts.expiresAt = time.Now().Add(lifetime - tokenExpiryMargin)
+	return token, nil
+}
+
+// fetch requests a token, authenticating the client with HTTP basic authentication.
+func (ts *tokenSource) fetch(ctx context.Context) (string, time.Duration, error) {
+	secret, err := ts.secrets.Resolve(ctx, ts.config.ClientSecretRef)
+	if err != nil {
+		return "", 0, fmt.Errorf("failed to resolve the OAuth2 client secret: %w", err)
+	}
+
+	form := url.Values{"grant_type": {"client_credentials"}}
+	if len(ts.config.Scopes) > 0 {
+		form.Set("scope", strings.Join(ts.config.Scopes, " "))
+	}
+	for key, value := range ts.config.EndpointParams {
+		form.Set(key, value)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, ts.config.TokenURL, strings.NewReader(form.Encode()))
+	if err != nil {
+		return "", 0, fmt.Errorf("failed to create OAuth2 token request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+	req.SetBasicAuth(url.QueryEscape(ts.config.ClientID), url.QueryEscape(secret))
+
+	resp, err := ts.client.Do(req)
+	if err != nil {
+		return "", 0, fmt.Errorf("OAuth2 token request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return "", 0, fmt.Errorf("failed to read OAuth2 token response: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return "", 0, fmt.Errorf("OAuth2 token endpoint returned status %d", resp.StatusCode)
+	}
+
+	var token tokenResponse
+	if err := json.Unmarshal(body, &token); err != nil {
+		return "", 0, fmt.Errorf("invalid OAuth2 token response: %w", err)
+	}
+	if token.AccessToken == "" {
+		return "", 0, fmt.Errorf("OAuth2 token response has no access_token")
+	}
+	if token.TokenType != "" && !strings.EqualFold(token.TokenType, "bearer") {
+		return "", 0, fmt.Errorf("unsupported OAuth2 token type %q", token.TokenType)
+	}
+
+	lifetime := defaultTokenLifetime
+	if token.ExpiresIn > 0 {
+		lifetime = time.Duration(token.ExpiresIn) * time.Second
+	}
+	if lifetime <= tokenExpiryMargin {
+		lifetime = tokenExpiryMargin + time.Second
+	}
+	return token.AccessToken, lifetime, nil
+}
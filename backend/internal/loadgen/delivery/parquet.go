@@ -0,0 +1,486 @@
+// backend/internal/loadgen/delivery/parquet.go
+
+package delivery
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"time"
+
+	"github.com/AkshayDubey29/MoniFlux/backend/internal/api/models"
+)
+
+// ParquetType is the type of a Parquet column.
+type ParquetType int
+
+// Supported Parquet column types.
+const (
+	ParquetString    ParquetType = iota // UTF-8 BYTE_ARRAY
+	ParquetInt64                        // INT64
+	ParquetDouble                       // DOUBLE
+	ParquetTimestamp                    // INT64 microseconds since the Unix epoch, in UTC
+)
+
+// physicalType returns the Parquet type the column values are stored as.
+func (t ParquetType) physicalType() int32 {
+	switch t {
+	case ParquetString:
+		return parquetTypeByteArray
+	case ParquetDouble:
+		return parquetTypeDouble
+	default:
+		return parquetTypeInt64
+	}
+}
+
+// ParquetColumn is a column of a Parquet file. Optional columns accept nil values.
+type ParquetColumn struct {
+	Name     string
+	Type     ParquetType
+	Optional bool
+}
+
+// Parquet format constants, see parquet.thrift.
+const (
+	parquetTypeInt64     = 2
+	parquetTypeDouble    = 5
+	parquetTypeByteArray = 6
+
+	parquetConvertedUTF8            = 0
+	parquetConvertedTimestampMicros = 10
+
+	parquetRequired      = 0
+	parquetOptional      = 1
+	parquetEncodingPlain = 0
+	parquetEncodingRLE   = 3
+	parquetDataPage      = 0
+
+	parquetCodecUncompressed = 0
+	parquetCodecGzip         = 2
+	parquetCodecZstd         = 6
+)
+
+var parquetMagic = []byte("PAR1")
+
+// parquetChunk is the metadata of a written column chunk.
+type parquetChunk struct {
+	offset       int64
+	compressed   int64
+	uncompressed int64
+}
+
+// parquetRowGroup is the metadata of a written row group.
+type parquetRowGroup struct {
+	rows   int64
+	size   int64 // Uncompressed size of its column chunks
+	chunks []parquetChunk
+}
+
+// ParquetWriter writes rows to a Parquet file, one row group per WriteRows call.
+// Each column chunk is a single PLAIN-encoded data page, compressed with the
+// writer's compression. It is not safe for concurrent use.
+type ParquetWriter struct {
+	w           io.Writer
+	columns     []ParquetColumn
+	compression string
+	codec       int32
+	offset      int64
+	rows        int64
+	rowGroups   []parquetRowGroup
+	metadata    [][2]string // Key-value metadata written to the footer
+	closed      bool
+}
+
+// NewParquetWriter writes the header of a Parquet file with the columns. compression
+// is none, gzip or zstd.
+func NewParquetWriter(w io.Writer, columns []ParquetColumn, compression string) (*ParquetWriter, error) {
+	if len(columns) == 0 {
+		return nil, errors.New("a Parquet file needs at least one column")
+	}
+	p := &ParquetWriter{w: w, columns: columns, compression: compression}
+	switch compression {
+	case "", CompressionNone:
+		p.codec = parquetCodecUncompressed
+	case CompressionGzip:
+		p.codec = parquetCodecGzip
+	case CompressionZstd:
+		p.codec = parquetCodecZstd
+	default:
+		return nil, fmt.Errorf("unsupported Parquet compression %q", compression)
+	}
+	if err := p.write(parquetMagic); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+func (p *ParquetWriter) write(data []byte) error {
+	n, err := p.w.Write(data)
+	p.offset += int64(n)
+	return err
+}
+
+// SetMetadata adds a key-value pair to the metadata of the file, for example to
+// describe its contents. It must be called before Close.
+func (p *ParquetWriter) SetMetadata(key, value string) {
+	p.metadata = append(p.metadata, [2]string{key, value})
+}
+
+// Size returns the number of bytes written so far.
+func (p *ParquetWriter) Size() int64 {
+	return p.offset
+}
+
+// WriteRows writes the rows as a row group. Each row holds a value per column: a
+// string, an int or int64, a float64 or a time.Time, or nil in optional columns.
+func (p *ParquetWriter) WriteRows(rows [][]interface{}) error {
+	if p.closed {
+		return errors.New("parquet writer is closed")
+	}
+	if len(rows) == 0 {
+		return nil
+	}
+	group := parquetRowGroup{rows: int64(len(rows)), chunks: make([]parquetChunk, len(p.columns))}
+	var page []byte
+	for i, column := range p.columns {
+		page = page[:0]
+		if column.Optional {
+			page = appendDefinitionLevels(page, rows, i)
+		}
+		for _, row := range rows {
+			if len(row) != len(p.columns) {
+				return fmt.Errorf("row has %d values for %d columns", len(row), len(p.columns))
+			}
+			if row[i] == nil && column.Optional {
+				continue
+			}
+			var err error
+			if page, err = appendParquetValue(page, column, row[i]); err != nil {
+				return err
+			}
+		}
+		compressed, _, err := Compress(p.compression, page)
+		if err != nil {
+			return err
+		}
+
+		var header thriftWriter
+		header.begin()
+		header.i32(1, parquetDataPage)
+		header.i32(2, int32(len(page)))
+		header.i32(3, int32(len(compressed)))
+		header.beginStruct(5)
+		header.i32(1, int32(len(rows)))
+		header.i32(2, parquetEncodingPlain)
+		header.i32(3, parquetEncodingRLE)
+		header.i32(4, parquetEncodingRLE)
+		header.endStruct()
+		header.end()
+
+		chunk := parquetChunk{
+			offset:       p.offset,
+			compressed:   int64(len(header.buf) + len(compressed)),
+			uncompressed: int64(len(header.buf) + len(page)),
+		}
+		if err := p.write(header.buf); err != nil {
+			return err
+		}
+		if err := p.write(compressed); err != nil {
+			return err
+		}
+		group.chunks[i] = chunk
+		group.size += chunk.uncompressed
+	}
+	p.rowGroups = append(p.rowGroups, group)
+	p.rows += group.rows
+	return nil
+}
+
+// appendDefinitionLevels appends the definition levels of column i of the rows, 0
+// for nil and 1 otherwise, as a length-prefixed bit-packed run.
+func appendDefinitionLevels(b []byte, rows [][]interface{}, i int) []byte {
+	groups := (len(rows) + 7) / 8
+	levels := appendUvarint(nil, uint64(groups)<<1|1)
+	start := len(levels)
+	levels = append(levels, make([]byte, groups)...)
+	for j, row := range rows {
+		if i < len(row) && row[i] != nil {
+			levels[start+j/8] |= 1 << (j % 8)
+		}
+	}
+	b = appendUint32LE(b, uint32(len(levels)))
+	return append(b, levels...)
+}
+
+// appendParquetValue appends the PLAIN encoding of a value of the column.
+func appendParquetValue(b []byte, column ParquetColumn, value interface{}) ([]byte, error) {
+	switch column.Type {
+	case ParquetString:
+		if s, ok := value.(string); ok {
+			b = appendUint32LE(b, uint32(len(s)))
+			return append(b, s...), nil
+		}
+	case ParquetInt64:
+		switch v := value.(type) {
+		case int:
+			return appendUint64LE(b, uint64(v)), nil
+		case int64:
+			return appendUint64LE(b, uint64(v)), nil
+		}
+	case ParquetDouble:
+		if f, ok := value.(float64); ok {
+			return appendUint64LE(b, math.Float64bits(f)), nil
+		}
+	case ParquetTimestamp:
+		if t, ok := value.(time.Time); ok {
+			return appendUint64LE(b, uint64(t.UnixNano()/int64(time.Microsecond))), nil
+		}
+	}
+	return nil, fmt.Errorf("invalid value %T for Parquet column %s", value, column.Name)
+}
+
+// Close writes the footer of the file. It does not close the underlying writer.
+func (p *ParquetWriter) Close() error {
+	if p.closed {
+		return nil
+	}
+	p.closed = true
+
+	var meta thriftWriter
+	meta.begin()
+	meta.i32(1, 1) // version
+	meta.beginList(2, thriftStruct, len(p.columns)+1)
+	meta.beginElement()
+	meta.binary(4, "schema")
+	meta.i32(5, int32(len(p.columns)))
+	meta.endStruct()
+	for _, column := range p.columns {
+		repetition := int32(parquetRequired)
+		if column.Optional {
+			repetition = parquetOptional
+		}
+		meta.beginElement()
+		meta.i32(1, column.Type.physicalType())
+		meta.i32(3, repetition)
+		meta.binary(4, column.Name)
+		switch column.Type {
+		case ParquetString:
+			meta.i32(6, parquetConvertedUTF8)
+		case ParquetTimestamp:
+			meta.i32(6, parquetConvertedTimestampMicros)
+		}
+		meta.endStruct()
+	}
+	meta.i64(3, p.rows)
+	meta.beginList(4, thriftStruct, len(p.rowGroups))
+	for _, group := range p.rowGroups {
+		meta.beginElement()
+		meta.beginList(1, thriftStruct, len(group.chunks))
+		for i, chunk := range group.chunks {
+			column := p.columns[i]
+			meta.beginElement()
+			meta.i64(2, chunk.offset)
+			meta.beginStruct(3)
+			meta.i32(1, column.Type.physicalType())
+			if column.Optional {
+				meta.beginList(2, thriftI32, 2)
+				meta.listI32(parquetEncodingPlain)
+				meta.listI32(parquetEncodingRLE)
+			} else {
+				meta.beginList(2, thriftI32, 1)
+				meta.listI32(parquetEncodingPlain)
+			}
+			meta.beginList(3, thriftBinary, 1)
+			meta.listBinary(column.Name)
+			meta.i32(4, p.codec)
+			meta.i64(5, group.rows)
+			meta.i64(6, chunk.uncompressed)
+			meta.i64(7, chunk.compressed)
+			meta.i64(9, chunk.offset)
+			meta.endStruct()
+			meta.endStruct()
+		}
+		meta.i64(2, group.size)
+		meta.i64(3, group.rows)
+		meta.endStruct()
+	}
+	if len(p.metadata) > 0 {
+		meta.beginList(5, thriftStruct, len(p.metadata))
+		for _, kv := range p.metadata {
+			meta.beginElement()
+			meta.binary(1, kv[0])
+			meta.binary(2, kv[1])
+			meta.endStruct()
+		}
+	}
+	meta.binary(6, "moniflux")
+	meta.end()
+
+	footer := appendUint32LE(meta.buf, uint32(len(meta.buf)))
+	footer = append(footer, parquetMagic...)
+	return p.write(footer)
+}
+
+// Thrift compact protocol types used by the Parquet metadata.
+const (
+	thriftI32    = 5
+	thriftI64    = 6
+	thriftBinary = 8
+	thriftList   = 9
+	thriftStruct = 12
+)
+
+// thriftWriter encodes Parquet metadata structs with the Thrift compact protocol.
+// Fields must be written in increasing order of their IDs.
+type thriftWriter struct {
+	buf  []byte
+	last []int16 // Last field ID of each open struct
+}
+
+// begin starts the top-level struct.
+func (t *thriftWriter) begin() {
+	t.last = append(t.last[:0], 0)
+}
+
+// end closes the top-level struct.
+func (t *thriftWriter) end() {
+	t.endStruct()
+}
+
+func (t *thriftWriter) field(id int16, typ byte) {
+	last := &t.last[len(t.last)-1]
+	if delta := id - *last; delta > 0 && delta <= 15 {
+		t.buf = append(t.buf, byte(delta)<<4|typ)
+	} else {
+		t.buf = append(t.buf, typ)
+		t.buf = appendThriftVarint(t.buf, int64(id))
+	}
+	*last = id
+}
+
+func (t *thriftWriter) i32(id int16, v int32) {
+	t.field(id, thriftI32)
+	t.buf = appendThriftVarint(t.buf, int64(v))
+}
+
+func (t *thriftWriter) i64(id int16, v int64) {
+	t.field(id, thriftI64)
+	t.buf = appendThriftVarint(t.buf, v)
+}
+
+func (t *thriftWriter) binary(id int16, s string) {
+	t.field(id, thriftBinary)
+	t.listBinary(s)
+}
+
+// beginStruct starts a struct field; endStruct closes it.
+func (t *thriftWriter) beginStruct(id int16) {
+	t.field(id, thriftStruct)
+	t.last = append(t.last, 0)
+}
+
+func (t *thriftWriter) endStruct() {
+	t.buf = append(t.buf, 0) // STOP
+	t.last = t.last[:len(t.last)-1]
+}
+
+// beginList starts a list field of n elements, which follow as listI32 and listBinary
+// values or as structs between beginElement and endStruct.
+func (t *thriftWriter) beginList(id int16, elemType byte, n int) {
+	t.field(id, thriftList)
+	if n < 15 {
+		t.buf = append(t.buf, byte(n)<<4|elemType)
+		return
+	}
+	t.buf = append(t.buf, 0xf0|elemType)
+	t.buf = appendUvarint(t.buf, uint64(n))
+}
+
+func (t *thriftWriter) beginElement() {
+	t.last = append(t.last, 0)
+}
+
+func (t *thriftWriter) listI32(v int32) {
+	t.buf = appendThriftVarint(t.buf, int64(v))
+}
+
+func (t *thriftWriter) listBinary(s string) {
+	t.buf = appendUvarint(t.buf, uint64(len(s)))
+	t.buf = append(t.buf, s...)
+}
+
+func appendUint32LE(b []byte, v uint32) []byte {
+	return append(b, byte(v), byte(v>>8), byte(v>>16), byte(v>>24))
+}
+
+func appendUint64LE(b []byte, v uint64) []byte {
+	return appendUint32LE(appendUint32LE(b, uint32(v)), uint32(v>>32))
+}
+
+// appendThriftVarint appends a zigzag-encoded varint.
+func appendThriftVarint(b []byte, v int64) []byte {
+	return appendUvarint(b, uint64(v<<1)^uint64(v>>63))
+}
+
+func appendUvarint(b []byte, v uint64) []byte {
+	for v >= 0x80 {
+		b = append(b, byte(v)|0x80)
+		v >>= 7
+	}
+	return append(b, byte(v))
+}
+
+// Signals of the generated entries, as used in object keys.
+const (
+	SignalLogs    = "logs"
+	SignalMetrics = "metrics"
+	SignalTraces  = "traces"
+)
+
+// entrySignal returns the signal of an entry, or "" for unknown types.
+func entrySignal(entry interface{}) string {
+	switch entry.(type) {
+	case models.LogEntry:
+		return SignalLogs
+	case models.Metric:
+		return SignalMetrics
+	case models.Trace:
+		return SignalTraces
+	}
+	return ""
+}
+
+// entryColumns returns the Parquet columns of the entries of a signal, named like
+// their JSON fields.
+func entryColumns(signal string) []ParquetColumn {
+	columns := []ParquetColumn{{Name: "testID", Type: ParquetString}, {Name: "timestamp", Type: ParquetTimestamp}}
+	switch signal {
+	case SignalLogs:
+		return append(columns, ParquetColumn{Name: "message", Type: ParquetString}, ParquetColumn{Name: "level", Type: ParquetString})
+	case SignalMetrics:
+		return append(columns, ParquetColumn{Name: "value", Type: ParquetDouble})
+	case SignalTraces:
+		return append(columns,
+			ParquetColumn{Name: "traceID", Type: ParquetString},
+			ParquetColumn{Name: "spanID", Type: ParquetString},
+			ParquetColumn{Name: "operation", Type: ParquetString},
+			ParquetColumn{Name: "duration", Type: ParquetInt64},
+		)
+	}
+	return nil
+}
+
+// entryRow returns the values of an entry in the order of entryColumns.
+func entryRow(entry interface{}) ([]interface{}, error) {
+	switch e := entry.(type) {
+	case models.LogEntry:
+		return []interface{}{e.TestID, e.Timestamp, e.Message, e.Level}, nil
+	case models.Metric:
+		return []interface{}{e.TestID, e.Timestamp, e.Value}, nil
+	case models.Trace:
+		return []interface{}{e.TestID, e.Timestamp, e.TraceID, e.SpanID, e.Operation, e.Duration}, nil
+	}
+	return nil, fmt.Errorf("cannot encode %T as Parquet", entry)
+}
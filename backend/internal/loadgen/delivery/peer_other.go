@@ -0,0 +1,13 @@
+// backend/internal/loadgen/delivery/peer_other.go
+
+//go:build !(linux || darwin || freebsd || netbsd || openbsd)
+
+package delivery
+
+import "net"
+
+// peerClosed cannot tell without reading on this platform; a closed connection is
+// detected when a write fails.
+func peerClosed(conn net.Conn) bool {
+	return false
+}
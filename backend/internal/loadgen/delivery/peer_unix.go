@@ -0,0 +1,42 @@
+// backend/internal/loadgen/delivery/peer_unix.go
+
+//go:build linux || darwin || freebsd || netbsd || openbsd
+
+package delivery
+
+import (
+	"crypto/tls"
+	"net"
+	"syscall"
+)
+
+// peerClosed reports whether the peer has closed the TCP connection, peeking at the
+// socket without blocking or consuming data. For TLS connections, unread records
+// from the peer hide its close until a write fails.
+func peerClosed(conn net.Conn) bool {
+	if tlsConn, ok := conn.(*tls.Conn); ok {
+		conn = tlsConn.NetConn()
+	}
+	sc, ok := conn.(syscall.Conn)
+	if !ok {
+		return false
+	}
+	raw, err := sc.SyscallConn()
+	if err != nil {
+		return false
+	}
+	closed := false
+	_ = raw.Read(func(fd uintptr) bool {
+		var buf [1]byte
+		n, _, err := syscall.Recvfrom(int(fd), buf[:], syscall.MSG_PEEK|syscall.MSG_DONTWAIT)
+		switch {
+		case err == syscall.EAGAIN || err == syscall.EWOULDBLOCK:
+		case err != nil:
+			closed = true
+		default:
+			closed = n == 0
+		}
+		return true
+	})
+	return closed
+}
@@ -0,0 +1,414 @@
+// backend/internal/loadgen/delivery/probe.go
+
+package delivery
+
+import (
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/AkshayDubey29/MoniFlux/backend/internal/api/models"
+	"github.com/AkshayDubey29/MoniFlux/backend/internal/common"
+)
+
+// probePhaseTimeout bounds each phase of a destination probe.
+const probePhaseTimeout = 10 * time.Second
+
+// ProbeKeyPrefix is the prefix of the objects uploaded by probes of s3 destinations.
+// They are deleted after the upload when the credentials allow it.
+const ProbeKeyPrefix = "moniflux-probe/"
+
+// ProbeSample is a sample entry sent by a probe.
+type ProbeSample struct {
+	Signal string // log, metric or trace
+	Entry  interface{}
+}
+
+// prober records the phases of a probe. Once a connection phase fails, the phases
+// after it are skipped.
+type prober struct {
+	report *models.ProbeReport
+	guard  DialGuard // Checks every connection of the probe; may be nil
+	failed bool
+}
+
+// run runs a phase and records its outcome. fn returns a detail of the outcome.
+func (p *prober) run(ctx context.Context, phase, signal string, fn func(ctx context.Context) (string, error)) error {
+	if p.failed {
+		p.skip(phase, signal, "")
+		return errors.New("skipped")
+	}
+	ctx, cancel := context.WithTimeout(ctx, probePhaseTimeout)
+	defer cancel()
+
+	start := time.Now()
+	detail, err := fn(ctx)
+	result := models.ProbePhase{
+		Phase:     phase,
+		Signal:    signal,
+		Status:    models.ProbeStatusOK,
+		LatencyMs: milliseconds(time.Since(start)),
+		Detail:    detail,
+	}
+	if err != nil {
+		result.Status = models.ProbeStatusFailed
+		result.Error = err.Error()
+		p.report.Success = false
+	}
+	p.report.Phases = append(p.report.Phases, result)
+	return err
+}
+
+// connect runs a phase that the following phases depend on.
+func (p *prober) connect(ctx context.Context, phase string, fn func(ctx context.Context) (string, error)) {
+	if err := p.run(ctx, phase, "", fn); err != nil {
+		p.failed = true
+	}
+}
+
+// skip records a phase that does not apply to the destination.
+func (p *prober) skip(phase, signal, detail string) {
+	p.report.Phases = append(p.report.Phases, models.ProbePhase{
+		Phase:  phase,
+		Signal: signal,
+		Status: models.ProbeStatusSkipped,
+		Detail: detail,
+	})
+}
+
+func milliseconds(d time.Duration) float64 {
+	return float64(d.Microseconds()) / 1000
+}
+
+// Probe checks that the destination accepts deliveries: it builds the sender,
+// resolves the host, opens a TCP connection, completes the TLS handshake, resolves
+// the credentials and sends each sample in its own request, batch or object. The
+// phases that do not apply to the destination are skipped. Samples are delivered for
+// real, so destinations receive one entry of each signal. Connections are checked by
+// the guard, if not nil.
+func Probe(ctx context.Context, dest common.Destination, secrets SecretResolver, guard DialGuard, samples []ProbeSample) *models.ProbeReport {
+	start := time.Now()
+	p := &prober{guard: guard, report: &models.ProbeReport{
+		Destination: dest.Type,
+		Success:     true,
+		CheckedAt:   start.UTC(),
+		Phases:      []models.ProbePhase{},
+	}}
+
+	switch {
+	case dest.Type == "http":
+		p.probeHTTP(ctx, dest, secrets, samples)
+	case dest.Type == "s3":
+		p.probeS3(ctx, dest, secrets, samples)
+	case IsSocketDestination(dest.Type):
+		p.probeSocket(ctx, dest, secrets, samples)
+	case dest.Type == "file":
+		p.probeFile(ctx, dest, samples)
+	default:
+		p.connect(ctx, models.ProbePhaseConfig, func(context.Context) (string, error) {
+			return "", fmt.Errorf("unsupported destination type %q", dest.Type)
+		})
+	}
+
+	p.report.LatencyMs = milliseconds(time.Since(start))
+	return p.report
+}
+
+func (p *prober) probeHTTP(ctx context.Context, dest common.Destination, secrets SecretResolver, samples []ProbeSample) {
+	var sender *HTTPSender
+	p.connect(ctx, models.ProbePhaseConfig, func(ctx context.Context) (string, error) {
+		var err error
+		sender, err = NewHTTPSender(ctx, dest, secrets, p.guard)
+		if err != nil {
+			return "", err
+		}
+		p.report.Target = sender.URL()
+		return "", nil
+	})
+
+	var target *url.URL
+	if sender != nil {
+		target, _ = url.Parse(sender.URL())
+	}
+	if target != nil && target.Scheme == "https" {
+		p.probeConnection(ctx, urlAddress(target), transportTLS(sender.client, target.Hostname()))
+	} else {
+		address := ""
+		if target != nil {
+			address = urlAddress(target)
+		}
+		p.probeConnection(ctx, address, nil)
+	}
+
+	if dest.SecretRef == "" && (dest.Auth == nil || dest.Auth.Basic == nil && dest.Auth.OAuth2 == nil && len(dest.Auth.SecretHeaders) == 0) {
+		p.skip(models.ProbePhaseAuth, "", "no credentials configured")
+	} else {
+		p.connect(ctx, models.ProbePhaseAuth, func(ctx context.Context) (string, error) {
+			req, err := http.NewRequestWithContext(ctx, http.MethodPost, sender.URL(), nil)
+			if err != nil {
+				return "", err
+			}
+			if err := sender.authenticate(ctx, req); err != nil {
+				return "", err
+			}
+			return authScheme(dest), nil
+		})
+	}
+
+	for _, sample := range samples {
+		p.run(ctx, models.ProbePhaseSend, sample.Signal, func(ctx context.Context) (string, error) {
+			payload, err := sender.Encode([]interface{}{sample.Entry})
+			if err != nil {
+				return "", err
+			}
+			err = sender.Send(ctx, "", payload)
+			var statusErr *StatusError
+			if errors.As(err, &statusErr) && (statusErr.StatusCode == http.StatusUnauthorized || statusErr.StatusCode == http.StatusForbidden) {
+				return "", fmt.Errorf("%w: the destination rejected the credentials", err)
+			}
+			if err != nil {
+				return "", err
+			}
+			return fmt.Sprintf("%d bytes as %s", len(payload.Body), payload.ContentType), nil
+		})
+	}
+}
+
+// authScheme describes the credentials sent to an HTTP destination.
+func authScheme(dest common.Destination) string {
+	switch {
+	case dest.Auth != nil && dest.Auth.Basic != nil:
+		return "basic"
+	case dest.Auth != nil && dest.Auth.OAuth2 != nil:
+		return "oauth2 token obtained"
+	case dest.SecretRef != "":
+		return "bearer"
+	default:
+		return "secret headers"
+	}
+}
+
+func (p *prober) probeS3(ctx context.Context, dest common.Destination, secrets SecretResolver, samples []ProbeSample) {
+	var client *s3Client
+	p.connect(ctx, models.ProbePhaseConfig, func(ctx context.Context) (string, error) {
+		if err := CheckS3Options(dest.S3); err != nil {
+			return "", err
+		}
+		var err error
+		client, err = newS3Client(ctx, dest, secrets, p.guard)
+		if err != nil {
+			return "", err
+		}
+		p.report.Target = "s3://" + client.bucket
+		return client.endpoint.String(), nil
+	})
+
+	address := ""
+	var tlsConfig *tls.Config
+	if client != nil {
+		address = urlAddress(client.endpoint)
+		if client.endpoint.Scheme == "https" {
+			tlsConfig = transportTLS(client.client, client.endpoint.Hostname())
+		}
+	}
+	p.probeConnection(ctx, address, tlsConfig)
+
+	p.connect(ctx, models.ProbePhaseAuth, func(ctx context.Context) (string, error) {
+		if _, err := client.secrets.Resolve(ctx, client.secretKeyRef); err != nil {
+			return "", fmt.Errorf("failed to resolve the S3 secret access key: %w", err)
+		}
+		if client.sessionRef != "" {
+			if _, err := client.secrets.Resolve(ctx, client.sessionRef); err != nil {
+				return "", fmt.Errorf("failed to resolve the S3 session token: %w", err)
+			}
+		}
+		return "signature v4 with access key " + client.accessKeyID, nil
+	})
+
+	prefix := ProbeKeyPrefix + strconv.FormatInt(time.Now().UnixNano(), 10) + "/"
+	for _, sample := range samples {
+		p.run(ctx, models.ProbePhaseSend, sample.Signal, func(ctx context.Context) (string, error) {
+			body, err := json.Marshal(sample.Entry)
+			if err != nil {
+				return "", err
+			}
+			key := prefix + sample.Signal + ".ndjson"
+			if err := client.putObject(ctx, key, "application/x-ndjson", append(body, '\n')); err != nil {
+				return "", err
+			}
+			if err := client.deleteObject(ctx, key); err != nil {
+				return "uploaded " + key + " (not deleted: " + err.Error() + ")", nil
+			}
+			return "uploaded and deleted " + key, nil
+		})
+	}
+}
+
+func (p *prober) probeSocket(ctx context.Context, dest common.Destination, secrets SecretResolver, samples []ProbeSample) {
+	var sender *SocketSender
+	p.connect(ctx, models.ProbePhaseConfig, func(ctx context.Context) (string, error) {
+		var err error
+		sender, err = NewSocketSender(ctx, dest, secrets, p.guard)
+		if err != nil {
+			return "", err
+		}
+		p.report.Target = sender.network + "://" + sender.address
+		return "", nil
+	})
+	if sender != nil {
+		defer sender.Close()
+	}
+
+	if sender != nil && sender.network == "udp" {
+		p.probeResolve(ctx, sender.address)
+		p.skip(models.ProbePhaseTCP, "", "udp is connectionless")
+		p.skip(models.ProbePhaseTLS, "", "")
+	} else {
+		address := ""
+		var tlsConfig *tls.Config
+		if sender != nil {
+			address, tlsConfig = sender.address, sender.tlsConfig
+		}
+		p.probeConnection(ctx, address, tlsConfig)
+	}
+	if sender != nil && sender.tlsConfig != nil && len(sender.tlsConfig.Certificates) > 0 {
+		p.skip(models.ProbePhaseAuth, "", "client certificate presented during the TLS handshake")
+	} else {
+		p.skip(models.ProbePhaseAuth, "", "no credentials configured")
+	}
+
+	for _, sample := range samples {
+		p.run(ctx, models.ProbePhaseSend, sample.Signal, func(ctx context.Context) (string, error) {
+			if err := sender.Send(ctx, []interface{}{sample.Entry}); err != nil {
+				return "", err
+			}
+			if sender.network == "udp" {
+				return "datagram written; delivery is not acknowledged", nil
+			}
+			return "", nil
+		})
+	}
+}
+
+func (p *prober) probeFile(ctx context.Context, dest common.Destination, samples []ProbeSample) {
+	p.report.Target = dest.FilePath
+	dir := filepath.Dir(dest.FilePath)
+	p.connect(ctx, models.ProbePhaseConfig, func(ctx context.Context) (string, error) {
+		if dest.FilePath == "" {
+			return "", errors.New("filePath must be specified for file destination")
+		}
+		return "", (&RotatingFile{opts: FileOptions(dest, 0)}).CheckReachable(ctx)
+	})
+	p.skip(models.ProbePhaseDNS, "", "local file")
+	p.skip(models.ProbePhaseTCP, "", "local file")
+	p.skip(models.ProbePhaseTLS, "", "local file")
+	p.skip(models.ProbePhaseAuth, "", "local file")
+
+	// Samples are written to a temporary file next to the destination, so the
+	// destination file is not created or rotated by the probe.
+	for _, sample := range samples {
+		p.run(ctx, models.ProbePhaseSend, sample.Signal, func(context.Context) (string, error) {
+			data, err := json.Marshal(sample.Entry)
+			if err != nil {
+				return "", err
+			}
+			f, err := os.CreateTemp(dir, ".moniflux-probe-*")
+			if err != nil {
+				return "", err
+			}
+			defer os.Remove(f.Name())
+			if _, err := f.Write(append(data, '\n')); err != nil {
+				f.Close()
+				return "", err
+			}
+			return fmt.Sprintf("%d bytes written to %s", len(data)+1, dir), f.Close()
+		})
+	}
+}
+
+// probeConnection runs the dns, tcp and, if tlsConfig is not nil, tls phases against
+// address.
+func (p *prober) probeConnection(ctx context.Context, address string, tlsConfig *tls.Config) {
+	ips := p.probeResolve(ctx, address)
+
+	var conn net.Conn
+	p.connect(ctx, models.ProbePhaseTCP, func(ctx context.Context) (string, error) {
+		host, port, _ := net.SplitHostPort(address)
+		dialer := newDialer(p.guard, host, 0)
+		err := fmt.Errorf("no address found for %s", address)
+		for _, ip := range ips {
+			if conn, err = dialer.DialContext(ctx, "tcp", net.JoinHostPort(ip, port)); err == nil {
+				return "connected to " + conn.RemoteAddr().String(), nil
+			}
+		}
+		return "", err
+	})
+	if conn != nil {
+		defer conn.Close()
+	}
+
+	if tlsConfig == nil {
+		p.skip(models.ProbePhaseTLS, "", "plaintext")
+		return
+	}
+	p.connect(ctx, models.ProbePhaseTLS, func(ctx context.Context) (string, error) {
+		tlsConn := tls.Client(conn, tlsConfig)
+		if err := tlsConn.HandshakeContext(ctx); err != nil {
+			return "", err
+		}
+		state := tlsConn.ConnectionState()
+		detail := tls.VersionName(state.Version)
+		if len(state.PeerCertificates) > 0 {
+			cert := state.PeerCertificates[0]
+			detail += fmt.Sprintf(", certificate %s expires %s", cert.Subject.CommonName, cert.NotAfter.UTC().Format(time.RFC3339))
+		}
+		return detail, nil
+	})
+}
+
+// probeResolve runs the dns phase and returns the addresses of the host of address.
+func (p *prober) probeResolve(ctx context.Context, address string) []string {
+	var ips []string
+	p.connect(ctx, models.ProbePhaseDNS, func(ctx context.Context) (string, error) {
+		host, _, err := net.SplitHostPort(address)
+		if err != nil {
+			return "", err
+		}
+		if net.ParseIP(host) != nil {
+			ips = []string{host}
+			return "IP address", nil
+		}
+		addrs, err := net.DefaultResolver.LookupIPAddr(ctx, host)
+		if err != nil {
+			return "", err
+		}
+		for _, addr := range addrs {
+			ips = append(ips, addr.IP.String())
+		}
+		return strings.Join(ips, ", "), nil
+	})
+	return ips
+}
+
+// transportTLS returns the TLS configuration of the client's transport for a
+// handshake with host.
+func transportTLS(client *http.Client, host string) *tls.Config {
+	config := &tls.Config{MinVersion: tls.VersionTLS12}
+	if transport, ok := client.Transport.(*http.Transport); ok && transport.TLSClientConfig != nil {
+		config = transport.TLSClientConfig.Clone()
+	}
+	if config.ServerName == "" {
+		config.ServerName = host
+	}
+	return config
+}
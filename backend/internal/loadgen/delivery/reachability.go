@@ -0,0 +1,110 @@
+// backend/internal/loadgen/delivery/reachability.go
+
+package delivery
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// newDialer returns a dialer whose connections to host are checked by the guard, if
+// not nil.
+func newDialer(guard DialGuard, host string, timeout time.Duration) *net.Dialer {
+	dialer := &net.Dialer{Timeout: timeout, KeepAlive: 30 * time.Second}
+	if guard != nil {
+		dialer.Control = guard.Control(host)
+	}
+	return dialer
+}
+
+// guardedTransport returns a clone of the default transport whose connections are
+// checked by the guard, if not nil. Proxies are then bypassed, as the guard could
+// only check the address of the proxy.
+func guardedTransport(guard DialGuard) *http.Transport {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	if guard == nil {
+		return transport
+	}
+	transport.Proxy = nil
+	transport.DialContext = func(ctx context.Context, network, address string) (net.Conn, error) {
+		host, _, err := net.SplitHostPort(address)
+		if err != nil {
+			return nil, err
+		}
+		return newDialer(guard, host, 30*time.Second).DialContext(ctx, network, address)
+	}
+	return transport
+}
+
+// dialReachable opens and closes a TCP connection to address, checked by the guard.
+// No data is sent.
+func dialReachable(ctx context.Context, address string, guard DialGuard) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+	conn, err := newDialer(guard, host, 0).DialContext(ctx, "tcp", address)
+	if err != nil {
+		return err
+	}
+	return conn.Close()
+}
+
+// urlAddress returns the host:port of an HTTP or HTTPS URL.
+func urlAddress(u *url.URL) string {
+	if u.Port() != "" {
+		return u.Host
+	}
+	port := "80"
+	if u.Scheme == "https" {
+		port = "443"
+	}
+	return net.JoinHostPort(u.Hostname(), port)
+}
+
+// CheckReachable reports whether a TCP connection can be opened to the destination.
+func (s *HTTPSender) CheckReachable(ctx context.Context) error {
+	u, err := url.Parse(s.url)
+	if err != nil {
+		return err
+	}
+	return dialReachable(ctx, urlAddress(u), s.guard)
+}
+
+// CheckReachable reports whether a TCP connection can be opened to the destination.
+func (d *Deliverer) CheckReachable(ctx context.Context) error {
+	return d.sender.CheckReachable(ctx)
+}
+
+// CheckReachable reports whether a TCP connection can be opened to the destination.
+// UDP destinations are connectionless and always reported reachable.
+func (s *SocketSender) CheckReachable(ctx context.Context) error {
+	if s.network == "udp" {
+		return nil
+	}
+	return dialReachable(ctx, s.address, s.guard)
+}
+
+// CheckReachable reports whether a TCP connection can be opened to the S3 endpoint.
+func (u *S3Uploader) CheckReachable(ctx context.Context) error {
+	return dialReachable(ctx, urlAddress(u.client.endpoint), u.client.guard)
+}
+
+// CheckReachable reports whether the directory of the file still exists.
+func (f *RotatingFile) CheckReachable(context.Context) error {
+	dir := filepath.Dir(f.opts.Path)
+	info, err := os.Stat(dir)
+	if err != nil {
+		return err
+	}
+	if !info.IsDir() {
+		return fmt.Errorf("%s is not a directory", dir)
+	}
+	return nil
+}
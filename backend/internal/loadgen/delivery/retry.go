@@ -0,0 +1,333 @@
+// backend/internal/loadgen/delivery/retry.go
+
+package delivery
+
+import (
+	"context"
+	"errors"
+	"math/rand"
+	"sync"
+	"time"
+
+	"github.com/AkshayDubey29/MoniFlux/backend/internal/api/models"
+	"github.com/AkshayDubey29/MoniFlux/backend/internal/common"
+	"github.com/sirupsen/logrus"
+)
+
+// Retry policy defaults, used for unset fields of common.RetryPolicy.
+const (
+	DefaultMaxAttempts    = 3
+	DefaultInitialBackoff = 200 * time.Millisecond
+	DefaultMaxBackoff     = 10 * time.Second
+)
+
+// DefaultRetryableStatusCodes are the statuses retried when a policy lists none.
+var DefaultRetryableStatusCodes = []int{408, 429, 500, 502, 503, 504}
+
+// attemptTimeout bounds a single delivery attempt, including the resolution of
+// its credentials.
+const attemptTimeout = 15 * time.Second
+
+// maxRetryingBatches bounds the batches waiting for a retry at once. Once reached,
+// Submit blocks, which pushes back on the workers instead of buffering without limit.
+const maxRetryingBatches = 64
+
+// errDeliveryStopped is recorded for entries dead-lettered because the delivery
+// stopped before they could be sent.
+var errDeliveryStopped = errors.New("delivery stopped before the entries could be sent")
+
+// retryPolicy is a common.RetryPolicy with its defaults applied.
+type retryPolicy struct {
+	maxAttempts    int
+	initialBackoff time.Duration
+	maxBackoff     time.Duration
+	retryable      map[int]bool
+}
+
+func newRetryPolicy(cfg *common.RetryPolicy) retryPolicy {
+	p := retryPolicy{
+		maxAttempts:    DefaultMaxAttempts,
+		initialBackoff: DefaultInitialBackoff,
+		maxBackoff:     DefaultMaxBackoff,
+	}
+	codes := DefaultRetryableStatusCodes
+	if cfg != nil {
+		if cfg.MaxAttempts > 0 {
+			p.maxAttempts = cfg.MaxAttempts
+		}
+		if cfg.InitialBackoffMs > 0 {
+			p.initialBackoff = time.Duration(cfg.InitialBackoffMs) * time.Millisecond
+		}
+		if cfg.MaxBackoffMs > 0 {
+			p.maxBackoff = time.Duration(cfg.MaxBackoffMs) * time.Millisecond
+		}
+		if len(cfg.RetryableStatusCodes) > 0 {
+			codes = cfg.RetryableStatusCodes
+		}
+	}
+	if p.maxBackoff < p.initialBackoff {
+		p.maxBackoff = p.initialBackoff
+	}
+	p.retryable = make(map[int]bool, len(codes))
+	for _, code := range codes {
+		p.retryable[code] = true
+	}
+	return p
+}
+
+// isRetryable reports whether a failed attempt may succeed if sent again. Errors
+// without a response, such as timeouts and refused connections, are retried.
+func (p retryPolicy) isRetryable(err error) bool {
+	var statusErr *StatusError
+	if errors.As(err, &statusErr) {
+		return p.retryable[statusErr.StatusCode]
+	}
+	return !errors.Is(err, ErrInvalidAuth)
+}
+
+// backoff returns the delay before the given retry (1 for the first): an exponential
+// backoff with equal jitter, or the Retry-After delay of the response if longer.
+func (p retryPolicy) backoff(retry int, err error) time.Duration {
+	base := p.initialBackoff
+	for i := 1; i < retry && base < p.maxBackoff; i++ {
+		base *= 2
+	}
+	if base > p.maxBackoff {
+		base = p.maxBackoff
+	}
+	delay := base/2 + time.Duration(rand.Int63n(int64(base/2)+1))
+
+	var statusErr *StatusError
+	if errors.As(err, &statusErr) && statusErr.RetryAfter > delay {
+		delay = statusErr.RetryAfter
+	}
+	return delay
+}
+
+// Deliverer sends batches to an HTTP destination with the destination's retry
+// policy and circuit breaker. Entries that cannot be delivered are written to the
+// dead letter, if there is one, and dropped otherwise.
+type Deliverer struct {
+	sender     *HTTPSender
+	policy     retryPolicy
+	breaker    *CircuitBreaker // nil if disabled
+	deadLetter *DeadLetter     // nil to drop undeliverable entries
+	logger     *logrus.Logger
+
+	stop     chan struct{}
+	stopOnce sync.Once
+	retrying chan struct{} // Semaphore of the batches waiting for a retry
+	wg       sync.WaitGroup
+
+	mu           sync.Mutex // Protects the counts
+	requests     int64
+	failures     int64
+	entries      int64
+	rawBytes     int64
+	wireBytes    int64
+	retries      int64
+	deadLettered int64
+}
+
+// NewDeliverer returns a deliverer sending through sender with the retry policy and
+// circuit breaker of dest.
+func NewDeliverer(dest common.Destination, sender *HTTPSender, deadLetter *DeadLetter, logger *logrus.Logger) *Deliverer {
+	return &Deliverer{
+		sender:     sender,
+		policy:     newRetryPolicy(dest.Retry),
+		breaker:    NewCircuitBreaker(dest.Breaker),
+		deadLetter: deadLetter,
+		logger:     logger,
+		stop:       make(chan struct{}),
+		retrying:   make(chan struct{}, maxRetryingBatches),
+	}
+}
+
+// Submit sends a batch once and returns. If the attempt fails with a retryable error,
+// the batch is retried in the background, so a slow destination does not hold up the
+// caller. The entries are copied, so the caller may reuse the slice. The requests are
+// traced as children of the span in ctx; cancelling ctx does not stop the delivery.
+func (d *Deliverer) Submit(ctx context.Context, entries []interface{}) {
+	ctx = context.WithoutCancel(ctx)
+	batch := append([]interface{}(nil), entries...)
+	payload, err := d.sender.Encode(batch)
+	if err != nil {
+		d.logger.Errorf("Failed to encode batch for %s: %v", d.sender.URL(), err)
+		d.fail(batch, 0, err)
+		return
+	}
+
+	err = d.attempt(ctx, d.stop, "", payload)
+	if err == nil {
+		return
+	}
+	if errors.Is(err, errDeliveryStopped) {
+		d.fail(batch, 0, err)
+		return
+	}
+	if !d.policy.isRetryable(err) || d.policy.maxAttempts <= 1 {
+		d.logger.Errorf("Failed to send batch to %s: %v", d.sender.URL(), err)
+		d.fail(batch, 1, err)
+		return
+	}
+
+	select {
+	case d.retrying <- struct{}{}:
+	case <-d.stop:
+		d.fail(batch, 1, err)
+		return
+	}
+	d.wg.Add(1)
+	go func() {
+		defer d.wg.Done()
+		defer func() { <-d.retrying }()
+		_ = d.retry(ctx, d.stop, "", payload, batch, err)
+	}()
+}
+
+// Deliver sends a batch to path under the destination URL, retrying until it is
+// delivered, the policy gives up or ctx is done, and returns the last error.
+func (d *Deliverer) Deliver(ctx context.Context, path string, entries []interface{}) error {
+	payload, err := d.sender.Encode(entries)
+	if err != nil {
+		d.fail(entries, 0, err)
+		return err
+	}
+	err = d.attempt(ctx, ctx.Done(), path, payload)
+	if err == nil {
+		return nil
+	}
+	if errors.Is(err, errDeliveryStopped) {
+		d.fail(entries, 0, ctx.Err())
+		return ctx.Err()
+	}
+	return d.retry(ctx, ctx.Done(), path, payload, entries, err)
+}
+
+// retry sends the payload again after a failed first attempt until it is delivered,
+// the error is not retryable, the attempts are exhausted or done is closed.
+func (d *Deliverer) retry(ctx context.Context, done <-chan struct{}, path string, payload *Payload, entries []interface{}, err error) error {
+	attempts := 1
+	for attempts < d.policy.maxAttempts && d.policy.isRetryable(err) {
+		delay := d.policy.backoff(attempts, err)
+		d.logger.Warnf("Attempt %d to send batch to %s failed, retrying in %s: %v", attempts, d.sender.URL(), delay, err)
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-timer.C:
+		case <-done:
+			timer.Stop()
+			d.fail(entries, attempts, err)
+			return err
+		}
+
+		next := d.attempt(ctx, done, path, payload)
+		if errors.Is(next, errDeliveryStopped) {
+			break
+		}
+		d.mu.Lock()
+		d.retries++
+		d.mu.Unlock()
+		if next == nil {
+			return nil
+		}
+		attempts++
+		err = next
+	}
+	d.logger.Errorf("Giving up on batch for %s after %d attempts: %v", d.sender.URL(), attempts, err)
+	d.fail(entries, attempts, err)
+	return err
+}
+
+// attempt sends the payload once the circuit lets it through and records the outcome.
+// It returns errDeliveryStopped if done is closed while the circuit is open.
+func (d *Deliverer) attempt(ctx context.Context, done <-chan struct{}, path string, payload *Payload) error {
+	if d.breaker != nil && !d.breaker.Wait(done) {
+		return errDeliveryStopped
+	}
+
+	attemptCtx, cancel := context.WithTimeout(ctx, attemptTimeout)
+	err := d.sender.Send(attemptCtx, path, payload)
+	cancel()
+
+	if d.breaker != nil {
+		// Only failures worth retrying say the destination is down.
+		if err != nil && d.policy.isRetryable(err) {
+			d.breaker.Failure()
+		} else {
+			d.breaker.Success()
+		}
+	}
+	if err == nil {
+		d.mu.Lock()
+		d.requests++
+		d.entries += int64(payload.Entries)
+		d.rawBytes += int64(payload.RawBytes)
+		d.wireBytes += int64(len(payload.Body))
+		d.mu.Unlock()
+	}
+	return err
+}
+
+// fail counts a batch that could not be delivered and writes it to the dead letter.
+func (d *Deliverer) fail(entries []interface{}, attempts int, cause error) {
+	d.mu.Lock()
+	d.failures++
+	d.mu.Unlock()
+	if d.deadLetter == nil {
+		return
+	}
+	if err := d.deadLetter.Write(d.sender.URL(), entries, attempts, cause); err != nil {
+		d.logger.Errorf("Failed to write %d entries to dead letter %s: %v", len(entries), d.deadLetter.Path(), err)
+		return
+	}
+	d.mu.Lock()
+	d.deadLettered += int64(len(entries))
+	d.mu.Unlock()
+}
+
+// Stop stops waiting on backoffs and on the circuit breaker: batches waiting for a
+// retry are dead-lettered, and so are batches submitted while the circuit is open.
+func (d *Deliverer) Stop() {
+	d.stopOnce.Do(func() { close(d.stop) })
+}
+
+// Close stops the deliverer, waits for the batches being retried and closes the
+// dead letter. It must be called after the last Submit.
+func (d *Deliverer) Close() error {
+	d.Stop()
+	d.wg.Wait()
+	if d.deadLetter != nil {
+		return d.deadLetter.Close()
+	}
+	return nil
+}
+
+// Counts returns the number of delivered and failed requests.
+func (d *Deliverer) Counts() (successes int64, failures int64) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.requests, d.failures
+}
+
+// Stats returns the statistics of the deliveries so far.
+func (d *Deliverer) Stats() *models.DeliveryStats {
+	d.mu.Lock()
+	stats := &models.DeliveryStats{
+		Requests:     d.requests,
+		Failures:     d.failures,
+		Entries:      d.entries,
+		RawBytes:     d.rawBytes,
+		WireBytes:    d.wireBytes,
+		Retries:      d.retries,
+		DeadLettered: d.deadLettered,
+	}
+	d.mu.Unlock()
+	if stats.WireBytes > 0 {
+		stats.CompressionRatio = float64(stats.RawBytes) / float64(stats.WireBytes)
+	}
+	if d.breaker != nil {
+		stats.CircuitOpens = d.breaker.Opens()
+	}
+	return stats
+}
@@ -0,0 +1,521 @@
+// backend/internal/loadgen/delivery/rotating_file.go
+
+package delivery
+
+import (
+	"bufio"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/AkshayDubey29/MoniFlux/backend/internal/api/models"
+	"github.com/AkshayDubey29/MoniFlux/backend/internal/common"
+	"github.com/klauspost/compress/zstd"
+)
+
+// ErrDiskBudgetExceeded is returned by writes once the files of a destination have
+// reached its disk budget. Later writes are refused too.
+var ErrDiskBudgetExceeded = errors.New("disk budget exceeded")
+
+const (
+	fileBufferSize    = 64 << 10
+	fileFlushInterval = time.Second
+)
+
+// RotatingFileOptions configures a RotatingFile. Zero values disable the option.
+type RotatingFileOptions struct {
+	Path        string        // The current file; rotated files are named <Path>.<timestamp>-<seq>
+	MaxSize     int64         // Rotate when the current file reaches this size in bytes
+	Interval    time.Duration // Rotate when the current file is this old
+	MaxFiles    int           // Files kept, including the current one
+	Compression string        // none, gzip or zstd, applied to rotated files or to the pages of Parquet files
+	DiskBudget  int64         // Bytes the current and retained files may use
+	Format      string        // ndjson, csv or parquet; defaults to ndjson
+}
+
+// FileOptions returns the rotation options of a file destination. budgetMB applies
+// when the destination sets no disk budget.
+func FileOptions(dest common.Destination, budgetMB int) RotatingFileOptions {
+	opts := RotatingFileOptions{
+		Path:        dest.FilePath,
+		MaxSize:     int64(dest.FileMaxSizeMB) << 20,
+		Interval:    time.Duration(dest.FileFreq) * time.Minute,
+		MaxFiles:    dest.FileCount,
+		Compression: dest.FileCompression,
+		DiskBudget:  int64(budgetMB) << 20,
+		Format:      dest.FileFormat,
+	}
+	if dest.DiskBudgetMB > 0 {
+		opts.DiskBudget = int64(dest.DiskBudgetMB) << 20
+	}
+	return opts
+}
+
+// segment is a rotated file.
+type segment struct {
+	seq  int
+	path string
+	size int64
+}
+
+// RotatingFile is a buffered file writer, safe for concurrent use, that rotates the
+// file by size or age, keeps MaxFiles files and stops writing at the disk budget.
+// Rotated files are compressed in the background.
+//
+// CSV files start with a header line. Parquet files buffer up to
+// parquetRowGroupRows rows per row group and are completed with their footer when
+// they are rotated or closed.
+type RotatingFile struct {
+	opts RotatingFileOptions
+
+	mu        sync.Mutex
+	file      *os.File
+	buf       *bufio.Writer
+	size      int64 // Of the current file, including buffered bytes
+	header    int64 // Size of the CSV header or Parquet magic of the current file
+	parquet   *ParquetWriter
+	rows      [][]interface{} // Rows of the next Parquet row group
+	rowSize   int64           // Estimated encoded size of rows
+	openedAt  time.Time
+	seq       int
+	segments  []segment // Rotated files, oldest first
+	pending   int64     // Size of rotated files being compressed
+	closed    bool
+	exhausted bool
+
+	written   int64
+	entries   int64
+	dropped   int64
+	rotations int64
+	deleted   int64
+
+	wg   sync.WaitGroup
+	quit chan struct{}
+}
+
+// NewRotatingFile creates the directory of the file, opens the file for appending
+// and starts flushing the buffer every second.
+func NewRotatingFile(opts RotatingFileOptions) (*RotatingFile, error) {
+	if opts.Path == "" {
+		return nil, errors.New("file path cannot be empty")
+	}
+	switch opts.Compression {
+	case "", CompressionNone, CompressionGzip, CompressionZstd:
+	default:
+		return nil, fmt.Errorf("unsupported file compression %q", opts.Compression)
+	}
+	switch opts.Format {
+	case "", FormatNDJSON, FormatCSV, FormatParquet:
+	default:
+		return nil, fmt.Errorf("unsupported file format %q", opts.Format)
+	}
+	if err := os.MkdirAll(filepath.Dir(opts.Path), 0o755); err != nil {
+		return nil, err
+	}
+
+	f := &RotatingFile{opts: opts, quit: make(chan struct{})}
+	if opts.Format == FormatParquet {
+		// A Parquet file cannot be appended to, so an existing one is kept as rotated.
+		if info, err := os.Stat(opts.Path); err == nil && info.Size() > 0 {
+			f.seq++
+			seg := segment{seq: f.seq, path: f.segmentPath(), size: info.Size()}
+			if err := os.Rename(opts.Path, seg.path); err != nil {
+				return nil, err
+			}
+			f.addSegmentLocked(seg)
+		}
+	}
+	if err := f.open(); err != nil {
+		return nil, err
+	}
+	f.wg.Add(1)
+	go f.flushLoop()
+	return f, nil
+}
+
+// open opens the current file. The caller must hold f.mu or own f.
+func (f *RotatingFile) open() error {
+	file, err := os.OpenFile(f.opts.Path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o644)
+	if err != nil {
+		return err
+	}
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return err
+	}
+	f.file = file
+	f.buf = bufio.NewWriterSize(file, fileBufferSize)
+	f.size = info.Size()
+	f.openedAt = time.Now()
+
+	switch {
+	case f.opts.Format == FormatCSV && f.size == 0:
+		_, err = fileBuffer{f}.Write(csvHeader())
+	case f.opts.Format == FormatParquet:
+		f.parquet, err = NewParquetWriter(fileBuffer{f}, fileColumns, f.opts.Compression)
+	}
+	if err != nil {
+		file.Close()
+		return err
+	}
+	f.header = f.size - info.Size()
+	return nil
+}
+
+// fileBuffer writes to the buffer of the current file and counts the bytes. The
+// caller must hold f.mu.
+type fileBuffer struct {
+	f *RotatingFile
+}
+
+func (b fileBuffer) Write(p []byte) (int, error) {
+	n, err := b.f.buf.Write(p)
+	b.f.size += int64(n)
+	b.f.written += int64(n)
+	return n, err
+}
+
+// segmentPath returns the path of the rotated file with sequence number f.seq.
+func (f *RotatingFile) segmentPath() string {
+	return f.opts.Path + "." + time.Now().UTC().Format("20060102T150405") + "-" + strconv.Itoa(f.seq)
+}
+
+// Path returns the path of the current file.
+func (f *RotatingFile) Path() string {
+	return f.opts.Path
+}
+
+// Write encodes an entry in the format of the file and writes it. Like WriteEntry,
+// it returns ErrDiskBudgetExceeded once the disk budget is reached.
+func (f *RotatingFile) Write(entry interface{}) error {
+	var line []byte
+	var row []interface{}
+	var err error
+	if f.opts.Format == FormatParquet {
+		row, err = fileRow(entry)
+	} else {
+		line, err = encodeLine(f.opts.Format, entry)
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err == nil {
+		if row != nil {
+			err = f.writeRowLocked(row)
+		} else {
+			err = f.writeLocked(line)
+		}
+	}
+	if err != nil {
+		f.dropped++
+		return err
+	}
+	f.entries++
+	return nil
+}
+
+// WriteEntry writes an encoded entry as a line of an ndjson or csv file. It returns
+// ErrDiskBudgetExceeded once the disk budget is reached and counts the entries that
+// could not be written.
+func (f *RotatingFile) WriteEntry(line []byte) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	err := errors.New("lines cannot be written to a Parquet file")
+	if f.parquet == nil {
+		err = f.writeLocked(line)
+	}
+	if err != nil {
+		f.dropped++
+		return err
+	}
+	f.entries++
+	return nil
+}
+
+func (f *RotatingFile) writeLocked(line []byte) error {
+	if f.closed {
+		return os.ErrClosed
+	}
+	if f.exhausted {
+		return ErrDiskBudgetExceeded
+	}
+	if f.shouldRotate(int64(len(line))) {
+		if err := f.rotateLocked(); err != nil {
+			return err
+		}
+	}
+	if f.opts.DiskBudget > 0 && f.diskUsageLocked()+int64(len(line)) > f.opts.DiskBudget {
+		f.exhausted = true
+		return ErrDiskBudgetExceeded
+	}
+	_, err := fileBuffer{f}.Write(line)
+	return err
+}
+
+// writeRowLocked adds a row to the next row group of the Parquet file and writes the
+// row group once it is full.
+func (f *RotatingFile) writeRowLocked(row []interface{}) error {
+	if f.closed {
+		return os.ErrClosed
+	}
+	if f.exhausted {
+		return ErrDiskBudgetExceeded
+	}
+	size := parquetRowSize(row)
+	if f.shouldRotate(size) {
+		if err := f.rotateLocked(); err != nil {
+			return err
+		}
+	}
+	if f.opts.DiskBudget > 0 && f.diskUsageLocked()+size > f.opts.DiskBudget {
+		f.exhausted = true
+		return ErrDiskBudgetExceeded
+	}
+	f.rows = append(f.rows, row)
+	f.rowSize += size
+	if len(f.rows) >= parquetRowGroupRows {
+		return f.writeRowGroupLocked()
+	}
+	return nil
+}
+
+// writeRowGroupLocked writes the buffered rows as a row group of the Parquet file.
+func (f *RotatingFile) writeRowGroupLocked() error {
+	if len(f.rows) == 0 {
+		return nil
+	}
+	err := f.parquet.WriteRows(f.rows)
+	f.rows, f.rowSize = f.rows[:0], 0
+	return err
+}
+
+// finishLocked completes the current file before it is rotated or closed: the
+// buffered rows and the footer of a Parquet file are written.
+func (f *RotatingFile) finishLocked() error {
+	if f.parquet == nil {
+		return nil
+	}
+	err := f.writeRowGroupLocked()
+	if closeErr := f.parquet.Close(); err == nil {
+		err = closeErr
+	}
+	f.parquet = nil
+	return err
+}
+
+// shouldRotate reports whether the current file must be rotated before writing n bytes.
+// An empty file, which holds at most its header, is never rotated.
+func (f *RotatingFile) shouldRotate(n int64) bool {
+	size := f.size + f.rowSize
+	if size <= f.header {
+		return false
+	}
+	if f.opts.MaxSize > 0 && size+n > f.opts.MaxSize {
+		return true
+	}
+	return f.opts.Interval > 0 && time.Since(f.openedAt) >= f.opts.Interval
+}
+
+// rotateLocked renames the current file to the next segment and opens a new one.
+// The caller must hold f.mu.
+func (f *RotatingFile) rotateLocked() error {
+	if err := f.finishLocked(); err != nil {
+		return err
+	}
+	if err := f.buf.Flush(); err != nil {
+		return err
+	}
+	if err := f.file.Close(); err != nil {
+		return err
+	}
+	f.seq++
+	seg := segment{
+		seq:  f.seq,
+		path: f.segmentPath(),
+		size: f.size,
+	}
+	if err := os.Rename(f.opts.Path, seg.path); err != nil {
+		// Keep writing to the current file.
+		if openErr := f.open(); openErr != nil {
+			return openErr
+		}
+		return err
+	}
+	f.rotations++
+	if err := f.open(); err != nil {
+		return err
+	}
+
+	if f.opts.Compression == "" || f.opts.Compression == CompressionNone || f.opts.Format == FormatParquet {
+		f.addSegmentLocked(seg)
+		return nil
+	}
+	f.pending += seg.size
+	f.wg.Add(1)
+	go f.compress(seg)
+	return nil
+}
+
+// compress compresses a rotated file and replaces it with the compressed one. The
+// uncompressed file is kept if compression fails.
+func (f *RotatingFile) compress(seg segment) {
+	defer f.wg.Done()
+	compressed, size, err := compressFile(seg.path, f.opts.Compression)
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.pending -= seg.size
+	if err == nil {
+		seg.path, seg.size = compressed, size
+	}
+	f.addSegmentLocked(seg)
+}
+
+// addSegmentLocked records a rotated file and removes the oldest files beyond
+// MaxFiles. The caller must hold f.mu.
+func (f *RotatingFile) addSegmentLocked(seg segment) {
+	f.segments = append(f.segments, seg)
+	sort.Slice(f.segments, func(i, j int) bool { return f.segments[i].seq < f.segments[j].seq })
+	if f.opts.MaxFiles <= 0 {
+		return
+	}
+	for len(f.segments) > 0 && len(f.segments)+1 > f.opts.MaxFiles {
+		if err := os.Remove(f.segments[0].path); err != nil && !os.IsNotExist(err) {
+			return
+		}
+		f.segments = f.segments[1:]
+		f.deleted++
+	}
+}
+
+func (f *RotatingFile) diskUsageLocked() int64 {
+	usage := f.size + f.rowSize + f.pending
+	for _, seg := range f.segments {
+		usage += seg.size
+	}
+	return usage
+}
+
+// flushLoop flushes the buffer and rotates idle files that reached their age.
+func (f *RotatingFile) flushLoop() {
+	defer f.wg.Done()
+	ticker := time.NewTicker(fileFlushInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			f.mu.Lock()
+			if !f.closed {
+				_ = f.buf.Flush()
+				if f.opts.Interval > 0 && f.shouldRotate(0) {
+					_ = f.rotateLocked()
+				}
+			}
+			f.mu.Unlock()
+		case <-f.quit:
+			return
+		}
+	}
+}
+
+// Stats returns the statistics of the files written so far.
+func (f *RotatingFile) Stats() *models.FileStats {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return &models.FileStats{
+		BytesWritten:    f.written,
+		DiskUsageBytes:  f.diskUsageLocked(),
+		Files:           len(f.segments) + 1,
+		Rotations:       f.rotations,
+		DeletedFiles:    f.deleted,
+		DroppedEntries:  f.dropped,
+		BudgetExhausted: f.exhausted,
+	}
+}
+
+// Entries returns the number of entries written and dropped.
+func (f *RotatingFile) Entries() (written int64, dropped int64) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.entries, f.dropped
+}
+
+// Close completes, flushes and closes the current file and waits for the rotated files being
+// compressed.
+func (f *RotatingFile) Close() error {
+	f.mu.Lock()
+	if f.closed {
+		f.mu.Unlock()
+		return nil
+	}
+	f.closed = true
+	err := f.finishLocked()
+	if flushErr := f.buf.Flush(); err == nil {
+		err = flushErr
+	}
+	if closeErr := f.file.Close(); err == nil {
+		err = closeErr
+	}
+	f.mu.Unlock()
+
+	close(f.quit)
+	f.wg.Wait()
+	return err
+}
+
+// compressFile writes a compressed copy of path next to it, removes path and
+// returns the new path and its size.
+func compressFile(path, compression string) (string, int64, error) {
+	src, err := os.Open(path)
+	if err != nil {
+		return "", 0, err
+	}
+	defer src.Close()
+
+	ext := ".gz"
+	if compression == CompressionZstd {
+		ext = ".zst"
+	}
+	target := path + ext
+	dst, err := os.OpenFile(target, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0o644)
+	if err != nil {
+		return "", 0, err
+	}
+
+	var writer io.WriteCloser
+	if compression == CompressionZstd {
+		writer, err = zstd.NewWriter(dst)
+	} else {
+		writer = gzip.NewWriter(dst)
+	}
+	if err == nil {
+		if _, err = io.Copy(writer, src); err == nil {
+			err = writer.Close()
+		}
+	}
+	if closeErr := dst.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(target)
+		return "", 0, err
+	}
+
+	info, err := os.Stat(target)
+	if err != nil {
+		return "", 0, err
+	}
+	src.Close()
+	if err := os.Remove(path); err != nil {
+		return "", 0, err
+	}
+	return target, info.Size(), nil
+}
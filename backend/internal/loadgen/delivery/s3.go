@@ -0,0 +1,375 @@
+// backend/internal/loadgen/delivery/s3.go
+
+package delivery
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/AkshayDubey29/MoniFlux/backend/internal/common"
+	"github.com/AkshayDubey29/MoniFlux/backend/pkg/tracing"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+)
+
+// DefaultS3Region is used for s3 destinations without a region.
+const DefaultS3Region = "us-east-1"
+
+// s3RequestTimeout bounds a single S3 request, including the upload of a part.
+const s3RequestTimeout = 2 * time.Minute
+
+// S3Error is the error document returned by S3 for failed requests. It wraps the
+// *StatusError of the response.
+type S3Error struct {
+	Status  *StatusError
+	Code    string
+	Message string
+}
+
+func (e *S3Error) Error() string {
+	if e.Code == "" {
+		return e.Status.Error()
+	}
+	return fmt.Sprintf("%s: %s: %s", e.Status.Error(), e.Code, e.Message)
+}
+
+func (e *S3Error) Unwrap() error {
+	return e.Status
+}
+
+// s3Client sends the multipart upload requests of an s3 destination, signed with
+// AWS Signature Version 4.
+type s3Client struct {
+	client       *http.Client
+	guard        DialGuard
+	endpoint     *url.URL
+	pathStyle    bool
+	bucket       string
+	region       string
+	accessKeyID  string
+	secretKeyRef string
+	sessionRef   string
+	secrets      SecretResolver
+	now          func() time.Time
+}
+
+// newS3Client builds the client of an s3 destination. Custom endpoints are addressed
+// path-style (<endpoint>/<bucket>/<key>) and AWS virtual-hosted-style. Connections
+// are checked by the guard, if not nil, and redirects are not followed.
+func newS3Client(ctx context.Context, dest common.Destination, secrets SecretResolver, guard DialGuard) (*s3Client, error) {
+	opts := dest.S3
+	if opts == nil || opts.Bucket == "" {
+		return nil, errors.New("s3 destinations require a bucket")
+	}
+	if opts.AccessKeyID == "" || opts.SecretAccessKeySecretRef == "" {
+		return nil, errors.New("s3 destinations require an access key ID and a secret access key reference")
+	}
+	if secrets == nil {
+		return nil, errors.New("destination credentials require a secret resolver")
+	}
+	if auth := dest.Auth; dest.SecretRef != "" || auth != nil && (auth.Basic != nil || auth.OAuth2 != nil || len(auth.Headers) > 0 || len(auth.SecretHeaders) > 0 || auth.TenantID != "") {
+		return nil, fmt.Errorf("%w: s3 destinations only support TLS authentication", ErrInvalidAuth)
+	}
+
+	c := &s3Client{
+		bucket:       opts.Bucket,
+		region:       opts.Region,
+		accessKeyID:  opts.AccessKeyID,
+		secretKeyRef: opts.SecretAccessKeySecretRef,
+		sessionRef:   opts.SessionTokenSecretRef,
+		secrets:      secrets,
+		guard:        guard,
+		now:          time.Now,
+	}
+	if c.region == "" {
+		c.region = DefaultS3Region
+	}
+	if dest.Endpoint != "" {
+		target, err := TargetURL(dest)
+		if err != nil {
+			return nil, err
+		}
+		if c.endpoint, err = url.Parse(strings.TrimSuffix(target, "/")); err != nil {
+			return nil, err
+		}
+		c.pathStyle = true
+	} else {
+		c.endpoint = &url.URL{Scheme: "https", Host: c.bucket + ".s3." + c.region + ".amazonaws.com"}
+	}
+
+	transport := guardedTransport(guard)
+	if dest.Auth != nil && dest.Auth.TLS != nil {
+		tlsConfig, err := buildTLSConfig(ctx, dest.Auth.TLS, secrets)
+		if err != nil {
+			return nil, err
+		}
+		transport.TLSClientConfig = tlsConfig
+	}
+	c.client = &http.Client{Timeout: s3RequestTimeout, Transport: transport, CheckRedirect: refuseRedirect}
+	return c, nil
+}
+
+// objectURL returns the URL of an object, without a query.
+func (c *s3Client) objectURL(key string) string {
+	path := "/" + s3Escape(key, false)
+	if c.pathStyle {
+		path = c.endpoint.EscapedPath() + "/" + s3Escape(c.bucket, false) + path
+	}
+	return c.endpoint.Scheme + "://" + c.endpoint.Host + path
+}
+
+// do sends a signed request for an object and returns the response body. Non-2xx
+// responses are returned as *S3Error.
+func (c *s3Client) do(ctx context.Context, method, key string, query url.Values, header http.Header, body []byte) (_ http.Header, _ []byte, err error) {
+	target := c.objectURL(key)
+	if len(query) > 0 {
+		target += "?" + s3CanonicalQuery(query)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, target, bytes.NewReader(body))
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to create S3 request: %w", err)
+	}
+	for name, values := range header {
+		req.Header[name] = values
+	}
+	span := startHTTPSpan(req)
+	defer func() { tracing.End(span, err) }()
+	if err := c.sign(ctx, req, body); err != nil {
+		return nil, nil, err
+	}
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer resp.Body.Close()
+	span.SetAttributes(semconv.HTTPResponseStatusCode(resp.StatusCode))
+	data, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return nil, nil, err
+	}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return nil, nil, newS3Error(resp, data)
+	}
+	return resp.Header, data, nil
+}
+
+func newS3Error(resp *http.Response, data []byte) *S3Error {
+	s3Err := &S3Error{Status: &StatusError{
+		StatusCode: resp.StatusCode,
+		RetryAfter: parseRetryAfter(resp.Header.Get("Retry-After"), time.Now()),
+	}}
+	var doc struct {
+		Code    string `xml:"Code"`
+		Message string `xml:"Message"`
+	}
+	if xml.Unmarshal(data, &doc) == nil {
+		s3Err.Code, s3Err.Message = doc.Code, doc.Message
+	}
+	return s3Err
+}
+
+// createMultipartUpload starts a multipart upload and returns its ID.
+func (c *s3Client) createMultipartUpload(ctx context.Context, key, contentType string) (string, error) {
+	header := http.Header{"Content-Type": {contentType}}
+	_, data, err := c.do(ctx, http.MethodPost, key, url.Values{"uploads": {""}}, header, nil)
+	if err != nil {
+		return "", err
+	}
+	var result struct {
+		UploadID string `xml:"UploadId"`
+	}
+	if err := xml.Unmarshal(data, &result); err != nil || result.UploadID == "" {
+		return "", fmt.Errorf("invalid CreateMultipartUpload response: %q", truncate(data, 256))
+	}
+	return result.UploadID, nil
+}
+
+// uploadPart uploads a part and returns its ETag.
+func (c *s3Client) uploadPart(ctx context.Context, key, uploadID string, number int, body []byte) (string, error) {
+	query := url.Values{"partNumber": {strconv.Itoa(number)}, "uploadId": {uploadID}}
+	header, _, err := c.do(ctx, http.MethodPut, key, query, nil, body)
+	if err != nil {
+		return "", err
+	}
+	etag := header.Get("ETag")
+	if etag == "" {
+		return "", fmt.Errorf("no ETag returned for part %d", number)
+	}
+	return etag, nil
+}
+
+// completedPart is a part of a CompleteMultipartUpload request.
+type completedPart struct {
+	PartNumber int    `xml:"PartNumber"`
+	ETag       string `xml:"ETag"`
+}
+
+// completeMultipartUpload assembles the object from its parts. S3 can report an error
+// in the body of a 200 response, which is returned as a 500 *S3Error so it is retried.
+func (c *s3Client) completeMultipartUpload(ctx context.Context, key, uploadID string, parts []completedPart) error {
+	body, err := xml.Marshal(struct {
+		XMLName xml.Name        `xml:"CompleteMultipartUpload"`
+		Parts   []completedPart `xml:"Part"`
+	}{Parts: parts})
+	if err != nil {
+		return err
+	}
+	header := http.Header{"Content-Type": {"application/xml"}}
+	_, data, err := c.do(ctx, http.MethodPost, key, url.Values{"uploadId": {uploadID}}, header, body)
+	if err != nil {
+		return err
+	}
+	var doc struct {
+		XMLName xml.Name
+		Code    string `xml:"Code"`
+		Message string `xml:"Message"`
+	}
+	if xml.Unmarshal(data, &doc) == nil && doc.XMLName.Local == "Error" {
+		return &S3Error{Status: &StatusError{StatusCode: http.StatusInternalServerError}, Code: doc.Code, Message: doc.Message}
+	}
+	return nil
+}
+
+// abortMultipartUpload discards an upload and the parts uploaded so far.
+func (c *s3Client) abortMultipartUpload(ctx context.Context, key, uploadID string) error {
+	_, _, err := c.do(ctx, http.MethodDelete, key, url.Values{"uploadId": {uploadID}}, nil, nil)
+	return err
+}
+
+// putObject uploads an object in a single request.
+func (c *s3Client) putObject(ctx context.Context, key, contentType string, body []byte) error {
+	_, _, err := c.do(ctx, http.MethodPut, key, nil, http.Header{"Content-Type": {contentType}}, body)
+	return err
+}
+
+// deleteObject deletes an object.
+func (c *s3Client) deleteObject(ctx context.Context, key string) error {
+	_, _, err := c.do(ctx, http.MethodDelete, key, nil, nil, nil)
+	return err
+}
+
+// sign adds the AWS Signature Version 4 headers to the request. The Host, Content-Type,
+// Content-MD5 and X-Amz-* headers are signed.
+func (c *s3Client) sign(ctx context.Context, req *http.Request, body []byte) error {
+	secretKey, err := c.secrets.Resolve(ctx, c.secretKeyRef)
+	if err != nil {
+		return fmt.Errorf("failed to resolve the S3 secret access key: %w", err)
+	}
+	if c.sessionRef != "" {
+		token, err := c.secrets.Resolve(ctx, c.sessionRef)
+		if err != nil {
+			return fmt.Errorf("failed to resolve the S3 session token: %w", err)
+		}
+		req.Header.Set("X-Amz-Security-Token", token)
+	}
+	signV4(req, body, c.accessKeyID, secretKey, c.region, "s3", c.now().UTC())
+	return nil
+}
+
+// signV4 signs the request with AWS Signature Version 4.
+func signV4(req *http.Request, body []byte, accessKeyID, secretKey, region, service string, now time.Time) {
+	amzDate := now.Format("20060102T150405Z")
+	date := now.Format("20060102")
+	payloadHash := sha256Hex(body)
+	req.Header.Set("X-Amz-Date", amzDate)
+	req.Header.Set("X-Amz-Content-Sha256", payloadHash)
+
+	headers := map[string]string{"host": req.URL.Host}
+	for name, values := range req.Header {
+		lower := strings.ToLower(name)
+		if strings.HasPrefix(lower, "x-amz-") || lower == "content-type" || lower == "content-md5" || lower == "range" {
+			headers[lower] = strings.TrimSpace(strings.Join(values, ","))
+		}
+	}
+	names := make([]string, 0, len(headers))
+	for name := range headers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	var canonicalHeaders strings.Builder
+	for _, name := range names {
+		canonicalHeaders.WriteString(name + ":" + headers[name] + "\n")
+	}
+	signedHeaders := strings.Join(names, ";")
+
+	canonicalRequest := strings.Join([]string{
+		req.Method,
+		req.URL.EscapedPath(),
+		s3CanonicalQuery(req.URL.Query()),
+		canonicalHeaders.String(),
+		signedHeaders,
+		payloadHash,
+	}, "\n")
+	scope := date + "/" + region + "/" + service + "/aws4_request"
+	stringToSign := "AWS4-HMAC-SHA256\n" + amzDate + "\n" + scope + "\n" + sha256Hex([]byte(canonicalRequest))
+
+	key := hmacSHA256([]byte("AWS4"+secretKey), date)
+	key = hmacSHA256(key, region)
+	key = hmacSHA256(key, service)
+	key = hmacSHA256(key, "aws4_request")
+	signature := hex.EncodeToString(hmacSHA256(key, stringToSign))
+
+	req.Header.Set("Authorization", "AWS4-HMAC-SHA256 Credential="+accessKeyID+"/"+scope+
+		", SignedHeaders="+signedHeaders+", Signature="+signature)
+}
+
+// s3CanonicalQuery returns the query sorted by name, with names and values escaped.
+func s3CanonicalQuery(query url.Values) string {
+	pairs := make([]string, 0, len(query))
+	for name, values := range query {
+		for _, value := range values {
+			pairs = append(pairs, s3Escape(name, true)+"="+s3Escape(value, true))
+		}
+	}
+	sort.Strings(pairs)
+	return strings.Join(pairs, "&")
+}
+
+// s3Escape percent-encodes every byte except the unreserved characters of RFC 3986,
+// and slashes unless escapeSlash is set.
+func s3Escape(s string, escapeSlash bool) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case 'A' <= c && c <= 'Z', 'a' <= c && c <= 'z', '0' <= c && c <= '9',
+			c == '-', c == '.', c == '_', c == '~', c == '/' && !escapeSlash:
+			b.WriteByte(c)
+		default:
+			fmt.Fprintf(&b, "%%%02X", c)
+		}
+	}
+	return b.String()
+}
+
+func sha256Hex(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
+
+// truncate returns at most n bytes of data, for error messages.
+func truncate(data []byte, n int) []byte {
+	if len(data) > n {
+		return data[:n]
+	}
+	return data
+}
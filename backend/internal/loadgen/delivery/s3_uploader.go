@@ -0,0 +1,647 @@
+// backend/internal/loadgen/delivery/s3_uploader.go
+
+package delivery
+
+import (
+	"bufio"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/AkshayDubey29/MoniFlux/backend/internal/api/models"
+	"github.com/AkshayDubey29/MoniFlux/backend/internal/common"
+	"github.com/klauspost/compress/zstd"
+	"github.com/sirupsen/logrus"
+)
+
+// S3 destination defaults, used for unset fields of common.S3Options.
+const (
+	DefaultS3KeyTemplate     = "{testID}/{signal}/{date}/{seq}"
+	DefaultS3SegmentSize     = 64 << 20
+	DefaultS3SegmentInterval = time.Minute
+	DefaultS3PartSize        = 8 << 20
+)
+
+const (
+	// parquetRowGroupRows is the number of entries buffered before they are written
+	// to a Parquet segment as a row group.
+	parquetRowGroupRows = 10000
+
+	// maxQueuedSegments bounds the closed segments waiting for their upload. Once
+	// reached, Write blocks, which pushes back on the workers.
+	maxQueuedSegments = 8
+
+	// s3UploadWorkers is the number of segments uploaded at once.
+	s3UploadWorkers = 2
+
+	segmentCheckInterval = time.Second
+)
+
+// keyPlaceholder matches the placeholders of key templates.
+var keyPlaceholder = regexp.MustCompile(`\{[^{}]*\}`)
+
+// objectSegment is a segment file being written or waiting for its upload.
+type objectSegment struct {
+	testID   string
+	signal   string
+	seq      int
+	path     string
+	openedAt time.Time
+
+	file       *os.File
+	compressor io.WriteCloser // NDJSON compression; nil if none
+	buf        *bufio.Writer
+	parquet    *ParquetWriter
+	rows       [][]interface{} // Parquet rows not yet written as a row group
+
+	entries  int64
+	rawBytes int64 // Encoded size before compression
+}
+
+// S3Uploader writes the entries of an s3 destination to segment files, one per test
+// and signal, and uploads each segment as an object with a multipart upload once it
+// reaches its size or age. Failed requests are retried with the destination's retry
+// policy; segments that cannot be uploaded are left in the segment directory.
+type S3Uploader struct {
+	client      *s3Client
+	policy      retryPolicy
+	logger      *logrus.Logger
+	dir         string
+	format      string
+	compression string
+	keyTemplate string
+	segmentSize int64
+	interval    time.Duration
+	partSize    int
+	uploadQueue chan *objectSegment
+	sending     sync.WaitGroup // Closed segments being queued
+	uploadersWG sync.WaitGroup
+	checkerWG   sync.WaitGroup
+	quit        chan struct{}
+	closeOnce   sync.Once
+
+	mu       sync.Mutex // Protects the fields below
+	segments map[string]*objectSegment
+	seqs     map[string]int
+	pending  int
+	closed   bool
+
+	objects   int64
+	failures  int64
+	entries   int64
+	rawBytes  int64
+	wireBytes int64
+	parts     int64
+	retries   int64
+	kept      int64
+	lastKey   string
+}
+
+// NewS3Uploader builds the uploader of an s3 destination. Segments are written to a
+// new directory in segmentDir, or in the system temporary directory if it is empty.
+// Connections are checked by the guard, if not nil.
+func NewS3Uploader(ctx context.Context, dest common.Destination, secrets SecretResolver, guard DialGuard, segmentDir string, logger *logrus.Logger) (*S3Uploader, error) {
+	if err := CheckS3Options(dest.S3); err != nil {
+		return nil, err
+	}
+	client, err := newS3Client(ctx, dest, secrets, guard)
+	if err != nil {
+		return nil, err
+	}
+	opts := dest.S3
+
+	if segmentDir == "" {
+		segmentDir = filepath.Join(os.TempDir(), "moniflux-segments")
+	}
+	if err := os.MkdirAll(segmentDir, 0o755); err != nil {
+		return nil, err
+	}
+	dir, err := os.MkdirTemp(segmentDir, "s3-")
+	if err != nil {
+		return nil, err
+	}
+
+	u := &S3Uploader{
+		client:      client,
+		policy:      newRetryPolicy(dest.Retry),
+		logger:      logger,
+		dir:         dir,
+		format:      opts.Format,
+		compression: opts.Compression,
+		keyTemplate: opts.KeyTemplate,
+		segmentSize: DefaultS3SegmentSize,
+		interval:    DefaultS3SegmentInterval,
+		partSize:    DefaultS3PartSize,
+		uploadQueue: make(chan *objectSegment, maxQueuedSegments),
+		quit:        make(chan struct{}),
+		segments:    make(map[string]*objectSegment),
+		seqs:        make(map[string]int),
+	}
+	if u.format == "" {
+		u.format = FormatNDJSON
+	}
+	if u.compression == CompressionNone {
+		u.compression = ""
+	}
+	if u.keyTemplate == "" {
+		u.keyTemplate = DefaultS3KeyTemplate
+	}
+	if opts.SegmentSizeMB > 0 {
+		u.segmentSize = int64(opts.SegmentSizeMB) << 20
+	}
+	if opts.SegmentIntervalSec > 0 {
+		u.interval = time.Duration(opts.SegmentIntervalSec) * time.Second
+	}
+	if opts.PartSizeMB > 0 {
+		u.partSize = opts.PartSizeMB << 20
+	}
+
+	for i := 0; i < s3UploadWorkers; i++ {
+		u.uploadersWG.Add(1)
+		go u.uploadLoop()
+	}
+	u.checkerWG.Add(1)
+	go u.checkLoop()
+	return u, nil
+}
+
+// CheckS3Options returns an error for missing or unsupported s3 destination options.
+// Key templates must contain {seq} so that segments get distinct keys.
+func CheckS3Options(opts *common.S3Options) error {
+	if opts == nil || opts.Bucket == "" {
+		return errors.New("s3 destinations require a bucket")
+	}
+	switch opts.Format {
+	case "", FormatNDJSON, FormatParquet:
+	default:
+		return fmt.Errorf("unsupported segment format %q", opts.Format)
+	}
+	switch opts.Compression {
+	case "", CompressionNone, CompressionGzip, CompressionZstd:
+	default:
+		return fmt.Errorf("unsupported segment compression %q", opts.Compression)
+	}
+	if opts.PartSizeMB != 0 && opts.PartSizeMB < 5 {
+		return errors.New("S3 multipart upload parts must be at least 5 MB")
+	}
+	if opts.KeyTemplate == "" {
+		return nil
+	}
+	if !strings.Contains(opts.KeyTemplate, "{seq}") {
+		return errors.New("the key template must contain {seq}")
+	}
+	for _, placeholder := range keyPlaceholder.FindAllString(opts.KeyTemplate, -1) {
+		switch placeholder {
+		case "{testID}", "{signal}", "{date}", "{seq}":
+		default:
+			return fmt.Errorf("unknown placeholder %s in the key template", placeholder)
+		}
+	}
+	return nil
+}
+
+// Bucket returns the bucket objects are uploaded to.
+func (u *S3Uploader) Bucket() string {
+	return u.client.bucket
+}
+
+// Write adds an entry (models.LogEntry, models.Metric or models.Trace) to the segment
+// of its test and signal. It blocks while too many segments wait for their upload.
+func (u *S3Uploader) Write(entry interface{}) error {
+	signal := entrySignal(entry)
+	if signal == "" {
+		return fmt.Errorf("cannot upload %T", entry)
+	}
+	u.mu.Lock()
+	if u.closed {
+		u.mu.Unlock()
+		return os.ErrClosed
+	}
+	seg, err := u.segmentLocked(entryTestID(entry), signal)
+	if err == nil {
+		err = u.writeEntry(seg, entry)
+	}
+	var full *objectSegment
+	if err == nil && seg.rawBytes >= u.segmentSize {
+		full = u.closeSegmentLocked(seg)
+	}
+	u.mu.Unlock()
+
+	if full != nil {
+		u.queue(full)
+	}
+	return err
+}
+
+// entryTestID returns the test ID of an entry.
+func entryTestID(entry interface{}) string {
+	switch e := entry.(type) {
+	case models.LogEntry:
+		return e.TestID
+	case models.Metric:
+		return e.TestID
+	case models.Trace:
+		return e.TestID
+	}
+	return ""
+}
+
+// segmentLocked returns the open segment of the test and signal, creating it if
+// needed. The caller must hold u.mu.
+func (u *S3Uploader) segmentLocked(testID, signal string) (*objectSegment, error) {
+	name := testID + "/" + signal
+	if seg := u.segments[name]; seg != nil {
+		return seg, nil
+	}
+	u.seqs[name]++
+	seg := &objectSegment{testID: testID, signal: signal, seq: u.seqs[name], openedAt: time.Now().UTC()}
+	seg.path = filepath.Join(u.dir, fmt.Sprintf("%s-%s-%06d%s", sanitizeFileName(testID), signal, seg.seq, u.extension()))
+
+	file, err := os.OpenFile(seg.path, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0o644)
+	if err != nil {
+		return nil, err
+	}
+	seg.file = file
+	var w io.Writer = file
+	switch {
+	case u.format == FormatParquet:
+		seg.buf = bufio.NewWriterSize(file, fileBufferSize)
+		if seg.parquet, err = NewParquetWriter(seg.buf, entryColumns(signal), u.compression); err != nil {
+			file.Close()
+			os.Remove(seg.path)
+			return nil, err
+		}
+	case u.compression == CompressionGzip:
+		seg.compressor = gzip.NewWriter(file)
+		w = seg.compressor
+	case u.compression == CompressionZstd:
+		encoder, err := zstd.NewWriter(file)
+		if err != nil {
+			file.Close()
+			os.Remove(seg.path)
+			return nil, err
+		}
+		seg.compressor = encoder
+		w = encoder
+	}
+	if seg.buf == nil {
+		seg.buf = bufio.NewWriterSize(w, fileBufferSize)
+	}
+	u.segments[name] = seg
+	u.pending++
+	return seg, nil
+}
+
+// writeEntry encodes an entry into the segment.
+func (u *S3Uploader) writeEntry(seg *objectSegment, entry interface{}) error {
+	if seg.parquet != nil {
+		row, err := entryRow(entry)
+		if err != nil {
+			return err
+		}
+		seg.rows = append(seg.rows, row)
+		seg.entries++
+		seg.rawBytes += rowSize(row)
+		if len(seg.rows) >= parquetRowGroupRows {
+			err = seg.parquet.WriteRows(seg.rows)
+			seg.rows = seg.rows[:0]
+		}
+		return err
+	}
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+	if _, err := seg.buf.Write(append(data, '\n')); err != nil {
+		return err
+	}
+	seg.entries++
+	seg.rawBytes += int64(len(data)) + 1
+	return nil
+}
+
+// rowSize returns the PLAIN-encoded size of a Parquet row.
+func rowSize(row []interface{}) int64 {
+	var size int64
+	for _, value := range row {
+		if s, ok := value.(string); ok {
+			size += 4 + int64(len(s))
+		} else {
+			size += 8
+		}
+	}
+	return size
+}
+
+// closeSegmentLocked finishes the segment file and removes it from the open segments.
+// The caller must hold u.mu and queue the returned segment.
+func (u *S3Uploader) closeSegmentLocked(seg *objectSegment) *objectSegment {
+	delete(u.segments, seg.testID+"/"+seg.signal)
+	var err error
+	if seg.parquet != nil {
+		if err = seg.parquet.WriteRows(seg.rows); err == nil {
+			err = seg.parquet.Close()
+		}
+		seg.rows = nil
+	}
+	if flushErr := seg.buf.Flush(); err == nil {
+		err = flushErr
+	}
+	if seg.compressor != nil {
+		if closeErr := seg.compressor.Close(); err == nil {
+			err = closeErr
+		}
+	}
+	if closeErr := seg.file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		u.logger.Errorf("Failed to write segment %s: %v", seg.path, err)
+	}
+	u.sending.Add(1)
+	return seg
+}
+
+// queue hands a closed segment to the upload workers.
+func (u *S3Uploader) queue(seg *objectSegment) {
+	u.uploadQueue <- seg
+	u.sending.Done()
+}
+
+// checkLoop closes the segments that reached their age.
+func (u *S3Uploader) checkLoop() {
+	defer u.checkerWG.Done()
+	ticker := time.NewTicker(segmentCheckInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			var due []*objectSegment
+			u.mu.Lock()
+			if !u.closed {
+				for _, seg := range u.segments {
+					if time.Since(seg.openedAt) >= u.interval {
+						due = append(due, u.closeSegmentLocked(seg))
+					}
+				}
+			}
+			u.mu.Unlock()
+			for _, seg := range due {
+				u.queue(seg)
+			}
+		case <-u.quit:
+			return
+		}
+	}
+}
+
+// uploadLoop uploads queued segments until the queue is closed.
+func (u *S3Uploader) uploadLoop() {
+	defer u.uploadersWG.Done()
+	for seg := range u.uploadQueue {
+		u.upload(seg)
+	}
+}
+
+// upload uploads a segment as an object and removes its file. The file is kept if
+// the upload fails.
+func (u *S3Uploader) upload(seg *objectSegment) {
+	key := u.objectKey(seg)
+	size, err := u.uploadFile(key, seg.path)
+
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	u.pending--
+	if err != nil {
+		u.failures++
+		u.kept++
+		u.logger.Errorf("Failed to upload segment %s to s3://%s/%s; the segment was kept: %v", seg.path, u.client.bucket, key, err)
+		return
+	}
+	if err := os.Remove(seg.path); err != nil {
+		u.logger.Warnf("Failed to remove uploaded segment %s: %v", seg.path, err)
+	}
+	u.objects++
+	u.entries += seg.entries
+	u.rawBytes += seg.rawBytes
+	u.wireBytes += size
+	u.lastKey = key
+}
+
+// objectKey expands the key template for the segment and appends the extension of
+// the segment format.
+func (u *S3Uploader) objectKey(seg *objectSegment) string {
+	key := strings.NewReplacer(
+		"{testID}", seg.testID,
+		"{signal}", seg.signal,
+		"{date}", seg.openedAt.Format("2006-01-02"),
+		"{seq}", fmt.Sprintf("%06d", seg.seq),
+	).Replace(u.keyTemplate)
+	return strings.TrimPrefix(key, "/") + u.extension()
+}
+
+// extension returns the file extension of the segments.
+func (u *S3Uploader) extension() string {
+	if u.format == FormatParquet {
+		return ".parquet"
+	}
+	switch u.compression {
+	case CompressionGzip:
+		return ".ndjson.gz"
+	case CompressionZstd:
+		return ".ndjson.zst"
+	}
+	return ".ndjson"
+}
+
+// contentType returns the Content-Type of the objects.
+func (u *S3Uploader) contentType() string {
+	if u.format == FormatParquet {
+		return "application/vnd.apache.parquet"
+	}
+	switch u.compression {
+	case CompressionGzip:
+		return "application/gzip"
+	case CompressionZstd:
+		return "application/zstd"
+	}
+	return "application/x-ndjson"
+}
+
+// uploadFile uploads the file in parts of partSize and returns its size. The upload
+// is aborted if a part or the completion fails.
+func (u *S3Uploader) uploadFile(key, path string) (int64, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return 0, err
+	}
+	defer file.Close()
+
+	var uploadID string
+	err = u.withRetry(func(ctx context.Context) error {
+		var err error
+		uploadID, err = u.client.createMultipartUpload(ctx, key, u.contentType())
+		return err
+	})
+	if err != nil {
+		return 0, fmt.Errorf("failed to start the upload: %w", err)
+	}
+
+	size, err := u.uploadParts(key, uploadID, file)
+	if err != nil {
+		ctx, cancel := context.WithTimeout(context.Background(), s3RequestTimeout)
+		defer cancel()
+		if abortErr := u.client.abortMultipartUpload(ctx, key, uploadID); abortErr != nil {
+			u.logger.Warnf("Failed to abort the upload of s3://%s/%s: %v", u.client.bucket, key, abortErr)
+		}
+		return 0, err
+	}
+	return size, nil
+}
+
+// uploadParts uploads the parts of a file and completes the upload.
+func (u *S3Uploader) uploadParts(key, uploadID string, file io.Reader) (int64, error) {
+	var parts []completedPart
+	var size int64
+	buf := make([]byte, u.partSize)
+	for number := 1; ; number++ {
+		n, err := io.ReadFull(file, buf)
+		if err == io.EOF && number > 1 {
+			break
+		}
+		if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
+			return 0, err
+		}
+		var etag string
+		err = u.withRetry(func(ctx context.Context) error {
+			var err error
+			etag, err = u.client.uploadPart(ctx, key, uploadID, number, buf[:n])
+			return err
+		})
+		if err != nil {
+			return 0, fmt.Errorf("failed to upload part %d: %w", number, err)
+		}
+		parts = append(parts, completedPart{PartNumber: number, ETag: etag})
+		size += int64(n)
+		u.mu.Lock()
+		u.parts++
+		u.mu.Unlock()
+		if n < len(buf) {
+			break
+		}
+	}
+
+	err := u.withRetry(func(ctx context.Context) error {
+		return u.client.completeMultipartUpload(ctx, key, uploadID, parts)
+	})
+	if err != nil {
+		return 0, fmt.Errorf("failed to complete the upload: %w", err)
+	}
+	return size, nil
+}
+
+// withRetry runs an S3 request, retrying it with the retry policy.
+func (u *S3Uploader) withRetry(request func(ctx context.Context) error) error {
+	for attempt := 1; ; attempt++ {
+		ctx, cancel := context.WithTimeout(context.Background(), s3RequestTimeout)
+		err := request(ctx)
+		cancel()
+		if err == nil || attempt >= u.policy.maxAttempts || !u.policy.isRetryable(err) {
+			return err
+		}
+		u.mu.Lock()
+		u.retries++
+		u.mu.Unlock()
+		time.Sleep(u.policy.backoff(attempt, err))
+	}
+}
+
+// Counts returns the number of uploaded and failed objects.
+func (u *S3Uploader) Counts() (successes int64, failures int64) {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	return u.objects, u.failures
+}
+
+// Stats returns the statistics of the uploads so far. Requests count the uploaded
+// objects and WireBytes their size.
+func (u *S3Uploader) Stats() *models.DeliveryStats {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	stats := &models.DeliveryStats{
+		Requests:  u.objects,
+		Failures:  u.failures,
+		Entries:   u.entries,
+		RawBytes:  u.rawBytes,
+		WireBytes: u.wireBytes,
+		Retries:   u.retries,
+		Objects: &models.ObjectStats{
+			Bucket:          u.client.bucket,
+			Parts:           u.parts,
+			PendingSegments: u.pending,
+			KeptSegments:    u.kept,
+			LastKey:         u.lastKey,
+		},
+	}
+	if stats.WireBytes > 0 {
+		stats.CompressionRatio = float64(stats.RawBytes) / float64(stats.WireBytes)
+	}
+	return stats
+}
+
+// Close closes the open segments and waits until every segment is uploaded or has
+// failed. It returns an error if segments were kept. Write must not be called after
+// Close.
+func (u *S3Uploader) Close() error {
+	u.closeOnce.Do(func() {
+		close(u.quit)
+		u.checkerWG.Wait()
+
+		u.mu.Lock()
+		u.closed = true
+		remaining := make([]*objectSegment, 0, len(u.segments))
+		for _, seg := range u.segments {
+			remaining = append(remaining, u.closeSegmentLocked(seg))
+		}
+		u.mu.Unlock()
+		for _, seg := range remaining {
+			u.queue(seg)
+		}
+
+		u.sending.Wait()
+		close(u.uploadQueue)
+		u.uploadersWG.Wait()
+		// Only succeeds if no segment was kept.
+		_ = os.Remove(u.dir)
+	})
+
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	if u.kept > 0 {
+		return fmt.Errorf("%d segments could not be uploaded and were kept in %s", u.kept, u.dir)
+	}
+	return nil
+}
+
+// sanitizeFileName replaces the characters of s that are unsafe in file names.
+func sanitizeFileName(s string) string {
+	if s == "" {
+		return "unknown"
+	}
+	return strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '-', r == '_', r == '.':
+			return r
+		}
+		return '_'
+	}, s)
+}
@@ -0,0 +1,463 @@
+// backend/internal/loadgen/delivery/socket_sender.go
+
+package delivery
+
+import (
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/AkshayDubey29/MoniFlux/backend/internal/api/models"
+	"github.com/AkshayDubey29/MoniFlux/backend/internal/common"
+	"github.com/AkshayDubey29/MoniFlux/backend/pkg/tracing"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+)
+
+// Supported TCP framings. UDP sends one message per datagram.
+const (
+	FramingOctetCounting = "octet-counting" // "<length> <message>", as defined by RFC 6587
+	FramingNewline       = "newline"        // The message followed by "\n"
+)
+
+// DefaultSyslogPort is used for syslog destinations without a port, and
+// DefaultSyslogTLSPort for those using TLS.
+const (
+	DefaultSyslogPort    = 514
+	DefaultSyslogTLSPort = 6514
+)
+
+// DefaultSocketConnections is the connection pool size of socket destinations.
+const DefaultSocketConnections = 2
+
+const (
+	socketDialTimeout  = 5 * time.Second
+	socketWriteTimeout = 10 * time.Second
+)
+
+// IsSocketDestination reports whether the destination type is delivered over a
+// syslog, TCP or UDP socket.
+func IsSocketDestination(destinationType string) bool {
+	switch destinationType {
+	case "syslog", "tcp", "udp":
+		return true
+	}
+	return false
+}
+
+// socketConn is a pooled connection, dialed on first use and after failures.
+type socketConn struct {
+	id         int
+	conn       net.Conn
+	dialedAt   time.Time // First successful dial
+	lastWrite  time.Time
+	messages   int64
+	bytes      int64
+	errors     int64
+	reconnects int64
+}
+
+// SocketSender delivers entries to a syslog, TCP or UDP destination over a pool of
+// connections. A connection that fails is closed and dialed again.
+type SocketSender struct {
+	network   string // tcp or udp
+	address   string
+	tlsConfig *tls.Config // nil for plain TCP and UDP
+	guard     DialGuard
+	framing   string
+	format    func(entry interface{}) ([]byte, error)
+
+	idle  chan *socketConn
+	conns []*socketConn
+	mu    sync.Mutex // Protects the connection statistics
+
+	requests int64
+	failures int64
+	entries  int64
+	rawBytes int64
+}
+
+// NewSocketSender builds a sender for a syslog, tcp or udp destination. Connections
+// are dialed when first used and checked by the guard, if not nil.
+func NewSocketSender(ctx context.Context, dest common.Destination, secrets SecretResolver, guard DialGuard) (*SocketSender, error) {
+	if !IsSocketDestination(dest.Type) {
+		return nil, fmt.Errorf("unsupported socket destination type %q", dest.Type)
+	}
+	opts := common.SocketOptions{}
+	if dest.Socket != nil {
+		opts = *dest.Socket
+	}
+	if auth := dest.Auth; dest.SecretRef != "" || auth != nil && (auth.Basic != nil || auth.OAuth2 != nil || len(auth.Headers) > 0 || len(auth.SecretHeaders) > 0 || auth.TenantID != "") {
+		return nil, fmt.Errorf("%w: %s destinations only support TLS authentication", ErrInvalidAuth, dest.Type)
+	}
+
+	network := dest.Type
+	if dest.Type == "syslog" {
+		network = "tcp"
+		if opts.Network != "" {
+			network = opts.Network
+		}
+	}
+	if network != "tcp" && network != "udp" {
+		return nil, fmt.Errorf("unsupported network %q", network)
+	}
+	useTLS := opts.TLS || dest.Auth != nil && dest.Auth.TLS != nil
+	if useTLS && network != "tcp" {
+		return nil, errors.New("TLS is only supported over TCP")
+	}
+
+	defaultPort := 0
+	if dest.Type == "syslog" {
+		defaultPort = DefaultSyslogPort
+		if useTLS {
+			defaultPort = DefaultSyslogTLSPort
+		}
+	}
+	host, port, err := SocketAddress(dest.Endpoint, dest.Port, defaultPort)
+	if err != nil {
+		return nil, err
+	}
+
+	framing := opts.Framing
+	if framing == "" {
+		framing = FramingNewline
+		if dest.Type == "syslog" {
+			framing = FramingOctetCounting
+		}
+	}
+	if framing != FramingOctetCounting && framing != FramingNewline {
+		return nil, fmt.Errorf("unsupported framing %q", framing)
+	}
+
+	sender := &SocketSender{
+		network: network,
+		address: net.JoinHostPort(host, strconv.Itoa(port)),
+		guard:   guard,
+		framing: framing,
+		format:  json.Marshal,
+	}
+	if dest.Type == "syslog" {
+		formatter, err := newSyslogFormatter(opts)
+		if err != nil {
+			return nil, err
+		}
+		sender.format = formatter.formatEntry
+	}
+	if useTLS {
+		tlsOptions := &common.DestinationTLS{}
+		if dest.Auth != nil && dest.Auth.TLS != nil {
+			tlsOptions = dest.Auth.TLS
+		}
+		if len(dest.SecretRefs()) > 0 && secrets == nil {
+			return nil, errors.New("destination credentials require a secret resolver")
+		}
+		tlsConfig, err := buildTLSConfig(ctx, tlsOptions, secrets)
+		if err != nil {
+			return nil, err
+		}
+		if tlsConfig.ServerName == "" {
+			tlsConfig.ServerName = host
+		}
+		sender.tlsConfig = tlsConfig
+	}
+
+	size := opts.Connections
+	if size <= 0 {
+		size = DefaultSocketConnections
+	}
+	sender.idle = make(chan *socketConn, size)
+	for i := 0; i < size; i++ {
+		conn := &socketConn{id: i + 1}
+		sender.conns = append(sender.conns, conn)
+		sender.idle <- conn
+	}
+	return sender, nil
+}
+
+func newSyslogFormatter(opts common.SocketOptions) (syslogFormatter, error) {
+	f := syslogFormatter{
+		format:   opts.Format,
+		facility: DefaultSyslogFacility,
+		hostname: opts.Hostname,
+		appName:  opts.AppName,
+	}
+	switch f.format {
+	case "":
+		f.format = SyslogRFC5424
+	case SyslogRFC5424, SyslogRFC3164:
+	default:
+		return f, fmt.Errorf("unsupported syslog format %q", opts.Format)
+	}
+	if opts.Facility != nil {
+		if *opts.Facility < 0 || *opts.Facility > 23 {
+			return f, fmt.Errorf("invalid syslog facility %d", *opts.Facility)
+		}
+		f.facility = *opts.Facility
+	}
+	if f.appName == "" {
+		f.appName = DefaultSyslogAppName
+	}
+	if f.hostname == "" {
+		f.hostname, _ = os.Hostname()
+	}
+	return f, nil
+}
+
+// SocketAddress returns the host and port of a socket destination. The endpoint is a
+// host, host:port or a URL such as tcp://host:port; its port takes precedence over
+// port, and defaultPort is used when neither is set.
+func SocketAddress(endpoint string, port, defaultPort int) (string, int, error) {
+	host := endpoint
+	if i := strings.Index(host, "://"); i >= 0 {
+		host = host[i+3:]
+	}
+	host = strings.TrimSuffix(host, "/")
+	if h, p, err := net.SplitHostPort(host); err == nil {
+		n, err := strconv.Atoi(p)
+		if err != nil {
+			return "", 0, fmt.Errorf("invalid port in endpoint %q", endpoint)
+		}
+		host, port = h, n
+	}
+	host = strings.Trim(host, "[]")
+	if host == "" || strings.ContainsAny(host, "/?#@ ") {
+		return "", 0, fmt.Errorf("invalid endpoint %q: expected host or host:port", endpoint)
+	}
+	if port == 0 {
+		port = defaultPort
+	}
+	if port < 1 || port > 65535 {
+		return "", 0, fmt.Errorf("a port is required for endpoint %q", endpoint)
+	}
+	return host, port, nil
+}
+
+// Address returns the host:port entries are sent to.
+func (s *SocketSender) Address() string {
+	return s.address
+}
+
+// Send formats and writes the entries on a pooled connection. TCP connections write
+// the batch at once; UDP sends a datagram per entry. A failed write is retried once
+// on a new connection.
+func (s *SocketSender) Send(ctx context.Context, entries []interface{}) (err error) {
+	host, _, splitErr := net.SplitHostPort(s.address)
+	if splitErr != nil {
+		host = s.address
+	}
+	ctx, span := startSpan(ctx, "send "+s.network,
+		semconv.NetworkTransportKey.String(s.network),
+		semconv.ServerAddress(host),
+		tracing.EntriesKey.Int(len(entries)),
+	)
+	defer func() { tracing.End(span, err) }()
+
+	messages := make([][]byte, 0, len(entries))
+	raw := 0
+	for _, entry := range entries {
+		msg, err := s.format(entry)
+		if err != nil {
+			s.recordFailure()
+			return err
+		}
+		messages = append(messages, msg)
+		raw += len(msg)
+	}
+
+	var conn *socketConn
+	select {
+	case conn = <-s.idle:
+	case <-ctx.Done():
+		s.recordFailure()
+		return ctx.Err()
+	}
+	defer func() { s.idle <- conn }()
+
+	var written int
+	if s.network == "udp" {
+		written, err = s.writeDatagrams(ctx, conn, messages)
+	} else {
+		written, err = s.writeStream(ctx, conn, s.frame(messages))
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err != nil {
+		s.failures++
+		return err
+	}
+	conn.lastWrite = time.Now()
+	conn.messages += int64(len(messages))
+	conn.bytes += int64(written)
+	s.requests++
+	s.entries += int64(len(messages))
+	s.rawBytes += int64(raw)
+	return nil
+}
+
+// frame joins the messages into a TCP stream.
+func (s *SocketSender) frame(messages [][]byte) []byte {
+	var buf []byte
+	for _, msg := range messages {
+		if s.framing == FramingOctetCounting {
+			buf = strconv.AppendInt(buf, int64(len(msg)), 10)
+			buf = append(buf, ' ')
+			buf = append(buf, msg...)
+			continue
+		}
+		// Newlines inside a message would split it in two.
+		for _, b := range msg {
+			if b == '\n' {
+				b = ' '
+			}
+			buf = append(buf, b)
+		}
+		buf = append(buf, '\n')
+	}
+	return buf
+}
+
+// writeStream writes data on the connection, reconnecting once if it fails.
+func (s *SocketSender) writeStream(ctx context.Context, conn *socketConn, data []byte) (int, error) {
+	var err error
+	for attempt := 0; attempt < 2; attempt++ {
+		if err = s.ensureConnected(ctx, conn); err != nil {
+			continue
+		}
+		_ = conn.conn.SetWriteDeadline(time.Now().Add(socketWriteTimeout))
+		if _, err = conn.conn.Write(data); err == nil {
+			return len(data), nil
+		}
+		s.dropConn(conn)
+	}
+	return 0, err
+}
+
+// writeDatagrams sends a datagram per message, reconnecting once if a write fails.
+func (s *SocketSender) writeDatagrams(ctx context.Context, conn *socketConn, messages [][]byte) (int, error) {
+	written := 0
+	for _, msg := range messages {
+		var err error
+		for attempt := 0; attempt < 2; attempt++ {
+			if err = s.ensureConnected(ctx, conn); err != nil {
+				continue
+			}
+			_ = conn.conn.SetWriteDeadline(time.Now().Add(socketWriteTimeout))
+			if _, err = conn.conn.Write(msg); err == nil {
+				break
+			}
+			s.dropConn(conn)
+		}
+		if err != nil {
+			return written, err
+		}
+		written += len(msg)
+	}
+	return written, nil
+}
+
+// ensureConnected dials the connection if it is closed or, for TCP, if the peer has
+// closed it. The first write to a connection closed by the peer would otherwise
+// succeed and lose the data.
+func (s *SocketSender) ensureConnected(ctx context.Context, conn *socketConn) error {
+	if conn.conn != nil && s.network == "tcp" && peerClosed(conn.conn) {
+		s.dropConn(conn)
+	}
+	if conn.conn != nil {
+		return nil
+	}
+
+	host, _, _ := net.SplitHostPort(s.address)
+	dialer := newDialer(s.guard, host, socketDialTimeout)
+	var c net.Conn
+	var err error
+	if s.tlsConfig != nil {
+		c, err = (&tls.Dialer{NetDialer: dialer, Config: s.tlsConfig}).DialContext(ctx, s.network, s.address)
+	} else {
+		c, err = dialer.DialContext(ctx, s.network, s.address)
+	}
+	if err != nil {
+		s.mu.Lock()
+		conn.errors++
+		s.mu.Unlock()
+		return fmt.Errorf("failed to connect to %s: %w", s.address, err)
+	}
+	conn.conn = c
+	if conn.dialedAt.IsZero() {
+		s.mu.Lock()
+		conn.dialedAt = time.Now()
+		s.mu.Unlock()
+	}
+	return nil
+}
+
+// dropConn closes a failed connection, so the next write dials a new one.
+func (s *SocketSender) dropConn(conn *socketConn) {
+	if conn.conn == nil {
+		return
+	}
+	_ = conn.conn.Close()
+	conn.conn = nil
+	s.mu.Lock()
+	conn.errors++
+	conn.reconnects++
+	s.mu.Unlock()
+}
+
+func (s *SocketSender) recordFailure() {
+	s.mu.Lock()
+	s.failures++
+	s.mu.Unlock()
+}
+
+// Stats returns the delivery statistics with the traffic of each connection.
+// Requests count the batches written and WireBytes include the framing.
+func (s *SocketSender) Stats() *models.DeliveryStats {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	stats := &models.DeliveryStats{
+		Requests:    s.requests,
+		Failures:    s.failures,
+		Entries:     s.entries,
+		RawBytes:    s.rawBytes,
+		Connections: make([]models.ConnectionStats, 0, len(s.conns)),
+	}
+	for _, conn := range s.conns {
+		c := models.ConnectionStats{
+			ID:         conn.id,
+			Address:    s.address,
+			Messages:   conn.messages,
+			Bytes:      conn.bytes,
+			Errors:     conn.errors,
+			Reconnects: conn.reconnects,
+		}
+		if elapsed := conn.lastWrite.Sub(conn.dialedAt).Seconds(); elapsed > 0 {
+			c.BytesPerSecond = float64(conn.bytes) / elapsed
+		}
+		stats.WireBytes += conn.bytes
+		stats.Connections = append(stats.Connections, c)
+	}
+	return stats
+}
+
+// Close closes the pooled connections. It must be called after the last Send.
+func (s *SocketSender) Close() error {
+	var firstErr error
+	for _, conn := range s.conns {
+		if conn.conn == nil {
+			continue
+		}
+		if err := conn.conn.Close(); err != nil && firstErr == nil {
+			firstErr = err
+		}
+		conn.conn = nil
+	}
+	return firstErr
+}
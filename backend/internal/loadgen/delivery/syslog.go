@@ -0,0 +1,92 @@
+// backend/internal/loadgen/delivery/syslog.go
+
+package delivery
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/AkshayDubey29/MoniFlux/backend/internal/api/models"
+)
+
+// Supported syslog message formats.
+const (
+	SyslogRFC5424 = "rfc5424"
+	SyslogRFC3164 = "rfc3164"
+)
+
+// Syslog defaults, used for unset fields of common.SocketOptions.
+const (
+	DefaultSyslogFacility = 16 // local0
+	DefaultSyslogAppName  = "moniflux"
+)
+
+// Syslog severities of the generated entries.
+const (
+	severityError         = 3
+	severityWarning       = 4
+	severityInformational = 6
+)
+
+// syslogFormatter formats entries as syslog messages.
+type syslogFormatter struct {
+	format   string
+	facility int
+	hostname string
+	appName  string
+}
+
+// formatEntry returns the syslog message of an entry. The message of a log entry is sent
+// as is; metrics and traces are sent as their JSON form.
+func (f syslogFormatter) formatEntry(entry interface{}) ([]byte, error) {
+	var timestamp time.Time
+	var msgID, msg string
+	severity := severityInformational
+	switch e := entry.(type) {
+	case models.LogEntry:
+		timestamp, msgID, msg = e.Timestamp, "log", e.Message
+		switch strings.ToUpper(e.Level) {
+		case "ERROR":
+			severity = severityError
+		case "WARN":
+			severity = severityWarning
+		}
+	case models.Metric:
+		data, err := json.Marshal(e)
+		if err != nil {
+			return nil, err
+		}
+		timestamp, msgID, msg = e.Timestamp, "metric", string(data)
+	case models.Trace:
+		data, err := json.Marshal(e)
+		if err != nil {
+			return nil, err
+		}
+		timestamp, msgID, msg = e.Timestamp, "trace", string(data)
+	default:
+		return nil, fmt.Errorf("cannot format %T as syslog", entry)
+	}
+	if timestamp.IsZero() {
+		timestamp = time.Now()
+	}
+
+	pri := "<" + strconv.Itoa(f.facility*8+severity) + ">"
+	if f.format == SyslogRFC3164 {
+		// The BSD format has no year, zone or sub-second precision and uses local time.
+		return []byte(pri + timestamp.Local().Format(time.Stamp) + " " + f.hostname + " " + f.appName + ": " + msg), nil
+	}
+	// VERSION TIMESTAMP HOSTNAME APP-NAME PROCID MSGID STRUCTURED-DATA MSG
+	return []byte(pri + "1 " + timestamp.UTC().Format("2006-01-02T15:04:05.000000Z07:00") + " " +
+		nilValue(f.hostname) + " " + nilValue(f.appName) + " - " + msgID + " - " + msg), nil
+}
+
+// nilValue returns the RFC 5424 NILVALUE for empty header fields.
+func nilValue(s string) string {
+	if s == "" {
+		return "-"
+	}
+	return s
+}
@@ -0,0 +1,34 @@
+// backend/internal/loadgen/delivery/tracing.go
+
+package delivery
+
+import (
+	"context"
+	"net/http"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/propagation"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const tracerName = "github.com/AkshayDubey29/MoniFlux/backend/internal/loadgen/delivery"
+
+// startSpan starts the client span of a delivery request.
+func startSpan(ctx context.Context, name string, attrs ...attribute.KeyValue) (context.Context, trace.Span) {
+	return otel.Tracer(tracerName).Start(ctx, name, trace.WithSpanKind(trace.SpanKindClient), trace.WithAttributes(attrs...))
+}
+
+// startHTTPSpan starts the span of an HTTP request and adds the W3C traceparent
+// header of the span to the request.
+func startHTTPSpan(req *http.Request, attrs ...attribute.KeyValue) trace.Span {
+	attrs = append(attrs,
+		semconv.HTTPRequestMethodKey.String(req.Method),
+		semconv.ServerAddress(req.URL.Hostname()),
+		semconv.URLPath(req.URL.Path),
+	)
+	ctx, span := startSpan(req.Context(), req.Method, attrs...)
+	otel.GetTextMapPropagator().Inject(ctx, propagation.HeaderCarrier(req.Header))
+	return span
+}
@@ -10,6 +10,7 @@ import (
 	"time"
 
 	"github.com/AkshayDubey29/MoniFlux/backend/internal/api/models"
+	"github.com/AkshayDubey29/MoniFlux/backend/internal/common"
 	"github.com/AkshayDubey29/MoniFlux/backend/internal/loadgen/delivery"
 	"github.com/sirupsen/logrus"
 )
@@ -30,13 +31,9 @@ type GeneratorService struct {
 
 // NewGeneratorService creates a new GeneratorService instance with configured rates and sizes.
 // It initializes the DeliveryService based on the provided destinations.
-func NewGeneratorService(logger *logrus.Logger, destinations []models.Destination, logRate, metricsRate, traceRate, logSize int, metricsValue float64) (*GeneratorService, error) {
-	// Convert common.Destination to models.Destination if necessary
-	modelDestinations := make([]models.Destination, len(destinations))
-	copy(modelDestinations, destinations)
-
+func NewGeneratorService(ctx context.Context, logger *logrus.Logger, destinations []common.Destination, secrets delivery.SecretResolver, logRate, metricsRate, traceRate, logSize int, metricsValue float64) (*GeneratorService, error) {
 	// Initialize the DeliveryService
-	deliveryService, err := delivery.NewDeliveryService(logger, destinations)
+	deliveryService, err := delivery.NewDeliveryService(ctx, logger, destinations, secrets)
 	if err != nil {
 		return nil, fmt.Errorf("failed to initialize DeliveryService: %w", err)
 	}
@@ -177,6 +174,12 @@ func (gs *GeneratorService) generateRandomLogMessage() string {
 	return randomString(gs.logSize)
 }
 
+// randomLogLevel picks a random level for generated log entries.
+func (gs *GeneratorService) randomLogLevel() string {
+	levels := []string{"INFO", "WARN", "ERROR"}
+	return levels[rand.Intn(len(levels))]
+}
+
 // generateRandomMetricValue generates a random metric value around the configured value.
 func (gs *GeneratorService) generateRandomMetricValue() float64 {
 	// Simulate generating a random metric value with some variation
@@ -0,0 +1,346 @@
+// backend/internal/loadgen/metrics/engine.go
+
+package metrics
+
+import (
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// Signals of the entry counters.
+const (
+	SignalLog    = "log"
+	SignalMetric = "metric"
+	SignalTrace  = "trace"
+	// SignalAll labels delivery outcomes; destinations acknowledge batches that mix
+	// logs, metrics and traces.
+	SignalAll = "all"
+)
+
+// OtherTestID is the test_id label shared by the tests started while the label
+// limit is reached.
+const OtherTestID = "other"
+
+const (
+	namespace = "moniflux"
+	subsystem = "loadgen"
+)
+
+// latencyBuckets range from 1ms to about 33s, for writes to a local file up to HTTP
+// requests that are retried.
+var latencyBuckets = prometheus.ExponentialBuckets(0.001, 2, 16)
+
+// Engine exposes the metrics of the load generator as a prometheus.Collector. Series
+// are labelled by test_id, signal and destination, the destination type of the test.
+//
+// At most maxTestIDs tests are labelled with their ID; the series of finished tests
+// are kept until their label is needed by a new test, and tests started while every
+// label belongs to a running test are counted under OtherTestID. With maxTestIDs 0
+// every test is labelled and its series are kept for the life of the process.
+type Engine struct {
+	generated   *prometheus.CounterVec
+	enqueued    *prometheus.CounterVec
+	dropped     *prometheus.CounterVec
+	delivered   *prometheus.CounterVec
+	failed      *prometheus.CounterVec
+	latency     *prometheus.HistogramVec
+	workers     *prometheus.GaugeVec
+	activeTests prometheus.Gauge
+	queueDepth  *prometheus.Desc
+
+	mu         sync.Mutex
+	maxTestIDs int
+	labelled   map[string]int       // Running tests per labelled test ID
+	finished   []string             // Labelled test IDs without running tests, oldest first
+	running    map[*Test]func() int // Queue length of each running test
+}
+
+// NewEngine creates the metrics of the load generator. The caller registers the
+// engine, usually with prometheus.MustRegister.
+func NewEngine(maxTestIDs int) *Engine {
+	entryLabels := []string{"test_id", "signal", "destination"}
+	testLabels := []string{"test_id", "destination"}
+	counter := func(name, help string) *prometheus.CounterVec {
+		return prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: subsystem,
+			Name:      name,
+			Help:      help,
+		}, entryLabels)
+	}
+	return &Engine{
+		generated: counter("entries_generated_total", "Entries generated, including those dropped later."),
+		enqueued:  counter("entries_enqueued_total", "Entries queued for the workers."),
+		dropped:   counter("entries_dropped_total", "Entries dropped because the queue was full."),
+		delivered: counter("entries_delivered_total", "Entries accepted by the destination or written to the file."),
+		failed:    counter("delivery_failures_total", "Requests, batches or uploads that failed on every attempt, and entries dropped from a file."),
+		latency: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Subsystem: subsystem,
+			Name:      "delivery_latency_seconds",
+			Help:      "Time taken to hand a batch, or an entry, to the destination.",
+			Buckets:   latencyBuckets,
+		}, testLabels),
+		workers: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: namespace,
+			Subsystem: subsystem,
+			Name:      "workers",
+			Help:      "Workers delivering the entries of running tests.",
+		}, testLabels),
+		activeTests: prometheus.NewGauge(prometheus.GaugeOpts{
+			Namespace: namespace,
+			Subsystem: subsystem,
+			Name:      "active_tests",
+			Help:      "Tests generating load.",
+		}),
+		queueDepth: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, subsystem, "queue_depth"),
+			"Entries waiting for a worker.",
+			testLabels, nil,
+		),
+		maxTestIDs: maxTestIDs,
+		labelled:   make(map[string]int),
+		running:    make(map[*Test]func() int),
+	}
+}
+
+// Describe implements prometheus.Collector.
+func (e *Engine) Describe(ch chan<- *prometheus.Desc) {
+	e.generated.Describe(ch)
+	e.enqueued.Describe(ch)
+	e.dropped.Describe(ch)
+	e.delivered.Describe(ch)
+	e.failed.Describe(ch)
+	e.latency.Describe(ch)
+	e.workers.Describe(ch)
+	e.activeTests.Describe(ch)
+	ch <- e.queueDepth
+}
+
+// Collect implements prometheus.Collector. Queue depths are read at scrape time.
+func (e *Engine) Collect(ch chan<- prometheus.Metric) {
+	e.generated.Collect(ch)
+	e.enqueued.Collect(ch)
+	e.dropped.Collect(ch)
+	e.delivered.Collect(ch)
+	e.failed.Collect(ch)
+	e.latency.Collect(ch)
+	e.workers.Collect(ch)
+	e.activeTests.Collect(ch)
+
+	type series struct{ testID, destination string }
+	depths := make(map[series]int)
+	e.mu.Lock()
+	for t, depth := range e.running {
+		if depth != nil {
+			depths[series{t.testID, t.destination}] += depth()
+		}
+	}
+	e.mu.Unlock()
+	for s, depth := range depths {
+		ch <- prometheus.MustNewConstMetric(e.queueDepth, prometheus.GaugeValue, float64(depth), s.testID, s.destination)
+	}
+}
+
+// Start records the start of a test delivering to a destination of the given type
+// with the given number of workers. The returned Test must be finished when the
+// test ends. A nil Engine returns a nil Test, whose methods do nothing.
+func (e *Engine) Start(testID, destination string, workers int) *Test {
+	if e == nil {
+		return nil
+	}
+	e.mu.Lock()
+	label := e.labelLocked(testID)
+	t := &Test{engine: e, testID: label, destination: destination, workers: float64(workers)}
+	e.running[t] = nil
+	e.mu.Unlock()
+
+	t.generated = e.signalCounters(e.generated, label, destination)
+	t.enqueued = e.signalCounters(e.enqueued, label, destination)
+	t.dropped = e.signalCounters(e.dropped, label, destination)
+	t.delivered = e.delivered.WithLabelValues(label, SignalAll, destination)
+	t.failed = e.failed.WithLabelValues(label, SignalAll, destination)
+	t.latency = e.latency.WithLabelValues(label, destination)
+	e.workers.WithLabelValues(label, destination).Add(t.workers)
+	e.activeTests.Inc()
+	return t
+}
+
+// signalCounters returns the counters of a test for each signal, in the order of
+// signalIndex.
+func (e *Engine) signalCounters(vec *prometheus.CounterVec, testID, destination string) [3]prometheus.Counter {
+	return [3]prometheus.Counter{
+		vec.WithLabelValues(testID, SignalLog, destination),
+		vec.WithLabelValues(testID, SignalMetric, destination),
+		vec.WithLabelValues(testID, SignalTrace, destination),
+	}
+}
+
+// labelLocked returns the test_id label of a starting test, taking over the label of
+// the oldest finished test when the limit is reached. The caller must hold e.mu.
+func (e *Engine) labelLocked(testID string) string {
+	if running, ok := e.labelled[testID]; ok {
+		if running == 0 {
+			e.unfinishLocked(testID)
+		}
+		e.labelled[testID] = running + 1
+		return testID
+	}
+	if e.maxTestIDs > 0 && len(e.labelled) >= e.maxTestIDs {
+		if len(e.finished) == 0 {
+			return OtherTestID
+		}
+		e.forgetLocked(e.finished[0])
+		e.finished = e.finished[1:]
+	}
+	e.labelled[testID] = 1
+	return testID
+}
+
+// releaseLocked records that a test using the label has ended. The caller must hold
+// e.mu.
+func (e *Engine) releaseLocked(testID string) {
+	running, ok := e.labelled[testID]
+	if !ok {
+		return // OtherTestID
+	}
+	if running > 1 {
+		e.labelled[testID] = running - 1
+		return
+	}
+	e.labelled[testID] = 0
+	e.finished = append(e.finished, testID)
+}
+
+func (e *Engine) unfinishLocked(testID string) {
+	for i, id := range e.finished {
+		if id == testID {
+			e.finished = append(e.finished[:i], e.finished[i+1:]...)
+			return
+		}
+	}
+}
+
+// forgetLocked deletes the series of a finished test. The caller must hold e.mu.
+func (e *Engine) forgetLocked(testID string) {
+	delete(e.labelled, testID)
+	labels := prometheus.Labels{"test_id": testID}
+	e.generated.DeletePartialMatch(labels)
+	e.enqueued.DeletePartialMatch(labels)
+	e.dropped.DeletePartialMatch(labels)
+	e.delivered.DeletePartialMatch(labels)
+	e.failed.DeletePartialMatch(labels)
+	e.latency.DeletePartialMatch(labels)
+	e.workers.DeletePartialMatch(labels)
+}
+
+// Test records the metrics of a running test. All methods are safe for concurrent
+// use, and do nothing on a nil Test.
+type Test struct {
+	engine      *Engine
+	testID      string // test_id label; OtherTestID beyond the label limit
+	destination string
+	workers     float64
+
+	generated [3]prometheus.Counter
+	enqueued  [3]prometheus.Counter
+	dropped   [3]prometheus.Counter
+	delivered prometheus.Counter
+	failed    prometheus.Counter
+	latency   prometheus.Observer
+
+	finishOnce sync.Once
+}
+
+// signalIndex returns the index of a signal in the counters of a Test.
+func signalIndex(signal string) int {
+	switch signal {
+	case SignalMetric:
+		return 1
+	case SignalTrace:
+		return 2
+	default:
+		return 0
+	}
+}
+
+// Label returns the test_id label of the test.
+func (t *Test) Label() string {
+	if t == nil {
+		return ""
+	}
+	return t.testID
+}
+
+// ObserveQueue sets the function returning the number of entries waiting for a
+// worker, read when the metrics are scraped.
+func (t *Test) ObserveQueue(depth func() int) {
+	if t == nil {
+		return
+	}
+	t.engine.mu.Lock()
+	defer t.engine.mu.Unlock()
+	if _, ok := t.engine.running[t]; ok {
+		t.engine.running[t] = depth
+	}
+}
+
+// Generated counts a generated entry of the signal.
+func (t *Test) Generated(signal string) {
+	if t != nil {
+		t.generated[signalIndex(signal)].Inc()
+	}
+}
+
+// Enqueued counts an entry of the signal queued for the workers.
+func (t *Test) Enqueued(signal string) {
+	if t != nil {
+		t.enqueued[signalIndex(signal)].Inc()
+	}
+}
+
+// Dropped counts an entry of the signal dropped because the queue was full.
+func (t *Test) Dropped(signal string) {
+	if t != nil {
+		t.dropped[signalIndex(signal)].Inc()
+	}
+}
+
+// Delivered adds entries accepted by the destination and failed deliveries.
+func (t *Test) Delivered(entries, failures int64) {
+	if t == nil {
+		return
+	}
+	if entries > 0 {
+		t.delivered.Add(float64(entries))
+	}
+	if failures > 0 {
+		t.failed.Add(float64(failures))
+	}
+}
+
+// ObserveLatency records the time it took to hand a batch to the destination.
+func (t *Test) ObserveLatency(latency time.Duration) {
+	if t != nil {
+		t.latency.Observe(latency.Seconds())
+	}
+}
+
+// Finish records the end of the test. Its series are kept until the label is
+// needed by another test.
+func (t *Test) Finish() {
+	if t == nil {
+		return
+	}
+	t.finishOnce.Do(func() {
+		e := t.engine
+		e.mu.Lock()
+		defer e.mu.Unlock()
+		delete(e.running, t)
+		e.workers.WithLabelValues(t.testID, t.destination).Sub(t.workers)
+		e.activeTests.Dec()
+		e.releaseLocked(t.testID)
+	})
+}
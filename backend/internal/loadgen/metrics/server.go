@@ -0,0 +1,32 @@
+// backend/internal/loadgen/metrics/server.go
+
+package metrics
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/AkshayDubey29/MoniFlux/backend/internal/common"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// NewServer returns the server exposing the metrics of the default Prometheus
+// registry on the configured port and endpoint, or nil if Prometheus metrics are
+// disabled.
+func NewServer(cfg common.Metrics) *http.Server {
+	if !cfg.PrometheusEnabled {
+		return nil
+	}
+	endpoint := cfg.PrometheusEndpoint
+	if endpoint == "" {
+		endpoint = "/metrics"
+	}
+	mux := http.NewServeMux()
+	mux.Handle(endpoint, promhttp.Handler())
+	return &http.Server{
+		Addr:              ":" + strconv.Itoa(cfg.PrometheusPort),
+		Handler:           mux,
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+}
@@ -0,0 +1,107 @@
+// backend/internal/services/audit/diff.go
+
+package audit
+
+import (
+	"encoding/json"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// redacted replaces the value of sensitive fields in recorded changes.
+const redacted = "[REDACTED]"
+
+// sensitiveFieldNames are substrings of field names whose values are never recorded.
+var sensitiveFieldNames = []string{"apikey", "password", "secret", "token", "authorization"}
+
+// ignoredFields change on every write and carry no information about the action.
+var ignoredFields = map[string]bool{"updatedAt": true}
+
+// Diff compares the JSON representation of before and after and returns the fields
+// that differ, ordered by field path. Nested objects are compared field by field;
+// arrays are compared as a whole. Values of sensitive fields are redacted.
+func Diff(before, after interface{}) []FieldChange {
+	beforeFields := flatten(before)
+	afterFields := flatten(after)
+
+	fields := map[string]bool{}
+	for field := range beforeFields {
+		fields[field] = true
+	}
+	for field := range afterFields {
+		fields[field] = true
+	}
+
+	changes := []FieldChange{}
+	for field := range fields {
+		if ignoredFields[field] {
+			continue
+		}
+		b, a := beforeFields[field], afterFields[field]
+		if reflect.DeepEqual(b, a) {
+			continue
+		}
+		if isSensitiveField(field) {
+			if b != nil {
+				b = redacted
+			}
+			if a != nil {
+				a = redacted
+			}
+		}
+		changes = append(changes, FieldChange{Field: field, Before: b, After: a})
+	}
+
+	sort.Slice(changes, func(i, j int) bool { return changes[i].Field < changes[j].Field })
+	return changes
+}
+
+// flatten returns the leaf values of the JSON representation of v keyed by their
+// dotted path. A nil value has no fields.
+func flatten(v interface{}) map[string]interface{} {
+	fields := map[string]interface{}{}
+	if v == nil || (reflect.ValueOf(v).Kind() == reflect.Ptr && reflect.ValueOf(v).IsNil()) {
+		return fields
+	}
+
+	raw, err := json.Marshal(v)
+	if err != nil {
+		return fields
+	}
+	var decoded interface{}
+	if err := json.Unmarshal(raw, &decoded); err != nil {
+		return fields
+	}
+
+	var walk func(prefix string, value interface{})
+	walk = func(prefix string, value interface{}) {
+		object, ok := value.(map[string]interface{})
+		if !ok {
+			if prefix != "" {
+				fields[prefix] = value
+			}
+			return
+		}
+		for key, child := range object {
+			path := key
+			if prefix != "" {
+				path = prefix + "." + key
+			}
+			walk(path, child)
+		}
+	}
+	walk("", decoded)
+	return fields
+}
+
+// isSensitiveField reports whether any segment of the field path names a secret.
+func isSensitiveField(field string) bool {
+	lower := strings.ToLower(field)
+	for _, name := range sensitiveFieldNames {
+		if strings.Contains(lower, name) {
+			return true
+		}
+	}
+	return false
+}
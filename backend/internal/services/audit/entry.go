@@ -0,0 +1,120 @@
+// backend/internal/services/audit/entry.go
+
+package audit
+
+import (
+	"context"
+	"sync"
+
+	"github.com/AkshayDubey29/MoniFlux/backend/internal/api/models"
+)
+
+type contextKey string
+
+const entryContextKey contextKey = "auditEntry"
+
+// Entry collects the audit event of a request while it is being handled. The audit
+// middleware creates it and records it once the response is written; handlers enrich
+// it with the action, target and state change. All methods are safe on a nil Entry,
+// so handlers need not check whether the request is audited.
+type Entry struct {
+	mu    sync.Mutex
+	event Event
+}
+
+// NewEntry returns an entry for a request.
+func NewEntry(event Event) *Entry {
+	return &Entry{event: event}
+}
+
+// ContextWithEntry returns a copy of ctx carrying the entry.
+func ContextWithEntry(ctx context.Context, entry *Entry) context.Context {
+	return context.WithValue(ctx, entryContextKey, entry)
+}
+
+// EntryFromContext returns the entry of the request, or nil if it is not audited.
+func EntryFromContext(ctx context.Context) *Entry {
+	entry, _ := ctx.Value(entryContextKey).(*Entry)
+	return entry
+}
+
+// SetActor records the authenticated principal as the actor.
+func (e *Entry) SetActor(principal *models.Principal) {
+	if e == nil || principal == nil {
+		return
+	}
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.event.Actor = Actor{
+		UserID:   principal.UserID,
+		Username: principal.Username,
+		APIKeyID: principal.APIKeyID,
+		Roles:    principal.Roles,
+	}
+}
+
+// SetActorName records the username claimed by an unauthenticated request, such as
+// a login attempt. It does not override an authenticated actor.
+func (e *Entry) SetActorName(username string) {
+	if e == nil {
+		return
+	}
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if e.event.Actor.UserID == "" {
+		e.event.Actor.Username = username
+	}
+}
+
+// SetAction names the action, e.g. "test.start".
+func (e *Entry) SetAction(action string) {
+	if e == nil {
+		return
+	}
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.event.Action = action
+}
+
+// SetTarget records the resource the action applies to.
+func (e *Entry) SetTarget(targetType, targetID string) {
+	if e == nil {
+		return
+	}
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.event.TargetType = targetType
+	e.event.TargetID = targetID
+}
+
+// AddMetadata records an action specific detail.
+func (e *Entry) AddMetadata(key, value string) {
+	if e == nil || value == "" {
+		return
+	}
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if e.event.Metadata == nil {
+		e.event.Metadata = map[string]string{}
+	}
+	e.event.Metadata[key] = value
+}
+
+// RecordChange records the difference between the state of the target before and
+// after the action. Either may be nil, e.g. for creations.
+func (e *Entry) RecordChange(before, after interface{}) {
+	if e == nil {
+		return
+	}
+	changes := Diff(before, after)
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.event.Changes = changes
+}
+
+// Event returns a copy of the collected event.
+func (e *Entry) Event() Event {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.event
+}
@@ -0,0 +1,74 @@
+// backend/internal/services/audit/models.go
+
+package audit
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Outcomes of an audited action.
+const (
+	OutcomeSuccess = "success"
+	OutcomeFailure = "failure"
+	OutcomeDenied  = "denied"
+)
+
+// Actor identifies who performed an audited action.
+type Actor struct {
+	UserID   string   `bson:"userID,omitempty" json:"userID,omitempty"`
+	Username string   `bson:"username,omitempty" json:"username,omitempty"`
+	APIKeyID string   `bson:"apiKeyID,omitempty" json:"apiKeyID,omitempty"`
+	Roles    []string `bson:"roles,omitempty" json:"roles,omitempty"`
+}
+
+// FieldChange is a single field that differs between the before and after state of
+// the target. Field is the dotted JSON path of the field.
+type FieldChange struct {
+	Field  string      `bson:"field" json:"field"`
+	Before interface{} `bson:"before,omitempty" json:"before,omitempty"`
+	After  interface{} `bson:"after,omitempty" json:"after,omitempty"`
+}
+
+// Event is an entry of the audit log. Events are only ever inserted, never updated
+// or deleted by the API.
+type Event struct {
+	ID           primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	Timestamp    time.Time          `bson:"timestamp" json:"timestamp"`
+	RequestID    string             `bson:"requestID,omitempty" json:"requestID,omitempty"`
+	Actor        Actor              `bson:"actor" json:"actor"`
+	Action       string             `bson:"action" json:"action"`
+	Method       string             `bson:"method" json:"method"`
+	Path         string             `bson:"path" json:"path"`
+	TargetType   string             `bson:"targetType,omitempty" json:"targetType,omitempty"`
+	TargetID     string             `bson:"targetID,omitempty" json:"targetID,omitempty"`
+	Params       map[string]string  `bson:"params,omitempty" json:"params,omitempty"`     // Route variables of the request
+	Metadata     map[string]string  `bson:"metadata,omitempty" json:"metadata,omitempty"` // Action specific details, e.g. the destination endpoint
+	SourceIP     string             `bson:"sourceIP" json:"sourceIP"`
+	ForwardedFor string             `bson:"forwardedFor,omitempty" json:"forwardedFor,omitempty"` // Raw X-Forwarded-For header; client supplied
+	UserAgent    string             `bson:"userAgent,omitempty" json:"userAgent,omitempty"`
+	Changes      []FieldChange      `bson:"changes,omitempty" json:"changes,omitempty"`
+	Outcome      string             `bson:"outcome" json:"outcome"`
+	StatusCode   int                `bson:"statusCode" json:"statusCode"`
+	DurationMs   int64              `bson:"durationMs" json:"durationMs"`
+}
+
+// Query filters the audit log. Events are returned newest first.
+type Query struct {
+	UserID    string    `validate:"omitempty"`
+	Action    string    `validate:"omitempty"`
+	TargetID  string    `validate:"omitempty"`
+	RequestID string    `validate:"omitempty"`
+	Outcome   string    `validate:"omitempty,oneof=success failure denied"`
+	Since     time.Time `validate:"omitempty"`
+	Until     time.Time `validate:"omitempty"`
+	Limit     int       `validate:"omitempty,min=1,max=500"`
+	Cursor    string    `validate:"omitempty"` // ID of the last event of the previous page
+}
+
+// EventPage is a page of audit events.
+type EventPage struct {
+	Events     []Event `json:"events"`
+	NextCursor string  `json:"nextCursor,omitempty"`
+}
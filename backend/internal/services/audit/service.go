@@ -0,0 +1,160 @@
+// backend/internal/services/audit/service.go
+
+package audit
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/AkshayDubey29/MoniFlux/backend/internal/api/models"
+	"github.com/AkshayDubey29/MoniFlux/backend/internal/common"
+	"github.com/sirupsen/logrus"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const (
+	defaultQueryLimit = 100
+
+	// recordTimeout bounds writing an event. Events are written with a context of
+	// their own so that a client disconnecting does not drop its audit record.
+	recordTimeout = 5 * time.Second
+)
+
+// AuditService records mutating API actions in the append-only audit_events
+// collection and, optionally, in an NDJSON file sink.
+type AuditService struct {
+	config          *common.Config
+	logger          *logrus.Logger
+	eventCollection *mongo.Collection
+	sink            *FileSink
+}
+
+// NewAuditService creates a new instance of AuditService. The file sink is opened
+// when Config.Audit.FilePath is set.
+func NewAuditService(cfg *common.Config, logger *logrus.Logger, mongoClient *mongo.Client) (*AuditService, error) {
+	as := &AuditService{
+		config:          cfg,
+		logger:          logger,
+		eventCollection: mongoClient.Database(cfg.MongoDB).Collection("audit_events"),
+	}
+
+	if cfg.Audit.FilePath != "" {
+		sink, err := NewFileSink(cfg.Audit.FilePath)
+		if err != nil {
+			return nil, err
+		}
+		as.sink = sink
+		logger.Infof("Audit events are also written to %s", cfg.Audit.FilePath)
+	}
+
+	return as, nil
+}
+
+// Record appends the event to the audit log. A failure to write one destination does
+// not prevent writing the other; the first error is returned.
+func (as *AuditService) Record(event *Event) error {
+	if event.ID.IsZero() {
+		event.ID = primitive.NewObjectID()
+	}
+	if event.Timestamp.IsZero() {
+		event.Timestamp = time.Now().UTC()
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), recordTimeout)
+	defer cancel()
+
+	var firstErr error
+	if _, err := as.eventCollection.InsertOne(ctx, event); err != nil {
+		as.logger.Errorf("Failed to record audit event %s for request %s: %v", event.Action, event.RequestID, err)
+		firstErr = errors.New("internal server error")
+	}
+	if as.sink != nil {
+		if err := as.sink.Write(event); err != nil {
+			as.logger.Errorf("Failed to write audit event %s to the file sink: %v", event.Action, err)
+			if firstErr == nil {
+				firstErr = errors.New("internal server error")
+			}
+		}
+	}
+	return firstErr
+}
+
+// Query returns a page of audit events matching the query, newest first.
+func (as *AuditService) Query(ctx context.Context, query *Query) (*EventPage, error) {
+	filter := bson.M{}
+	if query.UserID != "" {
+		filter["actor.userID"] = query.UserID
+	}
+	if query.Action != "" {
+		filter["action"] = query.Action
+	}
+	if query.TargetID != "" {
+		filter["targetID"] = query.TargetID
+	}
+	if query.RequestID != "" {
+		filter["requestID"] = query.RequestID
+	}
+	if query.Outcome != "" {
+		filter["outcome"] = query.Outcome
+	}
+
+	timeRange := bson.M{}
+	if !query.Since.IsZero() {
+		timeRange["$gte"] = query.Since
+	}
+	if !query.Until.IsZero() {
+		timeRange["$lt"] = query.Until
+	}
+	if len(timeRange) > 0 {
+		filter["timestamp"] = timeRange
+	}
+
+	// Event IDs are generated in insertion order, so they double as the page cursor.
+	if query.Cursor != "" {
+		cursorID, err := primitive.ObjectIDFromHex(query.Cursor)
+		if err != nil {
+			return nil, models.ErrInvalidCursor
+		}
+		filter["_id"] = bson.M{"$lt": cursorID}
+	}
+
+	limit := query.Limit
+	if limit <= 0 {
+		limit = defaultQueryLimit
+	}
+
+	opts := options.Find().
+		SetSort(bson.D{{Key: "_id", Value: -1}}).
+		SetLimit(int64(limit) + 1)
+	cursor, err := as.eventCollection.Find(ctx, filter, opts)
+	if err != nil {
+		as.logger.Errorf("Error querying audit events: %v", err)
+		return nil, errors.New("internal server error")
+	}
+	defer cursor.Close(ctx)
+
+	events := []Event{}
+	if err := cursor.All(ctx, &events); err != nil {
+		as.logger.Errorf("Error decoding audit events: %v", err)
+		return nil, errors.New("internal server error")
+	}
+
+	page := &EventPage{Events: events}
+	if len(events) > limit {
+		page.Events = events[:limit]
+		page.NextCursor = page.Events[limit-1].ID.Hex()
+	}
+	return page, nil
+}
+
+// Close closes the file sink, if any.
+func (as *AuditService) Close() error {
+	if as.sink == nil {
+		return nil
+	}
+	return as.sink.Close()
+}
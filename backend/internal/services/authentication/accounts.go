@@ -0,0 +1,429 @@
+// backend/internal/services/authentication/accounts.go
+
+package authentication
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/AkshayDubey29/MoniFlux/backend/internal/api/models"
+	"github.com/AkshayDubey29/MoniFlux/backend/internal/services/email"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const (
+	purposePasswordReset     = "password_reset"
+	purposeEmailVerification = "email_verification"
+
+	accountTokenLength = 40
+
+	defaultLockoutDuration         = 15 * time.Minute
+	defaultResetTokenExpiry        = time.Hour
+	defaultVerificationTokenExpiry = 48 * time.Hour
+
+	revokeReasonPasswordChange = "password changed"
+	revokeReasonDisabled       = "account disabled"
+)
+
+// Custom errors
+var (
+	ErrUserExists             = errors.New("user already exists")
+	ErrUserNotFound           = errors.New("user not found")
+	ErrInvalidCredentials     = errors.New("invalid username or password")
+	ErrAccountLocked          = errors.New("account is temporarily locked after too many failed logins")
+	ErrAccountDisabled        = errors.New("account is disabled")
+	ErrInvalidAccountToken    = errors.New("invalid or expired token")
+	ErrPasswordManagedBySSO   = errors.New("password is managed by the identity provider")
+	ErrPasswordReused         = errors.New("new password must differ from the current password")
+	ErrEmailAlreadyVerified   = errors.New("email address is already verified")
+	ErrEmailSenderUnavailable = errors.New("email delivery failed")
+)
+
+// accountToken is a single-use token for a password reset or email verification.
+// Only its hash is stored; entries are removed by a TTL index once ExpiresAt has passed.
+type accountToken struct {
+	TokenHash string    `bson:"tokenHash"`
+	UserID    string    `bson:"userID"`
+	Purpose   string    `bson:"purpose"`
+	Email     string    `bson:"email,omitempty"` // Address being verified
+	CreatedAt time.Time `bson:"createdAt"`
+	ExpiresAt time.Time `bson:"expiresAt"`
+}
+
+// SetEmailSender replaces the sender used for account emails, e.g. with an email.Outbox in tests.
+func (as *AuthenticationService) SetEmailSender(sender email.Sender) {
+	as.mailer = sender
+}
+
+// parsePolicyDuration parses a duration from the account policy, falling back to def.
+func parsePolicyDuration(value string, def time.Duration) time.Duration {
+	if d, err := time.ParseDuration(value); err == nil && d > 0 {
+		return d
+	}
+	return def
+}
+
+// userObjectID parses a user ID, mapping malformed IDs to ErrUserNotFound.
+func userObjectID(userID string) (primitive.ObjectID, error) {
+	objID, err := primitive.ObjectIDFromHex(userID)
+	if err != nil {
+		return primitive.NilObjectID, ErrUserNotFound
+	}
+	return objID, nil
+}
+
+// GetProfile returns the API representation of the user.
+func (as *AuthenticationService) GetProfile(ctx context.Context, userID string) (*models.UserProfile, error) {
+	user, err := as.GetUserByID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	roles, err := as.GetRoleNames(ctx, user.Roles)
+	if err != nil {
+		return nil, err
+	}
+	if roles == nil {
+		roles = []string{}
+	}
+	return &models.UserProfile{
+		ID:            user.ID.Hex(),
+		Username:      user.Username,
+		Email:         user.Email,
+		EmailVerified: user.EmailVerified,
+		Roles:         roles,
+		SSO:           user.OIDCSubject != "",
+		CreatedAt:     user.CreatedAt,
+		PasswordSetAt: user.PasswordChangedAt,
+	}, nil
+}
+
+// UpdateEmail changes the user's email address. The new address must be verified again.
+func (as *AuthenticationService) UpdateEmail(ctx context.Context, userID, address string) error {
+	user, err := as.GetUserByID(ctx, userID)
+	if err != nil {
+		return err
+	}
+	if strings.EqualFold(user.Email, address) {
+		return nil
+	}
+
+	_, err = as.userCollection.UpdateOne(ctx, bson.M{"_id": user.ID},
+		bson.M{"$set": bson.M{"email": address, "emailVerified": false, "updated_at": time.Now()}})
+	if err != nil {
+		as.logger.Errorf("Failed to update email of user %s: %v", user.Username, err)
+		return errors.New("internal server error")
+	}
+	as.logger.Infof("Email of user %s changed", user.Username)
+
+	user.Email, user.EmailVerified = address, false
+	return as.SendVerificationEmail(ctx, user)
+}
+
+// ChangePassword changes the password of a local account after checking the current
+// password. All sessions of the user are ended.
+func (as *AuthenticationService) ChangePassword(ctx context.Context, userID, currentPassword, newPassword string) error {
+	user, err := as.GetUserByID(ctx, userID)
+	if err != nil {
+		return err
+	}
+	if user.Password == "" {
+		return ErrPasswordManagedBySSO
+	}
+	if !CheckPasswordHash(currentPassword, user.Password) {
+		return ErrInvalidCredentials
+	}
+	if currentPassword == newPassword {
+		return ErrPasswordReused
+	}
+	return as.setPassword(ctx, user, newPassword)
+}
+
+// setPassword validates and stores a new password, clears any lockout and ends all
+// sessions of the user.
+func (as *AuthenticationService) setPassword(ctx context.Context, user *models.User, newPassword string) error {
+	if err := ValidatePassword(as.config.Accounts, user.Username, newPassword); err != nil {
+		return err
+	}
+	hashed, err := HashPassword(newPassword)
+	if err != nil {
+		return err
+	}
+
+	_, err = as.userCollection.UpdateOne(ctx, bson.M{"_id": user.ID}, bson.M{
+		"$set":   bson.M{"password": hashed, "passwordChangedAt": time.Now(), "failedLoginAttempts": 0, "updated_at": time.Now()},
+		"$unset": bson.M{"lockedUntil": ""},
+	})
+	if err != nil {
+		as.logger.Errorf("Failed to update password of user %s: %v", user.Username, err)
+		return errors.New("internal server error")
+	}
+
+	as.RevokeUserSessions(ctx, user.ID.Hex(), revokeReasonPasswordChange)
+	as.logger.Infof("Password of user %s changed", user.Username)
+	return nil
+}
+
+// CreatePasswordReset issues a password reset token for the user and emails it to them.
+// It is initiated by an administrator; the token is never returned to the caller.
+func (as *AuthenticationService) CreatePasswordReset(ctx context.Context, userID string) (time.Time, error) {
+	user, err := as.GetUserByID(ctx, userID)
+	if err != nil {
+		return time.Time{}, err
+	}
+	if user.Password == "" {
+		return time.Time{}, ErrPasswordManagedBySSO
+	}
+
+	ttl := parsePolicyDuration(as.config.Accounts.ResetTokenExpiry, defaultResetTokenExpiry)
+	token, expiresAt, err := as.issueAccountToken(ctx, user.ID.Hex(), purposePasswordReset, "", ttl)
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	link := as.accountLink("/reset-password", token)
+	body := fmt.Sprintf("Hello %s,\n\nAn administrator has started a password reset for your MoniFlux account.\n"+
+		"Choose a new password here:\n\n%s\n\nThe link expires at %s.\n",
+		user.Username, link, expiresAt.UTC().Format(time.RFC1123))
+	if err := as.sendEmail(ctx, user.Email, "Reset your MoniFlux password", body); err != nil {
+		return time.Time{}, err
+	}
+
+	as.logger.Infof("Password reset issued for user %s", user.Username)
+	return expiresAt, nil
+}
+
+// ResetPassword sets a new password using a reset token.
+func (as *AuthenticationService) ResetPassword(ctx context.Context, token, newPassword string) error {
+	// The new password is checked against the policy before the token is consumed,
+	// so a rejected password does not burn the reset link.
+	var pending accountToken
+	err := as.accountTokenCollection.FindOne(ctx, bson.M{"tokenHash": hashToken(token), "purpose": purposePasswordReset}).Decode(&pending)
+	if err != nil || time.Now().After(pending.ExpiresAt) {
+		return ErrInvalidAccountToken
+	}
+	user, err := as.GetUserByID(ctx, pending.UserID)
+	if err != nil {
+		return ErrInvalidAccountToken
+	}
+	if err := ValidatePassword(as.config.Accounts, user.Username, newPassword); err != nil {
+		return err
+	}
+
+	if _, err := as.redeemAccountToken(ctx, token, purposePasswordReset); err != nil {
+		return err
+	}
+	return as.setPassword(ctx, user, newPassword)
+}
+
+// SendVerificationEmail emails the user a link that verifies their current address.
+func (as *AuthenticationService) SendVerificationEmail(ctx context.Context, user *models.User) error {
+	if user.EmailVerified {
+		return ErrEmailAlreadyVerified
+	}
+
+	ttl := parsePolicyDuration(as.config.Accounts.VerificationTokenExpiry, defaultVerificationTokenExpiry)
+	token, expiresAt, err := as.issueAccountToken(ctx, user.ID.Hex(), purposeEmailVerification, user.Email, ttl)
+	if err != nil {
+		return err
+	}
+
+	link := as.accountLink("/verify-email", token)
+	body := fmt.Sprintf("Hello %s,\n\nPlease confirm the email address of your MoniFlux account:\n\n%s\n\nThe link expires at %s.\n",
+		user.Username, link, expiresAt.UTC().Format(time.RFC1123))
+	return as.sendEmail(ctx, user.Email, "Verify your MoniFlux email address", body)
+}
+
+// VerifyEmail marks the address the token was issued for as verified.
+func (as *AuthenticationService) VerifyEmail(ctx context.Context, token string) error {
+	pending, err := as.redeemAccountToken(ctx, token, purposeEmailVerification)
+	if err != nil {
+		return err
+	}
+	objID, err := userObjectID(pending.UserID)
+	if err != nil {
+		return ErrInvalidAccountToken
+	}
+
+	// The address must not have changed since the token was issued.
+	result, err := as.userCollection.UpdateOne(ctx, bson.M{"_id": objID, "email": pending.Email},
+		bson.M{"$set": bson.M{"emailVerified": true, "updated_at": time.Now()}})
+	if err != nil {
+		as.logger.Errorf("Failed to verify email of user %s: %v", pending.UserID, err)
+		return errors.New("internal server error")
+	}
+	if result.MatchedCount == 0 {
+		return ErrInvalidAccountToken
+	}
+
+	as.logger.Infof("Email of user %s verified", pending.UserID)
+	return nil
+}
+
+// SetUserDisabled disables or re-enables a user. Disabling ends all sessions of the
+// user; enabling also clears any login lockout.
+func (as *AuthenticationService) SetUserDisabled(ctx context.Context, userID string, disabled bool) error {
+	objID, err := userObjectID(userID)
+	if err != nil {
+		return err
+	}
+
+	update := bson.M{"$set": bson.M{"disabled": disabled, "updated_at": time.Now()}}
+	if !disabled {
+		update["$set"].(bson.M)["failedLoginAttempts"] = 0
+		update["$unset"] = bson.M{"lockedUntil": ""}
+	}
+	result, err := as.userCollection.UpdateOne(ctx, bson.M{"_id": objID}, update)
+	if err != nil {
+		as.logger.Errorf("Failed to update user %s: %v", userID, err)
+		return errors.New("internal server error")
+	}
+	if result.MatchedCount == 0 {
+		return ErrUserNotFound
+	}
+
+	if disabled {
+		as.RevokeUserSessions(ctx, userID, revokeReasonDisabled)
+	}
+	as.logger.Infof("User %s disabled=%t", userID, disabled)
+	return nil
+}
+
+// RevokeUserSessions revokes every active session of the user.
+func (as *AuthenticationService) RevokeUserSessions(ctx context.Context, userID, reason string) {
+	cursor, err := as.sessionCollection.Find(ctx, bson.M{"userID": userID, "revokedAt": nil})
+	if err != nil {
+		as.logger.Errorf("Failed to list sessions of user %s: %v", userID, err)
+		return
+	}
+	var sessions []Session
+	if err := cursor.All(ctx, &sessions); err != nil {
+		as.logger.Errorf("Failed to decode sessions of user %s: %v", userID, err)
+		return
+	}
+	for i := range sessions {
+		as.revokeSession(ctx, &sessions[i], reason)
+	}
+}
+
+// checkLoginAllowed returns an error if the user may not log in right now.
+func (as *AuthenticationService) checkLoginAllowed(user *models.User) error {
+	if user.Disabled {
+		return ErrAccountDisabled
+	}
+	if user.LockedUntil != nil && time.Now().Before(*user.LockedUntil) {
+		return ErrAccountLocked
+	}
+	return nil
+}
+
+// recordFailedLogin counts a failed login and locks the account once
+// Accounts.MaxFailedLogins consecutive failures have been reached.
+func (as *AuthenticationService) recordFailedLogin(ctx context.Context, user *models.User) {
+	maxFailures := as.config.Accounts.MaxFailedLogins
+	if maxFailures <= 0 {
+		return
+	}
+
+	var updated models.User
+	err := as.userCollection.FindOneAndUpdate(ctx, bson.M{"_id": user.ID},
+		bson.M{"$inc": bson.M{"failedLoginAttempts": 1}},
+		options.FindOneAndUpdate().SetReturnDocument(options.After)).Decode(&updated)
+	if err != nil {
+		as.logger.Errorf("Failed to record failed login of user %s: %v", user.Username, err)
+		return
+	}
+
+	if updated.FailedLoginAttempts >= maxFailures {
+		lockedUntil := time.Now().Add(parsePolicyDuration(as.config.Accounts.LockoutDuration, defaultLockoutDuration))
+		_, err := as.userCollection.UpdateOne(ctx, bson.M{"_id": user.ID},
+			bson.M{"$set": bson.M{"lockedUntil": lockedUntil, "failedLoginAttempts": 0}})
+		if err != nil {
+			as.logger.Errorf("Failed to lock user %s: %v", user.Username, err)
+			return
+		}
+		as.logger.Warnf("User %s locked until %s after %d failed logins", user.Username, lockedUntil.Format(time.RFC3339), maxFailures)
+	}
+}
+
+// resetFailedLogins clears the failed login counter after a successful login.
+func (as *AuthenticationService) resetFailedLogins(ctx context.Context, user *models.User) {
+	if user.FailedLoginAttempts == 0 && user.LockedUntil == nil {
+		return
+	}
+	_, err := as.userCollection.UpdateOne(ctx, bson.M{"_id": user.ID},
+		bson.M{"$set": bson.M{"failedLoginAttempts": 0}, "$unset": bson.M{"lockedUntil": ""}})
+	if err != nil {
+		as.logger.Errorf("Failed to reset failed logins of user %s: %v", user.Username, err)
+	}
+}
+
+// issueAccountToken stores a new single-use token for the purpose, replacing any
+// earlier token of the same purpose for the user.
+func (as *AuthenticationService) issueAccountToken(ctx context.Context, userID, purpose, address string, ttl time.Duration) (string, time.Time, error) {
+	token, err := randomString(accountTokenLength)
+	if err != nil {
+		return "", time.Time{}, err
+	}
+
+	if _, err := as.accountTokenCollection.DeleteMany(ctx, bson.M{"userID": userID, "purpose": purpose}); err != nil {
+		as.logger.Errorf("Failed to delete earlier %s tokens of user %s: %v", purpose, userID, err)
+		return "", time.Time{}, errors.New("internal server error")
+	}
+
+	now := time.Now()
+	record := accountToken{
+		TokenHash: hashToken(token),
+		UserID:    userID,
+		Purpose:   purpose,
+		Email:     address,
+		CreatedAt: now,
+		ExpiresAt: now.Add(ttl),
+	}
+	if _, err := as.accountTokenCollection.InsertOne(ctx, record); err != nil {
+		as.logger.Errorf("Failed to store %s token of user %s: %v", purpose, userID, err)
+		return "", time.Time{}, errors.New("internal server error")
+	}
+	return token, record.ExpiresAt, nil
+}
+
+// redeemAccountToken consumes a token of the given purpose.
+func (as *AuthenticationService) redeemAccountToken(ctx context.Context, token, purpose string) (*accountToken, error) {
+	var record accountToken
+	err := as.accountTokenCollection.FindOneAndDelete(ctx, bson.M{"tokenHash": hashToken(token), "purpose": purpose}).Decode(&record)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, ErrInvalidAccountToken
+		}
+		as.logger.Errorf("Failed to redeem %s token: %v", purpose, err)
+		return nil, errors.New("internal server error")
+	}
+	if time.Now().After(record.ExpiresAt) {
+		return nil, ErrInvalidAccountToken
+	}
+	return &record, nil
+}
+
+// accountLink builds a link to the public URL carrying the token.
+func (as *AuthenticationService) accountLink(path, token string) string {
+	return strings.TrimSuffix(as.config.Accounts.PublicURL, "/") + path + "?token=" + url.QueryEscape(token)
+}
+
+// sendEmail delivers an account email through the configured sender.
+func (as *AuthenticationService) sendEmail(ctx context.Context, to, subject, body string) error {
+	if as.mailer == nil {
+		return ErrEmailSenderUnavailable
+	}
+	err := as.mailer.Send(ctx, email.Message{To: to, From: as.config.Email.From, Subject: subject, Body: body})
+	if err != nil {
+		as.logger.Errorf("Failed to send %q to %s: %v", subject, to, err)
+		return fmt.Errorf("%w: %v", ErrEmailSenderUnavailable, err)
+	}
+	return nil
+}
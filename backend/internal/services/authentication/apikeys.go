@@ -0,0 +1,178 @@
+// backend/internal/services/authentication/apikeys.go
+
+package authentication
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+	"time"
+
+	"github.com/AkshayDubey29/MoniFlux/backend/internal/api/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const (
+	// APIKeyPrefix marks MoniFlux API keys so they can be told apart from JWTs.
+	APIKeyPrefix = "mfx_"
+
+	apiKeyIDLength     = 8
+	apiKeySecretLength = 40
+	apiKeyAlphabet     = "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789"
+
+	// lastUsedResolution bounds how often the last-used timestamp is written per key.
+	lastUsedResolution = time.Minute
+)
+
+// Custom errors
+var (
+	ErrInvalidAPIKey = errors.New("invalid API key")
+	ErrAPIKeyRevoked = errors.New("API key revoked")
+	ErrAPIKeyExpired = errors.New("API key expired")
+)
+
+// IsAPIKey reports whether the credential looks like a MoniFlux API key rather than a JWT.
+func IsAPIKey(credential string) bool {
+	return strings.HasPrefix(credential, APIKeyPrefix)
+}
+
+// randomString returns a cryptographically random string drawn from apiKeyAlphabet.
+func randomString(n int) (string, error) {
+	max := big.NewInt(int64(len(apiKeyAlphabet)))
+	b := make([]byte, n)
+	for i := range b {
+		idx, err := rand.Int(rand.Reader, max)
+		if err != nil {
+			return "", err
+		}
+		b[i] = apiKeyAlphabet[idx.Int64()]
+	}
+	return string(b), nil
+}
+
+// hashToken returns the hex-encoded SHA-256 digest of a key or token. These carry enough
+// entropy that a fast hash is sufficient and keeps per-request validation cheap.
+func hashToken(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
+
+// CreateAPIKey generates a new API key for the user. The plaintext key is returned
+// only once; only its hash is stored.
+func (as *AuthenticationService) CreateAPIKey(ctx context.Context, userID, name string, scopes []string, expiresAt *time.Time) (string, *models.APIKey, error) {
+	keyID, err := randomString(apiKeyIDLength)
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to generate API key: %w", err)
+	}
+	secret, err := randomString(apiKeySecretLength)
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to generate API key: %w", err)
+	}
+	plaintext := APIKeyPrefix + keyID + "_" + secret
+
+	key := &models.APIKey{
+		KeyID:     keyID,
+		Prefix:    APIKeyPrefix + keyID,
+		Hash:      hashToken(plaintext),
+		UserID:    userID,
+		Name:      name,
+		Scopes:    scopes,
+		ExpiresAt: expiresAt,
+		CreatedAt: time.Now(),
+	}
+
+	if _, err := as.apiKeyCollection.InsertOne(ctx, key); err != nil {
+		as.logger.Errorf("Failed to insert API key for user %s: %v", userID, err)
+		return "", nil, errors.New("internal server error")
+	}
+
+	as.logger.Infof("API key %s created for user %s", key.Prefix, userID)
+	return plaintext, key, nil
+}
+
+// ListAPIKeys returns the API keys belonging to the user, newest first.
+func (as *AuthenticationService) ListAPIKeys(ctx context.Context, userID string) ([]models.APIKey, error) {
+	opts := options.Find().SetSort(bson.D{{Key: "createdAt", Value: -1}})
+	cursor, err := as.apiKeyCollection.Find(ctx, bson.M{"userID": userID}, opts)
+	if err != nil {
+		as.logger.Errorf("Failed to list API keys for user %s: %v", userID, err)
+		return nil, errors.New("internal server error")
+	}
+	defer cursor.Close(ctx)
+
+	keys := []models.APIKey{}
+	if err := cursor.All(ctx, &keys); err != nil {
+		as.logger.Errorf("Failed to decode API keys: %v", err)
+		return nil, errors.New("internal server error")
+	}
+	return keys, nil
+}
+
+// RevokeAPIKey revokes an API key. Unless allowAnyOwner is set, the key must belong to the user.
+func (as *AuthenticationService) RevokeAPIKey(ctx context.Context, userID, keyID string, allowAnyOwner bool) error {
+	filter := bson.M{"keyID": keyID, "revokedAt": nil}
+	if !allowAnyOwner {
+		filter["userID"] = userID
+	}
+
+	result, err := as.apiKeyCollection.UpdateOne(ctx, filter, bson.M{"$set": bson.M{"revokedAt": time.Now()}})
+	if err != nil {
+		as.logger.Errorf("Failed to revoke API key %s: %v", keyID, err)
+		return errors.New("internal server error")
+	}
+	if result.MatchedCount == 0 {
+		return ErrInvalidAPIKey
+	}
+
+	as.logger.Infof("API key %s revoked by user %s", keyID, userID)
+	return nil
+}
+
+// ValidateAPIKey checks the key against its stored hash, revocation and expiry,
+// records its use and returns the stored key.
+func (as *AuthenticationService) ValidateAPIKey(ctx context.Context, plaintext string) (*models.APIKey, error) {
+	rest := strings.TrimPrefix(plaintext, APIKeyPrefix)
+	sep := strings.IndexByte(rest, '_')
+	if !IsAPIKey(plaintext) || sep != apiKeyIDLength {
+		return nil, ErrInvalidAPIKey
+	}
+	keyID := rest[:sep]
+
+	var key models.APIKey
+	err := as.apiKeyCollection.FindOne(ctx, bson.M{"keyID": keyID}).Decode(&key)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, ErrInvalidAPIKey
+		}
+		as.logger.Errorf("Error retrieving API key %s: %v", keyID, err)
+		return nil, errors.New("internal server error")
+	}
+
+	if subtle.ConstantTimeCompare([]byte(hashToken(plaintext)), []byte(key.Hash)) != 1 {
+		return nil, ErrInvalidAPIKey
+	}
+	if key.RevokedAt != nil {
+		return nil, ErrAPIKeyRevoked
+	}
+	now := time.Now()
+	if key.ExpiresAt != nil && now.After(*key.ExpiresAt) {
+		return nil, ErrAPIKeyExpired
+	}
+
+	if key.LastUsedAt == nil || now.Sub(*key.LastUsedAt) > lastUsedResolution {
+		if _, err := as.apiKeyCollection.UpdateOne(ctx, bson.M{"keyID": keyID}, bson.M{"$set": bson.M{"lastUsedAt": now}}); err != nil {
+			as.logger.Warnf("Failed to record use of API key %s: %v", key.Prefix, err)
+		}
+		key.LastUsedAt = &now
+	}
+
+	return &key, nil
+}
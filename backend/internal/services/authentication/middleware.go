@@ -7,6 +7,7 @@ import (
 	"net/http"
 	"strings"
 
+	"github.com/AkshayDubey29/MoniFlux/backend/internal/api/problem"
 	"github.com/AkshayDubey29/MoniFlux/backend/internal/common"
 	"github.com/sirupsen/logrus"
 )
@@ -31,24 +32,24 @@ func (am *AuthMiddleware) MiddlewareFunc(next http.Handler) http.Handler {
 		// Retrieve the Authorization header.
 		authHeader := r.Header.Get("Authorization")
 		if authHeader == "" {
-			http.Error(w, "Missing Authorization header", http.StatusUnauthorized)
+			problem.Error(w, http.StatusUnauthorized, problem.CodeUnauthorized, "Missing Authorization header")
 			return
 		}
 
 		// Expected format: "Bearer <token>"
 		parts := strings.SplitN(authHeader, " ", 2)
 		if len(parts) != 2 || strings.ToLower(parts[0]) != "bearer" {
-			http.Error(w, "Invalid Authorization header format", http.StatusUnauthorized)
+			problem.Error(w, http.StatusUnauthorized, problem.CodeUnauthorized, "Invalid Authorization header format")
 			return
 		}
 
 		tokenString := parts[1]
 
 		// Validate the JWT token using the AuthenticationService.
-		claims, err := am.authService.ValidateJWT(tokenString)
+		claims, err := am.authService.ValidateJWT(r.Context(), tokenString)
 		if err != nil {
 			am.logger.Errorf("Invalid JWT token: %v", err)
-			http.Error(w, "Invalid token", http.StatusUnauthorized)
+			problem.Error(w, http.StatusUnauthorized, problem.CodeUnauthorized, "Invalid token")
 			return
 		}
 
@@ -56,7 +57,7 @@ func (am *AuthMiddleware) MiddlewareFunc(next http.Handler) http.Handler {
 		user, err := am.authService.GetUserByID(r.Context(), claims.UserID)
 		if err != nil {
 			am.logger.Errorf("Failed to retrieve user from token: %v", err)
-			http.Error(w, "Invalid token", http.StatusUnauthorized)
+			problem.Error(w, http.StatusUnauthorized, problem.CodeUnauthorized, "Invalid token")
 			return
 		}
 
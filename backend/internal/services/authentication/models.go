@@ -3,8 +3,42 @@
 package authentication
 
 import (
+	"time"
+
 	"github.com/AkshayDubey29/MoniFlux/backend/internal/common"
 )
 
 // User represents a user in the system.
 type User = common.User
+
+// TokenPair is issued at login and on every refresh. The refresh token rotates on each use.
+type TokenPair struct {
+	AccessToken      string    `json:"token"`
+	RefreshToken     string    `json:"refreshToken"`
+	ExpiresAt        time.Time `json:"expiresAt"`
+	RefreshExpiresAt time.Time `json:"refreshExpiresAt"`
+}
+
+// Session represents a login session backed by a rotating refresh token.
+// Sessions are removed by a TTL index once ExpiresAt has passed.
+type Session struct {
+	SessionID        string     `json:"sessionID" bson:"sessionID"`
+	UserID           string     `json:"userID" bson:"userID"`
+	RefreshTokenHash string     `json:"-" bson:"refreshTokenHash"`
+	AccessTokenID    string     `json:"-" bson:"accessTokenID"` // jti of the most recently issued access token
+	Generation       int        `json:"generation" bson:"generation"`
+	CreatedAt        time.Time  `json:"createdAt" bson:"createdAt"`
+	LastRefreshedAt  time.Time  `json:"lastRefreshedAt" bson:"lastRefreshedAt"`
+	ExpiresAt        time.Time  `json:"expiresAt" bson:"expiresAt"`
+	RevokedAt        *time.Time `json:"revokedAt,omitempty" bson:"revokedAt,omitempty"`
+	RevokedReason    string     `json:"revokedReason,omitempty" bson:"revokedReason,omitempty"`
+}
+
+// RevokedToken records an access token that must be rejected before it expires.
+// Entries are removed by a TTL index once the token would have expired anyway.
+type RevokedToken struct {
+	TokenID   string    `bson:"jti"`
+	UserID    string    `bson:"userID"`
+	RevokedAt time.Time `bson:"revokedAt"`
+	ExpiresAt time.Time `bson:"expiresAt"`
+}
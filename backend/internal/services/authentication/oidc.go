@@ -0,0 +1,376 @@
+// backend/internal/services/authentication/oidc.go
+
+package authentication
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/AkshayDubey29/MoniFlux/backend/internal/common"
+	jwt "github.com/golang-jwt/jwt/v4"
+)
+
+// jwksRefreshInterval bounds how often the JWKS is re-fetched when an unknown key ID is seen.
+const jwksRefreshInterval = time.Minute
+
+// Custom errors
+var (
+	ErrUnsupportedAlgorithm = errors.New("unsupported token signing algorithm")
+	ErrUnknownSigningKey    = errors.New("unknown token signing key")
+	ErrNonceMismatch        = errors.New("ID token nonce does not match")
+)
+
+// supportedAlgorithms lists the asymmetric signing algorithms accepted from the IdP.
+var supportedAlgorithms = map[string]bool{
+	jwt.SigningMethodRS256.Alg(): true,
+	jwt.SigningMethodES256.Alg(): true,
+}
+
+// OIDCDiscovery holds the fields of the provider's discovery document that MoniFlux uses.
+type OIDCDiscovery struct {
+	Issuer                string `json:"issuer"`
+	AuthorizationEndpoint string `json:"authorization_endpoint"`
+	TokenEndpoint         string `json:"token_endpoint"`
+	JWKSURI               string `json:"jwks_uri"`
+}
+
+// OIDCIdentity is the identity asserted by a verified IdP token.
+type OIDCIdentity struct {
+	Issuer   string
+	Subject  string
+	Username string
+	Email    string
+	Groups   []string
+	TokenID  string
+	Expiry   time.Time
+}
+
+// jwk is a single JSON Web Key as served by the JWKS endpoint.
+type jwk struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+	Crv string `json:"crv"`
+	X   string `json:"x"`
+	Y   string `json:"y"`
+}
+
+// OIDCProvider verifies tokens issued by an OpenID Connect provider and performs
+// the authorization-code exchange. Signing keys are discovered through JWKS and
+// cached until a token with an unknown key ID is seen.
+type OIDCProvider struct {
+	config     common.OIDC
+	discovery  OIDCDiscovery
+	httpClient *http.Client
+
+	mu          sync.RWMutex
+	keys        map[string]interface{}
+	lastRefresh time.Time
+}
+
+// NewOIDCProvider fetches the provider's discovery document and signing keys.
+// A nil httpClient uses http.DefaultClient.
+func NewOIDCProvider(ctx context.Context, cfg common.OIDC, httpClient *http.Client) (*OIDCProvider, error) {
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+	if cfg.UsernameClaim == "" {
+		cfg.UsernameClaim = "preferred_username"
+	}
+	if cfg.GroupsClaim == "" {
+		cfg.GroupsClaim = "groups"
+	}
+	if len(cfg.Scopes) == 0 {
+		cfg.Scopes = []string{"openid", "profile", "email"}
+	}
+
+	p := &OIDCProvider{config: cfg, httpClient: httpClient, keys: map[string]interface{}{}}
+
+	discoveryURL := strings.TrimSuffix(cfg.IssuerURL, "/") + "/.well-known/openid-configuration"
+	if err := p.getJSON(ctx, discoveryURL, &p.discovery); err != nil {
+		return nil, fmt.Errorf("failed to fetch OIDC discovery document: %w", err)
+	}
+	if strings.TrimSuffix(p.discovery.Issuer, "/") != strings.TrimSuffix(cfg.IssuerURL, "/") {
+		return nil, fmt.Errorf("OIDC discovery issuer %q does not match configured issuer %q", p.discovery.Issuer, cfg.IssuerURL)
+	}
+	if p.discovery.JWKSURI == "" {
+		return nil, errors.New("OIDC discovery document has no jwks_uri")
+	}
+	if err := p.refreshKeys(ctx); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+// Issuer returns the issuer identifier of the provider.
+func (p *OIDCProvider) Issuer() string {
+	return p.discovery.Issuer
+}
+
+// getJSON fetches url and decodes its JSON body into out.
+func (p *OIDCProvider) getJSON(ctx context.Context, url string, out interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return err
+	}
+	resp, err := p.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("unexpected status %d from %s", resp.StatusCode, url)
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
+
+// refreshKeys re-fetches the JWKS and replaces the cached keys.
+func (p *OIDCProvider) refreshKeys(ctx context.Context) error {
+	var set struct {
+		Keys []jwk `json:"keys"`
+	}
+	if err := p.getJSON(ctx, p.discovery.JWKSURI, &set); err != nil {
+		return fmt.Errorf("failed to fetch JWKS: %w", err)
+	}
+
+	keys := make(map[string]interface{}, len(set.Keys))
+	for _, k := range set.Keys {
+		if k.Use != "" && k.Use != "sig" {
+			continue
+		}
+		key, err := k.publicKey()
+		if err != nil {
+			// Keys of unsupported types are skipped rather than failing the whole set.
+			continue
+		}
+		keys[k.Kid] = key
+	}
+
+	p.mu.Lock()
+	p.keys = keys
+	p.lastRefresh = time.Now()
+	p.mu.Unlock()
+	return nil
+}
+
+// publicKey converts the JWK into an *rsa.PublicKey or *ecdsa.PublicKey.
+func (k jwk) publicKey() (interface{}, error) {
+	switch k.Kty {
+	case "RSA":
+		n, err := base64.RawURLEncoding.DecodeString(k.N)
+		if err != nil {
+			return nil, err
+		}
+		e, err := base64.RawURLEncoding.DecodeString(k.E)
+		if err != nil {
+			return nil, err
+		}
+		return &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(new(big.Int).SetBytes(e).Int64())}, nil
+	case "EC":
+		if k.Crv != "P-256" {
+			return nil, fmt.Errorf("unsupported curve %s", k.Crv)
+		}
+		x, err := base64.RawURLEncoding.DecodeString(k.X)
+		if err != nil {
+			return nil, err
+		}
+		y, err := base64.RawURLEncoding.DecodeString(k.Y)
+		if err != nil {
+			return nil, err
+		}
+		return &ecdsa.PublicKey{Curve: elliptic.P256(), X: new(big.Int).SetBytes(x), Y: new(big.Int).SetBytes(y)}, nil
+	default:
+		return nil, fmt.Errorf("unsupported key type %s", k.Kty)
+	}
+}
+
+// signingKey returns the key for the token's key ID, refreshing the JWKS at most
+// once per jwksRefreshInterval when the key ID is unknown (e.g. after key rotation).
+func (p *OIDCProvider) signingKey(ctx context.Context, kid string) (interface{}, error) {
+	p.mu.RLock()
+	key, ok := p.keys[kid]
+	stale := time.Since(p.lastRefresh) > jwksRefreshInterval
+	p.mu.RUnlock()
+	if ok {
+		return key, nil
+	}
+	if !stale {
+		return nil, ErrUnknownSigningKey
+	}
+
+	if err := p.refreshKeys(ctx); err != nil {
+		return nil, err
+	}
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	if key, ok := p.keys[kid]; ok {
+		return key, nil
+	}
+	return nil, ErrUnknownSigningKey
+}
+
+// IsIssuedBy reports whether the unverified token claims to come from this provider.
+func (p *OIDCProvider) IsIssuedBy(tokenString string) bool {
+	claims := jwt.MapClaims{}
+	if _, _, err := jwt.NewParser().ParseUnverified(tokenString, claims); err != nil {
+		return false
+	}
+	iss, _ := claims["iss"].(string)
+	return iss != "" && iss == p.discovery.Issuer
+}
+
+// Verify validates the signature, issuer, audience and lifetime of a token issued by
+// the provider and returns the identity it asserts. A non-empty nonce must match the
+// token's nonce claim.
+func (p *OIDCProvider) Verify(ctx context.Context, tokenString, nonce string) (*OIDCIdentity, error) {
+	claims := jwt.MapClaims{}
+	parser := jwt.NewParser(jwt.WithValidMethods([]string{jwt.SigningMethodRS256.Alg(), jwt.SigningMethodES256.Alg()}))
+	_, err := parser.ParseWithClaims(tokenString, claims, func(token *jwt.Token) (interface{}, error) {
+		if !supportedAlgorithms[token.Method.Alg()] {
+			return nil, ErrUnsupportedAlgorithm
+		}
+		kid, _ := token.Header["kid"].(string)
+		return p.signingKey(ctx, kid)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if !claims.VerifyIssuer(p.discovery.Issuer, true) {
+		return nil, errors.New("token issuer mismatch")
+	}
+	if !claims.VerifyAudience(p.config.ClientID, true) {
+		return nil, errors.New("token audience mismatch")
+	}
+	if _, ok := claims["exp"]; !ok {
+		return nil, errors.New("token has no expiry")
+	}
+	if nonce != "" {
+		if got, _ := claims["nonce"].(string); got != nonce {
+			return nil, ErrNonceMismatch
+		}
+	}
+
+	identity := &OIDCIdentity{Issuer: p.discovery.Issuer}
+	identity.Subject, _ = claims["sub"].(string)
+	identity.Username, _ = claims[p.config.UsernameClaim].(string)
+	identity.Email, _ = claims["email"].(string)
+	identity.TokenID, _ = claims["jti"].(string)
+	if exp, ok := claims["exp"].(float64); ok {
+		identity.Expiry = time.Unix(int64(exp), 0)
+	}
+	if identity.Subject == "" {
+		return nil, errors.New("token has no subject")
+	}
+	if identity.Username == "" {
+		identity.Username = identity.Subject
+	}
+	switch groups := claims[p.config.GroupsClaim].(type) {
+	case []interface{}:
+		for _, g := range groups {
+			if s, ok := g.(string); ok {
+				identity.Groups = append(identity.Groups, s)
+			}
+		}
+	case string:
+		identity.Groups = []string{groups}
+	}
+
+	return identity, nil
+}
+
+// AuthCodeURL returns the provider URL that starts the authorization-code flow with
+// the given state, nonce and PKCE code challenge (S256).
+func (p *OIDCProvider) AuthCodeURL(state, nonce, codeChallenge string) string {
+	params := url.Values{
+		"response_type":         {"code"},
+		"client_id":             {p.config.ClientID},
+		"redirect_uri":          {p.config.RedirectURL},
+		"scope":                 {strings.Join(p.config.Scopes, " ")},
+		"state":                 {state},
+		"nonce":                 {nonce},
+		"code_challenge":        {codeChallenge},
+		"code_challenge_method": {"S256"},
+	}
+	sep := "?"
+	if strings.Contains(p.discovery.AuthorizationEndpoint, "?") {
+		sep = "&"
+	}
+	return p.discovery.AuthorizationEndpoint + sep + params.Encode()
+}
+
+// Exchange redeems an authorization code together with its PKCE verifier and returns
+// the raw ID token.
+func (p *OIDCProvider) Exchange(ctx context.Context, code, codeVerifier string) (string, error) {
+	form := url.Values{
+		"grant_type":    {"authorization_code"},
+		"code":          {code},
+		"redirect_uri":  {p.config.RedirectURL},
+		"client_id":     {p.config.ClientID},
+		"code_verifier": {codeVerifier},
+	}
+	if p.config.ClientSecret != "" {
+		form.Set("client_secret", p.config.ClientSecret)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, p.discovery.TokenEndpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := p.httpClient.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("token exchange failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	var body struct {
+		IDToken          string `json:"id_token"`
+		Error            string `json:"error"`
+		ErrorDescription string `json:"error_description"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return "", fmt.Errorf("failed to decode token response: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("token exchange failed: %s %s", body.Error, body.ErrorDescription)
+	}
+	if body.IDToken == "" {
+		return "", errors.New("token response contains no id_token")
+	}
+	return body.IDToken, nil
+}
+
+// NewPKCEVerifier returns a random PKCE code verifier and its S256 code challenge.
+func NewPKCEVerifier() (verifier, challenge string, err error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", "", err
+	}
+	verifier = base64.RawURLEncoding.EncodeToString(b)
+	return verifier, PKCEChallenge(verifier), nil
+}
+
+// PKCEChallenge returns the S256 code challenge for a code verifier.
+func PKCEChallenge(verifier string) string {
+	sum := sha256.Sum256([]byte(verifier))
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
@@ -0,0 +1,69 @@
+// backend/internal/services/authentication/password.go
+
+package authentication
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"unicode"
+
+	"github.com/AkshayDubey29/MoniFlux/backend/internal/common"
+)
+
+// bcryptMaxLength is the number of password bytes bcrypt takes into account.
+const bcryptMaxLength = 72
+
+// ErrWeakPassword is returned when a password does not satisfy the password policy.
+var ErrWeakPassword = errors.New("password does not meet the password policy")
+
+// ValidatePassword checks the password against the policy. All violations are reported
+// together, wrapped in ErrWeakPassword.
+func ValidatePassword(policy common.AccountPolicy, username, password string) error {
+	var problems []string
+
+	minLength := policy.PasswordMinLength
+	if minLength < 8 {
+		minLength = 8
+	}
+	if len(password) < minLength {
+		problems = append(problems, fmt.Sprintf("must be at least %d characters", minLength))
+	}
+	if len(password) > bcryptMaxLength {
+		problems = append(problems, fmt.Sprintf("must be at most %d bytes", bcryptMaxLength))
+	}
+
+	var upper, lower, digit, symbol bool
+	for _, r := range password {
+		switch {
+		case unicode.IsUpper(r):
+			upper = true
+		case unicode.IsLower(r):
+			lower = true
+		case unicode.IsDigit(r):
+			digit = true
+		case unicode.IsPunct(r) || unicode.IsSymbol(r):
+			symbol = true
+		}
+	}
+	if policy.PasswordRequireUpper && !upper {
+		problems = append(problems, "must contain an upper-case letter")
+	}
+	if policy.PasswordRequireLower && !lower {
+		problems = append(problems, "must contain a lower-case letter")
+	}
+	if policy.PasswordRequireDigit && !digit {
+		problems = append(problems, "must contain a digit")
+	}
+	if policy.PasswordRequireSymbol && !symbol {
+		problems = append(problems, "must contain a symbol")
+	}
+	if username != "" && strings.Contains(strings.ToLower(password), strings.ToLower(username)) {
+		problems = append(problems, "must not contain the username")
+	}
+
+	if len(problems) > 0 {
+		return fmt.Errorf("%w: %s", ErrWeakPassword, strings.Join(problems, "; "))
+	}
+	return nil
+}
@@ -9,6 +9,7 @@ import (
 
 	"github.com/AkshayDubey29/MoniFlux/backend/internal/api/models"
 	"github.com/AkshayDubey29/MoniFlux/backend/internal/common"
+	"github.com/AkshayDubey29/MoniFlux/backend/internal/services/email"
 	jwt "github.com/golang-jwt/jwt/v4"
 	"github.com/sirupsen/logrus"
 	"go.mongodb.org/mongo-driver/bson"
@@ -22,7 +23,21 @@ type AuthenticationService struct {
 	config         *common.Config
 	logger         *logrus.Logger
 	userCollection *mongo.Collection
-	jwtSecret      string
+	roleCollection *mongo.Collection
+	// apiKeyCollection stores hashed API keys for non-interactive clients.
+	apiKeyCollection *mongo.Collection
+	// sessionCollection and revokedTokenCollection back refresh tokens and logout.
+	sessionCollection      *mongo.Collection
+	revokedTokenCollection *mongo.Collection
+	// oidc, roleMapper and oidcStateCollection are set when single sign-on is enabled.
+	oidc                *OIDCProvider
+	roleMapper          RoleMapper
+	oidcStateCollection *mongo.Collection
+	// accountTokenCollection stores password reset and email verification tokens,
+	// which are delivered through mailer.
+	accountTokenCollection *mongo.Collection
+	mailer                 email.Sender
+	jwtSecret              string
 }
 
 // NewAuthenticationService creates a new instance of AuthenticationService.
@@ -32,16 +47,37 @@ func NewAuthenticationService(cfg *common.Config, logger *logrus.Logger, mongoCl
 		return nil, errors.New("failed to get users collection")
 	}
 
+	mailer, err := email.NewSender(cfg.Email, logger)
+	if err != nil {
+		return nil, err
+	}
+
 	return &AuthenticationService{
-		config:         cfg,
-		logger:         logger,
-		userCollection: userCol,
-		jwtSecret:      cfg.JWTSecret,
+		config:                 cfg,
+		logger:                 logger,
+		userCollection:         userCol,
+		roleCollection:         mongoClient.Database(cfg.MongoDB).Collection("roles"),
+		apiKeyCollection:       mongoClient.Database(cfg.MongoDB).Collection("api_keys"),
+		sessionCollection:      mongoClient.Database(cfg.MongoDB).Collection("sessions"),
+		revokedTokenCollection: mongoClient.Database(cfg.MongoDB).Collection("revoked_tokens"),
+		oidcStateCollection:    mongoClient.Database(cfg.MongoDB).Collection("oidc_states"),
+		accountTokenCollection: mongoClient.Database(cfg.MongoDB).Collection("account_tokens"),
+		mailer:                 mailer,
+		jwtSecret:              cfg.JWTSecret,
 	}, nil
 }
 
-// ValidateJWT validates the JWT token and returns the claims.
-func (as *AuthenticationService) ValidateJWT(tokenString string) (*models.Claims, error) {
+// ValidateJWT validates the JWT token, rejects revoked tokens and returns the claims.
+func (as *AuthenticationService) ValidateJWT(ctx context.Context, tokenString string) (*models.Claims, error) {
+	// Tokens issued by the single sign-on provider are verified against its JWKS.
+	if as.oidc != nil && as.oidc.IsIssuedBy(tokenString) {
+		claims, err := as.validateExternalJWT(ctx, tokenString)
+		if err != nil {
+			return nil, err
+		}
+		return claims, as.checkRevoked(ctx, claims.ID)
+	}
+
 	claims := &models.Claims{}
 
 	token, err := jwt.ParseWithClaims(tokenString, claims, func(token *jwt.Token) (interface{}, error) {
@@ -59,6 +95,10 @@ func (as *AuthenticationService) ValidateJWT(tokenString string) (*models.Claims
 		return nil, errors.New("invalid token")
 	}
 
+	if err := as.checkRevoked(ctx, claims.ID); err != nil {
+		return nil, err
+	}
+
 	return claims, nil
 }
 
@@ -74,7 +114,7 @@ func (as *AuthenticationService) GetUserByID(ctx context.Context, userID string)
 	err = as.userCollection.FindOne(ctx, bson.M{"_id": objID}).Decode(&user)
 	if err != nil {
 		if errors.Is(err, mongo.ErrNoDocuments) {
-			return nil, errors.New("user not found")
+			return nil, ErrUserNotFound
 		}
 		as.logger.Errorf("Error retrieving user: %v", err)
 		return nil, errors.New("internal server error")
@@ -83,71 +123,133 @@ func (as *AuthenticationService) GetUserByID(ctx context.Context, userID string)
 	return &user, nil
 }
 
-// GenerateJWT generates a JWT token for a given user.
-func (as *AuthenticationService) GenerateJWT(userID string) (string, error) {
-	claims := &models.Claims{
-		UserID: userID,
-		RegisteredClaims: jwt.RegisteredClaims{
-			ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Hour * 24)), // Token valid for 24 hours
-			IssuedAt:  jwt.NewNumericDate(time.Now()),
-			Issuer:    "MoniFlux",
-		},
-	}
-
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	return token.SignedString([]byte(as.jwtSecret))
+// GenerateJWT generates a standalone access token for a given user, embedding the names
+// of their roles. The token is not bound to a session and cannot be refreshed.
+func (as *AuthenticationService) GenerateJWT(userID string, roles ...string) (string, error) {
+	token, _, _, err := as.issueAccessToken(userID, "", roles)
+	return token, err
 }
 
-// RegisterUser registers a new user with a username, email, and password.
-func (as *AuthenticationService) RegisterUser(username, email, password string) error {
+// RegisterUser registers a new user with a username, email, and password, and sends
+// them an email verification link.
+func (as *AuthenticationService) RegisterUser(username, address, password string) error {
 	// Check if the user already exists
 	var existingUser struct{}
 	err := as.userCollection.FindOne(context.TODO(), bson.M{"username": username}).Decode(&existingUser)
 	if err == nil {
-		return errors.New("user already exists")
+		return ErrUserExists
 	}
 	if err != mongo.ErrNoDocuments {
 		return err
 	}
 
+	// Enforce the password policy
+	if err := ValidatePassword(as.config.Accounts, username, password); err != nil {
+		return err
+	}
+
 	// Hash the password
 	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
 	if err != nil {
 		return err
 	}
 
+	// New users receive the configured default role, if it exists.
+	roles := []primitive.ObjectID{}
+	if as.config.DefaultUserRole != "" {
+		var role struct {
+			ID primitive.ObjectID `bson:"_id"`
+		}
+		err := as.roleCollection.FindOne(context.TODO(), bson.M{"name": as.config.DefaultUserRole}).Decode(&role)
+		if err == nil {
+			roles = append(roles, role.ID)
+		} else if errors.Is(err, mongo.ErrNoDocuments) {
+			as.logger.Warnf("Default user role %s does not exist; registering %s without roles", as.config.DefaultUserRole, username)
+		} else {
+			return err
+		}
+	}
+
 	// Insert the new user into the database
-	_, err = as.userCollection.InsertOne(context.TODO(), bson.M{
-		"username":  username,
-		"email":     email,
-		"password":  string(hashedPassword),
-		"createdAt": time.Now(),
-	})
-	return err
+	now := time.Now()
+	user := models.User{
+		ID:                primitive.NewObjectID(),
+		Username:          username,
+		Email:             address,
+		Password:          string(hashedPassword),
+		Roles:             roles,
+		CreatedAt:         now,
+		UpdatedAt:         now,
+		PasswordChangedAt: &now,
+	}
+	if _, err := as.userCollection.InsertOne(context.TODO(), user); err != nil {
+		return err
+	}
+
+	// Registration succeeds even if the verification email cannot be sent; it can be re-requested.
+	if err := as.SendVerificationEmail(context.TODO(), &user); err != nil {
+		as.logger.Warnf("Failed to send verification email to user %s: %v", username, err)
+	}
+	return nil
 }
 
-// AuthenticateUser authenticates a user and returns a JWT token.
-func (as *AuthenticationService) AuthenticateUser(username, password string) (string, error) {
+// AuthenticateUser authenticates a user, starts a session and returns its token pair.
+func (as *AuthenticationService) AuthenticateUser(username, password string) (*TokenPair, error) {
 	var user models.User
 	err := as.userCollection.FindOne(context.TODO(), bson.M{"username": username}).Decode(&user)
 	if err != nil {
 		if errors.Is(err, mongo.ErrNoDocuments) {
-			return "", errors.New("invalid username or password")
+			return nil, ErrInvalidCredentials
 		}
-		return "", err
+		return nil, err
+	}
+
+	// Disabled and locked accounts are rejected before the password is checked
+	if err := as.checkLoginAllowed(&user); err != nil {
+		return nil, err
+	}
+
+	// Compare the provided password with the stored hashed password.
+	// Single sign-on users have no local password and cannot log in here.
+	if user.Password == "" || bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(password)) != nil {
+		as.recordFailedLogin(context.TODO(), &user)
+		return nil, ErrInvalidCredentials
 	}
+	as.resetFailedLogins(context.TODO(), &user)
 
-	// Compare the provided password with the stored hashed password
-	err = bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(password))
+	// Resolve the user's role names so they can be carried in the token.
+	roles, err := as.GetRoleNames(context.TODO(), user.Roles)
 	if err != nil {
-		return "", errors.New("invalid username or password")
+		return nil, err
+	}
+
+	// Start a session and issue its access and refresh tokens
+	return as.createSession(context.TODO(), user.ID.Hex(), roles)
+}
+
+// GetRoleNames resolves role IDs to their names.
+func (as *AuthenticationService) GetRoleNames(ctx context.Context, roleIDs []primitive.ObjectID) ([]string, error) {
+	if len(roleIDs) == 0 {
+		return nil, nil
 	}
 
-	// Generate JWT token
-	token, err := as.GenerateJWT(user.ID.Hex())
+	cursor, err := as.roleCollection.Find(ctx, bson.M{"_id": bson.M{"$in": roleIDs}})
 	if err != nil {
-		return "", err
+		as.logger.Errorf("Error retrieving roles: %v", err)
+		return nil, errors.New("internal server error")
 	}
+	defer cursor.Close(ctx)
 
-	return token, nil
+	var names []string
+	for cursor.Next(ctx) {
+		var role struct {
+			Name string `bson:"name"`
+		}
+		if err := cursor.Decode(&role); err != nil {
+			as.logger.Errorf("Error decoding role: %v", err)
+			continue
+		}
+		names = append(names, role.Name)
+	}
+	return names, cursor.Err()
 }
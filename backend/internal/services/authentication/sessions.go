@@ -0,0 +1,286 @@
+// backend/internal/services/authentication/sessions.go
+
+package authentication
+
+import (
+	"context"
+	"crypto/subtle"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/AkshayDubey29/MoniFlux/backend/internal/api/models"
+	jwt "github.com/golang-jwt/jwt/v4"
+	"github.com/google/uuid"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const (
+	defaultAccessTokenTTL  = 15 * time.Minute
+	defaultRefreshTokenTTL = 30 * 24 * time.Hour
+
+	refreshSecretLength = 48
+
+	revokeReasonLogout = "logout"
+	revokeReasonReuse  = "refresh token reuse detected"
+)
+
+// Custom errors
+var (
+	ErrInvalidRefreshToken = errors.New("invalid refresh token")
+	ErrRefreshTokenReused  = errors.New("refresh token reuse detected; session revoked")
+	ErrTokenRevoked        = errors.New("token has been revoked")
+)
+
+// accessTokenTTL returns the configured lifetime of access tokens.
+func (as *AuthenticationService) accessTokenTTL() time.Duration {
+	if d, err := time.ParseDuration(as.config.JWTExpiry); err == nil && d > 0 {
+		return d
+	}
+	return defaultAccessTokenTTL
+}
+
+// refreshTokenTTL returns the configured lifetime of a session and its refresh tokens.
+func (as *AuthenticationService) refreshTokenTTL() time.Duration {
+	if d, err := time.ParseDuration(as.config.RefreshTokenExpiry); err == nil && d > 0 {
+		return d
+	}
+	return defaultRefreshTokenTTL
+}
+
+// issueAccessToken signs a short-lived access token bound to the session. It returns
+// the token, its ID (jti) and its expiry.
+func (as *AuthenticationService) issueAccessToken(userID, sessionID string, roles []string) (string, string, time.Time, error) {
+	now := time.Now()
+	expiresAt := now.Add(as.accessTokenTTL())
+	tokenID := uuid.New().String()
+
+	claims := &models.Claims{
+		UserID:    userID,
+		Roles:     roles,
+		SessionID: sessionID,
+		RegisteredClaims: jwt.RegisteredClaims{
+			ID:        tokenID,
+			ExpiresAt: jwt.NewNumericDate(expiresAt),
+			IssuedAt:  jwt.NewNumericDate(now),
+			Issuer:    "MoniFlux",
+		},
+	}
+
+	token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString([]byte(as.jwtSecret))
+	if err != nil {
+		return "", "", time.Time{}, err
+	}
+	return token, tokenID, expiresAt, nil
+}
+
+// newRefreshToken returns a refresh token for the session. Its format is
+// "<sessionID>.<secret>"; only the hash of the whole token is stored.
+func newRefreshToken(sessionID string) (string, error) {
+	secret, err := randomString(refreshSecretLength)
+	if err != nil {
+		return "", err
+	}
+	return sessionID + "." + secret, nil
+}
+
+// createSession starts a new session for the user and issues its first token pair.
+func (as *AuthenticationService) createSession(ctx context.Context, userID string, roles []string) (*TokenPair, error) {
+	sessionID := uuid.New().String()
+	refreshToken, err := newRefreshToken(sessionID)
+	if err != nil {
+		return nil, err
+	}
+	accessToken, tokenID, expiresAt, err := as.issueAccessToken(userID, sessionID, roles)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	session := &Session{
+		SessionID:        sessionID,
+		UserID:           userID,
+		RefreshTokenHash: hashToken(refreshToken),
+		AccessTokenID:    tokenID,
+		CreatedAt:        now,
+		LastRefreshedAt:  now,
+		ExpiresAt:        now.Add(as.refreshTokenTTL()),
+	}
+	if _, err := as.sessionCollection.InsertOne(ctx, session); err != nil {
+		as.logger.Errorf("Failed to create session for user %s: %v", userID, err)
+		return nil, errors.New("internal server error")
+	}
+
+	return &TokenPair{
+		AccessToken:      accessToken,
+		RefreshToken:     refreshToken,
+		ExpiresAt:        expiresAt,
+		RefreshExpiresAt: session.ExpiresAt,
+	}, nil
+}
+
+// RefreshSession exchanges a refresh token for a new token pair and rotates the refresh
+// token. Presenting a refresh token that has already been rotated revokes the whole
+// session, since it means the token was copied.
+func (as *AuthenticationService) RefreshSession(ctx context.Context, refreshToken string) (*TokenPair, error) {
+	sep := strings.IndexByte(refreshToken, '.')
+	if sep <= 0 {
+		return nil, ErrInvalidRefreshToken
+	}
+	sessionID := refreshToken[:sep]
+
+	var session Session
+	err := as.sessionCollection.FindOne(ctx, bson.M{"sessionID": sessionID}).Decode(&session)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, ErrInvalidRefreshToken
+		}
+		as.logger.Errorf("Error retrieving session %s: %v", sessionID, err)
+		return nil, errors.New("internal server error")
+	}
+
+	if session.RevokedAt != nil || time.Now().After(session.ExpiresAt) {
+		return nil, ErrInvalidRefreshToken
+	}
+
+	presentedHash := hashToken(refreshToken)
+	if subtle.ConstantTimeCompare([]byte(presentedHash), []byte(session.RefreshTokenHash)) != 1 {
+		as.logger.Warnf("Refresh token reuse detected for session %s of user %s", sessionID, session.UserID)
+		as.revokeSession(ctx, &session, revokeReasonReuse)
+		return nil, ErrRefreshTokenReused
+	}
+
+	// Roles are re-resolved so that role changes take effect on the next refresh.
+	user, err := as.GetUserByID(ctx, session.UserID)
+	if err != nil || user.Disabled {
+		return nil, ErrInvalidRefreshToken
+	}
+	roles, err := as.GetRoleNames(ctx, user.Roles)
+	if err != nil {
+		return nil, err
+	}
+
+	newToken, err := newRefreshToken(sessionID)
+	if err != nil {
+		return nil, err
+	}
+	accessToken, tokenID, expiresAt, err := as.issueAccessToken(session.UserID, sessionID, roles)
+	if err != nil {
+		return nil, err
+	}
+
+	// The filter on the old hash makes rotation atomic: of two concurrent refreshes
+	// with the same token only one succeeds, and the other is treated as reuse.
+	result, err := as.sessionCollection.UpdateOne(ctx,
+		bson.M{"sessionID": sessionID, "refreshTokenHash": presentedHash, "revokedAt": nil},
+		bson.M{
+			"$set": bson.M{
+				"refreshTokenHash": hashToken(newToken),
+				"accessTokenID":    tokenID,
+				"lastRefreshedAt":  time.Now(),
+			},
+			"$inc": bson.M{"generation": 1},
+		})
+	if err != nil {
+		as.logger.Errorf("Failed to rotate refresh token for session %s: %v", sessionID, err)
+		return nil, errors.New("internal server error")
+	}
+	if result.MatchedCount == 0 {
+		as.logger.Warnf("Concurrent refresh of session %s of user %s", sessionID, session.UserID)
+		as.revokeSession(ctx, &session, revokeReasonReuse)
+		return nil, ErrRefreshTokenReused
+	}
+
+	return &TokenPair{
+		AccessToken:      accessToken,
+		RefreshToken:     newToken,
+		ExpiresAt:        expiresAt,
+		RefreshExpiresAt: session.ExpiresAt,
+	}, nil
+}
+
+// Logout revokes the access token of the principal and the session it belongs to.
+func (as *AuthenticationService) Logout(ctx context.Context, principal *models.Principal) error {
+	if principal.TokenID != "" {
+		if err := as.RevokeToken(ctx, principal.TokenID, principal.UserID, time.Now().Add(as.accessTokenTTL())); err != nil {
+			return err
+		}
+	}
+
+	if principal.SessionID != "" {
+		var session Session
+		err := as.sessionCollection.FindOne(ctx, bson.M{"sessionID": principal.SessionID, "userID": principal.UserID}).Decode(&session)
+		if err != nil && !errors.Is(err, mongo.ErrNoDocuments) {
+			as.logger.Errorf("Error retrieving session %s: %v", principal.SessionID, err)
+			return errors.New("internal server error")
+		}
+		if err == nil {
+			as.revokeSession(ctx, &session, revokeReasonLogout)
+		}
+	}
+
+	as.logger.Infof("User %s logged out of session %s", principal.UserID, principal.SessionID)
+	return nil
+}
+
+// revokeSession marks the session revoked and revokes its most recent access token.
+func (as *AuthenticationService) revokeSession(ctx context.Context, session *Session, reason string) {
+	_, err := as.sessionCollection.UpdateOne(ctx,
+		bson.M{"sessionID": session.SessionID, "revokedAt": nil},
+		bson.M{"$set": bson.M{"revokedAt": time.Now(), "revokedReason": reason}})
+	if err != nil {
+		as.logger.Errorf("Failed to revoke session %s: %v", session.SessionID, err)
+	}
+
+	// Re-read the session so a token issued by a racing refresh is revoked too.
+	var current Session
+	if err := as.sessionCollection.FindOne(ctx, bson.M{"sessionID": session.SessionID}).Decode(&current); err == nil {
+		session = &current
+	}
+	if session.AccessTokenID != "" {
+		if err := as.RevokeToken(ctx, session.AccessTokenID, session.UserID, time.Now().Add(as.accessTokenTTL())); err != nil {
+			as.logger.Errorf("Failed to revoke access token of session %s: %v", session.SessionID, err)
+		}
+	}
+}
+
+// RevokeToken adds an access token ID (jti) to the revocation list until expiresAt.
+func (as *AuthenticationService) RevokeToken(ctx context.Context, tokenID, userID string, expiresAt time.Time) error {
+	_, err := as.revokedTokenCollection.UpdateOne(ctx,
+		bson.M{"jti": tokenID},
+		bson.M{"$setOnInsert": RevokedToken{TokenID: tokenID, UserID: userID, RevokedAt: time.Now(), ExpiresAt: expiresAt}},
+		options.Update().SetUpsert(true))
+	if err != nil {
+		as.logger.Errorf("Failed to revoke token %s: %v", tokenID, err)
+		return errors.New("internal server error")
+	}
+	return nil
+}
+
+// checkRevoked returns ErrTokenRevoked if the token ID is on the revocation list.
+// Tokens without an ID cannot be revoked individually.
+func (as *AuthenticationService) checkRevoked(ctx context.Context, tokenID string) error {
+	if tokenID == "" {
+		return nil
+	}
+	revoked, err := as.isTokenRevoked(ctx, tokenID)
+	if err != nil {
+		return err
+	}
+	if revoked {
+		return ErrTokenRevoked
+	}
+	return nil
+}
+
+// isTokenRevoked reports whether the access token ID is on the revocation list.
+func (as *AuthenticationService) isTokenRevoked(ctx context.Context, tokenID string) (bool, error) {
+	count, err := as.revokedTokenCollection.CountDocuments(ctx, bson.M{"jti": tokenID}, options.Count().SetLimit(1))
+	if err != nil {
+		as.logger.Errorf("Failed to check revocation of token %s: %v", tokenID, err)
+		return false, errors.New("internal server error")
+	}
+	return count > 0, nil
+}
@@ -0,0 +1,235 @@
+// backend/internal/services/authentication/sso.go
+
+package authentication
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"time"
+
+	"github.com/AkshayDubey29/MoniFlux/backend/internal/api/models"
+	jwt "github.com/golang-jwt/jwt/v4"
+	"github.com/google/uuid"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// oidcLoginTTL is how long a started single sign-on login may take to complete.
+const oidcLoginTTL = 10 * time.Minute
+
+// Custom errors
+var (
+	ErrOIDCDisabled      = errors.New("single sign-on is not enabled")
+	ErrInvalidLoginState = errors.New("invalid or expired login state")
+	ErrUnknownSSOUser    = errors.New("single sign-on user has not signed in yet")
+)
+
+// RoleMapper maps IdP groups onto MoniFlux roles for a user and returns the names of
+// the roles the user holds afterwards. It is implemented by the authorization service.
+type RoleMapper interface {
+	SyncGroupRoles(ctx context.Context, userID string, groups []string) ([]string, error)
+}
+
+// oidcLoginState is the server-side state of a pending authorization-code login.
+// Entries are removed by a TTL index once ExpiresAt has passed.
+type oidcLoginState struct {
+	State        string    `bson:"state"`
+	Nonce        string    `bson:"nonce"`
+	CodeVerifier string    `bson:"codeVerifier"`
+	ExpiresAt    time.Time `bson:"expiresAt"`
+}
+
+// EnableOIDC turns on single sign-on through the provider. IdP groups are mapped onto
+// roles by mapper; a nil mapper keeps the roles already stored for the user.
+func (as *AuthenticationService) EnableOIDC(provider *OIDCProvider, mapper RoleMapper) {
+	as.oidc = provider
+	as.roleMapper = mapper
+	as.logger.Infof("Single sign-on enabled for issuer %s", provider.Issuer())
+}
+
+// OIDCEnabled reports whether single sign-on is configured.
+func (as *AuthenticationService) OIDCEnabled() bool {
+	return as.oidc != nil
+}
+
+// BeginOIDCLogin starts an authorization-code + PKCE login and returns the IdP URL
+// the user agent must be redirected to.
+func (as *AuthenticationService) BeginOIDCLogin(ctx context.Context) (string, error) {
+	if as.oidc == nil {
+		return "", ErrOIDCDisabled
+	}
+
+	verifier, challenge, err := NewPKCEVerifier()
+	if err != nil {
+		return "", err
+	}
+	state := oidcLoginState{
+		State:        uuid.New().String(),
+		Nonce:        uuid.New().String(),
+		CodeVerifier: verifier,
+		ExpiresAt:    time.Now().Add(oidcLoginTTL),
+	}
+	if _, err := as.oidcStateCollection.InsertOne(ctx, state); err != nil {
+		as.logger.Errorf("Failed to store login state: %v", err)
+		return "", errors.New("internal server error")
+	}
+
+	return as.oidc.AuthCodeURL(state.State, state.Nonce, challenge), nil
+}
+
+// CompleteOIDCLogin redeems the authorization code returned to the callback, provisions
+// the user if needed, syncs their roles from IdP groups and starts a session.
+func (as *AuthenticationService) CompleteOIDCLogin(ctx context.Context, state, code string) (*TokenPair, error) {
+	if as.oidc == nil {
+		return nil, ErrOIDCDisabled
+	}
+
+	// Each login state can be redeemed exactly once.
+	var pending oidcLoginState
+	err := as.oidcStateCollection.FindOneAndDelete(ctx, bson.M{"state": state}).Decode(&pending)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, ErrInvalidLoginState
+		}
+		as.logger.Errorf("Error retrieving login state: %v", err)
+		return nil, errors.New("internal server error")
+	}
+	if time.Now().After(pending.ExpiresAt) {
+		return nil, ErrInvalidLoginState
+	}
+
+	idToken, err := as.oidc.Exchange(ctx, code, pending.CodeVerifier)
+	if err != nil {
+		return nil, err
+	}
+	identity, err := as.oidc.Verify(ctx, idToken, pending.Nonce)
+	if err != nil {
+		return nil, err
+	}
+
+	user, roles, err := as.signInExternalUser(ctx, identity)
+	if err != nil {
+		return nil, err
+	}
+
+	as.logger.Infof("User %s signed in through %s", user.Username, identity.Issuer)
+	return as.createSession(ctx, user.ID.Hex(), roles)
+}
+
+// validateExternalJWT verifies a bearer token issued by the IdP and maps it onto the
+// claims of the corresponding MoniFlux user. Users are only provisioned and their
+// roles synced from IdP groups at sign-in, so validating a token never writes: the
+// user must have signed in once and holds the roles of their last sign-in.
+func (as *AuthenticationService) validateExternalJWT(ctx context.Context, tokenString string) (*models.Claims, error) {
+	identity, err := as.oidc.Verify(ctx, tokenString, "")
+	if err != nil {
+		return nil, err
+	}
+
+	var user models.User
+	err = as.userCollection.FindOne(ctx, bson.M{"oidcIssuer": identity.Issuer, "oidcSubject": identity.Subject}).Decode(&user)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, ErrUnknownSSOUser
+		}
+		as.logger.Errorf("Error retrieving SSO user: %v", err)
+		return nil, errors.New("internal server error")
+	}
+	if user.Disabled {
+		return nil, ErrAccountDisabled
+	}
+
+	roles, err := as.GetRoleNames(ctx, user.Roles)
+	if err != nil {
+		return nil, err
+	}
+
+	return &models.Claims{
+		UserID: user.ID.Hex(),
+		Roles:  roles,
+		RegisteredClaims: jwt.RegisteredClaims{
+			ID:        identity.TokenID,
+			Issuer:    identity.Issuer,
+			Subject:   identity.Subject,
+			ExpiresAt: jwt.NewNumericDate(identity.Expiry),
+		},
+	}, nil
+}
+
+// signInExternalUser provisions the user for the identity and syncs their roles.
+func (as *AuthenticationService) signInExternalUser(ctx context.Context, identity *OIDCIdentity) (*models.User, []string, error) {
+	user, err := as.provisionOIDCUser(ctx, identity)
+	if err != nil {
+		return nil, nil, err
+	}
+	if user.Disabled {
+		return nil, nil, ErrAccountDisabled
+	}
+
+	var roles []string
+	if as.roleMapper != nil {
+		roles, err = as.roleMapper.SyncGroupRoles(ctx, user.ID.Hex(), identity.Groups)
+	} else {
+		roles, err = as.GetRoleNames(ctx, user.Roles)
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+	return user, roles, nil
+}
+
+// provisionOIDCUser returns the user linked to the IdP identity, creating it on first
+// sign-in. Users are matched on issuer and subject, never on username or email, so
+// an IdP account cannot take over an existing local account.
+func (as *AuthenticationService) provisionOIDCUser(ctx context.Context, identity *OIDCIdentity) (*models.User, error) {
+	var user models.User
+	err := as.userCollection.FindOne(ctx, bson.M{"oidcIssuer": identity.Issuer, "oidcSubject": identity.Subject}).Decode(&user)
+	if err == nil {
+		if identity.Email != "" && identity.Email != user.Email {
+			_, err := as.userCollection.UpdateOne(ctx, bson.M{"_id": user.ID},
+				bson.M{"$set": bson.M{"email": identity.Email, "updated_at": time.Now()}})
+			if err != nil {
+				as.logger.Warnf("Failed to update email of user %s: %v", user.Username, err)
+			}
+			user.Email = identity.Email
+		}
+		return &user, nil
+	}
+	if !errors.Is(err, mongo.ErrNoDocuments) {
+		as.logger.Errorf("Error retrieving SSO user: %v", err)
+		return nil, errors.New("internal server error")
+	}
+
+	// Disambiguate usernames already taken by another account.
+	username := identity.Username
+	count, err := as.userCollection.CountDocuments(ctx, bson.M{"username": username})
+	if err != nil {
+		as.logger.Errorf("Error checking username %s: %v", username, err)
+		return nil, errors.New("internal server error")
+	}
+	if count > 0 {
+		sum := sha256.Sum256([]byte(identity.Issuer + "|" + identity.Subject))
+		username = username + "-" + hex.EncodeToString(sum[:])[:6]
+	}
+
+	user = models.User{
+		ID:          primitive.NewObjectID(),
+		Username:    username,
+		Email:       identity.Email,
+		Roles:       []primitive.ObjectID{},
+		CreatedAt:   time.Now(),
+		UpdatedAt:   time.Now(),
+		OIDCIssuer:  identity.Issuer,
+		OIDCSubject: identity.Subject,
+	}
+	if _, err := as.userCollection.InsertOne(ctx, user); err != nil {
+		as.logger.Errorf("Failed to provision SSO user %s: %v", username, err)
+		return nil, errors.New("internal server error")
+	}
+
+	as.logger.Infof("Provisioned user %s for %s subject %s", username, identity.Issuer, identity.Subject)
+	return &user, nil
+}
@@ -3,9 +3,12 @@
 package authorization
 
 import (
+	"errors"
+	"time"
+
+	"github.com/AkshayDubey29/MoniFlux/backend/internal/api/models"
 	"github.com/AkshayDubey29/MoniFlux/backend/internal/common"
 	"go.mongodb.org/mongo-driver/bson/primitive"
-	"time"
 )
 
 // Permission represents a permission entity.
@@ -29,3 +32,76 @@ type Role struct {
 // User represents a user entity.
 // Assuming the User struct has a Roles field which is a slice of ObjectIDs.
 type User = common.User
+
+// RoleView is the API representation of a role with its permission names resolved.
+type RoleView struct {
+	Name        string    `json:"name"`
+	Permissions []string  `json:"permissions"`
+	CreatedAt   time.Time `json:"createdAt"`
+	UpdatedAt   time.Time `json:"updatedAt"`
+}
+
+// Permission names enforced by the HTTP API.
+const (
+	PermTestsRead    = "tests:read"
+	PermTestsCreate  = "tests:create"
+	PermTestsStart   = "tests:start"
+	PermTestsCancel  = "tests:cancel"
+	PermResultsWrite = "results:write"
+	PermUsersAdmin   = "users:admin"
+	PermAuditRead    = "audit:read"
+	PermGuardrails   = "guardrails:admin"
+	PermSecrets      = "secrets:write"
+	PermDestinations = "destinations:write"
+	PermTenantsAdmin = models.AdminScope
+)
+
+// AdminRoleName is the name of the role holding every default permission.
+const AdminRoleName = "admin"
+
+// DefaultPermissions are created at startup if they do not exist.
+var DefaultPermissions = []Permission{
+	{Name: "create_user", Description: "Ability to create new users"},
+	{Name: "delete_user", Description: "Ability to delete existing users"},
+	{Name: "view_logs", Description: "Ability to view system logs"},
+	{Name: PermTestsRead, Description: "Ability to list and read load tests and their results"},
+	{Name: PermTestsCreate, Description: "Ability to create load tests"},
+	{Name: PermTestsStart, Description: "Ability to start, schedule and restart load tests"},
+	{Name: PermTestsCancel, Description: "Ability to cancel load tests"},
+	{Name: PermResultsWrite, Description: "Ability to save load test results"},
+	{Name: PermUsersAdmin, Description: "Ability to manage roles, permissions and user role assignments"},
+	{Name: PermAuditRead, Description: "Ability to query the audit log"},
+	{Name: PermGuardrails, Description: "Ability to manage destination, rate and concurrency guardrails"},
+	{Name: PermSecrets, Description: "Ability to store and delete destination secrets"},
+	{Name: PermDestinations, Description: "Ability to register and delete named destinations"},
+	{Name: PermTenantsAdmin, Description: "Ability to act on the resources of every tenant"},
+}
+
+// DefaultRolePermissions maps the well-known role names to their default permissions.
+// Only the roles listed in Config.DefaultRoles are seeded.
+var DefaultRolePermissions = map[string][]string{
+	AdminRoleName: {
+		"create_user", "delete_user", "view_logs",
+		PermTestsRead, PermTestsCreate, PermTestsStart, PermTestsCancel, PermResultsWrite, PermUsersAdmin, PermAuditRead, PermGuardrails, PermSecrets, PermDestinations,
+		PermTenantsAdmin,
+	},
+	"editor": {
+		"create_user", "view_logs",
+		PermTestsRead, PermTestsCreate, PermTestsStart, PermTestsCancel, PermResultsWrite, PermSecrets, PermDestinations,
+	},
+	"viewer": {
+		"view_logs", PermTestsRead,
+	},
+}
+
+// Custom errors
+var (
+	ErrPermissionNotFound = errors.New("permission not found")
+	ErrPermissionExists   = errors.New("permission already exists")
+	ErrRoleNotFound       = errors.New("role not found")
+	ErrRoleExists         = errors.New("role already exists")
+	ErrUserNotFound       = errors.New("user not found")
+	ErrInvalidUserID      = errors.New("invalid user ID format")
+	ErrAdminRoleProtected = errors.New("the admin role cannot be deleted")
+	ErrLastAdmin          = errors.New("the admin role cannot be removed from the last user holding it")
+)
@@ -5,6 +5,7 @@ package authorization
 import (
 	"context"
 	"errors"
+	"strings"
 	"time"
 
 	"github.com/AkshayDubey29/MoniFlux/backend/internal/common"           // Single import without alias
@@ -13,6 +14,7 @@ import (
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 	mongoDriver "go.mongodb.org/mongo-driver/mongo" // Aliased for official driver
+	"go.mongodb.org/mongo-driver/mongo/options"
 )
 
 // AuthorizationService provides methods for managing roles and permissions.
@@ -46,7 +48,7 @@ func (as *AuthorizationService) CreatePermission(ctx context.Context, name, desc
 	var existing Permission
 	err := as.permissionCollection.FindOne(ctx, bson.M{"name": name}).Decode(&existing)
 	if err == nil {
-		return nil, errors.New("permission already exists")
+		return nil, ErrPermissionExists
 	}
 	if err != mongoDriver.ErrNoDocuments {
 		as.logger.Errorf("Error checking existing permission: %v", err)
@@ -84,7 +86,7 @@ func (as *AuthorizationService) GetPermission(ctx context.Context, name string)
 	err := as.permissionCollection.FindOne(ctx, bson.M{"name": name}).Decode(&permission)
 	if err != nil {
 		if errors.Is(err, mongoDriver.ErrNoDocuments) {
-			return nil, errors.New("permission not found")
+			return nil, ErrPermissionNotFound
 		}
 		as.logger.Errorf("Error retrieving permission: %v", err)
 		return nil, errors.New("internal server error")
@@ -98,7 +100,7 @@ func (as *AuthorizationService) CreateRole(ctx context.Context, name string, per
 	var existing Role
 	err := as.roleCollection.FindOne(ctx, bson.M{"name": name}).Decode(&existing)
 	if err == nil {
-		return nil, errors.New("role already exists")
+		return nil, ErrRoleExists
 	}
 	if err != mongoDriver.ErrNoDocuments {
 		as.logger.Errorf("Error checking existing role: %v", err)
@@ -106,13 +108,9 @@ func (as *AuthorizationService) CreateRole(ctx context.Context, name string, per
 	}
 
 	// Fetch permission IDs.
-	permissionIDs := []primitive.ObjectID{}
-	for _, pname := range permissionNames {
-		perm, err := as.GetPermission(ctx, pname)
-		if err != nil {
-			return nil, err // Permission not found or internal error.
-		}
-		permissionIDs = append(permissionIDs, perm.ID)
+	permissionIDs, err := as.resolvePermissionIDs(ctx, permissionNames)
+	if err != nil {
+		return nil, err // Permission not found or internal error.
 	}
 
 	// Create new role.
@@ -146,7 +144,7 @@ func (as *AuthorizationService) GetRole(ctx context.Context, name string) (*Role
 	err := as.roleCollection.FindOne(ctx, bson.M{"name": name}).Decode(&role)
 	if err != nil {
 		if errors.Is(err, mongoDriver.ErrNoDocuments) {
-			return nil, errors.New("role not found")
+			return nil, ErrRoleNotFound
 		}
 		as.logger.Errorf("Error retrieving role: %v", err)
 		return nil, errors.New("internal server error")
@@ -161,7 +159,7 @@ func (as *AuthorizationService) AssignRoleToUser(ctx context.Context, userID str
 	userObjectID, err := primitive.ObjectIDFromHex(userID)
 	if err != nil {
 		as.logger.Errorf("Invalid userID format: %v", err)
-		return errors.New("invalid user ID format")
+		return ErrInvalidUserID
 	}
 
 	// Fetch the role by name.
@@ -188,20 +186,21 @@ func (as *AuthorizationService) AssignRoleToUser(ctx context.Context, userID str
 	}
 
 	if result.MatchedCount == 0 {
-		return errors.New("user not found")
+		return ErrUserNotFound
 	}
 
 	as.logger.Infof("Role %s assigned to user %s", roleName, userID)
 	return nil
 }
 
-// RemoveRoleFromUser removes a role from a user.
+// RemoveRoleFromUser removes a role from a user. The admin role is never removed from
+// the last user holding it, so that roles can still be administered.
 func (as *AuthorizationService) RemoveRoleFromUser(ctx context.Context, userID string, roleName string) error {
 	// Convert userID to ObjectID
 	userObjectID, err := primitive.ObjectIDFromHex(userID)
 	if err != nil {
 		as.logger.Errorf("Invalid userID format: %v", err)
-		return errors.New("invalid user ID format")
+		return ErrInvalidUserID
 	}
 
 	// Fetch the role by name.
@@ -210,6 +209,17 @@ func (as *AuthorizationService) RemoveRoleFromUser(ctx context.Context, userID s
 		return err
 	}
 
+	if role.Name == AdminRoleName {
+		others, err := as.userCollection.CountDocuments(ctx, bson.M{"roles": role.ID, "_id": bson.M{"$ne": userObjectID}})
+		if err != nil {
+			as.logger.Errorf("Error counting admin users: %v", err)
+			return errors.New("internal server error")
+		}
+		if others == 0 {
+			return ErrLastAdmin
+		}
+	}
+
 	// Update the user's roles.
 	filter := bson.M{"_id": userObjectID}
 	update := bson.M{
@@ -228,7 +238,7 @@ func (as *AuthorizationService) RemoveRoleFromUser(ctx context.Context, userID s
 	}
 
 	if result.MatchedCount == 0 {
-		return errors.New("user not found")
+		return ErrUserNotFound
 	}
 
 	as.logger.Infof("Role %s removed from user %s", roleName, userID)
@@ -241,7 +251,7 @@ func (as *AuthorizationService) UserHasPermission(ctx context.Context, userID st
 	userObjectID, err := primitive.ObjectIDFromHex(userID)
 	if err != nil {
 		as.logger.Errorf("Invalid userID format: %v", err)
-		return false, errors.New("invalid user ID format")
+		return false, ErrInvalidUserID
 	}
 
 	// Fetch the permission by name.
@@ -255,7 +265,7 @@ func (as *AuthorizationService) UserHasPermission(ctx context.Context, userID st
 	err = as.userCollection.FindOne(ctx, bson.M{"_id": userObjectID}).Decode(&user)
 	if err != nil {
 		if errors.Is(err, mongoDriver.ErrNoDocuments) {
-			return false, errors.New("user not found")
+			return false, ErrUserNotFound
 		}
 		as.logger.Errorf("Error retrieving user: %v", err)
 		return false, errors.New("internal server error")
@@ -295,107 +305,308 @@ func (as *AuthorizationService) UserHasPermission(ctx context.Context, userID st
 	return false, nil
 }
 
-// CreateDefaultRoles initializes default roles and permissions if they do not exist.
+// CreateDefaultRoles initializes the default permissions and the roles named in
+// Config.DefaultRoles if they do not exist. Roles that already exist are granted any
+// default permissions they are missing, so upgrades pick up newly introduced permissions.
 func (as *AuthorizationService) CreateDefaultRoles(ctx context.Context) error {
-	// Define default permissions.
-	defaultPermissions := []Permission{
-		{
-			Name:        "create_user",
-			Description: "Ability to create new users",
-			CreatedAt:   time.Now(),
-			UpdatedAt:   time.Now(),
-		},
-		{
-			Name:        "delete_user",
-			Description: "Ability to delete existing users",
-			CreatedAt:   time.Now(),
-			UpdatedAt:   time.Now(),
-		},
-		{
-			Name:        "view_logs",
-			Description: "Ability to view system logs",
-			CreatedAt:   time.Now(),
-			UpdatedAt:   time.Now(),
-		},
-		// Add more default permissions as needed.
-	}
-
-	for _, perm := range defaultPermissions {
+	for _, perm := range DefaultPermissions {
 		// Check if permission exists.
-		var existing Permission
-		err := as.permissionCollection.FindOne(ctx, bson.M{"name": perm.Name}).Decode(&existing)
-		if err == mongoDriver.ErrNoDocuments {
-			// Insert the permission.
-			result, err := as.permissionCollection.InsertOne(ctx, perm)
-			if err != nil {
-				as.logger.Errorf("Error inserting default permission %s: %v", perm.Name, err)
+		_, err := as.GetPermission(ctx, perm.Name)
+		if errors.Is(err, ErrPermissionNotFound) {
+			if _, err := as.CreatePermission(ctx, perm.Name, perm.Description); err != nil {
+				as.logger.Errorf("Error creating default permission %s: %v", perm.Name, err)
 				return err
 			}
-			insertedID, ok := result.InsertedID.(primitive.ObjectID)
-			if !ok {
-				as.logger.Errorf("Failed to assert InsertedID to primitive.ObjectID for permission %s", perm.Name)
-				return errors.New("internal server error")
-			}
-			perm.ID = insertedID
 			as.logger.Infof("Default permission created: %s", perm.Name)
 		} else if err != nil {
 			as.logger.Errorf("Error checking default permission %s: %v", perm.Name, err)
 			return err
-		} else {
-			as.logger.Infof("Default permission already exists: %s", perm.Name)
 		}
 	}
 
-	// Define default roles.
-	defaultRoles := []struct {
-		Name        string
-		Permissions []string
-	}{
-		{
-			Name: "admin",
-			Permissions: []string{
-				"create_user",
-				"delete_user",
-				"view_logs",
-				// Add more permissions as needed.
-			},
-		},
-		{
-			Name: "editor",
-			Permissions: []string{
-				"create_user",
-				"view_logs",
-				// Add more permissions as needed.
-			},
-		},
-		{
-			Name: "viewer",
-			Permissions: []string{
-				"view_logs",
-				// Add more permissions as needed.
-			},
-		},
-	}
+	for _, roleName := range as.config.DefaultRoles {
+		permissions, known := DefaultRolePermissions[roleName]
+		if !known {
+			as.logger.Warnf("Default role %s has no predefined permissions; creating it empty", roleName)
+		}
 
-	for _, roleDef := range defaultRoles {
 		// Check if role exists.
-		var existing Role
-		err := as.roleCollection.FindOne(ctx, bson.M{"name": roleDef.Name}).Decode(&existing)
-		if err == mongoDriver.ErrNoDocuments {
+		_, err := as.GetRole(ctx, roleName)
+		if errors.Is(err, ErrRoleNotFound) {
 			// Create the role.
-			role, err := as.CreateRole(ctx, roleDef.Name, roleDef.Permissions)
+			role, err := as.CreateRole(ctx, roleName, permissions)
 			if err != nil {
-				as.logger.Errorf("Error creating default role %s: %v", roleDef.Name, err)
+				as.logger.Errorf("Error creating default role %s: %v", roleName, err)
 				return err
 			}
 			as.logger.Infof("Default role created: %s", role.Name)
 		} else if err != nil {
-			as.logger.Errorf("Error checking default role %s: %v", roleDef.Name, err)
+			as.logger.Errorf("Error checking default role %s: %v", roleName, err)
 			return err
-		} else {
-			as.logger.Infof("Default role already exists: %s", roleDef.Name)
+		} else if len(permissions) > 0 {
+			if err := as.GrantPermissions(ctx, roleName, permissions); err != nil {
+				as.logger.Errorf("Error updating default role %s: %v", roleName, err)
+				return err
+			}
+			as.logger.Infof("Default role already exists: %s", roleName)
+		}
+	}
+
+	return nil
+}
+
+// ListPermissions returns all permissions ordered by name.
+func (as *AuthorizationService) ListPermissions(ctx context.Context) ([]Permission, error) {
+	cursor, err := as.permissionCollection.Find(ctx, bson.M{}, options.Find().SetSort(bson.D{{Key: "name", Value: 1}}))
+	if err != nil {
+		as.logger.Errorf("Error listing permissions: %v", err)
+		return nil, errors.New("internal server error")
+	}
+	defer cursor.Close(ctx)
+
+	permissions := []Permission{}
+	if err := cursor.All(ctx, &permissions); err != nil {
+		as.logger.Errorf("Error decoding permissions: %v", err)
+		return nil, errors.New("internal server error")
+	}
+	return permissions, nil
+}
+
+// ListRoles returns all roles with their permission names resolved, ordered by name.
+func (as *AuthorizationService) ListRoles(ctx context.Context) ([]RoleView, error) {
+	permissions, err := as.ListPermissions(ctx)
+	if err != nil {
+		return nil, err
+	}
+	permissionNames := make(map[primitive.ObjectID]string, len(permissions))
+	for _, p := range permissions {
+		permissionNames[p.ID] = p.Name
+	}
+
+	cursor, err := as.roleCollection.Find(ctx, bson.M{}, options.Find().SetSort(bson.D{{Key: "name", Value: 1}}))
+	if err != nil {
+		as.logger.Errorf("Error listing roles: %v", err)
+		return nil, errors.New("internal server error")
+	}
+	defer cursor.Close(ctx)
+
+	roles := []RoleView{}
+	for cursor.Next(ctx) {
+		var role Role
+		if err := cursor.Decode(&role); err != nil {
+			as.logger.Errorf("Error decoding role: %v", err)
+			continue
+		}
+		view := RoleView{Name: role.Name, Permissions: []string{}, CreatedAt: role.CreatedAt, UpdatedAt: role.UpdatedAt}
+		for _, pid := range role.Permissions {
+			if name, ok := permissionNames[pid]; ok {
+				view.Permissions = append(view.Permissions, name)
+			}
+		}
+		roles = append(roles, view)
+	}
+
+	if err := cursor.Err(); err != nil {
+		as.logger.Errorf("Cursor error: %v", err)
+		return nil, errors.New("internal server error")
+	}
+	return roles, nil
+}
+
+// resolvePermissionIDs looks up the IDs of the named permissions.
+func (as *AuthorizationService) resolvePermissionIDs(ctx context.Context, permissionNames []string) ([]primitive.ObjectID, error) {
+	permissionIDs := []primitive.ObjectID{}
+	for _, pname := range permissionNames {
+		perm, err := as.GetPermission(ctx, pname)
+		if err != nil {
+			return nil, err
 		}
+		permissionIDs = append(permissionIDs, perm.ID)
+	}
+	return permissionIDs, nil
+}
+
+// SetRolePermissions replaces the permissions of a role.
+func (as *AuthorizationService) SetRolePermissions(ctx context.Context, roleName string, permissionNames []string) error {
+	permissionIDs, err := as.resolvePermissionIDs(ctx, permissionNames)
+	if err != nil {
+		return err
+	}
+
+	result, err := as.roleCollection.UpdateOne(ctx, bson.M{"name": roleName}, bson.M{
+		"$set": bson.M{"permissions": permissionIDs, "updated_at": time.Now()},
+	})
+	if err != nil {
+		as.logger.Errorf("Error updating role %s: %v", roleName, err)
+		return errors.New("internal server error")
+	}
+	if result.MatchedCount == 0 {
+		return ErrRoleNotFound
+	}
+
+	as.logger.Infof("Permissions of role %s set to %v", roleName, permissionNames)
+	return nil
+}
+
+// GrantPermissions adds permissions to a role, keeping the ones it already has.
+func (as *AuthorizationService) GrantPermissions(ctx context.Context, roleName string, permissionNames []string) error {
+	permissionIDs, err := as.resolvePermissionIDs(ctx, permissionNames)
+	if err != nil {
+		return err
+	}
+
+	result, err := as.roleCollection.UpdateOne(ctx, bson.M{"name": roleName}, bson.M{
+		"$addToSet": bson.M{"permissions": bson.M{"$each": permissionIDs}},
+		"$set":      bson.M{"updated_at": time.Now()},
+	})
+	if err != nil {
+		as.logger.Errorf("Error granting permissions to role %s: %v", roleName, err)
+		return errors.New("internal server error")
+	}
+	if result.MatchedCount == 0 {
+		return ErrRoleNotFound
+	}
+	return nil
+}
+
+// DeleteRole deletes a role and removes it from every user holding it. The admin
+// role cannot be deleted.
+func (as *AuthorizationService) DeleteRole(ctx context.Context, roleName string) error {
+	if roleName == AdminRoleName {
+		return ErrAdminRoleProtected
 	}
 
+	role, err := as.GetRole(ctx, roleName)
+	if err != nil {
+		return err
+	}
+
+	if _, err := as.roleCollection.DeleteOne(ctx, bson.M{"_id": role.ID}); err != nil {
+		as.logger.Errorf("Error deleting role %s: %v", roleName, err)
+		return errors.New("internal server error")
+	}
+	if _, err := as.userCollection.UpdateMany(ctx, bson.M{"roles": role.ID}, bson.M{
+		"$pull": bson.M{"roles": role.ID},
+		"$set":  bson.M{"updated_at": time.Now()},
+	}); err != nil {
+		as.logger.Errorf("Error removing deleted role %s from users: %v", roleName, err)
+		return errors.New("internal server error")
+	}
+
+	as.logger.Infof("Role deleted: %s", roleName)
+	return nil
+}
+
+// EnsureAdminUsers grants the admin role to the users with the given usernames.
+// Unknown usernames are skipped with a warning.
+func (as *AuthorizationService) EnsureAdminUsers(ctx context.Context, usernames []string) error {
+	for _, username := range usernames {
+		var user User
+		err := as.userCollection.FindOne(ctx, bson.M{"username": username}).Decode(&user)
+		if errors.Is(err, mongoDriver.ErrNoDocuments) {
+			as.logger.Warnf("Admin user %s does not exist yet; skipping", username)
+			continue
+		}
+		if err != nil {
+			as.logger.Errorf("Error retrieving admin user %s: %v", username, err)
+			return err
+		}
+		if err := as.AssignRoleToUser(ctx, user.ID.Hex(), AdminRoleName); err != nil {
+			return err
+		}
+	}
 	return nil
 }
+
+// SyncGroupRoles replaces the roles of a single sign-on user with the roles that
+// Config.OIDC.GroupRoles maps their IdP groups onto. Users without a mapped group get
+// Config.DefaultUserRole, and users listed in Config.AdminUsers keep the admin role.
+// It returns the names of the roles the user now holds.
+func (as *AuthorizationService) SyncGroupRoles(ctx context.Context, userID string, groups []string) ([]string, error) {
+	userObjectID, err := primitive.ObjectIDFromHex(userID)
+	if err != nil {
+		return nil, ErrInvalidUserID
+	}
+
+	var user User
+	if err := as.userCollection.FindOne(ctx, bson.M{"_id": userObjectID}).Decode(&user); err != nil {
+		if errors.Is(err, mongoDriver.ErrNoDocuments) {
+			return nil, ErrUserNotFound
+		}
+		as.logger.Errorf("Error retrieving user: %v", err)
+		return nil, errors.New("internal server error")
+	}
+
+	seen := map[string]bool{}
+	var wanted []string
+	addRole := func(name string) {
+		if name != "" && !seen[name] {
+			seen[name] = true
+			wanted = append(wanted, name)
+		}
+	}
+	for _, group := range groups {
+		// Viper lower-cases map keys, so group names are matched case-insensitively.
+		addRole(as.config.OIDC.GroupRoles[strings.ToLower(group)])
+	}
+	if len(wanted) == 0 {
+		addRole(as.config.DefaultUserRole)
+	}
+	for _, admin := range as.config.AdminUsers {
+		if admin == user.Username {
+			addRole(AdminRoleName)
+		}
+	}
+
+	var roles []Role
+	if len(wanted) > 0 {
+		cursor, err := as.roleCollection.Find(ctx, bson.M{"name": bson.M{"$in": wanted}})
+		if err != nil {
+			as.logger.Errorf("Error fetching roles: %v", err)
+			return nil, errors.New("internal server error")
+		}
+		if err := cursor.All(ctx, &roles); err != nil {
+			as.logger.Errorf("Error decoding roles: %v", err)
+			return nil, errors.New("internal server error")
+		}
+	}
+
+	roleIDs := make([]primitive.ObjectID, 0, len(roles))
+	names := make([]string, 0, len(roles))
+	for _, role := range roles {
+		roleIDs = append(roleIDs, role.ID)
+		names = append(names, role.Name)
+	}
+	if len(names) < len(wanted) {
+		as.logger.Warnf("Some roles mapped from IdP groups of user %s do not exist: wanted %v, found %v", user.Username, wanted, names)
+	}
+
+	if !sameObjectIDs(user.Roles, roleIDs) {
+		_, err := as.userCollection.UpdateOne(ctx, bson.M{"_id": userObjectID},
+			bson.M{"$set": bson.M{"roles": roleIDs, "updated_at": time.Now()}})
+		if err != nil {
+			as.logger.Errorf("Error updating roles of user %s: %v", user.Username, err)
+			return nil, errors.New("internal server error")
+		}
+		as.logger.Infof("Roles of user %s synced from IdP groups: %v", user.Username, names)
+	}
+
+	return names, nil
+}
+
+// sameObjectIDs reports whether a and b contain the same IDs, ignoring order.
+func sameObjectIDs(a, b []primitive.ObjectID) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	set := make(map[primitive.ObjectID]bool, len(a))
+	for _, id := range a {
+		set[id] = true
+	}
+	for _, id := range b {
+		if !set[id] {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,69 @@
+// backend/internal/services/destinations/models.go
+
+package destinations
+
+import (
+	"fmt"
+	"regexp"
+	"time"
+
+	"github.com/AkshayDubey29/MoniFlux/backend/internal/common"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Sources of a registered destination.
+const (
+	SourceConfig = "config" // The destinations section of the configuration; read-only through the API
+	SourceAPI    = "api"
+)
+
+// namePattern restricts destination names so they can be used in URLs.
+var namePattern = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9._-]{0,127}$`)
+
+// RegisteredDestination is a named destination in the destinations collection, which
+// tests reference by name instead of embedding it.
+type RegisteredDestination struct {
+	ID          primitive.ObjectID `bson:"_id,omitempty" json:"-"`
+	Name        string             `bson:"name" json:"name"`
+	TeamID      string             `bson:"teamID,omitempty" json:"teamID,omitempty"` // Only tests of this team may use the destination; empty allows any test
+	Description string             `bson:"description,omitempty" json:"description,omitempty"`
+	Destination common.Destination `bson:"destination" json:"destination"`
+	Source      string             `bson:"source" json:"source"` // config or api
+	CreatedBy   string             `bson:"createdBy" json:"createdBy"`
+	CreatedAt   time.Time          `bson:"createdAt" json:"createdAt"`
+	UpdatedBy   string             `bson:"updatedBy" json:"updatedBy"`
+	UpdatedAt   time.Time          `bson:"updatedAt" json:"updatedAt"`
+}
+
+// DestinationRequest creates or replaces a registered destination.
+type DestinationRequest struct {
+	TeamID      string             `json:"teamID,omitempty"`
+	Description string             `json:"description,omitempty" validate:"max=256"`
+	Destination common.Destination `json:"destination"`
+}
+
+// ValidateName returns ErrInvalidName if the name cannot be used for a destination.
+func ValidateName(name string) error {
+	if !namePattern.MatchString(name) {
+		return fmt.Errorf("%w: %q must be 1 to 128 letters, digits, '.', '_' or '-' and start with a letter or digit", ErrInvalidName, name)
+	}
+	return nil
+}
+
+// ApplyDefaults fills in the destination settings clients may omit. Socket, S3 and
+// payload options default when the destination's sender is built.
+func ApplyDefaults(dest *common.Destination) {
+	switch dest.Type {
+	case "file":
+		if dest.FileCount == 0 {
+			dest.FileCount = 10
+		}
+		if dest.FileFreq == 0 && dest.FileMaxSizeMB == 0 {
+			dest.FileFreq = 5
+		}
+	case "http":
+		if dest.Port == 0 {
+			dest.Port = 80
+		}
+	}
+}
@@ -0,0 +1,234 @@
+// backend/internal/services/destinations/service.go
+
+package destinations
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/AkshayDubey29/MoniFlux/backend/internal/common"
+	"github.com/AkshayDubey29/MoniFlux/backend/internal/loadgen/delivery"
+	validator "github.com/go-playground/validator/v10"
+	"github.com/sirupsen/logrus"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// Custom errors
+var (
+	ErrDestinationNotFound = errors.New("destination not found")
+	ErrInvalidName         = errors.New("invalid destination name")
+	ErrInvalidDestination  = errors.New("invalid destination")
+	ErrReadOnly            = errors.New("destination is defined in the configuration file")
+)
+
+// configUser records the configuration file as the author of its destinations.
+const configUser = "config"
+
+// DestinationService stores the named destinations tests can reference: those of the
+// configuration file and those registered through the API.
+type DestinationService struct {
+	config     *common.Config
+	logger     *logrus.Logger
+	collection *mongo.Collection
+	validator  *validator.Validate
+}
+
+// NewDestinationService creates a new instance of DestinationService.
+func NewDestinationService(cfg *common.Config, logger *logrus.Logger, mongoClient *mongo.Client) *DestinationService {
+	return &DestinationService{
+		config:     cfg,
+		logger:     logger,
+		collection: mongoClient.Database(cfg.MongoDB).Collection("destinations"),
+		validator:  validator.New(),
+	}
+}
+
+// Validate applies the defaults to the destination and checks it like the
+// destination of a started test. Plaintext API keys are refused, as they would be
+// stored with the destination; use a secret reference instead.
+func (ds *DestinationService) Validate(dest *common.Destination) error {
+	ApplyDefaults(dest)
+	if err := ds.validator.Struct(dest); err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidDestination, err)
+	}
+	if dest.Endpoint == "" && (dest.Type == "http" || delivery.IsSocketDestination(dest.Type)) {
+		return fmt.Errorf("%w: %s destinations require an endpoint", ErrInvalidDestination, dest.Type)
+	}
+	if dest.APIKey != "" {
+		return fmt.Errorf("%w: apiKey cannot be registered, store it as a secret and set secretRef", ErrInvalidDestination)
+	}
+	if err := delivery.CheckAuth(*dest); err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidDestination, err)
+	}
+	if dest.Type == "s3" {
+		if err := delivery.CheckS3Options(dest.S3); err != nil {
+			return fmt.Errorf("%w: %v", ErrInvalidDestination, err)
+		}
+	}
+	return nil
+}
+
+// PutDestination validates and stores the destination under the name, replacing any
+// previous definition registered through the API.
+func (ds *DestinationService) PutDestination(ctx context.Context, name string, req *DestinationRequest, updatedBy string) (*RegisteredDestination, error) {
+	if err := ValidateName(name); err != nil {
+		return nil, err
+	}
+	req.Destination.Name = name
+	if err := ds.Validate(&req.Destination); err != nil {
+		return nil, err
+	}
+
+	existing, err := ds.GetDestination(ctx, name)
+	if err != nil && !errors.Is(err, ErrDestinationNotFound) {
+		return nil, err
+	}
+	if existing != nil && existing.Source == SourceConfig {
+		return nil, fmt.Errorf("%w: %s", ErrReadOnly, name)
+	}
+	return ds.upsert(ctx, name, req, SourceAPI, updatedBy)
+}
+
+// upsert stores the destination under the name.
+func (ds *DestinationService) upsert(ctx context.Context, name string, req *DestinationRequest, source, updatedBy string) (*RegisteredDestination, error) {
+	now := time.Now()
+	update := bson.M{
+		"$set": bson.M{
+			"teamID":      req.TeamID,
+			"description": req.Description,
+			"destination": req.Destination,
+			"source":      source,
+			"updatedBy":   updatedBy,
+			"updatedAt":   now,
+		},
+		"$setOnInsert": bson.M{
+			"createdBy": updatedBy,
+			"createdAt": now,
+		},
+	}
+	opts := options.FindOneAndUpdate().SetUpsert(true).SetReturnDocument(options.After)
+
+	var registered RegisteredDestination
+	if err := ds.collection.FindOneAndUpdate(ctx, bson.M{"name": name}, update, opts).Decode(&registered); err != nil {
+		ds.logger.Errorf("Failed to store destination %s: %v", name, err)
+		return nil, errors.New("internal server error")
+	}
+	ds.logger.Infof("Destination %s stored by %s", name, updatedBy)
+	return &registered, nil
+}
+
+// GetDestination returns a registered destination.
+func (ds *DestinationService) GetDestination(ctx context.Context, name string) (*RegisteredDestination, error) {
+	var registered RegisteredDestination
+	err := ds.collection.FindOne(ctx, bson.M{"name": name}).Decode(&registered)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, fmt.Errorf("%w: %s", ErrDestinationNotFound, name)
+		}
+		ds.logger.Errorf("Error retrieving destination %s: %v", name, err)
+		return nil, errors.New("internal server error")
+	}
+	return &registered, nil
+}
+
+// ListDestinations returns the registered destinations ordered by name. Unless all is
+// set, only the destinations shared with every team and those of the given teams
+// are returned.
+func (ds *DestinationService) ListDestinations(ctx context.Context, teamIDs []string, all bool) ([]RegisteredDestination, error) {
+	filter := bson.M{}
+	if !all {
+		scopes := bson.A{bson.M{"teamID": bson.M{"$in": bson.A{"", nil}}}}
+		if len(teamIDs) > 0 {
+			scopes = append(scopes, bson.M{"teamID": bson.M{"$in": teamIDs}})
+		}
+		filter = bson.M{"$or": scopes}
+	}
+	opts := options.Find().SetSort(bson.D{{Key: "name", Value: 1}})
+	cursor, err := ds.collection.Find(ctx, filter, opts)
+	if err != nil {
+		ds.logger.Errorf("Error listing destinations: %v", err)
+		return nil, errors.New("internal server error")
+	}
+
+	registered := []RegisteredDestination{}
+	if err := cursor.All(ctx, &registered); err != nil {
+		ds.logger.Errorf("Error decoding destinations: %v", err)
+		return nil, errors.New("internal server error")
+	}
+	return registered, nil
+}
+
+// DeleteDestination removes a destination registered through the API. Tests already
+// started with it keep their copy of the destination.
+func (ds *DestinationService) DeleteDestination(ctx context.Context, name string) error {
+	result, err := ds.collection.DeleteOne(ctx, bson.M{"name": name, "source": bson.M{"$ne": SourceConfig}})
+	if err != nil {
+		ds.logger.Errorf("Failed to delete destination %s: %v", name, err)
+		return errors.New("internal server error")
+	}
+	if result.DeletedCount == 0 {
+		if existing, err := ds.GetDestination(ctx, name); err == nil && existing.Source == SourceConfig {
+			return fmt.Errorf("%w: %s", ErrReadOnly, name)
+		}
+		return fmt.Errorf("%w: %s", ErrDestinationNotFound, name)
+	}
+	return nil
+}
+
+// Resolve returns the destination registered under the name for a test of the team.
+// Destinations of other teams are reported as not found so their existence is not
+// leaked.
+func (ds *DestinationService) Resolve(ctx context.Context, name, teamID string) (*RegisteredDestination, error) {
+	registered, err := ds.GetDestination(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+	if registered.TeamID != "" && registered.TeamID != teamID {
+		return nil, fmt.Errorf("%w: %s", ErrDestinationNotFound, name)
+	}
+	return registered, nil
+}
+
+// LoadConfig registers the destinations of the configuration file, shared with every
+// team, and removes the configuration destinations no longer listed. Destinations
+// without a type are HTTP destinations. Invalid destinations are logged and skipped.
+func (ds *DestinationService) LoadConfig(ctx context.Context, configured []common.Destination) error {
+	names := bson.A{}
+	for i, dest := range configured {
+		if dest.Type == "" {
+			dest.Type = "http"
+		}
+		if err := ValidateName(dest.Name); err != nil {
+			ds.logger.Errorf("Skipping configured destination %d: %v", i, err)
+			continue
+		}
+		if err := ds.Validate(&dest); err != nil {
+			ds.logger.Errorf("Skipping configured destination %s: %v", dest.Name, err)
+			continue
+		}
+		existing, err := ds.GetDestination(ctx, dest.Name)
+		if err != nil && !errors.Is(err, ErrDestinationNotFound) {
+			return err
+		}
+		if existing != nil && existing.Source != SourceConfig {
+			ds.logger.Warnf("Configured destination %s replaces the destination registered by %s", dest.Name, existing.UpdatedBy)
+		}
+		req := &DestinationRequest{Description: "Defined in the configuration file", Destination: dest}
+		if _, err := ds.upsert(ctx, dest.Name, req, SourceConfig, configUser); err != nil {
+			return err
+		}
+		names = append(names, dest.Name)
+	}
+
+	result, err := ds.collection.DeleteMany(ctx, bson.M{"source": SourceConfig, "name": bson.M{"$nin": names}})
+	if err != nil {
+		ds.logger.Errorf("Failed to remove stale configured destinations: %v", err)
+		return errors.New("internal server error")
+	}
+	ds.logger.Infof("Loaded %d configured destinations, removed %d", len(names), result.DeletedCount)
+	return nil
+}
@@ -0,0 +1,14 @@
+// backend/internal/services/email/models.go
+
+package email
+
+import "time"
+
+// Message is an email sent to a user.
+type Message struct {
+	To      string    `json:"to"`
+	From    string    `json:"from"`
+	Subject string    `json:"subject"`
+	Body    string    `json:"body"`
+	SentAt  time.Time `json:"sentAt"`
+}
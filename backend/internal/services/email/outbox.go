@@ -0,0 +1,70 @@
+// backend/internal/services/email/outbox.go
+
+package email
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// Outbox is a Sender that keeps messages locally instead of delivering them. Messages
+// are held in memory and, when a directory is configured, also written to it as .eml
+// files. It is used in tests and local development.
+type Outbox struct {
+	dir string
+
+	mu       sync.Mutex
+	messages []Message
+}
+
+// NewOutbox creates an Outbox. An empty dir keeps messages in memory only.
+func NewOutbox(dir string) (*Outbox, error) {
+	if dir != "" {
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			return nil, fmt.Errorf("failed to create outbox directory: %w", err)
+		}
+	}
+	return &Outbox{dir: dir}, nil
+}
+
+// Send stores the message in the outbox.
+func (o *Outbox) Send(ctx context.Context, msg Message) error {
+	if msg.SentAt.IsZero() {
+		msg.SentAt = time.Now()
+	}
+
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.messages = append(o.messages, msg)
+
+	if o.dir != "" {
+		name := fmt.Sprintf("%s-%03d.eml", msg.SentAt.Format("20060102T150405.000"), len(o.messages))
+		if err := os.WriteFile(filepath.Join(o.dir, name), formatMessage(msg), 0o600); err != nil {
+			return fmt.Errorf("failed to write outbox message: %w", err)
+		}
+	}
+	return nil
+}
+
+// Messages returns a copy of all messages sent so far.
+func (o *Outbox) Messages() []Message {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	return append([]Message(nil), o.messages...)
+}
+
+// Last returns the most recent message sent to the address.
+func (o *Outbox) Last(to string) (Message, bool) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	for i := len(o.messages) - 1; i >= 0; i-- {
+		if o.messages[i].To == to {
+			return o.messages[i], true
+		}
+	}
+	return Message{}, false
+}
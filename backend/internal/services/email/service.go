@@ -0,0 +1,86 @@
+// backend/internal/services/email/service.go
+
+package email
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/smtp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/AkshayDubey29/MoniFlux/backend/internal/common"
+	"github.com/sirupsen/logrus"
+)
+
+// Sender delivers account emails such as password resets and address verification.
+type Sender interface {
+	Send(ctx context.Context, msg Message) error
+}
+
+// NewSender creates the Sender selected by cfg.Provider: "smtp", "outbox" or "log".
+func NewSender(cfg common.EmailConfig, logger *logrus.Logger) (Sender, error) {
+	switch cfg.Provider {
+	case "smtp":
+		return &SMTPSender{config: cfg}, nil
+	case "outbox":
+		return NewOutbox(cfg.OutboxDir)
+	case "", "log":
+		return &LogSender{logger: logger}, nil
+	default:
+		return nil, fmt.Errorf("unknown email provider %q", cfg.Provider)
+	}
+}
+
+// LogSender writes emails to the application log instead of delivering them.
+// It is intended for development setups without a mail server.
+type LogSender struct {
+	logger *logrus.Logger
+}
+
+// Send logs the message.
+func (ls *LogSender) Send(ctx context.Context, msg Message) error {
+	ls.logger.WithFields(logrus.Fields{
+		"to":      msg.To,
+		"subject": msg.Subject,
+	}).Infof("Email not delivered (log provider):\n%s", msg.Body)
+	return nil
+}
+
+// SMTPSender delivers emails through an SMTP relay using PLAIN authentication.
+type SMTPSender struct {
+	config common.EmailConfig
+}
+
+// Send delivers the message through the configured SMTP server.
+func (ss *SMTPSender) Send(ctx context.Context, msg Message) error {
+	if msg.From == "" {
+		msg.From = ss.config.From
+	}
+	addr := net.JoinHostPort(ss.config.SMTPHost, strconv.Itoa(ss.config.SMTPPort))
+
+	var auth smtp.Auth
+	if ss.config.SMTPUsername != "" {
+		auth = smtp.PlainAuth("", ss.config.SMTPUsername, ss.config.SMTPPassword, ss.config.SMTPHost)
+	}
+
+	if err := smtp.SendMail(addr, auth, msg.From, []string{msg.To}, formatMessage(msg)); err != nil {
+		return fmt.Errorf("failed to send email to %s: %w", msg.To, err)
+	}
+	return nil
+}
+
+// formatMessage renders the message as an RFC 5322 plain-text email.
+func formatMessage(msg Message) []byte {
+	var b strings.Builder
+	b.WriteString("From: " + msg.From + "\r\n")
+	b.WriteString("To: " + msg.To + "\r\n")
+	b.WriteString("Subject: " + msg.Subject + "\r\n")
+	b.WriteString("Date: " + time.Now().Format(time.RFC1123Z) + "\r\n")
+	b.WriteString("MIME-Version: 1.0\r\n")
+	b.WriteString("Content-Type: text/plain; charset=UTF-8\r\n\r\n")
+	b.WriteString(strings.ReplaceAll(msg.Body, "\n", "\r\n"))
+	return []byte(b.String())
+}
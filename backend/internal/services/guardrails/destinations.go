@@ -0,0 +1,373 @@
+// backend/internal/services/guardrails/destinations.go
+
+package guardrails
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/url"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// resolveTimeout bounds the DNS lookup of a destination host.
+const resolveTimeout = 3 * time.Second
+
+// blockedNetworks can never be targeted, not even when allow-listed. Link-local
+// ranges host the cloud instance metadata services (169.254.169.254, fd00:ec2::254)
+// that expose instance credentials.
+var blockedNetworks = mustParseCIDRs(
+	"169.254.0.0/16",
+	"fe80::/10",
+	"fd00:ec2::254/128",
+	"100.100.100.200/32", // Alibaba Cloud metadata service
+)
+
+// blockedHosts are well-known names of instance metadata services.
+var blockedHosts = map[string]bool{
+	"metadata":                   true,
+	"metadata.google.internal":   true,
+	"metadata.goog":              true,
+	"instance-data":              true,
+	"instance-data.ec2.internal": true,
+}
+
+func mustParseCIDRs(cidrs ...string) []*net.IPNet {
+	networks := make([]*net.IPNet, len(cidrs))
+	for i, cidr := range cidrs {
+		_, network, err := net.ParseCIDR(cidr)
+		if err != nil {
+			panic(err)
+		}
+		networks[i] = network
+	}
+	return networks
+}
+
+// destinationPattern is a parsed entry of an allowed destinations list.
+type destinationPattern struct {
+	host    string     // Exact host name or IP, lower case
+	suffix  string     // Domain suffix for "*.domain" patterns, including the leading dot
+	network *net.IPNet // CIDR patterns; every resolved address must be in the network
+	port    int        // Required port; 0 allows any
+}
+
+// parseDestinationPattern parses "host", "host:port", "*.domain", "*.domain:port",
+// "[ipv6]:port" and "CIDR" patterns.
+func parseDestinationPattern(pattern string) (*destinationPattern, error) {
+	p := &destinationPattern{}
+	value := strings.ToLower(strings.TrimSpace(pattern))
+	if value == "" {
+		return nil, fmt.Errorf("empty destination pattern")
+	}
+
+	if strings.Contains(value, "/") {
+		_, network, err := net.ParseCIDR(value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid CIDR in destination pattern %q", pattern)
+		}
+		p.network = network
+		return p, nil
+	}
+
+	if host, port, err := net.SplitHostPort(value); err == nil {
+		portNumber, err := strconv.Atoi(port)
+		if err != nil || portNumber < 1 || portNumber > 65535 {
+			return nil, fmt.Errorf("invalid port in destination pattern %q", pattern)
+		}
+		value, p.port = host, portNumber
+	}
+
+	if strings.HasPrefix(value, "*.") {
+		p.suffix = value[1:]
+		if strings.Contains(p.suffix[1:], "*") || len(p.suffix) < 2 {
+			return nil, fmt.Errorf("invalid wildcard in destination pattern %q", pattern)
+		}
+		return p, nil
+	}
+	if strings.Contains(value, "*") {
+		return nil, fmt.Errorf("wildcards are only allowed as a leading \"*.\" in destination pattern %q", pattern)
+	}
+	p.host = strings.Trim(value, "[]")
+	return p, nil
+}
+
+// ValidateDestinationPatterns returns an error for the first invalid pattern.
+func ValidateDestinationPatterns(patterns []string) error {
+	for _, pattern := range patterns {
+		if _, err := parseDestinationPattern(pattern); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// matches reports whether the destination host, its resolved addresses and port
+// match the pattern.
+func (p *destinationPattern) matches(host string, ips []net.IP, port int) bool {
+	if p.port != 0 && p.port != port {
+		return false
+	}
+	switch {
+	case p.network != nil:
+		if len(ips) == 0 {
+			return false
+		}
+		for _, ip := range ips {
+			if !p.network.Contains(ip) {
+				return false
+			}
+		}
+		return true
+	case p.suffix != "":
+		return strings.HasSuffix(host, p.suffix)
+	default:
+		return host == p.host
+	}
+}
+
+// httpTarget returns the host and port an HTTP destination sends to. An explicit
+// port in the endpoint URL takes precedence over the destination port.
+func httpTarget(endpoint string, destinationPort int) (string, int, error) {
+	if !strings.Contains(endpoint, "://") {
+		endpoint = "http://" + endpoint
+	}
+	u, err := url.Parse(endpoint)
+	if err != nil || u.Hostname() == "" {
+		return "", 0, fmt.Errorf("invalid endpoint %q", endpoint)
+	}
+	host := strings.ToLower(strings.TrimSuffix(u.Hostname(), "."))
+
+	port := destinationPort
+	if u.Port() != "" {
+		port, _ = strconv.Atoi(u.Port())
+	}
+	if port == 0 {
+		port = 80
+		if u.Scheme == "https" {
+			port = 443
+		}
+	}
+	return host, port, nil
+}
+
+// socketTarget returns the host and port a syslog, tcp or udp destination sends to.
+// The endpoint is a host, host:port or a URL such as tcp://host:port.
+func socketTarget(endpoint string, destinationPort, defaultPort int) (string, int, error) {
+	host := endpoint
+	if i := strings.Index(host, "://"); i >= 0 {
+		host = host[i+3:]
+	}
+	host = strings.TrimSuffix(host, "/")
+	port := destinationPort
+	if h, p, err := net.SplitHostPort(host); err == nil {
+		if port, err = strconv.Atoi(p); err != nil {
+			return "", 0, fmt.Errorf("invalid endpoint %q", endpoint)
+		}
+		host = h
+	}
+	host = strings.ToLower(strings.TrimSuffix(strings.Trim(host, "[]"), "."))
+	if host == "" || strings.ContainsAny(host, "/?#@ ") {
+		return "", 0, fmt.Errorf("invalid endpoint %q", endpoint)
+	}
+	if port == 0 {
+		port = defaultPort
+	}
+	if port < 1 || port > 65535 {
+		return "", 0, fmt.Errorf("a port is required for endpoint %q", endpoint)
+	}
+	return host, port, nil
+}
+
+// resolveHost returns the addresses of the host; IP literals are returned as is.
+func resolveHost(ctx context.Context, host string) ([]net.IP, error) {
+	if ip := net.ParseIP(host); ip != nil {
+		return []net.IP{ip}, nil
+	}
+	ctx, cancel := context.WithTimeout(ctx, resolveTimeout)
+	defer cancel()
+	addrs, err := net.DefaultResolver.LookupIPAddr(ctx, host)
+	if err != nil {
+		return nil, err
+	}
+	ips := make([]net.IP, len(addrs))
+	for i, addr := range addrs {
+		ips[i] = addr.IP
+	}
+	return ips, nil
+}
+
+// isBlockedAddress reports whether the address belongs to a metadata service.
+func isBlockedAddress(ip net.IP) bool {
+	for _, network := range blockedNetworks {
+		if network.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// isInternalAddress reports whether the address is loopback, private or otherwise
+// not publicly routable. Such addresses may only be targeted when allow-listed.
+func isInternalAddress(ip net.IP) bool {
+	return ip.IsLoopback() || ip.IsPrivate() || ip.IsUnspecified() ||
+		ip.IsMulticast() || ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast()
+}
+
+// DialGuard applies the destination rules to the address of every connection, as a
+// destination validated by name can later resolve to, or redirect to, another address.
+type DialGuard struct {
+	restricted bool // An allow-list is set; otherwise any public address is allowed
+	allowed    []*destinationPattern
+}
+
+// NewDialGuard returns a guard allowing the destinations matched by the patterns,
+// or any public address if there are none. Invalid patterns match nothing.
+func NewDialGuard(allowed []string) *DialGuard {
+	g := &DialGuard{restricted: len(allowed) > 0}
+	for _, pattern := range allowed {
+		if p, err := parseDestinationPattern(pattern); err == nil {
+			g.allowed = append(g.allowed, p)
+		}
+	}
+	return g
+}
+
+// Control returns a net.Dialer Control function rejecting connections to host at a
+// blocked address, or at an address the allowed destinations do not match. A nil
+// guard allows every connection.
+func (g *DialGuard) Control(host string) func(network, address string, c syscall.RawConn) error {
+	if g == nil {
+		return nil
+	}
+	host = strings.ToLower(strings.TrimSuffix(host, "."))
+	return func(network, address string, _ syscall.RawConn) error {
+		ipString, portString, err := net.SplitHostPort(address)
+		if err != nil {
+			return err
+		}
+		ip := net.ParseIP(ipString)
+		port, err := strconv.Atoi(portString)
+		if ip == nil || err != nil {
+			return fmt.Errorf("%w: invalid address %s", ErrAddressNotAllowed, address)
+		}
+		return g.check(host, ip, port)
+	}
+}
+
+// check returns an error wrapping ErrAddressNotAllowed if host may not be reached at ip:port.
+func (g *DialGuard) check(host string, ip net.IP, port int) error {
+	if blockedHosts[host] || isBlockedAddress(ip) {
+		return fmt.Errorf("%w: %s (%s) is a link-local or instance metadata address", ErrAddressNotAllowed, host, ip)
+	}
+	if g.restricted {
+		for _, p := range g.allowed {
+			if p.matches(host, []net.IP{ip}, port) {
+				return nil
+			}
+		}
+		return fmt.Errorf("%w: %s (%s) port %d is not in the allowed destinations", ErrAddressNotAllowed, host, ip, port)
+	}
+	if isInternalAddress(ip) {
+		return fmt.Errorf("%w: %s (%s) is an internal address", ErrAddressNotAllowed, host, ip)
+	}
+	return nil
+}
+
+// checkHTTPDestination adds a violation for field if the HTTP endpoint may not be
+// targeted under the allowed destination patterns.
+func checkHTTPDestination(ctx context.Context, report *Report, field, endpoint string, port int, allowed []string) {
+	if endpoint == "" {
+		report.add(RuleDestinationRequired, field, "an endpoint is required")
+		return
+	}
+	host, port, err := httpTarget(endpoint, port)
+	if err != nil {
+		report.add(RuleDestinationRequired, field, "%v", err)
+		return
+	}
+	checkDestinationHost(ctx, report, field, host, port, allowed)
+}
+
+// checkSocketDestination adds a violation for field if the syslog, tcp or udp
+// endpoint may not be targeted under the allowed destination patterns.
+func checkSocketDestination(ctx context.Context, report *Report, field, endpoint string, port, defaultPort int, allowed []string) {
+	if endpoint == "" {
+		report.add(RuleDestinationRequired, field, "an endpoint is required")
+		return
+	}
+	host, port, err := socketTarget(endpoint, port, defaultPort)
+	if err != nil {
+		report.add(RuleDestinationRequired, field, "%v", err)
+		return
+	}
+	checkDestinationHost(ctx, report, field, host, port, allowed)
+}
+
+// checkDestinationHost adds a violation for field if host:port is blocked, cannot be
+// resolved or is not allowed.
+func checkDestinationHost(ctx context.Context, report *Report, field, host string, port int, allowed []string) {
+	if blockedHosts[host] {
+		report.add(RuleDestinationBlocked, field, "%s is an instance metadata service and cannot be targeted", host)
+		return
+	}
+
+	ips, err := resolveHost(ctx, host)
+	if err != nil {
+		report.add(RuleDestinationUnresolved, field, "host %s could not be resolved", host)
+		return
+	}
+	for _, ip := range ips {
+		if isBlockedAddress(ip) {
+			report.add(RuleDestinationBlocked, field, "%s (%s) is a link-local or instance metadata address and cannot be targeted", host, ip)
+			return
+		}
+	}
+
+	if len(allowed) > 0 {
+		for _, pattern := range allowed {
+			p, err := parseDestinationPattern(pattern)
+			if err != nil {
+				continue
+			}
+			if p.matches(host, ips, port) {
+				return
+			}
+		}
+		report.add(RuleDestinationNotAllowed, field, "%s:%d is not in the allowed destinations (%s)", host, port, strings.Join(allowed, ", "))
+		return
+	}
+
+	// Without an allow-list only public addresses may be targeted.
+	for _, ip := range ips {
+		if isInternalAddress(ip) {
+			report.add(RuleDestinationBlocked, field, "%s (%s) is an internal address; internal destinations must be allow-listed by an administrator", host, ip)
+			return
+		}
+	}
+}
+
+// checkFilePath adds a violation if the file destination is outside the allowed directories.
+func checkFilePath(report *Report, path string, allowed []string) {
+	if path == "" {
+		report.add(RuleDestinationRequired, "destination.filePath", "a file path is required for file destinations")
+		return
+	}
+	if len(allowed) == 0 {
+		return
+	}
+	cleaned := filepath.Clean(path)
+	if filepath.IsAbs(cleaned) {
+		for _, dir := range allowed {
+			dir = filepath.Clean(dir)
+			if cleaned == dir || strings.HasPrefix(cleaned, strings.TrimSuffix(dir, string(filepath.Separator))+string(filepath.Separator)) {
+				return
+			}
+		}
+	}
+	report.add(RuleFilePathNotAllowed, "destination.filePath", "%s is not in an allowed directory (%s)", path, strings.Join(allowed, ", "))
+}
@@ -0,0 +1,85 @@
+// backend/internal/services/guardrails/models.go
+
+package guardrails
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/AkshayDubey29/MoniFlux/backend/internal/common"
+)
+
+// DefaultRoleLimitKey is the role limit applied to users none of whose roles has a limit.
+const DefaultRoleLimitKey = "default"
+
+// Rules reported in violations.
+const (
+	RuleDestinationRequired   = "destination_required"
+	RuleDestinationNotAllowed = "destination_not_allowed"
+	RuleDestinationBlocked    = "destination_blocked"
+	RuleDestinationUnresolved = "destination_unresolved"
+	RuleFilePathNotAllowed    = "file_path_not_allowed"
+	RuleMaxRate               = "max_rate"
+	RuleMaxDuration           = "max_duration"
+	RuleMaxConcurrentTests    = "max_concurrent_tests"
+	RuleCapacityExceeded      = "capacity_exceeded"
+	RuleInvalidField          = "invalid_field"
+)
+
+// Policy is a set of guardrails. The global policy (empty TeamID) applies to every
+// test; a team policy overrides the allowed destinations and file paths, the role
+// limits it lists and the concurrency limit for the tests of that team.
+type Policy struct {
+	TeamID              string                      `bson:"teamID" json:"teamID,omitempty"`
+	AllowedDestinations []string                    `bson:"allowedDestinations" json:"allowedDestinations" validate:"omitempty,dive,required"` // Host, *.domain or CIDR patterns, optionally with :port; empty allows any public host
+	AllowedFilePaths    []string                    `bson:"allowedFilePaths" json:"allowedFilePaths" validate:"omitempty,dive,required"`       // Directory prefixes for file destinations; empty allows any path
+	RoleLimits          map[string]common.RoleLimit `bson:"roleLimits" json:"roleLimits" validate:"omitempty,dive"`
+	MaxConcurrentTests  int                         `bson:"maxConcurrentTests" json:"maxConcurrentTests" validate:"min=0"` // Running tests (of the team, for team policies); 0 is unlimited
+	UpdatedBy           string                      `bson:"updatedBy,omitempty" json:"updatedBy,omitempty"`
+	UpdatedAt           time.Time                   `bson:"updatedAt,omitempty" json:"updatedAt,omitempty"`
+}
+
+// Violation explains why a test configuration is rejected.
+type Violation struct {
+	Rule    string `json:"rule"`
+	Field   string `json:"field,omitempty"`
+	Message string `json:"message"`
+}
+
+// Report is the outcome of evaluating a test configuration against the guardrails.
+type Report struct {
+	Allowed    bool        `json:"allowed"`
+	Violations []Violation `json:"violations"`
+}
+
+func (r *Report) add(rule, field, format string, args ...interface{}) {
+	r.Violations = append(r.Violations, Violation{Rule: rule, Field: field, Message: fmt.Sprintf(format, args...)})
+}
+
+// Err returns a *ViolationError if the configuration was rejected, nil otherwise.
+func (r *Report) Err() error {
+	if len(r.Violations) == 0 {
+		return nil
+	}
+	return &ViolationError{Violations: r.Violations}
+}
+
+// ViolationError is returned when starting a test that breaks the guardrails.
+// It matches ErrGuardrailViolation with errors.Is.
+type ViolationError struct {
+	Violations []Violation
+}
+
+func (e *ViolationError) Error() string {
+	messages := make([]string, len(e.Violations))
+	for i, v := range e.Violations {
+		messages[i] = v.Message
+	}
+	return ErrGuardrailViolation.Error() + ": " + strings.Join(messages, "; ")
+}
+
+// Is reports whether target is ErrGuardrailViolation.
+func (e *ViolationError) Is(target error) bool {
+	return target == ErrGuardrailViolation
+}
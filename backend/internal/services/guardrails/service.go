@@ -0,0 +1,282 @@
+// backend/internal/services/guardrails/service.go
+
+package guardrails
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/AkshayDubey29/MoniFlux/backend/internal/api/models"
+	"github.com/AkshayDubey29/MoniFlux/backend/internal/common"
+	"github.com/sirupsen/logrus"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// Custom errors
+var (
+	ErrGuardrailViolation = errors.New("test configuration violates guardrails")
+	ErrPolicyNotFound     = errors.New("guardrail policy not found")
+	ErrInvalidPolicy      = errors.New("invalid guardrail policy")
+	ErrAddressNotAllowed  = errors.New("address not allowed by the destination guardrails")
+)
+
+// GuardrailService stores the guardrail policies and evaluates test configurations
+// against them before tests are created or started.
+type GuardrailService struct {
+	config           *common.Config
+	logger           *logrus.Logger
+	policyCollection *mongo.Collection
+	testCollection   *mongo.Collection
+}
+
+// NewGuardrailService creates a new instance of GuardrailService.
+func NewGuardrailService(cfg *common.Config, logger *logrus.Logger, mongoClient *mongo.Client) *GuardrailService {
+	db := mongoClient.Database(cfg.MongoDB)
+	return &GuardrailService{
+		config:           cfg,
+		logger:           logger,
+		policyCollection: db.Collection("guardrail_policies"),
+		testCollection:   db.Collection("tests"),
+	}
+}
+
+// configPolicy returns the global policy defined in the configuration file.
+func (gs *GuardrailService) configPolicy() *Policy {
+	g := gs.config.Guardrails
+	return &Policy{
+		AllowedDestinations: g.AllowedDestinations,
+		AllowedFilePaths:    g.AllowedFilePaths,
+		RoleLimits:          g.RoleLimits,
+		MaxConcurrentTests:  g.MaxConcurrentTests,
+	}
+}
+
+// GetPolicy returns the policy of the team, or the global policy for an empty teamID.
+// Until an administrator sets it, the global policy is the one from the configuration.
+func (gs *GuardrailService) GetPolicy(ctx context.Context, teamID string) (*Policy, error) {
+	var policy Policy
+	err := gs.policyCollection.FindOne(ctx, bson.M{"teamID": teamID}).Decode(&policy)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			if teamID == "" {
+				return gs.configPolicy(), nil
+			}
+			return nil, ErrPolicyNotFound
+		}
+		gs.logger.Errorf("Error retrieving guardrail policy for team %q: %v", teamID, err)
+		return nil, errors.New("internal server error")
+	}
+	return &policy, nil
+}
+
+// SetPolicy replaces the policy of the team, or the global policy for an empty teamID.
+func (gs *GuardrailService) SetPolicy(ctx context.Context, teamID string, policy *Policy, updatedBy string) (*Policy, error) {
+	if err := ValidateDestinationPatterns(policy.AllowedDestinations); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidPolicy, err)
+	}
+
+	policy.TeamID = teamID
+	policy.UpdatedBy = updatedBy
+	policy.UpdatedAt = time.Now()
+
+	_, err := gs.policyCollection.ReplaceOne(ctx, bson.M{"teamID": teamID}, policy, options.Replace().SetUpsert(true))
+	if err != nil {
+		gs.logger.Errorf("Failed to store guardrail policy for team %q: %v", teamID, err)
+		return nil, errors.New("internal server error")
+	}
+
+	gs.logger.Infof("Guardrail policy for team %q updated by user %s", teamID, updatedBy)
+	return policy, nil
+}
+
+// DeletePolicy removes the policy of the team. Deleting the global policy restores
+// the one from the configuration.
+func (gs *GuardrailService) DeletePolicy(ctx context.Context, teamID string) error {
+	result, err := gs.policyCollection.DeleteOne(ctx, bson.M{"teamID": teamID})
+	if err != nil {
+		gs.logger.Errorf("Failed to delete guardrail policy for team %q: %v", teamID, err)
+		return errors.New("internal server error")
+	}
+	if result.DeletedCount == 0 && teamID != "" {
+		return ErrPolicyNotFound
+	}
+	return nil
+}
+
+// effectivePolicy merges the team policy, if any, over the global policy. It also
+// returns the team policy on its own for the team concurrency limit.
+func (gs *GuardrailService) effectivePolicy(ctx context.Context, teamID string) (*Policy, *Policy, error) {
+	global, err := gs.GetPolicy(ctx, "")
+	if err != nil {
+		return nil, nil, err
+	}
+	if teamID == "" {
+		return global, nil, nil
+	}
+
+	team, err := gs.GetPolicy(ctx, teamID)
+	if errors.Is(err, ErrPolicyNotFound) {
+		return global, nil, nil
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+
+	merged := *global
+	if len(team.AllowedDestinations) > 0 {
+		merged.AllowedDestinations = team.AllowedDestinations
+	}
+	if len(team.AllowedFilePaths) > 0 {
+		merged.AllowedFilePaths = team.AllowedFilePaths
+	}
+	if len(team.RoleLimits) > 0 {
+		merged.RoleLimits = map[string]common.RoleLimit{}
+		for role, limit := range global.RoleLimits {
+			merged.RoleLimits[role] = limit
+		}
+		for role, limit := range team.RoleLimits {
+			merged.RoleLimits[role] = limit
+		}
+	}
+	return &merged, team, nil
+}
+
+// DialGuard returns the guard of the connections opened to the destinations of the
+// team's tests, under the allowed destinations of its effective policy.
+func (gs *GuardrailService) DialGuard(ctx context.Context, teamID string) (*DialGuard, error) {
+	policy, _, err := gs.effectivePolicy(ctx, teamID)
+	if err != nil {
+		return nil, err
+	}
+	return NewDialGuard(policy.AllowedDestinations), nil
+}
+
+// Evaluate checks the test configuration against the guardrails applying to its
+// team and to the roles of the principal. Concurrency limits are only checked when
+// starting is true. Without a principal (e.g. scheduled starts) role limits are skipped.
+func (gs *GuardrailService) Evaluate(ctx context.Context, principal *models.Principal, test *models.Test, starting bool) (*Report, error) {
+	policy, teamPolicy, err := gs.effectivePolicy(ctx, test.TeamID)
+	if err != nil {
+		return nil, err
+	}
+
+	report := &Report{Violations: []Violation{}}
+
+	switch test.Destination.Type {
+	case "http":
+		checkHTTPDestination(ctx, report, "destination.endpoint", test.Destination.Endpoint, test.Destination.Port, policy.AllowedDestinations)
+		// Credentials are sent to the OAuth2 token endpoint too, so it is held to the same rules.
+		if auth := test.Destination.Auth; auth != nil && auth.OAuth2 != nil {
+			checkHTTPDestination(ctx, report, "destination.auth.oauth2.tokenURL", auth.OAuth2.TokenURL, 0, policy.AllowedDestinations)
+		}
+	case "syslog", "tcp", "udp":
+		defaultPort := 0
+		if test.Destination.Type == "syslog" {
+			defaultPort = 514
+			if socket := test.Destination.Socket; socket != nil && socket.TLS || test.Destination.Auth != nil && test.Destination.Auth.TLS != nil {
+				defaultPort = 6514
+			}
+		}
+		checkSocketDestination(ctx, report, "destination.endpoint", test.Destination.Endpoint, test.Destination.Port, defaultPort, policy.AllowedDestinations)
+	case "s3":
+		// Without an endpoint, objects are uploaded to AWS in the destination's region.
+		if test.Destination.Endpoint != "" {
+			checkHTTPDestination(ctx, report, "destination.endpoint", test.Destination.Endpoint, test.Destination.Port, policy.AllowedDestinations)
+		} else if s3 := test.Destination.S3; s3 != nil {
+			region := s3.Region
+			if region == "" {
+				region = "us-east-1"
+			}
+			checkDestinationHost(ctx, report, "destination.s3.bucket", s3.Bucket+".s3."+region+".amazonaws.com", 443, policy.AllowedDestinations)
+		}
+	case "file":
+		checkFilePath(report, test.Destination.FilePath, policy.AllowedFilePaths)
+	}
+
+	if principal != nil {
+		limit := roleLimit(policy.RoleLimits, principal.Roles)
+		rate := test.LogRate + test.MetricsRate + test.TraceRate
+		if limit.MaxRate > 0 && rate > limit.MaxRate {
+			report.add(RuleMaxRate, "logRate", "combined rate of %d/s exceeds the limit of %d/s for your roles", rate, limit.MaxRate)
+		}
+		if limit.MaxDuration > 0 && test.Duration > limit.MaxDuration {
+			report.add(RuleMaxDuration, "duration", "duration of %ds exceeds the limit of %ds for your roles", test.Duration, limit.MaxDuration)
+		}
+	}
+
+	if starting {
+		if err := gs.checkConcurrency(ctx, report, test, policy.MaxConcurrentTests, teamPolicy); err != nil {
+			return nil, err
+		}
+	}
+
+	report.Allowed = len(report.Violations) == 0
+	return report, nil
+}
+
+// checkConcurrency adds a violation if starting the test would exceed the global or
+// team limit on running tests. Tests queued for capacity count as running.
+func (gs *GuardrailService) checkConcurrency(ctx context.Context, report *Report, test *models.Test, globalMax int, teamPolicy *Policy) error {
+	running := bson.M{"status": bson.M{"$in": bson.A{"Running", "Queued"}}, "testID": bson.M{"$ne": test.TestID}}
+
+	if globalMax > 0 {
+		count, err := gs.testCollection.CountDocuments(ctx, running)
+		if err != nil {
+			gs.logger.Errorf("Error counting running tests: %v", err)
+			return errors.New("internal server error")
+		}
+		if count >= int64(globalMax) {
+			report.add(RuleMaxConcurrentTests, "", "%d tests are already running or queued; the limit is %d", count, globalMax)
+		}
+	}
+
+	if teamPolicy != nil && teamPolicy.MaxConcurrentTests > 0 {
+		running["teamID"] = test.TeamID
+		count, err := gs.testCollection.CountDocuments(ctx, running)
+		if err != nil {
+			gs.logger.Errorf("Error counting running tests of team %s: %v", test.TeamID, err)
+			return errors.New("internal server error")
+		}
+		if count >= int64(teamPolicy.MaxConcurrentTests) {
+			report.add(RuleMaxConcurrentTests, "teamID", "team %s already has %d tests running or queued; its limit is %d", test.TeamID, count, teamPolicy.MaxConcurrentTests)
+		}
+	}
+	return nil
+}
+
+// roleLimit returns the most permissive limit among the roles that have one, where
+// zero is unlimited. Users none of whose roles has a limit get the default limit.
+func roleLimit(limits map[string]common.RoleLimit, roles []string) common.RoleLimit {
+	var result common.RoleLimit
+	found := false
+	for _, role := range roles {
+		limit, ok := limits[role]
+		if !ok {
+			continue
+		}
+		if !found {
+			result, found = limit, true
+			continue
+		}
+		result.MaxRate = mostPermissive(result.MaxRate, limit.MaxRate)
+		result.MaxDuration = mostPermissive(result.MaxDuration, limit.MaxDuration)
+	}
+	if !found {
+		return limits[DefaultRoleLimitKey]
+	}
+	return result
+}
+
+func mostPermissive(a, b int) int {
+	if a == 0 || b == 0 {
+		return 0
+	}
+	if a > b {
+		return a
+	}
+	return b
+}
@@ -5,11 +5,17 @@ package monitoring
 import (
 	"encoding/json"
 	"net/http"
+	"strconv"
 	"time"
 
+	"github.com/AkshayDubey29/MoniFlux/backend/internal/api/problem"
 	"github.com/sirupsen/logrus"
-	"go.mongodb.org/mongo-driver/bson"
-	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// Limits of the health check history.
+const (
+	defaultHistoryLimit = 100
+	maxHistoryLimit     = 1000
 )
 
 // MonitoringHandlers encapsulates handlers related to monitoring.
@@ -26,64 +32,74 @@ func NewMonitoringHandlers(ms *MonitoringService, logger *logrus.Logger) *Monito
 	}
 }
 
-// GetHealthCheckHistoryHandler retrieves the health check history for a specific service.
-func (mh *MonitoringHandlers) GetHealthCheckHistoryHandler(w http.ResponseWriter, r *http.Request) {
-	serviceName := r.URL.Query().Get("service")
-	if serviceName == "" {
-		http.Error(w, "Missing 'service' query parameter", http.StatusBadRequest)
-		return
-	}
-
-	// Define the time range for the history (e.g., last 24 hours)
-	since := time.Now().Add(-24 * time.Hour)
+// LivezHandler reports that the process is serving requests. It checks no
+// dependency, so that an unavailable database does not get the server restarted.
+func (mh *MonitoringHandlers) LivezHandler(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, map[string]string{"status": "ok"})
+}
 
-	// Query the database for health checks of the specified service since the defined time
-	filter := bson.M{
-		"service_name": serviceName,
-		"checked_at": bson.M{
-			"$gte": since,
-		},
+// ReadyzHandler runs the dependency checks and responds with 503 Service Unavailable
+// when a required check fails. The results are not recorded.
+func (mh *MonitoringHandlers) ReadyzHandler(w http.ResponseWriter, r *http.Request) {
+	report := mh.monitoringService.RunChecks(r.Context())
+	status := http.StatusOK
+	if report.Status != StatusHealthy {
+		status = http.StatusServiceUnavailable
 	}
+	writeJSON(w, status, report)
+}
 
-	opts := options.Find().SetSort(bson.D{{"checked_at", -1}}).SetLimit(100) // Limit to last 100 records
+// GetHealthCheckHistoryHandler retrieves the health check history, optionally of a
+// single check given by the 'service' query parameter. 'since' (RFC3339) defaults to
+// 24 hours ago and 'limit' to 100 records.
+func (mh *MonitoringHandlers) GetHealthCheckHistoryHandler(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	serviceName := query.Get("service")
 
-	cursor, err := mh.monitoringService.healthCheckCol.Find(r.Context(), filter, opts)
-	if err != nil {
-		mh.logger.Errorf("Error fetching health check history: %v", err)
-		http.Error(w, "Internal server error", http.StatusInternalServerError)
-		return
+	since := time.Now().Add(-24 * time.Hour)
+	if value := query.Get("since"); value != "" {
+		parsed, err := time.Parse(time.RFC3339, value)
+		if err != nil {
+			problem.Error(w, http.StatusBadRequest, problem.CodeInvalidRequest, "Invalid 'since' query parameter: expected RFC3339")
+			return
+		}
+		since = parsed
 	}
-	defer cursor.Close(r.Context())
 
-	var healthChecks []HealthCheck
-	for cursor.Next(r.Context()) {
-		var hc HealthCheck
-		if err := cursor.Decode(&hc); err != nil {
-			mh.logger.Errorf("Error decoding health check record: %v", err)
-			continue
+	limit := int64(defaultHistoryLimit)
+	if value := query.Get("limit"); value != "" {
+		parsed, err := strconv.ParseInt(value, 10, 64)
+		if err != nil || parsed < 1 || parsed > maxHistoryLimit {
+			problem.Error(w, http.StatusBadRequest, problem.CodeInvalidRequest, "Invalid 'limit' query parameter: expected 1 to 1000")
+			return
 		}
-		healthChecks = append(healthChecks, hc)
+		limit = parsed
 	}
 
-	if err := cursor.Err(); err != nil {
-		mh.logger.Errorf("Cursor error: %v", err)
-		http.Error(w, "Internal server error", http.StatusInternalServerError)
+	healthChecks, err := mh.monitoringService.History(r.Context(), serviceName, since, limit)
+	if err != nil {
+		mh.logger.Errorf("Error fetching health check history: %v", err)
+		problem.Error(w, http.StatusInternalServerError, problem.CodeInternal, "Internal server error")
 		return
 	}
-
-	// Respond with the health check history
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(http.StatusOK)
-	json.NewEncoder(w).Encode(healthChecks)
+	writeJSON(w, http.StatusOK, healthChecks)
 }
 
-// HealthCheckStatusHandler provides a simple health check endpoint for the monitoring service itself.
+// HealthCheckStatusHandler responds with the most recently recorded result of each
+// check, as run by the health check scheduler.
 func (mh *MonitoringHandlers) HealthCheckStatusHandler(w http.ResponseWriter, r *http.Request) {
-	response := map[string]string{
-		"status": "ok",
+	healthChecks, err := mh.monitoringService.LatestResults(r.Context())
+	if err != nil {
+		mh.logger.Errorf("Error fetching health check status: %v", err)
+		problem.Error(w, http.StatusInternalServerError, problem.CodeInternal, "Internal server error")
+		return
 	}
+	writeJSON(w, http.StatusOK, healthChecks)
+}
 
+func writeJSON(w http.ResponseWriter, status int, body interface{}) {
 	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(http.StatusOK)
-	json.NewEncoder(w).Encode(response)
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(body)
 }
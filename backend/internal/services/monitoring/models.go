@@ -3,16 +3,48 @@
 package monitoring
 
 import (
+	"context"
 	"time"
 
 	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
+// Statuses of a health check and of a Report.
+const (
+	StatusHealthy   = "healthy"
+	StatusUnhealthy = "unhealthy"
+)
+
+// Names of the dependency checks of the servers.
+const (
+	CheckMongoDB      = "mongodb"      // MongoDB answers a ping
+	CheckDestinations = "destinations" // The destinations of the running tests can be reached
+	CheckWorkers      = "workers"      // The workers of the running tests keep up with their rate
+)
+
 // HealthCheck represents the health status of a service or component.
 type HealthCheck struct {
 	ID          primitive.ObjectID `bson:"_id,omitempty" json:"id"`
-	ServiceName string             `bson:"service_name" json:"service_name"`
-	Status      string             `bson:"status" json:"status"` // e.g., "healthy", "unhealthy"
+	ServiceName string             `bson:"service_name" json:"service_name"` // Name of the Check
+	Status      string             `bson:"status" json:"status"`             // e.g., "healthy", "unhealthy"
+	Required    bool               `bson:"required" json:"required"`         // Whether readiness depends on the check
 	CheckedAt   time.Time          `bson:"checked_at" json:"checked_at"`
+	DurationMs  int64              `bson:"duration_ms" json:"duration_ms"`
 	Details     string             `bson:"details,omitempty" json:"details,omitempty"`
+	Instance    string             `bson:"instance,omitempty" json:"instance,omitempty"` // Host name of the server that ran the check
+	ExpiresAt   time.Time          `bson:"expiresAt" json:"-"`                           // Removed by the TTL index after the retention
+}
+
+// Check is a dependency check run by the MonitoringService. Run returns an error
+// when the dependency is unhealthy; its context carries the check timeout.
+type Check struct {
+	Name string
+	Run  func(ctx context.Context) error
+}
+
+// Report is the result of running every check.
+type Report struct {
+	Status    string        `json:"status"` // healthy unless a required check failed
+	CheckedAt time.Time     `json:"checked_at"`
+	Checks    []HealthCheck `json:"checks"`
 }
@@ -5,145 +5,213 @@ package monitoring
 import (
 	"context"
 	"errors"
-	"net/http"
+	"os"
+	"sync"
 	"time"
 
 	"github.com/AkshayDubey29/MoniFlux/backend/internal/common"
 
-	"github.com/prometheus/client_golang/prometheus"
-	"github.com/prometheus/client_golang/prometheus/promhttp"
 	"github.com/sirupsen/logrus"
+	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/mongo" // Correct import for mongo.Client and mongo.Collection
+	"go.mongodb.org/mongo-driver/mongo/options"
 )
 
-//// HealthCheck is a structure that stores the result of a health check.
-//type HealthCheck struct {
-//	ServiceName string    `bson:"service_name"`
-//	Status      string    `bson:"status"`
-//	CheckedAt   time.Time `bson:"checked_at"`
-//	Details     string    `bson:"details,omitempty"`
-//}
+// Defaults of the monitoring configuration, used when a value is missing or invalid.
+const (
+	defaultCheckInterval  = 5 * time.Minute
+	defaultCheckTimeout   = 2 * time.Second
+	defaultCheckRetention = 7 * 24 * time.Hour
+)
 
-// MonitoringService handles metrics collection and health checks.
+// MonitoringService handles health checks. Metrics are exposed by the
+// middlewares.Metrics HTTP middleware and the load generator's metrics.Engine.
 type MonitoringService struct {
 	config         *common.Config
 	logger         *logrus.Logger
 	mongoClient    *mongo.Client     // Updated to the correct mongo.Client type
 	healthCheckCol *mongo.Collection // Updated to the correct mongo.Collection type
 
-	// Prometheus metrics
-	requestCounter  *prometheus.CounterVec
-	requestDuration *prometheus.HistogramVec
-	errorCounter    *prometheus.CounterVec
+	checks    []Check
+	required  map[string]bool // Checks readiness depends on
+	timeout   time.Duration   // Of each check
+	retention time.Duration   // Of the recorded checks
+	instance  string
 }
 
 // NewMonitoringService creates a new instance of MonitoringService.
 func NewMonitoringService(cfg *common.Config, logger *logrus.Logger, mongoClient *mongo.Client) *MonitoringService {
 	healthCol := mongoClient.Database(cfg.MongoDB).Collection("health_checks") // Use the correct collection method
 
-	// Initialize Prometheus metrics
-	requestCounter := prometheus.NewCounterVec(
-		prometheus.CounterOpts{
-			Name: "moniflux_http_requests_total",
-			Help: "Total number of HTTP requests",
-		},
-		[]string{"method", "endpoint", "status"},
-	)
-
-	requestDuration := prometheus.NewHistogramVec(
-		prometheus.HistogramOpts{
-			Name:    "moniflux_http_request_duration_seconds",
-			Help:    "Duration of HTTP requests in seconds",
-			Buckets: prometheus.DefBuckets,
-		},
-		[]string{"method", "endpoint"},
-	)
-
-	errorCounter := prometheus.NewCounterVec(
-		prometheus.CounterOpts{
-			Name: "moniflux_http_errors_total",
-			Help: "Total number of HTTP errors",
-		},
-		[]string{"method", "endpoint", "error"},
-	)
-
-	// Register Prometheus metrics
-	prometheus.MustRegister(requestCounter, requestDuration, errorCounter)
-
-	return &MonitoringService{
-		config:          cfg,
-		logger:          logger,
-		mongoClient:     mongoClient,
-		healthCheckCol:  healthCol,
-		requestCounter:  requestCounter,
-		requestDuration: requestDuration,
-		errorCounter:    errorCounter,
+	ms := &MonitoringService{
+		config:         cfg,
+		logger:         logger,
+		mongoClient:    mongoClient,
+		healthCheckCol: healthCol,
+		required:       make(map[string]bool),
+		timeout:        parseDuration(logger, "monitoring.health_check_timeout", cfg.Monitoring.HealthCheckTimeout, defaultCheckTimeout),
+		retention:      parseDuration(logger, "monitoring.health_check_retention", cfg.Monitoring.HealthCheckRetention, defaultCheckRetention),
+	}
+	for _, name := range cfg.Monitoring.ReadinessChecks {
+		ms.required[name] = true
 	}
+	ms.instance, _ = os.Hostname()
+	return ms
 }
 
-// RecordRequest records an HTTP request metric.
-func (ms *MonitoringService) RecordRequest(method, endpoint, status string, duration time.Duration) {
-	ms.requestCounter.WithLabelValues(method, endpoint, status).Inc()
-	ms.requestDuration.WithLabelValues(method, endpoint).Observe(duration.Seconds())
+// parseDuration parses a configured duration, logging and returning fallback if it
+// is missing or not positive.
+func parseDuration(logger *logrus.Logger, key, value string, fallback time.Duration) time.Duration {
+	if value == "" {
+		return fallback
+	}
+	d, err := time.ParseDuration(value)
+	if err != nil || d <= 0 {
+		logger.Warnf("Invalid %s %q, using %s", key, value, fallback)
+		return fallback
+	}
+	return d
 }
 
-// RecordError records an HTTP error metric.
-func (ms *MonitoringService) RecordError(method, endpoint, errMsg string) {
-	ms.errorCounter.WithLabelValues(method, endpoint, errMsg).Inc()
+// AddCheck registers a dependency check. It is required for readiness when its name
+// is listed in monitoring.readiness_checks. Checks must be added before the
+// scheduler or the handlers run.
+func (ms *MonitoringService) AddCheck(name string, run func(ctx context.Context) error) {
+	ms.checks = append(ms.checks, Check{Name: name, Run: run})
 }
 
-// PerformHealthCheck performs a health check for a given service.
-func (ms *MonitoringService) PerformHealthCheck(ctx context.Context, serviceName string, checkFunc func() error) error {
-	status := "healthy"
-	details := ""
-
-	err := checkFunc()
+// PerformHealthCheck runs a check with the check timeout and returns its result.
+func (ms *MonitoringService) PerformHealthCheck(ctx context.Context, check Check) HealthCheck {
+	ctx, cancel := context.WithTimeout(ctx, ms.timeout)
+	defer cancel()
+
+	start := time.Now()
+	err := check.Run(ctx)
+	result := HealthCheck{
+		ServiceName: check.Name,
+		Status:      StatusHealthy,
+		Required:    ms.required[check.Name],
+		CheckedAt:   start.UTC(),
+		DurationMs:  time.Since(start).Milliseconds(),
+		Instance:    ms.instance,
+	}
 	if err != nil {
-		status = "unhealthy"
-		details = err.Error()
-		ms.logger.Errorf("Health check failed for %s: %v", serviceName, err)
-	} else {
-		ms.logger.Infof("Health check passed for %s", serviceName)
+		result.Status = StatusUnhealthy
+		result.Details = err.Error()
 	}
+	return result
+}
 
-	healthCheck := &HealthCheck{
-		ServiceName: serviceName,
-		Status:      status,
-		CheckedAt:   time.Now(),
-		Details:     details,
+// RunChecks runs every check concurrently. The report is unhealthy when a required
+// check failed.
+func (ms *MonitoringService) RunChecks(ctx context.Context) Report {
+	report := Report{
+		Status:    StatusHealthy,
+		CheckedAt: time.Now().UTC(),
+		Checks:    make([]HealthCheck, len(ms.checks)),
 	}
+	var wg sync.WaitGroup
+	for i, check := range ms.checks {
+		wg.Add(1)
+		go func(i int, check Check) {
+			defer wg.Done()
+			report.Checks[i] = ms.PerformHealthCheck(ctx, check)
+		}(i, check)
+	}
+	wg.Wait()
+	for _, result := range report.Checks {
+		if result.Status != StatusHealthy && result.Required {
+			report.Status = StatusUnhealthy
+		}
+	}
+	return report
+}
 
-	_, err = ms.healthCheckCol.InsertOne(ctx, healthCheck) // Insert health check into MongoDB
-	if err != nil {
-		ms.logger.Errorf("Failed to record health check for %s: %v", serviceName, err)
+// RecordReport stores the results of a report in the health_checks collection, where
+// they expire after the configured retention.
+func (ms *MonitoringService) RecordReport(ctx context.Context, report Report) error {
+	if len(report.Checks) == 0 {
+		return nil
+	}
+	docs := make([]interface{}, len(report.Checks))
+	for i, result := range report.Checks {
+		result.ExpiresAt = result.CheckedAt.Add(ms.retention)
+		docs[i] = result
+	}
+	if _, err := ms.healthCheckCol.InsertMany(ctx, docs); err != nil {
+		ms.logger.Errorf("Failed to record health checks: %v", err)
 		return errors.New("internal server error")
 	}
+	return nil
+}
+
+// History returns the recorded results since the given time, newest first. An empty
+// serviceName returns the results of every check.
+func (ms *MonitoringService) History(ctx context.Context, serviceName string, since time.Time, limit int64) ([]HealthCheck, error) {
+	filter := bson.M{"checked_at": bson.M{"$gte": since}}
+	if serviceName != "" {
+		filter["service_name"] = serviceName
+	}
+	opts := options.Find().SetSort(bson.D{{Key: "checked_at", Value: -1}}).SetLimit(limit)
+
+	cursor, err := ms.healthCheckCol.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
 
-	return err
+	healthChecks := []HealthCheck{}
+	if err := cursor.All(ctx, &healthChecks); err != nil {
+		return nil, err
+	}
+	return healthChecks, nil
 }
 
-// SetupPrometheusHandler sets up the Prometheus HTTP handler.
-func (ms *MonitoringService) SetupPrometheusHandler() http.Handler {
-	return promhttp.Handler()
+// LatestResults returns the most recently recorded result of each check.
+func (ms *MonitoringService) LatestResults(ctx context.Context) ([]HealthCheck, error) {
+	pipeline := mongo.Pipeline{
+		{{Key: "$sort", Value: bson.D{{Key: "checked_at", Value: -1}}}},
+		{{Key: "$group", Value: bson.D{
+			{Key: "_id", Value: "$service_name"},
+			{Key: "latest", Value: bson.D{{Key: "$first", Value: "$$ROOT"}}},
+		}}},
+		{{Key: "$replaceRoot", Value: bson.D{{Key: "newRoot", Value: "$latest"}}}},
+		{{Key: "$sort", Value: bson.D{{Key: "service_name", Value: 1}}}},
+	}
+	cursor, err := ms.healthCheckCol.Aggregate(ctx, pipeline)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	healthChecks := []HealthCheck{}
+	if err := cursor.All(ctx, &healthChecks); err != nil {
+		return nil, err
+	}
+	return healthChecks, nil
 }
 
-// Example of a periodic health check runner
-func (ms *MonitoringService) StartHealthCheckScheduler(ctx context.Context, interval time.Duration, services map[string]func() error) {
-	ticker := time.NewTicker(interval)
-	defer ticker.Stop()
+// StartHealthCheckScheduler runs the checks every monitoring.health_check_interval
+// until ctx is cancelled, and records their results.
+func (ms *MonitoringService) StartHealthCheckScheduler(ctx context.Context) {
+	interval := parseDuration(ms.logger, "monitoring.health_check_interval", ms.config.Monitoring.HealthCheckInterval, defaultCheckInterval)
 
 	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
 		for {
 			select {
 			case <-ctx.Done():
 				ms.logger.Info("Stopping health check scheduler")
 				return
 			case <-ticker.C:
-				for serviceName, checkFunc := range services {
-					if err := ms.PerformHealthCheck(ctx, serviceName, checkFunc); err != nil {
-						ms.logger.Errorf("Health check error for %s: %v", serviceName, err)
+				report := ms.RunChecks(ctx)
+				for _, result := range report.Checks {
+					if result.Status != StatusHealthy {
+						ms.logger.Warnf("Health check %s failed: %s", result.ServiceName, result.Details)
 					}
 				}
+				ms.RecordReport(ctx, report)
 			}
 		}
 	}()
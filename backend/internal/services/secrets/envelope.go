@@ -0,0 +1,104 @@
+// backend/internal/services/secrets/envelope.go
+
+package secrets
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+)
+
+// dataKeySize is the size of the AES-256 keys used for the master and data keys.
+const dataKeySize = 32
+
+// ParseMasterKey decodes a base64-encoded 32-byte master key.
+func ParseMasterKey(encoded string) ([]byte, error) {
+	key, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		return nil, fmt.Errorf("secrets.master_key is not valid base64: %w", err)
+	}
+	if len(key) != dataKeySize {
+		return nil, fmt.Errorf("secrets.master_key must decode to %d bytes, got %d", dataKeySize, len(key))
+	}
+	return key, nil
+}
+
+// envelope encrypts each value with a fresh data key and the data key with the
+// master key. The secret name is bound to both as additional data, so ciphertexts
+// cannot be swapped between secrets.
+type envelope struct {
+	master cipher.AEAD
+}
+
+func newEnvelope(masterKey []byte) (*envelope, error) {
+	master, err := newAEAD(masterKey)
+	if err != nil {
+		return nil, err
+	}
+	return &envelope{master: master}, nil
+}
+
+func newAEAD(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// seal returns the encrypted data key and the encrypted value.
+func (e *envelope) seal(name, value string) (encryptedKey, ciphertext []byte, err error) {
+	dataKey := make([]byte, dataKeySize)
+	if _, err := rand.Read(dataKey); err != nil {
+		return nil, nil, err
+	}
+	data, err := newAEAD(dataKey)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if encryptedKey, err = sealWith(e.master, dataKey, name); err != nil {
+		return nil, nil, err
+	}
+	if ciphertext, err = sealWith(data, []byte(value), name); err != nil {
+		return nil, nil, err
+	}
+	return encryptedKey, ciphertext, nil
+}
+
+// open decrypts the data key and then the value.
+func (e *envelope) open(name string, encryptedKey, ciphertext []byte) (string, error) {
+	dataKey, err := openWith(e.master, encryptedKey, name)
+	if err != nil {
+		return "", fmt.Errorf("failed to decrypt the data key: %w", err)
+	}
+	data, err := newAEAD(dataKey)
+	if err != nil {
+		return "", err
+	}
+	value, err := openWith(data, ciphertext, name)
+	if err != nil {
+		return "", fmt.Errorf("failed to decrypt the value: %w", err)
+	}
+	return string(value), nil
+}
+
+// sealWith encrypts the plaintext and prepends the random nonce.
+func sealWith(aead cipher.AEAD, plaintext []byte, name string) ([]byte, error) {
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+	return aead.Seal(nonce, nonce, plaintext, []byte(name)), nil
+}
+
+func openWith(aead cipher.AEAD, sealed []byte, name string) ([]byte, error) {
+	if len(sealed) < aead.NonceSize() {
+		return nil, errors.New("ciphertext too short")
+	}
+	nonce, ciphertext := sealed[:aead.NonceSize()], sealed[aead.NonceSize():]
+	return aead.Open(nil, nonce, ciphertext, []byte(name))
+}
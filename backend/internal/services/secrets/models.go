@@ -0,0 +1,85 @@
+// backend/internal/services/secrets/models.go
+
+package secrets
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Providers a secret reference can name.
+const (
+	ProviderStore = "store" // Encrypted secrets collection; the default
+	ProviderEnv   = "env"   // Environment variables with the configured prefix
+	ProviderFile  = "file"  // Files in the configured directory, e.g. mounted Kubernetes secrets
+)
+
+// Redacted replaces secret values in API responses and log lines.
+const Redacted = "[REDACTED]"
+
+// namePattern restricts secret names so they map safely onto environment variable
+// and file names.
+var namePattern = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9._-]{0,127}$`)
+
+// Secret is a value stored in the secrets collection. The value is encrypted with a
+// random data key, which is itself encrypted with the master key from the
+// configuration (envelope encryption). Neither is ever returned by the API.
+type Secret struct {
+	ID           primitive.ObjectID `bson:"_id,omitempty" json:"-"`
+	Name         string             `bson:"name" json:"name"`
+	TeamID       string             `bson:"teamID,omitempty" json:"teamID,omitempty"` // Only tests of this team may use the secret; empty allows any test
+	Description  string             `bson:"description,omitempty" json:"description,omitempty"`
+	EncryptedKey []byte             `bson:"encryptedKey" json:"-"` // Data key encrypted with the master key
+	Ciphertext   []byte             `bson:"ciphertext" json:"-"`   // Value encrypted with the data key
+	CreatedBy    string             `bson:"createdBy" json:"createdBy"`
+	CreatedAt    time.Time          `bson:"createdAt" json:"createdAt"`
+	UpdatedBy    string             `bson:"updatedBy" json:"updatedBy"`
+	UpdatedAt    time.Time          `bson:"updatedAt" json:"updatedAt"`
+}
+
+// SecretRequest creates or replaces a stored secret.
+type SecretRequest struct {
+	Value       string `json:"value" validate:"required"`
+	TeamID      string `json:"teamID,omitempty"`
+	Description string `json:"description,omitempty" validate:"max=256"`
+}
+
+// Ref is a parsed secret reference: "name" or "store:name" for the secrets
+// collection, "env:name" or "file:name" for the other providers.
+type Ref struct {
+	Provider string
+	Name     string
+}
+
+// ParseRef parses a secret reference.
+func ParseRef(ref string) (Ref, error) {
+	r := Ref{Provider: ProviderStore, Name: ref}
+	if i := strings.Index(ref, ":"); i >= 0 {
+		r.Provider, r.Name = ref[:i], ref[i+1:]
+	}
+	switch r.Provider {
+	case ProviderStore, ProviderEnv, ProviderFile:
+	default:
+		return Ref{}, fmt.Errorf("%w: unknown provider %q in %q", ErrInvalidRef, r.Provider, ref)
+	}
+	if err := ValidateName(r.Name); err != nil {
+		return Ref{}, fmt.Errorf("%w: %v", ErrInvalidRef, err)
+	}
+	return r, nil
+}
+
+func (r Ref) String() string {
+	return r.Provider + ":" + r.Name
+}
+
+// ValidateName returns ErrInvalidName if the name cannot be used for a secret.
+func ValidateName(name string) error {
+	if !namePattern.MatchString(name) {
+		return fmt.Errorf("%w: %q must be 1 to 128 letters, digits, '.', '_' or '-' and start with a letter or digit", ErrInvalidName, name)
+	}
+	return nil
+}
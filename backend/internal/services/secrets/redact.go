@@ -0,0 +1,67 @@
+// backend/internal/services/secrets/redact.go
+
+package secrets
+
+import (
+	"strings"
+	"sync"
+
+	"github.com/sirupsen/logrus"
+)
+
+// minRedactedLength avoids masking short values that would match ordinary text.
+const minRedactedLength = 4
+
+// Redactor replaces the values of resolved secrets in log lines. It is a logrus hook.
+type Redactor struct {
+	mu     sync.RWMutex
+	values map[string]struct{}
+}
+
+// NewRedactor creates an empty Redactor.
+func NewRedactor() *Redactor {
+	return &Redactor{values: make(map[string]struct{})}
+}
+
+// Add registers a secret value to be redacted.
+func (r *Redactor) Add(value string) {
+	if len(value) < minRedactedLength {
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.values[value] = struct{}{}
+}
+
+// Redact returns s with every registered value replaced by Redacted.
+func (r *Redactor) Redact(s string) string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	for value := range r.values {
+		if strings.Contains(s, value) {
+			s = strings.ReplaceAll(s, value, Redacted)
+		}
+	}
+	return s
+}
+
+// Levels implements logrus.Hook.
+func (r *Redactor) Levels() []logrus.Level {
+	return logrus.AllLevels
+}
+
+// Fire implements logrus.Hook by redacting the message and the string and error fields.
+func (r *Redactor) Fire(entry *logrus.Entry) error {
+	entry.Message = r.Redact(entry.Message)
+	for key, value := range entry.Data {
+		switch v := value.(type) {
+		case string:
+			entry.Data[key] = r.Redact(v)
+		case error:
+			if redacted := r.Redact(v.Error()); redacted != v.Error() {
+				entry.Data[key] = redacted
+			}
+		}
+	}
+	return nil
+}
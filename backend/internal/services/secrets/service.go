@@ -0,0 +1,311 @@
+// backend/internal/services/secrets/service.go
+
+package secrets
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/AkshayDubey29/MoniFlux/backend/internal/common"
+	"github.com/sirupsen/logrus"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// Custom errors
+var (
+	ErrSecretNotFound = errors.New("secret not found")
+	ErrInvalidRef     = errors.New("invalid secret reference")
+	ErrInvalidName    = errors.New("invalid secret name")
+	ErrStoreDisabled  = errors.New("secret store is disabled; set secrets.master_key")
+)
+
+// cachedValue is a resolved secret kept in memory for the cache TTL.
+type cachedValue struct {
+	value     string
+	expiresAt time.Time
+}
+
+// SecretService stores encrypted secrets and resolves secret references from the
+// store, environment variables or files. Values are only decrypted when a test
+// delivers data, and every resolved value is redacted from the logs.
+type SecretService struct {
+	config     *common.Config
+	logger     *logrus.Logger
+	collection *mongo.Collection
+	envelope   *envelope // nil when no master key is configured
+	redactor   *Redactor
+	cacheTTL   time.Duration
+	cacheMu    sync.Mutex
+	cache      map[string]cachedValue
+}
+
+// NewSecretService creates a new instance of SecretService and registers its
+// redaction hook on the logger.
+func NewSecretService(cfg *common.Config, logger *logrus.Logger, mongoClient *mongo.Client) *SecretService {
+	ss := &SecretService{
+		config:     cfg,
+		logger:     logger,
+		collection: mongoClient.Database(cfg.MongoDB).Collection("secrets"),
+		redactor:   NewRedactor(),
+		cacheTTL:   time.Minute,
+		cache:      make(map[string]cachedValue),
+	}
+
+	if cfg.Secrets.MasterKey != "" {
+		key, err := ParseMasterKey(cfg.Secrets.MasterKey)
+		if err == nil {
+			ss.envelope, err = newEnvelope(key)
+		}
+		if err != nil {
+			logger.Errorf("Secret store disabled: %v", err)
+		}
+	}
+	if cfg.Secrets.CacheTTL != "" {
+		if ttl, err := time.ParseDuration(cfg.Secrets.CacheTTL); err == nil {
+			ss.cacheTTL = ttl
+		} else {
+			logger.Warnf("Invalid secrets.cache_ttl %q, using %s", cfg.Secrets.CacheTTL, ss.cacheTTL)
+		}
+	}
+
+	logger.AddHook(ss.redactor)
+	return ss
+}
+
+// StoreEnabled reports whether secrets can be stored, i.e. a master key is configured.
+func (ss *SecretService) StoreEnabled() bool {
+	return ss.envelope != nil
+}
+
+// Redactor returns the redactor holding the values resolved so far.
+func (ss *SecretService) Redactor() *Redactor {
+	return ss.redactor
+}
+
+// PutSecret encrypts and stores the value under the name, replacing any previous value.
+func (ss *SecretService) PutSecret(ctx context.Context, name string, req *SecretRequest, updatedBy string) (*Secret, error) {
+	if ss.envelope == nil {
+		return nil, ErrStoreDisabled
+	}
+	if err := ValidateName(name); err != nil {
+		return nil, err
+	}
+
+	encryptedKey, ciphertext, err := ss.envelope.seal(name, req.Value)
+	if err != nil {
+		ss.logger.Errorf("Failed to encrypt secret %s: %v", name, err)
+		return nil, errors.New("internal server error")
+	}
+
+	now := time.Now()
+	update := bson.M{
+		"$set": bson.M{
+			"teamID":       req.TeamID,
+			"description":  req.Description,
+			"encryptedKey": encryptedKey,
+			"ciphertext":   ciphertext,
+			"updatedBy":    updatedBy,
+			"updatedAt":    now,
+		},
+		"$setOnInsert": bson.M{
+			"createdBy": updatedBy,
+			"createdAt": now,
+		},
+	}
+	opts := options.FindOneAndUpdate().SetUpsert(true).SetReturnDocument(options.After)
+
+	var secret Secret
+	if err := ss.collection.FindOneAndUpdate(ctx, bson.M{"name": name}, update, opts).Decode(&secret); err != nil {
+		ss.logger.Errorf("Failed to store secret %s: %v", name, err)
+		return nil, errors.New("internal server error")
+	}
+	ss.forget(Ref{Provider: ProviderStore, Name: name})
+
+	ss.logger.Infof("Secret %s stored by user %s", name, updatedBy)
+	return &secret, nil
+}
+
+// GetSecret returns the metadata of a stored secret.
+func (ss *SecretService) GetSecret(ctx context.Context, name string) (*Secret, error) {
+	var secret Secret
+	err := ss.collection.FindOne(ctx, bson.M{"name": name}).Decode(&secret)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, fmt.Errorf("%w: %s", ErrSecretNotFound, name)
+		}
+		ss.logger.Errorf("Error retrieving secret %s: %v", name, err)
+		return nil, errors.New("internal server error")
+	}
+	return &secret, nil
+}
+
+// ListSecrets returns the metadata of the stored secrets, ordered by name.
+func (ss *SecretService) ListSecrets(ctx context.Context) ([]Secret, error) {
+	opts := options.Find().
+		SetSort(bson.D{{Key: "name", Value: 1}}).
+		SetProjection(bson.M{"encryptedKey": 0, "ciphertext": 0})
+	cursor, err := ss.collection.Find(ctx, bson.M{}, opts)
+	if err != nil {
+		ss.logger.Errorf("Error listing secrets: %v", err)
+		return nil, errors.New("internal server error")
+	}
+
+	secrets := []Secret{}
+	if err := cursor.All(ctx, &secrets); err != nil {
+		ss.logger.Errorf("Error decoding secrets: %v", err)
+		return nil, errors.New("internal server error")
+	}
+	return secrets, nil
+}
+
+// DeleteSecret removes a stored secret. Tests referencing it fail to deliver until
+// it is stored again.
+func (ss *SecretService) DeleteSecret(ctx context.Context, name string) error {
+	result, err := ss.collection.DeleteOne(ctx, bson.M{"name": name})
+	if err != nil {
+		ss.logger.Errorf("Failed to delete secret %s: %v", name, err)
+		return errors.New("internal server error")
+	}
+	if result.DeletedCount == 0 {
+		return fmt.Errorf("%w: %s", ErrSecretNotFound, name)
+	}
+	ss.forget(Ref{Provider: ProviderStore, Name: name})
+	return nil
+}
+
+// CheckRef verifies, without decrypting anything, that the reference names an
+// existing secret that tests of the team may use.
+func (ss *SecretService) CheckRef(ctx context.Context, ref, teamID string) error {
+	r, err := ParseRef(ref)
+	if err != nil {
+		return err
+	}
+
+	switch r.Provider {
+	case ProviderEnv:
+		if _, ok := os.LookupEnv(ss.envName(r.Name)); !ok {
+			return fmt.Errorf("%w: environment variable %s is not set", ErrSecretNotFound, ss.envName(r.Name))
+		}
+	case ProviderFile:
+		path, err := ss.filePath(r.Name)
+		if err != nil {
+			return err
+		}
+		if _, err := os.Stat(path); err != nil {
+			return fmt.Errorf("%w: %s", ErrSecretNotFound, r)
+		}
+	default:
+		var secret Secret
+		err := ss.collection.FindOne(ctx, bson.M{"name": r.Name}, options.FindOne().SetProjection(bson.M{"teamID": 1})).Decode(&secret)
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return fmt.Errorf("%w: %s", ErrSecretNotFound, r.Name)
+		}
+		if err != nil {
+			ss.logger.Errorf("Error retrieving secret %s: %v", r.Name, err)
+			return errors.New("internal server error")
+		}
+		// Secrets of other teams are reported as not found so their existence is not leaked.
+		if secret.TeamID != "" && secret.TeamID != teamID {
+			return fmt.Errorf("%w: %s", ErrSecretNotFound, r.Name)
+		}
+	}
+	return nil
+}
+
+// Resolve returns the value of the referenced secret. Values are cached for the
+// cache TTL and registered with the redactor.
+func (ss *SecretService) Resolve(ctx context.Context, ref string) (string, error) {
+	r, err := ParseRef(ref)
+	if err != nil {
+		return "", err
+	}
+
+	ss.cacheMu.Lock()
+	cached, ok := ss.cache[r.String()]
+	ss.cacheMu.Unlock()
+	if ok && time.Now().Before(cached.expiresAt) {
+		return cached.value, nil
+	}
+
+	var value string
+	switch r.Provider {
+	case ProviderEnv:
+		v, ok := os.LookupEnv(ss.envName(r.Name))
+		if !ok {
+			return "", fmt.Errorf("%w: environment variable %s is not set", ErrSecretNotFound, ss.envName(r.Name))
+		}
+		value = v
+	case ProviderFile:
+		path, err := ss.filePath(r.Name)
+		if err != nil {
+			return "", err
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return "", fmt.Errorf("%w: %s: %v", ErrSecretNotFound, r, err)
+		}
+		value = strings.TrimRight(string(data), "\r\n")
+	default:
+		if value, err = ss.open(ctx, r.Name); err != nil {
+			return "", err
+		}
+	}
+
+	ss.redactor.Add(value)
+	ss.cacheMu.Lock()
+	ss.cache[r.String()] = cachedValue{value: value, expiresAt: time.Now().Add(ss.cacheTTL)}
+	ss.cacheMu.Unlock()
+	return value, nil
+}
+
+// open loads and decrypts a stored secret.
+func (ss *SecretService) open(ctx context.Context, name string) (string, error) {
+	if ss.envelope == nil {
+		return "", ErrStoreDisabled
+	}
+	var secret Secret
+	err := ss.collection.FindOne(ctx, bson.M{"name": name}).Decode(&secret)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return "", fmt.Errorf("%w: %s", ErrSecretNotFound, name)
+	}
+	if err != nil {
+		ss.logger.Errorf("Error retrieving secret %s: %v", name, err)
+		return "", errors.New("internal server error")
+	}
+
+	value, err := ss.envelope.open(name, secret.EncryptedKey, secret.Ciphertext)
+	if err != nil {
+		ss.logger.Errorf("Failed to decrypt secret %s: %v", name, err)
+		return "", errors.New("internal server error")
+	}
+	return value, nil
+}
+
+// forget drops a cached value so the next resolution reads the new one.
+func (ss *SecretService) forget(r Ref) {
+	ss.cacheMu.Lock()
+	defer ss.cacheMu.Unlock()
+	delete(ss.cache, r.String())
+}
+
+// envName maps a secret name onto an environment variable, e.g. "loki-prod" onto
+// MONIFLUX_SECRET_LOKI_PROD.
+func (ss *SecretService) envName(name string) string {
+	return ss.config.Secrets.EnvPrefix + strings.ToUpper(strings.NewReplacer("-", "_", ".", "_").Replace(name))
+}
+
+// filePath maps a secret name onto a file in the configured directory.
+func (ss *SecretService) filePath(name string) (string, error) {
+	if ss.config.Secrets.FileDir == "" {
+		return "", fmt.Errorf("%w: the file provider is disabled; set secrets.file_dir", ErrInvalidRef)
+	}
+	return filepath.Join(ss.config.Secrets.FileDir, name), nil
+}
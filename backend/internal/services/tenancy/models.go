@@ -0,0 +1,55 @@
+// backend/internal/services/tenancy/models.go
+
+package tenancy
+
+import (
+	"time"
+)
+
+// Team roles.
+const (
+	TeamRoleOwner  = "owner"
+	TeamRoleMember = "member"
+)
+
+// TeamMember represents a user's membership in a team.
+type TeamMember struct {
+	UserID string `bson:"userID" json:"userID" validate:"required"`
+	Role   string `bson:"role" json:"role" validate:"required,oneof=owner member"`
+}
+
+// Team represents a tenant that owns load tests shared between its members.
+type Team struct {
+	TeamID    string       `bson:"teamID" json:"teamID"`
+	Name      string       `bson:"name" json:"name" validate:"required,min=2,max=64"`
+	Members   []TeamMember `bson:"members" json:"members"`
+	CreatedBy string       `bson:"createdBy" json:"createdBy"`
+	CreatedAt time.Time    `bson:"createdAt" json:"createdAt"`
+	UpdatedAt time.Time    `bson:"updatedAt" json:"updatedAt"`
+}
+
+// Project groups load tests within a team.
+type Project struct {
+	ProjectID   string    `bson:"projectID" json:"projectID"`
+	TeamID      string    `bson:"teamID" json:"teamID"`
+	Name        string    `bson:"name" json:"name" validate:"required,min=2,max=64"`
+	Description string    `bson:"description,omitempty" json:"description,omitempty"`
+	CreatedBy   string    `bson:"createdBy" json:"createdBy"`
+	CreatedAt   time.Time `bson:"createdAt" json:"createdAt"`
+	UpdatedAt   time.Time `bson:"updatedAt" json:"updatedAt"`
+}
+
+// HasMember reports whether the user belongs to the team.
+func (t *Team) HasMember(userID string) bool {
+	return t.MemberRole(userID) != ""
+}
+
+// MemberRole returns the user's role in the team, or an empty string if they are not a member.
+func (t *Team) MemberRole(userID string) string {
+	for _, m := range t.Members {
+		if m.UserID == userID {
+			return m.Role
+		}
+	}
+	return ""
+}
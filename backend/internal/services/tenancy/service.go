@@ -0,0 +1,255 @@
+// backend/internal/services/tenancy/service.go
+
+package tenancy
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/AkshayDubey29/MoniFlux/backend/internal/api/models"
+	"github.com/AkshayDubey29/MoniFlux/backend/internal/common"
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// ErrLastTeamOwner is returned when removing a member would leave a team without an owner.
+var ErrLastTeamOwner = errors.New("cannot remove the last owner of a team")
+
+// TenancyService manages teams and projects and answers membership questions
+// used to scope load tests to their tenants.
+type TenancyService struct {
+	config            *common.Config
+	logger            *logrus.Logger
+	teamCollection    *mongo.Collection
+	projectCollection *mongo.Collection
+}
+
+// NewTenancyService creates a new instance of TenancyService.
+func NewTenancyService(cfg *common.Config, logger *logrus.Logger, mongoClient *mongo.Client) *TenancyService {
+	db := mongoClient.Database(cfg.MongoDB)
+	return &TenancyService{
+		config:            cfg,
+		logger:            logger,
+		teamCollection:    db.Collection("teams"),
+		projectCollection: db.Collection("projects"),
+	}
+}
+
+// CreateTeam creates a new team with the principal as its owner.
+func (ts *TenancyService) CreateTeam(ctx context.Context, principal *models.Principal, name string) (*Team, error) {
+	team := &Team{
+		TeamID:    uuid.New().String(),
+		Name:      name,
+		Members:   []TeamMember{{UserID: principal.UserID, Role: TeamRoleOwner}},
+		CreatedBy: principal.UserID,
+		CreatedAt: time.Now(),
+		UpdatedAt: time.Now(),
+	}
+
+	if _, err := ts.teamCollection.InsertOne(ctx, team); err != nil {
+		ts.logger.Errorf("Failed to insert team %s: %v", name, err)
+		return nil, fmt.Errorf("failed to create team: %w", err)
+	}
+
+	ts.logger.Infof("Team %s (%s) created by user %s", team.Name, team.TeamID, principal.UserID)
+	return team, nil
+}
+
+// GetTeam retrieves a team by its TeamID.
+func (ts *TenancyService) GetTeam(ctx context.Context, teamID string) (*Team, error) {
+	var team Team
+	err := ts.teamCollection.FindOne(ctx, bson.M{"teamID": teamID}).Decode(&team)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, models.ErrTeamNotFound
+		}
+		ts.logger.Errorf("Error retrieving team %s: %v", teamID, err)
+		return nil, fmt.Errorf("error retrieving team: %w", err)
+	}
+	return &team, nil
+}
+
+// ListTeams returns the teams visible to the principal: all teams for admins,
+// otherwise only the teams the principal belongs to.
+func (ts *TenancyService) ListTeams(ctx context.Context, principal *models.Principal) ([]Team, error) {
+	filter := bson.M{}
+	if !principal.IsAdmin() {
+		filter["members.userID"] = principal.UserID
+	}
+
+	cursor, err := ts.teamCollection.Find(ctx, filter, options.Find().SetSort(bson.D{{Key: "name", Value: 1}}))
+	if err != nil {
+		ts.logger.Errorf("Failed to list teams: %v", err)
+		return nil, fmt.Errorf("failed to list teams: %w", err)
+	}
+	defer cursor.Close(ctx)
+
+	teams := []Team{}
+	if err := cursor.All(ctx, &teams); err != nil {
+		return nil, fmt.Errorf("failed to decode teams: %w", err)
+	}
+	return teams, nil
+}
+
+// TeamIDsForUser returns the IDs of all teams the user is a member of.
+func (ts *TenancyService) TeamIDsForUser(ctx context.Context, userID string) ([]string, error) {
+	opts := options.Find().SetProjection(bson.M{"teamID": 1})
+	cursor, err := ts.teamCollection.Find(ctx, bson.M{"members.userID": userID}, opts)
+	if err != nil {
+		ts.logger.Errorf("Failed to look up teams for user %s: %v", userID, err)
+		return nil, fmt.Errorf("failed to look up teams: %w", err)
+	}
+	defer cursor.Close(ctx)
+
+	var teamIDs []string
+	for cursor.Next(ctx) {
+		var team Team
+		if err := cursor.Decode(&team); err != nil {
+			ts.logger.Errorf("Failed to decode team: %v", err)
+			continue
+		}
+		teamIDs = append(teamIDs, team.TeamID)
+	}
+	return teamIDs, cursor.Err()
+}
+
+// IsMember reports whether the user belongs to the team.
+func (ts *TenancyService) IsMember(ctx context.Context, teamID, userID string) (bool, error) {
+	count, err := ts.teamCollection.CountDocuments(ctx, bson.M{"teamID": teamID, "members.userID": userID})
+	if err != nil {
+		ts.logger.Errorf("Failed to check membership of user %s in team %s: %v", userID, teamID, err)
+		return false, fmt.Errorf("failed to check team membership: %w", err)
+	}
+	return count > 0, nil
+}
+
+// requireTeamOwner loads the team and ensures the principal owns it or is an admin.
+func (ts *TenancyService) requireTeamOwner(ctx context.Context, principal *models.Principal, teamID string) (*Team, error) {
+	team, err := ts.GetTeam(ctx, teamID)
+	if err != nil {
+		return nil, err
+	}
+	if !principal.IsAdmin() && team.MemberRole(principal.UserID) != TeamRoleOwner {
+		return nil, models.ErrForbidden
+	}
+	return team, nil
+}
+
+// AddMember adds a user to a team or updates their role. Only team owners and admins may do so.
+func (ts *TenancyService) AddMember(ctx context.Context, principal *models.Principal, teamID string, member TeamMember) error {
+	team, err := ts.requireTeamOwner(ctx, principal, teamID)
+	if err != nil {
+		return err
+	}
+
+	filter := bson.M{"teamID": teamID}
+	if team.HasMember(member.UserID) {
+		_, err = ts.teamCollection.UpdateOne(ctx,
+			bson.M{"teamID": teamID, "members.userID": member.UserID},
+			bson.M{"$set": bson.M{"members.$.role": member.Role, "updatedAt": time.Now()}})
+	} else {
+		_, err = ts.teamCollection.UpdateOne(ctx, filter,
+			bson.M{"$push": bson.M{"members": member}, "$set": bson.M{"updatedAt": time.Now()}})
+	}
+	if err != nil {
+		ts.logger.Errorf("Failed to add user %s to team %s: %v", member.UserID, teamID, err)
+		return fmt.Errorf("failed to update team members: %w", err)
+	}
+
+	ts.logger.Infof("User %s added to team %s as %s", member.UserID, teamID, member.Role)
+	return nil
+}
+
+// RemoveMember removes a user from a team. Only team owners and admins may do so,
+// and the last owner cannot be removed.
+func (ts *TenancyService) RemoveMember(ctx context.Context, principal *models.Principal, teamID, userID string) error {
+	team, err := ts.requireTeamOwner(ctx, principal, teamID)
+	if err != nil {
+		return err
+	}
+
+	if team.MemberRole(userID) == TeamRoleOwner {
+		owners := 0
+		for _, m := range team.Members {
+			if m.Role == TeamRoleOwner {
+				owners++
+			}
+		}
+		if owners == 1 {
+			return ErrLastTeamOwner
+		}
+	}
+
+	_, err = ts.teamCollection.UpdateOne(ctx, bson.M{"teamID": teamID},
+		bson.M{"$pull": bson.M{"members": bson.M{"userID": userID}}, "$set": bson.M{"updatedAt": time.Now()}})
+	if err != nil {
+		ts.logger.Errorf("Failed to remove user %s from team %s: %v", userID, teamID, err)
+		return fmt.Errorf("failed to update team members: %w", err)
+	}
+
+	ts.logger.Infof("User %s removed from team %s", userID, teamID)
+	return nil
+}
+
+// CreateProject creates a project within a team. Only team owners and admins may do so.
+func (ts *TenancyService) CreateProject(ctx context.Context, principal *models.Principal, teamID string, project *Project) error {
+	if _, err := ts.requireTeamOwner(ctx, principal, teamID); err != nil {
+		return err
+	}
+
+	project.ProjectID = uuid.New().String()
+	project.TeamID = teamID
+	project.CreatedBy = principal.UserID
+	project.CreatedAt, project.UpdatedAt = time.Now(), time.Now()
+
+	if _, err := ts.projectCollection.InsertOne(ctx, project); err != nil {
+		ts.logger.Errorf("Failed to insert project %s: %v", project.Name, err)
+		return fmt.Errorf("failed to create project: %w", err)
+	}
+
+	ts.logger.Infof("Project %s (%s) created in team %s", project.Name, project.ProjectID, teamID)
+	return nil
+}
+
+// GetProject retrieves a project by its ProjectID.
+func (ts *TenancyService) GetProject(ctx context.Context, projectID string) (*Project, error) {
+	var project Project
+	err := ts.projectCollection.FindOne(ctx, bson.M{"projectID": projectID}).Decode(&project)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, models.ErrProjectNotFound
+		}
+		ts.logger.Errorf("Error retrieving project %s: %v", projectID, err)
+		return nil, fmt.Errorf("error retrieving project: %w", err)
+	}
+	return &project, nil
+}
+
+// ListProjects returns the projects of a team. The principal must be a member or an admin.
+func (ts *TenancyService) ListProjects(ctx context.Context, principal *models.Principal, teamID string) ([]Project, error) {
+	team, err := ts.GetTeam(ctx, teamID)
+	if err != nil {
+		return nil, err
+	}
+	if !principal.IsAdmin() && !team.HasMember(principal.UserID) {
+		return nil, models.ErrTeamNotFound
+	}
+
+	cursor, err := ts.projectCollection.Find(ctx, bson.M{"teamID": teamID}, options.Find().SetSort(bson.D{{Key: "name", Value: 1}}))
+	if err != nil {
+		ts.logger.Errorf("Failed to list projects for team %s: %v", teamID, err)
+		return nil, fmt.Errorf("failed to list projects: %w", err)
+	}
+	defer cursor.Close(ctx)
+
+	projects := []Project{}
+	if err := cursor.All(ctx, &projects); err != nil {
+		return nil, fmt.Errorf("failed to decode projects: %w", err)
+	}
+	return projects, nil
+}
@@ -0,0 +1,73 @@
+// backend/pkg/tracing/mongo.go
+
+package tracing
+
+import (
+	"context"
+	"sync"
+
+	"go.mongodb.org/mongo-driver/event"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const mongoTracerName = "github.com/AkshayDubey29/MoniFlux/backend/pkg/tracing/mongo"
+
+// commandKey identifies a MongoDB command between its started and finished events.
+type commandKey struct {
+	connectionID string
+	requestID    int64
+}
+
+// mongoMonitor records a client span per MongoDB command.
+type mongoMonitor struct {
+	spans sync.Map // commandKey -> trace.Span
+}
+
+// MongoMonitor returns a command monitor recording a span for every MongoDB command,
+// as a child of the span in the context of the operation. Command documents are not
+// recorded, since they hold user data.
+func MongoMonitor() *event.CommandMonitor {
+	m := &mongoMonitor{}
+	return &event.CommandMonitor{
+		Started:   m.started,
+		Succeeded: m.succeeded,
+		Failed:    m.failed,
+	}
+}
+
+func (m *mongoMonitor) started(ctx context.Context, evt *event.CommandStartedEvent) {
+	name := evt.CommandName
+	attrs := []trace.SpanStartOption{
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			semconv.DBSystemMongoDB,
+			semconv.DBNamespace(evt.DatabaseName),
+			semconv.DBOperationName(evt.CommandName),
+		),
+	}
+	// The value of the command's first element is the collection for most commands.
+	if value, err := evt.Command.LookupErr(evt.CommandName); err == nil {
+		if collection, ok := value.StringValueOK(); ok {
+			name += " " + collection
+			attrs = append(attrs, trace.WithAttributes(semconv.DBCollectionName(collection)))
+		}
+	}
+	_, span := otel.Tracer(mongoTracerName).Start(ctx, name, attrs...)
+	m.spans.Store(commandKey{evt.ConnectionID, evt.RequestID}, span)
+}
+
+func (m *mongoMonitor) succeeded(_ context.Context, evt *event.CommandSucceededEvent) {
+	if span, ok := m.spans.LoadAndDelete(commandKey{evt.ConnectionID, evt.RequestID}); ok {
+		span.(trace.Span).End()
+	}
+}
+
+func (m *mongoMonitor) failed(_ context.Context, evt *event.CommandFailedEvent) {
+	if span, ok := m.spans.LoadAndDelete(commandKey{evt.ConnectionID, evt.RequestID}); ok {
+		span.(trace.Span).SetStatus(codes.Error, evt.Failure)
+		span.(trace.Span).End()
+	}
+}
@@ -0,0 +1,99 @@
+// backend/pkg/tracing/tracing.go
+
+package tracing
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"os"
+	"strings"
+
+	"github.com/AkshayDubey29/MoniFlux/backend/internal/common"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Exporters of common.Tracing.
+const (
+	ExporterOTLP   = "otlp"
+	ExporterStdout = "stdout"
+	ExporterNone   = "none"
+)
+
+// Span attributes of MoniFlux.
+const (
+	RequestIDKey = attribute.Key("moniflux.request_id") // X-Request-ID of an API request
+	TestIDKey    = attribute.Key("moniflux.test_id")
+	EntriesKey   = attribute.Key("moniflux.entries") // Entries of a delivery request
+)
+
+// Setup installs the global tracer provider for the configured exporter and the W3C
+// trace context and baggage propagators. With the "none" exporter no span is
+// recorded, but an incoming traceparent is still passed on to the destinations. The
+// returned function flushes the spans and stops the exporter.
+func Setup(ctx context.Context, cfg common.Tracing, service string) (func(context.Context) error, error) {
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{}))
+
+	var exporter sdktrace.SpanExporter
+	var err error
+	switch cfg.Exporter {
+	case "", ExporterNone:
+		return func(context.Context) error { return nil }, nil
+	case ExporterOTLP:
+		opts := []otlptracehttp.Option{otlptracehttp.WithHeaders(cfg.Headers)}
+		if cfg.Endpoint != "" {
+			opts = append(opts, otlptracehttp.WithEndpointURL(otlpTracesURL(cfg.Endpoint)))
+		}
+		exporter, err = otlptracehttp.New(ctx, opts...)
+	case ExporterStdout:
+		exporter, err = stdouttrace.New(stdouttrace.WithWriter(os.Stdout))
+	default:
+		return nil, fmt.Errorf("unsupported tracing exporter %q", cfg.Exporter)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to create %s trace exporter: %w", cfg.Exporter, err)
+	}
+
+	provider := NewTracerProvider(cfg, service, sdktrace.WithBatcher(exporter))
+	otel.SetTracerProvider(provider)
+	return provider.Shutdown, nil
+}
+
+// otlpTracesURL adds the default /v1/traces path to a collector URL without a path.
+func otlpTracesURL(endpoint string) string {
+	u, err := url.Parse(endpoint)
+	if err != nil || strings.Trim(u.Path, "/") != "" {
+		return endpoint
+	}
+	u.Path = "/v1/traces"
+	return u.String()
+}
+
+// NewTracerProvider returns a tracer provider for the service that samples
+// cfg.SampleRatio of the new traces, and every span of a sampled trace. Tests pass
+// sdktrace.WithSyncer with an in-memory exporter.
+func NewTracerProvider(cfg common.Tracing, service string, opts ...sdktrace.TracerProviderOption) *sdktrace.TracerProvider {
+	opts = append([]sdktrace.TracerProviderOption{
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(cfg.SampleRatio))),
+		sdktrace.WithResource(resource.NewSchemaless(semconv.ServiceName(service))),
+	}, opts...)
+	return sdktrace.NewTracerProvider(opts...)
+}
+
+// End records err, if it is not nil, on the span and ends the span.
+func End(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
@@ -0,0 +1,407 @@
+package unit
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/AkshayDubey29/MoniFlux/backend/internal/api/models"
+	"github.com/AkshayDubey29/MoniFlux/backend/internal/common"
+	"github.com/AkshayDubey29/MoniFlux/backend/internal/services/authentication"
+	"github.com/AkshayDubey29/MoniFlux/backend/internal/services/email"
+	"github.com/sirupsen/logrus"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/integration/mtest"
+)
+
+func TestValidatePassword(t *testing.T) {
+	policy := common.AccountPolicy{
+		PasswordMinLength:    12,
+		PasswordRequireUpper: true,
+		PasswordRequireLower: true,
+		PasswordRequireDigit: true,
+	}
+
+	valid := []string{"Correct-Horse-42", "AnotherGood1Password"}
+	for _, password := range valid {
+		if err := authentication.ValidatePassword(policy, "jdoe", password); err != nil {
+			t.Errorf("%q: unexpected error %v", password, err)
+		}
+	}
+
+	invalid := map[string]string{
+		"Short1a":                 "at least 12 characters",
+		"alllowercase123":         "upper-case",
+		"ALLUPPERCASE123":         "lower-case",
+		"NoDigitsAtAllHere":       "digit",
+		"Jdoe-Is-My-Password1":    "username",
+		strings.Repeat("Aa1", 30): "at most 72 bytes",
+	}
+	for password, want := range invalid {
+		err := authentication.ValidatePassword(policy, "jdoe", password)
+		if !errors.Is(err, authentication.ErrWeakPassword) {
+			t.Errorf("%q: expected ErrWeakPassword, got %v", password, err)
+			continue
+		}
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("%q: expected error mentioning %q, got %v", password, want, err)
+		}
+	}
+
+	policy.PasswordRequireSymbol = true
+	if err := authentication.ValidatePassword(policy, "jdoe", "NoSymbolsHere123"); err == nil {
+		t.Error("expected a password without symbols to be rejected")
+	}
+}
+
+func TestOutboxStoresMessages(t *testing.T) {
+	dir := t.TempDir()
+	outbox, err := email.NewOutbox(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx := context.Background()
+	outbox.Send(ctx, email.Message{To: "a@example.com", Subject: "first", Body: "one"})
+	outbox.Send(ctx, email.Message{To: "b@example.com", Subject: "other", Body: "two"})
+	outbox.Send(ctx, email.Message{To: "a@example.com", Subject: "second", Body: "three"})
+
+	if got := len(outbox.Messages()); got != 3 {
+		t.Fatalf("expected 3 messages, got %d", got)
+	}
+	last, ok := outbox.Last("a@example.com")
+	if !ok || last.Subject != "second" {
+		t.Fatalf("unexpected last message %+v", last)
+	}
+	if _, ok := outbox.Last("nobody@example.com"); ok {
+		t.Fatal("expected no message for unknown recipient")
+	}
+
+	files, err := filepath.Glob(filepath.Join(dir, "*.eml"))
+	if err != nil || len(files) != 3 {
+		t.Fatalf("expected 3 .eml files, got %v (%v)", files, err)
+	}
+	content, _ := os.ReadFile(files[0])
+	if !strings.Contains(string(content), "Subject: first") {
+		t.Errorf("unexpected message file content:\n%s", content)
+	}
+}
+
+func TestNewSenderSelectsProvider(t *testing.T) {
+	logger := logrus.New()
+
+	sender, err := email.NewSender(common.EmailConfig{Provider: "outbox"}, logger)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := sender.(*email.Outbox); !ok {
+		t.Errorf("expected *email.Outbox, got %T", sender)
+	}
+	if _, err := email.NewSender(common.EmailConfig{Provider: "carrier-pigeon"}, logger); err == nil {
+		t.Error("expected an unknown provider to be rejected")
+	}
+}
+
+// newMockAccounts returns an authentication service with the account policy whose
+// MongoDB commands are answered by mt, and the outbox its emails are sent to.
+func newMockAccounts(t *testing.T, mt *mtest.T, policy common.AccountPolicy) (*authentication.AuthenticationService, *email.Outbox) {
+	t.Helper()
+	policy.PublicURL = "https://moniflux.example.com"
+	cfg := &common.Config{MongoDB: mockDB, JWTSecret: "test-secret", Accounts: policy}
+	service, err := authentication.NewAuthenticationService(cfg, quietLogger(), mt.Client)
+	if err != nil {
+		t.Fatal(err)
+	}
+	outbox, err := email.NewOutbox(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	service.SetEmailSender(outbox)
+	return service, outbox
+}
+
+// findAndModifyResponse answers a findAndModify command with the document, or with
+// no document if it is nil.
+func findAndModifyResponse(t testing.TB, doc interface{}) bson.D {
+	if doc == nil {
+		return mtest.CreateSuccessResponse(bson.E{Key: "value", Value: nil})
+	}
+	return mtest.CreateSuccessResponse(bson.E{Key: "value", Value: toDoc(t, doc)})
+}
+
+func TestLoginLockout(t *testing.T) {
+	mt := newMockMongo(t)
+	const password = "Correct-Horse-42"
+	hashed, err := authentication.HashPassword(password)
+	if err != nil {
+		t.Fatal(err)
+	}
+	policy := common.AccountPolicy{MaxFailedLogins: 3, LockoutDuration: "10m"}
+	user := func(failures int, lockedUntil *time.Time) models.User {
+		return models.User{ID: primitive.NewObjectID(), Username: "jdoe", Password: hashed, FailedLoginAttempts: failures, LockedUntil: lockedUntil}
+	}
+	future, past := time.Now().Add(5*time.Minute), time.Now().Add(-time.Minute)
+
+	mt.Run("failure below the limit is counted", func(mt *mtest.T) {
+		service, _ := newMockAccounts(t, mt, policy)
+		current := user(0, nil)
+		counted := current
+		counted.FailedLoginAttempts = 1
+		mt.AddMockResponses(findResponse(mt, "users", current), findAndModifyResponse(mt, counted))
+		if _, err := service.AuthenticateUser("jdoe", "wrong"); !errors.Is(err, authentication.ErrInvalidCredentials) {
+			t.Fatalf("expected ErrInvalidCredentials, got %v", err)
+		}
+		if n := len(sentCommands(mt, "update")); n != 0 {
+			t.Errorf("expected the account not to be locked, got %d updates", n)
+		}
+	})
+
+	mt.Run("locks after the maximum failures", func(mt *mtest.T) {
+		service, _ := newMockAccounts(t, mt, policy)
+		current := user(2, nil)
+		counted := current
+		counted.FailedLoginAttempts = 3
+		mt.AddMockResponses(findResponse(mt, "users", current), findAndModifyResponse(mt, counted), writeResponse(1))
+		if _, err := service.AuthenticateUser("jdoe", "wrong"); !errors.Is(err, authentication.ErrInvalidCredentials) {
+			t.Fatalf("expected ErrInvalidCredentials, got %v", err)
+		}
+		increment := sentCommands(mt, "findAndModify")
+		if len(increment) != 1 || increment[0].Lookup("update", "$inc", "failedLoginAttempts").AsInt64() != 1 {
+			t.Fatalf("expected the failure to be counted, got %v", increment)
+		}
+		updates := sentCommands(mt, "update")
+		if len(updates) != 1 {
+			t.Fatalf("expected the account to be locked, got %d updates", len(updates))
+		}
+		set := updates[0].Lookup("updates", "0", "u", "$set").Document()
+		lockedUntil := set.Lookup("lockedUntil").Time()
+		if lockedUntil.Before(time.Now().Add(9*time.Minute)) || lockedUntil.After(time.Now().Add(10*time.Minute)) {
+			t.Errorf("expected a lock for the lockout duration, got %s", lockedUntil)
+		}
+		if set.Lookup("failedLoginAttempts").AsInt64() != 0 {
+			t.Error("expected the failure counter to restart")
+		}
+	})
+
+	mt.Run("locked account is rejected with the right password", func(mt *mtest.T) {
+		service, _ := newMockAccounts(t, mt, policy)
+		mt.AddMockResponses(findResponse(mt, "users", user(0, &future)))
+		if _, err := service.AuthenticateUser("jdoe", password); !errors.Is(err, authentication.ErrAccountLocked) {
+			t.Fatalf("expected ErrAccountLocked, got %v", err)
+		}
+		if n := len(mt.GetAllStartedEvents()); n != 1 {
+			t.Errorf("expected only the user lookup, got %d commands", n)
+		}
+	})
+
+	mt.Run("login after the lock expires resets the counter", func(mt *mtest.T) {
+		service, _ := newMockAccounts(t, mt, policy)
+		mt.AddMockResponses(findResponse(mt, "users", user(2, &past)), writeResponse(1), writeResponse(1))
+		if _, err := service.AuthenticateUser("jdoe", password); err != nil {
+			t.Fatalf("AuthenticateUser: %v", err)
+		}
+		updates := sentCommands(mt, "update")
+		if len(updates) != 1 {
+			t.Fatalf("expected the failures to be reset, got %d updates", len(updates))
+		}
+		update := updates[0].Lookup("updates", "0", "u").Document()
+		if _, err := update.LookupErr("$unset", "lockedUntil"); err != nil || update.Lookup("$set", "failedLoginAttempts").AsInt64() != 0 {
+			t.Errorf("expected the counter and lock to be cleared, got %s", update)
+		}
+		if n := len(sentCommands(mt, "insert")); n != 1 {
+			t.Errorf("expected a session to be created, got %d inserts", n)
+		}
+	})
+
+	mt.Run("lockout disabled", func(mt *mtest.T) {
+		service, _ := newMockAccounts(t, mt, common.AccountPolicy{})
+		mt.AddMockResponses(findResponse(mt, "users", user(10, nil)))
+		if _, err := service.AuthenticateUser("jdoe", "wrong"); !errors.Is(err, authentication.ErrInvalidCredentials) {
+			t.Fatalf("expected ErrInvalidCredentials, got %v", err)
+		}
+		if n := len(mt.GetAllStartedEvents()); n != 1 {
+			t.Errorf("expected failures not to be counted, got %d commands", n)
+		}
+	})
+
+	mt.Run("disabled account is rejected", func(mt *mtest.T) {
+		service, _ := newMockAccounts(t, mt, policy)
+		disabled := user(0, nil)
+		disabled.Disabled = true
+		mt.AddMockResponses(findResponse(mt, "users", disabled))
+		if _, err := service.AuthenticateUser("jdoe", password); !errors.Is(err, authentication.ErrAccountDisabled) {
+			t.Fatalf("expected ErrAccountDisabled, got %v", err)
+		}
+	})
+}
+
+// emailedToken returns the token of the link in the last email sent to the address.
+func emailedToken(t *testing.T, outbox *email.Outbox, address string) string {
+	t.Helper()
+	message, ok := outbox.Last(address)
+	if !ok {
+		t.Fatalf("expected an email to %s", address)
+	}
+	_, rest, found := strings.Cut(message.Body, "?token=")
+	if !found {
+		t.Fatalf("expected a link with a token, got %q", message.Body)
+	}
+	token, _, _ := strings.Cut(rest, "\n")
+	return token
+}
+
+// storedToken returns the account token stored by the last insert command.
+func storedToken(t *testing.T, mt *mtest.T) bson.Raw {
+	t.Helper()
+	inserts := sentCommands(mt, "insert")
+	if len(inserts) == 0 {
+		t.Fatal("expected the token to be stored")
+	}
+	return inserts[len(inserts)-1].Lookup("documents", "0").Document()
+}
+
+func TestAccountTokens(t *testing.T) {
+	mt := newMockMongo(t)
+	ctx := context.Background()
+	hashed, err := authentication.HashPassword("Old-Password-42")
+	if err != nil {
+		t.Fatal(err)
+	}
+	user := models.User{ID: primitive.NewObjectID(), Username: "jdoe", Email: "jdoe@example.com", Password: hashed}
+
+	mt.Run("reset token is single-use", func(mt *mtest.T) {
+		service, outbox := newMockAccounts(t, mt, common.AccountPolicy{ResetTokenExpiry: "30m"})
+		mt.AddMockResponses(findResponse(mt, "users", user), writeResponse(1), writeResponse(1))
+		expiresAt, err := service.CreatePasswordReset(ctx, user.ID.Hex())
+		if err != nil {
+			t.Fatalf("CreatePasswordReset: %v", err)
+		}
+		if expiresAt.Before(time.Now().Add(29*time.Minute)) || expiresAt.After(time.Now().Add(30*time.Minute)) {
+			t.Errorf("expected the token to expire after the policy expiry, got %s", expiresAt)
+		}
+		token := emailedToken(t, outbox, user.Email)
+		stored := storedToken(t, mt)
+		if stored.Lookup("tokenHash").StringValue() != tokenHash(token) {
+			t.Error("expected only the hash of the token to be stored")
+		}
+
+		mt.ClearEvents()
+		mt.AddMockResponses(
+			findResponse(mt, "account_tokens", stored),
+			findResponse(mt, "users", user),
+			findAndModifyResponse(mt, stored),
+			writeResponse(1),
+			findResponse(mt, "sessions"),
+		)
+		if err := service.ResetPassword(ctx, token, "New-Password-42"); err != nil {
+			t.Fatalf("ResetPassword: %v", err)
+		}
+		if n := len(sentCommands(mt, "findAndModify")); n != 1 {
+			t.Errorf("expected the token to be consumed, got %d commands", n)
+		}
+
+		// The consumed token is gone.
+		mt.AddMockResponses(findResponse(mt, "account_tokens"))
+		if err := service.ResetPassword(ctx, token, "Other-Password-42"); !errors.Is(err, authentication.ErrInvalidAccountToken) {
+			t.Errorf("expected ErrInvalidAccountToken on reuse, got %v", err)
+		}
+	})
+
+	mt.Run("expired reset token is rejected", func(mt *mtest.T) {
+		service, _ := newMockAccounts(t, mt, common.AccountPolicy{})
+		expired := bson.M{"tokenHash": tokenHash("expired"), "userID": user.ID.Hex(), "purpose": "password_reset", "expiresAt": time.Now().Add(-time.Minute)}
+		mt.AddMockResponses(findResponse(mt, "account_tokens", expired))
+		if err := service.ResetPassword(ctx, "expired", "New-Password-42"); !errors.Is(err, authentication.ErrInvalidAccountToken) {
+			t.Fatalf("expected ErrInvalidAccountToken, got %v", err)
+		}
+		if n := len(sentCommands(mt, "update")); n != 0 {
+			t.Errorf("expected the password not to change, got %d updates", n)
+		}
+	})
+
+	mt.Run("verification token is single-use", func(mt *mtest.T) {
+		service, outbox := newMockAccounts(t, mt, common.AccountPolicy{})
+		mt.AddMockResponses(writeResponse(1), writeResponse(1))
+		if err := service.SendVerificationEmail(ctx, &user); err != nil {
+			t.Fatalf("SendVerificationEmail: %v", err)
+		}
+		token := emailedToken(t, outbox, user.Email)
+		stored := storedToken(t, mt)
+
+		mt.ClearEvents()
+		mt.AddMockResponses(findAndModifyResponse(mt, stored), writeResponse(1))
+		if err := service.VerifyEmail(ctx, token); err != nil {
+			t.Fatalf("VerifyEmail: %v", err)
+		}
+		verify := sentCommands(mt, "update")[0].Lookup("updates", "0")
+		if verify.Document().Lookup("q", "email").StringValue() != user.Email {
+			t.Errorf("expected the verification to require the address it was issued for, got %s", verify)
+		}
+
+		mt.AddMockResponses(findAndModifyResponse(mt, nil))
+		if err := service.VerifyEmail(ctx, token); !errors.Is(err, authentication.ErrInvalidAccountToken) {
+			t.Errorf("expected ErrInvalidAccountToken on reuse, got %v", err)
+		}
+	})
+
+	mt.Run("expired verification token is rejected", func(mt *mtest.T) {
+		service, _ := newMockAccounts(t, mt, common.AccountPolicy{})
+		expired := bson.M{"tokenHash": tokenHash("expired"), "userID": user.ID.Hex(), "purpose": "email_verification", "email": user.Email, "expiresAt": time.Now().Add(-time.Minute)}
+		mt.AddMockResponses(findAndModifyResponse(mt, expired))
+		if err := service.VerifyEmail(ctx, "expired"); !errors.Is(err, authentication.ErrInvalidAccountToken) {
+			t.Fatalf("expected ErrInvalidAccountToken, got %v", err)
+		}
+		if n := len(sentCommands(mt, "update")); n != 0 {
+			t.Errorf("expected the address not to be verified, got %d updates", n)
+		}
+	})
+}
+
+func TestSetUserDisabled(t *testing.T) {
+	mt := newMockMongo(t)
+	ctx := context.Background()
+	userID := primitive.NewObjectID().Hex()
+
+	mt.Run("enabling clears the lockout", func(mt *mtest.T) {
+		service, _ := newMockAccounts(t, mt, common.AccountPolicy{})
+		mt.AddMockResponses(writeResponse(1))
+		if err := service.SetUserDisabled(ctx, userID, false); err != nil {
+			t.Fatalf("SetUserDisabled: %v", err)
+		}
+		update := sentCommands(mt, "update")[0].Lookup("updates", "0", "u").Document()
+		if update.Lookup("$set", "disabled").Boolean() || update.Lookup("$set", "failedLoginAttempts").AsInt64() != 0 {
+			t.Errorf("expected the user to be enabled with no failures, got %s", update)
+		}
+		if _, err := update.LookupErr("$unset", "lockedUntil"); err != nil {
+			t.Errorf("expected the lock to be cleared, got %s", update)
+		}
+	})
+
+	mt.Run("disabling ends the sessions", func(mt *mtest.T) {
+		service, _ := newMockAccounts(t, mt, common.AccountPolicy{})
+		mt.AddMockResponses(writeResponse(1), findResponse(mt, "sessions"))
+		if err := service.SetUserDisabled(ctx, userID, true); err != nil {
+			t.Fatalf("SetUserDisabled: %v", err)
+		}
+		update := sentCommands(mt, "update")[0].Lookup("updates", "0", "u").Document()
+		if !update.Lookup("$set", "disabled").Boolean() {
+			t.Errorf("expected the user to be disabled, got %s", update)
+		}
+		if finds := sentCommands(mt, "find"); len(finds) != 1 || finds[0].Lookup("find").StringValue() != "sessions" {
+			t.Error("expected the sessions of the user to be revoked")
+		}
+	})
+
+	mt.Run("unknown user", func(mt *mtest.T) {
+		service, _ := newMockAccounts(t, mt, common.AccountPolicy{})
+		mt.AddMockResponses(writeResponse(0))
+		if err := service.SetUserDisabled(ctx, userID, false); !errors.Is(err, authentication.ErrUserNotFound) {
+			t.Errorf("expected ErrUserNotFound, got %v", err)
+		}
+	})
+}
@@ -0,0 +1,195 @@
+package unit
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/AkshayDubey29/MoniFlux/backend/internal/api/models"
+	"github.com/AkshayDubey29/MoniFlux/backend/internal/common"
+	"github.com/AkshayDubey29/MoniFlux/backend/internal/controllers"
+	"go.mongodb.org/mongo-driver/mongo/integration/mtest"
+)
+
+// admissionTest returns a test generating rate logs per second into a file.
+func admissionTest(id, status string, rate int) models.Test {
+	return models.Test{
+		TestID:      id,
+		UserID:      "u1",
+		LogType:     "INFO",
+		LogRate:     rate,
+		LogSize:     100,
+		Duration:    60,
+		Status:      status,
+		Destination: common.Destination{Type: "file", FilePath: "/tmp/moniflux-admission.log"},
+	}
+}
+
+// startNewTest starts a test that does not exist yet, answering the lookup of the
+// test and of the global guardrail policy.
+func startNewTest(mt *mtest.T, controller *controllers.LoadGenController, test models.Test) error {
+	mt.AddMockResponses(findResponse(mt, "tests"), findResponse(mt, "guardrail_policies"), mtest.CreateSuccessResponse())
+	return controller.StartTest(context.Background(), &test)
+}
+
+// restoreQueue restores the queued tests, in the order given.
+func restoreQueue(mt *mtest.T, controller *controllers.LoadGenController, queued ...interface{}) {
+	mt.AddMockResponses(findResponse(mt, "tests", queued...))
+	if err := controller.RestoreQueue(context.Background()); err != nil {
+		mt.Fatal(err)
+	}
+}
+
+func TestAdmissionQueue(t *testing.T) {
+	mt := newMockMongo(t)
+
+	mt.Run("rejects a test that never fits", func(mt *mtest.T) {
+		controller := newMockController(mt, &common.Config{Admission: common.Admission{MaxTotalRate: 100}})
+		mt.AddMockResponses(findResponse(mt, "tests"), findResponse(mt, "guardrail_policies"))
+		test := admissionTest("too-big", "Pending", 500)
+		if err := controller.StartTest(context.Background(), &test); !errors.Is(err, models.ErrCapacityExceeded) {
+			t.Fatalf("expected ErrCapacityExceeded, got %v", err)
+		}
+		if len(sentCommands(mt, "insert")) != 0 {
+			t.Error("a test that never fits must not be stored")
+		}
+	})
+
+	mt.Run("restores the queue in order", func(mt *mtest.T) {
+		cfg := &common.Config{Admission: common.Admission{MaxTotalRate: 50}}
+		controller := newMockController(mt, cfg)
+		restoreQueue(mt, controller,
+			admissionTest("q1", "Queued", 80), admissionTest("q2", "Queued", 30), admissionTest("q3", "Queued", 10))
+
+		finds := sentCommands(mt, "find")
+		if len(finds) != 1 {
+			t.Fatalf("expected only the lookup of the queued tests while q1 does not fit, got %d finds", len(finds))
+		}
+		if filter := finds[0].Lookup("filter").String(); filter != `{"status": "Queued"}` {
+			t.Errorf("unexpected filter %s", filter)
+		}
+		if sort := finds[0].Lookup("sort").String(); sort != `{"updatedAt": {"$numberInt":"1"}}` {
+			t.Errorf("expected the oldest queued test first, got sort %s", sort)
+		}
+		// q2 and q3 fit, but wait behind q1.
+		for i, id := range []string{"q1", "q2", "q3"} {
+			if pos := controller.QueuePosition(id); pos != i+1 {
+				t.Errorf("%s: expected position %d, got %d", id, i+1, pos)
+			}
+		}
+		if status := controller.AdmissionStatus(); status.QueueLength != 3 || status.Running != 0 {
+			t.Errorf("unexpected admission status %+v", status)
+		}
+	})
+
+	mt.Run("queues new tests behind waiting ones until the queue is full", func(mt *mtest.T) {
+		cfg := &common.Config{Admission: common.Admission{MaxTotalRate: 50, MaxQueueLength: 2}}
+		controller := newMockController(mt, cfg)
+		restoreQueue(mt, controller, admissionTest("q1", "Queued", 80))
+
+		test := admissionTest("n1", "Pending", 10)
+		if err := startNewTest(mt, controller, test); err != nil {
+			t.Fatal(err)
+		}
+		if pos := controller.QueuePosition("n1"); pos != 2 {
+			t.Errorf("expected the new test to wait at position 2, got %d", pos)
+		}
+		inserts := sentCommands(mt, "insert")
+		if len(inserts) != 1 {
+			t.Fatalf("expected the new test to be stored, got %d inserts", len(inserts))
+		}
+		if status := inserts[0].Lookup("documents").Array().Index(0).Value().Document().Lookup("status").StringValue(); status != "Queued" {
+			t.Errorf("expected the new test to be stored as Queued, got %s", status)
+		}
+
+		mt.AddMockResponses(findResponse(mt, "tests"), findResponse(mt, "guardrail_policies"))
+		full := admissionTest("n2", "Pending", 10)
+		if err := controller.StartTest(context.Background(), &full); !errors.Is(err, models.ErrAdmissionQueueFull) {
+			t.Fatalf("expected ErrAdmissionQueueFull, got %v", err)
+		}
+	})
+
+	mt.Run("starts queued tests first in first out", func(mt *mtest.T) {
+		cfg := &common.Config{Admission: common.Admission{MaxTotalRate: 50}}
+		controller := newMockController(mt, cfg)
+		restoreQueue(mt, controller,
+			admissionTest("q1", "Queued", 80), admissionTest("q2", "Queued", 30), admissionTest("q3", "Queued", 10))
+
+		// Once q1 fits, the tests are taken from the queue in order. They were cancelled
+		// meanwhile, so none of them is launched.
+		cfg.Admission.MaxTotalRate = 120
+		mt.ClearEvents()
+		mt.AddMockResponses(
+			findResponse(mt, "tests"),
+			findResponse(mt, "tests", admissionTest("q1", "Cancelled", 80)),
+			findResponse(mt, "tests", admissionTest("q2", "Cancelled", 30)),
+			findResponse(mt, "tests", admissionTest("q3", "Cancelled", 10)),
+		)
+		if err := controller.RestoreQueue(context.Background()); err != nil {
+			t.Fatal(err)
+		}
+
+		var order []string
+		for _, find := range sentCommands(mt, "find")[1:] {
+			if id, ok := find.Lookup("filter", "testID").StringValueOK(); ok {
+				order = append(order, id)
+			}
+		}
+		if len(order) != 3 || order[0] != "q1" || order[1] != "q2" || order[2] != "q3" {
+			t.Errorf("expected q1, q2 and q3 to be started in order, got %v", order)
+		}
+		if status := controller.AdmissionStatus(); status.QueueLength != 0 || status.Running != 0 || status.Usage.Rate != 0 {
+			t.Errorf("expected the skipped tests to release their capacity, got %+v", status)
+		}
+	})
+
+	mt.Run("does not launch a test cancelled between the read and the update", func(mt *mtest.T) {
+		cfg := &common.Config{Admission: common.Admission{MaxTotalRate: 50}}
+		controller := newMockController(mt, cfg)
+		restoreQueue(mt, controller, admissionTest("q1", "Queued", 80))
+
+		cfg.Admission.MaxTotalRate = 100
+		mt.AddMockResponses(
+			findResponse(mt, "tests"),
+			findResponse(mt, "tests", admissionTest("q1", "Queued", 80)),
+			writeResponse(0),
+		)
+		if err := controller.RestoreQueue(context.Background()); err != nil {
+			t.Fatal(err)
+		}
+		if updates := sentCommands(mt, "update"); len(updates) != 1 {
+			t.Fatalf("expected the conditional status update, got %d updates", len(updates))
+		}
+		if status := controller.AdmissionStatus(); status.Running != 0 || status.Usage.Rate != 0 {
+			t.Errorf("expected the test not to be launched, got %+v", status)
+		}
+	})
+
+	mt.Run("fails a queued test whose status cannot be updated", func(mt *mtest.T) {
+		cfg := &common.Config{Admission: common.Admission{MaxTotalRate: 50}}
+		controller := newMockController(mt, cfg)
+		restoreQueue(mt, controller, admissionTest("q1", "Queued", 80))
+
+		cfg.Admission.MaxTotalRate = 100
+		mt.ClearEvents()
+		mt.AddMockResponses(
+			findResponse(mt, "tests"),
+			findResponse(mt, "tests", admissionTest("q1", "Queued", 80)),
+			mtest.CreateCommandErrorResponse(mtest.CommandError{Code: 91, Message: "shutting down"}),
+			writeResponse(1),
+		)
+		if err := controller.RestoreQueue(context.Background()); err != nil {
+			t.Fatal(err)
+		}
+		updates := sentCommands(mt, "update")
+		if len(updates) != 2 {
+			t.Fatalf("expected the failed update and the error status, got %d updates", len(updates))
+		}
+		if status := updates[1].Lookup("updates", "0", "u", "$set", "status").StringValue(); status != "Error" {
+			t.Errorf("expected the test to be marked Error, got %s", status)
+		}
+		if status := controller.AdmissionStatus(); status.QueueLength != 0 || status.Running != 0 || status.Usage.Rate != 0 {
+			t.Errorf("expected the test to release its capacity, got %+v", status)
+		}
+	})
+}
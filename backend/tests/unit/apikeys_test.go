@@ -0,0 +1,134 @@
+package unit
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/AkshayDubey29/MoniFlux/backend/internal/api/models"
+	"github.com/AkshayDubey29/MoniFlux/backend/internal/api/routers"
+	"github.com/AkshayDubey29/MoniFlux/backend/internal/services/authorization"
+	"github.com/gorilla/mux"
+	"go.mongodb.org/mongo-driver/mongo/integration/mtest"
+)
+
+func TestAPIKeyPrincipalIsAdminOnlyWithAdminScope(t *testing.T) {
+	cases := []struct {
+		name      string
+		principal models.Principal
+		want      bool
+	}{
+		{"admin with a token", models.Principal{Roles: []string{models.AdminRole}}, true},
+		{"editor with a token", models.Principal{Roles: []string{"editor"}}, false},
+		{"admin key without the admin scope", models.Principal{Roles: []string{models.AdminRole}, APIKeyID: "k1", Scopes: []string{authorization.PermTestsStart}}, false},
+		{"admin key with the admin scope", models.Principal{Roles: []string{models.AdminRole}, APIKeyID: "k1", Scopes: []string{models.AdminScope}}, true},
+		{"editor key with the admin scope", models.Principal{Roles: []string{"editor"}, APIKeyID: "k1", Scopes: []string{models.AdminScope}}, false},
+	}
+	for _, tc := range cases {
+		if got := tc.principal.IsAdmin(); got != tc.want {
+			t.Errorf("%s: IsAdmin() = %v, want %v", tc.name, got, tc.want)
+		}
+	}
+}
+
+func TestRegisterLimitsAPIKeysToRouteScopes(t *testing.T) {
+	ok := func(w http.ResponseWriter, r *http.Request) { w.WriteHeader(http.StatusOK) }
+	authorize := func(permission string, handler http.HandlerFunc) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			principal, _ := models.PrincipalFromContext(r.Context())
+			if !principal.AllowsScope(permission) {
+				w.WriteHeader(http.StatusForbidden)
+				return
+			}
+			handler(w, r)
+		})
+	}
+
+	var principal *models.Principal
+	router := mux.NewRouter()
+	protected := router.PathPrefix("/").Subrouter()
+	protected.Use(func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			next.ServeHTTP(w, r.WithContext(models.ContextWithPrincipal(r.Context(), principal)))
+		})
+	})
+	routers.Register(router, protected, []routers.Route{
+		{Method: "GET", Path: routers.APIPrefix + "/tests", Scope: authorization.PermTestsRead, Handler: ok},
+		{Method: "POST", Path: routers.APIPrefix + "/tests/{testID}:start", Permission: authorization.PermTestsStart, Handler: ok},
+		{Method: "POST", Path: routers.APIPrefix + "/teams", Handler: ok},
+		{Method: "PATCH", Path: routers.APIPrefix + "/users/me", Handler: ok},
+	}, authorize, quietLogger())
+
+	token := &models.Principal{UserID: "u1", Roles: []string{"editor"}}
+	startKey := &models.Principal{UserID: "u1", Roles: []string{models.AdminRole}, APIKeyID: "k1", Scopes: []string{authorization.PermTestsStart}}
+	readKey := &models.Principal{UserID: "u1", Roles: []string{"editor"}, APIKeyID: "k2", Scopes: []string{authorization.PermTestsRead}}
+
+	cases := []struct {
+		principal    *models.Principal
+		method, path string
+		want         int
+	}{
+		{token, "GET", "/api/v1/tests", http.StatusOK},
+		{token, "POST", "/api/v1/teams", http.StatusOK},
+		{token, "PATCH", "/api/v1/users/me", http.StatusOK},
+		{startKey, "POST", "/api/v1/tests/t1:start", http.StatusOK},
+		{startKey, "GET", "/api/v1/tests", http.StatusForbidden},
+		{startKey, "POST", "/api/v1/teams", http.StatusForbidden},
+		{startKey, "PATCH", "/api/v1/users/me", http.StatusForbidden},
+		{readKey, "GET", "/api/v1/tests", http.StatusOK},
+		{readKey, "POST", "/api/v1/tests/t1:start", http.StatusForbidden},
+		{readKey, "POST", "/api/v1/teams", http.StatusForbidden},
+	}
+	for _, tc := range cases {
+		principal = tc.principal
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, httptest.NewRequest(tc.method, tc.path, nil))
+		if rec.Code != tc.want {
+			t.Errorf("%s %s as %q: got %d, want %d", tc.method, tc.path, tc.principal.APIKeyID, rec.Code, tc.want)
+		}
+	}
+}
+
+func TestScopedAPIKeyCannotCrossTenants(t *testing.T) {
+	mt := newMockMongo(t)
+	other := models.Test{TestID: "t-other", UserID: "u-other", Status: "Completed"}
+	key := &models.Principal{UserID: "u-admin", Roles: []string{models.AdminRole}, APIKeyID: "k1", Scopes: []string{authorization.PermTestsStart}}
+	ctx := models.ContextWithPrincipal(context.Background(), key)
+
+	mt.Run("get another tenant's test", func(mt *mtest.T) {
+		controller := newMockController(mt, nil)
+		mt.AddMockResponses(findResponse(mt, "tests", other))
+		if _, err := controller.GetTestByID(ctx, other.TestID); !errors.Is(err, models.ErrTestNotFound) {
+			t.Errorf("expected ErrTestNotFound, got %v", err)
+		}
+	})
+
+	mt.Run("list is limited to the owner", func(mt *mtest.T) {
+		controller := newMockController(mt, nil)
+		mt.AddMockResponses(findResponse(mt, "teams"), findResponse(mt, "tests"))
+		if _, err := controller.GetAllTests(ctx, nil); err != nil {
+			t.Fatal(err)
+		}
+		finds := sentCommands(mt, "find")
+		if len(finds) != 2 {
+			t.Fatalf("expected a teams and a tests find, got %d", len(finds))
+		}
+		if filter := finds[1].Lookup("filter").String(); !strings.Contains(filter, `{"userID": "u-admin"}`) {
+			t.Errorf("expected the listing to be scoped to the key's owner, got %s", filter)
+		}
+	})
+
+	mt.Run("admin scope lifts the restriction", func(mt *mtest.T) {
+		controller := newMockController(mt, nil)
+		adminKey := *key
+		adminKey.Scopes = []string{authorization.PermTestsStart, models.AdminScope}
+		mt.AddMockResponses(findResponse(mt, "tests", other))
+		test, err := controller.GetTestByID(models.ContextWithPrincipal(context.Background(), &adminKey), other.TestID)
+		if err != nil || test.TestID != other.TestID {
+			t.Errorf("expected the admin-scoped key to read the test, got %v", err)
+		}
+	})
+}
@@ -0,0 +1,145 @@
+package unit
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/AkshayDubey29/MoniFlux/backend/internal/api/middlewares"
+	"github.com/AkshayDubey29/MoniFlux/backend/internal/common"
+	"github.com/AkshayDubey29/MoniFlux/backend/internal/services/audit"
+	"github.com/gorilla/mux"
+	"go.mongodb.org/mongo-driver/mongo/integration/mtest"
+)
+
+func TestAuditMiddlewareRecordsMutatingRequests(t *testing.T) {
+	mt := newMockMongo(t)
+	cases := []struct {
+		method string
+		status int
+		record bool
+	}{
+		{http.MethodPost, http.StatusCreated, true},
+		{http.MethodPut, http.StatusOK, true},
+		{http.MethodPatch, http.StatusOK, true},
+		{http.MethodDelete, http.StatusForbidden, true},
+		{http.MethodGet, http.StatusOK, false},
+	}
+
+	for _, tc := range cases {
+		mt.Run(tc.method, func(mt *mtest.T) {
+			auditService, err := audit.NewAuditService(&common.Config{MongoDB: mockDB}, quietLogger(), mt.Client)
+			if err != nil {
+				t.Fatal(err)
+			}
+			router := mux.NewRouter()
+			router.Use(middlewares.AuditMiddleware(auditService, quietLogger()))
+			router.HandleFunc("/api/v1/secrets/{name}", func(w http.ResponseWriter, r *http.Request) {
+				audit.EntryFromContext(r.Context()).RecordChange(
+					map[string]string{"token": "s3cret"},
+					map[string]string{"token": "rotated"},
+				)
+				w.WriteHeader(tc.status)
+			})
+			if tc.record {
+				mt.AddMockResponses(writeResponse(1))
+			}
+
+			router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(tc.method, "/api/v1/secrets/db-password", nil))
+
+			inserts := sentCommands(mt, "insert")
+			if !tc.record {
+				if len(inserts) != 0 {
+					t.Errorf("expected %s not to be audited, got %d events", tc.method, len(inserts))
+				}
+				return
+			}
+			if len(inserts) != 1 {
+				t.Fatalf("expected one audit event for %s, got %d", tc.method, len(inserts))
+			}
+			if collection := inserts[0].Lookup("insert").StringValue(); collection != "audit_events" {
+				t.Errorf("expected the event in audit_events, got %s", collection)
+			}
+			event := inserts[0].Lookup("documents", "0").Document()
+			if action := event.Lookup("action").StringValue(); action != tc.method+" /api/v1/secrets/{name}" {
+				t.Errorf("unexpected action %q", action)
+			}
+			if name := event.Lookup("params", "name").StringValue(); name != "db-password" {
+				t.Errorf("expected the route variable to be recorded, got %q", name)
+			}
+			if code := event.Lookup("statusCode").AsInt64(); code != int64(tc.status) {
+				t.Errorf("expected status %d, got %d", tc.status, code)
+			}
+			wantOutcome := audit.OutcomeSuccess
+			if tc.status == http.StatusForbidden {
+				wantOutcome = audit.OutcomeDenied
+			}
+			if outcome := event.Lookup("outcome").StringValue(); outcome != wantOutcome {
+				t.Errorf("expected outcome %s, got %s", wantOutcome, outcome)
+			}
+			change := event.Lookup("changes", "0").Document()
+			if change.Lookup("field").StringValue() != "token" || change.Lookup("after").StringValue() != "[REDACTED]" {
+				t.Errorf("expected the redacted change to be stored, got %s", change)
+			}
+		})
+	}
+}
+
+func TestDiffRedactsSensitiveFields(t *testing.T) {
+	cases := []struct {
+		field         string
+		before, after interface{}
+		redacted      bool
+	}{
+		{"apiKey", "mfx_old", "mfx_new", true},
+		{"password", "hunter2", "hunter3", true},
+		{"auth.clientSecret", "old", "new", true},
+		{"auth.oauth2.refreshToken", "old", "new", true},
+		{"headers.Authorization", "Bearer old", "Bearer new", true},
+		{"created.password", nil, "hunter2", true},
+		{"description", "old", "new", false},
+		{"endpoint", "https://a.example.com", "https://b.example.com", false},
+	}
+
+	// Build documents with every field nested along its path.
+	nest := func(value interface{}, path ...string) map[string]interface{} {
+		doc := map[string]interface{}{}
+		current := doc
+		for i, key := range path {
+			if i == len(path)-1 {
+				if value != nil {
+					current[key] = value
+				}
+				break
+			}
+			child := map[string]interface{}{}
+			current[key] = child
+			current = child
+		}
+		return doc
+	}
+
+	for _, tc := range cases {
+		changes := audit.Diff(nest(tc.before, strings.Split(tc.field, ".")...), nest(tc.after, strings.Split(tc.field, ".")...))
+		if len(changes) != 1 || changes[0].Field != tc.field {
+			t.Errorf("%s: expected a single change, got %+v", tc.field, changes)
+			continue
+		}
+		change := changes[0]
+		wantBefore, wantAfter := tc.before, tc.after
+		if tc.redacted {
+			wantBefore, wantAfter = "[REDACTED]", "[REDACTED]"
+			if tc.before == nil {
+				wantBefore = nil
+			}
+		}
+		if change.Before != wantBefore || change.After != wantAfter {
+			t.Errorf("%s: got %v -> %v, want %v -> %v", tc.field, change.Before, change.After, wantBefore, wantAfter)
+		}
+	}
+
+	if changes := audit.Diff(map[string]string{"password": "same"}, map[string]string{"password": "same"}); len(changes) != 0 {
+		t.Errorf("expected unchanged sensitive fields not to be recorded, got %+v", changes)
+	}
+}
@@ -0,0 +1,236 @@
+package unit
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/AkshayDubey29/MoniFlux/backend/internal/api/handlers"
+	"github.com/AkshayDubey29/MoniFlux/backend/internal/api/models"
+	"github.com/AkshayDubey29/MoniFlux/backend/internal/api/routers"
+	"github.com/AkshayDubey29/MoniFlux/backend/internal/common"
+	mongoDB "github.com/AkshayDubey29/MoniFlux/backend/internal/db/mongo"
+	"github.com/AkshayDubey29/MoniFlux/backend/internal/services/authorization"
+	"github.com/gorilla/mux"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/integration/mtest"
+)
+
+// newMockAuthz returns an authorization service whose MongoDB commands are answered by mt.
+func newMockAuthz(mt *mtest.T, defaultRoles ...string) *authorization.AuthorizationService {
+	cfg := &common.Config{MongoDB: mockDB, DefaultRoles: defaultRoles}
+	return authorization.NewAuthorizationService(cfg, quietLogger(), &mongoDB.MongoClient{Client: mt.Client})
+}
+
+// permissionIDs gives every default permission a fixed ID.
+func permissionIDs() map[string]primitive.ObjectID {
+	ids := map[string]primitive.ObjectID{}
+	for _, perm := range authorization.DefaultPermissions {
+		ids[perm.Name] = primitive.NewObjectID()
+	}
+	return ids
+}
+
+func TestCreateDefaultRolesSeedsRolesAndPermissions(t *testing.T) {
+	mt := newMockMongo(t)
+	mt.Run("missing permission and role", func(mt *mtest.T) {
+		ids := permissionIDs()
+		permission := func(name string) bson.D {
+			return findResponse(mt, "permissions", authorization.Permission{ID: ids[name], Name: name})
+		}
+
+		// view_logs is missing and gets created; the others exist.
+		var responses []bson.D
+		for _, perm := range authorization.DefaultPermissions {
+			if perm.Name == "view_logs" {
+				responses = append(responses, findResponse(mt, "permissions"), findResponse(mt, "permissions"), writeResponse(1))
+				continue
+			}
+			responses = append(responses, permission(perm.Name))
+		}
+		// The admin role is missing and gets created with its permissions.
+		responses = append(responses, findResponse(mt, "roles"), findResponse(mt, "roles"))
+		for _, name := range authorization.DefaultRolePermissions[authorization.AdminRoleName] {
+			responses = append(responses, permission(name))
+		}
+		responses = append(responses, writeResponse(1))
+		// The viewer role exists and is granted its default permissions.
+		responses = append(responses, findResponse(mt, "roles", authorization.Role{ID: primitive.NewObjectID(), Name: "viewer"}))
+		for _, name := range authorization.DefaultRolePermissions["viewer"] {
+			responses = append(responses, permission(name))
+		}
+		responses = append(responses, writeResponse(1))
+		mt.AddMockResponses(responses...)
+
+		if err := newMockAuthz(mt, authorization.AdminRoleName, "viewer").CreateDefaultRoles(context.Background()); err != nil {
+			t.Fatalf("CreateDefaultRoles: %v", err)
+		}
+
+		inserts := sentCommands(mt, "insert")
+		if len(inserts) != 2 {
+			t.Fatalf("expected a permission and a role insert, got %d", len(inserts))
+		}
+		if name := inserts[0].Lookup("documents", "0", "name").StringValue(); name != "view_logs" {
+			t.Errorf("expected view_logs to be created, got %s", name)
+		}
+		role := inserts[1].Lookup("documents", "0")
+		if name := role.Document().Lookup("name").StringValue(); name != authorization.AdminRoleName {
+			t.Errorf("expected the admin role to be created, got %s", name)
+		}
+		values, _ := role.Document().Lookup("permissions").Array().Values()
+		if len(values) != len(authorization.DefaultRolePermissions[authorization.AdminRoleName]) {
+			t.Errorf("expected the admin role to get %d permissions, got %d", len(authorization.DefaultRolePermissions[authorization.AdminRoleName]), len(values))
+		}
+
+		updates := sentCommands(mt, "update")
+		if len(updates) != 1 {
+			t.Fatalf("expected the viewer role to be updated once, got %d", len(updates))
+		}
+		update := updates[0].Lookup("updates", "0")
+		if name := update.Document().Lookup("q", "name").StringValue(); name != "viewer" {
+			t.Errorf("expected the viewer role to be updated, got %s", name)
+		}
+		if _, err := update.Document().LookupErr("u", "$addToSet", "permissions", "$each"); err != nil {
+			t.Errorf("expected permissions to be added to the viewer role, got %s", update)
+		}
+	})
+}
+
+func TestRequirePermissionOnRoute(t *testing.T) {
+	mt := newMockMongo(t)
+	userID := primitive.NewObjectID()
+	createID := primitive.NewObjectID()
+	editorID := primitive.NewObjectID()
+	viewerID := primitive.NewObjectID()
+
+	serve := func(mt *mtest.T, principal *models.Principal) int {
+		authz := newMockAuthz(mt)
+		authorize := func(permission string, handler http.HandlerFunc) http.Handler {
+			return authorization.NewAuthorizationMiddleware(authz, quietLogger(), []string{permission}).MiddlewareFunc(handler)
+		}
+		router := mux.NewRouter()
+		protected := router.PathPrefix("/").Subrouter()
+		protected.Use(func(next http.Handler) http.Handler {
+			return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if principal != nil {
+					r = r.WithContext(models.ContextWithPrincipal(r.Context(), principal))
+				}
+				next.ServeHTTP(w, r)
+			})
+		})
+		routers.Register(router, protected, []routers.Route{{
+			Method: "POST", Path: routers.APIPrefix + "/tests", Permission: authorization.PermTestsCreate,
+			Handler: func(w http.ResponseWriter, r *http.Request) { w.WriteHeader(http.StatusCreated) },
+		}}, authorize, quietLogger())
+
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, httptest.NewRequest("POST", "/api/v1/tests", nil))
+		return rec.Code
+	}
+	permission := func(mt *mtest.T) bson.D {
+		return findResponse(mt, "permissions", authorization.Permission{ID: createID, Name: authorization.PermTestsCreate})
+	}
+	user := func(mt *mtest.T, roles ...primitive.ObjectID) bson.D {
+		return findResponse(mt, "users", authorization.User{ID: userID, Username: "jdoe", Roles: roles})
+	}
+
+	mt.Run("allowed with the permission", func(mt *mtest.T) {
+		mt.AddMockResponses(permission(mt), user(mt, editorID),
+			findResponse(mt, "roles", authorization.Role{ID: editorID, Name: "editor", Permissions: []primitive.ObjectID{createID}}))
+		if code := serve(mt, &models.Principal{UserID: userID.Hex(), Roles: []string{"editor"}}); code != http.StatusCreated {
+			t.Errorf("got %d, want %d", code, http.StatusCreated)
+		}
+	})
+
+	mt.Run("denied without the permission", func(mt *mtest.T) {
+		mt.AddMockResponses(permission(mt), user(mt, viewerID),
+			findResponse(mt, "roles", authorization.Role{ID: viewerID, Name: "viewer"}))
+		if code := serve(mt, &models.Principal{UserID: userID.Hex(), Roles: []string{"viewer"}}); code != http.StatusForbidden {
+			t.Errorf("got %d, want %d", code, http.StatusForbidden)
+		}
+	})
+
+	mt.Run("denied without roles", func(mt *mtest.T) {
+		mt.AddMockResponses(permission(mt), user(mt))
+		if code := serve(mt, &models.Principal{UserID: userID.Hex()}); code != http.StatusForbidden {
+			t.Errorf("got %d, want %d", code, http.StatusForbidden)
+		}
+	})
+
+	mt.Run("denied to an API key without the scope", func(mt *mtest.T) {
+		key := &models.Principal{UserID: userID.Hex(), Roles: []string{"editor"}, APIKeyID: "k1", Scopes: []string{authorization.PermTestsStart}}
+		if code := serve(mt, key); code != http.StatusForbidden {
+			t.Errorf("got %d, want %d", code, http.StatusForbidden)
+		}
+		if n := len(mt.GetAllStartedEvents()); n != 0 {
+			t.Errorf("expected no permission lookup, got %d commands", n)
+		}
+	})
+
+	mt.Run("unauthenticated", func(mt *mtest.T) {
+		if code := serve(mt, nil); code != http.StatusUnauthorized {
+			t.Errorf("got %d, want %d", code, http.StatusUnauthorized)
+		}
+	})
+}
+
+func TestRoleAdministration(t *testing.T) {
+	mt := newMockMongo(t)
+	ctx := context.Background()
+	userID := primitive.NewObjectID()
+	adminRole := authorization.Role{ID: primitive.NewObjectID(), Name: authorization.AdminRoleName}
+
+	mt.Run("admin role cannot be deleted", func(mt *mtest.T) {
+		if err := newMockAuthz(mt).DeleteRole(ctx, authorization.AdminRoleName); !errors.Is(err, authorization.ErrAdminRoleProtected) {
+			t.Errorf("expected ErrAdminRoleProtected, got %v", err)
+		}
+		if n := len(mt.GetAllStartedEvents()); n != 0 {
+			t.Errorf("expected no commands, got %d", n)
+		}
+	})
+
+	mt.Run("other roles can be deleted", func(mt *mtest.T) {
+		mt.AddMockResponses(findResponse(mt, "roles", authorization.Role{ID: primitive.NewObjectID(), Name: "editor"}), writeResponse(1), writeResponse(3))
+		if err := newMockAuthz(mt).DeleteRole(ctx, "editor"); err != nil {
+			t.Fatalf("DeleteRole: %v", err)
+		}
+		if len(sentCommands(mt, "delete")) != 1 || len(sentCommands(mt, "update")) != 1 {
+			t.Error("expected the role to be deleted and pulled from its users")
+		}
+	})
+
+	mt.Run("admin role stays with its last holder", func(mt *mtest.T) {
+		mt.AddMockResponses(findResponse(mt, "roles", adminRole), countResponse(mt, "users", 0))
+		if err := newMockAuthz(mt).RemoveRoleFromUser(ctx, userID.Hex(), authorization.AdminRoleName); !errors.Is(err, authorization.ErrLastAdmin) {
+			t.Errorf("expected ErrLastAdmin, got %v", err)
+		}
+		if n := len(sentCommands(mt, "update")); n != 0 {
+			t.Errorf("expected the user not to be updated, got %d updates", n)
+		}
+	})
+
+	mt.Run("admin role removed while another admin remains", func(mt *mtest.T) {
+		mt.AddMockResponses(findResponse(mt, "roles", adminRole), countResponse(mt, "users", 1), writeResponse(1))
+		if err := newMockAuthz(mt).RemoveRoleFromUser(ctx, userID.Hex(), authorization.AdminRoleName); err != nil {
+			t.Fatalf("RemoveRoleFromUser: %v", err)
+		}
+		if n := len(sentCommands(mt, "update")); n != 1 {
+			t.Errorf("expected the user to be updated once, got %d", n)
+		}
+	})
+
+	mt.Run("removing the last admin is a conflict", func(mt *mtest.T) {
+		mt.AddMockResponses(findResponse(mt, "roles", adminRole), countResponse(mt, "users", 0))
+		admin := handlers.NewAdminHandler(newMockAuthz(mt), quietLogger())
+		req := mux.SetURLVars(httptest.NewRequest("DELETE", "/api/v1/admin/users/"+userID.Hex()+"/roles/admin", nil),
+			map[string]string{"userID": userID.Hex(), "role": authorization.AdminRoleName})
+		rec := httptest.NewRecorder()
+		admin.RemoveUserRole(rec, req)
+		if rec.Code != http.StatusConflict {
+			t.Errorf("got %d, want %d", rec.Code, http.StatusConflict)
+		}
+	})
+}
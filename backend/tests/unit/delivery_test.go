@@ -0,0 +1,1285 @@
+package unit
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"compress/zlib"
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/csv"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/pem"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/AkshayDubey29/MoniFlux/backend/internal/api/models"
+	"github.com/AkshayDubey29/MoniFlux/backend/internal/common"
+	"github.com/AkshayDubey29/MoniFlux/backend/internal/controllers"
+	"github.com/AkshayDubey29/MoniFlux/backend/internal/loadgen/delivery"
+	"github.com/AkshayDubey29/MoniFlux/backend/internal/loadgen/metrics"
+	"github.com/golang/snappy"
+	"github.com/klauspost/compress/zstd"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/sirupsen/logrus"
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+// staticSecrets resolves secret references from a map.
+type staticSecrets map[string]string
+
+func (s staticSecrets) Resolve(ctx context.Context, ref string) (string, error) {
+	value, ok := s[ref]
+	if !ok {
+		return "", fmt.Errorf("secret %s not found", ref)
+	}
+	return value, nil
+}
+
+// testCA issues certificates for the mTLS test.
+type testCA struct {
+	cert *x509.Certificate
+	key  *ecdsa.PrivateKey
+	pem  []byte
+}
+
+func newTestCA(t *testing.T) *testCA {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "MoniFlux Test CA"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		KeyUsage:              x509.KeyUsageCertSign,
+		BasicConstraintsValid: true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &testCA{cert: cert, key: key, pem: pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})}
+}
+
+// issue returns the PEM certificate and key of a leaf signed by the CA.
+func (ca *testCA) issue(t *testing.T, serial int64, usage x509.ExtKeyUsage) (certPEM, keyPEM []byte) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(serial),
+		Subject:      pkix.Name{CommonName: "moniflux-test"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{usage},
+		IPAddresses:  []net.IP{net.ParseIP("127.0.0.1")},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, ca.cert, &key.PublicKey, ca.key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}),
+		pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER})
+}
+
+var jsonPayload = &delivery.Payload{Body: []byte(`{}`), ContentType: "application/json"}
+
+func TestTargetURL(t *testing.T) {
+	cases := []struct {
+		endpoint string
+		port     int
+		want     string
+	}{
+		{"collector:8080", 0, "http://collector:8080"},
+		{"collector", 9000, "http://collector:9000"},
+		{"collector", 443, "https://collector"},
+		{"https://loki.example.com/loki/api/v1/push", 443, "https://loki.example.com/loki/api/v1/push"},
+		{"https://loki.example.com:3100/push", 443, "https://loki.example.com:3100/push"},
+		{"http://collector/ingest", 8080, "http://collector:8080/ingest"},
+	}
+	for _, tc := range cases {
+		got, err := delivery.TargetURL(common.Destination{Endpoint: tc.endpoint, Port: tc.port})
+		if err != nil || got != tc.want {
+			t.Errorf("%s port %d: got %q, %v; want %q", tc.endpoint, tc.port, got, err, tc.want)
+		}
+	}
+	if _, err := delivery.TargetURL(common.Destination{Endpoint: "ftp://collector"}); err == nil {
+		t.Error("expected an unsupported scheme to be rejected")
+	}
+}
+
+func TestHTTPSenderBasicAuthHeadersAndCustomCA(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		user, password, ok := r.BasicAuth()
+		switch {
+		case !ok || user != "loadgen" || password != "s3cret":
+			w.WriteHeader(http.StatusUnauthorized)
+		case r.URL.Path != "/logs":
+			w.WriteHeader(http.StatusNotFound)
+		case r.Header.Get("X-Scope-OrgID") != "team-a" || r.Header.Get("X-Env") != "test" || r.Header.Get("X-Api-Token") != "header-token":
+			w.WriteHeader(http.StatusBadRequest)
+		default:
+			w.WriteHeader(http.StatusNoContent)
+		}
+	}))
+	defer server.Close()
+
+	dest := common.Destination{
+		Type:     "http",
+		Endpoint: server.URL,
+		Auth: &common.DestinationAuth{
+			Basic:         &common.BasicAuth{Username: "loadgen", PasswordSecretRef: "env:password"},
+			Headers:       map[string]string{"X-Env": "test"},
+			SecretHeaders: map[string]string{"X-Api-Token": "env:token"},
+			TenantID:      "team-a",
+			TLS: &common.DestinationTLS{
+				CACert: string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: server.Certificate().Raw})),
+			},
+		},
+	}
+	resolver := staticSecrets{"env:password": "s3cret", "env:token": "header-token"}
+	ctx := context.Background()
+
+	sender, err := delivery.NewHTTPSender(ctx, dest, resolver, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := sender.Send(ctx, "logs", jsonPayload); err != nil {
+		t.Errorf("send failed: %v", err)
+	}
+
+	// Without the custom CA the server certificate is not trusted.
+	dest.Auth.TLS = nil
+	sender, err = delivery.NewHTTPSender(ctx, dest, resolver, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := sender.Send(ctx, "logs", jsonPayload); err == nil {
+		t.Error("expected an untrusted server certificate to fail")
+	}
+
+	dest.SecretRef = "env:token"
+	if _, err := delivery.NewHTTPSender(ctx, dest, resolver, nil); err == nil {
+		t.Error("expected secretRef combined with basic auth to be rejected")
+	}
+}
+
+func TestHTTPSenderMutualTLS(t *testing.T) {
+	ca := newTestCA(t)
+	serverCert, serverKey := ca.issue(t, 2, x509.ExtKeyUsageServerAuth)
+	clientCert, clientKey := ca.issue(t, 3, x509.ExtKeyUsageClientAuth)
+
+	pair, err := tls.X509KeyPair(serverCert, serverKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pool := x509.NewCertPool()
+	pool.AddCert(ca.cert)
+
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusAccepted)
+	}))
+	server.TLS = &tls.Config{
+		Certificates: []tls.Certificate{pair},
+		ClientCAs:    pool,
+		ClientAuth:   tls.RequireAndVerifyClientCert,
+	}
+	server.StartTLS()
+	defer server.Close()
+
+	ctx := context.Background()
+	resolver := staticSecrets{"client-key": string(clientKey)}
+	dest := common.Destination{
+		Type:     "http",
+		Endpoint: server.URL,
+		Auth: &common.DestinationAuth{TLS: &common.DestinationTLS{
+			CACert:             string(ca.pem),
+			ClientCert:         string(clientCert),
+			ClientKeySecretRef: "client-key",
+		}},
+	}
+
+	sender, err := delivery.NewHTTPSender(ctx, dest, resolver, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := sender.Send(ctx, "", jsonPayload); err != nil {
+		t.Errorf("mTLS send failed: %v", err)
+	}
+
+	dest.Auth.TLS.ClientCert, dest.Auth.TLS.ClientKeySecretRef = "", ""
+	sender, err = delivery.NewHTTPSender(ctx, dest, resolver, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := sender.Send(ctx, "", jsonPayload); err == nil {
+		t.Error("expected the server to reject a client without a certificate")
+	}
+}
+
+func TestHTTPSenderOAuth2TokenCaching(t *testing.T) {
+	var tokenRequests int32
+	tokenServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&tokenRequests, 1)
+		id, secret, _ := r.BasicAuth()
+		if err := r.ParseForm(); err != nil || id != "loadgen" || secret != "client-secret" ||
+			r.PostForm.Get("grant_type") != "client_credentials" || r.PostForm.Get("scope") != "write:logs" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprint(w, `{"access_token":"token-1","token_type":"Bearer","expires_in":3600}`)
+	}))
+	defer tokenServer.Close()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer token-1" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	dest := common.Destination{
+		Type:     "http",
+		Endpoint: server.URL,
+		Auth: &common.DestinationAuth{OAuth2: &common.OAuth2Credentials{
+			TokenURL:        tokenServer.URL,
+			ClientID:        "loadgen",
+			ClientSecretRef: "client-secret",
+			Scopes:          []string{"write:logs"},
+		}},
+	}
+	ctx := context.Background()
+	sender, err := delivery.NewHTTPSender(ctx, dest, staticSecrets{"client-secret": "client-secret"}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 3; i++ {
+		if err := sender.Send(ctx, "", jsonPayload); err != nil {
+			t.Fatalf("send %d failed: %v", i, err)
+		}
+	}
+	if n := atomic.LoadInt32(&tokenRequests); n != 1 {
+		t.Errorf("expected the token to be cached, got %d token requests", n)
+	}
+}
+
+func TestHTTPSenderEncodingAndCompression(t *testing.T) {
+	var received struct {
+		contentType, contentEncoding string
+		body                         []byte
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		received.contentType = r.Header.Get("Content-Type")
+		received.contentEncoding = r.Header.Get("Content-Encoding")
+		received.body, _ = io.ReadAll(r.Body)
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	now := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	entries := []interface{}{
+		models.LogEntry{TestID: "t1", Timestamp: now, Message: "hello", Level: "INFO"},
+		models.Metric{TestID: "t1", Timestamp: now, Value: 1.5},
+	}
+
+	decompress := map[string]func([]byte) ([]byte, error){
+		"": func(b []byte) ([]byte, error) { return b, nil },
+		"gzip": func(b []byte) ([]byte, error) {
+			r, err := gzip.NewReader(bytes.NewReader(b))
+			if err != nil {
+				return nil, err
+			}
+			return io.ReadAll(r)
+		},
+		"deflate": func(b []byte) ([]byte, error) {
+			r, err := zlib.NewReader(bytes.NewReader(b))
+			if err != nil {
+				return nil, err
+			}
+			return io.ReadAll(r)
+		},
+		"zstd": func(b []byte) ([]byte, error) {
+			d, err := zstd.NewReader(nil)
+			if err != nil {
+				return nil, err
+			}
+			defer d.Close()
+			return d.DecodeAll(b, nil)
+		},
+		"snappy": func(b []byte) ([]byte, error) { return snappy.Decode(nil, b) },
+	}
+
+	ctx := context.Background()
+	for _, compression := range []string{"none", "gzip", "deflate", "zstd", "snappy"} {
+		dest := common.Destination{Type: "http", Endpoint: server.URL, Encoding: "ndjson", Compression: compression}
+		sender, err := delivery.NewHTTPSender(ctx, dest, nil, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		payload, err := sender.Encode(entries)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := sender.Send(ctx, "", payload); err != nil {
+			t.Fatalf("%s: send failed: %v", compression, err)
+		}
+
+		encoding := received.contentEncoding
+		if (compression == "none" && encoding != "") || (compression != "none" && encoding != compression) {
+			t.Errorf("%s: unexpected Content-Encoding %q", compression, encoding)
+		}
+		if received.contentType != "application/x-ndjson" {
+			t.Errorf("%s: unexpected Content-Type %q", compression, received.contentType)
+		}
+		raw, err := decompress[encoding](received.body)
+		if err != nil {
+			t.Fatalf("%s: failed to decompress: %v", compression, err)
+		}
+		if len(raw) != payload.RawBytes || bytes.Count(raw, []byte("\n")) != 2 || !bytes.Contains(raw, []byte(`"message":"hello"`)) {
+			t.Errorf("%s: unexpected body %q", compression, raw)
+		}
+	}
+
+	// The protobuf Batch holds the log in field 1 and the metric in field 2.
+	payload, err := delivery.EncodePayload("protobuf", "", entries)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var fields []protowire.Number
+	for b := payload.Body; len(b) > 0; {
+		num, typ, n := protowire.ConsumeTag(b)
+		if n < 0 || typ != protowire.BytesType {
+			t.Fatalf("invalid protobuf tag")
+		}
+		b = b[n:]
+		_, n = protowire.ConsumeBytes(b)
+		if n < 0 {
+			t.Fatalf("invalid protobuf field")
+		}
+		b = b[n:]
+		fields = append(fields, num)
+	}
+	if len(fields) != 2 || fields[0] != 1 || fields[1] != 2 || payload.ContentType != "application/x-protobuf" {
+		t.Errorf("unexpected protobuf batch: fields %v, content type %s", fields, payload.ContentType)
+	}
+
+	// A msgpack array of two maps.
+	payload, err = delivery.EncodePayload("msgpack", "", entries)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if payload.Body[0] != 0x92 || payload.Body[1]&0xf0 != 0x80 || !bytes.Contains(payload.Body, []byte("hello")) {
+		t.Errorf("unexpected msgpack body % x", payload.Body[:8])
+	}
+
+	if _, err := delivery.NewHTTPSender(ctx, common.Destination{Type: "http", Endpoint: server.URL, Compression: "brotli"}, nil, nil); err == nil {
+		t.Error("expected an unsupported compression to be rejected")
+	}
+}
+
+func TestDelivererRetryPolicyAndDeadLetter(t *testing.T) {
+	var requests, failFirst int32
+	var status int32 = http.StatusServiceUnavailable
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := atomic.AddInt32(&requests, 1)
+		if n <= atomic.LoadInt32(&failFirst) {
+			w.Header().Set("Retry-After", "0")
+			w.WriteHeader(int(atomic.LoadInt32(&status)))
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	dest := common.Destination{
+		Type:     "http",
+		Endpoint: server.URL,
+		Retry:    &common.RetryPolicy{MaxAttempts: 3, InitialBackoffMs: 1, MaxBackoffMs: 5},
+		Breaker:  &common.CircuitBreaker{Disabled: true},
+	}
+	ctx := context.Background()
+	sender, err := delivery.NewHTTPSender(ctx, dest, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	deadLetter := delivery.NewDeadLetter(filepath.Join(t.TempDir(), "dead-letter", "t1.ndjson"))
+	deliverer := delivery.NewDeliverer(dest, sender, deadLetter, logrus.New())
+	entries := []interface{}{models.Metric{TestID: "t1", Value: 1}, models.Metric{TestID: "t1", Value: 2}}
+
+	// Two retryable failures are absorbed by the retries.
+	atomic.StoreInt32(&failFirst, 2)
+	if err := deliverer.Deliver(ctx, "metrics", entries); err != nil {
+		t.Fatalf("expected the retries to succeed, got %v", err)
+	}
+
+	// Exhausted retries are dead-lettered.
+	atomic.StoreInt32(&requests, 0)
+	atomic.StoreInt32(&failFirst, 3)
+	if err := deliverer.Deliver(ctx, "metrics", entries); err == nil {
+		t.Fatal("expected the delivery to fail after 3 attempts")
+	}
+	if n := atomic.LoadInt32(&requests); n != 3 {
+		t.Errorf("expected 3 attempts, got %d", n)
+	}
+
+	// Statuses that are not retryable are not sent again.
+	atomic.StoreInt32(&requests, 0)
+	atomic.StoreInt32(&status, http.StatusBadRequest)
+	if err := deliverer.Deliver(ctx, "metrics", entries); err == nil {
+		t.Fatal("expected a 400 to fail")
+	}
+	if n := atomic.LoadInt32(&requests); n != 1 {
+		t.Errorf("expected a single attempt for a 400, got %d", n)
+	}
+
+	if err := deliverer.Close(); err != nil {
+		t.Fatal(err)
+	}
+	stats := deliverer.Stats()
+	if stats.Requests != 1 || stats.Failures != 2 || stats.Retries != 4 || stats.DeadLettered != 4 {
+		t.Errorf("unexpected stats: %+v", stats)
+	}
+
+	data, err := os.ReadFile(deadLetter.Path())
+	if err != nil {
+		t.Fatal(err)
+	}
+	lines := bytes.Split(bytes.TrimSpace(data), []byte("\n"))
+	if len(lines) != 4 {
+		t.Fatalf("expected 4 dead-lettered entries, got %d", len(lines))
+	}
+	var record struct {
+		Attempts int                    `json:"attempts"`
+		Error    string                 `json:"error"`
+		Entry    map[string]interface{} `json:"entry"`
+	}
+	if err := json.Unmarshal(lines[0], &record); err != nil {
+		t.Fatal(err)
+	}
+	if record.Attempts != 3 || record.Error == "" || record.Entry["testID"] != "t1" {
+		t.Errorf("unexpected dead-letter record: %s", lines[0])
+	}
+}
+
+func TestDelivererRetryAfterAndCircuitBreaker(t *testing.T) {
+	var requests int32
+	var healthy atomic.Value
+	healthy.Store(false)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		if healthy.Load().(bool) {
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+		w.Header().Set("Retry-After", "2")
+		w.WriteHeader(http.StatusTooManyRequests)
+	}))
+	defer server.Close()
+
+	dest := common.Destination{
+		Type:     "http",
+		Endpoint: server.URL,
+		Retry:    &common.RetryPolicy{MaxAttempts: 1},
+		Breaker:  &common.CircuitBreaker{FailureThreshold: 2, CooldownMs: 100},
+	}
+	ctx := context.Background()
+	sender, err := delivery.NewHTTPSender(ctx, dest, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var statusErr *delivery.StatusError
+	if err := sender.Send(ctx, "", jsonPayload); !errors.As(err, &statusErr) || statusErr.RetryAfter != 2*time.Second {
+		t.Fatalf("expected a 429 with a 2s Retry-After, got %v", err)
+	}
+
+	deliverer := delivery.NewDeliverer(dest, sender, nil, logrus.New())
+	defer deliverer.Close()
+	entries := []interface{}{models.Metric{TestID: "t1", Value: 1}}
+	for i := 0; i < 2; i++ {
+		if err := deliverer.Deliver(ctx, "", entries); err == nil {
+			t.Fatal("expected the delivery to fail")
+		}
+	}
+
+	// The circuit is open: nothing is sent until the cooldown has passed.
+	atomic.StoreInt32(&requests, 0)
+	waitCtx, cancel := context.WithTimeout(ctx, 20*time.Millisecond)
+	err = deliverer.Deliver(waitCtx, "", entries)
+	cancel()
+	if !errors.Is(err, context.DeadlineExceeded) || atomic.LoadInt32(&requests) != 0 {
+		t.Fatalf("expected the open circuit to hold the delivery, got %v after %d requests", err, requests)
+	}
+
+	// After the cooldown a probe goes through and closes the circuit.
+	healthy.Store(true)
+	if err := deliverer.Deliver(ctx, "", entries); err != nil {
+		t.Fatalf("expected the probe to succeed, got %v", err)
+	}
+	if stats := deliverer.Stats(); stats.CircuitOpens != 1 || stats.Requests != 1 {
+		t.Errorf("unexpected stats: %+v", stats)
+	}
+}
+
+// readOctetCounted reads a "<length> <message>" frame.
+func readOctetCounted(r *bufio.Reader) (string, error) {
+	prefix, err := r.ReadString(' ')
+	if err != nil {
+		return "", err
+	}
+	n, err := strconv.Atoi(strings.TrimSpace(prefix))
+	if err != nil {
+		return "", err
+	}
+	msg := make([]byte, n)
+	_, err = io.ReadFull(r, msg)
+	return string(msg), err
+}
+
+func TestSocketSenderSyslogFramingAndReconnect(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer listener.Close()
+
+	// The first connection is closed after two messages, so the sender must reconnect.
+	received := make(chan string, 10)
+	closed := make(chan struct{})
+	go func() {
+		for i := 0; ; i++ {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			reader := bufio.NewReader(conn)
+			for j := 0; i > 0 || j < 2; j++ {
+				msg, err := readOctetCounted(reader)
+				if err != nil {
+					break
+				}
+				received <- msg
+			}
+			conn.Close()
+			if i == 0 {
+				close(closed)
+			}
+		}
+	}()
+
+	facility := 1
+	dest := common.Destination{
+		Type:     "syslog",
+		Endpoint: listener.Addr().String(),
+		Socket:   &common.SocketOptions{Connections: 1, Facility: &facility, Hostname: "loadgen", AppName: "moniflux"},
+	}
+	ctx := context.Background()
+	sender, err := delivery.NewSocketSender(ctx, dest, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer sender.Close()
+
+	now := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	batch := []interface{}{
+		models.LogEntry{TestID: "t1", Timestamp: now, Message: "disk full", Level: "ERROR"},
+		models.Metric{TestID: "t1", Timestamp: now, Value: 1.5},
+	}
+	if err := sender.Send(ctx, batch); err != nil {
+		t.Fatal(err)
+	}
+	if msg := <-received; msg != "<11>1 2024-01-02T03:04:05.000000Z loadgen moniflux - log - disk full" {
+		t.Errorf("unexpected RFC 5424 message %q", msg)
+	}
+	if msg := <-received; !strings.HasPrefix(msg, "<14>1 2024-01-02T03:04:05.000000Z loadgen moniflux - metric - {") {
+		t.Errorf("unexpected RFC 5424 message %q", msg)
+	}
+
+	<-closed
+	if err := sender.Send(ctx, batch[:1]); err != nil {
+		t.Fatalf("expected the sender to reconnect, got %v", err)
+	}
+	select {
+	case msg := <-received:
+		if !strings.HasSuffix(msg, " disk full") {
+			t.Errorf("unexpected message after reconnecting %q", msg)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("no message received after reconnecting")
+	}
+
+	stats := sender.Stats()
+	if stats.Requests != 2 || stats.Entries != 3 || len(stats.Connections) != 1 {
+		t.Fatalf("unexpected stats: %+v", stats)
+	}
+	if conn := stats.Connections[0]; conn.Messages != 3 || conn.Reconnects != 1 || conn.Bytes != stats.WireBytes || conn.BytesPerSecond <= 0 {
+		t.Errorf("unexpected connection stats: %+v", conn)
+	}
+}
+
+func TestSocketSenderUDPAndTLS(t *testing.T) {
+	ctx := context.Background()
+	entry := models.LogEntry{TestID: "t1", Timestamp: time.Now().UTC(), Message: "hello\nworld", Level: "INFO"}
+
+	packets, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer packets.Close()
+	sender, err := delivery.NewSocketSender(ctx, common.Destination{Type: "udp", Endpoint: packets.LocalAddr().String()}, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer sender.Close()
+	if err := sender.Send(ctx, []interface{}{entry, entry}); err != nil {
+		t.Fatal(err)
+	}
+	buf := make([]byte, 2048)
+	for i := 0; i < 2; i++ {
+		_ = packets.SetReadDeadline(time.Now().Add(2 * time.Second))
+		n, _, err := packets.ReadFrom(buf)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var got models.LogEntry
+		if err := json.Unmarshal(buf[:n], &got); err != nil || got.Message != entry.Message {
+			t.Errorf("unexpected datagram %q: %v", buf[:n], err)
+		}
+	}
+
+	ca := newTestCA(t)
+	serverCert, serverKey := ca.issue(t, 2, x509.ExtKeyUsageServerAuth)
+	pair, err := tls.X509KeyPair(serverCert, serverKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	listener, err := tls.Listen("tcp", "127.0.0.1:0", &tls.Config{Certificates: []tls.Certificate{pair}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer listener.Close()
+	lines := make(chan string, 1)
+	go func() {
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		line, _ := bufio.NewReader(conn).ReadString('\n')
+		lines <- line
+	}()
+
+	host, port, _ := net.SplitHostPort(listener.Addr().String())
+	portNumber, _ := strconv.Atoi(port)
+	dest := common.Destination{
+		Type:     "tcp",
+		Endpoint: host,
+		Port:     portNumber,
+		Auth:     &common.DestinationAuth{TLS: &common.DestinationTLS{CACert: string(ca.pem)}},
+	}
+	sender, err = delivery.NewSocketSender(ctx, dest, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer sender.Close()
+	if err := sender.Send(ctx, []interface{}{entry}); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case line := <-lines:
+		var got models.LogEntry
+		if err := json.Unmarshal([]byte(line), &got); err != nil || got.Message != entry.Message {
+			t.Errorf("unexpected newline-framed message %q: %v", line, err)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("no message received over TLS")
+	}
+}
+
+func TestRotatingFileRotationRetentionAndCompression(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "out", "test.log")
+	file, err := delivery.NewRotatingFile(delivery.RotatingFileOptions{
+		Path:        path,
+		MaxSize:     100,
+		MaxFiles:    3,
+		Compression: delivery.CompressionGzip,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Concurrent writers each write whole lines of 30 bytes.
+	line := []byte(strings.Repeat("x", 29) + "\n")
+	var wg sync.WaitGroup
+	for w := 0; w < 4; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < 10; i++ {
+				if err := file.WriteEntry(line); err != nil {
+					t.Error(err)
+				}
+			}
+		}()
+	}
+	wg.Wait()
+	if err := file.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	stats := file.Stats()
+	if stats.BytesWritten != 40*30 || stats.Rotations != 13 || stats.Files != 3 || stats.DeletedFiles != 11 {
+		t.Errorf("unexpected stats: %+v", stats)
+	}
+	matches, err := filepath.Glob(path + ".*")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(matches) != 2 {
+		t.Fatalf("expected 2 retained rotated files, got %v", matches)
+	}
+	var usage int64
+	for _, match := range matches {
+		if !strings.HasSuffix(match, ".gz") {
+			t.Errorf("expected %s to be compressed", match)
+			continue
+		}
+		compressed, err := os.ReadFile(match)
+		if err != nil {
+			t.Fatal(err)
+		}
+		usage += int64(len(compressed))
+		reader, err := gzip.NewReader(bytes.NewReader(compressed))
+		if err != nil {
+			t.Fatal(err)
+		}
+		data, err := io.ReadAll(reader)
+		if err != nil || len(data) != 90 || !bytes.Equal(data[:30], line) {
+			t.Errorf("unexpected content of %s: %q (%v)", match, data, err)
+		}
+	}
+	current, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if usage+int64(len(current)) != stats.DiskUsageBytes {
+		t.Errorf("expected disk usage %d, got %d", usage+int64(len(current)), stats.DiskUsageBytes)
+	}
+}
+
+func TestRotatingFileDiskBudget(t *testing.T) {
+	file, err := delivery.NewRotatingFile(delivery.RotatingFileOptions{
+		Path:       filepath.Join(t.TempDir(), "test.log"),
+		DiskBudget: 100,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+
+	line := []byte(strings.Repeat("x", 29) + "\n")
+	for i := 0; i < 3; i++ {
+		if err := file.WriteEntry(line); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for i := 0; i < 2; i++ {
+		if err := file.WriteEntry(line); !errors.Is(err, delivery.ErrDiskBudgetExceeded) {
+			t.Fatalf("expected the disk budget to be exceeded, got %v", err)
+		}
+	}
+	// Writing stays stopped even for entries that would fit.
+	if err := file.WriteEntry([]byte("x\n")); !errors.Is(err, delivery.ErrDiskBudgetExceeded) {
+		t.Fatalf("expected writing to stay stopped, got %v", err)
+	}
+	if stats := file.Stats(); !stats.BudgetExhausted || stats.DroppedEntries != 3 || stats.DiskUsageBytes != 90 {
+		t.Errorf("unexpected stats: %+v", stats)
+	}
+}
+
+func TestRotatingFileCSVAndParquet(t *testing.T) {
+	dir := t.TempDir()
+	now := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	entries := []interface{}{
+		models.LogEntry{TestID: "t1", Timestamp: now, Message: "hello, \"world\"", Level: "INFO"},
+		models.Metric{TestID: "t1", Timestamp: now, Value: 1.5},
+		models.Trace{TestID: "t1", Timestamp: now, TraceID: "trace", SpanID: "span", Operation: "op", Duration: 7},
+	}
+
+	csvPath := filepath.Join(dir, "out.csv")
+	file, err := delivery.NewRotatingFile(delivery.RotatingFileOptions{Path: csvPath, Format: delivery.FormatCSV})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, entry := range entries {
+		if err := file.Write(entry); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := file.Close(); err != nil {
+		t.Fatal(err)
+	}
+	data, _ := os.ReadFile(csvPath)
+	records, err := csv.NewReader(bytes.NewReader(data)).ReadAll()
+	if err != nil || len(records) != 4 {
+		t.Fatalf("expected a header and 3 records, got %q (%v)", data, err)
+	}
+	want := [][]string{
+		{"signal", "testID", "timestamp", "message", "level", "value", "traceID", "spanID", "operation", "duration"},
+		{"logs", "t1", "2024-01-02T03:04:05Z", "hello, \"world\"", "INFO", "", "", "", "", ""},
+		{"metrics", "t1", "2024-01-02T03:04:05Z", "", "", "1.5", "", "", "", ""},
+		{"traces", "t1", "2024-01-02T03:04:05Z", "", "", "", "trace", "span", "op", "7"},
+	}
+	for i := range want {
+		if strings.Join(records[i], "|") != strings.Join(want[i], "|") {
+			t.Errorf("record %d: expected %q, got %q", i, want[i], records[i])
+		}
+	}
+
+	// Parquet files are completed when they are rotated, and an existing file is
+	// kept rather than appended to.
+	parquetPath := filepath.Join(dir, "out.parquet")
+	if err := os.WriteFile(parquetPath, []byte("old"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	file, err = delivery.NewRotatingFile(delivery.RotatingFileOptions{
+		Path:        parquetPath,
+		Format:      delivery.FormatParquet,
+		Compression: delivery.CompressionZstd,
+		MaxSize:     64 << 10,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 3000; i++ {
+		if err := file.Write(entries[i%len(entries)]); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := file.Close(); err != nil {
+		t.Fatal(err)
+	}
+	paths, _ := filepath.Glob(parquetPath + "*")
+	if len(paths) < 3 {
+		t.Fatalf("expected the file to be rotated, got %v", paths)
+	}
+	for _, path := range paths {
+		data, _ := os.ReadFile(path)
+		if string(data) == "old" {
+			continue
+		}
+		if !bytes.HasPrefix(data, []byte("PAR1")) || !bytes.HasSuffix(data, []byte("PAR1")) {
+			t.Errorf("%s is not a complete Parquet file", path)
+		}
+	}
+	if written, dropped := file.Entries(); written != 3000 || dropped != 0 {
+		t.Errorf("expected 3000 entries, got %d written and %d dropped", written, dropped)
+	}
+	if err := file.WriteEntry([]byte("{}\n")); err == nil {
+		t.Error("expected lines to be refused by a Parquet file")
+	}
+}
+
+func TestWorkerPoolRecordsRun(t *testing.T) {
+	logger := logrus.New()
+	logger.SetOutput(io.Discard)
+	file, err := delivery.NewRotatingFile(delivery.RotatingFileOptions{Path: filepath.Join(t.TempDir(), "out.log")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	wp, err := controllers.NewWorkerPool(context.Background(), 2, controllers.FileDestination, file, nil, nil, nil, logger, 1, 10*time.Millisecond, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 100; i++ {
+		wp.Submit(models.LogEntry{TestID: "t1", Timestamp: time.Now(), Message: "m", Level: "INFO"})
+		wp.Submit(models.Metric{TestID: "t1", Timestamp: time.Now(), Value: 1})
+	}
+	if err := wp.Shutdown(); err != nil {
+		t.Fatal(err)
+	}
+
+	run := wp.Run()
+	var logs, metrics, delivered int64
+	for i, second := range run.Seconds {
+		if second.Second != i || !second.Timestamp.Equal(run.StartedAt.Add(time.Duration(i)*time.Second)) {
+			t.Errorf("unexpected second %d: %+v", i, second)
+		}
+		logs += second.Logs
+		metrics += second.Metrics
+		delivered += second.Delivered
+	}
+	if logs != 100 || metrics != 100 || delivered != 200 || run.Generated != 200 || run.Batches != 200 || run.EndedAt.IsZero() {
+		t.Errorf("unexpected run: %+v", run)
+	}
+}
+
+// gatheredValue returns the value of the counter or gauge series of reg with the
+// given labels, or the sample count of a histogram.
+func gatheredValue(t *testing.T, reg *prometheus.Registry, name string, labels map[string]string) (float64, bool) {
+	t.Helper()
+	families, err := reg.Gather()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, family := range families {
+		if family.GetName() != name {
+			continue
+		}
+	series:
+		for _, m := range family.GetMetric() {
+			for _, pair := range m.GetLabel() {
+				if value, ok := labels[pair.GetName()]; ok && value != pair.GetValue() {
+					continue series
+				}
+			}
+			switch {
+			case m.GetCounter() != nil:
+				return m.GetCounter().GetValue(), true
+			case m.GetGauge() != nil:
+				return m.GetGauge().GetValue(), true
+			case m.GetHistogram() != nil:
+				return float64(m.GetHistogram().GetSampleCount()), true
+			}
+		}
+	}
+	return 0, false
+}
+
+func TestEngineMetricsAndTestLabels(t *testing.T) {
+	logger := logrus.New()
+	logger.SetOutput(io.Discard)
+	engine := metrics.NewEngine(1)
+	reg := prometheus.NewRegistry()
+	reg.MustRegister(engine)
+
+	file, err := delivery.NewRotatingFile(delivery.RotatingFileOptions{Path: filepath.Join(t.TempDir(), "out.log")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	prom := engine.Start("t1", "file", 2)
+	wp, err := controllers.NewWorkerPool(context.Background(), 2, controllers.FileDestination, file, nil, nil, nil, logger, 1, 10*time.Millisecond, prom)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 30; i++ {
+		wp.Submit(models.LogEntry{TestID: "t1", Timestamp: time.Now(), Message: "m", Level: "INFO"})
+		wp.Submit(models.Trace{TestID: "t1", Timestamp: time.Now(), TraceID: "a", SpanID: "b", Operation: "op", Duration: 5})
+	}
+	if v, _ := gatheredValue(t, reg, "moniflux_loadgen_active_tests", nil); v != 1 {
+		t.Errorf("expected 1 active test, got %v", v)
+	}
+	if v, _ := gatheredValue(t, reg, "moniflux_loadgen_workers", map[string]string{"test_id": "t1"}); v != 2 {
+		t.Errorf("expected 2 workers, got %v", v)
+	}
+	if _, ok := gatheredValue(t, reg, "moniflux_loadgen_queue_depth", map[string]string{"test_id": "t1", "destination": "file"}); !ok {
+		t.Error("expected a queue depth for t1")
+	}
+	if err := wp.Shutdown(); err != nil {
+		t.Fatal(err)
+	}
+	prom.Finish()
+
+	expected := []struct {
+		name   string
+		signal string
+		value  float64
+	}{
+		{"moniflux_loadgen_entries_generated_total", metrics.SignalLog, 30},
+		{"moniflux_loadgen_entries_generated_total", metrics.SignalTrace, 30},
+		{"moniflux_loadgen_entries_generated_total", metrics.SignalMetric, 0},
+		{"moniflux_loadgen_entries_enqueued_total", metrics.SignalTrace, 30},
+		{"moniflux_loadgen_entries_dropped_total", metrics.SignalLog, 0},
+		{"moniflux_loadgen_entries_delivered_total", metrics.SignalAll, 60},
+		{"moniflux_loadgen_delivery_failures_total", metrics.SignalAll, 0},
+	}
+	for _, e := range expected {
+		labels := map[string]string{"test_id": "t1", "signal": e.signal, "destination": "file"}
+		if v, ok := gatheredValue(t, reg, e.name, labels); !ok || v != e.value {
+			t.Errorf("%s{signal=%q}: expected %v, got %v (found %v)", e.name, e.signal, e.value, v, ok)
+		}
+	}
+	if v, _ := gatheredValue(t, reg, "moniflux_loadgen_delivery_latency_seconds", map[string]string{"test_id": "t1"}); v != 60 {
+		t.Errorf("expected 60 latency samples, got %v", v)
+	}
+	if v, _ := gatheredValue(t, reg, "moniflux_loadgen_active_tests", nil); v != 0 {
+		t.Errorf("expected no active tests, got %v", v)
+	}
+
+	// t2 takes over the label of the finished t1; t3 starts while it is in use.
+	t2 := engine.Start("t2", "http", 1)
+	t3 := engine.Start("t3", "http", 1)
+	if t2.Label() != "t2" || t3.Label() != metrics.OtherTestID {
+		t.Errorf("unexpected labels %q and %q", t2.Label(), t3.Label())
+	}
+	if _, ok := gatheredValue(t, reg, "moniflux_loadgen_entries_generated_total", map[string]string{"test_id": "t1"}); ok {
+		t.Error("expected the series of t1 to be removed")
+	}
+	t2.Finish()
+	t3.Finish()
+	if v, _ := gatheredValue(t, reg, "moniflux_loadgen_workers", map[string]string{"test_id": metrics.OtherTestID}); v != 0 {
+		t.Errorf("expected no workers for other tests, got %v", v)
+	}
+}
+
+// fakeS3 is an in-memory S3 multipart upload API for one bucket.
+type fakeS3 struct {
+	t          *testing.T
+	bucket     string
+	mu         sync.Mutex
+	uploads    map[string]map[int][]byte
+	objects    map[string][]byte
+	types      map[string]string
+	partFails  int // UploadPart requests still to fail with 503
+	aborted    int
+	forbidden  bool
+	nextUpload int
+}
+
+func newFakeS3(t *testing.T, bucket string) *fakeS3 {
+	return &fakeS3{
+		t:       t,
+		bucket:  bucket,
+		uploads: make(map[string]map[int][]byte),
+		objects: make(map[string][]byte),
+		types:   make(map[string]string),
+	}
+}
+
+func (s *fakeS3) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	body, _ := io.ReadAll(r.Body)
+	if !strings.HasPrefix(r.Header.Get("Authorization"), "AWS4-HMAC-SHA256 Credential=AKIDTEST/") {
+		s.t.Errorf("unsigned request: %q", r.Header.Get("Authorization"))
+	}
+	sum := sha256.Sum256(body)
+	if r.Header.Get("X-Amz-Content-Sha256") != hex.EncodeToString(sum[:]) {
+		s.t.Errorf("payload hash mismatch for %s %s", r.Method, r.URL)
+	}
+	prefix := "/" + s.bucket + "/"
+	if !strings.HasPrefix(r.URL.Path, prefix) {
+		http.Error(w, "<Error><Code>NoSuchBucket</Code></Error>", http.StatusNotFound)
+		return
+	}
+	key := strings.TrimPrefix(r.URL.Path, prefix)
+	query := r.URL.Query()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.forbidden {
+		http.Error(w, "<Error><Code>AccessDenied</Code><Message>Access Denied</Message></Error>", http.StatusForbidden)
+		return
+	}
+	switch {
+	case r.Method == http.MethodPost && query.Has("uploads"):
+		s.nextUpload++
+		id := "upload-" + strconv.Itoa(s.nextUpload)
+		s.uploads[id] = make(map[int][]byte)
+		s.types[key] = r.Header.Get("Content-Type")
+		fmt.Fprintf(w, "<InitiateMultipartUploadResult><Bucket>%s</Bucket><Key>%s</Key><UploadId>%s</UploadId></InitiateMultipartUploadResult>", s.bucket, key, id)
+	case r.Method == http.MethodPut && query.Has("partNumber"):
+		if s.partFails > 0 {
+			s.partFails--
+			w.Header().Set("Retry-After", "0")
+			http.Error(w, "<Error><Code>SlowDown</Code></Error>", http.StatusServiceUnavailable)
+			return
+		}
+		number, _ := strconv.Atoi(query.Get("partNumber"))
+		s.uploads[query.Get("uploadId")][number] = body
+		w.Header().Set("ETag", fmt.Sprintf("%q", "etag-"+strconv.Itoa(number)))
+	case r.Method == http.MethodPost && query.Has("uploadId"):
+		var req struct {
+			Parts []struct {
+				PartNumber int
+				ETag       string
+			} `xml:"Part"`
+		}
+		if err := xml.Unmarshal(body, &req); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		parts := s.uploads[query.Get("uploadId")]
+		var object []byte
+		for i, part := range req.Parts {
+			if part.PartNumber != i+1 || part.ETag != fmt.Sprintf("%q", "etag-"+strconv.Itoa(i+1)) {
+				http.Error(w, "<Error><Code>InvalidPart</Code></Error>", http.StatusBadRequest)
+				return
+			}
+			if i < len(req.Parts)-1 && len(parts[part.PartNumber]) < 5<<20 {
+				http.Error(w, "<Error><Code>EntityTooSmall</Code></Error>", http.StatusBadRequest)
+				return
+			}
+			object = append(object, parts[part.PartNumber]...)
+		}
+		s.objects[key] = object
+		delete(s.uploads, query.Get("uploadId"))
+		fmt.Fprintf(w, "<CompleteMultipartUploadResult><Key>%s</Key></CompleteMultipartUploadResult>", key)
+	case r.Method == http.MethodDelete && query.Has("uploadId"):
+		delete(s.uploads, query.Get("uploadId"))
+		s.aborted++
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		http.Error(w, "unexpected request", http.StatusBadRequest)
+	}
+}
+
+func s3Destination(endpoint string, opts common.S3Options) common.Destination {
+	opts.Bucket = "loadtest"
+	opts.AccessKeyID = "AKIDTEST"
+	opts.SecretAccessKeySecretRef = "s3-secret"
+	return common.Destination{
+		Type:     "s3",
+		Endpoint: endpoint,
+		S3:       &opts,
+		Retry:    &common.RetryPolicy{InitialBackoffMs: 1, MaxBackoffMs: 5},
+	}
+}
+
+func TestS3UploaderMultipartSegments(t *testing.T) {
+	fake := newFakeS3(t, "loadtest")
+	fake.partFails = 1
+	server := httptest.NewServer(fake)
+	defer server.Close()
+
+	logger := logrus.New()
+	logger.SetOutput(io.Discard)
+	secrets := staticSecrets{"s3-secret": "secret-key"}
+	dest := s3Destination(server.URL, common.S3Options{SegmentSizeMB: 6, PartSizeMB: 5})
+	uploader, err := delivery.NewS3Uploader(context.Background(), dest, secrets, nil, t.TempDir(), logger)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Fill one segment of more than 6 MB, which is uploaded in two parts, and start
+	// a second one of about 1 MB that is uploaded on Close.
+	now := time.Now().UTC()
+	message := strings.Repeat("m", 200)
+	const written = 25000
+	for i := 0; i < written; i++ {
+		entry := models.LogEntry{TestID: "t1", Timestamp: now, Message: message, Level: "INFO"}
+		if err := uploader.Write(entry); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := uploader.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	date := now.Format("2006-01-02")
+	first, second := fake.objects["t1/logs/"+date+"/000001.ndjson"], fake.objects["t1/logs/"+date+"/000002.ndjson"]
+	if len(first) < 6<<20 || len(second) == 0 || len(fake.objects) != 2 {
+		t.Fatalf("unexpected objects: %d and %d bytes, %d objects", len(first), len(second), len(fake.objects))
+	}
+	lines := bytes.Count(first, []byte("\n")) + bytes.Count(second, []byte("\n"))
+	if lines != written {
+		t.Errorf("expected %d entries, got %d", written, lines)
+	}
+	var entry models.LogEntry
+	if err := json.Unmarshal(second[:bytes.IndexByte(second, '\n')], &entry); err != nil || entry.Message != message {
+		t.Errorf("unexpected entry %+v (%v)", entry, err)
+	}
+	if contentType := fake.types["t1/logs/"+date+"/000001.ndjson"]; contentType != "application/x-ndjson" {
+		t.Errorf("unexpected content type %q", contentType)
+	}
+
+	stats := uploader.Stats()
+	if stats.Requests != 2 || stats.Failures != 0 || stats.Entries != int64(written) || stats.Retries != 1 ||
+		stats.Objects.Parts != 3 || stats.Objects.PendingSegments != 0 || stats.WireBytes != int64(len(first)+len(second)) {
+		t.Errorf("unexpected stats: %+v %+v", stats, stats.Objects)
+	}
+}
+
+func TestS3UploaderParquetAndKeptSegments(t *testing.T) {
+	fake := newFakeS3(t, "loadtest")
+	server := httptest.NewServer(fake)
+	defer server.Close()
+
+	logger := logrus.New()
+	logger.SetOutput(io.Discard)
+	secrets := staticSecrets{"s3-secret": "secret-key"}
+	dest := s3Destination(server.URL, common.S3Options{
+		Format:      "parquet",
+		Compression: "gzip",
+		KeyTemplate: "runs/{signal}-{testID}-{seq}",
+	})
+	uploader, err := delivery.NewS3Uploader(context.Background(), dest, secrets, nil, t.TempDir(), logger)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 100; i++ {
+		if err := uploader.Write(models.Metric{TestID: "t2", Timestamp: time.Now(), Value: float64(i)}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := uploader.Close(); err != nil {
+		t.Fatal(err)
+	}
+	object := fake.objects["runs/metrics-t2-000001.parquet"]
+	if !bytes.HasPrefix(object, []byte("PAR1")) || !bytes.HasSuffix(object, []byte("PAR1")) {
+		t.Fatalf("expected a Parquet object, got %d bytes: %v", len(object), fake.objects)
+	}
+	if stats := uploader.Stats(); stats.Objects.LastKey != "runs/metrics-t2-000001.parquet" || stats.Entries != 100 {
+		t.Errorf("unexpected stats: %+v %+v", stats, stats.Objects)
+	}
+
+	// Segments that cannot be uploaded are kept and the upload is aborted.
+	fake.forbidden = true
+	segmentDir := t.TempDir()
+	uploader, err = delivery.NewS3Uploader(context.Background(), s3Destination(server.URL, common.S3Options{}), secrets, nil, segmentDir, logger)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := uploader.Write(models.LogEntry{TestID: "t3", Timestamp: time.Now(), Message: "kept", Level: "WARN"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := uploader.Close(); err == nil {
+		t.Fatal("expected an error for the failed upload")
+	}
+	kept, _ := filepath.Glob(filepath.Join(segmentDir, "*", "t3-logs-000001.ndjson"))
+	if stats := uploader.Stats(); len(kept) != 1 || stats.Failures != 1 || stats.Objects.KeptSegments != 1 {
+		t.Errorf("expected the segment to be kept, got %v and %+v", kept, stats.Objects)
+	}
+
+	if err := delivery.CheckS3Options(&common.S3Options{Bucket: "b", KeyTemplate: "{testID}/{date}"}); err == nil {
+		t.Error("expected key templates without {seq} to be rejected")
+	}
+}
@@ -0,0 +1,50 @@
+package unit
+
+import (
+	"errors"
+	"io"
+	"testing"
+
+	"github.com/AkshayDubey29/MoniFlux/backend/internal/common"
+	"github.com/AkshayDubey29/MoniFlux/backend/internal/services/destinations"
+	"github.com/sirupsen/logrus"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+func TestValidateRegisteredDestination(t *testing.T) {
+	// The client is never connected; validation does not use MongoDB.
+	client, err := mongo.NewClient(options.Client().ApplyURI("mongodb://127.0.0.1:1"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	logger := logrus.New()
+	logger.SetOutput(io.Discard)
+	service := destinations.NewDestinationService(&common.Config{MongoDB: "test"}, logger, client)
+
+	dest := common.Destination{Type: "file", FilePath: "/var/log/moniflux/out.log"}
+	if err := service.Validate(&dest); err != nil {
+		t.Fatalf("file destination: %v", err)
+	}
+	if dest.FileCount != 10 || dest.FileFreq != 5 {
+		t.Errorf("expected the file defaults to be applied, got %+v", dest)
+	}
+
+	invalid := map[string]common.Destination{
+		"missing endpoint":  {Type: "http"},
+		"plaintext key":     {Type: "http", Endpoint: "https://collector.example.com", APIKey: "s3cret"},
+		"unknown type":      {Type: "kafka", Endpoint: "kafka.example.com"},
+		"s3 without bucket": {Type: "s3", S3: &common.S3Options{Region: "eu-west-1"}},
+	}
+	for name, dest := range invalid {
+		if err := service.Validate(&dest); !errors.Is(err, destinations.ErrInvalidDestination) {
+			t.Errorf("%s: expected ErrInvalidDestination, got %v", name, err)
+		}
+	}
+
+	for _, name := range []string{"", "-loki", "loki/prod", "probe?x"} {
+		if err := destinations.ValidateName(name); !errors.Is(err, destinations.ErrInvalidName) {
+			t.Errorf("%q: expected ErrInvalidName, got %v", name, err)
+		}
+	}
+}
@@ -0,0 +1,282 @@
+package unit
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+
+	"github.com/AkshayDubey29/MoniFlux/backend/internal/api/models"
+	"github.com/AkshayDubey29/MoniFlux/backend/internal/common"
+	"github.com/AkshayDubey29/MoniFlux/backend/internal/loadgen/delivery"
+	"github.com/AkshayDubey29/MoniFlux/backend/internal/services/guardrails"
+	"go.mongodb.org/mongo-driver/mongo/integration/mtest"
+)
+
+// evaluateGuardrails evaluates the test under the global policy from the configuration.
+func evaluateGuardrails(t *testing.T, mt *mtest.T, cfg common.Guardrails, principal *models.Principal, test *models.Test) *guardrails.Report {
+	t.Helper()
+	service := guardrails.NewGuardrailService(&common.Config{MongoDB: mockDB, Guardrails: cfg}, quietLogger(), mt.Client)
+	mt.AddMockResponses(findResponse(mt, "guardrail_policies"))
+	report, err := service.Evaluate(context.Background(), principal, test, false)
+	if err != nil {
+		t.Fatalf("Evaluate: %v", err)
+	}
+	return report
+}
+
+// violatedRules returns the rules of the report's violations keyed by field.
+func violatedRules(report *guardrails.Report) map[string]string {
+	rules := map[string]string{}
+	for _, v := range report.Violations {
+		rules[v.Field] = v.Rule
+	}
+	return rules
+}
+
+func TestGuardrailDestinations(t *testing.T) {
+	mt := newMockMongo(t)
+	// IP literals keep the cases independent of DNS.
+	cases := []struct {
+		name        string
+		destination common.Destination
+		allowed     []string
+		filePaths   []string
+		field       string
+		rule        string // Empty when the destination is allowed
+	}{
+		{name: "public address", destination: common.Destination{Type: "http", Endpoint: "https://93.184.216.34/ingest"}},
+		{name: "allow-listed host and port", destination: common.Destination{Type: "http", Endpoint: "http://10.0.0.5:8080/ingest"}, allowed: []string{"10.0.0.5:8080"}},
+		{name: "allow-listed CIDR", destination: common.Destination{Type: "tcp", Endpoint: "10.1.2.3", Port: 5170}, allowed: []string{"10.0.0.0/8"}},
+		{name: "syslog default port", destination: common.Destination{Type: "syslog", Endpoint: "udp://10.1.2.3"}, allowed: []string{"10.1.2.3:514"}},
+		{name: "file in allowed directory", destination: common.Destination{Type: "file", FilePath: "/var/log/moniflux/out.log"}, filePaths: []string{"/var/log/moniflux"}},
+
+		{name: "private address without allow-list", destination: common.Destination{Type: "http", Endpoint: "http://10.0.0.5/ingest"},
+			field: "destination.endpoint", rule: guardrails.RuleDestinationBlocked},
+		{name: "loopback without allow-list", destination: common.Destination{Type: "tcp", Endpoint: "127.0.0.1:9000"},
+			field: "destination.endpoint", rule: guardrails.RuleDestinationBlocked},
+		{name: "port outside allow-list", destination: common.Destination{Type: "http", Endpoint: "http://10.0.0.5:9090"}, allowed: []string{"10.0.0.5:8080"},
+			field: "destination.endpoint", rule: guardrails.RuleDestinationNotAllowed},
+		{name: "address outside allowed CIDR", destination: common.Destination{Type: "http", Endpoint: "http://192.168.1.10"}, allowed: []string{"10.0.0.0/8"},
+			field: "destination.endpoint", rule: guardrails.RuleDestinationNotAllowed},
+		{name: "metadata address even when allow-listed", destination: common.Destination{Type: "http", Endpoint: "http://169.254.169.254/latest/meta-data"}, allowed: []string{"169.254.0.0/16"},
+			field: "destination.endpoint", rule: guardrails.RuleDestinationBlocked},
+		{name: "IPv6 metadata address", destination: common.Destination{Type: "http", Endpoint: "http://[fd00:ec2::254]/"},
+			field: "destination.endpoint", rule: guardrails.RuleDestinationBlocked},
+		{name: "Alibaba metadata address", destination: common.Destination{Type: "tcp", Endpoint: "100.100.100.200:80"},
+			field: "destination.endpoint", rule: guardrails.RuleDestinationBlocked},
+		{name: "metadata host name", destination: common.Destination{Type: "http", Endpoint: "http://metadata.google.internal/computeMetadata/v1"}, allowed: []string{"*.internal"},
+			field: "destination.endpoint", rule: guardrails.RuleDestinationBlocked},
+		{name: "OAuth2 token URL at metadata host", destination: common.Destination{Type: "http", Endpoint: "https://93.184.216.34/ingest",
+			Auth: &common.DestinationAuth{OAuth2: &common.OAuth2Credentials{TokenURL: "http://169.254.169.254/token"}}},
+			field: "destination.auth.oauth2.tokenURL", rule: guardrails.RuleDestinationBlocked},
+		{name: "missing endpoint", destination: common.Destination{Type: "http"},
+			field: "destination.endpoint", rule: guardrails.RuleDestinationRequired},
+		{name: "file escaping allowed directory", destination: common.Destination{Type: "file", FilePath: "/var/log/moniflux/../../../etc/passwd"}, filePaths: []string{"/var/log/moniflux"},
+			field: "destination.filePath", rule: guardrails.RuleFilePathNotAllowed},
+	}
+
+	for _, tc := range cases {
+		mt.Run(tc.name, func(mt *mtest.T) {
+			cfg := common.Guardrails{AllowedDestinations: tc.allowed, AllowedFilePaths: tc.filePaths}
+			report := evaluateGuardrails(t, mt, cfg, nil, &models.Test{TestID: "t1", Destination: tc.destination})
+			if tc.rule == "" {
+				if !report.Allowed {
+					t.Errorf("expected the destination to be allowed, got %+v", report.Violations)
+				}
+				return
+			}
+			if report.Allowed || len(report.Violations) != 1 {
+				t.Fatalf("expected a single violation, got %+v", report.Violations)
+			}
+			if rule := violatedRules(report)[tc.field]; rule != tc.rule {
+				t.Errorf("expected %s on %s, got %+v", tc.rule, tc.field, report.Violations)
+			}
+		})
+	}
+}
+
+func TestGuardrailRoleLimits(t *testing.T) {
+	mt := newMockMongo(t)
+	cfg := common.Guardrails{RoleLimits: map[string]common.RoleLimit{
+		"viewer":                       {MaxRate: 100, MaxDuration: 60},
+		"editor":                       {MaxRate: 1000},
+		guardrails.DefaultRoleLimitKey: {MaxRate: 10, MaxDuration: 30},
+	}}
+	cases := []struct {
+		name      string
+		roles     []string
+		rate      int
+		duration  int
+		violation []string
+	}{
+		{name: "within the role limit", roles: []string{"viewer"}, rate: 100, duration: 60},
+		{name: "rate over the role limit", roles: []string{"viewer"}, rate: 101, duration: 60, violation: []string{guardrails.RuleMaxRate}},
+		{name: "duration over the role limit", roles: []string{"viewer"}, rate: 50, duration: 61, violation: []string{guardrails.RuleMaxDuration}},
+		{name: "most permissive of several roles", roles: []string{"viewer", "editor"}, rate: 500, duration: 3600},
+		{name: "unlimited duration", roles: []string{"editor"}, rate: 1000, duration: 86400},
+		{name: "default limit for unlisted roles", roles: []string{"auditor"}, rate: 11, duration: 31, violation: []string{guardrails.RuleMaxRate, guardrails.RuleMaxDuration}},
+	}
+
+	for _, tc := range cases {
+		mt.Run(tc.name, func(mt *mtest.T) {
+			test := &models.Test{TestID: "t1", LogRate: tc.rate, Duration: tc.duration, Destination: common.Destination{Type: "file", FilePath: "/tmp/out.log"}}
+			report := evaluateGuardrails(t, mt, cfg, &models.Principal{UserID: "u1", Roles: tc.roles}, test)
+			if len(report.Violations) != len(tc.violation) {
+				t.Fatalf("expected violations %v, got %+v", tc.violation, report.Violations)
+			}
+			for i, rule := range tc.violation {
+				if report.Violations[i].Rule != rule {
+					t.Errorf("expected violation %d to be %s, got %+v", i, rule, report.Violations[i])
+				}
+			}
+			if report.Allowed != (len(tc.violation) == 0) {
+				t.Errorf("expected Allowed to be %v", len(tc.violation) == 0)
+			}
+		})
+	}
+
+	mt.Run("no limits without a principal", func(mt *mtest.T) {
+		test := &models.Test{TestID: "t1", LogRate: 100000, Duration: 86400, Destination: common.Destination{Type: "file", FilePath: "/tmp/out.log"}}
+		if report := evaluateGuardrails(t, mt, cfg, nil, test); !report.Allowed {
+			t.Errorf("expected scheduled starts to skip role limits, got %+v", report.Violations)
+		}
+	})
+}
+
+func TestValidateTestReportsEveryViolation(t *testing.T) {
+	mt := newMockMongo(t)
+	mt.Run("dry run", func(mt *mtest.T) {
+		controller := newMockController(mt, &common.Config{Guardrails: common.Guardrails{
+			RoleLimits: map[string]common.RoleLimit{"viewer": {MaxRate: 100}},
+		}})
+		mt.AddMockResponses(findResponse(mt, "guardrail_policies"))
+		ctx := models.ContextWithPrincipal(context.Background(), &models.Principal{UserID: "u1", Roles: []string{"viewer"}})
+		test := &models.Test{
+			TestID: "t1", LogType: "INFO", LogRate: 500, MetricsRate: 1, TraceRate: 1, Duration: 60,
+			Destination: common.Destination{Type: "http", Endpoint: "http://169.254.169.254/latest/meta-data"},
+		}
+
+		report, err := controller.ValidateTest(ctx, test)
+		if err != nil {
+			t.Fatalf("ValidateTest: %v", err)
+		}
+		if report.Allowed {
+			t.Fatal("expected the configuration to be rejected")
+		}
+		rules := violatedRules(report)
+		if rules["destination.endpoint"] != guardrails.RuleDestinationBlocked || rules["logRate"] != guardrails.RuleMaxRate {
+			t.Errorf("expected the blocked destination and the rate to be reported, got %+v", report.Violations)
+		}
+		for _, name := range []string{"insert", "update"} {
+			if n := len(sentCommands(mt, name)); n != 0 {
+				t.Errorf("expected a dry run not to write, got %d %s commands", n, name)
+			}
+		}
+		if test.UserID != "" || test.Status != "" {
+			t.Errorf("expected the submitted test to be left unchanged, got %+v", test)
+		}
+	})
+}
+
+func TestDialGuard(t *testing.T) {
+	cases := []struct {
+		name    string
+		allowed []string
+		host    string
+		address string
+		ok      bool
+	}{
+		{name: "public address", host: "example.com", address: "93.184.216.34:443", ok: true},
+		{name: "private address without allow-list", host: "example.com", address: "10.0.0.5:443"},
+		{name: "loopback without allow-list", host: "localhost", address: "127.0.0.1:8080"},
+		{name: "metadata address", host: "169.254.169.254", address: "169.254.169.254:80", allowed: []string{"169.254.0.0/16"}},
+		{name: "IPv6 metadata address", host: "example.com", address: "[fd00:ec2::254]:80"},
+		{name: "metadata host name", host: "metadata.google.internal", address: "93.184.216.34:80", allowed: []string{"*.internal"}},
+		{name: "allow-listed CIDR", host: "ingest.corp", address: "10.1.2.3:443", allowed: []string{"10.0.0.0/8"}, ok: true},
+		{name: "allow-listed host rebound outside CIDR", host: "ingest.corp", address: "192.168.1.10:443", allowed: []string{"10.0.0.0/8"}},
+		{name: "port outside allow-list", host: "10.0.0.5", address: "10.0.0.5:9090", allowed: []string{"10.0.0.5:8080"}},
+		{name: "invalid allow-list allows nothing", host: "example.com", address: "93.184.216.34:443", allowed: []string{"*bad*"}},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := guardrails.NewDialGuard(tc.allowed).Control(tc.host)("tcp", tc.address, nil)
+			if tc.ok && err != nil {
+				t.Errorf("expected the connection to be allowed, got %v", err)
+			}
+			if !tc.ok && !errors.Is(err, guardrails.ErrAddressNotAllowed) {
+				t.Errorf("expected ErrAddressNotAllowed, got %v", err)
+			}
+		})
+	}
+}
+
+func TestGuardedDeliveryRefusesRedirects(t *testing.T) {
+	var metadataRequests int32
+	metadata := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&metadataRequests, 1)
+	}))
+	defer metadata.Close()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "http://169.254.169.254/latest/meta-data/iam/security-credentials/", http.StatusTemporaryRedirect)
+	}))
+	defer server.Close()
+
+	// The loopback test server is allow-listed; the metadata service never is.
+	guard := guardrails.NewDialGuard([]string{"127.0.0.1"})
+	ctx := context.Background()
+	sender, err := delivery.NewHTTPSender(ctx, common.Destination{Type: "http", Endpoint: server.URL}, nil, guard)
+	if err != nil {
+		t.Fatal(err)
+	}
+	payload, err := sender.Encode([]interface{}{models.LogEntry{TestID: "t1", Message: "hello"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var statusErr *delivery.StatusError
+	if err := sender.Send(ctx, "", payload); !errors.As(err, &statusErr) || statusErr.StatusCode != http.StatusTemporaryRedirect {
+		t.Fatalf("expected the redirect to be returned as a failed delivery, got %v", err)
+	}
+
+	// A destination resolving to the metadata service is refused when connecting.
+	metadataSender, err := delivery.NewHTTPSender(ctx, common.Destination{Type: "http", Endpoint: "http://169.254.169.254/latest/meta-data"}, nil, guard)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := metadataSender.Send(ctx, "", payload); !errors.Is(err, guardrails.ErrAddressNotAllowed) {
+		t.Errorf("expected the connection to the metadata service to be refused, got %v", err)
+	}
+
+	// Loopback is only reachable while allow-listed.
+	unlisted := guardrails.NewDialGuard(nil)
+	loopback, err := delivery.NewHTTPSender(ctx, common.Destination{Type: "http", Endpoint: metadata.URL}, nil, unlisted)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := loopback.Send(ctx, "", payload); !errors.Is(err, guardrails.ErrAddressNotAllowed) {
+		t.Errorf("expected the HTTP connection to loopback to be refused, got %v", err)
+	}
+	socket, err := delivery.NewSocketSender(ctx, common.Destination{Type: "tcp", Endpoint: metadata.Listener.Addr().String()}, nil, unlisted)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer socket.Close()
+	if err := socket.Send(ctx, []interface{}{models.LogEntry{TestID: "t1", Message: "hello"}}); !errors.Is(err, guardrails.ErrAddressNotAllowed) {
+		t.Errorf("expected the socket connection to loopback to be refused, got %v", err)
+	}
+	report := delivery.Probe(ctx, common.Destination{Type: "http", Endpoint: metadata.URL}, nil, unlisted, nil)
+	for _, phase := range report.Phases {
+		if phase.Phase == models.ProbePhaseTCP && phase.Status != models.ProbeStatusFailed {
+			t.Errorf("expected the probe connection to loopback to be refused, got %+v", phase)
+		}
+	}
+	if report.Success {
+		t.Error("expected the probe to fail")
+	}
+	if n := atomic.LoadInt32(&metadataRequests); n != 0 {
+		t.Errorf("expected no request to reach the refused server, got %d", n)
+	}
+}
@@ -0,0 +1,124 @@
+package unit
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/AkshayDubey29/MoniFlux/backend/internal/common"
+	"github.com/AkshayDubey29/MoniFlux/backend/internal/loadgen/delivery"
+	"github.com/AkshayDubey29/MoniFlux/backend/internal/services/monitoring"
+	"github.com/sirupsen/logrus"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+func TestReadyzFailsOnlyOnRequiredChecks(t *testing.T) {
+	// The client is never connected; the checks do not use MongoDB.
+	client, err := mongo.NewClient(options.Client().ApplyURI("mongodb://127.0.0.1:1"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg := &common.Config{MongoDB: "test", Monitoring: common.Monitoring{
+		HealthCheckTimeout: "50ms",
+		ReadinessChecks:    []string{monitoring.CheckMongoDB},
+	}}
+	logger := logrus.New()
+	logger.SetOutput(io.Discard)
+
+	mongoErr := errors.New("server selection timeout")
+	service := monitoring.NewMonitoringService(cfg, logger, client)
+	service.AddCheck(monitoring.CheckMongoDB, func(context.Context) error { return mongoErr })
+	service.AddCheck(monitoring.CheckDestinations, func(ctx context.Context) error {
+		<-ctx.Done() // An unreachable destination hangs until the check timeout
+		return ctx.Err()
+	})
+	handlers := monitoring.NewMonitoringHandlers(service, logger)
+
+	readyz := func() (int, monitoring.Report) {
+		rec := httptest.NewRecorder()
+		handlers.ReadyzHandler(rec, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+		var report monitoring.Report
+		if err := json.NewDecoder(rec.Body).Decode(&report); err != nil {
+			t.Fatal(err)
+		}
+		return rec.Code, report
+	}
+
+	code, report := readyz()
+	if code != http.StatusServiceUnavailable || report.Status != monitoring.StatusUnhealthy {
+		t.Errorf("failing mongodb check: got %d %s, want 503 unhealthy", code, report.Status)
+	}
+	if len(report.Checks) != 2 || report.Checks[0].Details != mongoErr.Error() || !report.Checks[0].Required {
+		t.Fatalf("unexpected checks %+v", report.Checks)
+	}
+	if destinations := report.Checks[1]; destinations.Status != monitoring.StatusUnhealthy || destinations.Required {
+		t.Errorf("expected the timed out destinations check to be an optional failure, got %+v", destinations)
+	}
+
+	service = monitoring.NewMonitoringService(cfg, logger, client)
+	service.AddCheck(monitoring.CheckMongoDB, func(context.Context) error { return nil })
+	service.AddCheck(monitoring.CheckWorkers, func(context.Context) error { return errors.New("job queue above 90%") })
+	handlers = monitoring.NewMonitoringHandlers(service, logger)
+	if code, report := readyz(); code != http.StatusOK || report.Status != monitoring.StatusHealthy {
+		t.Errorf("optional failure: got %d %s, want 200 healthy", code, report.Status)
+	}
+
+	rec := httptest.NewRecorder()
+	handlers.LivezHandler(rec, httptest.NewRequest(http.MethodGet, "/livez", nil))
+	if rec.Code != http.StatusOK {
+		t.Errorf("livez: got %d", rec.Code)
+	}
+}
+
+func TestDestinationReachability(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	address := listener.Addr().String()
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			conn.Close()
+		}
+	}()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	sender, err := delivery.NewSocketSender(ctx, common.Destination{Type: "tcp", Endpoint: address}, staticSecrets{}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := sender.CheckReachable(ctx); err != nil {
+		t.Errorf("listening tcp destination: %v", err)
+	}
+	httpSender, err := delivery.NewHTTPSender(ctx, common.Destination{Type: "http", Endpoint: "http://" + address}, staticSecrets{}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := httpSender.CheckReachable(ctx); err != nil {
+		t.Errorf("listening http destination: %v", err)
+	}
+
+	listener.Close()
+	if err := sender.CheckReachable(ctx); err == nil {
+		t.Error("expected a closed tcp destination to be unreachable")
+	}
+	udp, err := delivery.NewSocketSender(ctx, common.Destination{Type: "udp", Endpoint: address}, staticSecrets{}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := udp.CheckReachable(ctx); err != nil {
+		t.Errorf("udp destinations are connectionless, got %v", err)
+	}
+}
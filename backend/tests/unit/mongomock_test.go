@@ -0,0 +1,83 @@
+package unit
+
+import (
+	"io"
+	"testing"
+
+	"github.com/AkshayDubey29/MoniFlux/backend/internal/common"
+	"github.com/AkshayDubey29/MoniFlux/backend/internal/controllers"
+	"github.com/sirupsen/logrus"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo/integration/mtest"
+)
+
+// mockDB is the database the services under test are configured with.
+const mockDB = "test"
+
+// newMockMongo returns a test whose mt.Client answers each MongoDB command, in
+// order, with the next response queued by mt.AddMockResponses. The commands sent are
+// recorded as started events.
+func newMockMongo(t *testing.T) *mtest.T {
+	return mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+}
+
+// quietLogger returns a logger discarding its output.
+func quietLogger() *logrus.Logger {
+	logger := logrus.New()
+	logger.SetOutput(io.Discard)
+	return logger
+}
+
+// newMockController returns a controller whose MongoDB commands are answered by mt.
+func newMockController(mt *mtest.T, cfg *common.Config) *controllers.LoadGenController {
+	if cfg == nil {
+		cfg = &common.Config{}
+	}
+	cfg.MongoDB = mockDB
+	return controllers.NewLoadGenController(cfg, quietLogger(), mt.Client)
+}
+
+// toDoc converts a model into the BSON document MongoDB would return for it.
+func toDoc(t testing.TB, v interface{}) bson.D {
+	t.Helper()
+	raw, err := bson.Marshal(v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var doc bson.D
+	if err := bson.Unmarshal(raw, &doc); err != nil {
+		t.Fatal(err)
+	}
+	return doc
+}
+
+// findResponse answers a find command on the collection with the documents.
+func findResponse(t testing.TB, collection string, docs ...interface{}) bson.D {
+	t.Helper()
+	batch := make([]bson.D, len(docs))
+	for i, d := range docs {
+		batch[i] = toDoc(t, d)
+	}
+	return mtest.CreateCursorResponse(0, mockDB+"."+collection, mtest.FirstBatch, batch...)
+}
+
+// countResponse answers a countDocuments command.
+func countResponse(t testing.TB, collection string, n int) bson.D {
+	return findResponse(t, collection, bson.D{{Key: "n", Value: n}})
+}
+
+// writeResponse answers an update or delete command matching n documents.
+func writeResponse(n int) bson.D {
+	return mtest.CreateSuccessResponse(bson.E{Key: "n", Value: n}, bson.E{Key: "nModified", Value: n})
+}
+
+// sentCommands returns the commands named name sent so far, in order.
+func sentCommands(mt *mtest.T, name string) []bson.Raw {
+	var commands []bson.Raw
+	for _, evt := range mt.GetAllStartedEvents() {
+		if evt.CommandName == name {
+			commands = append(commands, evt.Command)
+		}
+	}
+	return commands
+}
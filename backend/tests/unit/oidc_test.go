@@ -0,0 +1,270 @@
+package unit
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/AkshayDubey29/MoniFlux/backend/internal/api/models"
+	"github.com/AkshayDubey29/MoniFlux/backend/internal/common"
+	"github.com/AkshayDubey29/MoniFlux/backend/internal/services/authentication"
+	jwt "github.com/golang-jwt/jwt/v4"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/integration/mtest"
+)
+
+// stubIdP is a minimal OpenID Connect provider serving discovery, JWKS and a token
+// endpoint that enforces PKCE.
+type stubIdP struct {
+	server    *httptest.Server
+	rsaKey    *rsa.PrivateKey
+	ecKey     *ecdsa.PrivateKey
+	challenge string // code_challenge of the pending authorization
+	idToken   string // ID token returned by the token endpoint
+}
+
+func newStubIdP(t *testing.T) *stubIdP {
+	t.Helper()
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	idp := &stubIdP{rsaKey: rsaKey, ecKey: ecKey}
+
+	b64 := func(b []byte) string { return base64.RawURLEncoding.EncodeToString(b) }
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]string{
+			"issuer":                 idp.server.URL,
+			"authorization_endpoint": idp.server.URL + "/authorize",
+			"token_endpoint":         idp.server.URL + "/token",
+			"jwks_uri":               idp.server.URL + "/jwks",
+		})
+	})
+	mux.HandleFunc("/jwks", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]interface{}{"keys": []map[string]string{
+			{"kty": "RSA", "kid": "rsa1", "use": "sig", "n": b64(rsaKey.N.Bytes()), "e": b64(big.NewInt(int64(rsaKey.E)).Bytes())},
+			{"kty": "EC", "kid": "ec1", "use": "sig", "crv": "P-256", "x": b64(ecKey.X.Bytes()), "y": b64(ecKey.Y.Bytes())},
+		}})
+	})
+	mux.HandleFunc("/token", func(w http.ResponseWriter, r *http.Request) {
+		r.ParseForm()
+		if r.Form.Get("code") != "good-code" || authentication.PKCEChallenge(r.Form.Get("code_verifier")) != idp.challenge {
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(map[string]string{"error": "invalid_grant"})
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]string{"id_token": idp.idToken, "token_type": "Bearer"})
+	})
+	idp.server = httptest.NewServer(mux)
+	t.Cleanup(idp.server.Close)
+	return idp
+}
+
+func (idp *stubIdP) sign(t *testing.T, method jwt.SigningMethod, kid string, claims jwt.MapClaims) string {
+	t.Helper()
+	token := jwt.NewWithClaims(method, claims)
+	token.Header["kid"] = kid
+	var key interface{} = idp.rsaKey
+	if method == jwt.SigningMethodES256 {
+		key = idp.ecKey
+	}
+	signed, err := token.SignedString(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return signed
+}
+
+func (idp *stubIdP) claims(overrides jwt.MapClaims) jwt.MapClaims {
+	claims := jwt.MapClaims{
+		"iss":                idp.server.URL,
+		"aud":                "moniflux",
+		"sub":                "user-123",
+		"preferred_username": "jdoe",
+		"email":              "jdoe@example.com",
+		"groups":             []string{"moniflux-admins", "other"},
+		"exp":                time.Now().Add(time.Hour).Unix(),
+		"iat":                time.Now().Unix(),
+	}
+	for k, v := range overrides {
+		claims[k] = v
+	}
+	return claims
+}
+
+func newTestProvider(t *testing.T, idp *stubIdP) *authentication.OIDCProvider {
+	t.Helper()
+	provider, err := authentication.NewOIDCProvider(context.Background(), common.OIDC{
+		Enabled:     true,
+		IssuerURL:   idp.server.URL,
+		ClientID:    "moniflux",
+		RedirectURL: "https://moniflux.example.com/auth/oidc/callback",
+	}, idp.server.Client())
+	if err != nil {
+		t.Fatalf("NewOIDCProvider: %v", err)
+	}
+	return provider
+}
+
+func TestOIDCVerifyRS256AndES256(t *testing.T) {
+	idp := newStubIdP(t)
+	provider := newTestProvider(t, idp)
+
+	for _, tc := range []struct {
+		method jwt.SigningMethod
+		kid    string
+	}{{jwt.SigningMethodRS256, "rsa1"}, {jwt.SigningMethodES256, "ec1"}} {
+		token := idp.sign(t, tc.method, tc.kid, idp.claims(nil))
+		if !provider.IsIssuedBy(token) {
+			t.Fatalf("%s: token not recognised as issued by the provider", tc.method.Alg())
+		}
+		identity, err := provider.Verify(context.Background(), token, "")
+		if err != nil {
+			t.Fatalf("%s: Verify: %v", tc.method.Alg(), err)
+		}
+		if identity.Subject != "user-123" || identity.Username != "jdoe" || identity.Email != "jdoe@example.com" {
+			t.Errorf("%s: unexpected identity %+v", tc.method.Alg(), identity)
+		}
+		if len(identity.Groups) != 2 || identity.Groups[0] != "moniflux-admins" {
+			t.Errorf("%s: unexpected groups %v", tc.method.Alg(), identity.Groups)
+		}
+	}
+}
+
+func TestOIDCVerifyRejectsInvalidTokens(t *testing.T) {
+	idp := newStubIdP(t)
+	provider := newTestProvider(t, idp)
+
+	hmac := jwt.NewWithClaims(jwt.SigningMethodHS256, idp.claims(nil))
+	hmacToken, _ := hmac.SignedString([]byte("shared-secret"))
+
+	cases := map[string]string{
+		"wrong audience": idp.sign(t, jwt.SigningMethodRS256, "rsa1", idp.claims(jwt.MapClaims{"aud": "someone-else"})),
+		"expired":        idp.sign(t, jwt.SigningMethodRS256, "rsa1", idp.claims(jwt.MapClaims{"exp": time.Now().Add(-time.Minute).Unix()})),
+		"wrong issuer":   idp.sign(t, jwt.SigningMethodRS256, "rsa1", idp.claims(jwt.MapClaims{"iss": "https://evil.example.com"})),
+		"unknown key":    idp.sign(t, jwt.SigningMethodRS256, "rotated", idp.claims(nil)),
+		"HMAC signed":    hmacToken,
+	}
+	for name, token := range cases {
+		if _, err := provider.Verify(context.Background(), token, ""); err == nil {
+			t.Errorf("%s: expected verification to fail", name)
+		}
+	}
+
+	nonceToken := idp.sign(t, jwt.SigningMethodRS256, "rsa1", idp.claims(jwt.MapClaims{"nonce": "abc"}))
+	if _, err := provider.Verify(context.Background(), nonceToken, "xyz"); err == nil {
+		t.Error("expected nonce mismatch to fail")
+	}
+	if _, err := provider.Verify(context.Background(), nonceToken, "abc"); err != nil {
+		t.Errorf("expected matching nonce to verify: %v", err)
+	}
+}
+
+func TestOIDCAuthorizationCodeWithPKCE(t *testing.T) {
+	idp := newStubIdP(t)
+	provider := newTestProvider(t, idp)
+
+	verifier, challenge, err := authentication.NewPKCEVerifier()
+	if err != nil {
+		t.Fatal(err)
+	}
+	authURL, err := url.Parse(provider.AuthCodeURL("state-1", "nonce-1", challenge))
+	if err != nil {
+		t.Fatal(err)
+	}
+	q := authURL.Query()
+	if q.Get("code_challenge") != challenge || q.Get("code_challenge_method") != "S256" || q.Get("state") != "state-1" || q.Get("nonce") != "nonce-1" {
+		t.Fatalf("unexpected authorization URL %s", authURL)
+	}
+
+	// The IdP records the challenge when the user authorizes.
+	idp.challenge = q.Get("code_challenge")
+	idp.idToken = idp.sign(t, jwt.SigningMethodES256, "ec1", idp.claims(jwt.MapClaims{"nonce": "nonce-1"}))
+
+	if _, err := provider.Exchange(context.Background(), "good-code", "wrong-verifier"); err == nil {
+		t.Fatal("expected exchange with the wrong verifier to fail")
+	}
+	idToken, err := provider.Exchange(context.Background(), "good-code", verifier)
+	if err != nil {
+		t.Fatalf("Exchange: %v", err)
+	}
+	if _, err := provider.Verify(context.Background(), idToken, "nonce-1"); err != nil {
+		t.Fatalf("Verify ID token: %v", err)
+	}
+}
+
+// failingRoleMapper fails the test when roles are synced.
+type failingRoleMapper struct{ t *testing.T }
+
+func (m failingRoleMapper) SyncGroupRoles(ctx context.Context, userID string, groups []string) ([]string, error) {
+	m.t.Errorf("roles of user %s synced while validating a bearer token", userID)
+	return nil, nil
+}
+
+func TestOIDCBearerTokenDoesNotWriteUsers(t *testing.T) {
+	idp := newStubIdP(t)
+	provider := newTestProvider(t, idp)
+	token := idp.sign(t, jwt.SigningMethodRS256, "rsa1", idp.claims(jwt.MapClaims{"jti": "idp-token-1"}))
+
+	mt := newMockMongo(t)
+	newService := func(mt *mtest.T) *authentication.AuthenticationService {
+		service, err := authentication.NewAuthenticationService(&common.Config{MongoDB: mockDB}, quietLogger(), mt.Client)
+		if err != nil {
+			t.Fatal(err)
+		}
+		service.EnableOIDC(provider, failingRoleMapper{t})
+		return service
+	}
+
+	mt.Run("known user", func(mt *mtest.T) {
+		service := newService(mt)
+		roleID := primitive.NewObjectID()
+		user := models.User{ID: primitive.NewObjectID(), Username: "jdoe", Roles: []primitive.ObjectID{roleID}, OIDCIssuer: idp.server.URL, OIDCSubject: "user-123"}
+		mt.AddMockResponses(
+			findResponse(mt, "users", user),
+			findResponse(mt, "roles", bson.M{"_id": roleID, "name": "editor"}),
+			countResponse(mt, "revoked_tokens", 0),
+		)
+
+		claims, err := service.ValidateJWT(context.Background(), token)
+		if err != nil {
+			t.Fatalf("ValidateJWT: %v", err)
+		}
+		if claims.UserID != user.ID.Hex() || len(claims.Roles) != 1 || claims.Roles[0] != "editor" || claims.ID != "idp-token-1" {
+			t.Errorf("unexpected claims %+v", claims)
+		}
+		for _, name := range []string{"insert", "update"} {
+			if n := len(sentCommands(mt, name)); n != 0 {
+				t.Errorf("expected no %s commands, got %d", name, n)
+			}
+		}
+	})
+
+	mt.Run("user who never signed in", func(mt *mtest.T) {
+		service := newService(mt)
+		mt.AddMockResponses(findResponse(mt, "users"))
+		if _, err := service.ValidateJWT(context.Background(), token); !errors.Is(err, authentication.ErrUnknownSSOUser) {
+			t.Errorf("expected ErrUnknownSSOUser, got %v", err)
+		}
+		if n := len(sentCommands(mt, "insert")); n != 0 {
+			t.Errorf("expected the user not to be provisioned, got %d inserts", n)
+		}
+	})
+}
@@ -0,0 +1,125 @@
+package unit
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/AkshayDubey29/MoniFlux/backend/internal/api/routers"
+	"github.com/gorilla/mux"
+	"github.com/sirupsen/logrus"
+)
+
+func TestOpenAPIDocument(t *testing.T) {
+	doc := routers.OpenAPIDocument()
+
+	// The test resource is served under /api/v1.
+	for path, methods := range map[string][]string{
+		"/api/v1/tests":                   {"get", "post"},
+		"/api/v1/tests/{testID}":          {"get", "patch", "delete"},
+		"/api/v1/tests/{testID}:start":    {"post"},
+		"/api/v1/tests/{testID}:cancel":   {"post"},
+		"/api/v1/tests/{testID}:restart":  {"post"},
+		"/api/v1/tests/{testID}:schedule": {"post"},
+	} {
+		item, ok := doc.Paths[path]
+		if !ok {
+			t.Errorf("expected path %s", path)
+			continue
+		}
+		for _, method := range methods {
+			op, ok := item[method]
+			if !ok {
+				t.Errorf("expected %s %s", method, path)
+				continue
+			}
+			if op.Deprecated {
+				t.Errorf("%s %s should not be deprecated", method, path)
+			}
+			if _, ok := op.Responses["default"].Content["application/problem+json"]; !ok {
+				t.Errorf("%s %s should document problem responses", method, path)
+			}
+		}
+	}
+	if params := doc.Paths["/api/v1/tests/{testID}:start"]["post"].Parameters; len(params) != 1 || params[0].Name != "testID" || params[0].In != "path" {
+		t.Errorf("unexpected parameters %+v", params)
+	}
+
+	// The old paths are deprecated aliases.
+	for _, path := range []string{"/start-test", "/teams"} {
+		for method, op := range doc.Paths[path] {
+			if !op.Deprecated {
+				t.Errorf("%s %s should be deprecated", method, path)
+			}
+		}
+	}
+
+	// Public operations need no credentials.
+	if op := doc.Paths["/api/v1/openapi.json"]["get"]; op == nil || op.Security == nil || len(*op.Security) != 0 {
+		t.Errorf("expected the OpenAPI document to be public")
+	}
+
+	// Schemas follow the json and validate tags.
+	test := doc.Components.Schemas["Test"]
+	if test == nil {
+		t.Fatalf("expected a Test schema")
+	}
+	required := make(map[string]bool)
+	for _, name := range test.Required {
+		required[name] = true
+	}
+	if !required["testID"] || !required["duration"] || required["tags"] {
+		t.Errorf("unexpected required fields %v", test.Required)
+	}
+	if logType := test.Properties["logType"]; logType == nil || len(logType.Enum) != 4 {
+		t.Errorf("expected the log types to be enumerated, got %+v", logType)
+	}
+	if status := test.Properties["status"]; status == nil || status.Enum[len(status.Enum)-1] != "Results Saved" {
+		t.Errorf("expected quoted enum values to be kept whole, got %+v", status)
+	}
+	if created := test.Properties["createdAt"]; created == nil || created.Format != "date-time" {
+		t.Errorf("expected createdAt to be a date-time, got %+v", created)
+	}
+}
+
+func TestRegisterRoutes(t *testing.T) {
+	logger := logrus.New()
+	logger.SetOutput(io.Discard)
+
+	var got string
+	handler := func(w http.ResponseWriter, r *http.Request) {
+		got = r.Method + " " + mux.Vars(r)["testID"]
+	}
+	router := mux.NewRouter()
+	routers.Register(router, router, []routers.Route{
+		{Method: "GET", Path: routers.APIPrefix + "/tests/{testID}", Handler: handler},
+		{Method: "POST", Path: routers.APIPrefix + "/tests/{testID}:start", Handler: handler},
+		{Method: "GET", Path: routers.APIPrefix + "/tests/{testID}/results/export", Alias: true, Handler: handler},
+	}, nil, logger)
+
+	cases := []struct {
+		method, path string
+		want         string
+		deprecated   bool
+	}{
+		{"GET", "/api/v1/tests/t1", "GET t1", false},
+		{"POST", "/api/v1/tests/t1:start", "POST t1", false},
+		{"GET", "/api/v1/tests/t1/results/export", "GET t1", false},
+		{"GET", "/tests/t1/results/export", "GET t1", true},
+	}
+	for _, tc := range cases {
+		got = ""
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, httptest.NewRequest(tc.method, tc.path, nil))
+		if got != tc.want {
+			t.Errorf("%s %s: expected %q, got %q (status %d)", tc.method, tc.path, tc.want, got, rec.Code)
+		}
+		if deprecated := rec.Header().Get("Deprecation") == "true"; deprecated != tc.deprecated {
+			t.Errorf("%s %s: expected deprecated %v", tc.method, tc.path, tc.deprecated)
+		}
+		if tc.deprecated && rec.Header().Get("Link") != `</api/v1/tests/{testID}/results/export>; rel="successor-version"` {
+			t.Errorf("%s %s: unexpected Link %q", tc.method, tc.path, rec.Header().Get("Link"))
+		}
+	}
+}
@@ -0,0 +1,88 @@
+package unit
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/AkshayDubey29/MoniFlux/backend/internal/api/models"
+	"github.com/AkshayDubey29/MoniFlux/backend/internal/common"
+	"github.com/AkshayDubey29/MoniFlux/backend/internal/loadgen/delivery"
+)
+
+func TestProbeDestination(t *testing.T) {
+	var received int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer s3cret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		atomic.AddInt32(&received, 1)
+		w.WriteHeader(http.StatusAccepted)
+	}))
+	defer server.Close()
+
+	samples := []delivery.ProbeSample{
+		{Signal: "log", Entry: models.LogEntry{TestID: "probe", Timestamp: time.Now(), Message: "hello", Level: "INFO"}},
+		{Signal: "metric", Entry: models.Metric{TestID: "probe", Timestamp: time.Now(), Value: 1}},
+		{Signal: "trace", Entry: models.Trace{TestID: "probe", Timestamp: time.Now(), TraceID: "t", SpanID: "s", Operation: "op"}},
+	}
+	dest := common.Destination{Type: "http", Endpoint: server.URL, SecretRef: "api-key"}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	report := delivery.Probe(ctx, dest, staticSecrets{"api-key": "s3cret"}, nil, samples)
+	if !report.Success {
+		t.Fatalf("expected the probe to succeed, got %+v", report.Phases)
+	}
+	phases := map[string]string{}
+	for _, phase := range report.Phases {
+		phases[phase.Phase+" "+phase.Signal] = phase.Status
+	}
+	for name, status := range map[string]string{
+		"config ": models.ProbeStatusOK, "dns ": models.ProbeStatusOK, "tcp ": models.ProbeStatusOK,
+		"tls ": models.ProbeStatusSkipped, "auth ": models.ProbeStatusOK,
+		"send log": models.ProbeStatusOK, "send metric": models.ProbeStatusOK, "send trace": models.ProbeStatusOK,
+	} {
+		if phases[name] != status {
+			t.Errorf("phase %q: got %q, want %q", name, phases[name], status)
+		}
+	}
+	if received != 3 {
+		t.Errorf("expected one request per signal, got %d", received)
+	}
+
+	report = delivery.Probe(ctx, dest, staticSecrets{"api-key": "wrong"}, nil, samples)
+	if report.Success {
+		t.Fatal("expected rejected credentials to fail the probe")
+	}
+	preflight := &models.PreflightError{Report: report}
+	if !errors.Is(preflight, models.ErrPreflightFailed) || !strings.Contains(preflight.Error(), "send log") {
+		t.Errorf("unexpected preflight error %q", preflight.Error())
+	}
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	closed := listener.Addr().String()
+	listener.Close()
+	report = delivery.Probe(ctx, common.Destination{Type: "http", Endpoint: "http://" + closed}, nil, nil, samples)
+	if report.Success {
+		t.Fatal("expected a closed port to fail the probe")
+	}
+	for _, phase := range report.Phases {
+		switch {
+		case phase.Phase == models.ProbePhaseTCP && phase.Status != models.ProbeStatusFailed:
+			t.Errorf("tcp phase: got %q, want failed", phase.Status)
+		case phase.Phase == models.ProbePhaseSend && phase.Status != models.ProbeStatusSkipped:
+			t.Errorf("send %s after a failed connection: got %q, want skipped", phase.Signal, phase.Status)
+		}
+	}
+}
@@ -0,0 +1,57 @@
+package unit
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/AkshayDubey29/MoniFlux/backend/internal/api/handlers"
+	"github.com/AkshayDubey29/MoniFlux/backend/internal/api/problem"
+	"github.com/sirupsen/logrus"
+)
+
+func TestProblemResponses(t *testing.T) {
+	logger := logrus.New()
+	logger.SetOutput(io.Discard)
+	// Requests rejected before reaching the controller do not need one.
+	h := handlers.NewHandler(nil, nil, logger)
+
+	cases := []struct {
+		name   string
+		body   string
+		status int
+		code   string
+		fields bool
+	}{
+		{"malformed body", "{", http.StatusBadRequest, problem.CodeInvalidRequest, false},
+		{"invalid fields", `{"testID": "t1", "logRate": -1}`, http.StatusBadRequest, problem.CodeValidationFailed, true},
+	}
+	for _, tc := range cases {
+		rec := httptest.NewRecorder()
+		rec.Header().Set("X-Request-ID", "req-1")
+		h.StartTest(rec, httptest.NewRequest(http.MethodPost, "/start-test", strings.NewReader(tc.body)))
+
+		if rec.Code != tc.status {
+			t.Errorf("%s: expected status %d, got %d", tc.name, tc.status, rec.Code)
+		}
+		if ct := rec.Header().Get("Content-Type"); ct != problem.ContentType {
+			t.Errorf("%s: expected %s, got %s", tc.name, problem.ContentType, ct)
+		}
+		var p problem.Problem
+		if err := json.NewDecoder(rec.Body).Decode(&p); err != nil {
+			t.Fatalf("%s: %v", tc.name, err)
+		}
+		if p.Code != tc.code || p.Type != "urn:moniflux:error:"+tc.code || p.Status != tc.status {
+			t.Errorf("%s: unexpected problem %+v", tc.name, p)
+		}
+		if p.RequestID != "req-1" {
+			t.Errorf("%s: expected the request ID, got %q", tc.name, p.RequestID)
+		}
+		if tc.fields != (len(p.Errors) > 0) {
+			t.Errorf("%s: unexpected field errors %+v", tc.name, p.Errors)
+		}
+	}
+}
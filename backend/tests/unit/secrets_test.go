@@ -0,0 +1,94 @@
+package unit
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/AkshayDubey29/MoniFlux/backend/internal/common"
+	"github.com/AkshayDubey29/MoniFlux/backend/internal/services/secrets"
+	"github.com/sirupsen/logrus"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+func TestParseSecretRef(t *testing.T) {
+	valid := map[string]secrets.Ref{
+		"loki-prod":      {Provider: secrets.ProviderStore, Name: "loki-prod"},
+		"store:loki":     {Provider: secrets.ProviderStore, Name: "loki"},
+		"env:loki_prod":  {Provider: secrets.ProviderEnv, Name: "loki_prod"},
+		"file:token.txt": {Provider: secrets.ProviderFile, Name: "token.txt"},
+	}
+	for ref, want := range valid {
+		got, err := secrets.ParseRef(ref)
+		if err != nil || got != want {
+			t.Errorf("%q: got %+v, %v; want %+v", ref, got, err, want)
+		}
+	}
+
+	for _, ref := range []string{"", "vault:loki", "file:../etc/passwd", "file:a/b", "-leading-dash"} {
+		if _, err := secrets.ParseRef(ref); !errors.Is(err, secrets.ErrInvalidRef) {
+			t.Errorf("%q: expected ErrInvalidRef, got %v", ref, err)
+		}
+	}
+}
+
+func TestParseMasterKey(t *testing.T) {
+	if _, err := secrets.ParseMasterKey("MDEyMzQ1Njc4OWFiY2RlZjAxMjM0NTY3ODlhYmNkZWY="); err != nil {
+		t.Errorf("unexpected error for a 32-byte key: %v", err)
+	}
+	if _, err := secrets.ParseMasterKey("c2hvcnQ="); err == nil {
+		t.Error("expected a short key to be rejected")
+	}
+	if _, err := secrets.ParseMasterKey("not base64!"); err == nil {
+		t.Error("expected invalid base64 to be rejected")
+	}
+}
+
+func TestResolveSecretProvidersAndRedaction(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "collector"), []byte("file-token-5678\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("TEST_SECRET_LOKI_PROD", "env-token-1234")
+
+	// The client is never connected; the env and file providers do not use MongoDB.
+	client, err := mongo.NewClient(options.Client().ApplyURI("mongodb://127.0.0.1:1"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg := &common.Config{MongoDB: "test", Secrets: common.SecretsConfig{EnvPrefix: "TEST_SECRET_", FileDir: dir}}
+
+	var out bytes.Buffer
+	logger := logrus.New()
+	logger.SetOutput(&out)
+	service := secrets.NewSecretService(cfg, logger, client)
+
+	ctx := context.Background()
+	if value, err := service.Resolve(ctx, "env:loki-prod"); err != nil || value != "env-token-1234" {
+		t.Errorf("env provider: got %q, %v", value, err)
+	}
+	if value, err := service.Resolve(ctx, "file:collector"); err != nil || value != "file-token-5678" {
+		t.Errorf("file provider: got %q, %v", value, err)
+	}
+	if err := service.CheckRef(ctx, "env:missing", ""); !errors.Is(err, secrets.ErrSecretNotFound) {
+		t.Errorf("expected ErrSecretNotFound for an unset variable, got %v", err)
+	}
+	if _, err := service.PutSecret(ctx, "loki", &secrets.SecretRequest{Value: "x"}, "u1"); !errors.Is(err, secrets.ErrStoreDisabled) {
+		t.Errorf("expected ErrStoreDisabled without a master key, got %v", err)
+	}
+
+	logger.Infof("sending with Authorization: Bearer %s", "env-token-1234")
+	logger.WithField("token", "file-token-5678").Warn("delivery failed")
+	logged := out.String()
+	if strings.Contains(logged, "env-token-1234") || strings.Contains(logged, "file-token-5678") {
+		t.Errorf("resolved secret values were logged: %s", logged)
+	}
+	if strings.Count(logged, secrets.Redacted) != 2 {
+		t.Errorf("expected both values to be redacted, got: %s", logged)
+	}
+}
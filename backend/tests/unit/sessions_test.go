@@ -0,0 +1,207 @@
+package unit
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/AkshayDubey29/MoniFlux/backend/internal/api/models"
+	"github.com/AkshayDubey29/MoniFlux/backend/internal/common"
+	"github.com/AkshayDubey29/MoniFlux/backend/internal/services/authentication"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/integration/mtest"
+)
+
+// tokenHash is the hash under which refresh and account tokens are stored.
+func tokenHash(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+func newMockAuth(t *testing.T, mt *mtest.T) *authentication.AuthenticationService {
+	t.Helper()
+	service, err := authentication.NewAuthenticationService(&common.Config{MongoDB: mockDB, JWTSecret: "test-secret"}, quietLogger(), mt.Client)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return service
+}
+
+// expectRevoked checks that the session was marked revoked for reuse and its access token revoked.
+func expectRevoked(t *testing.T, mt *mtest.T, accessTokenID string) {
+	t.Helper()
+	var revokedSession, revokedToken bool
+	for _, cmd := range sentCommands(mt, "update") {
+		update := cmd.Lookup("updates", "0").Document()
+		switch cmd.Lookup("update").StringValue() {
+		case "sessions":
+			if reason, ok := update.Lookup("u", "$set", "revokedReason").StringValueOK(); ok && strings.Contains(reason, "reuse") {
+				revokedSession = true
+			}
+		case "revoked_tokens":
+			if update.Lookup("q", "jti").StringValue() == accessTokenID {
+				revokedToken = true
+			}
+		}
+	}
+	if !revokedSession {
+		t.Error("expected the session to be revoked")
+	}
+	if !revokedToken {
+		t.Errorf("expected access token %s to be revoked", accessTokenID)
+	}
+}
+
+func TestRefreshTokenRotation(t *testing.T) {
+	mt := newMockMongo(t)
+	ctx := context.Background()
+	user := models.User{ID: primitive.NewObjectID(), Username: "jdoe"}
+	const refreshToken = "sess-1.first-secret"
+	session := func(hash string, expiresAt time.Time) authentication.Session {
+		return authentication.Session{
+			SessionID: "sess-1", UserID: user.ID.Hex(), RefreshTokenHash: hash, AccessTokenID: "jti-1",
+			CreatedAt: time.Now().Add(-time.Hour), ExpiresAt: expiresAt,
+		}
+	}
+	valid := time.Now().Add(time.Hour)
+
+	mt.Run("rotates once and revokes the session on replay", func(mt *mtest.T) {
+		service := newMockAuth(t, mt)
+		mt.AddMockResponses(
+			findResponse(mt, "sessions", session(tokenHash(refreshToken), valid)),
+			findResponse(mt, "users", user),
+			writeResponse(1),
+		)
+		pair, err := service.RefreshSession(ctx, refreshToken)
+		if err != nil {
+			t.Fatalf("RefreshSession: %v", err)
+		}
+		if pair.RefreshToken == refreshToken || !strings.HasPrefix(pair.RefreshToken, "sess-1.") {
+			t.Errorf("expected a new refresh token for sess-1, got %q", pair.RefreshToken)
+		}
+		rotation := sentCommands(mt, "update")[0].Lookup("updates", "0").Document()
+		if got := rotation.Lookup("q", "refreshTokenHash").StringValue(); got != tokenHash(refreshToken) {
+			t.Error("expected rotation to be conditional on the presented token")
+		}
+		if got := rotation.Lookup("u", "$set", "refreshTokenHash").StringValue(); got != tokenHash(pair.RefreshToken) {
+			t.Error("expected the new refresh token to be stored")
+		}
+
+		mt.AddMockResponses(countResponse(mt, "revoked_tokens", 0))
+		claims, err := service.ValidateJWT(ctx, pair.AccessToken)
+		if err != nil {
+			t.Fatalf("ValidateJWT: %v", err)
+		}
+		if claims.SessionID != "sess-1" || claims.UserID != user.ID.Hex() {
+			t.Errorf("unexpected claims %+v", claims)
+		}
+
+		// The session now holds the rotated token, so the old one is a replay.
+		rotated := session(tokenHash(pair.RefreshToken), valid)
+		rotated.AccessTokenID = claims.ID
+		mt.ClearEvents()
+		mt.AddMockResponses(
+			findResponse(mt, "sessions", rotated),
+			writeResponse(1),
+			findResponse(mt, "sessions", rotated),
+			writeResponse(1),
+		)
+		if _, err := service.RefreshSession(ctx, refreshToken); !errors.Is(err, authentication.ErrRefreshTokenReused) {
+			t.Fatalf("expected ErrRefreshTokenReused, got %v", err)
+		}
+		expectRevoked(t, mt, claims.ID)
+	})
+
+	mt.Run("concurrent rotation is treated as reuse", func(mt *mtest.T) {
+		service := newMockAuth(t, mt)
+		current := session(tokenHash(refreshToken), valid)
+		mt.AddMockResponses(
+			findResponse(mt, "sessions", current),
+			findResponse(mt, "users", user),
+			writeResponse(0),
+			writeResponse(1),
+			findResponse(mt, "sessions", current),
+			writeResponse(1),
+		)
+		if _, err := service.RefreshSession(ctx, refreshToken); !errors.Is(err, authentication.ErrRefreshTokenReused) {
+			t.Fatalf("expected ErrRefreshTokenReused, got %v", err)
+		}
+		expectRevoked(t, mt, "jti-1")
+	})
+
+	mt.Run("expired refresh token is rejected", func(mt *mtest.T) {
+		service := newMockAuth(t, mt)
+		mt.AddMockResponses(findResponse(mt, "sessions", session(tokenHash(refreshToken), time.Now().Add(-time.Minute))))
+		if _, err := service.RefreshSession(ctx, refreshToken); !errors.Is(err, authentication.ErrInvalidRefreshToken) {
+			t.Fatalf("expected ErrInvalidRefreshToken, got %v", err)
+		}
+		if n := len(sentCommands(mt, "update")); n != 0 {
+			t.Errorf("expected no updates, got %d", n)
+		}
+	})
+
+	mt.Run("malformed refresh token is rejected", func(mt *mtest.T) {
+		if _, err := newMockAuth(t, mt).RefreshSession(ctx, "no-session-id"); !errors.Is(err, authentication.ErrInvalidRefreshToken) {
+			t.Fatalf("expected ErrInvalidRefreshToken, got %v", err)
+		}
+	})
+}
+
+func TestLogoutRevokesAccessToken(t *testing.T) {
+	mt := newMockMongo(t)
+	ctx := context.Background()
+	userID := primitive.NewObjectID().Hex()
+
+	mt.Run("logout", func(mt *mtest.T) {
+		service := newMockAuth(t, mt)
+		token, err := service.GenerateJWT(userID, "editor")
+		if err != nil {
+			t.Fatal(err)
+		}
+		mt.AddMockResponses(countResponse(mt, "revoked_tokens", 0))
+		claims, err := service.ValidateJWT(ctx, token)
+		if err != nil {
+			t.Fatalf("ValidateJWT before logout: %v", err)
+		}
+
+		current := authentication.Session{SessionID: "sess-1", UserID: userID, AccessTokenID: claims.ID, ExpiresAt: time.Now().Add(time.Hour)}
+		mt.ClearEvents()
+		mt.AddMockResponses(
+			writeResponse(1),
+			findResponse(mt, "sessions", current),
+			writeResponse(1),
+			findResponse(mt, "sessions", current),
+			writeResponse(1),
+		)
+		principal := &models.Principal{UserID: userID, SessionID: "sess-1", TokenID: claims.ID}
+		if err := service.Logout(ctx, principal); err != nil {
+			t.Fatalf("Logout: %v", err)
+		}
+
+		var revoked, sessionRevoked bool
+		for _, cmd := range sentCommands(mt, "update") {
+			update := cmd.Lookup("updates", "0").Document()
+			switch cmd.Lookup("update").StringValue() {
+			case "revoked_tokens":
+				revoked = revoked || update.Lookup("q", "jti").StringValue() == claims.ID
+			case "sessions":
+				sessionRevoked = sessionRevoked || update.Lookup("u", "$set", "revokedReason").StringValue() == "logout"
+			}
+		}
+		if !revoked {
+			t.Error("expected the access token to be revoked")
+		}
+		if !sessionRevoked {
+			t.Error("expected the session to be revoked")
+		}
+
+		mt.AddMockResponses(countResponse(mt, "revoked_tokens", 1))
+		if _, err := service.ValidateJWT(ctx, token); !errors.Is(err, authentication.ErrTokenRevoked) {
+			t.Errorf("expected ErrTokenRevoked after logout, got %v", err)
+		}
+	})
+}
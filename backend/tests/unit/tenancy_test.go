@@ -0,0 +1,155 @@
+package unit
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/AkshayDubey29/MoniFlux/backend/internal/api/models"
+	"github.com/AkshayDubey29/MoniFlux/backend/internal/common"
+	"github.com/AkshayDubey29/MoniFlux/backend/internal/services/tenancy"
+	"go.mongodb.org/mongo-driver/mongo/integration/mtest"
+)
+
+func TestTestVisibilityByTenant(t *testing.T) {
+	owner := &models.Principal{UserID: "u1", Roles: []string{"editor"}}
+	member := &models.Principal{UserID: "u2", Roles: []string{"editor"}}
+	outsider := &models.Principal{UserID: "u3", Roles: []string{"editor"}}
+	admin := &models.Principal{UserID: "u4", Roles: []string{models.AdminRole}}
+	shared := models.Test{TestID: "t1", UserID: "u1", TeamID: "team1", Status: "Completed"}
+
+	cases := []struct {
+		name      string
+		principal *models.Principal
+		member    bool // Answer to the team membership lookup, when made
+		lookup    bool // Whether team membership is looked up
+		visible   bool
+	}{
+		{"owner", owner, false, false, true},
+		{"team member", member, true, true, true},
+		{"admin", admin, false, false, true},
+		{"outsider", outsider, false, true, false},
+	}
+
+	mt := newMockMongo(t)
+	for _, tc := range cases {
+		mt.Run(tc.name, func(mt *mtest.T) {
+			controller := newMockController(mt, nil)
+			count := 0
+			if tc.member {
+				count = 1
+			}
+			mt.AddMockResponses(findResponse(mt, "tests", shared), countResponse(mt, "teams", count))
+
+			ctx := models.ContextWithPrincipal(context.Background(), tc.principal)
+			test, err := controller.GetTestByID(ctx, shared.TestID)
+			if tc.visible && (err != nil || test.TestID != shared.TestID) {
+				t.Errorf("expected the test to be visible, got %v", err)
+			}
+			// Tests outside the caller's tenants are reported as not found, not forbidden.
+			if !tc.visible && !errors.Is(err, models.ErrTestNotFound) {
+				t.Errorf("expected ErrTestNotFound, got %v", err)
+			}
+			if lookups := len(sentCommands(mt, "aggregate")); (lookups == 1) != tc.lookup {
+				t.Errorf("expected membership lookup %v, got %d lookups", tc.lookup, lookups)
+			}
+		})
+	}
+}
+
+func TestTestListingScopedToTenants(t *testing.T) {
+	mt := newMockMongo(t)
+
+	mt.Run("user", func(mt *mtest.T) {
+		controller := newMockController(mt, nil)
+		mt.AddMockResponses(
+			findResponse(mt, "teams", tenancy.Team{TeamID: "team1"}, tenancy.Team{TeamID: "team2"}),
+			findResponse(mt, "tests"),
+		)
+		ctx := models.ContextWithPrincipal(context.Background(), &models.Principal{UserID: "u1", Roles: []string{"editor"}})
+		if _, err := controller.GetAllTests(ctx, nil); err != nil {
+			t.Fatal(err)
+		}
+		finds := sentCommands(mt, "find")
+		if len(finds) != 2 {
+			t.Fatalf("expected a teams and a tests find, got %d", len(finds))
+		}
+		filter := finds[1].Lookup("filter").String()
+		if !strings.Contains(filter, `{"$or": [{"userID": "u1"},{"teamID": {"$in": ["team1","team2"]}}]}`) {
+			t.Errorf("expected the listing to be limited to the user's and their teams' tests, got %s", filter)
+		}
+	})
+
+	mt.Run("admin", func(mt *mtest.T) {
+		controller := newMockController(mt, nil)
+		mt.AddMockResponses(findResponse(mt, "tests"))
+		ctx := models.ContextWithPrincipal(context.Background(), &models.Principal{UserID: "u4", Roles: []string{models.AdminRole}})
+		if _, err := controller.GetAllTests(ctx, nil); err != nil {
+			t.Fatal(err)
+		}
+		finds := sentCommands(mt, "find")
+		if len(finds) != 1 || strings.Contains(finds[0].Lookup("filter").String(), "userID") {
+			t.Errorf("expected an unscoped listing for admins, got %d finds", len(finds))
+		}
+	})
+}
+
+// tenantTest returns a valid test for the team and project.
+func tenantTest(teamID, projectID string) models.Test {
+	return models.Test{
+		TestID:      "t1",
+		TeamID:      teamID,
+		ProjectID:   projectID,
+		LogType:     "INFO",
+		LogRate:     10,
+		LogSize:     100,
+		Duration:    60,
+		Destination: common.Destination{Type: "file", FilePath: "/tmp/moniflux-tenancy.log"},
+	}
+}
+
+func TestCreateTestRejectsForeignTenants(t *testing.T) {
+	ctx := models.ContextWithPrincipal(context.Background(), &models.Principal{UserID: "u1", Roles: []string{"editor"}})
+	mt := newMockMongo(t)
+
+	mt.Run("team the caller does not belong to", func(mt *mtest.T) {
+		controller := newMockController(mt, nil)
+		mt.AddMockResponses(findResponse(mt, "tests"), countResponse(mt, "teams", 0))
+		test := tenantTest("team9", "")
+		if err := controller.CreateTest(ctx, &test); !errors.Is(err, models.ErrForbidden) {
+			t.Errorf("expected ErrForbidden, got %v", err)
+		}
+		if n := len(sentCommands(mt, "insert")); n != 0 {
+			t.Errorf("expected the test not to be stored, got %d inserts", n)
+		}
+	})
+
+	mt.Run("project of a team the caller does not belong to", func(mt *mtest.T) {
+		controller := newMockController(mt, nil)
+		mt.AddMockResponses(
+			findResponse(mt, "tests"),
+			findResponse(mt, "projects", tenancy.Project{ProjectID: "p9", TeamID: "team9"}),
+			countResponse(mt, "teams", 0),
+		)
+		test := tenantTest("", "p9")
+		if err := controller.CreateTest(ctx, &test); !errors.Is(err, models.ErrForbidden) {
+			t.Errorf("expected ErrForbidden, got %v", err)
+		}
+		if n := len(sentCommands(mt, "insert")); n != 0 {
+			t.Errorf("expected the test not to be stored, got %d inserts", n)
+		}
+	})
+
+	mt.Run("project outside the requested team", func(mt *mtest.T) {
+		controller := newMockController(mt, nil)
+		mt.AddMockResponses(findResponse(mt, "tests"), findResponse(mt, "projects", tenancy.Project{ProjectID: "p9", TeamID: "team9"}))
+		test := tenantTest("team1", "p9")
+		if err := controller.CreateTest(ctx, &test); !errors.Is(err, models.ErrInvalidTest) {
+			t.Errorf("expected ErrInvalidTest, got %v", err)
+		}
+		if n := len(sentCommands(mt, "insert")); n != 0 {
+			t.Errorf("expected the test not to be stored, got %d inserts", n)
+		}
+	})
+}
@@ -0,0 +1,205 @@
+package unit
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/AkshayDubey29/MoniFlux/backend/internal/api/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo/integration/mtest"
+)
+
+// pageOfTests returns n tests created a minute apart, newest first.
+func pageOfTests(n int) []interface{} {
+	start := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+	tests := make([]interface{}, n)
+	for i := range tests {
+		tests[i] = models.Test{
+			TestID:    fmt.Sprintf("t%03d", n-i),
+			Status:    "Completed",
+			CreatedAt: start.Add(time.Duration(n-i) * time.Minute),
+		}
+	}
+	return tests
+}
+
+// sortedJSON returns the document as extended JSON with the keys of every embedded
+// document sorted, as bson.M filters are marshaled in no particular order.
+func sortedJSON(t *testing.T, v interface{}) string {
+	t.Helper()
+	var sortKeys func(doc bson.D) bson.D
+	sortKeys = func(doc bson.D) bson.D {
+		for i, e := range doc {
+			if embedded, ok := e.Value.(bson.D); ok {
+				doc[i].Value = sortKeys(embedded)
+			}
+		}
+		sort.Slice(doc, func(i, j int) bool { return doc[i].Key < doc[j].Key })
+		return doc
+	}
+	data, err := bson.MarshalExtJSON(sortKeys(toDoc(t, v)), false, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(data)
+}
+
+// sentFind returns the single find command sent on the tests collection.
+func sentFind(t *testing.T, mt *mtest.T) bson.Raw {
+	t.Helper()
+	finds := sentCommands(mt, "find")
+	if len(finds) != 1 {
+		t.Fatalf("expected one find, got %d", len(finds))
+	}
+	return finds[0]
+}
+
+func TestGetAllTestsFilters(t *testing.T) {
+	mt := newMockMongo(t)
+	after := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	cases := []struct {
+		name  string
+		query models.TestListQuery
+		want  bson.M // Expected filter, as extended JSON values
+	}{
+		{name: "no filters", want: bson.M{}},
+		{name: "single status", query: models.TestListQuery{Status: []string{"Running"}},
+			want: bson.M{"status": "Running"}},
+		{name: "several statuses", query: models.TestListQuery{Status: []string{"Running", "Queued"}},
+			want: bson.M{"status": bson.M{"$in": bson.A{"Running", "Queued"}}}},
+		{name: "owner, destination and tags", query: models.TestListQuery{UserID: "u1", DestinationType: "http", Tags: []string{"a", "b"}},
+			want: bson.M{"userID": "u1", "destination.type": "http", "tags": bson.M{"$all": bson.A{"a", "b"}}}},
+		{name: "team and project", query: models.TestListQuery{TeamID: "team-1", ProjectID: "p1"},
+			want: bson.M{"teamID": "team-1", "projectID": "p1"}},
+		{name: "created range", query: models.TestListQuery{CreatedAfter: after, CreatedBefore: after.Add(time.Hour)},
+			want: bson.M{"createdAt": bson.M{"$gte": after, "$lt": after.Add(time.Hour)}}},
+		{name: "updated after", query: models.TestListQuery{UpdatedAfter: after},
+			want: bson.M{"updatedAt": bson.M{"$gte": after}}},
+	}
+
+	for _, tc := range cases {
+		mt.Run(tc.name, func(mt *mtest.T) {
+			mt.AddMockResponses(findResponse(mt, "tests"))
+			query := tc.query
+			if _, err := newMockController(mt, nil).GetAllTests(context.Background(), &query); err != nil {
+				t.Fatalf("GetAllTests: %v", err)
+			}
+			got := sortedJSON(t, sentFind(t, mt).Lookup("filter").Document())
+			if want := sortedJSON(t, tc.want); got != want {
+				t.Errorf("filter: got %s, want %s", got, want)
+			}
+		})
+	}
+}
+
+func TestGetAllTestsPagination(t *testing.T) {
+	mt := newMockMongo(t)
+	ctx := context.Background()
+	cases := []struct {
+		name      string
+		limit     int
+		returned  int // Documents the database returns
+		wantLimit int64
+		wantPage  int
+		more      bool
+	}{
+		{name: "default page size", limit: 0, returned: 3, wantLimit: 51, wantPage: 3},
+		{name: "page size capped", limit: 10000, returned: 2, wantLimit: 501, wantPage: 2},
+		{name: "last page", limit: 5, returned: 5, wantLimit: 6, wantPage: 5},
+		{name: "another page follows", limit: 5, returned: 6, wantLimit: 6, wantPage: 5, more: true},
+	}
+
+	for _, tc := range cases {
+		mt.Run(tc.name, func(mt *mtest.T) {
+			mt.AddMockResponses(findResponse(mt, "tests", pageOfTests(tc.returned)...))
+			page, err := newMockController(mt, nil).GetAllTests(ctx, &models.TestListQuery{Limit: tc.limit})
+			if err != nil {
+				t.Fatalf("GetAllTests: %v", err)
+			}
+			find := sentFind(t, mt)
+			if limit := find.Lookup("limit").AsInt64(); limit != tc.wantLimit {
+				t.Errorf("expected a limit of %d, got %d", tc.wantLimit, limit)
+			}
+			order := find.Lookup("sort").Document()
+			if order.String() != `{"createdAt": {"$numberInt":"-1"},"testID": {"$numberInt":"-1"}}` {
+				t.Errorf("expected newest first with testID as tie-breaker, got %s", order)
+			}
+			if len(page.Tests) != tc.wantPage {
+				t.Errorf("expected %d tests, got %d", tc.wantPage, len(page.Tests))
+			}
+			if (page.NextCursor != "") != tc.more {
+				t.Errorf("expected a next cursor: %t, got %q", tc.more, page.NextCursor)
+			}
+		})
+	}
+
+	mt.Run("next cursor resumes after the last test", func(mt *mtest.T) {
+		tests := pageOfTests(3)
+		mt.AddMockResponses(findResponse(mt, "tests", tests...))
+		controller := newMockController(mt, nil)
+		page, err := controller.GetAllTests(ctx, &models.TestListQuery{Limit: 2})
+		if err != nil {
+			t.Fatalf("GetAllTests: %v", err)
+		}
+		last := tests[1].(models.Test)
+		if len(page.Tests) != 2 || page.Tests[1].TestID != last.TestID || page.NextCursor == "" {
+			t.Fatalf("expected two tests and a cursor, got %+v", page)
+		}
+
+		mt.ClearEvents()
+		mt.AddMockResponses(findResponse(mt, "tests", tests[2]))
+		page, err = controller.GetAllTests(ctx, &models.TestListQuery{Limit: 2, Cursor: page.NextCursor})
+		if err != nil {
+			t.Fatalf("GetAllTests with cursor: %v", err)
+		}
+		if len(page.Tests) != 1 || page.NextCursor != "" {
+			t.Errorf("expected the last test without a cursor, got %+v", page)
+		}
+		after := sentFind(t, mt).Lookup("filter", "$and", "1", "$or").Array()
+		older := after.Lookup("0", "createdAt", "$lt").Time()
+		tie := after.Lookup("1", "testID", "$lt").StringValue()
+		if !older.Equal(last.CreatedAt) || tie != last.TestID {
+			t.Errorf("expected the filter to resume after %s, got %s", last.TestID, after)
+		}
+	})
+}
+
+func TestGetAllTestsRejectsInvalidCursors(t *testing.T) {
+	mt := newMockMongo(t)
+	ctx := context.Background()
+	encode := func(s string) string { return base64.RawURLEncoding.EncodeToString([]byte(s)) }
+	cases := []struct {
+		name   string
+		cursor string
+		sortBy string
+	}{
+		{name: "bad base64", cursor: "not*base64!"},
+		{name: "bad JSON", cursor: encode("{not json")},
+		{name: "missing test ID", cursor: encode(`{"s":"createdAt","v":"2026-01-01T00:00:00Z"}`)},
+		{name: "bad time", cursor: encode(`{"s":"createdAt","v":"yesterday","id":"t1"}`)},
+		{name: "issued for another sort", cursor: encode(`{"s":"createdAt","v":"2026-01-01T00:00:00Z","id":"t1"}`), sortBy: "status"},
+	}
+
+	for _, tc := range cases {
+		mt.Run(tc.name, func(mt *mtest.T) {
+			_, err := newMockController(mt, nil).GetAllTests(ctx, &models.TestListQuery{SortBy: tc.sortBy, Cursor: tc.cursor})
+			if !errors.Is(err, models.ErrInvalidCursor) {
+				t.Errorf("expected ErrInvalidCursor, got %v", err)
+			}
+			if n := len(mt.GetAllStartedEvents()); n != 0 {
+				t.Errorf("expected no query, got %d commands", n)
+			}
+		})
+	}
+
+	mt.Run("unsupported sort field", func(mt *mtest.T) {
+		if _, err := newMockController(mt, nil).GetAllTests(ctx, &models.TestListQuery{SortBy: "logRate"}); err == nil {
+			t.Error("expected an unsupported sort field to be rejected")
+		}
+	})
+}
@@ -0,0 +1,82 @@
+package unit
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/AkshayDubey29/MoniFlux/backend/internal/api/middlewares"
+	"github.com/AkshayDubey29/MoniFlux/backend/internal/common"
+	"github.com/AkshayDubey29/MoniFlux/backend/internal/loadgen/delivery"
+	"github.com/AkshayDubey29/MoniFlux/backend/pkg/tracing"
+	"github.com/gorilla/mux"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+)
+
+func TestTracingPropagatesToDestinations(t *testing.T) {
+	exporter := tracetest.NewInMemoryExporter()
+	provider := tracing.NewTracerProvider(common.Tracing{SampleRatio: 1}, "test", sdktrace.WithSyncer(exporter))
+	defer provider.Shutdown(context.Background())
+	otel.SetTracerProvider(provider)
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+
+	// The destination receives the traceparent of the delivery span.
+	var received string
+	destination := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		received = r.Header.Get("traceparent")
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer destination.Close()
+	sender, err := delivery.NewHTTPSender(context.Background(), common.Destination{Type: "http", Endpoint: destination.URL}, staticSecrets{}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	router := mux.NewRouter()
+	router.Use(middlewares.RequestIDMiddleware, middlewares.TracingMiddleware)
+	router.HandleFunc("/tests/{testID}", func(w http.ResponseWriter, r *http.Request) {
+		if err := sender.Send(r.Context(), "logs", jsonPayload); err != nil {
+			t.Errorf("send failed: %v", err)
+		}
+	})
+
+	const traceID = "4bf92f3577b34da6a3ce929d0e0e4736"
+	req := httptest.NewRequest(http.MethodGet, "/tests/t1", nil)
+	req.Header.Set("traceparent", "00-"+traceID+"-00f067aa0ba902b7-01")
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+
+	if got := rec.Header().Get("X-Trace-ID"); got != traceID {
+		t.Errorf("X-Trace-ID = %q, want %q", got, traceID)
+	}
+	carried := propagation.TraceContext{}.Extract(context.Background(), propagation.HeaderCarrier{"Traceparent": []string{received}})
+	if got := trace.SpanContextFromContext(carried).TraceID().String(); got != traceID {
+		t.Errorf("destination traceparent %q does not continue trace %s", received, traceID)
+	}
+
+	spans := exporter.GetSpans()
+	if len(spans) != 2 {
+		t.Fatalf("got %d spans, want the server and delivery spans", len(spans))
+	}
+	server := spans[1]
+	if server.Name != "GET /tests/{testID}" || server.SpanKind != trace.SpanKindServer {
+		t.Errorf("unexpected server span %q (%v)", server.Name, server.SpanKind)
+	}
+	var requestID string
+	for _, attr := range server.Attributes {
+		if attr.Key == tracing.RequestIDKey {
+			requestID = attr.Value.AsString()
+		}
+	}
+	if requestID == "" || requestID != rec.Header().Get("X-Request-ID") {
+		t.Errorf("request_id attribute %q does not match X-Request-ID %q", requestID, rec.Header().Get("X-Request-ID"))
+	}
+	if spans[0].Parent.SpanID() != server.SpanContext.SpanID() {
+		t.Error("delivery span is not a child of the server span")
+	}
+}